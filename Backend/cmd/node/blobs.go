@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func newBlobCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "blob",
+		Short: "Store payloads off-chain and reference them by hash",
+	}
+	put := &cobra.Command{
+		Use:   "put <file|->",
+		Short: "Upload a payload to the node's blob store",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var data []byte
+			var err error
+			if args[0] == "-" {
+				data, err = io.ReadAll(os.Stdin)
+			} else {
+				data, err = os.ReadFile(args[0])
+			}
+			if err != nil {
+				return err
+			}
+			c := newClient(cmd)
+			if submit, _ := cmd.Flags().GetBool("tx"); submit {
+				note, _ := cmd.Flags().GetString("data")
+				res, err := c.SubmitBlob(cmd.Context(), data, note)
+				if err != nil {
+					return err
+				}
+				return printJSON(res)
+			}
+			hash, err := c.PutBlob(cmd.Context(), data)
+			if err != nil {
+				return err
+			}
+			return printJSON(map[string]interface{}{"hash": hash, "size": len(data)})
+		},
+	}
+	put.Flags().Bool("tx", false, "also submit a transaction carrying the blob's hash")
+	put.Flags().String("data", "", "note to put in the transaction with --tx")
+	cmd.AddCommand(put, &cobra.Command{
+		Use:   "get <hash> [file]",
+		Short: "Download and verify a blob to file or stdout",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := newClient(cmd).GetBlob(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			if len(args) == 1 || args[1] == "-" {
+				_, err = os.Stdout.Write(data)
+				return err
+			}
+			return os.WriteFile(args[1], data, 0o644)
+		},
+	})
+	return cmd
+}
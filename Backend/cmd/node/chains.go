@@ -0,0 +1,121 @@
+package main
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"salmanahmed/blockchain/pkg/api"
+)
+
+func newChainsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "chains",
+		Short: "Manage the extra chains a node hosts (address one with --chain)",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List hosted chains",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			chains, err := newRootClient(cmd).Chains(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(chains)
+		},
+	})
+	create := &cobra.Command{
+		Use:   "create <id>",
+		Short: "Start a new chain with its own genesis and mempool",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			spec := api.ChainSpec{ID: args[0]}
+			spec.Difficulty, _ = cmd.Flags().GetInt("difficulty")
+			spec.Genesis, _ = cmd.Flags().GetString("genesis")
+			if err := newRootClient(cmd).CreateChain(cmd.Context(), spec, policyFlags(cmd)); err != nil {
+				return err
+			}
+			return printJSON(map[string]string{"status": "chain created", "url": nodeRootURL(cmd) + "/chains/" + spec.ID + "/"})
+		},
+	}
+	create.Flags().Int("difficulty", 0, "leading zeros required (0 uses the node's difficulty)")
+	create.Flags().String("genesis", "", "genesis transaction data (default the roll number)")
+	addPolicyFlags(create)
+	cmd.AddCommand(create)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "show <id>",
+		Short: "Show a chain's spec and policy (needs the node token)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info, err := newRootClient(cmd).ChainAdmin(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return printJSON(info)
+		},
+	})
+	setPolicy := &cobra.Command{
+		Use:   "set-policy <id>",
+		Short: "Replace a chain's API keys, quotas and retention (needs the node token)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info, err := newRootClient(cmd).SetChainPolicy(cmd.Context(), args[0], policyFlags(cmd))
+			if err != nil {
+				return err
+			}
+			return printJSON(info)
+		},
+	}
+	addPolicyFlags(setPolicy)
+	cmd.AddCommand(setPolicy)
+	cmd.AddCommand(&cobra.Command{
+		Use:   "delete <id>",
+		Short: "Stop hosting a chain and discard it (needs the node token)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := newRootClient(cmd).DeleteChain(cmd.Context(), args[0]); err != nil {
+				return err
+			}
+			return printJSON(map[string]string{"status": "chain deleted", "id": args[0]})
+		},
+	}, &cobra.Command{
+		Use:   "usage",
+		Short: "Show each API key's requests and transactions per chain (needs the node token)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			usage, err := newRootClient(cmd).Usage(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(usage)
+		},
+	})
+	return cmd
+}
+
+// addPolicyFlags adds the flags read by policyFlags
+func addPolicyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("api-keys", nil, "bearer tokens allowed to write to the chain")
+	cmd.Flags().Int("rate-per-minute", 0, "requests per minute the chain accepts (0 is unlimited)")
+	cmd.Flags().Int("max-pending", 0, "mempool size cap (0 is unlimited)")
+	cmd.Flags().Duration("retain-idle", 0, "delete the chain after this long without writes (0 keeps it)")
+	cmd.Flags().Int("high-priority-per-minute", 0, "high-priority transactions each API key may submit a minute (0 is unlimited)")
+	cmd.Flags().Int("daily-requests", 0, "requests each API key may make per UTC day (0 is unlimited)")
+	cmd.Flags().Int("daily-transactions", 0, "transactions each API key may submit per UTC day (0 is unlimited)")
+}
+
+// policyFlags builds a chain policy from the command's flags
+func policyFlags(cmd *cobra.Command) api.ChainPolicy {
+	var p api.ChainPolicy
+	p.APIKeys, _ = cmd.Flags().GetStringSlice("api-keys")
+	p.RatePerMinute, _ = cmd.Flags().GetInt("rate-per-minute")
+	p.MaxPending, _ = cmd.Flags().GetInt("max-pending")
+	idle, _ := cmd.Flags().GetDuration("retain-idle")
+	p.RetainIdleSecond = int64(idle / time.Second)
+	p.HighPriorityPerMinute, _ = cmd.Flags().GetInt("high-priority-per-minute")
+	p.DailyRequests, _ = cmd.Flags().GetInt("daily-requests")
+	p.DailyTransactions, _ = cmd.Flags().GetInt("daily-transactions")
+	return p
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"salmanahmed/blockchain/pkg/chaos"
+)
+
+func newChaosCmd() *cobra.Command {
+	cmd := &cobra.Command{Use: "chaos", Short: "Inject faults into a node started with --chaos"}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "show",
+		Short: "Show the faults being injected",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := newClient(cmd).Chaos(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(f)
+		},
+	})
+
+	set := &cobra.Command{
+		Use:   "set",
+		Short: "Replace the injected faults; unset flags turn that fault off",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var f chaos.Faults
+			fs := cmd.Flags()
+			f.StorageFailRate, _ = fs.GetFloat64("storage-fail-rate")
+			f.GossipDropRate, _ = fs.GetFloat64("gossip-drop-rate")
+			delay, _ := fs.GetDuration("peer-delay")
+			skew, _ := fs.GetDuration("clock-skew")
+			f.PeerDelayMS, f.ClockSkewMS = delay.Milliseconds(), skew.Milliseconds()
+			if err := f.Validate(); err != nil {
+				return err
+			}
+			out, err := newClient(cmd).SetChaos(cmd.Context(), f)
+			if err != nil {
+				return err
+			}
+			return printJSON(out)
+		},
+	}
+	set.Flags().Float64("storage-fail-rate", 0, "probability a storage write fails")
+	set.Flags().Float64("gossip-drop-rate", 0, "probability a gossip message is dropped")
+	set.Flags().Duration("peer-delay", 0, "delay added to every peer message")
+	set.Flags().Duration("clock-skew", 0, "offset added to the node's clock, e.g. 90s or -5m")
+
+	cmd.AddCommand(set, &cobra.Command{
+		Use:   "off",
+		Short: "Stop injecting faults",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out, err := newClient(cmd).SetChaos(cmd.Context(), chaos.Faults{})
+			if err != nil {
+				return err
+			}
+			return printJSON(out)
+		},
+	})
+	return cmd
+}
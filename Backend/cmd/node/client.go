@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"salmanahmed/blockchain/pkg/client"
+)
+
+// nodeRootURL returns the --node base URL without a trailing slash
+func nodeRootURL(cmd *cobra.Command) string {
+	u, _ := cmd.Flags().GetString("node")
+	return strings.TrimRight(u, "/")
+}
+
+// nodeURL returns the base URL of the chain selected by --chain
+func nodeURL(cmd *cobra.Command) string {
+	u := nodeRootURL(cmd)
+	if id, _ := cmd.Flags().GetString("chain"); id != "" {
+		u += "/chains/" + url.PathEscape(id)
+	}
+	return u
+}
+
+// newClient returns an SDK client for --node and --chain, authenticated with --token
+func newClient(cmd *cobra.Command) *client.Client {
+	token, _ := cmd.Flags().GetString("token")
+	key, _ := cmd.Flags().GetString("key")
+	return client.New(nodeURL(cmd), client.WithToken(token), client.WithKey(key))
+}
+
+// newRootClient is newClient ignoring --chain, for node-wide endpoints
+func newRootClient(cmd *cobra.Command) *client.Client {
+	token, _ := cmd.Flags().GetString("token")
+	key, _ := cmd.Flags().GetString("key")
+	return client.New(nodeRootURL(cmd), client.WithToken(token), client.WithKey(key))
+}
+
+// printJSON pretty-prints v to stdout
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,927 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"salmanahmed/blockchain/pkg/api"
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/client"
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+// newLedgerCmd exports the double-entry journal for reconciling balances
+func newLedgerCmd() *cobra.Command {
+	var format, account string
+	cmd := &cobra.Command{
+		Use:   "ledger [file]",
+		Short: "Export debits and credits per account per block to file or stdout",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "csv" && format != "json" {
+				return fmt.Errorf("invalid format %q (want csv or json)", format)
+			}
+			if len(args) == 0 || args[0] == "-" {
+				return newClient(cmd).ExportLedger(cmd.Context(), os.Stdout, format, account)
+			}
+			f, err := os.Create(args[0])
+			if err != nil {
+				return err
+			}
+			if err := newClient(cmd).ExportLedger(cmd.Context(), f, format, account); err != nil {
+				f.Close()
+				return err
+			}
+			return f.Close()
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "csv", "csv or json")
+	cmd.Flags().StringVar(&account, "account", "", "only this address, or coinbase, issuance, fees or nonstandard")
+	return cmd
+}
+
+func newExportCmd() *cobra.Command {
+	var snapshot, binary bool
+	cmd := &cobra.Command{
+		Use:   "export [file]",
+		Short: "Stream the chain as newline-delimited JSON or binary blocks, or a snapshot with the mempool, to file or stdout",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if snapshot && binary {
+				return fmt.Errorf("--snapshot and --binary are exclusive")
+			}
+			c := newClient(cmd)
+			export := c.Export
+			switch {
+			case snapshot:
+				export = c.ExportSnapshot
+			case binary:
+				export = c.ExportBinary
+			}
+			if len(args) == 0 || args[0] == "-" {
+				return export(cmd.Context(), os.Stdout)
+			}
+			f, err := os.Create(args[0])
+			if err != nil {
+				return err
+			}
+			if err := export(cmd.Context(), f); err != nil {
+				f.Close()
+				return err
+			}
+			return f.Close()
+		},
+	}
+	cmd.Flags().BoolVar(&snapshot, "snapshot", false, "write one JSON document holding the chain and mempool")
+	cmd.Flags().BoolVar(&binary, "binary", false, "write framed binary blocks instead of JSON")
+	return cmd
+}
+
+func newImportCmd() *cobra.Command {
+	var snapshot, binary, dryRun bool
+	cmd := &cobra.Command{
+		Use:   "import <file|->",
+		Short: "Stream blocks from an export into the node, skipping ones it has, or restore a snapshot",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if snapshot && binary {
+				return fmt.Errorf("--snapshot and --binary are exclusive")
+			}
+			in := os.Stdin
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				in = f
+			}
+			c := newClient(cmd)
+			if dryRun {
+				format := "ndjson"
+				switch {
+				case snapshot:
+					format = "snapshot"
+				case binary:
+					format = "binary"
+				}
+				plan, err := c.PlanImport(cmd.Context(), in, format)
+				if err != nil {
+					return err
+				}
+				return printJSON(plan)
+			}
+			if snapshot {
+				res, err := c.RestoreSnapshot(cmd.Context(), in)
+				if err != nil {
+					return err
+				}
+				return printJSON(res)
+			}
+			importBlocks := c.Import
+			if binary {
+				importBlocks = c.ImportBinary
+			}
+			res, err := importBlocks(cmd.Context(), in)
+			if err != nil {
+				return err
+			}
+			return printJSON(res)
+		},
+	}
+	cmd.Flags().BoolVar(&snapshot, "snapshot", false, "replace the node's chain and mempool with a snapshot from export --snapshot")
+	cmd.Flags().BoolVar(&binary, "binary", false, "read framed binary blocks from export --binary")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "validate only and print the blocks the import would roll back and apply")
+	return cmd
+}
+
+func newChainCmd() *cobra.Command {
+	cmd := &cobra.Command{Use: "chain", Short: "Inspect the chain"}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show height, tip, mempool size and readiness",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient(cmd)
+			blocks, err := c.Blocks(cmd.Context())
+			if err != nil {
+				return err
+			}
+			pending, err := c.Pending(cmd.Context())
+			if err != nil {
+				return err
+			}
+			ready := "ready"
+			if err := c.Ready(cmd.Context()); err != nil {
+				ready = err.Error()
+			}
+			if len(blocks) == 0 {
+				return fmt.Errorf("node returned an empty chain")
+			}
+			tip := blocks[len(blocks)-1]
+			return printJSON(map[string]interface{}{
+				"height":   tip.Index,
+				"tip_hash": tip.Hash,
+				"pending":  len(pending),
+				"status":   ready,
+			})
+		},
+	}, &cobra.Command{
+		Use:   "stats",
+		Short: "Show height, transactions, block time, difficulty, hashrate, and block and mempool sizes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := newClient(cmd).Stats(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(st)
+		},
+	}, newReorgsCmd(), newForksCmd(), newTamperCmd(), &cobra.Command{
+		Use:   "untamper",
+		Short: "Undo every tamper edit on a node running with --demo",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			restored, err := newClient(cmd).Untamper(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(map[string][]int{"restored": restored})
+		},
+	}, newAttestationsCmd(), newVerifyAttestationCmd(), &cobra.Command{
+		Use:   "validate",
+		Short: "Re-check every block's hash, link, proof of work and merkle root",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			res, err := newClient(cmd).Validate(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if err := printJSON(res); err != nil {
+				return err
+			}
+			if !res.Valid {
+				return fmt.Errorf("block %d is invalid: %s", *res.Block, res.Reason)
+			}
+			return nil
+		},
+	}, &cobra.Command{
+		Use:   "blocktime",
+		Short: "Show recent block intervals and the estimated time to the next block",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := newClient(cmd).BlockTimeStats(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(st)
+		},
+	}, &cobra.Command{
+		Use:   "difficulty",
+		Short: "Show the difficulty in force, how it retargets and recent block times",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := newClient(cmd).Difficulty(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(st)
+		},
+	}, &cobra.Command{
+		Use:   "estimate <difficulty>",
+		Short: "Show the expected hashes and mining time per block at a difficulty",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			level, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid difficulty %q", args[0])
+			}
+			est, err := newClient(cmd).EstimateDifficulty(cmd.Context(), level)
+			if err != nil {
+				return err
+			}
+			return printJSON(est)
+		},
+	}, &cobra.Command{
+		Use:   "set-difficulty <difficulty>",
+		Short: "Change the difficulty new blocks are mined to (needs the node token)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			level, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid difficulty %q", args[0])
+			}
+			out, err := newClient(cmd).SetDifficulty(cmd.Context(), level)
+			if err != nil {
+				return err
+			}
+			return printJSON(out)
+		},
+	}, &cobra.Command{
+		Use:   "deadline",
+		Short: "Show when the node stops accepting new transactions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			d, err := newClient(cmd).Deadline(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(d)
+		},
+	}, &cobra.Command{
+		Use:   "testvectors",
+		Short: "Print hashing, merkle and signature test vectors computed by the node",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := newClient(cmd).TestVectors(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(v)
+		},
+	}, &cobra.Command{
+		Use:   "replica",
+		Short: "Show how far a read-only replica has followed its upstream",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := newClient(cmd).ReplicaStatus(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(st)
+		},
+	}, &cobra.Command{
+		Use:   "set-deadline <rfc3339-time|none>",
+		Short: "Move the submission deadline, or remove it with none (needs the node token)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var deadline time.Time
+			if args[0] != "none" {
+				var err error
+				if deadline, err = time.Parse(time.RFC3339, args[0]); err != nil {
+					return fmt.Errorf("invalid deadline %q (want e.g. 2026-05-01T23:59:00Z)", args[0])
+				}
+			}
+			d, err := newClient(cmd).SetDeadline(cmd.Context(), deadline)
+			if err != nil {
+				return err
+			}
+			return printJSON(d)
+		},
+	}, &cobra.Command{
+		Use:   "reset [confirm-token]",
+		Short: "Wipe the chain back to genesis; run without a token to get one (needs the node token)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient(cmd)
+			if len(args) == 0 {
+				conf, err := c.RequestReset(cmd.Context())
+				if err != nil {
+					return err
+				}
+				return printJSON(conf)
+			}
+			res, err := c.Reset(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return printJSON(res)
+		},
+	}, &cobra.Command{
+		Use:   "freeze [reason]",
+		Short: "Halt mining, new transactions and peer sync for maintenance, keeping reads (needs the node token)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reason := ""
+			if len(args) > 0 {
+				reason = args[0]
+			}
+			st, err := newClient(cmd).Freeze(cmd.Context(), reason)
+			if err != nil {
+				return err
+			}
+			return printJSON(st)
+		},
+	}, &cobra.Command{
+		Use:   "unfreeze",
+		Short: "Resume mining, new transactions and peer sync after a freeze (needs the node token)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := newClient(cmd).Unfreeze(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(st)
+		},
+	}, newExportCmd(), newLedgerCmd(), newImportCmd())
+	return cmd
+}
+
+func newTxCmd() *cobra.Command {
+	cmd := &cobra.Command{Use: "tx", Short: "Submit and inspect transactions"}
+	send := &cobra.Command{
+		Use:   "send <data>",
+		Short: "Submit a transaction to the mempool",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient(cmd)
+			var res client.SubmitResult
+			var err error
+			to, _ := cmd.Flags().GetString("to")
+			priority, _ := cmd.Flags().GetString("priority")
+			switch {
+			case to != "" && (priority != "" || cmd.Flags().Changed("expires-at")):
+				return fmt.Errorf("--priority and --expires-at apply to plain data transactions only")
+			case to != "":
+				res, err = c.SubmitConfidential(cmd.Context(), to, args[0])
+			default:
+				expires, _ := cmd.Flags().GetInt("expires-at")
+				res, err = c.SubmitTransaction(cmd.Context(), blockchain.Transaction{Data: args[0], Priority: priority, ExpiresAt: expires})
+			}
+			if err != nil {
+				return err
+			}
+			return printJSON(res)
+		},
+	}
+	send.Flags().String("to", "", "encrypt the data to this hex public key, keeping only ciphertext on-chain")
+	send.Flags().String("priority", "", "template lane: high, normal or low (high may be rate limited per API key)")
+	send.Flags().Int("expires-at", 0, "last block height that may include the transaction; the mempool drops it after (0 never expires)")
+	cmd.AddCommand(send, &cobra.Command{
+		Use:   "pending",
+		Short: "List pending transactions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pending, err := newClient(cmd).Pending(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(pending)
+		},
+	}, &cobra.Command{
+		Use:   "drop <txid>",
+		Short: "Remove a pending transaction from the mempool",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			res, err := newClient(cmd).DropTransaction(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return printJSON(res)
+		},
+	}, &cobra.Command{
+		Use:   "orphans",
+		Short: "List transactions held until the transactions they spend from confirm",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orphans, err := newClient(cmd).Orphans(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(orphans)
+		},
+	}, newTxHistoryCmd(), newIssueCmd(), newPayCmd(), newDecryptCmd(), newCommitCmd(), newRevealCmd(), newWaitCmd(),
+		newFeeEstimateCmd(), newBuildTxCmd(), newSignTxCmd(), newPreviewTxCmd(), newSubmitSignedCmd())
+	return cmd
+}
+
+// newTxHistoryCmd shows what became of transactions that left the mempool
+func newTxHistoryCmd() *cobra.Command {
+	var outcome string
+	var limit int
+	cmd := &cobra.Command{
+		Use:   "history [txid]",
+		Short: "Show what became of transactions that left the mempool: confirmed, expired, dropped and so on",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			txid := ""
+			if len(args) == 1 {
+				txid = args[0]
+			}
+			h, err := newClient(cmd).MempoolHistory(cmd.Context(), txid, outcome, limit)
+			if err != nil {
+				return err
+			}
+			return printJSON(h)
+		},
+	}
+	cmd.Flags().StringVar(&outcome, "outcome", "", "only confirmed, expired, dropped, invalid, conflict or cleared transactions")
+	cmd.Flags().IntVar(&limit, "limit", 0, "show only the latest N (0 for all kept)")
+	return cmd
+}
+
+func newWaitCmd() *cobra.Command {
+	var confirmations int
+	var timeout time.Duration
+	cmd := &cobra.Command{
+		Use:   "wait <txid>",
+		Short: "Wait until a transaction is buried under enough blocks",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := newClient(cmd).WaitForTx(cmd.Context(), args[0], confirmations, timeout)
+			if err != nil {
+				return err
+			}
+			return printJSON(st)
+		},
+	}
+	cmd.Flags().IntVar(&confirmations, "confirmations", 1, "blocks deep the transaction must be")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "give up after this long (default the node's, 30s)")
+	return cmd
+}
+
+func newBlockCmd() *cobra.Command {
+	cmd := &cobra.Command{Use: "block", Short: "Inspect blocks"}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "get <index|hash>",
+		Short: "Print a single block, by height or by hash",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient(cmd)
+			var b blockchain.Block
+			var err error
+			if idx, convErr := strconv.Atoi(args[0]); convErr == nil {
+				b, err = c.GetBlock(cmd.Context(), idx)
+			} else {
+				b, err = c.BlockByHash(cmd.Context(), args[0])
+			}
+			if err != nil {
+				return err
+			}
+			return printJSON(b)
+		},
+	})
+	cmd.AddCommand(newBlockListCmd(), newBlockFollowCmd())
+	cmd.AddCommand(&cobra.Command{
+		Use:   "merkle-tree <index>",
+		Short: "Print every level of a block's merkle tree",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			idx, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid block index %q", args[0])
+			}
+			t, err := newClient(cmd).MerkleTree(cmd.Context(), idx)
+			if err != nil {
+				return err
+			}
+			return printJSON(t)
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "tx <index> <position>",
+		Short: "Print the transaction at a position within a block",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			idx, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid block index %q", args[0])
+			}
+			pos, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid position %q", args[1])
+			}
+			m, err := newClient(cmd).TxAt(cmd.Context(), idx, pos)
+			if err != nil {
+				return err
+			}
+			return printJSON(m)
+		},
+	})
+	return cmd
+}
+
+// newBlockListCmd pages through the chain
+func newBlockListCmd() *cobra.Command {
+	var q api.BlockQuery
+	var from, to int64
+	var order string
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List a page of blocks, optionally within a range of heights or times or by who mined them",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("from") {
+				q.From = &from
+			}
+			if cmd.Flags().Changed("to") {
+				q.To = &to
+			}
+			switch order {
+			case "asc":
+			case "desc":
+				q.Desc = true
+			default:
+				return fmt.Errorf("invalid order %q: want asc or desc", order)
+			}
+			p, err := newClient(cmd).BlocksPage(cmd.Context(), q)
+			if err != nil {
+				return err
+			}
+			return printJSON(p)
+		},
+	}
+	cmd.Flags().IntVar(&q.Page, "page", 1, "page to show, from 1")
+	cmd.Flags().IntVar(&q.Limit, "limit", 20, "blocks per page")
+	cmd.Flags().Int64Var(&from, "from", 0, "first height, or unix time with --by-time")
+	cmd.Flags().Int64Var(&to, "to", 0, "last height, or unix time with --by-time")
+	cmd.Flags().BoolVar(&q.ByTime, "by-time", false, "--from and --to are block timestamps")
+	cmd.Flags().StringVar(&order, "order", "asc", "asc for oldest first, desc for newest first")
+	cmd.Flags().StringVar(&q.Miner, "miner", "", "only blocks credited to this address or API key")
+	cmd.Flags().StringVar(&q.Producer, "producer", "", "only blocks mined by this node ID")
+	return cmd
+}
+
+// newBlockFollowCmd streams the chain from a height, then new blocks, reconnecting as needed
+func newBlockFollowCmd() *cobra.Command {
+	var from int
+	var resume string
+	cmd := &cobra.Command{
+		Use:   "follow",
+		Short: "Replay blocks from a height, then stream new ones and rollbacks, resuming after disconnects",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			msgs, err := newClient(cmd).FollowChain(cmd.Context(), from, resume)
+			if err != nil {
+				return err
+			}
+			for m := range msgs {
+				if err := printJSON(m); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&from, "from", 0, "first height to replay")
+	cmd.Flags().StringVar(&resume, "resume", "", "resume token of the last message handled, instead of --from")
+	return cmd
+}
+
+func newMineCmd() *cobra.Command {
+	var miner string
+	var difficulty int
+	var detach bool
+	cmd := &cobra.Command{
+		Use:   "mine",
+		Short: "Mine the pending transactions into a new block",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if detach {
+				job, err := newClient(cmd).StartMining(cmd.Context(), miner, difficulty)
+				if err != nil {
+					return err
+				}
+				return printJSON(job)
+			}
+			res, err := newClient(cmd).MineAt(cmd.Context(), miner, difficulty)
+			if err != nil {
+				return err
+			}
+			if res.Block == nil {
+				return printJSON(res)
+			}
+			cmd.PrintErrf("mined in %.2fs: %d hashes at %.0f hashes/s on %d workers\n",
+				res.Elapsed, res.Nonces, res.Hashrate, res.Workers)
+			return printJSON(res.Block)
+		},
+	}
+	cmd.Flags().StringVar(&miner, "miner", "", "wallet address credited with the block and its reward")
+	cmd.Flags().IntVar(&difficulty, "difficulty", 0, "mine this block at this difficulty instead of the chain's, within the range the node allows")
+	cmd.Flags().BoolVar(&detach, "detach", false, "print the mining job and return without waiting for the block")
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status <job-id>",
+		Short: "Show a mining job's progress",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			job, err := newClient(cmd).MineStatus(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return printJSON(job)
+		},
+	}, &cobra.Command{
+		Use:   "cancel <job-id>",
+		Short: "Abort a mining job, returning its transactions to the mempool",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			job, err := newClient(cmd).CancelMining(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return printJSON(job)
+		},
+	})
+	return cmd
+}
+
+func newFaucetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "faucet <address>",
+		Short: "Ask the node's faucet to fund a wallet address",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			res, err := newClient(cmd).Faucet(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return printJSON(res)
+		},
+	}
+}
+
+func newStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the node's version, consensus, enabled features, uptime, tip, peers and mempool depth",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := newClient(cmd).Status(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(st)
+		},
+	}
+}
+
+func newLeaderboardCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "leaderboard",
+		Short: "Show blocks mined, rewards and best hash per miner",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rows, err := newClient(cmd).Leaderboard(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(rows)
+		},
+	}
+}
+
+func newWalletCmd() *cobra.Command {
+	cmd := &cobra.Command{Use: "wallet", Short: "Manage keys"}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "new",
+		Short: "Generate a new keypair locally",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kp, err := wallet.New()
+			if err != nil {
+				return err
+			}
+			return printJSON(kp)
+		},
+	}, newUTXOsCmd(), newBalanceCmd(), newHistoryCmd())
+	return cmd
+}
+
+func newReorgsCmd() *cobra.Command {
+	var limit int
+	cmd := &cobra.Command{
+		Use:   "reorgs",
+		Short: "Show the chain reorganizations the node has gone through",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			h, err := newClient(cmd).Reorgs(cmd.Context(), limit)
+			if err != nil {
+				return err
+			}
+			return printJSON(h)
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 0, "show only the latest N (0 for all)")
+	return cmd
+}
+
+// newForksCmd shows the top of the chain and the blocks that branched off it
+func newForksCmd() *cobra.Command {
+	var depth int
+	cmd := &cobra.Command{
+		Use:   "forks",
+		Short: "Show the top of the chain with the stale and reorganized-away blocks beside it",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := newClient(cmd).ForkView(cmd.Context(), depth)
+			if err != nil {
+				return err
+			}
+			return printJSON(v)
+		},
+	}
+	cmd.Flags().IntVar(&depth, "depth", 0, "main-chain blocks to show (0 for the node's default)")
+	return cmd
+}
+
+// newTamperCmd edits a confirmed transaction on a node running with --demo
+func newTamperCmd() *cobra.Command {
+	var req api.TamperRequest
+	var data string
+	var amount int64
+	cmd := &cobra.Command{
+		Use:   "tamper <block> <tx>",
+		Short: "Edit a transaction in a confirmed block of a --demo node and show how validation catches it",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if req.Block, err = strconv.Atoi(args[0]); err != nil {
+				return fmt.Errorf("invalid block height %q", args[0])
+			}
+			if req.Tx, err = strconv.Atoi(args[1]); err != nil {
+				return fmt.Errorf("invalid transaction position %q", args[1])
+			}
+			if cmd.Flags().Changed("data") {
+				req.Data = &data
+			}
+			if cmd.Flags().Changed("amount") {
+				req.Amount = &amount
+			}
+			res, err := newClient(cmd).Tamper(cmd.Context(), req)
+			if err != nil {
+				return err
+			}
+			return printJSON(res)
+		},
+	}
+	cmd.Flags().StringVar(&data, "data", "", "replace the transaction's data")
+	cmd.Flags().Int64Var(&amount, "amount", 0, "replace the amount of its first output")
+	cmd.Flags().BoolVar(&req.Rehash, "rehash", false, "recompute the txid, merkle root and block hash as a forger would")
+	return cmd
+}
+
+// newAttestationsCmd lists the node's finality attestations
+func newAttestationsCmd() *cobra.Command {
+	var limit int
+	cmd := &cobra.Command{
+		Use:   "attestations",
+		Short: "Show the node's signed finality attestations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			atts, err := newClient(cmd).Attestations(cmd.Context(), limit)
+			if err != nil {
+				return err
+			}
+			return printJSON(atts)
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 0, "show only the latest N (0 for all)")
+	return cmd
+}
+
+// newVerifyAttestationCmd checks an attestation's signature offline
+func newVerifyAttestationCmd() *cobra.Command {
+	var signer string
+	cmd := &cobra.Command{
+		Use:   "verify-attestation <file|->",
+		Short: "Check an attestation's signature offline, without asking a node",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := readFileOrStdin(args[0])
+			if err != nil {
+				return err
+			}
+			var a blockchain.Attestation
+			if err := json.Unmarshal(data, &a); err != nil {
+				return fmt.Errorf("invalid attestation: %v", err)
+			}
+			if err := a.Verify(); err != nil {
+				return err
+			}
+			if signer != "" && a.Signer != signer {
+				return fmt.Errorf("signed by %s, not %s", a.Signer, signer)
+			}
+			return printJSON(map[string]interface{}{
+				"valid":  true,
+				"signer": a.Signer,
+				"height": a.Height,
+				"hash":   a.Hash,
+			})
+		},
+	}
+	cmd.Flags().StringVar(&signer, "signer", "", "also require this signer address")
+	return cmd
+}
+
+// newLabelCmd manages the node's address labels
+func newLabelCmd() *cobra.Command {
+	cmd := &cobra.Command{Use: "label", Short: "Name addresses for readability"}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the node's address labels",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			list, err := newClient(cmd).Labels(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(list)
+		},
+	}, &cobra.Command{
+		Use:   "set <address> <label>",
+		Short: "Label an address (needs the node token)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			l, err := newClient(cmd).SetLabel(cmd.Context(), args[0], args[1])
+			if err != nil {
+				return err
+			}
+			return printJSON(l)
+		},
+	}, &cobra.Command{
+		Use:   "rm <address>",
+		Short: "Remove an address's label (needs the node token)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := newClient(cmd).DeleteLabel(cmd.Context(), args[0]); err != nil {
+				return err
+			}
+			return printJSON(map[string]string{"status": "label removed", "address": args[0]})
+		},
+	})
+	return cmd
+}
+
+func newPeerCmd() *cobra.Command {
+	cmd := &cobra.Command{Use: "peer", Short: "Manage the node's peers"}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "add <url>",
+		Short: "Register a peer with the node",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			status, err := newClient(cmd).AddPeer(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return printJSON(map[string]string{"status": status})
+		},
+	}, &cobra.Command{
+		Use:   "list",
+		Short: "List the node's peers",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			peers, err := newClient(cmd).Peers(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(peers)
+		},
+	}, &cobra.Command{
+		Use:   "sync",
+		Short: "Adopt the longest valid chain among the node's peers",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			res, err := newClient(cmd).Sync(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(res)
+		},
+	})
+	return cmd
+}
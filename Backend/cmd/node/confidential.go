@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"salmanahmed/blockchain/pkg/client"
+)
+
+// newDecryptCmd reads a confirmed confidential transaction with the recipient's key
+func newDecryptCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "decrypt <txid> <private-key>",
+		Short: "Decrypt a confirmed confidential transaction's payload",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			matches, err := newClient(cmd).Search(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			for _, m := range matches {
+				if m.Transaction.ID != args[0] {
+					continue
+				}
+				data, err := client.Decrypt(m.Transaction, args[1])
+				if err != nil {
+					return err
+				}
+				return printJSON(map[string]interface{}{"txid": m.Transaction.ID, "block_index": m.BlockIndex, "data": data})
+			}
+			return fmt.Errorf("transaction %s not confirmed", args[0])
+		},
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/contract"
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+func newContractCmd() *cobra.Command {
+	cmd := &cobra.Command{Use: "contract", Short: "Deploy, call and inspect contracts"}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "deploy <file>",
+		Short: "Deploy the contract code in file (- for stdin)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var code []byte
+			var err error
+			if args[0] == "-" {
+				code, err = io.ReadAll(cmd.InOrStdin())
+			} else {
+				code, err = os.ReadFile(args[0])
+			}
+			if err != nil {
+				return err
+			}
+			if _, err := contract.Compile(string(code)); err != nil {
+				return err
+			}
+			tx := blockchain.Transaction{Contract: &blockchain.ContractOp{Code: string(code)}}
+			res, err := newClient(cmd).SubmitTransaction(cmd.Context(), tx)
+			if err != nil {
+				return err
+			}
+			return printJSON(map[string]string{
+				"status":  res.Status,
+				"txid":    res.TxID,
+				"address": blockchain.ContractAddress(res.TxID),
+			})
+		},
+	})
+
+	call := &cobra.Command{
+		Use:   "call <address> [args...]",
+		Short: "Call a deployed contract",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			gas, _ := cmd.Flags().GetInt64("gas")
+			key, _ := cmd.Flags().GetString("key")
+			tx := blockchain.Transaction{Contract: &blockchain.ContractOp{
+				Address:  args[0],
+				Args:     args[1:],
+				GasLimit: gas,
+			}}
+			if key != "" {
+				kp, err := wallet.FromPrivateKey(key)
+				if err != nil {
+					return err
+				}
+				tx.Contract.PubKey = kp.PublicKey
+				sig, err := wallet.Sign(kp.PrivateKey, tx.SigHash())
+				if err != nil {
+					return err
+				}
+				tx.Contract.Sig = sig
+				tx = tx.Seal() // already signed; --key here is the caller's
+			}
+			res, err := newClient(cmd).SubmitTransaction(cmd.Context(), tx)
+			if err != nil {
+				return err
+			}
+			return printJSON(res)
+		},
+	}
+	call.Flags().Int64("gas", 10000, "gas limit for the call")
+	call.Flags().String("key", "", "private key to sign the call with, exposed to the code as CALLER")
+
+	cmd.AddCommand(call, &cobra.Command{
+		Use:   "get [address]",
+		Short: "Show a contract's code and storage, or list contracts",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient(cmd)
+			if len(args) == 0 {
+				addrs, err := c.Contracts(cmd.Context())
+				if err != nil {
+					return err
+				}
+				return printJSON(addrs)
+			}
+			cs, err := c.Contract(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return printJSON(cs)
+		},
+	}, &cobra.Command{
+		Use:   "receipt <txid>",
+		Short: "Show the outcome of a confirmed contract transaction",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := newClient(cmd).Receipt(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return printJSON(r)
+		},
+	})
+	return cmd
+}
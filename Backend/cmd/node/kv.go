@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+func newKVCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "kv",
+		Short: "Read and write the chain's replicated key-value store",
+	}
+	// submit queues a key-value transaction; it takes effect once mined
+	submit := func(cmd *cobra.Command, op blockchain.KVOp) error {
+		tx := blockchain.Transaction{KV: []blockchain.KVOp{op}}
+		res, err := newClient(cmd).SubmitTransaction(cmd.Context(), tx)
+		if err != nil {
+			return err
+		}
+		return printJSON(res)
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Submit a transaction setting key to value",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return submit(cmd, blockchain.KVOp{Op: blockchain.KVSet, Key: args[0], Value: args[1]})
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "delete <key>",
+		Short: "Submit a transaction deleting key",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return submit(cmd, blockchain.KVOp{Op: blockchain.KVDelete, Key: args[0]})
+		},
+	})
+	get := &cobra.Command{
+		Use:   "get <key>",
+		Short: "Show key's confirmed value",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			height, _ := cmd.Flags().GetInt("at-height")
+			e, err := newClient(cmd).KV(cmd.Context(), args[0], height)
+			if err != nil {
+				return err
+			}
+			return printJSON(e)
+		},
+	}
+	get.Flags().Int("at-height", -1, "read the value as of this block (default the tip)")
+	cmd.AddCommand(get)
+	return cmd
+}
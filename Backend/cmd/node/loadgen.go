@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newLoadgenCmd() *cobra.Command {
+	var (
+		rate        int
+		duration    time.Duration
+		mineEvery   time.Duration
+		concurrency int
+	)
+	cmd := &cobra.Command{
+		Use:   "loadgen",
+		Short: "Submit synthetic transactions to a node and report latency and errors",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if rate <= 0 || duration <= 0 || concurrency <= 0 {
+				return fmt.Errorf("--rate, --duration and --concurrency must be positive")
+			}
+			// requests run until Ctrl-C; only new sends stop at --duration
+			reqCtx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			ctx, cancel := context.WithTimeout(reqCtx, duration)
+			defer cancel()
+
+			c := newClient(cmd)
+			txs, mines := &loadStats{}, &loadStats{}
+			fmt.Printf("loadgen: %d tx/s for %s against %s\n", rate, duration, nodeURL(cmd))
+			start := time.Now()
+			var wg sync.WaitGroup
+
+			if mineEvery > 0 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					t := time.NewTicker(mineEvery)
+					defer t.Stop()
+					for {
+						select {
+						case <-ctx.Done():
+							return
+						case <-t.C:
+							mines.time(func() error {
+								_, err := c.Mine(reqCtx)
+								return err
+							})
+						}
+					}
+				}()
+			}
+
+			// a fixed pool of workers drains a ticker-paced queue, so a slow
+			// node shows up as latency and dropped sends rather than unbounded goroutines
+			queue := make(chan int, concurrency)
+			for w := 0; w < concurrency; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for seq := range queue {
+						data := fmt.Sprintf("loadgen-%d-%d", start.UnixNano(), seq)
+						txs.time(func() error {
+							_, err := c.SubmitTx(reqCtx, data)
+							return err
+						})
+					}
+				}()
+			}
+			t := time.NewTicker(time.Second / time.Duration(rate))
+			dropped := 0
+		send:
+			for seq := 0; ; seq++ {
+				select {
+				case <-ctx.Done():
+					break send
+				case <-t.C:
+					select {
+					case queue <- seq:
+					default:
+						dropped++
+					}
+				}
+			}
+			t.Stop()
+			close(queue)
+			wg.Wait()
+
+			elapsed := time.Since(start)
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "OP\tCOUNT\tERRORS\tERR%\tP50\tP90\tP99\tMAX")
+			txs.report(tw, "transactions")
+			if mineEvery > 0 {
+				mines.report(tw, "mine")
+			}
+			tw.Flush()
+			fmt.Printf("elapsed %s, achieved %.1f tx/s, %d sends dropped (workers saturated)\n",
+				elapsed.Round(time.Millisecond), float64(txs.count())/elapsed.Seconds(), dropped)
+			for msg, n := range txs.errorCounts() {
+				fmt.Printf("  %5d× %s\n", n, msg)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&rate, "rate", 100, "transactions per second to submit")
+	cmd.Flags().DurationVar(&duration, "duration", 60*time.Second, "how long to run")
+	cmd.Flags().DurationVar(&mineEvery, "mine-every", 0, "also trigger mining at this interval (0 disables)")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 32, "maximum in-flight requests")
+	return cmd
+}
+
+// loadStats collects latencies and errors for one operation
+type loadStats struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+	errors    map[string]int
+}
+
+func (s *loadStats) time(op func() error) {
+	start := time.Now()
+	err := op()
+	d := time.Since(start)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latencies = append(s.latencies, d)
+	if err != nil {
+		if s.errors == nil {
+			s.errors = map[string]int{}
+		}
+		s.errors[err.Error()]++
+	}
+}
+
+func (s *loadStats) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.latencies)
+}
+
+func (s *loadStats) errorCounts() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.errors
+}
+
+func (s *loadStats) report(tw *tabwriter.Writer, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.latencies)
+	errs := 0
+	for _, c := range s.errors {
+		errs += c
+	}
+	if n == 0 {
+		fmt.Fprintf(tw, "%s\t0\t0\t-\t-\t-\t-\t-\n", name)
+		return
+	}
+	sorted := append([]time.Duration(nil), s.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	pct := func(p float64) time.Duration { return sorted[int(p*float64(n-1))].Round(time.Microsecond) }
+	fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\n", name, n, errs,
+		100*float64(errs)/float64(n), pct(.50), pct(.90), pct(.99), sorted[n-1].Round(time.Microsecond))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+func newLogsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Query confirmed event logs, or follow new ones",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var f blockchain.LogFilter
+			f.Address, _ = cmd.Flags().GetString("address")
+			f.Event, _ = cmd.Flags().GetString("event")
+			f.FromBlock, _ = cmd.Flags().GetInt("from-block")
+			f.ToBlock, _ = cmd.Flags().GetInt("to-block")
+			c := newClient(cmd)
+			if follow, _ := cmd.Flags().GetBool("follow"); follow {
+				logs, err := c.SubscribeLogs(cmd.Context(), f)
+				if err != nil {
+					return err
+				}
+				for l := range logs {
+					if err := printJSON(l); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+			logs, err := c.Logs(cmd.Context(), f)
+			if err != nil {
+				return err
+			}
+			return printJSON(logs)
+		},
+	}
+	cmd.Flags().String("address", "", "only logs emitted by or paying to this address")
+	cmd.Flags().String("event", "", "only logs with this event name")
+	cmd.Flags().Int("from-block", 0, "first block to include")
+	cmd.Flags().Int("to-block", 0, "last block to include (0 for the tip)")
+	cmd.Flags().Bool("follow", false, "stream new matching logs over a WebSocket instead")
+	return cmd
+}
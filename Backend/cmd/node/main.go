@@ -0,0 +1,57 @@
+// Command node runs a blockchain node and doubles as a client for driving a
+// running node from the terminal.
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// Name of the chain owner
+const Name = "Salman Ahmed"
+
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCmd() *cobra.Command {
+	root := newServeCmd()
+	root.Use = "node"
+	root.Short = "Blockchain node and command-line client"
+	root.SilenceUsage = true
+	root.PersistentFlags().String("node", "http://localhost:8080", "base URL of the node client commands talk to")
+	root.PersistentFlags().String("chain", "", "hosted chain ID client commands address (default chain if empty)")
+	root.PersistentFlags().String("token", os.Getenv("BLOCKCHAIN_AUTH_TOKEN"), "bearer token sent with client requests")
+	root.PersistentFlags().String("key", os.Getenv("BLOCKCHAIN_KEY"), "hex private key signing submitted transactions")
+
+	root.AddCommand(
+		newServeCmd(),
+		newStatusCmd(),
+		newChainCmd(),
+		newTxCmd(),
+		newBlockCmd(),
+		newQueryCmd(),
+		newSnapshotCmd(),
+		newMineCmd(),
+		newLeaderboardCmd(),
+		newFaucetCmd(),
+		newWalletCmd(),
+		newPeerCmd(),
+		newLabelCmd(),
+		newContractCmd(),
+		newKVCmd(),
+		newBlobCmd(),
+		newSwapCmd(),
+		newLogsCmd(),
+		newWatchCmd(),
+		newSimulateCmd(),
+		newChaosCmd(),
+		newChainsCmd(),
+		newTUICmd(),
+		newLoadgenCmd(),
+	)
+	return root
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"salmanahmed/blockchain/pkg/api"
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+// newBuildTxCmd asks the node for an unsigned transfer, so the key that
+// signs it can stay on an offline machine
+func newBuildTxCmd() *cobra.Command {
+	var fee int64
+	cmd := &cobra.Command{
+		Use:   "build <from> <to> <amount>",
+		Short: "Build an unsigned transfer for tx sign",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			amount, err := parseAmount(args[2])
+			if err != nil {
+				return err
+			}
+			utx, err := newClient(cmd).BuildTransaction(cmd.Context(), args[0], args[1], amount, fee)
+			if err != nil {
+				return err
+			}
+			return printJSON(utx)
+		},
+	}
+	cmd.Flags().Int64Var(&fee, "fee", 0, "leave this much unclaimed as a fee (see tx fee-estimate)")
+	return cmd
+}
+
+// newSignTxCmd signs the output of tx build without contacting the node
+func newSignTxCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "sign <file|-> <private-key>",
+		Short: "Sign an unsigned transfer offline, printing the transaction for tx submit-signed",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := readFileOrStdin(args[0])
+			if err != nil {
+				return err
+			}
+			var utx api.UnsignedTx
+			if err := json.Unmarshal(data, &utx); err != nil {
+				return fmt.Errorf("invalid unsigned transaction: %w", err)
+			}
+			kp, err := wallet.FromPrivateKey(args[1])
+			if err != nil {
+				return err
+			}
+			// check what is signed rather than trusting the node that built it
+			if got := hex.EncodeToString(utx.Tx.SigHash()); got != utx.SigHash {
+				return fmt.Errorf("sighash %s does not match the transaction (%s)", utx.SigHash, got)
+			}
+			if kp.Address != utx.From {
+				return fmt.Errorf("key is for %s, the transaction spends from %s", kp.Address, utx.From)
+			}
+			tx := utx.Tx
+			if err := signInputs(&tx, kp); err != nil {
+				return err
+			}
+			return printJSON(tx.Seal())
+		},
+	}
+}
+
+// newSubmitSignedCmd submits the output of tx sign
+func newSubmitSignedCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "submit-signed <file|->",
+		Short: "Submit a transaction signed with tx sign",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := readFileOrStdin(args[0])
+			if err != nil {
+				return err
+			}
+			var tx blockchain.Transaction
+			if err := json.Unmarshal(data, &tx); err != nil {
+				return fmt.Errorf("invalid transaction: %w", err)
+			}
+			res, err := newClient(cmd).SubmitSigned(cmd.Context(), tx)
+			if err != nil {
+				return err
+			}
+			return printJSON(res)
+		},
+	}
+}
+
+// newPreviewTxCmd shows what submitting a transaction would do
+func newPreviewTxCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "preview <file|->",
+		Short: "Validate a transaction and show its fee and balance changes without submitting it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := readFileOrStdin(args[0])
+			if err != nil {
+				return err
+			}
+			var tx blockchain.Transaction
+			if err := json.Unmarshal(data, &tx); err != nil {
+				return fmt.Errorf("invalid transaction: %w", err)
+			}
+			p, err := newClient(cmd).PreviewTransaction(cmd.Context(), tx)
+			if err != nil {
+				return err
+			}
+			return printJSON(p)
+		},
+	}
+}
+
+// readFileOrStdin reads the named file, or stdin for "-"
+func readFileOrStdin(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
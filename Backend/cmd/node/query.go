@@ -0,0 +1,50 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"salmanahmed/blockchain/pkg/api"
+)
+
+// newQueryCmd runs an analytics query on the node
+func newQueryCmd() *cobra.Command {
+	var q api.Query
+	var fromHeight, toHeight int
+	var fromTime, toTime int64
+	cmd := &cobra.Command{
+		Use:   "query <blocks|transactions>",
+		Short: "Filter, count and sum blocks or transactions on the node, optionally grouped",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			q.Select = args[0]
+			if cmd.Flags().Changed("from-height") {
+				q.Where.FromHeight = &fromHeight
+			}
+			if cmd.Flags().Changed("to-height") {
+				q.Where.ToHeight = &toHeight
+			}
+			if cmd.Flags().Changed("from-time") {
+				q.Where.FromTime = &fromTime
+			}
+			if cmd.Flags().Changed("to-time") {
+				q.Where.ToTime = &toTime
+			}
+			res, err := newClient(cmd).Query(cmd.Context(), q)
+			if err != nil {
+				return err
+			}
+			return printJSON(res)
+		},
+	}
+	cmd.Flags().IntVar(&fromHeight, "from-height", 0, "first block height")
+	cmd.Flags().IntVar(&toHeight, "to-height", 0, "last block height, inclusive")
+	cmd.Flags().Int64Var(&fromTime, "from-time", 0, "earliest block time, unix seconds")
+	cmd.Flags().Int64Var(&toTime, "to-time", 0, "latest block time, unix seconds, inclusive")
+	cmd.Flags().StringVar(&q.Where.Address, "address", "", "only transactions spending from or paying to this address, or blocks holding one")
+	cmd.Flags().StringVar(&q.Where.Miner, "miner", "", "only blocks credited to this address or API key")
+	cmd.Flags().StringVar(&q.Where.Producer, "producer", "", "only blocks mined by this node ID")
+	cmd.Flags().StringArrayVar(&q.Aggregate, "agg", nil, "count, or sum, min or max of a numeric field as sum:<field> (repeatable)")
+	cmd.Flags().StringVar(&q.GroupBy, "group-by", "", "a field, or hour or day of the block time")
+	cmd.Flags().IntVar(&q.Limit, "limit", 0, "rows or groups to return (0 for the node's default)")
+	return cmd
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// newCommitCmd submits only a salted hash of data, to be revealed later
+func newCommitCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "commit <data>",
+		Short: "Submit a commitment to data; keep the printed salt to reveal it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			res, salt, err := newClient(cmd).SubmitCommitment(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return printJSON(map[string]string{"status": res.Status, "txid": res.TxID, "salt": salt})
+		},
+	}
+}
+
+// newRevealCmd discloses the data behind a mined commitment, or shows its status
+func newRevealCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reveal <commit-txid> [<salt> <data>]",
+		Short: "Reveal a mined commitment's data, or show whether it has been revealed",
+		Args:  cobra.RangeArgs(1, 3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient(cmd)
+			if len(args) == 1 {
+				st, err := c.RevealStatus(cmd.Context(), args[0])
+				if err != nil {
+					return err
+				}
+				return printJSON(st)
+			}
+			if len(args) != 3 {
+				return fmt.Errorf("reveal takes the commit txid alone, or with its salt and data")
+			}
+			res, err := c.Reveal(cmd.Context(), args[0], args[1], args[2])
+			if err != nil {
+				return err
+			}
+			return printJSON(res)
+		},
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"salmanahmed/blockchain/pkg/config"
+	"salmanahmed/blockchain/pkg/node"
+)
+
+// newServeCmd runs the node; it is also what `node` does without a subcommand
+func newServeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run the node and its HTTP API",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			n, err := node.New(cfg)
+			if err != nil {
+				return err
+			}
+			// shut down cleanly on Ctrl-C or a service manager's stop
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			if err := n.Start(ctx); err != nil {
+				return err
+			}
+			go func() {
+				// a second signal while shutting down kills the process
+				<-ctx.Done()
+				stop()
+			}()
+			return n.Wait()
+		},
+	}
+	config.RegisterFlags(cmd.Flags())
+	return cmd
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"salmanahmed/blockchain/pkg/sim"
+)
+
+func newSimulateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Have the node fabricate blocks of random activity for demos",
+		Long: "Have the node fabricate blocks of random activity for demos.\n\n" +
+			"Blocks are mined at the node's own difficulty, so run it with a low\n" +
+			"--difficulty to generate a long chain quickly.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var opts sim.Options
+			opts.Blocks, _ = cmd.Flags().GetInt("blocks")
+			opts.TxsPerBlock, _ = cmd.Flags().GetInt("txs-per-block")
+			opts.Seed, _ = cmd.Flags().GetInt64("seed")
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			rep, err := newClient(cmd).Simulate(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+			return printJSON(rep)
+		},
+	}
+	cmd.Flags().Int("blocks", 10, "blocks to mine")
+	cmd.Flags().Int("txs-per-block", 5, "transactions submitted per block")
+	cmd.Flags().Int64("seed", 0, "random seed (0 for a fresh one)")
+	return cmd
+}
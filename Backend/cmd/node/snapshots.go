@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+func newSnapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Record the chain's state and compare two recorded states",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "take [name]",
+		Short: "Record the tip, balances and mempool",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := ""
+			if len(args) == 1 {
+				name = args[0]
+			}
+			snap, err := newClient(cmd).TakeSnapshot(cmd.Context(), name)
+			if err != nil {
+				return err
+			}
+			return printJSON(snap)
+		},
+	}, &cobra.Command{
+		Use:   "list",
+		Short: "List the recorded snapshots, oldest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snaps, err := newClient(cmd).Snapshots(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(snaps)
+		},
+	}, &cobra.Command{
+		Use:   "show <id>",
+		Short: "Print a snapshot with its balances and mempool",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := snapshotID(args[0])
+			if err != nil {
+				return err
+			}
+			snap, err := newClient(cmd).Snapshot(cmd.Context(), id)
+			if err != nil {
+				return err
+			}
+			return printJSON(snap)
+		},
+	}, &cobra.Command{
+		Use:   "diff <a> <b>",
+		Short: "Show the blocks mined, balances changed and mempool changes from snapshot a to b",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a, err := snapshotID(args[0])
+			if err != nil {
+				return err
+			}
+			b, err := snapshotID(args[1])
+			if err != nil {
+				return err
+			}
+			d, err := newClient(cmd).DiffSnapshots(cmd.Context(), a, b)
+			if err != nil {
+				return err
+			}
+			return printJSON(d)
+		},
+	})
+	return cmd
+}
+
+// snapshotID parses a snapshot id argument
+func snapshotID(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid snapshot id %q", s)
+	}
+	return id, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"salmanahmed/blockchain/pkg/client"
+	"salmanahmed/blockchain/pkg/swap"
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+// newSwapCmd runs hashed-timelock atomic swaps between two nodes. The side
+// that picks the secret opens its lock with the longer timeout; the other
+// side locks with the same hash and a shorter one, then relays the secret
+// back once the first side claims.
+func newSwapCmd() *cobra.Command {
+	cmd := &cobra.Command{Use: "swap", Short: "Atomically swap coins between two chains with hashed timelocks"}
+	open := &cobra.Command{
+		Use:   "open <private-key> <recipient-address> <amount>",
+		Short: "Lock coins to recipient until the secret behind --hash is revealed",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kp, err := wallet.FromPrivateKey(args[0])
+			if err != nil {
+				return err
+			}
+			amount, err := parseAmount(args[2])
+			if err != nil {
+				return err
+			}
+			hash, _ := cmd.Flags().GetString("hash")
+			timeout, _ := cmd.Flags().GetInt64("timeout")
+			if hash == "" || timeout <= 0 {
+				return fmt.Errorf("--hash and a positive --timeout are required")
+			}
+			l, err := swap.Open(cmd.Context(), newClient(cmd), kp, hash, args[1], amount, timeout)
+			if err != nil {
+				return err
+			}
+			return printJSON(l)
+		},
+	}
+	open.Flags().String("hash", "", "hex SHA-256 of the swap secret (see swap secret)")
+	open.Flags().Int64("timeout", 0, "block height from which the coins can be refunded")
+
+	relay := &cobra.Command{
+		Use:   "relay <private-key> <hash>",
+		Short: "Wait for the secret to be revealed on --from, then claim the lock paying the key on --node",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kp, err := wallet.FromPrivateKey(args[0])
+			if err != nil {
+				return err
+			}
+			fromURL, _ := cmd.Flags().GetString("from")
+			if fromURL == "" {
+				return fmt.Errorf("--from is required")
+			}
+			token, _ := cmd.Flags().GetString("token")
+			interval, _ := cmd.Flags().GetDuration("interval")
+			from := client.New(fromURL, client.WithToken(token))
+			cmd.PrintErrf("watching %s for the secret behind %s\n", fromURL, args[1])
+			txid, err := swap.Relay(cmd.Context(), from, newClient(cmd), kp, args[1], interval, func(format string, a ...interface{}) {
+				cmd.PrintErrf(format+"\n", a...)
+			})
+			if err != nil {
+				return err
+			}
+			return printJSON(map[string]string{"status": "claimed", "txid": txid})
+		},
+	}
+	relay.Flags().String("from", "", "base URL of the chain where the other side claims")
+	relay.Flags().Duration("interval", 2*time.Second, "how often to poll --from")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "secret",
+		Short: "Generate a swap secret and its hash",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			preimage, hash, err := swap.NewSecret()
+			if err != nil {
+				return err
+			}
+			return printJSON(map[string]string{"secret": hex.EncodeToString(preimage), "hash": hash})
+		},
+	}, open, &cobra.Command{
+		Use:   "find <hash>",
+		Short: "Show the unspent lock behind a hash",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			l, err := swap.Find(cmd.Context(), newClient(cmd), args[0], "")
+			if err != nil {
+				return err
+			}
+			return printJSON(l)
+		},
+	}, &cobra.Command{
+		Use:   "claim <private-key> <secret>",
+		Short: "Claim the lock paying the key by revealing the secret",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kp, err := wallet.FromPrivateKey(args[0])
+			if err != nil {
+				return err
+			}
+			preimage, err := hex.DecodeString(args[1])
+			if err != nil {
+				return fmt.Errorf("secret must be hex: %v", err)
+			}
+			c := newClient(cmd)
+			l, err := swap.Find(cmd.Context(), c, swap.Hash(preimage), kp.Address)
+			if err != nil {
+				return err
+			}
+			txid, err := swap.Claim(cmd.Context(), c, kp, l, preimage)
+			if err != nil {
+				return err
+			}
+			return printJSON(map[string]string{"status": "claimed", "txid": txid})
+		},
+	}, &cobra.Command{
+		Use:   "refund <private-key> <hash>",
+		Short: "Take back a lock the key opened once it has timed out",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kp, err := wallet.FromPrivateKey(args[0])
+			if err != nil {
+				return err
+			}
+			c := newClient(cmd)
+			l, err := swap.Find(cmd.Context(), c, args[1], "")
+			if err != nil {
+				return err
+			}
+			if l.Refund != kp.Address {
+				return fmt.Errorf("the lock behind %s refunds %s, not %s", args[1], l.Refund, kp.Address)
+			}
+			txid, err := swap.Refund(cmd.Context(), c, kp, l)
+			if err != nil {
+				return err
+			}
+			return printJSON(map[string]string{"status": "refunded", "txid": txid})
+		},
+	}, relay)
+	return cmd
+}
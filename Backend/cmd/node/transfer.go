@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/script"
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+// newIssueCmd creates coins out of nothing; besides block rewards this is
+// how value first enters a chain, one that allows unsigned transactions
+func newIssueCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "issue <address> <amount>",
+		Short: "Submit an input-less transaction paying amount to address",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			amount, err := parseAmount(args[1])
+			if err != nil {
+				return err
+			}
+			tx := blockchain.Transaction{Outputs: []blockchain.TxOutput{
+				{Amount: amount, Lock: script.P2PKH(args[0])},
+			}}
+			res, err := newClient(cmd).SubmitTransaction(cmd.Context(), tx)
+			if err != nil {
+				return err
+			}
+			return printJSON(res)
+		},
+	}
+}
+
+// newPayCmd spends the key's P2PKH outputs, paying change back to itself
+func newPayCmd() *cobra.Command {
+	var fee int64
+	cmd := &cobra.Command{
+		Use:   "pay <private-key> <address> <amount>",
+		Short: "Pay amount to address from the key's unspent outputs",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kp, err := wallet.FromPrivateKey(args[0])
+			if err != nil {
+				return err
+			}
+			amount, err := parseAmount(args[2])
+			if err != nil {
+				return err
+			}
+			c := newClient(cmd)
+			utxos, err := c.UTXOs(cmd.Context(), kp.Address)
+			if err != nil {
+				return err
+			}
+			if fee < 0 {
+				return fmt.Errorf("invalid fee %d", fee)
+			}
+			var tx blockchain.Transaction
+			var total int64
+			for _, u := range utxos {
+				if total >= amount+fee {
+					break
+				}
+				tx.Inputs = append(tx.Inputs, blockchain.TxInput{TxID: u.TxID, Index: u.Index})
+				total += u.Amount
+			}
+			if total < amount+fee {
+				return fmt.Errorf("insufficient funds: %s holds %d", kp.Address, total)
+			}
+			tx.Outputs = append(tx.Outputs, blockchain.TxOutput{Amount: amount, Lock: script.P2PKH(args[1])})
+			if change := total - amount - fee; change > 0 {
+				tx.Outputs = append(tx.Outputs, blockchain.TxOutput{Amount: change, Lock: script.P2PKH(kp.Address)})
+			}
+			tx.Recipient, tx.Amount = args[1], amount
+			tx, err = tx.Sign(kp.PrivateKey, time.Now().Unix())
+			if err != nil {
+				return err
+			}
+			res, err := c.SubmitTransaction(cmd.Context(), tx)
+			if err != nil {
+				return err
+			}
+			return printJSON(res)
+		},
+	}
+	cmd.Flags().Int64Var(&fee, "fee", 0, "leave this much unclaimed as a fee (see tx fee-estimate)")
+	return cmd
+}
+
+// signInputs unlocks every input of tx with kp's P2PKH signature
+func signInputs(tx *blockchain.Transaction, kp wallet.Keypair) error {
+	sig, err := wallet.Sign(kp.PrivateKey, tx.SigHash())
+	if err != nil {
+		return err
+	}
+	for i := range tx.Inputs {
+		tx.Inputs[i].Unlock = script.P2PKHUnlock(sig, kp.PublicKey)
+	}
+	return nil
+}
+
+// newFeeEstimateCmd suggests a fee for tx pay --fee
+func newFeeEstimateCmd() *cobra.Command {
+	var target int
+	cmd := &cobra.Command{
+		Use:   "fee-estimate",
+		Short: "Suggest a fee likely to confirm within --target-blocks blocks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			est, err := newClient(cmd).EstimateFee(cmd.Context(), target)
+			if err != nil {
+				return err
+			}
+			return printJSON(est)
+		},
+	}
+	cmd.Flags().IntVar(&target, "target-blocks", 1, "blocks the transaction should confirm within")
+	return cmd
+}
+
+// newUTXOsCmd lists the unspent outputs paying to an address
+func newUTXOsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "utxos [address]",
+		Short: "List unspent outputs, optionally only those paying to address",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr := ""
+			if len(args) == 1 {
+				addr = args[0]
+			}
+			height, _ := cmd.Flags().GetInt("at-height")
+			utxos, err := newClient(cmd).UTXOsAt(cmd.Context(), addr, height)
+			if err != nil {
+				return err
+			}
+			return printJSON(utxos)
+		},
+	}
+	cmd.Flags().Int("at-height", -1, "list the outputs unspent as of this block (default the tip)")
+	return cmd
+}
+
+// newBalanceCmd shows what an address holds
+func newBalanceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "balance <address>",
+		Short: "Show the total of the unspent outputs paying to address",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			height, _ := cmd.Flags().GetInt("at-height")
+			bal, err := newClient(cmd).Balance(cmd.Context(), args[0], height)
+			if err != nil {
+				return err
+			}
+			return printJSON(bal)
+		},
+	}
+	cmd.Flags().Int("at-height", -1, "show the balance as of this block (default the tip)")
+	return cmd
+}
+
+// newHistoryCmd lists an address's transactions with running balances
+func newHistoryCmd() *cobra.Command {
+	var limit int
+	cmd := &cobra.Command{
+		Use:   "history <address>",
+		Short: "Show the transactions paying to or spending from address",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			h, err := newClient(cmd).History(cmd.Context(), args[0], limit)
+			if err != nil {
+				return err
+			}
+			return printJSON(h)
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 0, "show only the latest N (0 for all)")
+	return cmd
+}
+
+func parseAmount(s string) (int64, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return n, nil
+}
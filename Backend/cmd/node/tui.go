@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/client"
+	"salmanahmed/blockchain/pkg/events"
+)
+
+func newTUICmd() *cobra.Command {
+	var refresh time.Duration
+	cmd := &cobra.Command{
+		Use:   "tui",
+		Short: "Live terminal explorer for a running node",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			return runTUI(ctx, newClient(cmd), refresh)
+		},
+	}
+	cmd.Flags().DurationVar(&refresh, "refresh", 5*time.Second, "poll interval when no events arrive")
+	return cmd
+}
+
+// tuiState is everything one screen shows
+type tuiState struct {
+	blocks  []blockchain.Block
+	pending []blockchain.Transaction
+	peers   []string
+	ready   string
+	mining  string
+	log     []string
+	err     error
+	live    bool // event stream connected
+}
+
+func runTUI(ctx context.Context, c *client.Client, refresh time.Duration) error {
+	evs := make(chan events.Event, 16)
+	conn := make(chan bool, 1)
+	go followEvents(ctx, c, evs, conn)
+
+	st := &tuiState{mining: "idle"}
+	fmt.Print("\x1b[?25l") // hide cursor
+	defer fmt.Print("\x1b[?25h\n")
+
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+	for {
+		st.refresh(ctx, c)
+		st.render(c.BaseURL())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case st.live = <-conn:
+		case e := <-evs:
+			st.apply(e)
+		}
+	}
+}
+
+// followEvents subscribes to the node's events, reconnecting until ctx ends
+func followEvents(ctx context.Context, c *client.Client, out chan<- events.Event, conn chan<- bool) {
+	for ctx.Err() == nil {
+		if sub, err := c.Subscribe(ctx); err == nil {
+			conn <- true
+			for e := range sub {
+				out <- e
+			}
+		}
+		select {
+		case conn <- false:
+		default:
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// refresh re-reads the panels from the node
+func (st *tuiState) refresh(ctx context.Context, c *client.Client) {
+	st.blocks, st.err = c.Blocks(ctx)
+	if st.err != nil {
+		return
+	}
+	st.pending, _ = c.Pending(ctx)
+	st.peers, _ = c.Peers(ctx)
+	st.ready = "ready"
+	if err := c.Ready(ctx); err != nil {
+		st.ready = err.Error()
+	}
+}
+
+// apply updates mining status and the event log from an event
+func (st *tuiState) apply(e events.Event) {
+	at := time.Unix(e.Time, 0).Format("15:04:05")
+	raw, _ := json.Marshal(e.Data)
+	switch e.Type {
+	case events.MiningStarted:
+		st.mining = "mining since " + at
+	case events.BlockMined:
+		st.mining = "idle (last block at " + at + ")"
+	}
+	st.log = append(st.log, fmt.Sprintf("%s %-15s %s", at, e.Type, truncate(string(raw), 60)))
+	if len(st.log) > 8 {
+		st.log = st.log[len(st.log)-8:]
+	}
+}
+
+func (st *tuiState) render(node string) {
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	stream := "polling"
+	if st.live {
+		stream = "live"
+	}
+	fmt.Fprintf(&b, "%s  node %s  [%s]  %s\n", Name, node, stream, time.Now().Format("15:04:05"))
+	if st.err != nil {
+		fmt.Fprintf(&b, "\n  node unreachable: %v\n", st.err)
+		fmt.Print(b.String())
+		return
+	}
+	height := 0
+	if len(st.blocks) > 0 {
+		height = st.blocks[len(st.blocks)-1].Index
+	}
+	fmt.Fprintf(&b, "height %d   status %s   mining %s\n", height, st.ready, st.mining)
+
+	panel(&b, "Latest blocks")
+	for i := len(st.blocks) - 1; i >= 0 && i >= len(st.blocks)-8; i-- {
+		blk := st.blocks[i]
+		fmt.Fprintf(&b, "  #%-5d %s  txs=%-3d nonce=%-8d %s\n", blk.Index, truncate(blk.Hash, 20),
+			len(blk.Txns), blk.Nonce, time.Unix(blk.Timestamp, 0).Format("15:04:05"))
+	}
+
+	panel(&b, fmt.Sprintf("Mempool (%d)", len(st.pending)))
+	for i, tx := range st.pending {
+		if i == 6 {
+			fmt.Fprintf(&b, "  ... %d more\n", len(st.pending)-i)
+			break
+		}
+		fmt.Fprintf(&b, "  %s\n", truncate(tx.String(), 70))
+	}
+
+	panel(&b, fmt.Sprintf("Peers (%d)", len(st.peers)))
+	for _, p := range st.peers {
+		fmt.Fprintf(&b, "  %s\n", p)
+	}
+
+	panel(&b, "Events")
+	for _, l := range st.log {
+		fmt.Fprintf(&b, "  %s\n", l)
+	}
+	b.WriteString("\nCtrl-C to quit\n")
+	fmt.Print(b.String())
+}
+
+func panel(b *strings.Builder, title string) {
+	fmt.Fprintf(b, "\n\x1b[1m── %s %s\x1b[0m\n", title, strings.Repeat("─", 60-len([]rune(title))))
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
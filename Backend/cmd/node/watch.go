@@ -0,0 +1,64 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"salmanahmed/blockchain/pkg/api"
+)
+
+func newWatchCmd() *cobra.Command {
+	cmd := &cobra.Command{Use: "watch", Short: "Get notified about an address or transaction"}
+	var w api.Watch
+	add := &cobra.Command{
+		Use:   "add",
+		Short: "Watch transactions paying to --address, or --txid",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out, err := newClient(cmd).AddWatch(cmd.Context(), w)
+			if err != nil {
+				return err
+			}
+			return printJSON(out)
+		},
+	}
+	add.Flags().StringVar(&w.Address, "address", "", "notify about transactions paying to this address")
+	add.Flags().StringVar(&w.TxID, "txid", "", "notify about this transaction")
+	add.Flags().IntVar(&w.Confirmations, "confirmations", 1, "blocks deep a transaction must be before it counts as confirmed")
+	add.Flags().StringVar(&w.Webhook, "webhook", "", "URL to POST each notification to")
+
+	cmd.AddCommand(add, &cobra.Command{
+		Use:   "list",
+		Short: "List the registered watches",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out, err := newClient(cmd).Watches(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printJSON(out)
+		},
+	}, &cobra.Command{
+		Use:   "remove <id>",
+		Short: "Unregister a watch",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return newClient(cmd).RemoveWatch(cmd.Context(), args[0])
+		},
+	}, &cobra.Command{
+		Use:   "follow [id...]",
+		Short: "Stream notifications for the given watches, or all of them, over a WebSocket",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ns, err := newClient(cmd).SubscribeWatches(cmd.Context(), args...)
+			if err != nil {
+				return err
+			}
+			for n := range ns {
+				if err := printJSON(n); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+	return cmd
+}
@@ -0,0 +1,25 @@
+// Command validator-plugin is an example transaction validator loaded by the
+// node at runtime. Build it with
+//
+//	go build -buildmode=plugin -o studentid.so ./examples/validator-plugin
+//
+// and start the node with --validator-plugins studentid.so.
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Name is how the node reports rejections from this plugin
+var Name = "lab-prefix"
+
+// Validate requires every payload to be tagged with a lab section
+func Validate(tx string) error {
+	if !strings.HasPrefix(tx, "lab") {
+		return fmt.Errorf("payload must start with a lab section, e.g. lab3:")
+	}
+	return nil
+}
+
+func main() {}
@@ -0,0 +1,60 @@
+//go:build wasip1
+
+// Command validator-wasm is an example transaction validator the node runs
+// as a WebAssembly module. Build it with
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o nospam.wasm ./examples/validator-wasm
+//
+// (Go 1.24 or later) and start the node with --validator-wasm nospam.wasm.
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"unsafe"
+)
+
+// banned words no payload may contain
+var banned = []string{"spam", "scam"}
+
+// buffers keeps the memory handed to the node alive
+var buffers [][]byte
+
+// alloc returns a buffer of size bytes for the node to write into
+//
+//go:wasmexport alloc
+func alloc(size uint32) uint32 {
+	buf := make([]byte, size)
+	buffers = append(buffers, buf)
+	return uint32(uintptr(unsafe.Pointer(unsafe.SliceData(buf))))
+}
+
+// validate accepts the transaction in memory at ptr unless its payload
+// holds a banned word
+//
+//go:wasmexport validate
+func validate(ptr, size uint32) uint64 {
+	var tx struct {
+		ID   string `json:"id"`
+		Data string `json:"data"`
+	}
+	in := unsafe.Slice((*byte)(unsafe.Pointer(uintptr(ptr))), size)
+	if err := json.Unmarshal(in, &tx); err != nil {
+		return reject("unreadable transaction: " + err.Error())
+	}
+	for _, w := range banned {
+		if strings.Contains(strings.ToLower(tx.Data), w) {
+			return reject("payload mentions " + w)
+		}
+	}
+	return 0
+}
+
+// reject packs a reason's address and length into validate's result
+func reject(reason string) uint64 {
+	buf := []byte(reason)
+	buffers = append(buffers, buf)
+	return uint64(uintptr(unsafe.Pointer(unsafe.SliceData(buf))))<<32 | uint64(len(buf))
+}
+
+func main() {}
@@ -1,3 +1,17 @@
 module salmanahmed/blockchain
 
-go 1.20
\ No newline at end of file
+go 1.20
+
+require (
+	github.com/BurntSushi/toml v1.3.2
+	github.com/gorilla/websocket v1.5.1
+	github.com/spf13/cobra v1.8.0
+	github.com/spf13/pflag v1.0.5
+	github.com/tetratelabs/wazero v1.7.3
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	golang.org/x/net v0.17.0 // indirect
+)
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+// AddressHistory is the response of /address/{address}/history
+type AddressHistory struct {
+	Address string                 `json:"address"`
+	Label   string                 `json:"label,omitempty"`
+	Balance int64                  `json:"balance"` // at the tip
+	Count   int                    `json:"count"`   // every transaction, including ones limit left out
+	Txs     []blockchain.AddressTx `json:"txs"`     // oldest first
+}
+
+// History returns the transactions moving coins to or from address, only
+// the latest limit when limit is positive
+func (s *Server) History(address string, limit int) AddressHistory {
+	txs := s.chain.AddressHistory(address)
+	out := AddressHistory{Address: address, Label: s.opts.Labels.Get(address), Count: len(txs)}
+	if n := len(txs); n > 0 {
+		out.Balance = txs[n-1].Balance
+	}
+	if limit > 0 && len(txs) > limit {
+		txs = txs[len(txs)-limit:]
+	}
+	out.Txs = txs
+	return out
+}
+
+// an address's transactions with running balances:
+// GET /address/{address}/history?limit=N (the latest N, default all)
+func (s *Server) addressHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	addr, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/address/"), "/")
+	if rest != "history" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if addr == "" {
+		writeError(w, http.StatusBadRequest, "address required")
+		return
+	}
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+	json.NewEncoder(w).Encode(s.History(addr, limit))
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/chaos"
+	"salmanahmed/blockchain/pkg/clock"
+	"salmanahmed/blockchain/pkg/sim"
+)
+
+// simNode lets the simulator drive the server like any other client would
+type simNode struct{ *Server }
+
+func (n simNode) UTXOs(address string) []blockchain.UTXO {
+	return n.chain.UTXOs(address)
+}
+
+func (n simNode) Now() time.Time {
+	return clock.Or(n.opts.Clock).Now()
+}
+
+// fabricate random activity: POST {"blocks": N, "txs_per_block": M, "seed": S}
+func (s *Server) simulateHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var opts sim.Options
+	if err := decodeJSON(w, r, &opts); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+	if err := opts.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	logf(r.Context(), "simulating %d blocks of %d transactions", opts.Blocks, opts.TxsPerBlock)
+	rep, err := sim.Run(r.Context(), simNode{s}, opts)
+	if err != nil {
+		writeChainError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(rep)
+}
+
+// view (GET) or replace (PUT) the injected faults; 404 unless the node runs with --chaos
+func (s *Server) chaosHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if s.opts.Chaos == nil {
+		writeError(w, http.StatusNotFound, "fault injection disabled; start the node with --chaos")
+		return
+	}
+	switch r.Method {
+	case "GET":
+	case "PUT":
+		var f chaos.Faults
+		if err := decodeJSON(w, r, &f); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid body")
+			return
+		}
+		if err := s.opts.Chaos.Set(f); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		logf(r.Context(), "fault injection set to %+v", f)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	json.NewEncoder(w).Encode(s.opts.Chaos.Faults())
+}
+
+// DifficultyChange is the response of /admin/difficulty
+type DifficultyChange struct {
+	Difficulty int `json:"difficulty"`
+	Previous   int `json:"previous"`
+}
+
+// view (GET) or change (PUT {"difficulty": N}) the difficulty new blocks are mined to
+func (s *Server) difficultyHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	d := s.chain.Difficulty()
+	out := DifficultyChange{Difficulty: d, Previous: d}
+	switch r.Method {
+	case "GET":
+	case "PUT":
+		var body struct {
+			Difficulty *int `json:"difficulty"`
+		}
+		if err := decodeJSON(w, r, &body); err != nil || body.Difficulty == nil {
+			writeError(w, http.StatusBadRequest, "invalid body")
+			return
+		}
+		prev, err := s.chain.SetDifficulty(*body.Difficulty)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		out = DifficultyChange{Difficulty: *body.Difficulty, Previous: prev}
+		s.persistDifficulty(r.Context())
+		logf(r.Context(), "difficulty changed from %d to %d", prev, out.Difficulty)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/clock"
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+// ErrFinalized is returned for a chain that rewrites a block the node has attested as final
+var ErrFinalized = errors.New("chain rewrites a finalized block")
+
+// AttestOptions has the node sign finality attestations as its chain grows
+type AttestOptions struct {
+	Key   string // hex private key signing attestations; empty disables them
+	Every int    // blocks between attestations
+	Depth int    // confirmations a block needs before it is attested
+}
+
+// AttestationStatus is an attestation as /attestations reports it
+type AttestationStatus struct {
+	blockchain.Attestation
+	Valid bool `json:"valid"` // the signature verifies and the chain still holds Hash at Height
+}
+
+// Attestations is the response of /attestations
+type Attestations struct {
+	Signer       string              `json:"signer,omitempty"` // address signing new attestations; empty when disabled
+	Every        int                 `json:"every,omitempty"`
+	Depth        int                 `json:"depth,omitempty"`
+	Finalized    int                 `json:"finalized"` // highest attested height, 0 when none
+	Attestations []AttestationStatus `json:"attestations"`
+}
+
+// attest signs the deepest block due an attestation, if any: one Depth
+// blocks below the tip and at least Every blocks above the last one
+func (s *Server) attest(ctx context.Context) {
+	opts := s.opts.Attest
+	if opts.Key == "" {
+		return
+	}
+	height := s.chain.Len() - 1 - opts.Depth
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	last := 0
+	if n := len(s.attestations); n > 0 {
+		last = s.attestations[n-1].Height
+	}
+	if height <= 0 || height < last+opts.Every {
+		return
+	}
+	genesis, _ := s.chain.BlockAt(0)
+	b, ok := s.chain.BlockAt(height)
+	if !ok {
+		return
+	}
+	a, err := blockchain.NewAttestation(opts.Key, genesis.Hash, b.Index, b.Hash, clock.Or(s.opts.Clock).Now().Unix())
+	if err != nil {
+		logf(ctx, "attesting block %d failed: %v", b.Index, err)
+		return
+	}
+	s.attestations = append(s.attestations, a)
+	logf(ctx, "attested block %d (%s) as final", b.Index, b.Hash)
+	s.persistAttestations(ctx)
+}
+
+// persistAttestations snapshots the attestations to the store; a failure
+// is only logged, the next attestation writes them all again (caller holds mu)
+func (s *Server) persistAttestations(ctx context.Context) {
+	if s.opts.Store == nil {
+		return
+	}
+	if err := s.opts.Store.SaveAttestations(s.attestations); err != nil {
+		logf(ctx, "storing attestations failed: %v", err)
+	}
+}
+
+// loadAttestations restores stored attestations, refusing ones the chain
+// contradicts
+func (s *Server) loadAttestations(atts []blockchain.Attestation) error {
+	for _, a := range atts {
+		if err := s.checkAttestation(a, s.chain.BlockAt); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	s.attestations = atts
+	s.mu.Unlock()
+	return nil
+}
+
+// checkAttestation reports whether the chain whose blocks blockAt returns
+// still holds the attested block; a chain not yet that long agrees
+func (s *Server) checkAttestation(a blockchain.Attestation, blockAt func(int) (blockchain.Block, bool)) error {
+	if genesis, _ := blockAt(0); genesis.Hash != a.Genesis {
+		return fmt.Errorf("%w: genesis is %s, attested as %s", ErrFinalized, genesis.Hash, a.Genesis)
+	}
+	if b, ok := blockAt(a.Height); ok && b.Hash != a.Hash {
+		return fmt.Errorf("%w: block %d is %s, attested as %s", ErrFinalized, a.Height, b.Hash, a.Hash)
+	}
+	return nil
+}
+
+// checkFinality rejects blocks that rewrite the latest attested block
+func (s *Server) checkFinality(blocks []blockchain.Block) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.attestations) == 0 {
+		return nil
+	}
+	return s.checkAttestation(s.attestations[len(s.attestations)-1], func(i int) (blockchain.Block, bool) {
+		if i < 0 || i >= len(blocks) {
+			return blockchain.Block{}, false
+		}
+		return blocks[i], true
+	})
+}
+
+// dropAttestations forgets every attestation, after the chain was
+// deliberately rewritten (caller holds mu)
+func (s *Server) dropAttestations(ctx context.Context) int {
+	n := len(s.attestations)
+	s.attestations = nil
+	if n > 0 {
+		s.persistAttestations(ctx)
+	}
+	return n
+}
+
+// Attestations returns the node's attestations, only the latest limit when
+// limit is positive, each checked against the chain
+func (s *Server) Attestations(limit int) Attestations {
+	s.mu.Lock()
+	atts := append([]blockchain.Attestation(nil), s.attestations...)
+	s.mu.Unlock()
+	out := Attestations{Attestations: []AttestationStatus{}}
+	if opts := s.opts.Attest; opts.Key != "" {
+		out.Every, out.Depth = opts.Every, opts.Depth
+		if kp, err := wallet.FromPrivateKey(opts.Key); err == nil {
+			out.Signer = kp.Address
+		}
+	}
+	if n := len(atts); n > 0 {
+		out.Finalized = atts[n-1].Height
+	}
+	if limit > 0 && len(atts) > limit {
+		atts = atts[len(atts)-limit:]
+	}
+	for _, a := range atts {
+		valid := a.Verify() == nil && s.checkAttestation(a, s.chain.BlockAt) == nil
+		out.Attestations = append(out.Attestations, AttestationStatus{a, valid})
+	}
+	return out
+}
+
+// list finality attestations: GET /attestations?limit=N (the latest N, default all)
+func (s *Server) attestationsHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+	json.NewEncoder(w).Encode(s.Attestations(limit))
+}
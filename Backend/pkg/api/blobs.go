@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"salmanahmed/blockchain/pkg/blobstore"
+)
+
+// ErrBlobMissing is returned for transactions referencing a blob this node doesn't hold
+var ErrBlobMissing = errors.New("blob not stored on this node")
+
+// checkBlob makes sure a referenced blob can be served back
+func (s *Server) checkBlob(ctx context.Context, hash string) error {
+	if s.opts.Blobs == nil {
+		return fmt.Errorf("%w: blob store disabled", ErrBlobMissing)
+	}
+	ok, err := s.opts.Blobs.Has(ctx, hash)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: upload %s to /blobs first", ErrBlobMissing, hash)
+	}
+	return nil
+}
+
+// store a raw request body off-chain: POST /blobs returns {"hash","size"}
+func (s *Server) putBlobHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.opts.Blobs == nil {
+		writeError(w, http.StatusNotFound, "blob store disabled")
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, blobstore.MaxSize))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("blobs are limited to %d bytes", blobstore.MaxSize))
+		return
+	}
+	hash, err := s.opts.Blobs.Put(r.Context(), data)
+	if err != nil {
+		logf(r.Context(), "storing blob failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "storing blob failed")
+		return
+	}
+	logf(r.Context(), "stored blob %s (%d bytes)", hash, len(data))
+	json.NewEncoder(w).Encode(map[string]interface{}{"hash": hash, "size": len(data)})
+}
+
+// fetch a blob by hash, re-verified against it: GET /blobs/{hash}
+func (s *Server) getBlobHandler(w http.ResponseWriter, r *http.Request) {
+	if s.opts.Blobs == nil {
+		jsonHeaders(w)
+		writeError(w, http.StatusNotFound, "blob store disabled")
+		return
+	}
+	hash := strings.TrimPrefix(r.URL.Path, "/blobs/")
+	data, err := s.opts.Blobs.Get(r.Context(), hash)
+	switch {
+	case errors.Is(err, blobstore.ErrNotFound):
+		jsonHeaders(w)
+		writeError(w, http.StatusNotFound, "blob not found")
+		return
+	case err != nil:
+		logf(r.Context(), "reading blob %s failed: %v", hash, err)
+		jsonHeaders(w)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("ETag", `"`+hash+`"`)
+	w.Write(data)
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+// Page sizes of /blocks once any paging or filtering parameter is given
+const (
+	defaultBlockPage = 20
+	maxBlockPage     = 1000
+)
+
+// BlockQuery selects a page of blocks
+type BlockQuery struct {
+	Page   int    // 1-based
+	Limit  int    // blocks per page
+	From   *int64 // first index, or unix time when ByTime; nil for the start
+	To     *int64 // last index or unix time, inclusive; nil for the tip
+	ByTime bool   // From and To are block timestamps rather than indexes
+	Desc   bool   // newest first
+
+	Miner    string // only blocks credited to this address or API key
+	Producer string // only blocks mined by this node ID
+}
+
+// BlockPage is the response of /blocks when paging or filtering
+type BlockPage struct {
+	Total  int                `json:"total"`  // blocks matching the query, across every page
+	Height int                `json:"height"` // index of the tip
+	Page   int                `json:"page"`
+	Limit  int                `json:"limit"`
+	Pages  int                `json:"pages"`
+	Order  string             `json:"order"` // "asc" or "desc"
+	Blocks []blockchain.Block `json:"blocks"`
+}
+
+// BlocksPage returns the blocks q selects, with their sizes and confirmations
+func (s *Server) BlocksPage(q BlockQuery) BlockPage {
+	blocks := s.chain.Blocks()
+	height := len(blocks)
+	matched := make([]blockchain.Block, 0, len(blocks))
+	for _, b := range blocks {
+		at := int64(b.Index)
+		if q.ByTime {
+			at = b.Timestamp
+		}
+		if (q.From != nil && at < *q.From) || (q.To != nil && at > *q.To) {
+			continue
+		}
+		if (q.Miner != "" && b.Miner != q.Miner) || (q.Producer != "" && b.Producer != q.Producer) {
+			continue
+		}
+		matched = append(matched, b)
+	}
+	if q.Desc {
+		for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+			matched[i], matched[j] = matched[j], matched[i]
+		}
+	}
+	out := BlockPage{Total: len(matched), Height: height - 1, Page: q.Page, Limit: q.Limit, Order: "asc", Blocks: []blockchain.Block{}}
+	if q.Desc {
+		out.Order = "desc"
+	}
+	out.Pages = (len(matched) + q.Limit - 1) / q.Limit
+	if start := (q.Page - 1) * q.Limit; start < len(matched) {
+		end := start + q.Limit
+		if end > len(matched) {
+			end = len(matched)
+		}
+		for _, b := range matched[start:end] {
+			b = blockchain.WithSizes(b)
+			b.Confirmations = height - b.Index
+			out.Blocks = append(out.Blocks, b)
+		}
+	}
+	return out
+}
+
+// Block returns the block at index with its size and confirmations
+func (s *Server) Block(index int) (blockchain.Block, bool) {
+	b, ok := s.chain.BlockAt(index)
+	if !ok {
+		return b, false
+	}
+	return s.withConfirmations(b), true
+}
+
+// BlockByHash returns the block with the given hash, as Block does
+func (s *Server) BlockByHash(hash string) (blockchain.Block, bool) {
+	b, ok := s.chain.BlockByHash(hash)
+	if !ok {
+		return b, false
+	}
+	return s.withConfirmations(b), true
+}
+
+// withConfirmations fills in the response-only fields of b
+func (s *Server) withConfirmations(b blockchain.Block) blockchain.Block {
+	b = blockchain.WithSizes(b)
+	b.Confirmations = s.chain.Confirmations(b.Index)
+	return b
+}
+
+// parseBlockQuery reads the paging and filtering parameters of /blocks,
+// reporting whether any were given
+func parseBlockQuery(r *http.Request) (BlockQuery, bool, error) {
+	v := r.URL.Query()
+	q := BlockQuery{Page: 1, Limit: defaultBlockPage}
+	given := false
+	for _, p := range []struct {
+		name string
+		dst  *int
+		min  int
+	}{{"page", &q.Page, 1}, {"limit", &q.Limit, 1}} {
+		if s := v.Get(p.name); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < p.min {
+				return q, true, fmt.Errorf("invalid %s", p.name)
+			}
+			*p.dst, given = n, true
+		}
+	}
+	if q.Limit > maxBlockPage {
+		return q, true, fmt.Errorf("limit may be at most %d", maxBlockPage)
+	}
+	for _, p := range []struct {
+		name string
+		dst  **int64
+	}{{"from", &q.From}, {"to", &q.To}} {
+		if s := v.Get(p.name); s != "" {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || n < 0 {
+				return q, true, fmt.Errorf("invalid %s", p.name)
+			}
+			*p.dst, given = &n, true
+		}
+	}
+	q.Miner, q.Producer = v.Get("miner"), v.Get("producer")
+	given = given || q.Miner != "" || q.Producer != ""
+	switch v.Get("by") {
+	case "", "index":
+	case "time":
+		q.ByTime = true
+	default:
+		return q, true, errors.New(`invalid by: want "index" or "time"`)
+	}
+	switch v.Get("order") {
+	case "", "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		return q, true, errors.New(`invalid order: want "asc" or "desc"`)
+	}
+	given = given || v.Get("by") != "" || v.Get("order") != ""
+	return q, given, nil
+}
+
+// a single block: GET /block/{index} or GET /block/hash/{hash}, as JSON or
+// with ?format=binary in the binary encoding
+func (s *Server) blockHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	binary, ok := wantBinary(w, r)
+	if !ok {
+		return
+	}
+	rest := strings.TrimPrefix(r.URL.Path, "/block/")
+	var b blockchain.Block
+	if hash, byHash := strings.CutPrefix(rest, "hash/"); byHash {
+		if hash == "" {
+			writeError(w, http.StatusBadRequest, "hash required")
+			return
+		}
+		b, ok = s.BlockByHash(hash)
+		if !ok {
+			writeError(w, http.StatusNotFound, "no block with hash "+hash)
+			return
+		}
+	} else {
+		n, err := strconv.Atoi(rest)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid block index")
+			return
+		}
+		b, ok = s.Block(n)
+		if !ok {
+			writeError(w, http.StatusNotFound, "no block at height "+rest)
+			return
+		}
+	}
+	if binary {
+		w.Header().Set("Content-Type", blockchain.BinaryMediaType)
+		w.Write(blockchain.EncodeBlock(b))
+		return
+	}
+	json.NewEncoder(w).Encode(b)
+}
@@ -0,0 +1,303 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"salmanahmed/blockchain/pkg/clock"
+)
+
+// ErrChainExists is returned when creating a chain whose ID is taken
+var ErrChainExists = errors.New("chain already exists")
+
+// chainID keeps IDs safe to use as a path segment
+var chainID = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// ChainSpec describes a hosted chain
+type ChainSpec struct {
+	ID         string `json:"id"`
+	Difficulty int    `json:"difficulty,omitempty"` // 0 uses the node's difficulty
+	Genesis    string `json:"genesis,omitempty"`    // genesis transaction data; empty uses the roll number
+}
+
+// ChainInfo summarises a hosted chain for GET /chains
+type ChainInfo struct {
+	ChainSpec
+	Height  int  `json:"height"`
+	Pending int  `json:"pending"`
+	Keyed   bool `json:"keyed"` // writes need one of the chain's API keys
+}
+
+// ChainAdmin is a hosted chain's full settings, as seen by the node admin
+type ChainAdmin struct {
+	ChainSpec
+	Policy   ChainPolicy `json:"policy"`
+	LastUsed int64       `json:"last_used"` // unix time of the last write
+}
+
+// ChainFactory builds the server for a new chain
+type ChainFactory func(spec ChainSpec) (*Server, error)
+
+// Chains hosts independent chains next to the default one. Each has its own
+// genesis, mempool, peers and event stream and is served under /chains/{id}/,
+// so e.g. one lab section's ledger lives at /chains/section-a/blocks.
+type Chains struct {
+	primary *Server
+	factory ChainFactory
+
+	mu       sync.RWMutex
+	specs    map[string]ChainSpec
+	servers  map[string]*Server
+	handlers map[string]http.Handler
+	limiters map[string]*rateLimiter
+	lastUsed map[string]time.Time
+}
+
+// NewChains hosts primary at / and creates further chains with factory
+func NewChains(primary *Server, factory ChainFactory) *Chains {
+	return &Chains{
+		primary:  primary,
+		factory:  factory,
+		specs:    map[string]ChainSpec{},
+		servers:  map[string]*Server{},
+		handlers: map[string]http.Handler{},
+		limiters: map[string]*rateLimiter{},
+		lastUsed: map[string]time.Time{},
+	}
+}
+
+func (c *Chains) now() time.Time {
+	return clock.Or(c.primary.opts.Clock).Now()
+}
+
+// Create starts a new chain governed by policy
+func (c *Chains) Create(spec ChainSpec, policy ChainPolicy) (*Server, error) {
+	if !chainID.MatchString(spec.ID) {
+		return nil, fmt.Errorf("chain id %q must be 1-32 lowercase letters, digits or dashes", spec.ID)
+	}
+	if spec.Difficulty < 0 || spec.Difficulty > 64 {
+		return nil, fmt.Errorf("difficulty %d out of range 0-64", spec.Difficulty)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.servers[spec.ID]; ok {
+		return nil, fmt.Errorf("%w: %s", ErrChainExists, spec.ID)
+	}
+	srv, err := c.factory(spec)
+	if err != nil {
+		return nil, err
+	}
+	srv.SetPolicy(policy)
+	c.specs[spec.ID] = spec
+	c.servers[spec.ID] = srv
+	c.handlers[spec.ID] = srv.Handler()
+	c.limiters[spec.ID] = &rateLimiter{}
+	c.lastUsed[spec.ID] = c.now()
+	return srv, nil
+}
+
+// Delete stops hosting a chain; ok is false if it didn't exist
+func (c *Chains) Delete(id string) (ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.delete(id)
+}
+
+// Close stops the mining jobs of every chain, the default one included
+func (c *Chains) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.primary.Close()
+	for _, srv := range c.servers {
+		srv.Close()
+	}
+}
+
+// delete removes a chain, stopping its mining jobs (caller holds mu)
+func (c *Chains) delete(id string) bool {
+	srv, ok := c.servers[id]
+	if !ok {
+		return false
+	}
+	srv.Close()
+	delete(c.specs, id)
+	delete(c.servers, id)
+	delete(c.handlers, id)
+	delete(c.limiters, id)
+	delete(c.lastUsed, id)
+	return true
+}
+
+// expire deletes chains idle for longer than their retention policy
+func (c *Chains) expire() {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, srv := range c.servers {
+		keep := srv.Policy().RetainIdleSecond
+		if keep > 0 && now.Sub(c.lastUsed[id]) > time.Duration(keep)*time.Second {
+			log.Printf("chain %s idle for over %ds, deleting it", id, keep)
+			c.delete(id)
+		}
+	}
+}
+
+// Get returns the server for a hosted chain
+func (c *Chains) Get(id string) (*Server, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	srv, ok := c.servers[id]
+	return srv, ok
+}
+
+// List describes the hosted chains in ID order
+func (c *Chains) List() []ChainInfo {
+	c.expire()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make([]ChainInfo, 0, len(c.specs))
+	for id, spec := range c.specs {
+		srv := c.servers[id]
+		out = append(out, ChainInfo{
+			ChainSpec: spec,
+			Height:    srv.chain.Len() - 1,
+			Pending:   srv.pool.Len(),
+			Keyed:     len(srv.Policy().APIKeys) > 0,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Handler serves the default chain at / and hosted chains under /chains/{id}/
+func (c *Chains) Handler() http.Handler {
+	p := c.primary
+	mux := http.NewServeMux()
+	guard := func(h http.Handler) http.Handler {
+		return p.withRequestContext(p.cors(p.limitIPs(p.readOnlyAdmin(p.limit(h)))))
+	}
+	mux.Handle("/chains", guard(p.requireAuth(c.chainsHandler)))
+	mux.HandleFunc("/chains/", c.dispatch)
+	mux.Handle("/admin/chains/", guard(p.requireAdmin(c.adminHandler)))
+	mux.Handle("/admin/usage", guard(p.requireAdmin(c.usageHandler)))
+	mux.Handle("/", p.Handler())
+	return p.readOnly(mux)
+}
+
+// list (GET) or create (POST {"id", "difficulty", "genesis", "policy"}) hosted chains
+func (c *Chains) chainsHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	switch r.Method {
+	case "GET":
+		json.NewEncoder(w).Encode(c.List())
+	case "POST":
+		var body struct {
+			ChainSpec
+			Policy ChainPolicy `json:"policy"`
+		}
+		if err := decodeJSON(w, r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid body")
+			return
+		}
+		spec := body.ChainSpec
+		if _, err := c.Create(spec, body.Policy); err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, ErrChainExists) {
+				status = http.StatusConflict
+			}
+			writeError(w, status, err.Error())
+			return
+		}
+		logf(r.Context(), "created chain %s", spec.ID)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"status": "chain created", "id": spec.ID})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// view (GET), replace the policy of (PUT) or delete (DELETE) a hosted chain
+// at /admin/chains/{id}
+func (c *Chains) adminHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	c.expire()
+	id := strings.TrimPrefix(r.URL.Path, "/admin/chains/")
+	srv, ok := c.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown chain "+id)
+		return
+	}
+	switch r.Method {
+	case "GET":
+	case "PUT":
+		var p ChainPolicy
+		if err := decodeJSON(w, r, &p); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid body")
+			return
+		}
+		if err := p.Validate(); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		srv.SetPolicy(p)
+		logf(r.Context(), "updated policy of chain %s", id)
+	case "DELETE":
+		c.Delete(id)
+		logf(r.Context(), "deleted chain %s", id)
+		json.NewEncoder(w).Encode(map[string]string{"status": "chain deleted", "id": id})
+		return
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	c.mu.RLock()
+	info := ChainAdmin{ChainSpec: c.specs[id], Policy: srv.Policy(), LastUsed: c.lastUsed[id].Unix()}
+	c.mu.RUnlock()
+	json.NewEncoder(w).Encode(info)
+}
+
+// dispatch hands /chains/{id}/rest to that chain's handler as /rest,
+// enforcing the chain's rate quota and tracking its last write
+func (c *Chains) dispatch(w http.ResponseWriter, r *http.Request) {
+	c.expire()
+	id, rest, hasRest := strings.Cut(strings.TrimPrefix(r.URL.Path, "/chains/"), "/")
+	now := c.now()
+	c.mu.Lock()
+	h, ok := c.handlers[id]
+	if ok && r.Method != "GET" && r.Method != "OPTIONS" {
+		c.lastUsed[id] = now
+	}
+	limiter, srv := c.limiters[id], c.servers[id]
+	c.mu.Unlock()
+	if !ok {
+		jsonHeaders(w)
+		writeError(w, http.StatusNotFound, "unknown chain "+id)
+		return
+	}
+	if !limiter.allow(now, srv.Policy().RatePerMinute) {
+		jsonHeaders(w)
+		w.Header().Set("Retry-After", "60")
+		writeError(w, http.StatusTooManyRequests, fmt.Sprintf("%v: chain %s allows %d requests a minute", ErrQuotaExceeded, id, srv.Policy().RatePerMinute))
+		return
+	}
+	if !hasRest {
+		// the explorer uses relative paths, so it needs the trailing slash
+		http.Redirect(w, r, "/chains/"+id+"/", http.StatusMovedPermanently)
+		return
+	}
+	r2 := r.Clone(r.Context())
+	r2.URL.Path = "/" + rest
+	r2.URL.RawPath = ""
+	h.ServeHTTP(w, r2)
+}
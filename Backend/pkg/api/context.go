@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	identityKey
+)
+
+// RequestIDHeader carries the request ID in and out of the node
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID returns the request ID stored in ctx, or ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// Identity returns the authenticated caller stored in ctx, or "anonymous"
+func Identity(ctx context.Context) string {
+	if id, ok := ctx.Value(identityKey).(string); ok {
+		return id
+	}
+	return "anonymous"
+}
+
+func withIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, identityKey, identity)
+}
+
+// withRequestContext tags every request with an ID (the caller's
+// X-Request-ID if given) and echoes it back in the response
+func (s *Server) withRequestContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 64 {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	})
+}
+
+// detach returns a context under base carrying ctx's request ID and
+// identity, for work that outlives the request
+func detach(base, ctx context.Context) context.Context {
+	return context.WithValue(withIdentity(base, Identity(ctx)), requestIDKey, RequestID(ctx))
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// logf logs with the request ID and identity from ctx, when present
+func logf(ctx context.Context, format string, args ...interface{}) {
+	if id := RequestID(ctx); id != "" {
+		format = fmt.Sprintf("[req=%s auth=%s] ", id, Identity(ctx)) + format
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"salmanahmed/blockchain/pkg/clock"
+)
+
+// ErrSubmissionsClosed is returned for new transactions once the submission deadline has passed
+var ErrSubmissionsClosed = errors.New("submissions closed")
+
+// Deadline is the response of /deadline and /admin/deadline
+type Deadline struct {
+	Deadline  int64 `json:"deadline"` // unix seconds; 0 when submissions never close
+	Closed    bool  `json:"closed"`
+	Remaining int64 `json:"remaining_seconds"` // until submissions close, 0 once closed or with no deadline
+}
+
+// Deadline reports when new transactions stop being accepted
+func (s *Server) Deadline() Deadline {
+	s.mu.Lock()
+	deadline := s.deadline
+	s.mu.Unlock()
+	if deadline.IsZero() {
+		return Deadline{}
+	}
+	out := Deadline{Deadline: deadline.Unix()}
+	if left := deadline.Sub(clock.Or(s.opts.Clock).Now()); left > 0 {
+		out.Remaining = int64(left.Seconds())
+	} else {
+		out.Closed = true
+	}
+	return out
+}
+
+// SetDeadline moves the submission deadline; the zero time removes it
+func (s *Server) SetDeadline(ctx context.Context, deadline time.Time) Deadline {
+	s.mu.Lock()
+	s.deadline = deadline
+	s.mu.Unlock()
+	if deadline.IsZero() {
+		logf(ctx, "AUDIT submission deadline removed")
+	} else {
+		logf(ctx, "AUDIT submission deadline set to %s", deadline.UTC().Format(time.RFC3339))
+	}
+	return s.Deadline()
+}
+
+// checkDeadline refuses new transactions past the submission deadline
+func (s *Server) checkDeadline() error {
+	s.mu.Lock()
+	deadline := s.deadline
+	s.mu.Unlock()
+	if deadline.IsZero() || clock.Or(s.opts.Clock).Now().Before(deadline) {
+		return nil
+	}
+	return fmt.Errorf("%w at %s", ErrSubmissionsClosed, deadline.UTC().Format(time.RFC3339))
+}
+
+// when new transactions stop being accepted: GET /deadline
+func (s *Server) deadlineStatusHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	json.NewEncoder(w).Encode(s.Deadline())
+}
+
+// view (GET) or move (PUT {"deadline": unix seconds, 0 for none}) the
+// submission deadline
+func (s *Server) deadlineHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	switch r.Method {
+	case "GET":
+		json.NewEncoder(w).Encode(s.Deadline())
+	case "PUT":
+		var body struct {
+			Deadline *int64 `json:"deadline"`
+		}
+		if err := decodeJSON(w, r, &body); err != nil || body.Deadline == nil || *body.Deadline < 0 {
+			writeError(w, http.StatusBadRequest, "invalid body")
+			return
+		}
+		var deadline time.Time
+		if *body.Deadline > 0 {
+			deadline = time.Unix(*body.Deadline, 0)
+		}
+		json.NewEncoder(w).Encode(s.SetDeadline(r.Context(), deadline))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
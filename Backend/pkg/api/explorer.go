@@ -0,0 +1,21 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// explorerFiles is the static block explorer served at /
+//
+//go:embed explorer
+var explorerFiles embed.FS
+
+// explorerHandler serves the embedded explorer
+func explorerHandler() http.Handler {
+	sub, err := fs.Sub(explorerFiles, "explorer")
+	if err != nil {
+		panic(err) // embedded at build time, cannot fail
+	}
+	return http.FileServer(http.FS(sub))
+}
@@ -0,0 +1,153 @@
+// Minimal explorer served by the node itself. Paths are relative so the
+// same page works for the default chain at / and for /chains/{id}/.
+const $ = (id) => document.getElementById(id);
+const tokenInput = $('token');
+tokenInput.value = localStorage.getItem('token') || '';
+tokenInput.addEventListener('change', () => localStorage.setItem('token', tokenInput.value));
+
+function message(text, isError) {
+  const el = $('message');
+  el.textContent = text;
+  el.className = isError ? 'error' : 'success';
+  clearTimeout(message.timer);
+  message.timer = setTimeout(() => { el.textContent = ''; }, 3000);
+}
+
+async function api(path, options = {}) {
+  const headers = { 'Content-Type': 'application/json' };
+  if (tokenInput.value) headers.Authorization = 'Bearer ' + tokenInput.value;
+  const res = await fetch(path, { ...options, headers });
+  const body = await res.json();
+  if (!res.ok) throw new Error(body.error || res.statusText);
+  return body;
+}
+
+function item(text) {
+  const li = document.createElement('li');
+  li.textContent = text;
+  return li;
+}
+
+// address labels, refreshed with the chain
+let names = {};
+
+// name an address by its label, or shorten it
+function addr(a) {
+  return names[a] || `${a.slice(0, 12)}…`;
+}
+
+// describe a transaction: its data, or a short contract or transfer summary
+function txLabel(tx) {
+  if (tx.commit) return `commitment ${tx.commit.slice(0, 12)}…`;
+  if (tx.blob) return `${tx.data ? tx.data + ' ' : ''}blob ${tx.blob.slice(0, 12)}…`;
+  if (tx.data) return tx.data;
+  if (tx.contract) {
+    return tx.contract.code
+      ? `deploy contract ${tx.id.slice(0, 40)}`
+      : `call contract ${tx.contract.address.slice(0, 12)}… (${(tx.contract.args || []).join(', ')})`;
+  }
+  if (tx.confidential) return `confidential payload (${tx.confidential.commitment.slice(0, 12)}…)`;
+  if (tx.kv) return `kv ${tx.kv.map((op) => `${op.op} ${op.key}`).join(', ')}`;
+  const total = (tx.outputs || []).reduce((sum, o) => sum + o.amount, 0);
+  if (tx.coinbase) return `coinbase for block ${tx.coinbase} (${total} to ${addr(tx.outputs[0].lock.split(' ')[2])})`;
+  const to = (tx.outputs || []).map((o) => o.lock.split(' ')[2]).filter((a) => a && names[a]).map(addr);
+  return `transfer ${tx.id.slice(0, 12)}… (${(tx.inputs || []).length} in, ${total} out` +
+    (to.length ? ` to ${to.join(', ')})` : ')');
+}
+
+let blocks = [];
+
+async function refresh() {
+  try {
+    blocks = await api('blocks');
+    names = Object.fromEntries((await api('labels')).map((l) => [l.address, l.label]));
+    const pending = await api('pending');
+    const stats = await api('stats/blocktime');
+    const tip = blocks[blocks.length - 1];
+    const recent = stats.windows[0];
+    $('summary').textContent = `height ${tip.index} · ${pending.length} pending` +
+      (recent.intervals ? ` · avg block ${recent.avg_seconds.toFixed(1)}s` : '') +
+      ` · next ${stats.mining ? 'in' : 'takes'} ~${stats.eta_seconds.toFixed(1)}s`;
+    $('pending').replaceChildren(...pending.map((tx) => item(txLabel(tx))));
+    $('blocks').replaceChildren(...blocks.slice().reverse().map((b) => {
+      const tr = document.createElement('tr');
+      [b.index, b.hash.slice(0, 24) + '…', b.transactions.length, b.nonce,
+        new Date(b.timestamp * 1000).toLocaleTimeString()].forEach((v, i) => {
+        const td = document.createElement('td');
+        td.textContent = v;
+        if (i === 1) td.className = 'hash';
+        tr.appendChild(td);
+      });
+      tr.addEventListener('click', () => showBlock(b.index));
+      return tr;
+    }));
+  } catch (err) {
+    message('Error: ' + err.message, true);
+  }
+}
+
+function showBlock(index) {
+  const b = blocks.find((x) => x.index === index);
+  if (!b) return;
+  $('detail-index').textContent = '#' + index;
+  $('detail-body').textContent = JSON.stringify(b, null, 2);
+  $('detail').hidden = false;
+  $('detail').scrollIntoView({ behavior: 'smooth' });
+}
+
+$('detail-close').addEventListener('click', () => { $('detail').hidden = true; });
+
+$('tx-form').addEventListener('submit', async (e) => {
+  e.preventDefault();
+  const data = $('tx-data').value.trim();
+  if (!data) return message('Please enter transaction data', true);
+  try {
+    await api('transactions', { method: 'POST', body: JSON.stringify({ data }) });
+    $('tx-data').value = '';
+    message('Transaction added');
+    refresh();
+  } catch (err) {
+    message('Error: ' + err.message, true);
+  }
+});
+
+$('mine').addEventListener('click', async () => {
+  const btn = $('mine');
+  btn.disabled = true;
+  message('Mining block…');
+  try {
+    const res = await api('mine?wait=true', { method: 'POST' });
+    message(res.status || `Mined block #${res.index} at ${Math.round(res.hashrate)} hashes/s on ${res.workers} workers`);
+    refresh();
+  } catch (err) {
+    message('Error: ' + err.message, true);
+  }
+  btn.disabled = false;
+});
+
+$('search-form').addEventListener('submit', async (e) => {
+  e.preventDefault();
+  const q = $('search-q').value.trim();
+  if (!q) return message('Please enter a search query', true);
+  try {
+    const results = await api('search?q=' + encodeURIComponent(q));
+    results.forEach((r) => Object.assign(names, r.labels));
+    $('results').replaceChildren(...results.map((r) => {
+      const li = item(`#${r.block_index}: ${txLabel(r.transaction)}`);
+      li.style.cursor = 'pointer';
+      li.addEventListener('click', () => showBlock(r.block_index));
+      return li;
+    }));
+    message(`Found ${results.length} matching transactions`);
+  } catch (err) {
+    message('Error: ' + err.message, true);
+  }
+});
+
+// refresh on every node event, falling back to polling
+if (window.EventSource) {
+  const events = new EventSource('events');
+  ['tx_added', 'tx_dropped', 'block_mined', 'chain_replaced', 'chain_tampered'].forEach((t) => events.addEventListener(t, refresh));
+}
+setInterval(refresh, 10000);
+refresh();
@@ -0,0 +1,481 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/events"
+)
+
+// maxImportLine caps a single block's encoding in a streamed import
+const maxImportLine = 16 << 20
+
+// flushEvery is how many exported blocks go out between flushes
+const flushEvery = 64
+
+// snapshotVersion is the format version written into chain snapshots
+const snapshotVersion = 1
+
+// maxSnapshotBytes caps a snapshot posted to /import?format=snapshot
+const maxSnapshotBytes = 1 << 30
+
+// ImportResult summarises a streamed import
+type ImportResult struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"` // blocks the chain already had
+	Height   int `json:"height"`
+}
+
+// ChainSnapshot is the chain and its mempool as one JSON document, the
+// format of /export?format=snapshot and /import?format=snapshot
+type ChainSnapshot struct {
+	Version int                      `json:"version"`
+	Blocks  []blockchain.Block       `json:"blocks"`
+	Pending []blockchain.Transaction `json:"pending"`
+}
+
+// RestoreResult summarises RestoreChain
+type RestoreResult struct {
+	Height         int    `json:"height"`
+	TipHash        string `json:"tip_hash"`
+	DroppedBlocks  int    `json:"dropped_blocks"`  // local blocks the snapshot replaced
+	DroppedPending int    `json:"dropped_pending"` // local pending transactions discarded
+	Pending        int    `json:"pending"`         // snapshot transactions back in the mempool
+	Orphaned       int    `json:"orphaned"`        // held until their inputs confirm
+	Rejected       int    `json:"rejected"`        // no longer valid on the restored chain
+}
+
+// ImportPlan is the response of /import?dry_run=true: what the import
+// would do, worked out without changing the chain or mempool
+type ImportPlan struct {
+	DryRun   bool           `json:"dry_run"`
+	Fork     int            `json:"fork_height"` // the last local block kept; -1 when the genesis block changes
+	Rollback []BlockSummary `json:"rollback"`    // local blocks dropped, oldest first
+	Apply    []BlockSummary `json:"apply"`       // imported blocks added, oldest first
+	Skipped  int            `json:"skipped"`     // blocks the chain already has (ndjson)
+	Height   int            `json:"height"`      // of the resulting tip
+	TipHash  string         `json:"tip_hash"`
+	// DroppedPending and Pending are the local pending transactions
+	// discarded and the snapshot's offered to the mempool (snapshot)
+	DroppedPending int `json:"dropped_pending"`
+	Pending        int `json:"pending"`
+}
+
+// importPlan turns p into the ImportPlan reported to the caller
+func importPlan(p blockchain.ReplacePlan) ImportPlan {
+	out := ImportPlan{DryRun: true, Fork: p.Fork, Rollback: []BlockSummary{}, Apply: []BlockSummary{}, Height: p.Tip.Index, TipHash: p.Tip.Hash}
+	for _, b := range p.Dropped {
+		out.Rollback = append(out.Rollback, blockSummary(b))
+	}
+	for _, b := range p.Added {
+		out.Apply = append(out.Apply, blockSummary(b))
+	}
+	return out
+}
+
+// ExportBlocks writes the chain to w one block at a time as newline-delimited
+// JSON, so the whole chain is never held in memory at once
+func (s *Server) ExportBlocks(ctx context.Context, w io.Writer) error {
+	return s.exportEach(ctx, w, json.NewEncoder(w).Encode)
+}
+
+// ExportBinary writes the chain to w one block at a time in the framed
+// binary encoding of blockchain.WriteBlock
+func (s *Server) ExportBinary(ctx context.Context, w io.Writer) error {
+	return s.exportEach(ctx, w, func(v interface{}) error { return blockchain.WriteBlock(w, v.(blockchain.Block)) })
+}
+
+// exportEach hands the chain's blocks to encode in order, flushing w now and then
+func (s *Server) exportEach(ctx context.Context, w io.Writer, encode func(interface{}) error) error {
+	flusher, _ := w.(http.Flusher)
+	for i := 0; ; i++ {
+		b, ok := s.chain.BlockAt(i)
+		if !ok {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := encode(b); err != nil {
+			return err
+		}
+		if flusher != nil && i%flushEvery == flushEvery-1 {
+			flusher.Flush()
+		}
+	}
+}
+
+// blockStream reads the blocks of an import, one JSON block per line or
+// framed binary blocks
+type blockStream struct {
+	lines *bufio.Scanner
+	bin   *bufio.Reader
+	n     int // lines or frames read
+}
+
+func newBlockStream(r io.Reader, binary bool) *blockStream {
+	if binary {
+		return &blockStream{bin: bufio.NewReaderSize(r, 64*1024)}
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxImportLine)
+	return &blockStream{lines: sc}
+}
+
+// next returns the next block, or io.EOF at the end of the stream
+func (bs *blockStream) next() (blockchain.Block, error) {
+	var b blockchain.Block
+	if bs.bin != nil {
+		bs.n++
+		b, _, err := blockchain.ReadBlock(bs.bin)
+		if err != nil && err != io.EOF {
+			err = fmt.Errorf("%s: %v", bs.where(), err)
+		}
+		return b, err
+	}
+	for bs.lines.Scan() {
+		bs.n++
+		if len(bs.lines.Bytes()) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(bs.lines.Bytes(), &b); err != nil {
+			return b, fmt.Errorf("%s: %v", bs.where(), err)
+		}
+		return b, nil
+	}
+	if err := bs.lines.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			err = fmt.Errorf("a block exceeds %d bytes", maxImportLine)
+		}
+		return b, err
+	}
+	return b, io.EOF
+}
+
+// where names the position of the block last read, for errors
+func (bs *blockStream) where() string {
+	if bs.bin != nil {
+		return fmt.Sprintf("frame %d", bs.n)
+	}
+	return fmt.Sprintf("line %d", bs.n)
+}
+
+// ImportBlocks reads newline-delimited blocks from r and appends them as
+// they arrive. Blocks the chain already holds are skipped, so an export can
+// be replayed onto a node holding a prefix of it. Blocks appended before a
+// failure stay appended.
+func (s *Server) ImportBlocks(ctx context.Context, r io.Reader) (ImportResult, error) {
+	return s.importStream(ctx, newBlockStream(r, false))
+}
+
+// ImportBinary is ImportBlocks for framed binary blocks, as
+// /export?format=binary writes them
+func (s *Server) ImportBinary(ctx context.Context, r io.Reader) (ImportResult, error) {
+	return s.importStream(ctx, newBlockStream(r, true))
+}
+
+// importStream appends the blocks of bs as they arrive
+func (s *Server) importStream(ctx context.Context, bs *blockStream) (res ImportResult, err error) {
+	if s.Unhealthy() != "" {
+		return res, ErrUnhealthy
+	}
+	s.mineMu.Lock()
+	defer s.mineMu.Unlock()
+	defer func() { res.Height = s.chain.Len() - 1 }()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		b, err := bs.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return res, err
+		}
+		if have, ok := s.chain.BlockAt(b.Index); ok {
+			if have.Hash != b.Hash {
+				return res, fmt.Errorf("%s: block %d conflicts with the local chain", bs.where(), b.Index)
+			}
+			res.Skipped++
+			continue
+		}
+		s.txMu.Lock()
+		err = s.chain.AddBlock(b)
+		s.txMu.Unlock()
+		if err != nil {
+			return res, fmt.Errorf("%s: %w", bs.where(), err)
+		}
+		s.persistBlock(ctx, b)
+		res.Imported++
+	}
+	s.txMu.Lock()
+	confirmed := s.pool.RemoveConfirmed(s.confirmed)
+	s.txMu.Unlock()
+	s.pendingLeft(PendingConfirmed, "", confirmed...)
+	s.persistPending(ctx)
+	s.promoteOrphans(ctx)
+	s.assertInvariants(ctx)
+	s.watchBlocks(ctx)
+	s.attest(ctx)
+	return res, nil
+}
+
+// PlanImport validates the blocks from r, newline-delimited JSON or framed
+// binary ones, as ImportBlocks would and returns what importing them would
+// do, without appending any. Unlike ImportBlocks it reads them all first,
+// and rejects them all when one fails.
+func (s *Server) PlanImport(ctx context.Context, r io.Reader, binary bool) (ImportPlan, error) {
+	var added []blockchain.Block
+	skipped := 0
+	bs := newBlockStream(r, binary)
+	for {
+		if err := ctx.Err(); err != nil {
+			return ImportPlan{}, err
+		}
+		b, err := bs.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return ImportPlan{}, err
+		}
+		if have, ok := s.chain.BlockAt(b.Index); ok {
+			if have.Hash != b.Hash {
+				return ImportPlan{}, fmt.Errorf("%s: block %d conflicts with the local chain", bs.where(), b.Index)
+			}
+			skipped++
+			continue
+		}
+		added = append(added, b)
+	}
+	p, err := s.chain.PlanAppend(added)
+	if err != nil {
+		return ImportPlan{}, err
+	}
+	plan := importPlan(p)
+	plan.Skipped = skipped
+	return plan, nil
+}
+
+// PlanRestore validates a snapshot as RestoreChain would and returns what
+// restoring it would do, without touching the chain or mempool
+func (s *Server) PlanRestore(snap ChainSnapshot) (ImportPlan, error) {
+	if snap.Version != snapshotVersion {
+		return ImportPlan{}, fmt.Errorf("unsupported snapshot version %d (want %d)", snap.Version, snapshotVersion)
+	}
+	p, err := s.chain.PlanRestore(snap.Blocks)
+	if err != nil {
+		return ImportPlan{}, err
+	}
+	plan := importPlan(p)
+	plan.DroppedPending = s.pool.Len()
+	plan.Pending = len(snap.Pending)
+	return plan, nil
+}
+
+// RestoreChain replaces the chain and mempool with a snapshot's, whatever
+// the local chain holds. Every block is checked first, its hash, link,
+// proof of work and merkle root, then replayed; on any failure the local
+// chain is left untouched. Pending transactions are validated again on the
+// restored chain and ones no longer valid are skipped.
+func (s *Server) RestoreChain(ctx context.Context, snap ChainSnapshot) (res RestoreResult, err error) {
+	if snap.Version != snapshotVersion {
+		return res, fmt.Errorf("unsupported snapshot version %d (want %d)", snap.Version, snapshotVersion)
+	}
+	// wait out any mining so no block lands on the old chain mid-restore
+	s.mineMu.Lock()
+	s.txMu.Lock()
+	dropped, err := s.chain.Restore(snap.Blocks)
+	var cleared []blockchain.Transaction
+	if err == nil {
+		cleared = s.pool.Drain()
+		s.orphans.Clear()
+	}
+	s.txMu.Unlock()
+	s.mineMu.Unlock()
+	if err != nil {
+		return res, err
+	}
+	res.DroppedPending = len(cleared)
+	s.pendingLeft(PendingCleared, "chain restored from a snapshot", cleared...)
+	res.DroppedBlocks = len(dropped)
+
+	s.mu.Lock()
+	s.unhealthy = "" // whatever state broke an invariant is gone
+	if len(dropped) > 0 {
+		s.rewindWatches(dropped[0].Index - 1)
+		s.dropAttestations(ctx)
+	}
+	s.mu.Unlock()
+	s.recordSideBlocks(SideReorged, dropped...)
+	s.persistChain(ctx)
+	for _, tx := range snap.Pending {
+		switch err := s.addTransaction(ctx, tx, false); {
+		case err == nil:
+			res.Pending++
+		case errors.Is(err, ErrOrphaned):
+			res.Orphaned++
+		default:
+			res.Rejected++
+			logf(ctx, "skipping snapshot transaction %s: %v", tx.ID, err)
+		}
+	}
+	s.persistPending(ctx)
+	s.promoteOrphans(ctx)
+	s.assertInvariants(ctx)
+
+	res.Height = s.chain.Len() - 1
+	tip, _ := s.chain.BlockAt(res.Height)
+	res.TipHash = tip.Hash
+	logf(ctx, "AUDIT restored a snapshot of %d blocks, tip %s: dropped %d blocks and %d pending transactions",
+		len(snap.Blocks), tip.Hash, res.DroppedBlocks, res.DroppedPending)
+	s.events.Publish(events.ChainReplaced, SyncResult{Height: res.Height, Replaced: true, Peer: "snapshot", Dropped: res.DroppedBlocks})
+	s.watchBlocks(ctx)
+	s.attest(ctx)
+	return res, nil
+}
+
+// stream the chain: GET /export?format=ndjson (one block per line),
+// format=json (a single array, the default), format=binary (framed binary
+// blocks) or format=snapshot (the chain and mempool as a ChainSnapshot)
+func (s *Server) exportHandler(w http.ResponseWriter, r *http.Request) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "ndjson":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		if err := s.ExportBlocks(r.Context(), w); err != nil {
+			logf(r.Context(), "export stopped: %v", err)
+		}
+	case "binary":
+		w.Header().Set("Content-Type", blockchain.BinaryMediaType)
+		if err := s.ExportBinary(r.Context(), w); err != nil {
+			logf(r.Context(), "export stopped: %v", err)
+		}
+	case "", "json":
+		jsonHeaders(w)
+		io.WriteString(w, "[")
+		sep := &arrayWriter{w: w}
+		if err := s.ExportBlocks(r.Context(), sep); err != nil {
+			logf(r.Context(), "export stopped: %v", err)
+		}
+		io.WriteString(w, "]\n")
+	case "snapshot":
+		jsonHeaders(w)
+		fmt.Fprintf(w, `{"version":%d,"blocks":[`, snapshotVersion)
+		sep := &arrayWriter{w: w}
+		if err := s.ExportBlocks(r.Context(), sep); err != nil {
+			// leave the document unterminated so it can't be restored
+			logf(r.Context(), "export stopped: %v", err)
+			return
+		}
+		pending, _ := json.Marshal(s.pool.All())
+		fmt.Fprintf(w, "],\"pending\":%s}\n", pending)
+	default:
+		jsonHeaders(w)
+		writeError(w, http.StatusBadRequest, "unknown format "+format+" (want json, ndjson, binary or snapshot)")
+	}
+}
+
+// arrayWriter turns ExportBlocks' one-block-per-Write lines into JSON array elements
+type arrayWriter struct {
+	w    http.ResponseWriter
+	more bool
+}
+
+func (a *arrayWriter) Write(p []byte) (int, error) {
+	if a.more {
+		if _, err := io.WriteString(a.w, ","); err != nil {
+			return 0, err
+		}
+	}
+	a.more = true
+	return a.w.Write(p)
+}
+
+func (a *arrayWriter) Flush() {
+	if f, ok := a.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// append newline-delimited blocks from the request body: POST /import, or
+// framed binary ones: POST /import?format=binary, or replace the chain and
+// mempool with a snapshot: POST /import?format=snapshot. With ?dry_run=true
+// any of them only validates and returns the ImportPlan.
+func (s *Server) importHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	binary := false
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "ndjson":
+	case "binary":
+		binary = true
+	case "snapshot":
+		s.restoreHandler(w, r)
+		return
+	default:
+		writeError(w, http.StatusBadRequest, "unknown format "+format+" (want ndjson, binary or snapshot)")
+		return
+	}
+	if r.URL.Query().Get("dry_run") == "true" {
+		plan, err := s.PlanImport(r.Context(), r.Body, binary)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "import would fail: "+err.Error())
+			return
+		}
+		json.NewEncoder(w).Encode(plan)
+		return
+	}
+	importBlocks := s.ImportBlocks
+	if binary {
+		importBlocks = s.ImportBinary
+	}
+	res, err := importBlocks(r.Context(), r.Body)
+	if err != nil {
+		logf(r.Context(), "import failed after %d blocks: %v", res.Imported, err)
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrUnhealthy) {
+			status = http.StatusServiceUnavailable
+		}
+		writeError(w, status, fmt.Sprintf("imported %d blocks, then: %v", res.Imported, err))
+		return
+	}
+	logf(r.Context(), "imported %d blocks (%d already present), height %d", res.Imported, res.Skipped, res.Height)
+	json.NewEncoder(w).Encode(res)
+}
+
+// restore a ChainSnapshot from the request body: POST /import?format=snapshot
+func (s *Server) restoreHandler(w http.ResponseWriter, r *http.Request) {
+	var snap ChainSnapshot
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSnapshotBytes))
+	if err := dec.Decode(&snap); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid snapshot: "+err.Error())
+		return
+	}
+	if r.URL.Query().Get("dry_run") == "true" {
+		plan, err := s.PlanRestore(snap)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "snapshot rejected: "+err.Error())
+			return
+		}
+		json.NewEncoder(w).Encode(plan)
+		return
+	}
+	res, err := s.RestoreChain(r.Context(), snap)
+	if err != nil {
+		logf(r.Context(), "restore failed: %v", err)
+		writeError(w, http.StatusBadRequest, "snapshot rejected: "+err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(res)
+}
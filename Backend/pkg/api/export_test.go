@@ -0,0 +1,109 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+// mineBlocks mines n blocks of two data transactions each on s
+func mineBlocks(t testing.TB, s *Server, n int) {
+	t.Helper()
+	ctx := context.Background()
+	for i := 0; i < n; i++ {
+		for j := 0; j < 2; j++ {
+			if err := s.AddTransaction(ctx, blockchain.NewDataTx(fmt.Sprintf("tx-%d-%d", i, j))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, _, err := s.MinePending(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// postImport sends body to the import handler at target and returns the
+// response
+func postImport(s *Server, target string, body []byte) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.importHandler(w, httptest.NewRequest("POST", target, bytes.NewReader(body)))
+	return w
+}
+
+// FuzzImport posts arbitrary newline-delimited or framed binary block
+// streams to /import, first as a dry run. Whatever arrives, the dry run leaves the chain as it
+// was, the chain still passes its invariant checks after the import, a
+// successful import reports the height the chain ends at, and an import
+// the dry run accepted succeeds with the tip it planned.
+func FuzzImport(f *testing.F) {
+	src := newTestServer(blockchain.NewChain(1).Tip())
+	mineBlocks(f, src, 3)
+	var ndjson bytes.Buffer
+	if err := src.ExportBlocks(context.Background(), &ndjson); err != nil {
+		f.Fatal(err)
+	}
+	var framed bytes.Buffer
+	if err := src.ExportBinary(context.Background(), &framed); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(ndjson.Bytes(), false)
+	f.Add(bytes.ReplaceAll(ndjson.Bytes(), []byte(`"nonce":`), []byte(`"nonce":1`)), false)
+	f.Add(ndjson.Bytes()[:ndjson.Len()/2], false)
+	f.Add([]byte("{}\n"), false)
+	f.Add([]byte("\n\n[1,2]\n"), false)
+	f.Add(framed.Bytes(), true)
+	f.Add(framed.Bytes()[:framed.Len()/2], true)
+	f.Add(ndjson.Bytes(), true)
+
+	genesis := src.chain.Blocks()[0]
+	f.Fuzz(func(t *testing.T, body []byte, binary bool) {
+		target, dryRun := "/import", "/import?dry_run=true"
+		if binary {
+			target, dryRun = "/import?format=binary", "/import?format=binary&dry_run=true"
+		}
+		s := newTestServer(genesis)
+		dry := postImport(s, dryRun, body)
+		if dry.Code >= 500 {
+			t.Fatalf("dry run: status %d: %s", dry.Code, dry.Body)
+		}
+		if s.chain.Len() != 1 {
+			t.Fatalf("the dry run appended %d blocks", s.chain.Len()-1)
+		}
+		var plan ImportPlan
+		if dry.Code == http.StatusOK {
+			if err := json.Unmarshal(dry.Body.Bytes(), &plan); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		w := postImport(s, target, body)
+		if w.Code >= 500 {
+			t.Fatalf("import: status %d: %s", w.Code, w.Body)
+		}
+		if err := s.chain.CheckInvariants(); err != nil {
+			t.Fatalf("the chain is inconsistent after an import: %v", err)
+		}
+		if w.Code != http.StatusOK {
+			if dry.Code == http.StatusOK {
+				t.Fatalf("the dry run accepted an import that fails: %s", w.Body)
+			}
+			return
+		}
+		var res ImportResult
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Height != s.chain.Len()-1 {
+			t.Fatalf("the import reports height %d, the chain ends at %d", res.Height, s.chain.Len()-1)
+		}
+		if dry.Code == http.StatusOK && (plan.Height != res.Height || plan.TipHash != s.chain.Tip().Hash) {
+			t.Fatalf("the dry run planned tip %d %s, the import reached %d %s", plan.Height, plan.TipHash, res.Height, s.chain.Tip().Hash)
+		}
+	})
+}
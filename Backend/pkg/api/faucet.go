@@ -0,0 +1,153 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/clock"
+	"salmanahmed/blockchain/pkg/script"
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+// ErrFaucetDry is returned when the faucet has no confirmed coins left to send
+var ErrFaucetDry = errors.New("faucet has no spendable coins")
+
+// FaucetOptions configures the testnet faucet; it is off without a Key
+type FaucetOptions struct {
+	Key      string        // hex private key of the funded faucet account
+	Amount   int64         // coins sent per request
+	Cooldown time.Duration // wait before the same address or IP is paid again
+}
+
+// faucet remembers recent payouts to enforce the cooldowns
+type faucet struct {
+	mu   sync.Mutex
+	paid map[string]time.Time // "address ..." or "ip ..." -> last payout
+}
+
+// reserve claims a payout for every key, or returns how long until the
+// longest cooldown among them ends
+func (f *faucet) reserve(now time.Time, cooldown time.Duration, keys ...string) time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.paid == nil {
+		f.paid = map[string]time.Time{}
+	}
+	var wait time.Duration
+	for _, k := range keys {
+		if d := f.paid[k].Add(cooldown).Sub(now); d > wait {
+			wait = d
+		}
+	}
+	if wait > 0 {
+		return wait
+	}
+	for _, k := range keys {
+		f.paid[k] = now
+	}
+	return 0
+}
+
+// release forgets a reservation whose payment failed
+func (f *faucet) release(keys ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, k := range keys {
+		delete(f.paid, k)
+	}
+}
+
+// faucetTx pays the faucet amount to address from confirmed faucet outputs
+// that no pending transaction already spends, with change back to the faucet
+func (s *Server) faucetTx(address string) (blockchain.Transaction, error) {
+	opts := s.opts.Faucet
+	kp, err := wallet.FromPrivateKey(opts.Key)
+	if err != nil {
+		return blockchain.Transaction{}, err
+	}
+	var tx blockchain.Transaction
+	var total int64
+	for _, u := range s.chain.UTXOs(kp.Address) {
+		if total >= opts.Amount {
+			break
+		}
+		if s.pool.Spending(blockchain.OutPoint{TxID: u.TxID, Index: u.Index}) {
+			continue
+		}
+		tx.Inputs = append(tx.Inputs, blockchain.TxInput{TxID: u.TxID, Index: u.Index})
+		total += u.Amount
+	}
+	if total < opts.Amount {
+		return tx, fmt.Errorf("%w: %s holds %d unspent and confirmed", ErrFaucetDry, kp.Address, total)
+	}
+	tx.Outputs = append(tx.Outputs, blockchain.TxOutput{Amount: opts.Amount, Lock: script.P2PKH(address)})
+	if change := total - opts.Amount; change > 0 {
+		tx.Outputs = append(tx.Outputs, blockchain.TxOutput{Amount: change, Lock: script.P2PKH(kp.Address)})
+	}
+	sig, err := wallet.Sign(kp.PrivateKey, tx.SigHash())
+	if err != nil {
+		return tx, err
+	}
+	for i := range tx.Inputs {
+		tx.Inputs[i].Unlock = script.P2PKHUnlock(sig, kp.PublicKey)
+	}
+	return tx.Seal(), nil
+}
+
+// fund a wallet from the faucet: POST {"address":"..."}, at most once per
+// cooldown for each address and each client IP
+func (s *Server) faucetHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	opts := s.opts.Faucet
+	if opts.Key == "" {
+		writeError(w, http.StatusNotFound, "faucet disabled")
+		return
+	}
+	var body struct {
+		Address string `json:"address"`
+	}
+	if err := decodeJSON(w, r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+	if !blockchain.IsAddress(body.Address) {
+		writeError(w, http.StatusBadRequest, "address must be a wallet address")
+		return
+	}
+	keys := []string{"address " + body.Address, "ip " + clientIP(r)}
+	if wait := s.faucet.reserve(clock.Or(s.opts.Clock).Now(), opts.Cooldown, keys...); wait > 0 {
+		secs := int(wait.Round(time.Second) / time.Second)
+		if secs < 1 {
+			secs = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(secs))
+		writeError(w, http.StatusTooManyRequests, fmt.Sprintf("%v: try the faucet again in %s", ErrQuotaExceeded, wait.Round(time.Second)))
+		return
+	}
+	tx, err := s.faucetTx(body.Address)
+	if err == nil {
+		err = s.AddTransaction(r.Context(), tx)
+	}
+	if err != nil {
+		s.faucet.release(keys...)
+		logf(r.Context(), "faucet payment to %s failed: %v", body.Address, err)
+		if errors.Is(err, ErrFaucetDry) {
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		writeChainError(w, err)
+		return
+	}
+	logf(r.Context(), "faucet sent %d to %s in %s", opts.Amount, body.Address, tx.ID)
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "faucet payment added", "txid": tx.ID, "amount": opts.Amount})
+}
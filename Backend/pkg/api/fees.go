@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+// Fee estimation settings
+const (
+	feeHistory         = 20  // recent blocks whose inclusion patterns are analyzed
+	maxFeeTarget       = 100 // furthest target_blocks accepted
+	typicalTransferLen = 250 // bytes assumed for a transfer before any have been seen
+)
+
+// FeeEstimate is the response of /fees/estimate
+type FeeEstimate struct {
+	TargetBlocks  int     `json:"target_blocks"`
+	FeeRate       float64 `json:"fee_rate"`       // suggested fee per byte
+	Fee           int64   `json:"fee"`            // FeeRate for a transfer of TypicalBytes
+	TypicalBytes  int     `json:"typical_bytes"`  // mean size of recent transfers
+	Basis         string  `json:"basis"`          // what set the rate: "mempool", "recent blocks" or "none"
+	MempoolDepth  int     `json:"mempool_depth"`  // pending transactions
+	AvgBlockTxns  float64 `json:"avg_block_txns"` // over the recent blocks
+	BlocksSampled int     `json:"blocks_sampled"`
+}
+
+// feeRate is tx's fee per byte, or 0 when it pays none or its inputs
+// aren't confirmed
+func (s *Server) feeRate(tx blockchain.Transaction) float64 {
+	if fee, ok := s.chain.TxFee(tx); ok {
+		return blockchain.FeeRate(fee, tx)
+	}
+	return 0
+}
+
+// EstimateFee suggests a fee likely to confirm within target blocks. A
+// transaction has to outbid the pending ones that would fill the next
+// target blocks at the recent average block size, and pay at least the
+// median of the lowest rates recent blocks accepted.
+func (s *Server) EstimateFee(target int) (FeeEstimate, error) {
+	if target < 1 || target > maxFeeTarget {
+		return FeeEstimate{}, fmt.Errorf("target_blocks must be 1-%d", maxFeeTarget)
+	}
+	est := FeeEstimate{TargetBlocks: target, Basis: "none", TypicalBytes: typicalTransferLen}
+
+	history := s.chain.FeeHistory(feeHistory)
+	est.BlocksSampled = len(history)
+	var floors []float64
+	txns, transfers, size := 0, 0, 0.0
+	for _, bf := range history {
+		txns += bf.Txns
+		if bf.Transfers > 0 {
+			floors = append(floors, bf.MinRate)
+			transfers += bf.Transfers
+			size += bf.AvgSize * float64(bf.Transfers)
+		}
+	}
+	if len(history) > 0 {
+		est.AvgBlockTxns = float64(txns) / float64(len(history))
+	}
+	if transfers > 0 {
+		est.TypicalBytes = int(math.Round(size / float64(transfers)))
+	}
+	if len(floors) > 0 {
+		sort.Float64s(floors)
+		est.FeeRate, est.Basis = floors[len(floors)/2], "recent blocks"
+	}
+
+	// transactions that pay no fee (data, or spending unconfirmed
+	// outputs) still take room, at a rate of zero
+	pending := s.pool.All()
+	est.MempoolDepth = len(pending)
+	rates := make([]float64, 0, len(pending))
+	for _, t := range pending {
+		rates = append(rates, s.feeRate(t))
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(rates)))
+	room := int(math.Ceil(float64(target) * math.Max(est.AvgBlockTxns, 1)))
+	if room <= len(rates) && rates[room-1] >= est.FeeRate {
+		// outbid the last transaction that would still make it in
+		est.FeeRate, est.Basis = rates[room-1]+1/float64(est.TypicalBytes), "mempool"
+	}
+	est.Fee = int64(math.Ceil(est.FeeRate * float64(est.TypicalBytes)))
+	return est, nil
+}
+
+// suggest a fee: GET /fees/estimate?target_blocks=N (default 1)
+func (s *Server) feeEstimateHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	target := 1
+	if v := r.URL.Query().Get("target_blocks"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid target_blocks")
+			return
+		}
+		target = n
+	}
+	est, err := s.EstimateFee(target)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(est)
+}
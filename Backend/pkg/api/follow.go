@@ -0,0 +1,170 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/events"
+)
+
+// FollowChain message types
+const (
+	FollowBlock    = "block"    // the next block of the chain
+	FollowRollback = "rollback" // the chain reorganized: drop every block above Height
+	FollowLive     = "live"     // history is replayed; blocks from here on are new
+)
+
+// FollowMessage is one message of the /follow stream
+type FollowMessage struct {
+	Type   string            `json:"type"`
+	Height int               `json:"height"` // the block's index, the last block kept (-1 for none) or the tip
+	Block  *blockchain.Block `json:"block,omitempty"`
+	// Resume reconnects to /follow right after this message; empty replays
+	// the chain from genesis
+	Resume string `json:"resume,omitempty"`
+}
+
+// followCursor is the last block a follower holds; height -1 for none
+type followCursor struct {
+	height int
+	hash   string
+}
+
+// token is the resume token of c
+func (c followCursor) token() string {
+	if c.height < 0 {
+		return ""
+	}
+	return strconv.Itoa(c.height) + ":" + c.hash
+}
+
+// parseResume reads a resume token
+func parseResume(token string) (followCursor, error) {
+	h, hash, ok := strings.Cut(token, ":")
+	height, err := strconv.Atoi(h)
+	if !ok || err != nil || height < 0 || hash == "" {
+		return followCursor{}, fmt.Errorf("invalid resume token %q", token)
+	}
+	return followCursor{height: height, hash: hash}, nil
+}
+
+// cursorAt is the cursor of a follower holding the chain up to height
+func (s *Server) cursorAt(height int) followCursor {
+	b, ok := s.chain.BlockAt(height)
+	if !ok {
+		return followCursor{height: -1}
+	}
+	return followCursor{height: b.Index, hash: b.Hash}
+}
+
+// forkPoint returns the cursor of the highest block c's chain shares with
+// the local one. Blocks the node reorganized away are walked back through
+// its side blocks; a history it never saw falls back to replaying it all.
+func (s *Server) forkPoint(c followCursor) followCursor {
+	for c.height >= 0 {
+		if b, ok := s.chain.BlockAt(c.height); ok && b.Hash == c.hash {
+			return c
+		}
+		prev, ok := s.sideBlock(c.hash)
+		if !ok {
+			break
+		}
+		c = followCursor{height: prev.Index - 1, hash: prev.PrevHash}
+	}
+	return followCursor{height: -1}
+}
+
+// followFrom sends every block after cur, rolling the follower back first
+// wherever the chain no longer holds what it was sent, and returns the new
+// cursor; ok is false once the follower is gone
+func (s *Server) followFrom(cur followCursor, send func(FollowMessage) bool) (followCursor, bool) {
+	for {
+		next, more := s.chain.BlockAt(cur.height + 1)
+		linked := cur.height < 0 || (more && next.PrevHash == cur.hash)
+		if !more && cur.height >= 0 {
+			have, ok := s.chain.BlockAt(cur.height)
+			linked = ok && have.Hash == cur.hash
+		}
+		if !linked {
+			if fork := s.forkPoint(cur); fork.height < cur.height {
+				cur = fork
+				if !send(FollowMessage{Type: FollowRollback, Height: cur.height, Resume: cur.token()}) {
+					return cur, false
+				}
+				continue
+			}
+			// the block cur names is still on the chain but no longer
+			// linked from above (a --demo tamper); carry on past it
+		}
+		if !more {
+			return cur, true
+		}
+		next = blockchain.WithSizes(next)
+		cur = followCursor{height: next.Index, hash: next.Hash}
+		if !send(FollowMessage{Type: FollowBlock, Height: next.Index, Block: &next, Resume: cur.token()}) {
+			return cur, false
+		}
+	}
+}
+
+// replay the chain from ?from_height=N (default 0) or right after
+// ?resume=<token> over a WebSocket, then stream new blocks as they land:
+// GET /follow
+func (s *Server) followHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	cur := followCursor{height: -1}
+	switch {
+	case q.Get("resume") != "" && q.Get("from_height") != "":
+		jsonHeaders(w)
+		writeError(w, http.StatusBadRequest, "give from_height or resume, not both")
+		return
+	case q.Get("resume") != "":
+		c, err := parseResume(q.Get("resume"))
+		if err != nil {
+			jsonHeaders(w)
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		cur = c
+	case q.Get("from_height") != "":
+		n, err := strconv.Atoi(q.Get("from_height"))
+		if err != nil || n < 0 || n > s.chain.Len() {
+			jsonHeaders(w)
+			writeError(w, http.StatusBadRequest, "from_height must be between 0 and "+strconv.Itoa(s.chain.Len()))
+			return
+		}
+		cur = s.cursorAt(n - 1)
+	}
+	conn, closed, err := s.upgradeWS(w, r)
+	if err != nil {
+		return // the upgrader has already replied
+	}
+	defer conn.Close()
+	send := func(m FollowMessage) bool { return conn.WriteJSON(m) == nil }
+
+	// subscribe first so no block lands unnoticed between replay and live
+	sub, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+	cur, ok := s.followFrom(cur, send)
+	if !ok || !send(FollowMessage{Type: FollowLive, Height: s.chain.Len() - 1, Resume: cur.token()}) {
+		return
+	}
+	for {
+		select {
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			return
+		case e := <-sub:
+			switch e.Type {
+			case events.BlockMined, events.ChainReplaced, events.ChainReset:
+				if cur, ok = s.followFrom(cur, send); !ok {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+const (
+	// maxSideBlocks bounds the off-chain blocks kept for /fork-view and /follow
+	// rollbacks; older ones are forgotten
+	maxSideBlocks = 256
+	// defaultForkDepth and maxForkDepth bound how many main-chain blocks /fork-view shows
+	defaultForkDepth = 50
+	maxForkDepth     = 1000
+)
+
+// Where a side block came from
+const (
+	SideReceived = "received" // a peer's block that lost to, or never joined, the local chain
+	SideReorged  = "reorged"  // a local block dropped when the node switched chains
+)
+
+// Fork view statuses
+const (
+	ForkMain   = "main"   // on the local chain
+	ForkStale  = "stale"  // off the chain, its parent known
+	ForkOrphan = "orphan" // off the chain, its parent unknown
+)
+
+// ForkNode is one block of a ForkView
+type ForkNode struct {
+	BlockSummary
+	PrevHash string   `json:"prev_hash"`
+	Status   string   `json:"status"`
+	Source   string   `json:"source,omitempty"` // for side blocks: received or reorged
+	Tampered bool     `json:"tampered,omitempty"`
+	Children []string `json:"children"` // hashes of the blocks in the view building on this one
+}
+
+// ForkView is the response of /fork-view: the top of the chain and the
+// blocks that branched off it, as a tree linked by hash
+type ForkView struct {
+	Height int        `json:"height"`
+	Tip    string     `json:"tip"`
+	Roots  []string   `json:"roots"` // blocks whose parent is outside the view
+	Nodes  []ForkNode `json:"nodes"` // main chain first, lowest first, then side blocks as seen
+}
+
+// sideBlock is a block seen off the local chain
+type sideBlock struct {
+	blockchain.Block
+	source string
+}
+
+// recordSideBlocks remembers blocks that aren't, or are no longer, on the
+// local chain, for /fork-view and /follow; blocks already kept are skipped
+func (s *Server) recordSideBlocks(source string, blocks ...blockchain.Block) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+next:
+	for _, b := range blocks {
+		for _, have := range s.sideBlocks {
+			if have.Hash == b.Hash {
+				continue next
+			}
+		}
+		s.sideBlocks = append(s.sideBlocks, sideBlock{Block: b, source: source})
+	}
+	if len(s.sideBlocks) > maxSideBlocks {
+		s.sideBlocks = append([]sideBlock(nil), s.sideBlocks[len(s.sideBlocks)-maxSideBlocks:]...)
+	}
+}
+
+// sideBlock returns the remembered side block with the given hash
+func (s *Server) sideBlock(hash string) (blockchain.Block, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range s.sideBlocks {
+		if b.Hash == hash {
+			return b.Block, true
+		}
+	}
+	return blockchain.Block{}, false
+}
+
+// ForkView returns the top depth blocks of the chain and every remembered
+// side block above them, so forks and stale blocks can be drawn as a tree
+func (s *Server) ForkView(depth int) ForkView {
+	if depth <= 0 {
+		depth = defaultForkDepth
+	}
+	tampered := map[int]bool{}
+	for _, i := range s.chain.Tampered() {
+		tampered[i] = true
+	}
+	blocks := s.chain.Blocks()
+	tip := blocks[len(blocks)-1]
+	from := len(blocks) - depth
+	if from < 0 {
+		from = 0
+	}
+	out := ForkView{Height: tip.Index, Tip: tip.Hash, Roots: []string{}, Nodes: []ForkNode{}}
+	pos := map[string]int{}
+	add := func(b blockchain.Block, status, source string) {
+		pos[b.Hash] = len(out.Nodes)
+		out.Nodes = append(out.Nodes, ForkNode{
+			BlockSummary: blockSummary(b),
+			PrevHash:     b.PrevHash,
+			Status:       status,
+			Source:       source,
+			Children:     []string{},
+		})
+	}
+	for _, b := range blocks[from:] {
+		add(b, ForkMain, "")
+		out.Nodes[len(out.Nodes)-1].Tampered = tampered[b.Index]
+	}
+
+	s.mu.Lock()
+	side := append([]sideBlock(nil), s.sideBlocks...)
+	s.mu.Unlock()
+	for _, b := range side {
+		if _, ok := pos[b.Hash]; ok || b.Index < from {
+			continue
+		}
+		if have, ok := s.chain.BlockAt(b.Index); ok && have.Hash == b.Hash {
+			continue // on the chain below the view
+		}
+		add(b.Block, ForkStale, b.source)
+	}
+
+	for i, n := range out.Nodes {
+		parent, ok := pos[n.PrevHash]
+		if !ok {
+			out.Roots = append(out.Roots, n.Hash)
+			if n.Status == ForkStale {
+				if _, onChain := s.chain.BlockByHash(n.PrevHash); !onChain {
+					out.Nodes[i].Status = ForkOrphan
+				}
+			}
+			continue
+		}
+		out.Nodes[parent].Children = append(out.Nodes[parent].Children, n.Hash)
+	}
+	return out
+}
+
+// the chain and its forks as a tree: GET /fork-view?depth=N
+func (s *Server) forkViewHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	depth := defaultForkDepth
+	if v := r.URL.Query().Get("depth"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxForkDepth {
+			writeError(w, http.StatusBadRequest, "depth must be between 1 and "+strconv.Itoa(maxForkDepth))
+			return
+		}
+		depth = n
+	}
+	json.NewEncoder(w).Encode(s.ForkView(depth))
+}
@@ -0,0 +1,626 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/events"
+	"salmanahmed/blockchain/pkg/mempool"
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+// jsonHeaders marks the response as JSON (CORS is handled by the cors middleware)
+func jsonHeaders(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+}
+
+// maxBodyBytes caps request bodies from untrusted clients and peers
+const maxBodyBytes = 1 << 20
+
+// decodeJSON reads a single JSON value from a size-limited request body
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("trailing data after JSON body")
+	}
+	return nil
+}
+
+// decodeBlock reads a block from the request body, binary when sent as
+// blockchain.BinaryMediaType and JSON otherwise
+func decodeBlock(w http.ResponseWriter, r *http.Request) (blockchain.Block, error) {
+	if r.Header.Get("Content-Type") != blockchain.BinaryMediaType {
+		var b blockchain.Block
+		err := decodeJSON(w, r, &b)
+		return b, err
+	}
+	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		return blockchain.Block{}, err
+	}
+	return blockchain.DecodeBlock(raw)
+}
+
+// wantBinary reports whether the request asks for ?format=binary rather
+// than JSON; ok is false, with a 400 written, for an unknown format
+func wantBinary(w http.ResponseWriter, r *http.Request) (binary, ok bool) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		return false, true
+	case "binary":
+		return true, true
+	default:
+		writeError(w, http.StatusBadRequest, "unknown format "+format+" (want json or binary)")
+		return false, false
+	}
+}
+
+// writeError writes {"error": msg} with status
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// writeChainError maps chain errors onto HTTP status codes
+func writeChainError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, ErrBusy):
+		w.Header().Set("Retry-After", "1")
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, ErrUnhealthy), errors.Is(err, mempool.ErrOrphanPoolFull), errors.Is(err, ErrFrozen):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, ErrAlreadyConfirmed), errors.Is(err, mempool.ErrConflict), errors.Is(err, mempool.ErrDuplicate),
+		errors.Is(err, ErrFinalized):
+		status = http.StatusConflict
+	case errors.Is(err, ErrSubmissionsClosed):
+		status = http.StatusForbidden
+	case errors.Is(err, ErrQuotaExceeded):
+		status = http.StatusTooManyRequests
+	case errors.Is(err, blockchain.ErrNoCommitment):
+		status = http.StatusNotFound
+	}
+	writeError(w, status, err.Error())
+}
+
+// getBlocks returns full blockchain, or with
+// ?page=&limit=&from=&to=&by=&order=&miner=&producer= a page of it: GET /blocks;
+// ?format=binary sends them as framed binary blocks
+func (s *Server) getBlocksHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	binary, ok := wantBinary(w, r)
+	if !ok {
+		return
+	}
+	q, paged, err := parseBlockQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if binary {
+		// the page's blocks, or the whole chain, as framed binary blocks
+		w.Header().Set("Content-Type", blockchain.BinaryMediaType)
+		if !paged {
+			w.Header().Set("X-Total-Count", strconv.Itoa(s.chain.Len()))
+			if err := s.ExportBinary(r.Context(), w); err != nil {
+				logf(r.Context(), "block stream stopped: %v", err)
+			}
+			return
+		}
+		page := s.BlocksPage(q)
+		w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+		for _, b := range page.Blocks {
+			if err := blockchain.WriteBlock(w, b); err != nil {
+				return
+			}
+		}
+		return
+	}
+	if paged {
+		json.NewEncoder(w).Encode(s.BlocksPage(q))
+		return
+	}
+	blocks := s.chain.Blocks()
+	for i := range blocks {
+		blocks[i] = blockchain.WithSizes(blocks[i])
+		blocks[i].Confirmations = len(blocks) - i
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(blocks)))
+	json.NewEncoder(w).Encode(blocks)
+}
+
+// a block's merkle tree level by level: GET /blocks/{index}/merkle-tree,
+// or one of its transactions by position: GET /blocks/{index}/txs/{n}
+func (s *Server) blockTreeHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	index, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/blocks/"), "/")
+	n, err := strconv.Atoi(index)
+	if err != nil || n < 0 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if pos, ok := strings.CutPrefix(rest, "txs/"); ok {
+		s.blockTxHandler(w, n, pos)
+		return
+	}
+	if rest != "merkle-tree" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	b, ok := s.chain.BlockAt(n)
+	if !ok {
+		writeError(w, http.StatusNotFound, "no block at height "+index)
+		return
+	}
+	json.NewEncoder(w).Encode(blockchain.BuildMerkleTree(s.chain.Hasher(), b.Txns))
+}
+
+// the transaction at position pos of block index
+func (s *Server) blockTxHandler(w http.ResponseWriter, index int, pos string) {
+	n, err := strconv.Atoi(pos)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid transaction position")
+		return
+	}
+	m, ok := s.chain.TxAt(index, n)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no transaction %d in block %d", n, index))
+		return
+	}
+	m.Transaction.Size = blockchain.TxSize(m.Transaction)
+	json.NewEncoder(w).Encode(m)
+}
+
+// add transaction: POST {"data":"..."} or a full transaction with inputs
+// and outputs; the id is filled in when omitted
+func (s *Server) addTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var tx blockchain.Transaction
+	if err := decodeJSON(w, r, &tx); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+	if tx.ID == "" {
+		tx = tx.Seal()
+	}
+	err := s.AddTransaction(r.Context(), tx)
+	if errors.Is(err, ErrOrphaned) {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{"status": "held as orphan until its inputs confirm", "txid": tx.ID})
+		return
+	}
+	if err != nil {
+		writeChainError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "transaction added", "txid": tx.ID})
+}
+
+// a transaction by id: DELETE /transactions/{txid} drops it from the
+// mempool (auth required), GET /transactions/{txid}/wait waits for it
+func (s *Server) transactionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		s.txWaitHandler(w, r)
+		return
+	}
+	s.requireAuth(s.dropTransactionHandler)(w, r)
+}
+
+// drop a pending transaction: DELETE /transactions/{txid}
+func (s *Server) dropTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	txid := strings.TrimPrefix(r.URL.Path, "/transactions/")
+	if txid == "" || strings.Contains(txid, "/") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	tx, err := s.DropTransaction(r.Context(), txid)
+	switch {
+	case errors.Is(err, ErrUnknownTx):
+		writeError(w, http.StatusNotFound, err.Error())
+	case err != nil:
+		writeChainError(w, err)
+	default:
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": "transaction dropped", "txid": tx.ID, "transaction": tx})
+	}
+}
+
+// mine pending transactions, credited to ?miner=<address> or else the
+// caller's API key, in the background: the 202 response names the job to
+// poll at /mine/status/{id}. ?difficulty=N mines the block at N instead of
+// the chain's difficulty, within the range the node allows. With
+// ?wait=true the request blocks until the block is mined and returns it
+// with the hashrate achieved.
+func (s *Server) mineHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	miner := r.URL.Query().Get("miner")
+	if miner != "" && !blockchain.IsAddress(miner) {
+		writeError(w, http.StatusBadRequest, "miner must be a wallet address")
+		return
+	}
+	if id := Identity(r.Context()); miner == "" && strings.HasPrefix(id, "api-key:") {
+		miner = id
+	}
+	difficulty := 0
+	if v := r.URL.Query().Get("difficulty"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d < 1 {
+			writeError(w, http.StatusBadRequest, "invalid difficulty")
+			return
+		}
+		difficulty = d
+	}
+	job, err := s.StartMining(r.Context(), miner, difficulty)
+	if err != nil {
+		writeChainError(w, err)
+		return
+	}
+	if r.URL.Query().Get("wait") != "true" {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(job)
+		return
+	}
+	job, err = s.waitMining(r.Context(), job.ID)
+	if err != nil {
+		writeChainError(w, err)
+		return
+	}
+	if job.Block == nil {
+		json.NewEncoder(w).Encode(map[string]string{"status": "no transactions to mine"})
+		return
+	}
+	mined := MinedBlock{Block: *job.Block, Nonces: job.Nonces, Hashrate: job.Hashrate, Workers: job.Workers, Elapsed: job.Elapsed}
+	mined.Confirmations = s.chain.Confirmations(mined.Index)
+	json.NewEncoder(w).Encode(mined)
+}
+
+// search transactions by id, data or address label: GET /search?q=
+func (s *Server) searchHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		writeError(w, http.StatusBadRequest, "query required")
+		return
+	}
+	matches := s.chain.Search(q)
+	// a label also finds the transactions paying its address
+	if addr, ok := s.opts.Labels.Lookup(q); ok {
+		seen := map[string]bool{}
+		for _, m := range matches {
+			seen[m.Transaction.ID] = true
+		}
+		for _, m := range s.chain.SearchAddress(addr) {
+			if !seen[m.Transaction.ID] {
+				matches = append(matches, m)
+			}
+		}
+		sort.SliceStable(matches, func(i, j int) bool {
+			if matches[i].BlockIndex != matches[j].BlockIndex {
+				return matches[i].BlockIndex < matches[j].BlockIndex
+			}
+			return matches[i].TxIndex < matches[j].TxIndex
+		})
+	}
+	s.labelMatches(matches)
+	for i := range matches {
+		matches[i].Transaction.Size = blockchain.TxSize(matches[i].Transaction)
+	}
+	json.NewEncoder(w).Encode(matches)
+}
+
+// view pending
+func (s *Server) pendingHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	pending := s.pool.All()
+	for i := range pending {
+		pending[i].Size = blockchain.TxSize(pending[i])
+	}
+	json.NewEncoder(w).Encode(pending)
+}
+
+// transactions waiting for their parents to confirm
+func (s *Server) orphansHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	json.NewEncoder(w).Encode(s.orphans.All())
+}
+
+// unspent outputs, optionally only those paying ?address=, as of
+// ?at_height=N (default the tip)
+func (s *Server) utxosHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	height, ok := atHeight(w, r)
+	if !ok {
+		return
+	}
+	utxos, err := s.chain.UTXOsAt(r.URL.Query().Get("address"), height)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(utxos)
+}
+
+// an address's balance: GET /balance/{address}?at_height=N (default the tip)
+func (s *Server) balanceHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	addr := strings.TrimPrefix(r.URL.Path, "/balance/")
+	if addr == "" {
+		writeError(w, http.StatusBadRequest, "address required")
+		return
+	}
+	height, ok := atHeight(w, r)
+	if !ok {
+		return
+	}
+	bal, err := s.chain.BalanceAt(addr, height)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	bal.Label = s.opts.Labels.Get(addr)
+	json.NewEncoder(w).Encode(bal)
+}
+
+// atHeight parses ?at_height=, -1 (the tip) when absent; it writes the
+// error and returns false when the value is invalid
+func atHeight(w http.ResponseWriter, r *http.Request) (int, bool) {
+	v := r.URL.Query().Get("at_height")
+	if v == "" {
+		return -1, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		writeError(w, http.StatusBadRequest, "invalid at_height")
+		return 0, false
+	}
+	return n, true
+}
+
+// deployed contract addresses, or one contract's code and storage with ?address=
+func (s *Server) contractsHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	addr := r.URL.Query().Get("address")
+	if addr == "" {
+		json.NewEncoder(w).Encode(s.chain.Contracts())
+		return
+	}
+	cs, ok := s.chain.Contract(addr)
+	if !ok {
+		writeError(w, http.StatusNotFound, "contract not found")
+		return
+	}
+	json.NewEncoder(w).Encode(cs)
+}
+
+// outcome of a confirmed contract transaction: ?txid=
+func (s *Server) receiptsHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	rc, ok := s.chain.Receipt(r.URL.Query().Get("txid"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "receipt not found")
+		return
+	}
+	json.NewEncoder(w).Encode(rc)
+}
+
+// a key-value store entry: GET /kv/{key}?at_height=N (default the tip)
+func (s *Server) kvHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	key := strings.TrimPrefix(r.URL.Path, "/kv/")
+	height, ok := atHeight(w, r)
+	if !ok {
+		return
+	}
+	e, err := s.chain.KV(key, height)
+	switch {
+	case errors.Is(err, blockchain.ErrKeyNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		json.NewEncoder(w).Encode(e)
+	}
+}
+
+// a commit transaction's status (GET), or reveal its payload (POST
+// {"salt":"hex","data":"..."}) at /reveal/{txid}; the reveal is checked
+// against the on-chain commitment and queued as a transaction
+func (s *Server) revealHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	txid := strings.TrimPrefix(r.URL.Path, "/reveal/")
+	switch r.Method {
+	case "GET":
+		st, ok := s.chain.RevealStatus(txid)
+		if !ok {
+			writeError(w, http.StatusNotFound, "no commitment "+txid)
+			return
+		}
+		json.NewEncoder(w).Encode(st)
+	case "POST":
+		var body struct {
+			Salt string `json:"salt"`
+			Data string `json:"data"`
+		}
+		if err := decodeJSON(w, r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid body")
+			return
+		}
+		tx := blockchain.NewRevealTx(txid, body.Salt, body.Data)
+		if err := s.AddTransaction(r.Context(), tx); err != nil {
+			writeChainError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"status": "reveal added", "txid": tx.ID})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// blocks mined, rewards and best hash per miner
+func (s *Server) leaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	board := s.chain.Leaderboard()
+	for i := range board {
+		board[i].Label = s.opts.Labels.Get(board[i].Miner)
+	}
+	json.NewEncoder(w).Encode(board)
+}
+
+// readiness: 503 once an invariant check has failed; a frozen chain still
+// serves reads, so it stays ready
+func (s *Server) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if reason := s.Unhealthy(); reason != "" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "unhealthy", "reason": reason})
+		return
+	}
+	if st := s.FreezeStatus(); st.Frozen {
+		json.NewEncoder(w).Encode(map[string]string{"status": "frozen", "reason": st.Reason})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+}
+
+// re-check every block's hash, link, proof of work and merkle root:
+// GET /validate reports the first block failing and why
+func (s *Server) validateHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	res := s.chain.Verify()
+	if !res.Valid {
+		logf(r.Context(), "chain integrity check failed at block %d: %s", *res.Block, res.Reason)
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+// generate a keypair for development: POST /wallet/new. The private key
+// travels in the response and is not kept; real wallets should generate
+// keys locally (`node wallet new`).
+func (s *Server) newWalletHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	kp, err := wallet.New()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(kp)
+}
+
+// list peers (GET) or register one: POST {"url":"http://host:port"}
+func (s *Server) peersHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	switch r.Method {
+	case "GET":
+		json.NewEncoder(w).Encode(s.peers.List())
+	case "POST":
+		if err := s.opts.Chaos.PeerDelay(r.Context()); err != nil {
+			return
+		}
+		var body struct {
+			URL string `json:"url"`
+		}
+		if err := decodeJSON(w, r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid body")
+			return
+		}
+		added, err := s.peers.Add(body.URL)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		status := "peer added"
+		if !added {
+			status = "peer already known"
+		} else {
+			s.events.Publish(events.PeerAdded, map[string]string{"url": body.URL})
+			// the new peer may already hold a longer chain
+			go s.syncInBackground(r.Context())
+		}
+		json.NewEncoder(w).Encode(map[string]string{"status": status})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// stream events as server-sent events until the client disconnects
+func (s *Server) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	jsonHeaders(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	sub, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-sub:
+			raw, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, raw)
+			flusher.Flush()
+		}
+	}
+}
+
+// push events over a WebSocket, one JSON event per message, optionally
+// only some types: GET /ws?types=tx_added,block_mined
+func (s *Server) wsHandler(w http.ResponseWriter, r *http.Request) {
+	var types map[string]bool
+	if v := r.URL.Query().Get("types"); v != "" {
+		types = map[string]bool{}
+		for _, t := range strings.Split(v, ",") {
+			types[strings.TrimSpace(t)] = true
+		}
+	}
+	conn, closed, err := s.upgradeWS(w, r)
+	if err != nil {
+		return // the upgrader has already replied
+	}
+	defer conn.Close()
+
+	sub, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			return
+		case e := <-sub:
+			if types != nil && !types[e.Type] {
+				continue
+			}
+			if err := conn.WriteJSON(e); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/mempool"
+)
+
+// newTestServer returns a server over a fresh difficulty 1 chain started
+// from genesis, with no auth and everything in memory
+func newTestServer(genesis blockchain.Block) *Server {
+	chain := blockchain.NewChainFromGenesis(genesis, &blockchain.ProofOfWork{Difficulty: 1}, blockchain.DefaultHasher())
+	return NewServer(chain, mempool.New(), Options{})
+}
+
+// FuzzAddTransactionBody posts arbitrary bodies to /transactions. Whatever
+// arrives, the node answers with JSON and a client error at worst, and a
+// transaction it accepts is the one pending under the txid it reports.
+func FuzzAddTransactionBody(f *testing.F) {
+	for _, seed := range []string{
+		`{"data":"hello"}`,
+		`{"data":"i22-0743"}`,
+		`{"id":"00","data":"wrong id"}`,
+		`{"inputs":[{"txid":"ab","index":0,"unlock":"OP_1"}],"outputs":[{"amount":1,"lock":"OP_1"}]}`,
+		`{"outputs":[{"amount":-1,"lock":""}]}`,
+		`{"data":"a"}{"data":"b"}`,
+		`{"data":1}`,
+		`null`,
+		`[]`,
+		``,
+	} {
+		f.Add([]byte(seed))
+	}
+	genesis := blockchain.NewChain(1).Tip()
+	f.Fuzz(func(t *testing.T, body []byte) {
+		s := newTestServer(genesis)
+		w := httptest.NewRecorder()
+		s.addTransactionHandler(w, httptest.NewRequest("POST", "/transactions", bytes.NewReader(body)))
+		if w.Code >= 500 {
+			t.Fatalf("status %d for %q: %s", w.Code, body, w.Body)
+		}
+		var out map[string]string
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+			t.Fatalf("status %d with a body that isn't a JSON object: %q", w.Code, w.Body)
+		}
+		if w.Code == http.StatusAccepted {
+			// held as an orphan: its inputs are unknown, so it isn't pending
+			if out["txid"] == "" || s.pool.Len() != 0 {
+				t.Fatalf("orphaned %q, yet the mempool holds %d transactions", out["txid"], s.pool.Len())
+			}
+			return
+		}
+		if w.Code != http.StatusOK {
+			if out["error"] == "" {
+				t.Fatalf("status %d without an error: %s", w.Code, w.Body)
+			}
+			if s.pool.Len() != 0 {
+				t.Fatalf("status %d, yet the mempool holds %d transactions", w.Code, s.pool.Len())
+			}
+			return
+		}
+		if ids := s.pool.IDs(); len(ids) != 1 || ids[0] != out["txid"] {
+			t.Fatalf("accepted %s, but the mempool holds %v", out["txid"], ids)
+		}
+		if err := s.pool.CheckInvariants(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// a txid is confirmed once: a record already in a block, such as the
+// genesis roll number or one mined earlier, is refused with 409
+func TestResubmittingAConfirmedRecordIsRejected(t *testing.T) {
+	s := newTestServer(blockchain.NewChain(1).Tip())
+	post := func(body string) int {
+		w := httptest.NewRecorder()
+		s.addTransactionHandler(w, httptest.NewRequest("POST", "/transactions", bytes.NewReader([]byte(body))))
+		return w.Code
+	}
+	if code := post(`{"data":"` + blockchain.GenesisTx + `"}`); code != http.StatusConflict {
+		t.Fatalf("submitting the genesis record again: status %d, want %d", code, http.StatusConflict)
+	}
+	if code := post(`{"data":"hello"}`); code != http.StatusOK {
+		t.Fatalf("submitting hello: status %d", code)
+	}
+	if code := post(`{"data":"hello"}`); code != http.StatusConflict {
+		t.Fatalf("submitting hello while it is pending: status %d, want %d", code, http.StatusConflict)
+	}
+	if _, _, err := s.MinePending(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if code := post(`{"data":"hello"}`); code != http.StatusConflict {
+		t.Fatalf("submitting hello after it was mined: status %d, want %d", code, http.StatusConflict)
+	}
+	if matches := s.chain.Search("hello"); len(matches) != 1 {
+		t.Fatalf("the chain records hello %d times, want once", len(matches))
+	}
+}
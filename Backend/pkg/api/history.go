@@ -0,0 +1,146 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/clock"
+)
+
+// maxPendingHistory bounds the mempool departures kept for /mempool/history;
+// older ones are forgotten
+const maxPendingHistory = 1000
+
+// Why a transaction left the mempool
+const (
+	PendingConfirmed = "confirmed" // included in a block, mined here or received
+	PendingExpired   = "expired"   // past its expires_at_height, or pending longer than the node keeps transactions
+	PendingDropped   = "dropped"   // removed on request, or gone from a replica's upstream
+	PendingInvalid   = "invalid"   // no longer valid on top of the tip when a block was built
+	PendingConflict  = "conflict"  // its input was spent by a transaction accepted meanwhile
+	PendingCleared   = "cleared"   // the chain was reset or restored from a snapshot
+)
+
+// PendingRecord is one transaction that left the mempool
+type PendingRecord struct {
+	TxID    string `json:"txid"`
+	Outcome string `json:"outcome"`
+	Reason  string `json:"reason,omitempty"`
+	Block   int    `json:"block,omitempty"` // the block that confirmed it
+	Added   int64  `json:"added,omitempty"` // unix seconds it entered the mempool, if this node saw it
+	Removed int64  `json:"removed"`         // unix seconds it left
+}
+
+// MempoolHistory is the response of /mempool/history
+type MempoolHistory struct {
+	Count   int             `json:"count"`   // departures since start, including forgotten ones
+	Records []PendingRecord `json:"records"` // newest first, at most maxPendingHistory
+}
+
+// pendingHistory remembers when pending transactions arrived and, once
+// they leave, what became of them
+type pendingHistory struct {
+	since   map[string]int64 // txid -> unix seconds it entered the mempool
+	records []PendingRecord  // oldest first
+	count   int
+}
+
+// pendingAdded notes when txs entered the mempool; a transaction requeued
+// after a failed mining attempt keeps its first arrival
+func (s *Server) pendingAdded(txs ...blockchain.Transaction) {
+	now := clock.Or(s.opts.Clock).Now().Unix()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := &s.history
+	if h.since == nil {
+		h.since = map[string]int64{}
+	}
+	for _, tx := range txs {
+		if _, ok := h.since[tx.ID]; !ok {
+			h.since[tx.ID] = now
+		}
+	}
+}
+
+// pendingLeft records that txs left the mempool with outcome; confirmed
+// ones are looked up on the chain for their block
+func (s *Server) pendingLeft(outcome, reason string, txs ...blockchain.Transaction) {
+	if len(txs) == 0 {
+		return
+	}
+	now := clock.Or(s.opts.Clock).Now().Unix()
+	blocks := make([]int, len(txs))
+	if outcome == PendingConfirmed {
+		for i, tx := range txs {
+			blocks[i], _ = s.chain.HasTx(tx.ID)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := &s.history
+	seen := map[string]bool{}
+	for i, tx := range txs {
+		if seen[tx.ID] {
+			continue
+		}
+		seen[tx.ID] = true
+		h.records = append(h.records, PendingRecord{
+			TxID:    tx.ID,
+			Outcome: outcome,
+			Reason:  reason,
+			Block:   blocks[i],
+			Added:   h.since[tx.ID],
+			Removed: now,
+		})
+		delete(h.since, tx.ID)
+		h.count++
+	}
+	if len(h.records) > maxPendingHistory {
+		h.records = append([]PendingRecord(nil), h.records[len(h.records)-maxPendingHistory:]...)
+	}
+}
+
+// PendingHistory returns the latest limit transactions to leave the mempool
+// (all kept when limit is 0), newest first, only those with txid or outcome
+// when given
+func (s *Server) PendingHistory(txid, outcome string, limit int) MempoolHistory {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := MempoolHistory{Count: s.history.count, Records: []PendingRecord{}}
+	for i := len(s.history.records) - 1; i >= 0 && (limit == 0 || len(out.Records) < limit); i-- {
+		r := s.history.records[i]
+		if (txid == "" || r.TxID == txid) && (outcome == "" || r.Outcome == outcome) {
+			out.Records = append(out.Records, r)
+		}
+	}
+	return out
+}
+
+// list what became of transactions that left the mempool:
+// GET /mempool/history?txid=<txid>&outcome=<outcome>&limit=N
+func (s *Server) mempoolHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	q := r.URL.Query()
+	switch q.Get("outcome") {
+	case "", PendingConfirmed, PendingExpired, PendingDropped, PendingInvalid, PendingConflict, PendingCleared:
+	default:
+		writeError(w, http.StatusBadRequest, "unknown outcome "+strconv.Quote(q.Get("outcome")))
+		return
+	}
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+	json.NewEncoder(w).Encode(s.PendingHistory(q.Get("txid"), q.Get("outcome"), limit))
+}
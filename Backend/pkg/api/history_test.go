@@ -0,0 +1,177 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/clock"
+	"salmanahmed/blockchain/pkg/mempool"
+	"salmanahmed/blockchain/pkg/script"
+)
+
+func TestPendingHistoryRecordsWhatLeftTheMempool(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewMock(time.Unix(1000, 0))
+	s := newTestServer(blockchain.NewChain(1).Tip())
+	s.opts.Clock = clk
+	s.opts.MaxBlockTxns = 1
+	for i := 0; i < 3; i++ {
+		if err := s.AddTransaction(ctx, record(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clk.Advance(time.Minute)
+	if _, err := s.DropTransaction(ctx, record(1).ID); err != nil {
+		t.Fatal(err)
+	}
+	mined, _, err := s.MinePending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := s.PendingHistory("", "", 0)
+	if h.Count != 2 || len(h.Records) != 2 {
+		t.Fatalf("history holds %d of %d records, want 2 of 2", len(h.Records), h.Count)
+	}
+	confirmed, dropped := h.Records[0], h.Records[1]
+	if confirmed.TxID != record(0).ID || confirmed.Outcome != PendingConfirmed || confirmed.Block != mined.Index {
+		t.Errorf("newest record %+v, want record 0 confirmed in block %d", confirmed, mined.Index)
+	}
+	if dropped.TxID != record(1).ID || dropped.Outcome != PendingDropped {
+		t.Errorf("oldest record %+v, want record 1 dropped", dropped)
+	}
+	for _, r := range h.Records {
+		if r.Added != 1000 || r.Removed != 1060 {
+			t.Errorf("%s added at %d and removed at %d, want 1000 and 1060", r.TxID, r.Added, r.Removed)
+		}
+	}
+
+	// record 2 was left out of the full block and is still pending
+	if got := s.PendingHistory(record(2).ID, "", 0); len(got.Records) != 0 {
+		t.Errorf("a transaction still pending has history %+v", got.Records)
+	}
+	if got := s.PendingHistory("", PendingDropped, 0); len(got.Records) != 1 || got.Records[0].TxID != record(1).ID {
+		t.Errorf("filtering on the dropped outcome gives %+v", got.Records)
+	}
+	if got := s.PendingHistory("", "", 1); len(got.Records) != 1 || got.Records[0].TxID != record(0).ID || got.Count != 2 {
+		t.Errorf("the latest record gives %+v of %d", got.Records, got.Count)
+	}
+}
+
+func TestPendingHistoryIsBounded(t *testing.T) {
+	s := newTestServer(blockchain.NewChain(1).Tip())
+	n := maxPendingHistory + 10
+	for i := 0; i < n; i++ {
+		s.pendingLeft(PendingDropped, "", blockchain.NewDataTx(fmt.Sprintf("tx-%d", i)))
+	}
+	h := s.PendingHistory("", "", 0)
+	if h.Count != n || len(h.Records) != maxPendingHistory {
+		t.Fatalf("history holds %d of %d records, want %d of %d", len(h.Records), h.Count, maxPendingHistory, n)
+	}
+	if newest := blockchain.NewDataTx(fmt.Sprintf("tx-%d", n-1)).ID; h.Records[0].TxID != newest {
+		t.Fatalf("newest record is %s, want %s", h.Records[0].TxID, newest)
+	}
+	if oldest := blockchain.NewDataTx("tx-10").ID; h.Records[len(h.Records)-1].TxID != oldest {
+		t.Fatalf("oldest record kept is %s, want %s", h.Records[len(h.Records)-1].TxID, oldest)
+	}
+}
+
+// hookClock runs hook, once, the next time it is read
+type hookClock struct {
+	mu   sync.Mutex
+	hook func()
+}
+
+func (c *hookClock) Now() time.Time {
+	c.mu.Lock()
+	hook := c.hook
+	c.hook = nil
+	c.mu.Unlock()
+	if hook != nil {
+		hook()
+	}
+	return clock.Real.Now()
+}
+
+// a transaction left for the next block by max_block_txns goes back to the
+// mempool, even when a spend of its input arrives while the block is built,
+// and its history records only the block that finally confirms it
+func TestBlockCutKeepsTheRestPending(t *testing.T) {
+	ctx := context.Background()
+	clk := &hookClock{}
+	chain := blockchain.NewChainFromGenesis(blockchain.NewChain(1).Tip(), &blockchain.ProofOfWork{Difficulty: 1}, blockchain.DefaultHasher())
+	s := NewServer(chain, mempool.New(), Options{Clock: clk, MaxBlockTxns: 1})
+	preimage := []byte("open sesame")
+	hash := sha256.Sum256(preimage)
+	lock := script.HashLock(hex.EncodeToString(hash[:]))
+	issue := blockchain.Transaction{Outputs: []blockchain.TxOutput{{Amount: 100, Lock: lock}, {Amount: 100, Lock: lock}}}.Seal()
+	if err := s.AddTransaction(ctx, issue); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.MinePending(ctx); err != nil {
+		t.Fatal(err)
+	}
+	spend := func(index int, amount int64) blockchain.Transaction {
+		return blockchain.Transaction{
+			Inputs:  []blockchain.TxInput{{TxID: issue.ID, Index: index, Unlock: script.HashLockUnlock(preimage)}},
+			Outputs: []blockchain.TxOutput{{Amount: amount, Lock: lock}},
+		}.Seal()
+	}
+	first, left, rival := spend(0, 90), spend(1, 95), spend(1, 99)
+	doomed := blockchain.NewDataTx("doomed")
+	for _, tx := range []blockchain.Transaction{first, left, doomed} {
+		if err := s.AddTransaction(ctx, tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// doomed turns invalid, and while the block is built, once the mempool
+	// has been drained, the rival of the transaction left out is submitted
+	chain.RegisterTxValidator("doom", func(tx blockchain.Transaction) error {
+		if tx.ID == doomed.ID {
+			return errors.New("doomed")
+		}
+		return nil
+	})
+	submitted := make(chan error, 1)
+	clk.hook = func() {
+		go func() { submitted <- s.AddTransaction(ctx, rival) }()
+		select {
+		case err := <-submitted:
+			submitted <- err
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	b, ok, err := s.MinePending(ctx)
+	if err != nil || !ok {
+		t.Fatalf("mining: %v", err)
+	}
+	if len(b.Txns) != 1 || b.Txns[0].ID != first.ID {
+		t.Fatalf("block %d holds %d transactions, want only %s", b.Index, len(b.Txns), first.ID)
+	}
+	if err := <-submitted; !errors.Is(err, mempool.ErrConflict) {
+		t.Fatalf("submitting a rival of the pending %s: %v, want ErrConflict", left.ID, err)
+	}
+	if ids := s.pool.IDs(); len(ids) != 1 || ids[0] != left.ID {
+		t.Fatalf("pending %v, want %s, left for the next block", ids, left.ID)
+	}
+	if h := s.PendingHistory(left.ID, "", 0); len(h.Records) != 0 {
+		t.Fatalf("%s is still pending, yet its history holds %+v", left.ID, h.Records)
+	}
+
+	next, _, err := s.MinePending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := s.PendingHistory(left.ID, "", 0)
+	if len(h.Records) != 1 || h.Records[0].Outcome != PendingConfirmed || h.Records[0].Block != next.Index {
+		t.Fatalf("history of %s: %+v, want confirmed in block %d", left.ID, h.Records, next.Index)
+	}
+}
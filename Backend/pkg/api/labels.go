@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/labels"
+	"salmanahmed/blockchain/pkg/script"
+)
+
+// Labels returns the node's address label registry
+func (s *Server) Labels() *labels.Registry {
+	return s.opts.Labels
+}
+
+// labelMatches names the labelled addresses each match pays
+func (s *Server) labelMatches(matches []blockchain.TxMatch) {
+	for i := range matches {
+		for _, o := range matches[i].Transaction.Outputs {
+			addr := script.Address(o.Lock)
+			if addr == "" {
+				continue
+			}
+			if l := s.opts.Labels.Get(addr); l != "" {
+				if matches[i].Labels == nil {
+					matches[i].Labels = map[string]string{}
+				}
+				matches[i].Labels[addr] = l
+			}
+		}
+	}
+}
+
+// list every address label: GET /labels
+func (s *Server) labelsHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	json.NewEncoder(w).Encode(s.opts.Labels.List())
+}
+
+// view (GET), set (PUT {"label": "..."}) or remove (DELETE) an address's
+// label: /admin/labels/{address}
+func (s *Server) labelHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	addr := strings.ToLower(strings.TrimPrefix(r.URL.Path, "/admin/labels/"))
+	if !blockchain.IsAddress(addr) {
+		writeError(w, http.StatusBadRequest, "invalid address")
+		return
+	}
+	switch r.Method {
+	case "GET":
+		l := s.opts.Labels.Get(addr)
+		if l == "" {
+			writeError(w, http.StatusNotFound, "address has no label")
+			return
+		}
+		json.NewEncoder(w).Encode(labels.Label{Address: addr, Label: l})
+	case "PUT":
+		var body struct {
+			Label string `json:"label"`
+		}
+		if err := decodeJSON(w, r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid body")
+			return
+		}
+		err := s.opts.Labels.Set(addr, body.Label)
+		switch {
+		case errors.Is(err, labels.ErrTaken):
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		case err != nil:
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		logf(r.Context(), "AUDIT labelled %s %q", addr, s.opts.Labels.Get(addr))
+		json.NewEncoder(w).Encode(labels.Label{Address: addr, Label: s.opts.Labels.Get(addr)})
+	case "DELETE":
+		ok, err := s.opts.Labels.Delete(addr)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !ok {
+			writeError(w, http.StatusNotFound, "address has no label")
+			return
+		}
+		logf(r.Context(), "AUDIT removed the label of %s", addr)
+		json.NewEncoder(w).Encode(map[string]string{"status": "label removed", "address": addr})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+// ExportLedger passes the chain's double-entry journal to emit one entry at
+// a time, genesis first, with running balances filled in. A non-empty
+// account limits it to that account's entries.
+func (s *Server) ExportLedger(ctx context.Context, account string, emit func(blockchain.LedgerEntry) error) error {
+	balances := map[string]int64{}
+	for i := 0; ; i++ {
+		entries, ok := s.chain.BlockLedger(i)
+		if !ok {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for _, e := range entries {
+			balances[e.Account] += e.Credit - e.Debit
+			e.Balance = balances[e.Account]
+			if account != "" && e.Account != account {
+				continue
+			}
+			if err := emit(e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// stream the double-entry journal: GET /export/ledger?format=json (a single
+// array, the default) or format=csv, optionally only ?account= (an address
+// or one of coinbase, issuance, fees and nonstandard)
+func (s *Server) ledgerHandler(w http.ResponseWriter, r *http.Request) {
+	account := r.URL.Query().Get("account")
+	var err error
+	switch format := r.URL.Query().Get("format"); format {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"block", "account", "debit", "credit", "balance"})
+		err = s.ExportLedger(r.Context(), account, func(e blockchain.LedgerEntry) error {
+			return cw.Write([]string{
+				strconv.Itoa(e.Block), e.Account,
+				strconv.FormatInt(e.Debit, 10), strconv.FormatInt(e.Credit, 10), strconv.FormatInt(e.Balance, 10),
+			})
+		})
+		cw.Flush()
+	case "", "json":
+		jsonHeaders(w)
+		io.WriteString(w, "[")
+		sep := &arrayWriter{w: w}
+		enc := json.NewEncoder(sep)
+		err = s.ExportLedger(r.Context(), account, func(e blockchain.LedgerEntry) error {
+			return enc.Encode(e)
+		})
+		io.WriteString(w, "]\n")
+	default:
+		jsonHeaders(w)
+		writeError(w, http.StatusBadRequest, "unknown format "+format+" (want json or csv)")
+		return
+	}
+	if err != nil {
+		logf(r.Context(), "ledger export stopped: %v", err)
+	}
+}
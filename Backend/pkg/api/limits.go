@@ -0,0 +1,131 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Request classes limited separately, so a burst of one can't starve the others
+const (
+	ClassRead  = "read"  // everything not below, writes such as POST /transactions included
+	ClassMine  = "mine"  // mining jobs and /admin/simulate
+	ClassAdmin = "admin" // /admin/*, /import, /peers/sync and hosted chain management
+)
+
+// ErrBusy is returned when a request class is at its concurrency limit
+var ErrBusy = errors.New("server busy")
+
+// ClassLimit bounds one request class; zero values leave it unbounded
+type ClassLimit struct {
+	Concurrency int           // requests (or mining jobs) in progress at once
+	Timeout     time.Duration // before a request is answered 503, or a mining job fails
+}
+
+// LimitOptions holds the limit of each request class
+type LimitOptions struct {
+	Read  ClassLimit
+	Mine  ClassLimit
+	Admin ClassLimit
+}
+
+// Limits admits requests per class. One is shared by every chain a node
+// hosts, so the limits hold node-wide.
+type Limits struct {
+	opts  LimitOptions
+	slots map[string]chan struct{} // nil for unbounded classes
+}
+
+// NewLimits returns limits enforcing opts
+func NewLimits(opts LimitOptions) *Limits {
+	l := &Limits{opts: opts, slots: map[string]chan struct{}{}}
+	for class, c := range map[string]ClassLimit{ClassRead: opts.Read, ClassMine: opts.Mine, ClassAdmin: opts.Admin} {
+		if c.Concurrency > 0 {
+			l.slots[class] = make(chan struct{}, c.Concurrency)
+		}
+	}
+	return l
+}
+
+// limit returns class's limit; a nil Limits bounds nothing
+func (l *Limits) limit(class string) ClassLimit {
+	if l == nil {
+		return ClassLimit{}
+	}
+	switch class {
+	case ClassRead:
+		return l.opts.Read
+	case ClassMine:
+		return l.opts.Mine
+	case ClassAdmin:
+		return l.opts.Admin
+	}
+	return ClassLimit{}
+}
+
+// acquire takes a slot of class without waiting, returning the function
+// that frees it
+func (l *Limits) acquire(class string) (release func(), err error) {
+	if l == nil || l.slots[class] == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.slots[class] <- struct{}{}:
+		return func() { <-l.slots[class] }, nil
+	default:
+		return nil, fmt.Errorf("%w: the %s limit of %d at once is reached", ErrBusy, class, cap(l.slots[class]))
+	}
+}
+
+// requestClass returns the class r counts against, or "" for requests left
+// unlimited: streams, which stay open by design, and POST /mine, whose
+// mining job holds the slot instead
+func requestClass(r *http.Request) string {
+	p := r.URL.Path
+	switch {
+	case p == "/events", p == "/export", p == "/export/ledger", strings.HasSuffix(p, "/ws"), strings.HasSuffix(p, "/follow"),
+		strings.HasPrefix(p, "/transactions/") && strings.HasSuffix(p, "/wait"):
+		return ""
+	case p == "/mine":
+		return ""
+	case p == "/admin/simulate":
+		return ClassMine
+	case strings.HasPrefix(p, "/admin/"), p == "/import", p == "/peers/sync", p == "/chains":
+		return ClassAdmin
+	}
+	return ClassRead
+}
+
+// limit answers 503 at once when r's class is at its concurrency limit,
+// and 503 once the class's timeout passes
+func (s *Server) limit(next http.Handler) http.Handler {
+	l := s.opts.Limits
+	if l == nil {
+		return next
+	}
+	timeouts := map[string]http.Handler{}
+	for _, class := range []string{ClassRead, ClassMine, ClassAdmin} {
+		timeouts[class] = next
+		if d := l.limit(class).Timeout; d > 0 {
+			msg := fmt.Sprintf(`{"error":"%s request timed out after %s"}`, class, d)
+			timeouts[class] = http.TimeoutHandler(next, d, msg)
+		}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class := requestClass(r)
+		if class == "" || r.Method == "OPTIONS" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		release, err := l.acquire(class)
+		if err != nil {
+			jsonHeaders(w)
+			writeChainError(w, err)
+			return
+		}
+		defer release()
+		timeouts[class].ServeHTTP(w, r)
+	})
+}
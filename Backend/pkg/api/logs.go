@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/websocket"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/events"
+)
+
+// logFilter reads ?address=&event=&from_block=&to_block=
+func logFilter(r *http.Request) (blockchain.LogFilter, bool) {
+	q := r.URL.Query()
+	f := blockchain.LogFilter{Address: q.Get("address"), Event: q.Get("event")}
+	for name, dst := range map[string]*int{"from_block": &f.FromBlock, "to_block": &f.ToBlock} {
+		if v := q.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return f, false
+			}
+			*dst = n
+		}
+	}
+	return f, true
+}
+
+// confirmed logs matching the query filter
+func (s *Server) logsHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	f, ok := logFilter(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid block range")
+		return
+	}
+	json.NewEncoder(w).Encode(s.chain.Logs(f))
+}
+
+// stream newly confirmed logs matching the query filter over a WebSocket,
+// one JSON log per message
+func (s *Server) logsWSHandler(w http.ResponseWriter, r *http.Request) {
+	f, ok := logFilter(r)
+	if !ok {
+		jsonHeaders(w)
+		writeError(w, http.StatusBadRequest, "invalid block range")
+		return
+	}
+	conn, closed, err := s.upgradeWS(w, r)
+	if err != nil {
+		return // the upgrader has already replied
+	}
+	defer conn.Close()
+
+	sub, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			return
+		case e := <-sub:
+			if e.Type != events.Logs {
+				continue
+			}
+			logs, _ := e.Data.([]blockchain.Log)
+			for _, l := range logs {
+				if !f.Match(l) {
+					continue
+				}
+				if err := conn.WriteJSON(l); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// upgradeWS accepts a WebSocket from an allowed origin. The client is not
+// expected to send anything; closed is closed once it goes away.
+func (s *Server) upgradeWS(w http.ResponseWriter, r *http.Request) (conn *websocket.Conn, closed <-chan struct{}, err error) {
+	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || s.allowedOrigin(origin) != ""
+	}}
+	conn, err = upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	return conn, done, nil
+}
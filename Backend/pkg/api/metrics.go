@@ -0,0 +1,58 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// metricsContentType is the Prometheus text exposition format
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// metric writes one Prometheus metric family with a single unlabelled sample
+func metric(w io.Writer, name, kind, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", name, help, name, kind, name, formatSample(value))
+}
+
+// formatSample formats a sample value the way Prometheus parses it
+func formatSample(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// WriteMetrics writes what /stats reports, and the node's mining totals,
+// as Prometheus gauges and counters
+func (s *Server) WriteMetrics(w io.Writer) {
+	st := s.Stats()
+	hashes, _, _, _ := s.mining.totals()
+	mining := 0.0
+	if st.Mining {
+		mining = 1
+	}
+	metric(w, "blockchain_height", "gauge", "Index of the tip block.", float64(st.Height))
+	metric(w, "blockchain_transactions_total", "counter", "Confirmed transactions, coinbases and genesis included.", float64(st.Transactions))
+	metric(w, "blockchain_difficulty", "gauge", "Leading zero hex digits the next block's hash needs.", float64(st.Difficulty))
+
+	const interval = "blockchain_block_interval_seconds"
+	fmt.Fprintf(w, "# HELP %s Average seconds between block timestamps over the last window blocks.\n# TYPE %s gauge\n", interval, interval)
+	for _, n := range statsWindows {
+		window := "all"
+		if n > 0 {
+			window = strconv.Itoa(n)
+		}
+		fmt.Fprintf(w, "%s{window=%q} %s\n", interval, window, formatSample(s.chain.BlockIntervals(n).Avg))
+	}
+
+	metric(w, "blockchain_mempool_transactions", "gauge", "Transactions waiting to be mined.", float64(st.Pending))
+	metric(w, "blockchain_mempool_bytes", "gauge", "Encoded size of the transactions waiting to be mined.", float64(st.PendingBytes))
+	metric(w, "blockchain_mining", "gauge", "1 while the node is searching for a block.", mining)
+	metric(w, "blockchain_last_hashrate", "gauge", "Hashes per second of the last block this node mined.", st.Hashrate)
+	metric(w, "blockchain_mined_blocks_total", "counter", "Blocks this node has mined since it started.", float64(st.MinedBlocks))
+	metric(w, "blockchain_hashes_total", "counter", "Hashes this node has tried in searches that found a block.", float64(hashes))
+}
+
+// the node's statistics for Prometheus to scrape: GET /metrics
+func (s *Server) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metricsContentType)
+	s.WriteMetrics(w)
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"salmanahmed/blockchain/pkg/clock"
+)
+
+// maxTrackedIPs bounds the clients limitIPs keeps a bucket for; past it the
+// buckets start over
+const maxTrackedIPs = 10000
+
+// cors sets CORS headers for allowed origins and answers preflight requests
+func (s *Server) cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := s.allowedOrigin(r.Header.Get("Origin")); origin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			if origin != "*" {
+				w.Header().Add("Vary", "Origin")
+			}
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Expose-Headers", "X-Total-Count")
+		}
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedOrigin returns the Access-Control-Allow-Origin value for origin, or "" if not allowed
+func (s *Server) allowedOrigin(origin string) string {
+	if len(s.opts.CORSOrigins) == 0 {
+		return "*"
+	}
+	for _, o := range s.opts.CORSOrigins {
+		if o == "*" {
+			return "*"
+		}
+		if origin != "" && strings.EqualFold(o, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// requireAuth rejects non-GET requests without the configured bearer token
+// or one of the chain policy's API keys
+func (s *Server) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keyed := len(s.Policy().APIKeys) > 0
+		if (s.opts.AuthToken == "" && !keyed) || r.Method == "GET" {
+			next(w, r)
+			return
+		}
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		identity := ""
+		switch {
+		case s.opts.AuthToken != "" && subtle.ConstantTimeCompare([]byte(got), []byte(s.opts.AuthToken)) == 1:
+			identity = "token"
+		case keyed && s.apiKeyValid(got):
+			identity = keyIdentity(got)
+		default:
+			logf(r.Context(), "%s %s rejected: bad or missing token", r.Method, r.URL.Path)
+			jsonHeaders(w)
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next(w, r.WithContext(withIdentity(r.Context(), identity)))
+	}
+}
+
+// requireNode rejects non-GET requests without the node token: API keys
+// may submit and mine but not rewrite the chain. A node with neither a
+// token nor keys stays open, as under requireAuth.
+func (s *Server) requireNode(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if (s.opts.AuthToken == "" && len(s.Policy().APIKeys) == 0) || r.Method == "GET" {
+			next(w, r)
+			return
+		}
+		if !s.isNodeToken(r) {
+			logf(r.Context(), "%s %s rejected: node token required", r.Method, r.URL.Path)
+			jsonHeaders(w)
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next(w, r.WithContext(withIdentity(r.Context(), "token")))
+	}
+}
+
+// requireAdmin rejects any request, reads included, without the node token
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.opts.AuthToken == "" {
+			next(w, r)
+			return
+		}
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(s.opts.AuthToken)) != 1 {
+			logf(r.Context(), "%s %s rejected: admin token required", r.Method, r.URL.Path)
+			jsonHeaders(w)
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next(w, r.WithContext(withIdentity(r.Context(), "token")))
+	}
+}
+
+// clientIP returns the address r came from, without its port
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// isNodeToken reports whether r carries the node's bearer token
+func (s *Server) isNodeToken(r *http.Request) bool {
+	got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return s.opts.AuthToken != "" && subtle.ConstantTimeCompare([]byte(got), []byte(s.opts.AuthToken)) == 1
+}
+
+// ipLimits holds a request bucket per client IP
+type ipLimits struct {
+	mu      sync.Mutex
+	buckets map[string]*rateLimiter
+}
+
+// bucket returns ip's bucket, creating it if needed
+func (l *ipLimits) bucket(ip string) *rateLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.buckets[ip]
+	if b == nil {
+		if l.buckets == nil || len(l.buckets) >= maxTrackedIPs {
+			l.buckets = map[string]*rateLimiter{}
+		}
+		b = &rateLimiter{}
+		l.buckets[ip] = b
+	}
+	return b
+}
+
+// limitIPs answers 429 to a client IP past RatePerIP requests a minute;
+// the node token is never limited
+func (s *Server) limitIPs(next http.Handler) http.Handler {
+	rate := s.opts.RatePerIP
+	if rate <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" || s.isNodeToken(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ip := clientIP(r)
+		if !s.ipLimits.bucket(ip).allow(clock.Or(s.opts.Clock).Now(), rate) {
+			jsonHeaders(w)
+			w.Header().Set("Retry-After", strconv.Itoa((60+rate-1)/rate))
+			writeError(w, http.StatusTooManyRequests, fmt.Sprintf("%v: %s may make %d requests a minute", ErrQuotaExceeded, ip, rate))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminWrite reports whether r would change how the node runs or rewrite
+// its chain, rather than use it
+func adminWrite(r *http.Request) bool {
+	if r.Method == "GET" || r.Method == "HEAD" || r.Method == "OPTIONS" {
+		return false
+	}
+	p := r.URL.Path
+	return strings.HasPrefix(p, "/admin/") || p == "/import" || p == "/tamper" || p == "/chains" ||
+		p == "/peers" || p == "/peers/sync" || p == "/p2p/blocks" || strings.HasPrefix(p, "/snapshots")
+}
+
+// readOnlyAdmin answers 403 to admin writes without the node token when
+// ReadOnlyAdmin is set, leaving the admin views readable
+func (s *Server) readOnlyAdmin(next http.Handler) http.Handler {
+	if !s.opts.ReadOnlyAdmin {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if adminWrite(r) && !s.isNodeToken(r) {
+			logf(r.Context(), "%s %s rejected: admin is read-only", r.Method, r.URL.Path)
+			jsonHeaders(w)
+			writeError(w, http.StatusForbidden, "admin endpoints are read-only on this node")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,338 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/clock"
+)
+
+// Mining job states
+const (
+	JobQueued    = "queued" // waiting for the mining in progress to finish
+	JobMining    = "mining"
+	JobMined     = "mined"
+	JobEmpty     = "empty" // there was nothing to mine
+	JobCancelled = "cancelled"
+	JobFailed    = "failed"
+)
+
+// maxMineJobs is how many finished jobs are remembered for /mine/status
+const maxMineJobs = 100
+
+// ErrNoJob is returned for a mining job ID the node doesn't know
+var ErrNoJob = errors.New("no such mining job")
+
+// ErrDifficultyOverride is returned for a requested mining difficulty the
+// chain doesn't allow
+var ErrDifficultyOverride = errors.New("difficulty can't be requested")
+
+// MineJob is a block being mined in the background, as /mine/status reports it
+type MineJob struct {
+	ID         string            `json:"id"`
+	State      string            `json:"state"`
+	Miner      string            `json:"miner,omitempty"`
+	Difficulty int               `json:"difficulty,omitempty"` // requested instead of the chain's
+	Index      int               `json:"index,omitempty"`      // height of the block, once mining starts
+	Nonces     int64             `json:"nonces"`               // tried so far
+	Hashrate   float64           `json:"hashrate"`             // hashes per second
+	Workers    int               `json:"workers"`              // goroutines searching nonces in parallel
+	Elapsed    float64           `json:"elapsed_seconds"`      // since mining started
+	Created    int64             `json:"created"`              // unix seconds
+	Block      *blockchain.Block `json:"block,omitempty"`      // once mined
+	Error      string            `json:"error,omitempty"`
+}
+
+// Finished reports whether the job has stopped, one way or another
+func (j MineJob) Finished() bool {
+	return j.State != JobQueued && j.State != JobMining
+}
+
+// MinedBlock is the response of /mine?wait=true: the block and how fast
+// its nonce was found
+type MinedBlock struct {
+	blockchain.Block
+	Nonces   int64   `json:"nonces"` // tried across every worker
+	Hashrate float64 `json:"hashrate"`
+	Workers  int     `json:"workers"`
+	Elapsed  float64 `json:"elapsed_seconds"`
+}
+
+// mineJob is a MineJob and what it takes to cancel and time it
+type mineJob struct {
+	MineJob
+	cancel  context.CancelFunc
+	done    chan struct{}
+	err     error // why the job failed
+	started time.Time
+	ended   time.Time
+}
+
+// mineJobs runs mining jobs under a context that Close cancels
+type mineJobs struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu    sync.Mutex
+	jobs  map[string]*mineJob
+	order []string // oldest first
+}
+
+type mineJobKey struct{}
+
+func newMineJobs() *mineJobs {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &mineJobs{ctx: ctx, cancel: cancel, jobs: map[string]*mineJob{}}
+}
+
+// StartMining mines a block in the background as MineAt would and returns
+// the job at once; its progress is read back with MineStatus. Each job
+// holds a mine slot of the node's limits until it finishes, so ErrBusy is
+// returned when they are all taken.
+func (s *Server) StartMining(ctx context.Context, miner string, difficulty int) (MineJob, error) {
+	if err := s.checkFrozen(); err != nil {
+		return MineJob{}, err
+	}
+	if err := s.checkOverride(difficulty); err != nil {
+		return MineJob{}, err
+	}
+	release, err := s.opts.Limits.acquire(ClassMine)
+	if err != nil {
+		return MineJob{}, err
+	}
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	var cancel context.CancelFunc
+	timeout := s.opts.Limits.limit(ClassMine).Timeout
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(detach(s.jobs.ctx, ctx), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(detach(s.jobs.ctx, ctx))
+	}
+	j := &mineJob{
+		MineJob: MineJob{
+			ID:         hex.EncodeToString(buf),
+			State:      JobQueued,
+			Miner:      miner,
+			Difficulty: difficulty,
+			Workers:    s.miningWorkers(),
+			Created:    clock.Or(s.opts.Clock).Now().Unix(),
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	ctx = context.WithValue(ctx, mineJobKey{}, j)
+	ctx = blockchain.WithProgress(ctx, func(tried int64) {
+		s.jobs.mu.Lock()
+		j.Nonces = tried
+		s.jobs.mu.Unlock()
+	})
+
+	s.jobs.mu.Lock()
+	s.jobs.jobs[j.ID] = j
+	s.jobs.order = append(s.jobs.order, j.ID)
+	s.jobs.forget()
+	out := j.snapshot()
+	s.jobs.mu.Unlock()
+
+	s.jobs.wg.Add(1)
+	go func() {
+		defer s.jobs.wg.Done()
+		defer close(j.done)
+		defer release()
+		defer cancel()
+		mined, ok, err := s.MineAt(ctx, miner, difficulty)
+		s.jobs.mu.Lock()
+		defer s.jobs.mu.Unlock()
+		j.ended = time.Now()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("mining timed out after %s", timeout)
+		}
+		switch {
+		case j.State == JobCancelled:
+			// cancelled while queued
+		case errors.Is(err, context.Canceled):
+			j.State = JobCancelled
+		case err != nil:
+			j.State, j.Error, j.err = JobFailed, err.Error(), err
+		case !ok:
+			j.State = JobEmpty
+		default:
+			b := blockchain.WithSizes(mined)
+			j.State, j.Block = JobMined, &b
+			if j.Nonces == 0 {
+				j.Nonces = mined.Nonce + 1 // consensus without a nonce search
+			}
+		}
+	}()
+	return out, nil
+}
+
+// checkOverride returns ErrDifficultyOverride unless difficulty is 0, the
+// chain's, or one the chain lets blocks be mined at on request. Peers hold
+// every block to the difficulty in force at its height, so it can't be
+// below that.
+func (s *Server) checkOverride(difficulty int) error {
+	if difficulty == 0 {
+		return nil
+	}
+	r := s.chain.DifficultyOverrides()
+	required := s.chain.Difficulty()
+	switch {
+	case r.Max == 0:
+		return fmt.Errorf("%w: this chain mines only at its own difficulty", ErrDifficultyOverride)
+	case !r.Allows(difficulty):
+		return fmt.Errorf("%w: %d is outside %d-%d", ErrDifficultyOverride, difficulty, r.Min, r.Max)
+	case difficulty < required:
+		return fmt.Errorf("%w: %d is below the %d in force", ErrDifficultyOverride, difficulty, required)
+	}
+	return nil
+}
+
+// waitMining waits for a job to finish and returns it, with the error it
+// failed with if any; when ctx ends first the job is cancelled
+func (s *Server) waitMining(ctx context.Context, id string) (MineJob, error) {
+	s.jobs.mu.Lock()
+	j, ok := s.jobs.jobs[id]
+	s.jobs.mu.Unlock()
+	if !ok {
+		return MineJob{}, ErrNoJob
+	}
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+		s.CancelMining(ctx, id)
+		return MineJob{}, ctx.Err()
+	}
+	s.jobs.mu.Lock()
+	defer s.jobs.mu.Unlock()
+	return j.snapshot(), j.err
+}
+
+// miningStarted marks the job mining ctx belongs to, if any, as searching
+// for block index
+func (s *Server) miningStarted(ctx context.Context, index int) {
+	j, ok := ctx.Value(mineJobKey{}).(*mineJob)
+	if !ok {
+		return
+	}
+	s.jobs.mu.Lock()
+	defer s.jobs.mu.Unlock()
+	if j.State == JobQueued {
+		j.State, j.Index, j.started = JobMining, index, time.Now()
+	}
+}
+
+// MineStatus returns a mining job's progress
+func (s *Server) MineStatus(id string) (MineJob, error) {
+	s.jobs.mu.Lock()
+	defer s.jobs.mu.Unlock()
+	j, ok := s.jobs.jobs[id]
+	if !ok {
+		return MineJob{}, ErrNoJob
+	}
+	return j.snapshot(), nil
+}
+
+// CancelMining stops a mining job, returning its pending transactions to
+// the mempool; cancelling a finished job changes nothing
+func (s *Server) CancelMining(ctx context.Context, id string) (MineJob, error) {
+	s.jobs.mu.Lock()
+	j, ok := s.jobs.jobs[id]
+	if !ok {
+		s.jobs.mu.Unlock()
+		return MineJob{}, ErrNoJob
+	}
+	finished, queued := j.Finished(), j.State == JobQueued
+	if queued {
+		j.State, j.ended = JobCancelled, time.Now()
+	}
+	s.jobs.mu.Unlock()
+	if finished {
+		return s.MineStatus(id)
+	}
+	j.cancel()
+	if !queued {
+		// the search notices within a few thousand nonces
+		select {
+		case <-j.done:
+		case <-time.After(time.Second):
+		}
+	}
+	logf(ctx, "mining job %s cancelled", id)
+	return s.MineStatus(id)
+}
+
+// Close cancels the mining jobs in progress and waits for them, and any
+// other mining under way, to stop
+func (s *Server) Close() {
+	s.jobs.cancel()
+	s.jobs.wg.Wait()
+	// mining outside a job, e.g. by /admin/simulate, has stored its block or requeued its transactions once it lets go
+	s.mineMu.Lock()
+	s.mineMu.Unlock()
+}
+
+// snapshot returns the job as reported (caller holds mineJobs.mu)
+func (j *mineJob) snapshot() MineJob {
+	out := j.MineJob
+	if !j.started.IsZero() {
+		end := j.ended
+		if end.IsZero() {
+			end = time.Now()
+		}
+		if took := end.Sub(j.started); took > 0 {
+			out.Elapsed = took.Seconds()
+			out.Hashrate = float64(out.Nonces) / took.Seconds()
+		}
+	}
+	return out
+}
+
+// forget drops the oldest finished jobs beyond maxMineJobs (caller holds mu)
+func (m *mineJobs) forget() {
+	for len(m.order) > maxMineJobs {
+		id := m.order[0]
+		if j := m.jobs[id]; j != nil && !j.Finished() {
+			return
+		}
+		delete(m.jobs, id)
+		m.order = m.order[1:]
+	}
+}
+
+// poll a mining job: GET /mine/status/{id}
+func (s *Server) mineStatusHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	j, err := s.MineStatus(strings.TrimPrefix(r.URL.Path, "/mine/status/"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(j)
+}
+
+// abort a mining job: POST /mine/cancel/{id}
+func (s *Server) mineCancelHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	j, err := s.CancelMining(r.Context(), strings.TrimPrefix(r.URL.Path, "/mine/cancel/"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(j)
+}
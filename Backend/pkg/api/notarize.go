@@ -0,0 +1,305 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/clock"
+)
+
+// Limits on notarization
+const (
+	maxNotarizations = 1000             // kept for /notarizations; older ones are forgotten
+	notarizeTimeout  = 10 * time.Second // per target
+	maxAck           = 256              // bytes of a webhook's reply kept as its acknowledgment
+)
+
+// NotarizeOptions has the node publish its tip hash off the node, so a
+// later rewrite of its history can be shown against the copies kept there
+type NotarizeOptions struct {
+	Node    string        // another node's URL; the tip is anchored on its chain as a data transaction
+	Token   string        // bearer token for Node
+	Key     string        // hex private key signing the anchors, for a Node requiring signatures
+	Webhook string        // URL the tip is POSTed to as JSON
+	Every   time.Duration // between notarizations
+}
+
+// NotarizationStatus is a notarization as /notarizations reports it
+type NotarizationStatus struct {
+	blockchain.Notarization
+	Valid bool `json:"valid"` // the chain still holds Hash at Height
+}
+
+// Notarizations is the response of /notarizations
+type Notarizations struct {
+	Targets       []string             `json:"targets"`
+	Every         float64              `json:"every_seconds,omitempty"`
+	Rewritten     int                  `json:"rewritten"` // acknowledged blocks the chain no longer holds
+	Notarizations []NotarizationStatus `json:"notarizations"`
+}
+
+// notarizeTargets returns the configured targets, node first
+func (s *Server) notarizeTargets() []string {
+	var out []string
+	for _, t := range []string{s.opts.Notarize.Node, s.opts.Notarize.Webhook} {
+		if t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Notarize publishes the tip to the configured targets every Every until
+// ctx ends
+func (s *Server) Notarize(ctx context.Context) {
+	t := time.NewTicker(s.opts.Notarize.Every)
+	defer t.Stop()
+	for {
+		s.NotarizeTip(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// NotarizeTip publishes the tip to every target that hasn't acknowledged
+// it yet and returns what each answered. A failure is retried on the next
+// call; repeated failures for the same tip keep one record, the latest.
+func (s *Server) NotarizeTip(ctx context.Context) []blockchain.Notarization {
+	genesis, _ := s.chain.BlockAt(0)
+	tip, _ := s.chain.BlockAt(s.chain.Len() - 1)
+	out := []blockchain.Notarization{}
+	if tip.Index == 0 {
+		return out
+	}
+	for _, target := range s.notarizeTargets() {
+		if s.acknowledged(target, tip.Hash) {
+			continue
+		}
+		n := blockchain.Notarization{
+			Genesis: genesis.Hash,
+			Height:  tip.Index,
+			Hash:    tip.Hash,
+			Time:    clock.Or(s.opts.Clock).Now().Unix(),
+			Target:  target,
+		}
+		var err error
+		if target == s.opts.Notarize.Node {
+			n.Ack, err = s.anchorOnNode(ctx, n)
+		} else {
+			n.Ack, err = postNotarization(ctx, n)
+		}
+		if err != nil {
+			n.Error = err.Error()
+			logf(ctx, "notarizing block %d at %s failed: %v", n.Height, target, err)
+		} else {
+			logf(ctx, "notarized block %d (%s) at %s: %s", n.Height, n.Hash, target, n.Ack)
+		}
+		s.recordNotarization(n)
+		out = append(out, n)
+	}
+	if len(out) > 0 {
+		s.persistNotarizations(ctx)
+	}
+	return out
+}
+
+// acknowledged reports whether target acknowledged hash last time
+func (s *Server) acknowledged(target, hash string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.notarizations) - 1; i >= 0; i-- {
+		if n := s.notarizations[i]; n.Target == target {
+			return n.Hash == hash && n.Error == ""
+		}
+	}
+	return false
+}
+
+// recordNotarization keeps n, replacing its target's last record if that
+// was a failure for the same block
+func (s *Server) recordNotarization(n blockchain.Notarization) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.notarizations) - 1; i >= 0; i-- {
+		if last := s.notarizations[i]; last.Target == n.Target {
+			if last.Hash == n.Hash && last.Error != "" {
+				s.notarizations = append(s.notarizations[:i], s.notarizations[i+1:]...)
+			}
+			break
+		}
+	}
+	s.notarizations = append(s.notarizations, n)
+	if len(s.notarizations) > maxNotarizations {
+		s.notarizations = append([]blockchain.Notarization(nil), s.notarizations[len(s.notarizations)-maxNotarizations:]...)
+	}
+}
+
+// anchorOnNode submits n as a data transaction to the notary node and
+// returns its txid. A node already holding the transaction, pending or
+// confirmed, has anchored it too. With a Key the anchor is signed, afresh
+// on each attempt, so a retry after a lost reply may anchor twice.
+func (s *Server) anchorOnNode(ctx context.Context, n blockchain.Notarization) (string, error) {
+	tx := blockchain.NewDataTx(blockchain.NotarizationData(n.Genesis, n.Height, n.Hash))
+	if key := s.opts.Notarize.Key; key != "" {
+		var err error
+		if tx, err = tx.Sign(key, n.Time); err != nil {
+			return "", err
+		}
+	}
+	raw, err := json.Marshal(tx)
+	if err != nil {
+		return "", err
+	}
+	status, _, err := notarizeRequest(ctx, n.Target+"/transactions", s.opts.Notarize.Token, raw)
+	if err != nil && status != http.StatusConflict {
+		return "", err
+	}
+	return tx.ID, nil
+}
+
+// postNotarization posts n to a webhook and returns its reply
+func postNotarization(ctx context.Context, n blockchain.Notarization) (string, error) {
+	raw, err := json.Marshal(n)
+	if err != nil {
+		return "", err
+	}
+	_, reply, err := notarizeRequest(ctx, n.Target, "", raw)
+	return reply, err
+}
+
+// notarizeRequest POSTs body to url and returns the status and the start
+// of the reply, or the status line when the reply is empty
+func notarizeRequest(ctx context.Context, url, token string, body []byte) (int, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, notarizeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxAck))
+	reply := strings.TrimSpace(strings.ToValidUTF8(string(raw), "?"))
+	if reply == "" {
+		reply = resp.Status
+	}
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, reply, fmt.Errorf("%s: %s", url, reply)
+	}
+	return resp.StatusCode, reply, nil
+}
+
+// loadNotarizations restores stored notarizations, warning about blocks
+// the chain no longer holds
+func (s *Server) loadNotarizations(ctx context.Context, ns []blockchain.Notarization) {
+	s.mu.Lock()
+	s.notarizations = ns
+	s.mu.Unlock()
+	for _, n := range ns {
+		if n.Error == "" && !s.holdsNotarized(n) {
+			logf(ctx, "AUDIT block %d notarized at %s as %s is no longer on the chain", n.Height, n.Target, n.Hash)
+		}
+	}
+}
+
+// holdsNotarized reports whether the chain still holds n's block; one cut
+// back below it doesn't
+func (s *Server) holdsNotarized(n blockchain.Notarization) bool {
+	if genesis, _ := s.chain.BlockAt(0); genesis.Hash != n.Genesis {
+		return false
+	}
+	b, ok := s.chain.BlockAt(n.Height)
+	return ok && b.Hash == n.Hash
+}
+
+// persistNotarizations snapshots the notarizations to the store; a failure
+// is only logged, the next notarization writes them all again
+func (s *Server) persistNotarizations(ctx context.Context) {
+	if s.opts.Store == nil {
+		return
+	}
+	s.mu.Lock()
+	ns := append([]blockchain.Notarization(nil), s.notarizations...)
+	s.mu.Unlock()
+	if err := s.opts.Store.SaveNotarizations(ns); err != nil {
+		logf(ctx, "storing notarizations failed: %v", err)
+	}
+}
+
+// Notarizations returns the node's notarizations, only the latest limit
+// when limit is positive, each checked against the chain
+func (s *Server) Notarizations(limit int) Notarizations {
+	s.mu.Lock()
+	ns := append([]blockchain.Notarization(nil), s.notarizations...)
+	s.mu.Unlock()
+	out := Notarizations{Targets: s.notarizeTargets(), Notarizations: []NotarizationStatus{}}
+	if out.Targets == nil {
+		out.Targets = []string{}
+	} else {
+		out.Every = s.opts.Notarize.Every.Seconds()
+	}
+	for _, n := range ns {
+		if n.Error == "" && !s.holdsNotarized(n) {
+			out.Rewritten++
+		}
+	}
+	if limit > 0 && len(ns) > limit {
+		ns = ns[len(ns)-limit:]
+	}
+	for _, n := range ns {
+		out.Notarizations = append(out.Notarizations, NotarizationStatus{n, n.Error == "" && s.holdsNotarized(n)})
+	}
+	return out
+}
+
+// list notarizations: GET /notarizations?limit=N (the latest N, default all)
+func (s *Server) notarizationsHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+	json.NewEncoder(w).Encode(s.Notarizations(limit))
+}
+
+// notarize the tip now rather than on schedule: POST /admin/notarize
+func (s *Server) notarizeHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if len(s.notarizeTargets()) == 0 {
+		writeError(w, http.StatusBadRequest, "notarization is not configured")
+		return
+	}
+	logf(r.Context(), "AUDIT notarizing the tip on request")
+	json.NewEncoder(w).Encode(s.NotarizeTip(r.Context()))
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/script"
+)
+
+var (
+	// ErrInsufficientFunds is returned when an address can't cover a transfer
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrUnsigned is returned for a transaction missing a signature, by
+	// SubmitSigned and by chains requiring them
+	ErrUnsigned = blockchain.ErrUnsigned
+)
+
+// TransferRequest asks /transactions/build for a transfer
+type TransferRequest struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Amount int64  `json:"amount"`
+	Fee    int64  `json:"fee"`
+	// ExpiresAt is the last block height that may include the transfer; 0 never expires
+	ExpiresAt int `json:"expires_at_height,omitempty"`
+}
+
+// UnsignedTx is a transfer built for signing offline. Every input is
+// signed with From's key over SigHash, so the node never sees the key.
+type UnsignedTx struct {
+	Tx      blockchain.Transaction `json:"tx"`      // inputs without unlocks
+	SigHash string                 `json:"sighash"` // hex digest the signatures cover
+	From    string                 `json:"from"`
+	Fee     int64                  `json:"fee"`
+}
+
+// BuildTransfer spends from's confirmed outputs not already claimed by a
+// pending transaction, paying amount to to, fee to the miner and the rest
+// back to from. The inputs are left unsigned.
+func (s *Server) BuildTransfer(req TransferRequest) (UnsignedTx, error) {
+	switch {
+	case !blockchain.IsAddress(req.From):
+		return UnsignedTx{}, fmt.Errorf("invalid from address %q", req.From)
+	case !blockchain.IsAddress(req.To):
+		return UnsignedTx{}, fmt.Errorf("invalid to address %q", req.To)
+	case req.Amount <= 0 || req.Amount > blockchain.MaxAmount:
+		return UnsignedTx{}, fmt.Errorf("amount must be 1-%d", blockchain.MaxAmount)
+	case req.Fee < 0 || req.Fee > blockchain.MaxAmount:
+		return UnsignedTx{}, fmt.Errorf("fee must be 0-%d", blockchain.MaxAmount)
+	case req.ExpiresAt < 0:
+		return UnsignedTx{}, fmt.Errorf("expires_at_height must not be negative")
+	}
+	tx := blockchain.Transaction{ExpiresAt: req.ExpiresAt}
+	if next := s.chain.Len(); tx.Expired(next) {
+		return UnsignedTx{}, fmt.Errorf("%w at height %d, block %d is next", blockchain.ErrTxExpired, req.ExpiresAt, next)
+	}
+	var total int64
+	for _, u := range s.chain.UTXOs(req.From) {
+		if total >= req.Amount+req.Fee {
+			break
+		}
+		if s.pool.Spending(u.OutPoint) {
+			continue
+		}
+		tx.Inputs = append(tx.Inputs, blockchain.TxInput{TxID: u.TxID, Index: u.Index})
+		total += u.Amount
+	}
+	if total < req.Amount+req.Fee {
+		return UnsignedTx{}, fmt.Errorf("%w: %s holds %d unspent and unclaimed", ErrInsufficientFunds, req.From, total)
+	}
+	tx.Outputs = append(tx.Outputs, blockchain.TxOutput{Amount: req.Amount, Lock: script.P2PKH(req.To)})
+	if change := total - req.Amount - req.Fee; change > 0 {
+		tx.Outputs = append(tx.Outputs, blockchain.TxOutput{Amount: change, Lock: script.P2PKH(req.From)})
+	}
+	return UnsignedTx{Tx: tx, SigHash: hex.EncodeToString(tx.SigHash()), From: req.From, Fee: req.Fee}, nil
+}
+
+// SubmitSigned queues a transaction signed offline, refusing one without
+// its sender's signature or with an input left unsigned before it is
+// validated
+func (s *Server) SubmitSigned(ctx context.Context, tx blockchain.Transaction) (blockchain.Transaction, error) {
+	if len(tx.Inputs) == 0 && tx.Signature == "" {
+		return tx, fmt.Errorf("%w: it has no sender's signature and spends no inputs", ErrUnsigned)
+	}
+	for i, in := range tx.Inputs {
+		if in.Unlock == "" {
+			return tx, fmt.Errorf("%w: input %d has no unlock", ErrUnsigned, i)
+		}
+	}
+	if tx.ID == "" {
+		tx = tx.Seal()
+	}
+	return tx, s.AddTransaction(ctx, tx)
+}
+
+// build an unsigned transfer: POST /transactions/build {"from","to","amount","fee"}
+func (s *Server) buildTransferHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req TransferRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+	utx, err := s.BuildTransfer(req)
+	switch {
+	case errors.Is(err, ErrInsufficientFunds):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		json.NewEncoder(w).Encode(utx)
+	}
+}
+
+// submit a transaction signed offline: POST /transactions/submit-signed
+// with the signed transaction as the body
+func (s *Server) submitSignedHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var tx blockchain.Transaction
+	if err := decodeJSON(w, r, &tx); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+	tx, err := s.SubmitSigned(r.Context(), tx)
+	if errors.Is(err, ErrOrphaned) {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{"status": "held as orphan until its inputs confirm", "txid": tx.ID})
+		return
+	}
+	if err != nil {
+		writeChainError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"status": "transaction added", "txid": tx.ID})
+}
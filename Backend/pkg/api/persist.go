@@ -0,0 +1,181 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+// CorruptChainError is returned by LoadChain for stored blocks that fail
+// verification
+type CorruptChainError struct {
+	Block int // position of the first bad block; the ones before it are sound
+	Err   error
+}
+
+func (e *CorruptChainError) Error() string {
+	return fmt.Sprintf("stored block %d: %v", e.Block, e.Err)
+}
+
+func (e *CorruptChainError) Unwrap() error {
+	return e.Err
+}
+
+// LoadChain replays blocks read back from the store onto a chain that
+// holds only their genesis block, then requeues the stored pending
+// transactions and attestations. The stored difficulty schedule is
+// restored first, taken from the difficulties the blocks record when the
+// store predates it, so blocks are held to the difficulty of their height
+// whatever the node now runs at. The blocks are integrity-checked first,
+// and pending transactions that no longer validate are dropped. A block
+// failing either check is reported as a *CorruptChainError, with the chain
+// back at genesis so LoadChain can run again on a repaired chain. A sound
+// block refused only for a missing signature is not corruption; it is
+// reported as ErrUnsigned, leaving the store as it is. With no blocks the
+// store is new, and the chain's genesis block is written to it.
+func (s *Server) LoadChain(ctx context.Context, blocks []blockchain.Block, pending []blockchain.Transaction) error {
+	if len(blocks) == 0 && s.opts.Store != nil {
+		genesis, _ := s.chain.BlockAt(0)
+		if err := s.opts.Store.Rewrite([]blockchain.Block{genesis}); err != nil {
+			return err
+		}
+	}
+	if err := s.loadDifficulty(blocks); err != nil {
+		return err
+	}
+	if bad, err := blockchain.VerifyBlocks(blocks, s.chain.Consensus(), s.chain.Hasher()); err != nil {
+		return &CorruptChainError{Block: bad, Err: err}
+	}
+	s.mineMu.Lock()
+	if len(blocks) > 0 {
+		if genesis, _ := s.chain.BlockAt(0); blocks[0].Hash != genesis.Hash {
+			s.mineMu.Unlock()
+			return fmt.Errorf("stored genesis %s does not match the chain's %s", blocks[0].Hash, genesis.Hash)
+		}
+	}
+	for i := 1; i < len(blocks); i++ {
+		b := blocks[i]
+		s.txMu.Lock()
+		err := s.chain.AddBlock(b)
+		s.txMu.Unlock()
+		if err != nil {
+			s.chain.Reset()
+			s.mineMu.Unlock()
+			if errors.Is(err, blockchain.ErrUnsigned) {
+				// the block is sound; the node requires signatures below it
+				return fmt.Errorf("stored block %d: %w", i, err)
+			}
+			return &CorruptChainError{Block: i, Err: err}
+		}
+	}
+	s.mineMu.Unlock()
+	for _, tx := range pending {
+		if err := s.addTransaction(ctx, tx, false); err != nil {
+			logf(ctx, "dropping stored pending transaction %s: %v", tx.ID, err)
+		}
+	}
+	s.persistPending(ctx)
+	s.persistDifficulty(ctx)
+	if s.opts.Store != nil {
+		atts, err := s.opts.Store.LoadAttestations()
+		if err != nil {
+			return err
+		}
+		if err := s.loadAttestations(atts); err != nil {
+			return fmt.Errorf("stored attestation: %w", err)
+		}
+		snaps, err := s.opts.Store.LoadSnapshots()
+		if err != nil {
+			return err
+		}
+		s.loadSnapshots(snaps)
+		ns, err := s.opts.Store.LoadNotarizations()
+		if err != nil {
+			return err
+		}
+		s.loadNotarizations(ctx, ns)
+	}
+	s.assertInvariants(ctx)
+	s.attest(ctx)
+	return nil
+}
+
+// loadDifficulty restores the stored difficulty schedule ahead of blocks
+func (s *Server) loadDifficulty(blocks []blockchain.Block) error {
+	if s.opts.Store == nil {
+		return nil
+	}
+	steps, err := s.opts.Store.LoadDifficulty()
+	if err != nil {
+		return err
+	}
+	if steps == nil && s.chain.Consensus().Name() == "pow" {
+		steps = blockchain.RecordedSteps(blocks)
+	}
+	from := len(blocks)
+	if from == 0 {
+		from = 1
+	}
+	return s.chain.SetDifficultySchedule(steps, from)
+}
+
+// persistBlock appends b to the store. A failed append leaves the store
+// behind the chain, so writes are refused until a reset rewrites it.
+func (s *Server) persistBlock(ctx context.Context, b blockchain.Block) {
+	if s.opts.Store == nil {
+		return
+	}
+	err := s.opts.Store.AppendBlock(b)
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.unhealthy == "" {
+		s.unhealthy = fmt.Sprintf("storing block %d: %v", b.Index, err)
+	}
+	s.mu.Unlock()
+	logf(ctx, "storing block %d failed, refusing further writes: %v", b.Index, err)
+}
+
+// persistPending snapshots the mempool to the store. A failed snapshot is
+// only logged; the next change to the mempool writes a fresh one.
+func (s *Server) persistPending(ctx context.Context) {
+	if s.opts.Store == nil {
+		return
+	}
+	s.persistMu.Lock()
+	defer s.persistMu.Unlock()
+	if err := s.opts.Store.SavePending(s.pool.All()); err != nil {
+		logf(ctx, "storing pending transactions failed: %v", err)
+	}
+}
+
+// persistChain rewrites the store to match the chain, after a reset or a
+// switch to a peer's chain
+func (s *Server) persistChain(ctx context.Context) {
+	if s.opts.Store == nil {
+		return
+	}
+	if err := s.opts.Store.Rewrite(s.chain.Blocks()); err != nil {
+		s.mu.Lock()
+		s.unhealthy = fmt.Sprintf("storing chain: %v", err)
+		s.mu.Unlock()
+		logf(ctx, "storing chain failed, refusing further writes: %v", err)
+		return
+	}
+	s.persistPending(ctx)
+	s.persistDifficulty(ctx)
+}
+
+// persistDifficulty saves the difficulty schedule to the store. A failed
+// save is only logged; the next difficulty change or restart writes it.
+func (s *Server) persistDifficulty(ctx context.Context) {
+	if s.opts.Store == nil {
+		return
+	}
+	if err := s.opts.Store.SaveDifficulty(s.chain.DifficultySchedule()); err != nil {
+		logf(ctx, "storing difficulty schedule failed: %v", err)
+	}
+}
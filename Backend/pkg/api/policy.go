@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"salmanahmed/blockchain/pkg/clock"
+)
+
+// ErrQuotaExceeded is returned when a chain's policy refuses more work
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Limits on policies
+const (
+	maxAPIKeys    = 64
+	minAPIKeySize = 16
+)
+
+// ChainPolicy isolates a hosted chain: who may write to it, how fast it may
+// be used and how long it is kept
+type ChainPolicy struct {
+	APIKeys          []string `json:"api_keys,omitempty"`            // bearer tokens allowed to write, besides the node token
+	RatePerMinute    int      `json:"rate_per_minute,omitempty"`     // requests per minute across the chain; 0 is unlimited
+	MaxPending       int      `json:"max_pending,omitempty"`         // mempool size cap; 0 is unlimited
+	RetainIdleSecond int64    `json:"retain_idle_seconds,omitempty"` // delete the chain after this long without writes; 0 keeps it
+
+	HighPriorityPerMinute int `json:"high_priority_per_minute,omitempty"` // high-priority submissions per caller other than the node token; 0 is unlimited
+	DailyRequests         int `json:"daily_requests,omitempty"`           // requests per API key per UTC day; 0 is unlimited
+	DailyTransactions     int `json:"daily_transactions,omitempty"`       // transactions per API key per UTC day; 0 is unlimited
+}
+
+// Validate checks the policy is usable
+func (p ChainPolicy) Validate() error {
+	if len(p.APIKeys) > maxAPIKeys {
+		return fmt.Errorf("at most %d api keys", maxAPIKeys)
+	}
+	for _, k := range p.APIKeys {
+		if len(k) < minAPIKeySize {
+			return fmt.Errorf("api keys must be at least %d characters", minAPIKeySize)
+		}
+	}
+	if p.RatePerMinute < 0 || p.MaxPending < 0 || p.RetainIdleSecond < 0 || p.HighPriorityPerMinute < 0 ||
+		p.DailyRequests < 0 || p.DailyTransactions < 0 {
+		return errors.New("rates, quotas and retention cannot be negative")
+	}
+	return nil
+}
+
+// SetPolicy applies p's API keys and mempool cap to the server
+func (s *Server) SetPolicy(p ChainPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policy = p
+}
+
+// Policy returns the server's policy
+func (s *Server) Policy() ChainPolicy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.policy
+}
+
+// apiKeyValid reports whether token is one of the policy's API keys
+func (s *Server) apiKeyValid(token string) bool {
+	ok := false
+	for _, k := range s.Policy().APIKeys {
+		// check every key so timing doesn't reveal which one matched
+		if subtle.ConstantTimeCompare([]byte(token), []byte(k)) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
+
+// allowHighPriority takes one of the caller's high-priority submissions;
+// the node token is never limited
+func (s *Server) allowHighPriority(ctx context.Context) error {
+	limit := s.Policy().HighPriorityPerMinute
+	id := Identity(ctx)
+	if limit <= 0 || id == "token" {
+		return nil
+	}
+	s.mu.Lock()
+	l := s.priorityLimits[id]
+	if l == nil {
+		if s.priorityLimits == nil {
+			s.priorityLimits = map[string]*rateLimiter{}
+		}
+		l = &rateLimiter{}
+		s.priorityLimits[id] = l
+	}
+	s.mu.Unlock()
+	if !l.allow(clock.Or(s.opts.Clock).Now(), limit) {
+		return fmt.Errorf("%w: %s may submit %d high-priority transactions a minute", ErrQuotaExceeded, id, limit)
+	}
+	return nil
+}
+
+// keyIdentity names an API key caller without revealing the key
+func keyIdentity(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "api-key:" + hex.EncodeToString(sum[:4])
+}
+
+// rateLimiter is a token bucket refilled at perMinute tokens a minute
+type rateLimiter struct {
+	mu        sync.Mutex
+	perMinute int
+	tokens    float64
+	last      time.Time
+}
+
+// allow takes a token if one is available at now
+func (l *rateLimiter) allow(now time.Time, perMinute int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if perMinute <= 0 {
+		return true
+	}
+	if l.perMinute != perMinute || l.last.IsZero() {
+		// new or changed quota: start with a full bucket
+		l.perMinute, l.tokens, l.last = perMinute, float64(perMinute), now
+	}
+	l.tokens += now.Sub(l.last).Minutes() * float64(perMinute)
+	if l.tokens > float64(perMinute) {
+		l.tokens = float64(perMinute)
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+// BalanceEffect is how a previewed transaction would move one address's
+// confirmed balance
+type BalanceEffect struct {
+	Address string `json:"address"`
+	Label   string `json:"label,omitempty"`
+	Before  int64  `json:"before"`
+	Change  int64  `json:"change"`
+	After   int64  `json:"after"`
+}
+
+// TxPreview is the response of /transactions/preview
+type TxPreview struct {
+	TxID         string          `json:"txid"`
+	Valid        bool            `json:"valid"`
+	Error        string          `json:"error,omitempty"`  // why the node would refuse it
+	Orphan       bool            `json:"orphan,omitempty"` // it would be held until the outputs it spends confirm
+	Size         int             `json:"size"`
+	Fee          int64           `json:"fee"` // its inputs beyond its outputs; 0 without confirmed inputs
+	FeeRate      float64         `json:"fee_rate"`
+	EstimatedFee int64           `json:"estimated_fee"` // what a transaction this size should pay to confirm in the next block
+	Effects      []BalanceEffect `json:"effects"`       // ordered by address
+}
+
+// PreviewTransaction runs the checks AddTransaction would on tx and
+// reports its txid, fee and effect on balances, without queuing it or
+// counting it against any quota. The id is filled in when omitted.
+func (s *Server) PreviewTransaction(ctx context.Context, tx blockchain.Transaction) TxPreview {
+	tx.Size = 0 // response-only
+	if tx.ID == "" {
+		tx = tx.Seal()
+	}
+	p := TxPreview{TxID: tx.ID, Size: blockchain.TxSize(tx), Effects: []BalanceEffect{}}
+	orphan, err := s.checkTransaction(ctx, tx)
+	p.Valid, p.Orphan = err == nil, orphan
+	if err != nil {
+		p.Error = err.Error()
+	}
+
+	if fee, ok := s.chain.TxFee(tx); ok {
+		p.Fee, p.FeeRate = fee, blockchain.FeeRate(fee, tx)
+	}
+	if est, err := s.EstimateFee(1); err == nil {
+		p.EstimatedFee = int64(math.Ceil(est.FeeRate * float64(p.Size)))
+	}
+	for addr, change := range s.chain.BalanceChanges(tx) {
+		e := BalanceEffect{Address: addr, Label: s.opts.Labels.Get(addr), Change: change}
+		if bal, err := s.chain.BalanceAt(addr, -1); err == nil {
+			e.Before = bal.Balance
+		}
+		e.After = e.Before + change
+		p.Effects = append(p.Effects, e)
+	}
+	sort.Slice(p.Effects, func(i, j int) bool { return p.Effects[i].Address < p.Effects[j].Address })
+	return p
+}
+
+// checkTransaction is addTransaction's validation of a new transaction,
+// leaving out the per-caller quotas it charges
+func (s *Server) checkTransaction(ctx context.Context, tx blockchain.Transaction) (orphan bool, err error) {
+	if s.Unhealthy() != "" {
+		return false, ErrUnhealthy
+	}
+	if err := s.checkDeadline(); err != nil {
+		return false, err
+	}
+	invalid, err := s.validateNew(ctx, tx)
+	if err != nil || invalid != nil {
+		return invalid != nil, err
+	}
+	return false, s.pool.Check(tx)
+}
+
+// validate a transaction without submitting it: POST /transactions/preview
+// with the body POST /transactions takes
+func (s *Server) previewTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var tx blockchain.Transaction
+	if err := decodeJSON(w, r, &tx); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+	json.NewEncoder(w).Encode(s.PreviewTransaction(r.Context(), tx))
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/mempool"
+)
+
+// What a mempoolOp does
+const (
+	opSubmit  = iota // submit records to the node
+	opMine           // mine what is pending
+	opReceive        // receive a block a peer mined on our tip
+	opReorg          // adopt a longer chain a peer forked off ours
+	opKinds
+)
+
+// mempoolOp is one step of a mempoolScenario
+type mempoolOp struct {
+	Kind    int
+	Records []int // data records the step submits, or a peer mines, by number
+	Fork    int   // for opReorg, picks the height the peer forks after
+}
+
+// mempoolScenario is a random run of submitting, mining and taking blocks
+// from peers. Records come from a small set, so the same one is submitted
+// again while pending, after it confirmed and after a reorg dropped it.
+type mempoolScenario []mempoolOp
+
+func (mempoolScenario) Generate(r *rand.Rand, size int) reflect.Value {
+	ops := make(mempoolScenario, 1+r.Intn(20))
+	for i := range ops {
+		op := mempoolOp{Kind: r.Intn(opKinds), Fork: r.Intn(100)}
+		for n := r.Intn(4); n > 0; n-- {
+			op.Records = append(op.Records, r.Intn(6))
+		}
+		ops[i] = op
+	}
+	return reflect.ValueOf(ops)
+}
+
+func record(n int) blockchain.Transaction {
+	return blockchain.NewDataTx(fmt.Sprintf("record-%d", n))
+}
+
+// peerOf returns a node holding the first n blocks of s's chain
+func peerOf(t *testing.T, s *Server, n int) *Server {
+	t.Helper()
+	blocks := s.chain.Blocks()
+	peer := newTestServer(blocks[0])
+	for _, b := range blocks[1:n] {
+		if err := peer.chain.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return peer
+}
+
+// peerMine has peer mine the records, less any its chain confirmed, into
+// one block followed by extra empty ones
+func peerMine(t *testing.T, peer *Server, records []int, extra int) {
+	t.Helper()
+	ctx := context.Background()
+	for _, n := range records {
+		err := peer.AddTransaction(ctx, record(n))
+		if err != nil && !errors.Is(err, ErrAlreadyConfirmed) && !errors.Is(err, mempool.ErrDuplicate) {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i <= extra; i++ {
+		if _, err := peer.MineScheduled(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// the mempool never holds a confirmed txid: a mined, received or adopted
+// block takes the pending copies of its transactions with it, a confirmed
+// txid is refused, and a reorg requeues only what the new chain lacks
+func TestMempoolNeverHoldsAConfirmedTxid(t *testing.T) {
+	ctx := context.Background()
+	genesis := blockchain.NewChain(1).Tip()
+	prop := func(ops mempoolScenario) bool {
+		s := newTestServer(genesis)
+		s.opts.Debug = true
+		for step, op := range ops {
+			switch op.Kind {
+			case opSubmit:
+				for _, n := range op.Records {
+					tx := record(n)
+					_, confirmed := s.chain.HasTx(tx.ID)
+					pending := s.pool.Check(tx) != nil
+					err := s.AddTransaction(ctx, tx)
+					switch {
+					case err == nil && !confirmed && !pending:
+					case errors.Is(err, ErrAlreadyConfirmed) && confirmed:
+					case errors.Is(err, mempool.ErrDuplicate) && pending:
+					default:
+						t.Fatalf("step %d: submitting %q: %v", step, tx.Data, err)
+					}
+				}
+			case opMine:
+				if _, _, err := s.MinePending(ctx); err != nil {
+					t.Fatalf("step %d: mining: %v", step, err)
+				}
+			case opReceive:
+				peer := peerOf(t, s, s.chain.Len())
+				peerMine(t, peer, op.Records, 0)
+				if status, err := s.ReceiveBlock(ctx, peer.chain.Tip()); err != nil || status != BlockAdded {
+					t.Fatalf("step %d: receiving a block on our tip: %q, %v", step, status, err)
+				}
+			case opReorg:
+				ours := s.chain.Len()
+				fork := 1 + op.Fork%ours
+				peer := peerOf(t, s, fork)
+				peerMine(t, peer, op.Records, ours-fork)
+				if err := s.adoptChain(ctx, "peer", peer.chain.Blocks(), &SyncResult{}); err != nil {
+					t.Fatalf("step %d: adopting a chain forked after block %d: %v", step, fork-1, err)
+				}
+			}
+
+			for _, id := range s.pool.IDs() {
+				if idx, ok := s.chain.HasTx(id); ok {
+					t.Fatalf("step %d (%+v): %s is pending, yet block %d confirmed it", step, op, id, idx)
+				}
+			}
+			if reason := s.Unhealthy(); reason != "" {
+				t.Fatalf("step %d: %s", step, reason)
+			}
+		}
+		return true
+	}
+	if err := quick.Check(prop, &quick.Config{MaxCount: 50}); err != nil {
+		t.Fatal(err)
+	}
+}
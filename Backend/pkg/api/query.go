@@ -0,0 +1,335 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+const (
+	// defaultQueryLimit and maxQueryLimit bound the rows or groups a query returns
+	defaultQueryLimit = 100
+	maxQueryLimit     = 1000
+)
+
+// queryFields lists what each kind of row holds; true marks numeric fields,
+// the only ones sum, min and max apply to
+var queryFields = map[string]map[string]bool{
+	"blocks": {
+		"index": true, "time": true, "transactions": true, "size": true, "difficulty": true,
+		"hash": false, "miner": false, "producer": false,
+	},
+	"transactions": {
+		"block": true, "position": true, "time": true, "value": true, "fee": true, "size": true, "inputs": true, "outputs": true,
+		"txid": false, "type": false, "miner": false, "producer": false,
+	},
+}
+
+// Query is the body of POST /query: what to select, which of it, and how
+// to summarize it
+type Query struct {
+	Select    string      `json:"select"` // blocks or transactions
+	Where     QueryFilter `json:"where"`
+	Aggregate []string    `json:"aggregate,omitempty"` // count, or sum, min or max of a numeric field, e.g. "sum:value"
+	GroupBy   string      `json:"group_by,omitempty"`  // a field, or the hour or day of the block time (UTC)
+	Limit     int         `json:"limit,omitempty"`     // rows or groups returned; 0 is 100, at most 1000
+}
+
+// QueryFilter narrows a Query; zero fields match everything
+type QueryFilter struct {
+	FromHeight *int   `json:"from_height,omitempty"`
+	ToHeight   *int   `json:"to_height,omitempty"` // inclusive
+	FromTime   *int64 `json:"from_time,omitempty"` // unix seconds, of the block
+	ToTime     *int64 `json:"to_time,omitempty"`   // inclusive
+	Address    string `json:"address,omitempty"`   // transactions spending from or paying to it, or blocks holding one
+	Miner      string `json:"miner,omitempty"`     // blocks credited to this address or API key, and their transactions
+	Producer   string `json:"producer,omitempty"`  // blocks mined by this node ID, and their transactions
+}
+
+// QueryResult is the response of POST /query
+type QueryResult struct {
+	Select    string                   `json:"select"`
+	Scanned   int                      `json:"scanned_blocks"`
+	Matched   int                      `json:"matched"`          // rows matching Where, across every group
+	Truncated bool                     `json:"truncated"`        // more rows or groups matched than Limit
+	Rows      []map[string]interface{} `json:"rows,omitempty"`   // without Aggregate, in chain order
+	Groups    []QueryGroup             `json:"groups,omitempty"` // with Aggregate, ordered by key; one without GroupBy
+}
+
+// QueryGroup holds the aggregates over the rows sharing a key
+type QueryGroup struct {
+	Key    string           `json:"key,omitempty"`
+	Values map[string]int64 `json:"values"` // by aggregate; min and max are left out of an empty group
+}
+
+// aggregate is one parsed Query.Aggregate entry
+type aggregate struct {
+	name, op, field string
+}
+
+// queryGroup accumulates one QueryGroup
+type queryGroup struct {
+	QueryGroup
+	order interface{} // int64 for numeric keys, string otherwise
+	rows  int
+}
+
+// checkQuery validates q and fills in its defaults, returning its aggregates
+func checkQuery(q *Query) ([]aggregate, error) {
+	fields, ok := queryFields[q.Select]
+	if !ok {
+		return nil, fmt.Errorf("select must be blocks or transactions")
+	}
+	switch {
+	case q.Limit == 0:
+		q.Limit = defaultQueryLimit
+	case q.Limit < 0 || q.Limit > maxQueryLimit:
+		return nil, fmt.Errorf("limit must be between 1 and %d", maxQueryLimit)
+	}
+	if w := q.Where; (w.FromHeight != nil && *w.FromHeight < 0) || (w.ToHeight != nil && *w.ToHeight < 0) {
+		return nil, fmt.Errorf("heights must not be negative")
+	}
+	if _, ok := fields[q.GroupBy]; !ok && q.GroupBy != "" && q.GroupBy != "hour" && q.GroupBy != "day" {
+		return nil, fmt.Errorf("cannot group %s by %q", q.Select, q.GroupBy)
+	}
+	if q.GroupBy != "" && len(q.Aggregate) == 0 {
+		q.Aggregate = []string{"count"}
+	}
+	aggs := make([]aggregate, 0, len(q.Aggregate))
+	for _, name := range q.Aggregate {
+		op, field, _ := strings.Cut(name, ":")
+		switch {
+		case name == "count":
+		case op != "sum" && op != "min" && op != "max":
+			return nil, fmt.Errorf("unknown aggregate %q (want count, or sum, min or max:<field>)", name)
+		case !fields[field]:
+			return nil, fmt.Errorf("%s needs a numeric %s field", op, strings.TrimSuffix(q.Select, "s"))
+		}
+		aggs = append(aggs, aggregate{name: name, op: op, field: field})
+	}
+	return aggs, nil
+}
+
+// RunQuery evaluates q over the chain, block by block, without copying it
+func (s *Server) RunQuery(ctx context.Context, q Query) (QueryResult, error) {
+	aggs, err := checkQuery(&q)
+	if err != nil {
+		return QueryResult{}, err
+	}
+	res := QueryResult{Select: q.Select}
+	groups := map[string]*queryGroup{}
+	add := func(row map[string]interface{}) {
+		res.Matched++
+		if len(aggs) == 0 {
+			if len(res.Rows) < q.Limit {
+				res.Rows = append(res.Rows, row)
+			}
+			return
+		}
+		key, order := queryKey(row, q.GroupBy)
+		g := groups[key]
+		if g == nil {
+			g = &queryGroup{QueryGroup: QueryGroup{Key: key, Values: map[string]int64{}}, order: order}
+			groups[key] = g
+		}
+		for _, a := range aggs {
+			v, _ := row[a.field].(int64)
+			switch a.op {
+			case "count":
+				g.Values[a.name]++
+			case "sum":
+				g.Values[a.name] += v
+			case "min":
+				if old, ok := g.Values[a.name]; !ok || v < old {
+					g.Values[a.name] = v
+				}
+			case "max":
+				if old, ok := g.Values[a.name]; !ok || v > old {
+					g.Values[a.name] = v
+				}
+			}
+		}
+		g.rows++
+	}
+
+	w := q.Where
+	from, to := 0, s.chain.Len()-1
+	if w.FromHeight != nil {
+		from = *w.FromHeight
+	}
+	if w.ToHeight != nil && *w.ToHeight < to {
+		to = *w.ToHeight
+	}
+	for i := from; i <= to; i++ {
+		if err := ctx.Err(); err != nil {
+			return QueryResult{}, err
+		}
+		b, ok := s.chain.BlockAt(i)
+		if !ok {
+			break
+		}
+		res.Scanned++
+		if (w.FromTime != nil && b.Timestamp < *w.FromTime) || (w.ToTime != nil && b.Timestamp > *w.ToTime) ||
+			(w.Miner != "" && b.Miner != w.Miner) || (w.Producer != "" && b.Producer != w.Producer) {
+			continue
+		}
+		var activity []blockchain.TxActivity
+		if q.Select == "transactions" || w.Address != "" {
+			activity, _ = s.chain.BlockActivity(i)
+		}
+		involves := func(j int) bool {
+			return w.Address == "" || (j < len(activity) &&
+				(containsString(activity[j].Senders, w.Address) || containsString(activity[j].Recipients, w.Address)))
+		}
+		if q.Select == "blocks" {
+			hit := w.Address == ""
+			for j := range b.Txns {
+				if hit = hit || involves(j); hit {
+					break
+				}
+			}
+			if hit {
+				add(map[string]interface{}{
+					"index": int64(b.Index), "time": b.Timestamp, "transactions": int64(len(b.Txns)),
+					"size": int64(blockchain.BlockSize(b)), "difficulty": int64(b.Difficulty),
+					"hash": b.Hash, "miner": b.Miner, "producer": b.Producer,
+				})
+			}
+			continue
+		}
+		for j, t := range b.Txns {
+			if !involves(j) {
+				continue
+			}
+			var value int64
+			for _, o := range t.Outputs {
+				value += o.Amount
+			}
+			var act blockchain.TxActivity
+			if j < len(activity) {
+				act = activity[j]
+			}
+			add(map[string]interface{}{
+				"block": int64(b.Index), "position": int64(j), "time": b.Timestamp, "value": value, "fee": act.Fee,
+				"size": int64(blockchain.TxSize(t)), "inputs": int64(len(t.Inputs)), "outputs": int64(len(t.Outputs)),
+				"txid": t.ID, "type": txType(t), "miner": b.Miner, "producer": b.Producer,
+				"senders": act.Senders, "recipients": act.Recipients,
+			})
+		}
+	}
+
+	if len(aggs) == 0 {
+		res.Truncated = res.Matched > len(res.Rows)
+		if res.Rows == nil {
+			res.Rows = []map[string]interface{}{}
+		}
+		return res, nil
+	}
+	if len(groups) == 0 && q.GroupBy == "" {
+		groups[""] = &queryGroup{QueryGroup: QueryGroup{Values: map[string]int64{}}}
+		for _, a := range aggs {
+			if a.op == "count" || a.op == "sum" {
+				groups[""].Values[a.name] = 0
+			}
+		}
+	}
+	sorted := make([]*queryGroup, 0, len(groups))
+	for _, g := range groups {
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if a, ok := sorted[i].order.(int64); ok {
+			return a < sorted[j].order.(int64)
+		}
+		return sorted[i].Key < sorted[j].Key
+	})
+	if res.Truncated = len(sorted) > q.Limit; res.Truncated {
+		sorted = sorted[:q.Limit]
+	}
+	res.Groups = make([]QueryGroup, 0, len(sorted))
+	for _, g := range sorted {
+		res.Groups = append(res.Groups, g.QueryGroup)
+	}
+	return res, nil
+}
+
+// queryKey is the group row falls in under groupBy, and the value groups
+// are ordered by
+func queryKey(row map[string]interface{}, groupBy string) (string, interface{}) {
+	switch groupBy {
+	case "":
+		return "", ""
+	case "hour", "day":
+		t := time.Unix(row["time"].(int64), 0).UTC()
+		if groupBy == "hour" {
+			t = t.Truncate(time.Hour)
+			return t.Format("2006-01-02T15:04Z"), t.Format("2006-01-02T15:04Z")
+		}
+		return t.Format("2006-01-02"), t.Format("2006-01-02")
+	}
+	switch v := row[groupBy].(type) {
+	case int64:
+		return strconv.FormatInt(v, 10), v
+	case string:
+		return v, v
+	}
+	return "", ""
+}
+
+// txType names what kind of transaction t is, for grouping
+func txType(t blockchain.Transaction) string {
+	switch {
+	case t.Coinbase != 0:
+		return "coinbase"
+	case t.Contract != nil:
+		return "contract"
+	case len(t.KV) > 0:
+		return "kv"
+	case t.Confidential != nil:
+		return "confidential"
+	case t.Commit != "":
+		return "commit"
+	case t.Reveal != nil:
+		return "reveal"
+	case t.Blob != "":
+		return "blob"
+	case len(t.Inputs) > 0:
+		return "transfer"
+	case len(t.Outputs) > 0:
+		return "issuance"
+	}
+	return "data"
+}
+
+// containsString reports whether the sorted list holds s
+func containsString(list []string, s string) bool {
+	i := sort.SearchStrings(list, s)
+	return i < len(list) && list[i] == s
+}
+
+// evaluate a declarative query over the chain: POST /query with a Query
+func (s *Server) queryHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var q Query
+	if err := decodeJSON(w, r, &q); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+	res, err := s.RunQuery(r.Context(), q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(res)
+}
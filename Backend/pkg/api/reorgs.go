@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// maxReorgs bounds the reorganizations kept for /reorgs; older ones are forgotten
+const maxReorgs = 1000
+
+// TipRef names a chain tip
+type TipRef struct {
+	Index int    `json:"index"`
+	Hash  string `json:"hash"`
+}
+
+// Reorg is one switch to a peer's chain that dropped local blocks
+type Reorg struct {
+	Time     int64    `json:"time"` // unix seconds
+	Peer     string   `json:"peer"` // whose chain was adopted
+	OldTip   TipRef   `json:"old_tip"`
+	NewTip   TipRef   `json:"new_tip"`
+	Depth    int      `json:"depth"`    // local blocks dropped
+	Requeued []string `json:"requeued"` // txids returned to the mempool
+}
+
+// ReorgHistory is the response of /reorgs
+type ReorgHistory struct {
+	Count    int     `json:"count"` // every reorg since start, including forgotten ones
+	MaxDepth int     `json:"max_depth"`
+	AvgDepth float64 `json:"avg_depth"`
+	Reorgs   []Reorg `json:"reorgs"` // oldest first, at most maxReorgs
+}
+
+// reorgLog records reorganizations
+type reorgLog struct {
+	reorgs     []Reorg
+	count      int
+	totalDepth int
+	maxDepth   int
+}
+
+// recordReorg adds r to the history
+func (s *Server) recordReorg(r Reorg) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := &s.reorgs
+	l.count++
+	l.totalDepth += r.Depth
+	if r.Depth > l.maxDepth {
+		l.maxDepth = r.Depth
+	}
+	l.reorgs = append(l.reorgs, r)
+	if len(l.reorgs) > maxReorgs {
+		l.reorgs = append([]Reorg(nil), l.reorgs[len(l.reorgs)-maxReorgs:]...)
+	}
+}
+
+// Reorgs returns the recorded reorganizations, only the latest limit when
+// limit is positive
+func (s *Server) Reorgs(limit int) ReorgHistory {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := s.reorgs
+	h := ReorgHistory{Count: l.count, MaxDepth: l.maxDepth, Reorgs: l.reorgs}
+	if l.count > 0 {
+		h.AvgDepth = float64(l.totalDepth) / float64(l.count)
+	}
+	if limit > 0 && len(h.Reorgs) > limit {
+		h.Reorgs = h.Reorgs[len(h.Reorgs)-limit:]
+	}
+	h.Reorgs = append([]Reorg{}, h.Reorgs...)
+	return h
+}
+
+// list reorganizations: GET /reorgs?limit=N (the latest N, default all)
+func (s *Server) reorgsHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+	json.NewEncoder(w).Encode(s.Reorgs(limit))
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/clock"
+	"salmanahmed/blockchain/pkg/events"
+)
+
+// replicaBatch is how many blocks a replica fetches from its upstream per request
+const replicaBatch = 500
+
+// ReplicaStatus is the response of /replica
+type ReplicaStatus struct {
+	Upstream       string `json:"upstream"`
+	Height         int    `json:"height"`
+	UpstreamHeight int    `json:"upstream_height"` // as of the last successful poll
+	Lag            int    `json:"lag"`             // blocks behind the upstream
+	LastSync       int64  `json:"last_sync,omitempty"`
+	LastError      string `json:"last_error,omitempty"` // of the last poll, cleared once one succeeds
+}
+
+// replica tracks how far a read-only replica has followed its upstream
+type replica struct {
+	mu        sync.Mutex
+	upstream  int
+	lastSync  time.Time
+	lastError string
+}
+
+// Replica reports whether the server is a read-only replica
+func (s *Server) Replica() bool {
+	return s.opts.Upstream != ""
+}
+
+// ReplicaStatus reports how far the replica has caught up with its upstream
+func (s *Server) ReplicaStatus() ReplicaStatus {
+	st := ReplicaStatus{Upstream: s.opts.Upstream, Height: s.chain.Len() - 1}
+	s.replica.mu.Lock()
+	defer s.replica.mu.Unlock()
+	st.UpstreamHeight, st.LastError = s.replica.upstream, s.replica.lastError
+	if !s.replica.lastSync.IsZero() {
+		st.LastSync = s.replica.lastSync.Unix()
+	}
+	if st.UpstreamHeight > st.Height {
+		st.Lag = st.UpstreamHeight - st.Height
+	}
+	return st
+}
+
+// Follow keeps a replica's chain and mempool in step with its upstream,
+// polling every interval until ctx ends. An upstream that lists the replica
+// among its peers also pushes each block as it is mined.
+func (s *Server) Follow(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		err := s.followOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		s.replica.mu.Lock()
+		if err != nil {
+			if s.replica.lastError != err.Error() {
+				logf(ctx, "following %s: %v", s.opts.Upstream, err)
+			}
+			s.replica.lastError = err.Error()
+		} else {
+			s.replica.lastError = ""
+			s.replica.lastSync = clock.Or(s.opts.Clock).Now()
+		}
+		s.replica.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// followOnce fetches the upstream's new blocks and mirrors its mempool. A
+// fork from the local tip, after the upstream reorganized, adopts the
+// upstream's whole chain instead.
+func (s *Server) followOnce(ctx context.Context) error {
+	if err := s.checkFrozen(); err != nil {
+		return err
+	}
+	up := s.opts.Upstream
+	height, err := s.gossip().Height(ctx, up)
+	if err != nil {
+		return err
+	}
+	s.replica.mu.Lock()
+	s.replica.upstream = height
+	s.replica.mu.Unlock()
+	for local := s.chain.Len() - 1; local < height; local = s.chain.Len() - 1 {
+		blocks, err := s.gossip().FetchBlocks(ctx, up, local+1, replicaBatch)
+		if err != nil {
+			return err
+		}
+		if len(blocks) == 0 {
+			break
+		}
+		tip, _ := s.chain.BlockAt(local)
+		if blocks[0].Index != tip.Index+1 || blocks[0].PrevHash != tip.Hash {
+			return s.refollow(ctx)
+		}
+		for _, b := range blocks {
+			status, err := s.ReceiveBlock(ctx, b)
+			if err != nil {
+				return fmt.Errorf("block %d: %w", b.Index, err)
+			}
+			if status != BlockAdded && status != BlockKnown {
+				return s.refollow(ctx)
+			}
+		}
+	}
+	pending, err := s.gossip().Pending(ctx, up)
+	if err != nil {
+		return err
+	}
+	s.mirrorPending(ctx, pending)
+	return nil
+}
+
+// refollow adopts the upstream's whole chain after it reorganized
+func (s *Server) refollow(ctx context.Context) error {
+	up := s.opts.Upstream
+	blocks, err := s.gossip().FetchChain(ctx, up)
+	if err != nil {
+		return err
+	}
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	var res SyncResult
+	if err := s.adoptChain(ctx, up, blocks, &res); err != nil {
+		return fmt.Errorf("adopting the upstream chain: %w", err)
+	}
+	return nil
+}
+
+// mirrorPending replaces the mempool with the upstream's, announcing the
+// transactions that arrived or left without confirming
+func (s *Server) mirrorPending(ctx context.Context, txs []blockchain.Transaction) {
+	s.txMu.Lock()
+	gone := map[string]blockchain.Transaction{}
+	for _, tx := range s.pool.Drain() {
+		gone[tx.ID] = tx
+	}
+	for i := range txs {
+		txs[i].Size = 0 // response-only
+	}
+	s.pool.Restore(txs)
+	s.txMu.Unlock()
+	s.pendingAdded(txs...)
+	changed := false
+	for _, tx := range txs {
+		if _, ok := gone[tx.ID]; ok {
+			delete(gone, tx.ID)
+			continue
+		}
+		changed = true
+		s.events.Publish(events.TxAdded, map[string]string{"txid": tx.ID, "data": tx.Data})
+	}
+	for id, tx := range gone {
+		changed = true
+		if _, ok := s.chain.HasTx(id); ok {
+			s.pendingLeft(PendingConfirmed, "", tx)
+			continue
+		}
+		s.pendingLeft(PendingDropped, "gone from the upstream mempool", tx)
+		s.events.Publish(events.TxDropped, map[string]string{"txid": id})
+	}
+	if changed {
+		s.persistPending(ctx)
+		s.assertInvariants(ctx)
+	}
+}
+
+// readOnly redirects a replica's writes, and reads of mining jobs that only
+// the primary runs, to its upstream; blocks pushed by the upstream are
+// still taken, and the replica itself can be frozen
+func (s *Server) readOnly(next http.Handler) http.Handler {
+	if !s.Replica() {
+		return next
+	}
+	redirect := s.withRequestContext(s.cors(http.HandlerFunc(s.redirectWrite)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/mine/"):
+			redirect.ServeHTTP(w, r)
+		case r.Method == "GET", r.Method == "HEAD", r.Method == "OPTIONS", r.URL.Path == "/p2p/blocks", r.URL.Path == "/query",
+			r.URL.Path == "/admin/freeze", r.URL.Path == "/admin/unfreeze":
+			next.ServeHTTP(w, r)
+		default:
+			redirect.ServeHTTP(w, r)
+		}
+	})
+}
+
+// redirectWrite sends a request to the primary, keeping its method and body
+func (s *Server) redirectWrite(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	w.Header().Set("Location", s.opts.Upstream+r.URL.RequestURI())
+	writeError(w, http.StatusTemporaryRedirect, "read-only replica: send writes to "+s.opts.Upstream)
+}
+
+// how far a replica has followed its upstream: GET /replica
+func (s *Server) replicaHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if !s.Replica() {
+		writeError(w, http.StatusNotFound, "not a replica")
+		return
+	}
+	json.NewEncoder(w).Encode(s.ReplicaStatus())
+}
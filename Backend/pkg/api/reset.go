@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"salmanahmed/blockchain/pkg/clock"
+	"salmanahmed/blockchain/pkg/events"
+)
+
+// resetConfirmTTL is how long a /admin/reset confirmation token stays valid
+const resetConfirmTTL = time.Minute
+
+// ErrResetUnconfirmed is returned by Reset for a wrong or expired confirmation token
+var ErrResetUnconfirmed = errors.New("invalid or expired reset confirmation token")
+
+// ResetConfirmation is the token a reset must be confirmed with
+type ResetConfirmation struct {
+	Confirm string `json:"confirm"`
+	Expires int64  `json:"expires"` // unix time
+}
+
+// ResetResult is the outcome of Reset
+type ResetResult struct {
+	Genesis             string `json:"genesis"` // hash of the block the chain restarted from
+	DroppedBlocks       int    `json:"dropped_blocks"`
+	DroppedPending      int    `json:"dropped_pending"`
+	DroppedOrphans      int    `json:"dropped_orphans"`
+	DroppedAttestations int    `json:"dropped_attestations"`
+}
+
+// resetGuard holds the one outstanding reset confirmation token
+type resetGuard struct {
+	token   string
+	expires time.Time
+}
+
+// RequestReset issues a fresh confirmation token for Reset, replacing any
+// earlier one
+func (s *Server) RequestReset() ResetConfirmation {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reset = resetGuard{
+		token:   hex.EncodeToString(buf),
+		expires: clock.Or(s.opts.Clock).Now().Add(resetConfirmTTL),
+	}
+	return ResetConfirmation{Confirm: s.reset.token, Expires: s.reset.expires.Unix()}
+}
+
+// Reset wipes the chain back to its genesis block and empties the mempool
+// and orphan pool. confirm must be the token from the latest RequestReset;
+// each token works once.
+func (s *Server) Reset(ctx context.Context, confirm string) (ResetResult, error) {
+	s.mu.Lock()
+	ok := confirm != "" && confirm == s.reset.token &&
+		clock.Or(s.opts.Clock).Now().Before(s.reset.expires)
+	s.reset = resetGuard{}
+	s.mu.Unlock()
+	if !ok {
+		return ResetResult{}, ErrResetUnconfirmed
+	}
+	return s.resetChain(ctx), nil
+}
+
+// ResetScheduled wipes the chain back to its genesis block as Reset does,
+// without a confirmation token, for nodes reset on a schedule
+func (s *Server) ResetScheduled(ctx context.Context) ResetResult {
+	logf(ctx, "scheduled chain reset")
+	return s.resetChain(ctx)
+}
+
+// resetChain does the work of Reset
+func (s *Server) resetChain(ctx context.Context) ResetResult {
+	// wait out any mining so no block lands on the old chain mid-reset
+	s.mineMu.Lock()
+	defer s.mineMu.Unlock()
+	s.txMu.Lock()
+	cleared := s.pool.Drain()
+	res := ResetResult{
+		DroppedBlocks:  s.chain.Reset(),
+		DroppedPending: len(cleared),
+		DroppedOrphans: s.orphans.Clear(),
+	}
+	s.txMu.Unlock()
+	s.pendingLeft(PendingCleared, "chain reset", cleared...)
+	genesis, _ := s.chain.BlockAt(0)
+	res.Genesis = genesis.Hash
+
+	s.mu.Lock()
+	s.unhealthy = "" // whatever state broke an invariant is gone
+	res.DroppedAttestations = s.dropAttestations(ctx)
+	s.sideBlocks = nil
+	s.mu.Unlock()
+	s.persistChain(ctx)
+	logf(ctx, "AUDIT chain reset to genesis %s: dropped %d blocks, %d pending and %d orphan transactions and %d attestations",
+		res.Genesis, res.DroppedBlocks, res.DroppedPending, res.DroppedOrphans, res.DroppedAttestations)
+	s.events.Publish(events.ChainReset, res)
+	s.watchBlocks(ctx)
+	return res
+}
+
+// reset the chain to genesis in two steps: POST without a body returns a
+// confirmation token, then POST {"confirm": token} performs the reset
+func (s *Server) resetHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var body struct {
+		Confirm string `json:"confirm"`
+	}
+	if r.ContentLength != 0 {
+		if err := decodeJSON(w, r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid body")
+			return
+		}
+	}
+	if body.Confirm == "" {
+		logf(r.Context(), "chain reset requested")
+		json.NewEncoder(w).Encode(s.RequestReset())
+		return
+	}
+	res, err := s.Reset(r.Context(), body.Confirm)
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,674 @@
+// Package api exposes the chain and mempool over HTTP.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"salmanahmed/blockchain/pkg/blobstore"
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/chaos"
+	"salmanahmed/blockchain/pkg/clock"
+	"salmanahmed/blockchain/pkg/events"
+	"salmanahmed/blockchain/pkg/labels"
+	"salmanahmed/blockchain/pkg/mempool"
+	"salmanahmed/blockchain/pkg/p2p"
+	"salmanahmed/blockchain/pkg/script"
+	"salmanahmed/blockchain/pkg/store"
+)
+
+var (
+	// ErrUnhealthy is returned for writes once an invariant has been violated
+	ErrUnhealthy = errors.New("node unhealthy")
+	// ErrAlreadyConfirmed is returned when a transaction is already in a block
+	ErrAlreadyConfirmed = blockchain.ErrConfirmed
+	// ErrOrphaned is returned when a transaction is held until its parents confirm
+	ErrOrphaned = errors.New("transaction held until its inputs confirm")
+)
+
+// Server wires the chain and mempool to HTTP handlers
+type Server struct {
+	chain   *blockchain.Chain
+	pool    *mempool.Mempool
+	orphans *mempool.Orphans
+	peers   *p2p.Peers
+	events  *events.Hub
+
+	mineMu sync.Mutex // serializes mining so templates always build on the tip
+	txMu   sync.Mutex // orders confirmation checks against block appends
+
+	persistMu sync.Mutex // keeps mempool snapshots in order
+	syncMu    sync.Mutex // one longest-chain sync at a time
+
+	opts Options
+
+	// debug-mode invariant checking
+	mu        sync.Mutex
+	unhealthy string      // non-empty once an invariant has been violated
+	policy    ChainPolicy // API keys and quotas for hosted chains
+
+	priorityLimits map[string]*rateLimiter // caller identity -> high-priority quota
+
+	faucet  faucet
+	mining  miningMeter
+	usage   usageMeter
+	reset   resetGuard // guarded by mu
+	watches watchList  // guarded by mu
+	reorgs  reorgLog   // guarded by mu
+
+	sideBlocks []sideBlock // guarded by mu; off-chain blocks for /fork-view, oldest first
+
+	history  pendingHistory // guarded by mu; what became of transactions that left the mempool
+	ipLimits ipLimits
+
+	notarizations []blockchain.Notarization // guarded by mu; oldest first
+
+	attestations []blockchain.Attestation // guarded by mu
+	snapshots    []blockchain.Snapshot    // guarded by mu; oldest first
+	deadline     time.Time                // guarded by mu; new transactions are refused from then on
+	frozen       freezeState              // guarded by mu
+	stopMining   context.CancelCauseFunc  // guarded by mu; abandons the block being mined, if any
+
+	jobs    *mineJobs
+	replica replica
+	started time.Time // for /status uptime
+}
+
+// Options tunes the HTTP surface
+type Options struct {
+	Debug       bool     // check invariants after every write
+	CORSOrigins []string // allowed origins; "*" or empty allows any
+	AuthToken   string   // bearer token required for writes; empty disables auth
+	Clock       clock.Clock
+	Chaos       *chaos.Injector  // runtime fault injection; nil disables /admin/chaos
+	Demo        bool             // allow tampering with confirmed blocks through /tamper
+	Blobs       blobstore.Store  // off-chain payloads; nil disables /blobs
+	Store       *store.Store     // on-disk blocks and mempool; nil keeps them in memory only
+	Labels      *labels.Registry // address labels; nil starts an empty in-memory registry
+	Faucet      FaucetOptions
+	Attest      AttestOptions
+	Notarize    NotarizeOptions
+	Deadline    time.Time // stop accepting new transactions from then on; zero never does
+	Limits      *Limits   // per-class concurrency and timeouts, shared by a node's chains; nil limits nothing
+
+	MaxPending   int // mempool size cap, lowered by a policy's MaxPending; 0 is unlimited
+	MaxBlockTxns int // pending transactions a mined block takes; 0 takes them all
+
+	RatePerIP     int           // requests a minute per client IP, the node token aside; 0 is unlimited
+	ReadOnlyAdmin bool          // refuse admin writes without the node token, even when no token is set
+	MaxTxBytes    int           // largest transaction accepted, as JSON; 0 is unlimited
+	MaxBlockBytes int           // transaction bytes a mined block takes; 0 is unlimited
+	PendingTTL    time.Duration // drop transactions pending longer than this; 0 keeps them
+
+	Upstream    string        // primary node URL a read-only replica follows (see Follow); empty for a primary
+	NodeID      string        // recorded as the producer of the blocks this node mines; empty records none
+	ChainID     string        // hosted chain ID reported by /status; empty for the default chain
+	MineEvery   time.Duration // the schedule the node mines on, reported by /status; 0 mines only on request
+	MineAddress string        // the miner MineScheduled credits and pays the block reward to; empty is unrewarded
+	Profile     string        // config profile the node started from, reported by /status
+}
+
+// NewServer returns a server for chain and pool
+func NewServer(chain *blockchain.Chain, pool *mempool.Mempool, opts Options) *Server {
+	if opts.Labels == nil {
+		opts.Labels = labels.New()
+	}
+	return &Server{
+		chain:   chain,
+		pool:    pool,
+		orphans: mempool.NewOrphans(),
+		peers:   p2p.NewPeers(),
+		events:  events.NewHub(opts.Clock),
+		opts:    opts,
+
+		deadline: opts.Deadline,
+		jobs:     newMineJobs(),
+		started:  clock.Or(opts.Clock).Now(),
+	}
+}
+
+// Peers returns the server's peer set
+func (s *Server) Peers() *p2p.Peers {
+	return s.peers
+}
+
+// Handler returns the HTTP routes
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/blocks", s.getBlocksHandler)
+	mux.HandleFunc("/blocks/", s.blockTreeHandler)
+	mux.HandleFunc("/block/", s.blockHandler)
+	mux.HandleFunc("/export", s.exportHandler)
+	mux.HandleFunc("/export/ledger", s.ledgerHandler)
+	mux.HandleFunc("/import", s.requireNode(s.importHandler))
+	mux.HandleFunc("/transactions", s.requireAuth(s.addTransactionHandler))
+	mux.HandleFunc("/transactions/", s.transactionHandler)
+	mux.HandleFunc("/transactions/build", s.requireAuth(s.buildTransferHandler))
+	mux.HandleFunc("/transactions/preview", s.requireAuth(s.previewTransactionHandler))
+	mux.HandleFunc("/transactions/submit-signed", s.requireAuth(s.submitSignedHandler))
+	mux.HandleFunc("/mine", s.requireAuth(s.mineHandler))
+	mux.HandleFunc("/mine/status/", s.mineStatusHandler)
+	mux.HandleFunc("/mine/cancel/", s.requireAuth(s.mineCancelHandler))
+	mux.HandleFunc("/leaderboard", s.leaderboardHandler)
+	mux.HandleFunc("/stats", s.statsHandler)
+	mux.HandleFunc("/stats/blocktime", s.blockTimeStatsHandler)
+	mux.HandleFunc("/metrics", s.metricsHandler)
+	mux.HandleFunc("/difficulty", s.difficultyStatusHandler)
+	mux.HandleFunc("/attestations", s.attestationsHandler)
+	mux.HandleFunc("/notarizations", s.notarizationsHandler)
+	mux.HandleFunc("/deadline", s.deadlineStatusHandler)
+	mux.HandleFunc("/testvectors", s.testVectorsHandler)
+	mux.HandleFunc("/difficulty/estimate", s.difficultyEstimateHandler)
+	mux.HandleFunc("/fees/estimate", s.feeEstimateHandler)
+	mux.HandleFunc("/faucet", s.faucetHandler)
+	mux.HandleFunc("/search", s.searchHandler)
+	mux.HandleFunc("/query", s.queryHandler)
+	mux.HandleFunc("/pending", s.pendingHandler)
+	mux.HandleFunc("/orphans", s.orphansHandler)
+	mux.HandleFunc("/mempool/history", s.mempoolHistoryHandler)
+	mux.HandleFunc("/utxos", s.utxosHandler)
+	mux.HandleFunc("/balance/", s.balanceHandler)
+	mux.HandleFunc("/address/", s.addressHandler)
+	mux.HandleFunc("/wallet/new", s.newWalletHandler)
+	mux.HandleFunc("/contracts", s.contractsHandler)
+	mux.HandleFunc("/receipts", s.receiptsHandler)
+	mux.HandleFunc("/kv/", s.kvHandler)
+	mux.HandleFunc("/blobs", s.requireAuth(s.putBlobHandler))
+	mux.HandleFunc("/blobs/", s.getBlobHandler)
+	mux.HandleFunc("/reveal/", s.requireAuth(s.revealHandler))
+	mux.HandleFunc("/logs", s.logsHandler)
+	mux.HandleFunc("/logs/ws", s.logsWSHandler)
+	mux.HandleFunc("/admin/simulate", s.requireAdmin(s.simulateHandler))
+	mux.HandleFunc("/admin/chaos", s.requireAdmin(s.chaosHandler))
+	mux.HandleFunc("/admin/difficulty", s.requireAdmin(s.difficultyHandler))
+	mux.HandleFunc("/admin/notarize", s.requireAdmin(s.notarizeHandler))
+	mux.HandleFunc("/admin/deadline", s.requireAdmin(s.deadlineHandler))
+	mux.HandleFunc("/admin/reset", s.requireAdmin(s.resetHandler))
+	mux.HandleFunc("/admin/freeze", s.requireAdmin(s.freezeHandler))
+	mux.HandleFunc("/admin/unfreeze", s.requireAdmin(s.unfreezeHandler))
+	mux.HandleFunc("/admin/labels/", s.requireAdmin(s.labelHandler))
+	mux.HandleFunc("/labels", s.labelsHandler)
+	mux.HandleFunc("/readyz", s.readyzHandler)
+	mux.HandleFunc("/status", s.statusHandler)
+	mux.HandleFunc("/validate", s.validateHandler)
+	mux.HandleFunc("/tamper", s.requireNode(s.tamperHandler))
+	mux.HandleFunc("/fork-view", s.forkViewHandler)
+	mux.HandleFunc("/peers", s.requireAuth(s.peersHandler))
+	mux.HandleFunc("/peers/sync", s.requireAuth(s.syncHandler))
+	mux.HandleFunc("/reorgs", s.reorgsHandler)
+	mux.HandleFunc("/snapshots", s.requireAuth(s.snapshotsHandler))
+	mux.HandleFunc("/snapshots/", s.snapshotHandler)
+	mux.HandleFunc("/replica", s.replicaHandler)
+	mux.HandleFunc("/p2p/blocks", s.requireNode(s.receiveBlockHandler))
+	mux.HandleFunc("/events", s.eventsHandler)
+	mux.HandleFunc("/ws", s.wsHandler)
+	mux.HandleFunc("/follow", s.followHandler)
+	mux.HandleFunc("/watch", s.requireAuth(s.watchHandler))
+	mux.HandleFunc("/watch/", s.requireAuth(s.watchItemHandler))
+	mux.Handle("/", explorerHandler())
+	return s.withRequestContext(s.cors(s.limitIPs(s.readOnlyAdmin(s.limit(s.meterKeys(mux))))))
+}
+
+// AddTransaction queues tx unless writes are disabled or the submission
+// deadline has passed. A transaction
+// spending from one that hasn't confirmed is held in the orphan pool and
+// ErrOrphaned returned; it is queued once its parents are mined.
+func (s *Server) AddTransaction(ctx context.Context, tx blockchain.Transaction) error {
+	return s.addTransaction(ctx, tx, true)
+}
+
+// addTransaction is AddTransaction; charge is false for transactions
+// accepted once already (promoted orphans, requeued or stored ones), which
+// skip the quotas and the submission deadline
+func (s *Server) addTransaction(ctx context.Context, tx blockchain.Transaction, charge bool) error {
+	tx.Size = 0 // response-only
+	if s.Unhealthy() != "" {
+		return ErrUnhealthy
+	}
+	if charge {
+		if err := s.checkFrozen(); err != nil {
+			return err
+		}
+		if err := s.checkDeadline(); err != nil {
+			return err
+		}
+	}
+	invalid, err := s.validateNew(ctx, tx)
+	if err != nil {
+		return err
+	}
+	orphan := invalid != nil
+	undo := func() {}
+	if charge {
+		if tx.Priority == blockchain.PriorityHigh {
+			if err := s.allowHighPriority(ctx); err != nil {
+				return err
+			}
+		}
+		if undo, err = s.takeTransaction(ctx); err != nil {
+			return err
+		}
+	}
+	if orphan {
+		now := clock.Or(s.opts.Clock).Now()
+		s.orphans.Expire(now, mempool.OrphanTTL)
+		if err := s.orphans.Add(tx, now); err != nil {
+			undo()
+			return err
+		}
+		logf(ctx, "holding orphan %s: %v", tx.ID, invalid)
+		return fmt.Errorf("%w: %v", ErrOrphaned, invalid)
+	}
+	s.txMu.Lock()
+	if idx, ok := s.chain.HasTx(tx.ID); ok {
+		// confirmed since it was validated
+		s.txMu.Unlock()
+		undo()
+		return fmt.Errorf("%w: %s in block %d", ErrAlreadyConfirmed, tx.ID, idx)
+	}
+	if charge {
+		// a freeze that began since the first check waits on txMu
+		if err := s.checkFrozen(); err != nil {
+			s.txMu.Unlock()
+			undo()
+			return err
+		}
+	}
+	err = s.pool.Add(tx)
+	s.txMu.Unlock()
+	if err != nil {
+		undo()
+		return err
+	}
+	s.pendingAdded(tx)
+	s.persistPending(ctx)
+	s.assertInvariants(ctx)
+	s.events.Publish(events.TxAdded, map[string]string{"txid": tx.ID, "data": tx.Data})
+	s.watchPending(ctx, tx)
+	return nil
+}
+
+// DropTransaction removes the pending transaction txid from the mempool
+// and returns it
+func (s *Server) DropTransaction(ctx context.Context, txid string) (blockchain.Transaction, error) {
+	s.txMu.Lock()
+	tx, ok := s.pool.Remove(txid)
+	s.txMu.Unlock()
+	if !ok {
+		if _, confirmed := s.chain.HasTx(txid); confirmed {
+			return tx, ErrAlreadyConfirmed
+		}
+		return tx, fmt.Errorf("%w %s: not pending", ErrUnknownTx, txid)
+	}
+	s.pendingLeft(PendingDropped, "dropped on request", tx)
+	s.persistPending(ctx)
+	s.assertInvariants(ctx)
+	logf(ctx, "AUDIT dropped pending transaction %s", txid)
+	s.events.Publish(events.TxDropped, map[string]string{"txid": txid})
+	return tx, nil
+}
+
+// dropExpired removes the pending transactions the next block may no
+// longer include, and those pending longer than PendingTTL, and returns
+// how many; callers persist the mempool
+func (s *Server) dropExpired(ctx context.Context) int {
+	stale := s.stalePending()
+	s.txMu.Lock()
+	expired := s.pool.RemoveExpired(s.chain.Len())
+	var aged []blockchain.Transaction
+	for _, txid := range stale {
+		if tx, ok := s.pool.Remove(txid); ok {
+			aged = append(aged, tx)
+		}
+	}
+	s.txMu.Unlock()
+	for _, tx := range expired {
+		logf(ctx, "dropping pending transaction %s: expired at height %d", tx.ID, tx.ExpiresAt)
+		s.pendingLeft(PendingExpired, fmt.Sprintf("expired at height %d", tx.ExpiresAt), tx)
+		s.events.Publish(events.TxDropped, map[string]string{"txid": tx.ID, "reason": "expired"})
+	}
+	for _, tx := range aged {
+		logf(ctx, "dropping pending transaction %s: pending for over %s", tx.ID, s.opts.PendingTTL)
+		s.pendingLeft(PendingExpired, fmt.Sprintf("pending for over %s", s.opts.PendingTTL), tx)
+		s.events.Publish(events.TxDropped, map[string]string{"txid": tx.ID, "reason": "expired"})
+	}
+	return len(expired) + len(aged)
+}
+
+// stalePending lists the transactions that entered the mempool more than
+// PendingTTL ago
+func (s *Server) stalePending() []string {
+	if s.opts.PendingTTL <= 0 {
+		return nil
+	}
+	cutoff := clock.Or(s.opts.Clock).Now().Add(-s.opts.PendingTTL).Unix()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []string
+	for txid, added := range s.history.since {
+		if added < cutoff {
+			out = append(out, txid)
+		}
+	}
+	return out
+}
+
+// ExpirePending drops the transactions pending longer than PendingTTL, or
+// past their expiry height, and returns how many
+func (s *Server) ExpirePending(ctx context.Context) int {
+	n := s.dropExpired(ctx)
+	if n > 0 {
+		s.persistPending(ctx)
+		s.assertInvariants(ctx)
+	}
+	return n
+}
+
+// validateNew checks tx against the chain, its blob store and the mempool
+// size limit. invalid is the missing input error of a transaction that has
+// to wait as an orphan; err is why tx is refused outright.
+func (s *Server) validateNew(ctx context.Context, tx blockchain.Transaction) (invalid, err error) {
+	invalid = s.chain.ValidateTx(tx)
+	if invalid != nil && !errors.Is(invalid, blockchain.ErrMissingInput) {
+		return nil, invalid
+	}
+	if max := s.opts.MaxTxBytes; max > 0 && blockchain.TxSize(tx) > max {
+		return nil, fmt.Errorf("%w: %d bytes, over the %d this node accepts", blockchain.ErrInvalidTx, blockchain.TxSize(tx), max)
+	}
+	if tx.Blob != "" {
+		if err := s.checkBlob(ctx, tx.Blob); err != nil {
+			return nil, err
+		}
+	}
+	if max := s.maxPending(); max > 0 && s.pool.Len() >= max {
+		return nil, fmt.Errorf("%w: mempool holds %d transactions", ErrQuotaExceeded, max)
+	}
+	return invalid, nil
+}
+
+// blockCut returns how many of txns fit a block under MaxBlockTxns and
+// MaxBlockBytes; the first always fits
+func (s *Server) blockCut(txns []blockchain.Transaction) int {
+	n := len(txns)
+	if max := s.opts.MaxBlockTxns; max > 0 && n > max {
+		n = max
+	}
+	if max := s.opts.MaxBlockBytes; max > 0 {
+		size := 0
+		for i := 0; i < n; i++ {
+			size += blockchain.TxSize(txns[i])
+			if size > max && i > 0 {
+				return i
+			}
+		}
+	}
+	return n
+}
+
+// maxPending is the smaller of the node's and the policy's mempool caps; 0 is unlimited
+func (s *Server) maxPending() int {
+	max, p := s.opts.MaxPending, s.Policy().MaxPending
+	if p > 0 && (max == 0 || p < max) {
+		max = p
+	}
+	return max
+}
+
+// MinePending mines the pending transactions into a new block, up to
+// MaxBlockTxns of them, high priority first and then by fee rate; ok is false
+// when the mempool is empty. If ctx ends first the transactions go back to
+// the mempool and ctx.Err() is returned.
+func (s *Server) MinePending(ctx context.Context) (mined blockchain.Block, ok bool, err error) {
+	return s.MineAs(ctx, "")
+}
+
+// MineAs is MinePending crediting the block to miner on the leaderboard.
+// When miner is an address and the chain pays a block reward, the block
+// opens with a coinbase paying it, and is mined even with nothing pending.
+func (s *Server) MineAs(ctx context.Context, miner string) (mined blockchain.Block, ok bool, err error) {
+	return s.mine(ctx, miner, 0, false)
+}
+
+// MineAt is MineAs mining the block at difficulty, recorded in its header,
+// instead of the chain's; 0 keeps the chain's. The difficulty must be one
+// the chain's overrides allow.
+func (s *Server) MineAt(ctx context.Context, miner string, difficulty int) (mined blockchain.Block, ok bool, err error) {
+	if err := s.checkOverride(difficulty); err != nil {
+		return blockchain.Block{}, false, err
+	}
+	return s.mine(ctx, miner, difficulty, false)
+}
+
+// MineScheduled mines whatever is pending, even nothing, so the chain grows
+// on a fixed schedule, credited to the MineAddress option
+func (s *Server) MineScheduled(ctx context.Context) (blockchain.Block, error) {
+	mined, _, err := s.mine(ctx, s.opts.MineAddress, 0, true)
+	return mined, err
+}
+
+// mine builds, seals and appends the next block, at difficulty unless 0;
+// unless empty is set it gives up when there is nothing to mine
+func (s *Server) mine(ctx context.Context, miner string, difficulty int, empty bool) (mined blockchain.Block, ok bool, err error) {
+	if s.Unhealthy() != "" {
+		return blockchain.Block{}, false, ErrUnhealthy
+	}
+	s.mineMu.Lock()
+	defer s.mineMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return blockchain.Block{}, false, err
+	}
+	// checked under mineMu, which Freeze waits on, so no block starts after it
+	ctx, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
+	s.mu.Lock()
+	frozen := !s.frozen.since.IsZero()
+	s.stopMining = stop
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.stopMining = nil
+		s.mu.Unlock()
+	}()
+	if frozen {
+		return blockchain.Block{}, false, ErrFrozen
+	}
+	if s.chain.BlockReward() > 0 && blockchain.IsAddress(miner) {
+		// the coinbase alone makes the block worth mining, which is how
+		// coins first appear on a chain that only takes signed transfers
+		empty = true
+	}
+	// txMu keeps submissions from claiming the inputs of the transactions
+	// left for the next block, so all of them go back
+	s.txMu.Lock()
+	pending := s.pool.Drain()
+	blockchain.ByPriorityAndFee(pending, s.feeRate)
+	txns := s.selectTxns(ctx, pending)
+	if n := s.blockCut(txns); n < len(txns) {
+		// the rest wait for the next block, ahead of later arrivals
+		s.pool.Restore(txns[n:])
+		txns = txns[:n]
+	}
+	s.txMu.Unlock()
+	if len(txns) == 0 && !empty {
+		return blockchain.Block{}, false, nil
+	}
+	template := s.chain.NextBlock(txns)
+	if len(template.Txns) < len(txns) {
+		s.pendingLeft(PendingInvalid, "left out of the block template", leftOut(txns, template.Txns)...)
+	}
+	if len(template.Txns) == 0 && !empty {
+		return blockchain.Block{}, false, nil
+	}
+	txns = template.Txns
+	if miner != "" {
+		template = s.chain.NextBlock(s.withCoinbase(template.Index, miner, txns))
+		template.Miner = miner
+	}
+	template.Producer = s.opts.NodeID
+	if difficulty != 0 {
+		template.Difficulty = difficulty
+	}
+	s.miningStarted(ctx, template.Index)
+	s.events.Publish(events.MiningStarted, map[string]interface{}{"index": template.Index, "transactions": len(txns)})
+	logf(ctx, "mining block %d with %d transactions at difficulty %d", template.Index, len(txns), template.Difficulty)
+	start := time.Now()
+	s.mining.start(start)
+	var tried int64
+	mined, err = s.chain.Produce(blockchain.WithProgress(ctx, func(n int64) { tried = n }), template)
+	if err != nil {
+		s.mining.finish(0, 0)
+		s.pendingLeft(PendingConflict, "input spent while the block was mined", s.pool.Restore(txns)...)
+		if cause := context.Cause(ctx); errors.Is(cause, ErrFrozen) {
+			err = cause
+		}
+		logf(ctx, "mining block %d aborted: %v", template.Index, err)
+		return blockchain.Block{}, false, err
+	}
+	if tried == 0 {
+		tried = mined.Nonce + 1 // consensus without a nonce search
+	}
+	s.mining.finish(tried, time.Since(start))
+	s.txMu.Lock()
+	if err := s.chain.AddBlock(mined); err != nil {
+		s.txMu.Unlock()
+		s.pendingLeft(PendingConflict, "input spent while the block was mined", s.pool.Restore(txns)...)
+		return blockchain.Block{}, false, err
+	}
+	// copies of the mined transactions submitted while mining are now stale
+	stale := s.pool.RemoveConfirmed(s.confirmed)
+	s.txMu.Unlock()
+	s.pendingLeft(PendingConfirmed, "", txns...)
+	s.pendingLeft(PendingConfirmed, "", stale...)
+	logf(ctx, "mined block %d in %s (nonce %d, %d hashes)", mined.Index, time.Since(start).Round(time.Millisecond), mined.Nonce, tried)
+	s.blockAdded(ctx, mined)
+	return mined, true, nil
+}
+
+// blockAdded follows up on b joining the tip, whether mined here or
+// received from a peer: it stores b, promotes orphans, notifies
+// subscribers and watches, and passes b on to the peers
+func (s *Server) blockAdded(ctx context.Context, b blockchain.Block) {
+	s.persistBlock(ctx, b)
+	s.dropExpired(ctx)
+	s.persistPending(ctx)
+	s.promoteOrphans(ctx)
+	s.assertInvariants(ctx)
+	s.events.Publish(events.BlockMined, b)
+	s.watchBlocks(ctx)
+	if logs := s.chain.Logs(blockchain.LogFilter{FromBlock: b.Index, ToBlock: b.Index}); len(logs) > 0 {
+		s.events.Publish(events.Logs, logs)
+	}
+	s.broadcastBlock(ctx, b)
+	s.attest(ctx)
+}
+
+// confirmed reports whether a block holds txid
+func (s *Server) confirmed(txid string) bool {
+	_, ok := s.chain.HasTx(txid)
+	return ok
+}
+
+// withCoinbase prepends the block reward for miner to txns, if there is one
+func (s *Server) withCoinbase(height int, miner string, txns []blockchain.Transaction) []blockchain.Transaction {
+	reward := s.chain.BlockReward()
+	if reward <= 0 || !blockchain.IsAddress(miner) {
+		return txns
+	}
+	coinbase := blockchain.NewCoinbaseTx(height, script.P2PKH(miner), reward)
+	return append([]blockchain.Transaction{coinbase}, txns...)
+}
+
+// promoteOrphans queues the orphans whose parents have now confirmed
+func (s *Server) promoteOrphans(ctx context.Context) {
+	ready := s.orphans.Promote(func(id string) bool {
+		_, ok := s.chain.HasTx(id)
+		return ok
+	})
+	for _, tx := range ready {
+		if err := s.addTransaction(ctx, tx, false); err != nil {
+			logf(ctx, "dropping orphan %s: %v", tx.ID, err)
+			continue
+		}
+		logf(ctx, "promoted orphan %s to the mempool", tx.ID)
+	}
+}
+
+// selectTxns drops transactions that are no longer valid on top of the tip,
+// such as spends of outputs another transaction claimed first
+func (s *Server) selectTxns(ctx context.Context, txns []blockchain.Transaction) []blockchain.Transaction {
+	kept := txns[:0]
+	spent := map[blockchain.OutPoint]bool{}
+next:
+	for _, t := range txns {
+		if err := s.chain.ValidateTx(t); err != nil {
+			logf(ctx, "dropping pending transaction %s: %v", t.ID, err)
+			s.pendingLeft(PendingInvalid, err.Error(), t)
+			continue
+		}
+		for _, op := range t.Spends() {
+			if spent[op] {
+				logf(ctx, "dropping pending transaction %s: %s already spent in this block", t.ID, op)
+				s.pendingLeft(PendingConflict, fmt.Sprintf("%s already spent in the same block", op), t)
+				continue next
+			}
+		}
+		for _, op := range t.Spends() {
+			spent[op] = true
+		}
+		kept = append(kept, t)
+	}
+	return kept
+}
+
+// leftOut returns the transactions of txns missing from kept, which holds
+// a subsequence of them
+func leftOut(txns, kept []blockchain.Transaction) []blockchain.Transaction {
+	var out []blockchain.Transaction
+	j := 0
+	for _, t := range txns {
+		if j < len(kept) && kept[j].ID == t.ID {
+			j++
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// Unhealthy returns the first invariant violation, or "" while healthy
+func (s *Server) Unhealthy() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.unhealthy
+}
+
+// assertInvariants marks the node unhealthy on the first violation (debug mode only)
+func (s *Server) assertInvariants(ctx context.Context) {
+	if !s.opts.Debug || s.Unhealthy() != "" {
+		return
+	}
+	err := s.chain.CheckInvariants()
+	if err == nil {
+		err = s.pool.CheckInvariants()
+	}
+	if err == nil {
+		// the mempool never holds a confirmed txid
+		for _, id := range s.pool.IDs() {
+			if idx, ok := s.chain.HasTx(id); ok {
+				err = fmt.Errorf("pending txid %s already confirmed in block %d", id, idx)
+				break
+			}
+		}
+	}
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.unhealthy = err.Error()
+	s.mu.Unlock()
+	logf(ctx, "invariant violated, refusing further writes: %v", err)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+func TestMaxPendingCapsTheMempool(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(blockchain.NewChain(1).Tip())
+	s.opts.MaxPending = 2
+	for i := 0; i < 2; i++ {
+		if err := s.AddTransaction(ctx, record(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.AddTransaction(ctx, record(2)); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("submitting to a full mempool: %v, want ErrQuotaExceeded", err)
+	}
+	if _, err := s.DropTransaction(ctx, record(0).ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddTransaction(ctx, record(2)); err != nil {
+		t.Fatalf("submitting after a drop made room: %v", err)
+	}
+}
+
+func TestMaxBlockTxnsLeavesTheRestPendingFirst(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(blockchain.NewChain(1).Tip())
+	s.opts.MaxBlockTxns = 2
+	for i := 0; i < 3; i++ {
+		if err := s.AddTransaction(ctx, record(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mined, ok, err := s.MinePending(ctx)
+	if err != nil || !ok {
+		t.Fatalf("mining: %t, %v", ok, err)
+	}
+	if len(mined.Txns) != 2 || mined.Txns[0].ID != record(0).ID || mined.Txns[1].ID != record(1).ID {
+		t.Fatalf("mined %d transactions, want records 0 and 1", len(mined.Txns))
+	}
+	// a later arrival queues behind the one the block left out
+	if err := s.AddTransaction(ctx, record(3)); err != nil {
+		t.Fatal(err)
+	}
+	pending := s.pool.All()
+	if len(pending) != 2 || pending[0].ID != record(2).ID {
+		t.Fatalf("pending %d transactions, want record 2 first", len(pending))
+	}
+}
+
+func TestDropTransaction(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(blockchain.NewChain(1).Tip())
+	if err := s.AddTransaction(ctx, record(0)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.MinePending(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DropTransaction(ctx, record(0).ID); !errors.Is(err, ErrAlreadyConfirmed) {
+		t.Errorf("dropping a confirmed transaction: %v, want ErrAlreadyConfirmed", err)
+	}
+	if _, err := s.DropTransaction(ctx, record(1).ID); !errors.Is(err, ErrUnknownTx) {
+		t.Errorf("dropping an unknown transaction: %v, want ErrUnknownTx", err)
+	}
+}
@@ -0,0 +1,267 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/clock"
+)
+
+// maxSnapshots is how many snapshots a chain keeps; taking another drops the oldest
+const maxSnapshots = 200
+
+// ErrNoSnapshot is returned for a snapshot id that isn't kept
+var ErrNoSnapshot = errors.New("no such snapshot")
+
+// SnapshotRef identifies a snapshot without its balances and mempool
+type SnapshotRef struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Time    int64  `json:"time"`
+	Height  int    `json:"height"`
+	TipHash string `json:"tip_hash"`
+	Pending int    `json:"pending"` // transactions in the mempool
+}
+
+// BlockSummary is a block without its transactions
+type BlockSummary struct {
+	Index     int    `json:"index"`
+	Hash      string `json:"hash"`
+	Timestamp int64  `json:"timestamp"`
+	Txns      int    `json:"transactions"`
+	Miner     string `json:"miner,omitempty"`
+	Producer  string `json:"producer,omitempty"`
+}
+
+// blockSummary summarizes b
+func blockSummary(b blockchain.Block) BlockSummary {
+	return BlockSummary{Index: b.Index, Hash: b.Hash, Timestamp: b.Timestamp, Txns: len(b.Txns), Miner: b.Miner, Producer: b.Producer}
+}
+
+// RemovedTx is a transaction pending in the first snapshot but not the second
+type RemovedTx struct {
+	blockchain.Transaction
+	Confirmed bool `json:"confirmed"` // mined by the second snapshot, rather than dropped
+}
+
+// SnapshotDiff is the response of /snapshots/{a}/diff/{b}
+type SnapshotDiff struct {
+	From SnapshotRef `json:"from"`
+	To   SnapshotRef `json:"to"`
+	// Reorganized is set when the chain no longer holds both snapshots'
+	// tips, after a reset or a switch to a peer's chain; Blocks is then
+	// empty while balances and the mempool are still compared
+	Reorganized bool                     `json:"reorganized"`
+	Blocks      []BlockSummary           `json:"blocks_added"`     // after From's tip up to To's
+	Balances    []BalanceEffect          `json:"balances_changed"` // ordered by address
+	Added       []blockchain.Transaction `json:"mempool_added"`
+	Removed     []RemovedTx              `json:"mempool_removed"`
+}
+
+// snapshotRef summarizes snap
+func snapshotRef(snap blockchain.Snapshot) SnapshotRef {
+	return SnapshotRef{ID: snap.ID, Name: snap.Name, Time: snap.Time, Height: snap.Height, TipHash: snap.TipHash, Pending: len(snap.Pending)}
+}
+
+// TakeSnapshot records the tip, balances and mempool under name
+func (s *Server) TakeSnapshot(ctx context.Context, name string) blockchain.Snapshot {
+	s.txMu.Lock()
+	tip, _ := s.chain.BlockAt(s.chain.Len() - 1)
+	snap := blockchain.Snapshot{
+		Name:     name,
+		Time:     clock.Or(s.opts.Clock).Now().Unix(),
+		Height:   tip.Index,
+		TipHash:  tip.Hash,
+		Balances: s.chain.Balances(),
+		Pending:  s.pool.All(),
+	}
+	s.txMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap.ID = 1
+	if n := len(s.snapshots); n > 0 {
+		snap.ID = s.snapshots[n-1].ID + 1
+	}
+	s.snapshots = append(s.snapshots, snap)
+	if len(s.snapshots) > maxSnapshots {
+		s.snapshots = append([]blockchain.Snapshot{}, s.snapshots[len(s.snapshots)-maxSnapshots:]...)
+	}
+	logf(ctx, "took snapshot %d at height %d", snap.ID, snap.Height)
+	s.persistSnapshots(ctx)
+	return snap
+}
+
+// Snapshots lists the kept snapshots, oldest first
+func (s *Server) Snapshots() []SnapshotRef {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]SnapshotRef, 0, len(s.snapshots))
+	for _, snap := range s.snapshots {
+		out = append(out, snapshotRef(snap))
+	}
+	return out
+}
+
+// Snapshot returns the snapshot with the given id
+func (s *Server) Snapshot(id int) (blockchain.Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, snap := range s.snapshots {
+		if snap.ID == id {
+			return snap, nil
+		}
+	}
+	return blockchain.Snapshot{}, fmt.Errorf("%w %d", ErrNoSnapshot, id)
+}
+
+// DiffSnapshots reports what happened between snapshots a and b: the
+// blocks mined, the balances that moved and how the mempool changed
+func (s *Server) DiffSnapshots(a, b int) (SnapshotDiff, error) {
+	from, err := s.Snapshot(a)
+	if err != nil {
+		return SnapshotDiff{}, err
+	}
+	to, err := s.Snapshot(b)
+	if err != nil {
+		return SnapshotDiff{}, err
+	}
+	d := SnapshotDiff{
+		From:     snapshotRef(from),
+		To:       snapshotRef(to),
+		Blocks:   []BlockSummary{},
+		Balances: []BalanceEffect{},
+		Added:    []blockchain.Transaction{},
+		Removed:  []RemovedTx{},
+	}
+	onChain := func(snap blockchain.Snapshot) bool {
+		b, ok := s.chain.BlockAt(snap.Height)
+		return ok && b.Hash == snap.TipHash
+	}
+	d.Reorganized = !onChain(from) || !onChain(to)
+	if !d.Reorganized {
+		for i := from.Height + 1; i <= to.Height; i++ {
+			b, _ := s.chain.BlockAt(i)
+			d.Blocks = append(d.Blocks, blockSummary(b))
+		}
+	}
+
+	for addr, after := range to.Balances {
+		if before := from.Balances[addr]; before != after {
+			d.Balances = append(d.Balances, BalanceEffect{Address: addr, Before: before, Change: after - before, After: after})
+		}
+	}
+	for addr, before := range from.Balances {
+		if _, ok := to.Balances[addr]; !ok {
+			d.Balances = append(d.Balances, BalanceEffect{Address: addr, Before: before, Change: -before})
+		}
+	}
+	sort.Slice(d.Balances, func(i, j int) bool { return d.Balances[i].Address < d.Balances[j].Address })
+	for i := range d.Balances {
+		d.Balances[i].Label = s.opts.Labels.Get(d.Balances[i].Address)
+	}
+
+	was := map[string]bool{}
+	for _, tx := range from.Pending {
+		was[tx.ID] = true
+	}
+	is := map[string]bool{}
+	for _, tx := range to.Pending {
+		is[tx.ID] = true
+		if !was[tx.ID] {
+			d.Added = append(d.Added, tx)
+		}
+	}
+	for _, tx := range from.Pending {
+		if is[tx.ID] {
+			continue
+		}
+		idx, _, ok := s.chain.TxPosition(tx.ID)
+		d.Removed = append(d.Removed, RemovedTx{Transaction: tx, Confirmed: ok && !d.Reorganized && idx <= to.Height})
+	}
+	return d, nil
+}
+
+// loadSnapshots restores stored snapshots
+func (s *Server) loadSnapshots(snaps []blockchain.Snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots = snaps
+}
+
+// persistSnapshots writes the snapshots to the store; a failure is only
+// logged, the next snapshot writes them all again (caller holds mu)
+func (s *Server) persistSnapshots(ctx context.Context) {
+	if s.opts.Store == nil {
+		return
+	}
+	if err := s.opts.Store.SaveSnapshots(s.snapshots); err != nil {
+		logf(ctx, "storing snapshots failed: %v", err)
+	}
+}
+
+// list snapshots (GET) or take one (POST, optionally {"name": "..."}): /snapshots
+func (s *Server) snapshotsHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	switch r.Method {
+	case "GET":
+		json.NewEncoder(w).Encode(s.Snapshots())
+	case "POST":
+		var body struct {
+			Name string `json:"name"`
+		}
+		if r.ContentLength != 0 {
+			if err := decodeJSON(w, r, &body); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid body")
+				return
+			}
+		}
+		snap := s.TakeSnapshot(r.Context(), body.Name)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(snapshotRef(snap))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// a snapshot: GET /snapshots/{id}, or what changed between two:
+// GET /snapshots/{a}/diff/{b}
+func (s *Server) snapshotHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/snapshots/"), "/")
+	if len(parts) != 1 && (len(parts) != 3 || parts[1] != "diff") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	ids := make([]int, 0, 2)
+	for i := 0; i < len(parts); i += 2 {
+		id, err := strconv.Atoi(parts[i])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid snapshot id "+strconv.Quote(parts[i]))
+			return
+		}
+		ids = append(ids, id)
+	}
+	var out interface{}
+	var err error
+	if len(ids) == 1 {
+		out, err = s.Snapshot(ids[0])
+	} else {
+		out, err = s.DiffSnapshots(ids[0], ids[1])
+	}
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,276 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+// statsWindows are the block counts /stats and /stats/blocktime summarize; 0 is the whole chain
+var statsWindows = []int{10, 100, 0}
+
+// benchmarkSample is how long /stats/blocktime benchmarks the hasher when
+// the node hasn't mined anything yet
+const benchmarkSample = 100 * time.Millisecond
+
+// miningMeter tracks the hashrate the node achieves while mining
+type miningMeter struct {
+	mu        sync.Mutex
+	hashes    int64
+	took      time.Duration
+	blocks    int64     // searches that found a block
+	last      float64   // hashes per second of the last search that found one
+	since     time.Time // start of the search in progress, zero when idle
+	benchOnce sync.Once
+	bench     float64
+}
+
+func (m *miningMeter) start(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.since = now
+}
+
+// finish ends the search in progress; hashes is 0 when it was abandoned
+func (m *miningMeter) finish(hashes int64, took time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.since = time.Time{}
+	if hashes > 0 {
+		m.hashes += hashes
+		m.took += took
+		m.blocks++
+		if took > 0 {
+			m.last = float64(hashes) / took.Seconds()
+		}
+	}
+}
+
+// totals returns the hashes tried and blocks found so far, and the rate of
+// the last search that found one
+func (m *miningMeter) totals() (hashes, blocks int64, last float64, mining bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.hashes, m.blocks, m.last, !m.since.IsZero()
+}
+
+// rate returns hashes per second observed while mining, falling back to a
+// one-off benchmark of the hasher on workers goroutines before the first
+// block
+func (m *miningMeter) rate(h blockchain.Hasher, workers int) (rate float64, source string, since time.Time) {
+	m.mu.Lock()
+	hashes, took, since := m.hashes, m.took, m.since
+	m.mu.Unlock()
+	if hashes > 0 && took > 0 {
+		return float64(hashes) / took.Seconds(), "observed", since
+	}
+	m.benchOnce.Do(func() { m.bench = blockchain.MeasureHashrate(h, benchmarkSample, workers) })
+	return m.bench, "benchmark", since
+}
+
+// miningWorkers is how many goroutines the chain's consensus mines with
+func (s *Server) miningWorkers() int {
+	if pow, ok := s.chain.Consensus().(*blockchain.ProofOfWork); ok {
+		return pow.Parallelism()
+	}
+	return 1
+}
+
+// BlockTimeStats is the response of /stats/blocktime
+type BlockTimeStats struct {
+	Height         int                        `json:"height"`
+	Difficulty     int                        `json:"difficulty"`
+	Windows        []blockchain.IntervalStats `json:"windows"`
+	Hashrate       float64                    `json:"hashrate"`        // hashes per second
+	HashrateSource string                     `json:"hashrate_source"` // "observed" while mining, else "benchmark"
+	ExpectedBlock  float64                    `json:"expected_block_seconds"`
+	Mining         bool                       `json:"mining"`
+	Pending        int                        `json:"pending"`
+	ETA            float64                    `json:"eta_seconds"` // until the next block, counting a search in progress
+}
+
+// BlockTimeStats summarizes recent block intervals and estimates when the next block lands
+func (s *Server) BlockTimeStats() BlockTimeStats {
+	st := BlockTimeStats{
+		Height:     s.chain.Len() - 1,
+		Difficulty: s.chain.Difficulty(),
+		Pending:    s.pool.Len(),
+	}
+	for _, w := range statsWindows {
+		st.Windows = append(st.Windows, s.chain.BlockIntervals(w))
+	}
+	rate, source, since := s.mining.rate(s.chain.Hasher(), s.miningWorkers())
+	st.Hashrate, st.HashrateSource = rate, source
+	if rate > 0 {
+		st.ExpectedBlock = blockchain.ExpectedBlockTime(rate, st.Difficulty).Seconds()
+	}
+	st.ETA = st.ExpectedBlock
+	if !since.IsZero() {
+		st.Mining = true
+		// proof-of-work is memoryless, but the elapsed search is a better
+		// guide for a live countdown than a fresh estimate
+		if st.ETA -= time.Since(since).Seconds(); st.ETA < 0 {
+			st.ETA = 0
+		}
+	}
+	return st
+}
+
+// Stats is the response of /stats
+type Stats struct {
+	Height       int                    `json:"height"`
+	Transactions int                    `json:"transactions"` // confirmed, coinbases included
+	AvgBlockTime float64                `json:"avg_block_seconds"`
+	Difficulty   int                    `json:"difficulty"`
+	Pending      int                    `json:"pending"`
+	PendingBytes int                    `json:"pending_bytes"`
+	Hashrate     float64                `json:"last_hashrate"` // hashes per second of the last block mined here, 0 before one
+	MinedBlocks  int64                  `json:"mined_blocks"`  // by this node since it started
+	Mining       bool                   `json:"mining"`
+	Sizes        []blockchain.SizeStats `json:"sizes"`
+}
+
+// Stats reports the chain's height, transactions, block time and
+// difficulty, the node's mining, and block and transaction sizes over
+// recent windows and the size of the mempool
+func (s *Server) Stats() Stats {
+	st := Stats{
+		Height:       s.chain.Len() - 1,
+		Transactions: s.chain.TxCount(),
+		AvgBlockTime: s.chain.BlockIntervals(0).Avg,
+		Difficulty:   s.chain.Difficulty(),
+	}
+	for _, t := range s.pool.All() {
+		st.Pending++
+		st.PendingBytes += blockchain.TxSize(t)
+	}
+	_, st.MinedBlocks, st.Hashrate, st.Mining = s.mining.totals()
+	for _, w := range statsWindows {
+		st.Sizes = append(st.Sizes, s.chain.BlockSizes(w))
+	}
+	return st
+}
+
+// chain, mining and size statistics
+func (s *Server) statsHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	json.NewEncoder(w).Encode(s.Stats())
+}
+
+// block interval statistics and next-block ETA
+func (s *Server) blockTimeStatsHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	json.NewEncoder(w).Encode(s.BlockTimeStats())
+}
+
+// maxDifficultySteps bounds the difficulty changes /difficulty lists
+const maxDifficultySteps = 20
+
+// DifficultyStatus is the response of /difficulty
+type DifficultyStatus struct {
+	Height       int                         `json:"height"`
+	Difficulty   int                         `json:"difficulty"` // what the next block must meet
+	Retargeting  bool                        `json:"retargeting"`
+	Every        int                         `json:"retarget_every,omitempty"`       // blocks between adjustments
+	Target       float64                     `json:"target_block_seconds,omitempty"` // block interval aimed for
+	NextRetarget int                         `json:"next_retarget_height,omitempty"` // height of the next adjustment
+	Recent       blockchain.IntervalStats    `json:"recent"`                         // over the last retarget window, else statsWindows[0] blocks
+	Steps        []blockchain.DifficultyStep `json:"steps"`                          // latest changes, oldest first
+	Schedule     []blockchain.DifficultyStep `json:"schedule,omitempty"`             // minimum in force from each height on, when it has changed
+	Overrides    *blockchain.DifficultyRange `json:"overrides,omitempty"`            // difficulties POST /mine?difficulty= may ask for
+}
+
+// Difficulty reports the difficulty in force, how it retargets and the
+// block times it retargets from
+func (s *Server) Difficulty() DifficultyStatus {
+	rt := s.chain.Retarget()
+	st := DifficultyStatus{
+		Height:      s.chain.Len() - 1,
+		Difficulty:  s.chain.Difficulty(),
+		Retargeting: rt.Every > 0,
+		Steps:       s.chain.DifficultySteps(maxDifficultySteps),
+		Schedule:    s.chain.DifficultySchedule(),
+	}
+	window := statsWindows[0]
+	if st.Retargeting {
+		st.Every, st.Target = rt.Every, rt.Target.Seconds()
+		st.NextRetarget = s.chain.NextRetarget()
+		window = rt.Every + 1 // Every intervals
+	}
+	if r := s.chain.DifficultyOverrides(); r.Max > 0 {
+		st.Overrides = &r
+	}
+	st.Recent = s.chain.BlockIntervals(window)
+	return st
+}
+
+// the difficulty in force and recent block times: GET /difficulty
+func (s *Server) difficultyStatusHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	json.NewEncoder(w).Encode(s.Difficulty())
+}
+
+// maxDifficulty is the most leading zeros a 64-digit hex hash can have
+const maxDifficulty = 64
+
+// DifficultyEstimate is the response of /difficulty/estimate
+type DifficultyEstimate struct {
+	Level          int     `json:"level"`
+	Current        int     `json:"current"`  // the chain's difficulty now
+	Attempts       float64 `json:"attempts"` // expected hashes per block, 16^level
+	Relative       float64 `json:"relative"` // attempts compared to the current difficulty
+	Hashrate       float64 `json:"hashrate"`
+	HashrateSource string  `json:"hashrate_source"`
+	Seconds        float64 `json:"expected_seconds"`
+	Duration       string  `json:"expected,omitempty"` // Seconds as a duration, when it fits one
+}
+
+// EstimateDifficulty returns what mining a block at level would cost at the
+// node's measured hashrate
+func (s *Server) EstimateDifficulty(level int) (DifficultyEstimate, error) {
+	if level < 0 || level > maxDifficulty {
+		return DifficultyEstimate{}, fmt.Errorf("level must be 0-%d", maxDifficulty)
+	}
+	est := DifficultyEstimate{
+		Level:    level,
+		Current:  s.chain.Difficulty(),
+		Attempts: math.Pow(16, float64(level)),
+	}
+	est.Relative = math.Pow(16, float64(level-est.Current))
+	est.Hashrate, est.HashrateSource, _ = s.mining.rate(s.chain.Hasher(), s.miningWorkers())
+	if est.Hashrate > 0 {
+		// ExpectedBlockTime overflows a time.Duration past ~292 years
+		est.Seconds = est.Attempts / est.Hashrate
+		if est.Seconds < float64(math.MaxInt64/int64(time.Second)) {
+			est.Duration = blockchain.ExpectedBlockTime(est.Hashrate, level).Round(time.Millisecond).String()
+		}
+	}
+	return est, nil
+}
+
+// expected work and mining time at a difficulty: GET /difficulty/estimate?level=N
+// (default the current difficulty)
+func (s *Server) difficultyEstimateHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	level := s.chain.Difficulty()
+	if v := r.URL.Query().Get("level"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid level")
+			return
+		}
+		level = n
+	}
+	est, err := s.EstimateDifficulty(level)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(est)
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"salmanahmed/blockchain/pkg/clock"
+)
+
+// Version identifies the node build in /status; release builds set it with
+// -ldflags "-X salmanahmed/blockchain/pkg/api.Version=v1.2.3"
+var Version = "dev"
+
+// NodeStatus is the response of /status: what the node runs and how it is
+// doing, so frontends and scripts can adapt to its configuration
+type NodeStatus struct {
+	Version   string         `json:"version"`
+	ChainID   string         `json:"chain_id"` // empty for the default chain
+	NodeID    string         `json:"node_id,omitempty"`
+	Profile   string         `json:"profile,omitempty"` // config profile the node started from
+	Consensus string         `json:"consensus"`
+	Features  StatusFeatures `json:"features"`
+	Started   int64          `json:"started"` // unix seconds
+	Uptime    float64        `json:"uptime_seconds"`
+	Tip       BlockSummary   `json:"tip"`
+	Peers     int            `json:"peers"`
+	Pending   int            `json:"pending"` // transactions in the mempool
+	Orphans   int            `json:"orphans"` // held until their inputs confirm
+	Health    string         `json:"health"`  // ready, frozen or unhealthy, as /readyz
+}
+
+// StatusFeatures lists the behaviour a node's configuration switches on
+type StatusFeatures struct {
+	SignaturesRequired bool     `json:"signatures_required"`              // every transaction in the next block must carry an ECDSA signature
+	SignaturesFrom     int      `json:"signatures_from_height,omitempty"` // the height signatures are required from
+	Validators         []string `json:"validators"`                       // transaction rules, in the order they run
+	AutoMine           bool     `json:"auto_mine"`
+	MineEvery          float64  `json:"mine_every_seconds,omitempty"`
+	P2P                bool     `json:"p2p"` // blocks are exchanged with known peers
+	Replica            bool     `json:"replica"`
+	AuthRequired       bool     `json:"auth_required"` // writes need a bearer token
+	Faucet             bool     `json:"faucet"`
+	Attestations       bool     `json:"attestations"`
+	Blobs              bool     `json:"blobs"`
+	Demo               bool     `json:"demo"`
+	Chaos              bool     `json:"chaos"`
+	Debug              bool     `json:"debug"`
+}
+
+// Status reports the node's version, configuration and state in one call
+func (s *Server) Status() NodeStatus {
+	now := clock.Or(s.opts.Clock).Now()
+	tip, _ := s.chain.BlockAt(s.chain.Len() - 1)
+	validators := s.chain.TxValidators()
+	signedFrom, signed := s.chain.SignaturesFrom()
+	peers := len(s.peers.List())
+	st := NodeStatus{
+		Version:   Version,
+		ChainID:   s.opts.ChainID,
+		NodeID:    s.opts.NodeID,
+		Profile:   s.opts.Profile,
+		Consensus: s.chain.Consensus().Name(),
+		Features: StatusFeatures{
+			SignaturesRequired: signed && tip.Index+1 >= signedFrom,
+			SignaturesFrom:     signedFrom,
+			Validators:         validators,
+			AutoMine:           s.opts.MineEvery > 0,
+			MineEvery:          s.opts.MineEvery.Seconds(),
+			P2P:                peers > 0,
+			Replica:            s.Replica(),
+			AuthRequired:       s.opts.AuthToken != "",
+			Faucet:             s.opts.Faucet.Key != "",
+			Attestations:       s.opts.Attest.Key != "",
+			Blobs:              s.opts.Blobs != nil,
+			Demo:               s.opts.Demo,
+			Chaos:              s.opts.Chaos != nil,
+			Debug:              s.opts.Debug,
+		},
+		Started: s.started.Unix(),
+		Uptime:  now.Sub(s.started).Truncate(time.Second).Seconds(),
+		Tip:     blockSummary(tip),
+		Peers:   peers,
+		Pending: s.pool.Len(),
+		Orphans: s.orphans.Len(),
+		Health:  "ready",
+	}
+	if s.Unhealthy() != "" {
+		st.Health = "unhealthy"
+	} else if s.FreezeStatus().Frozen {
+		st.Health = "frozen"
+	}
+	return st
+}
+
+// the node's version, configuration and state: GET /status
+func (s *Server) statusHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	json.NewEncoder(w).Encode(s.Status())
+}
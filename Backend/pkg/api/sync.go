@@ -0,0 +1,289 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/clock"
+	"salmanahmed/blockchain/pkg/events"
+	"salmanahmed/blockchain/pkg/p2p"
+)
+
+// Statuses ReceiveBlock reports for a block offered by a peer
+const (
+	BlockAdded   = "block added"
+	BlockKnown   = "block known"
+	BlockStale   = "block stale"   // for a height the chain already holds another block at
+	BlockSyncing = "syncing chain" // the block doesn't extend the tip; peers' chains are being checked
+)
+
+// SyncResult reports a longest-chain sync with the peers
+type SyncResult struct {
+	Height   int               `json:"height"`
+	Replaced bool              `json:"replaced"`
+	Peer     string            `json:"peer,omitempty"` // whose chain was adopted
+	Dropped  int               `json:"dropped_blocks"` // local blocks the adopted chain replaced
+	Requeued int               `json:"requeued"`       // their transactions back in the mempool
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+// gossip returns a client for the peers, authenticating with the node's token
+func (s *Server) gossip() p2p.Client {
+	return p2p.Client{Token: s.opts.AuthToken}
+}
+
+// broadcastBlock offers b to every peer in the background
+func (s *Server) broadcastBlock(ctx context.Context, b blockchain.Block) {
+	for _, peer := range s.peers.List() {
+		if s.opts.Chaos.DropGossip() {
+			logf(ctx, "dropping block %d for %s", b.Index, peer)
+			continue
+		}
+		go func(peer string) {
+			status, err := s.gossip().SendBlock(context.Background(), peer, b)
+			if err != nil {
+				logf(ctx, "sending block %d to %s failed: %v", b.Index, peer, err)
+				return
+			}
+			logf(ctx, "sent block %d to %s: %s", b.Index, peer, status)
+		}(peer)
+	}
+}
+
+// ReceiveBlock takes a block a peer broadcast. A block extending the tip is
+// appended and passed on to the other peers; one that doesn't starts a
+// sync in the background, in case a peer now holds a longer chain.
+func (s *Server) ReceiveBlock(ctx context.Context, b blockchain.Block) (string, error) {
+	if s.Unhealthy() != "" {
+		return "", ErrUnhealthy
+	}
+	s.mineMu.Lock()
+	defer s.mineMu.Unlock()
+	if err := s.checkFrozen(); err != nil {
+		return "", err
+	}
+	if have, ok := s.chain.BlockAt(b.Index); ok {
+		if have.Hash == b.Hash {
+			return BlockKnown, nil
+		}
+		s.recordSideBlocks(SideReceived, b)
+		return BlockStale, nil
+	}
+	tip, _ := s.chain.BlockAt(s.chain.Len() - 1)
+	if b.Index != tip.Index+1 || b.PrevHash != tip.Hash {
+		s.recordSideBlocks(SideReceived, b)
+		go s.syncInBackground(ctx)
+		return BlockSyncing, nil
+	}
+	s.txMu.Lock()
+	if err := s.chain.AddBlock(b); err != nil {
+		s.txMu.Unlock()
+		return "", err
+	}
+	confirmed := s.pool.RemoveConfirmed(s.confirmed)
+	s.txMu.Unlock()
+	s.pendingLeft(PendingConfirmed, "", confirmed...)
+	logf(ctx, "received block %d", b.Index)
+	s.blockAdded(ctx, b)
+	return BlockAdded, nil
+}
+
+// Sync applies the longest-chain rule: it asks every peer for its height
+// and adopts the longest valid chain that beats the local one. Peers that
+// can't be reached or offer an invalid chain are listed in Errors.
+func (s *Server) Sync(ctx context.Context) (SyncResult, error) {
+	if s.Unhealthy() != "" {
+		return SyncResult{}, ErrUnhealthy
+	}
+	if err := s.checkFrozen(); err != nil {
+		return SyncResult{}, err
+	}
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	return s.sync(ctx)
+}
+
+// sync is Sync (caller holds syncMu)
+func (s *Server) sync(ctx context.Context) (SyncResult, error) {
+	res := SyncResult{Errors: map[string]string{}}
+
+	type candidate struct {
+		peer   string
+		height int
+	}
+	var longer []candidate
+	local := s.chain.Len() - 1
+	for _, peer := range s.peers.List() {
+		if err := s.opts.Chaos.PeerDelay(ctx); err != nil {
+			return res, err
+		}
+		h, err := s.gossip().Height(ctx, peer)
+		switch {
+		case err != nil:
+			res.Errors[peer] = err.Error()
+		case h > local:
+			longer = append(longer, candidate{peer, h})
+		}
+	}
+	sort.SliceStable(longer, func(i, j int) bool { return longer[i].height > longer[j].height })
+
+	for _, c := range longer {
+		blocks, err := s.gossip().FetchChain(ctx, c.peer)
+		if err == nil {
+			err = s.adoptChain(ctx, c.peer, blocks, &res)
+		}
+		if err != nil {
+			res.Errors[c.peer] = err.Error()
+			continue
+		}
+		break
+	}
+	res.Height = s.chain.Len() - 1
+	if len(res.Errors) == 0 {
+		res.Errors = nil
+	}
+	return res, nil
+}
+
+// syncInBackground runs Sync unless one is already running
+func (s *Server) syncInBackground(ctx context.Context) {
+	if !s.syncMu.TryLock() {
+		return
+	}
+	defer s.syncMu.Unlock()
+	res, err := s.sync(context.Background())
+	switch {
+	case err != nil:
+		logf(ctx, "sync failed: %v", err)
+	case res.Replaced:
+		logf(ctx, "sync adopted %s's chain, height %d", res.Peer, res.Height)
+	}
+}
+
+// adoptChain replaces the local chain with blocks, if they form a longer
+// valid chain, requeues the transactions of the blocks it drops and
+// broadcasts the new tip
+func (s *Server) adoptChain(ctx context.Context, peer string, blocks []blockchain.Block, res *SyncResult) error {
+	s.mineMu.Lock()
+	s.txMu.Lock()
+	oldTip, _ := s.chain.BlockAt(s.chain.Len() - 1)
+	var dropped []blockchain.Block
+	var confirmed []blockchain.Transaction
+	err := s.checkFrozen()
+	if err == nil {
+		err = s.checkFinality(blocks)
+	}
+	if err == nil {
+		dropped, err = s.chain.Replace(blocks)
+	}
+	if err == nil {
+		confirmed = s.pool.RemoveConfirmed(s.confirmed)
+	}
+	s.txMu.Unlock()
+	s.mineMu.Unlock()
+	if err != nil {
+		return err
+	}
+	s.pendingLeft(PendingConfirmed, "", confirmed...)
+	res.Replaced, res.Peer, res.Dropped = true, peer, len(dropped)
+	logf(ctx, "AUDIT adopted %s's chain of %d blocks, dropping %d local blocks", peer, len(blocks), len(dropped))
+
+	if len(dropped) > 0 {
+		s.mu.Lock()
+		s.rewindWatches(dropped[0].Index - 1)
+		s.mu.Unlock()
+		s.recordSideBlocks(SideReorged, dropped...)
+	}
+	s.persistChain(ctx)
+	var requeued []string
+	for _, b := range dropped {
+		for _, tx := range b.Txns {
+			if tx.Coinbase != 0 {
+				continue
+			}
+			if s.confirmed(tx.ID) {
+				continue
+			}
+			err := s.addTransaction(ctx, tx, false)
+			switch {
+			case err == nil:
+				requeued = append(requeued, tx.ID)
+			case errors.Is(err, ErrOrphaned):
+				// held until its parents confirm again
+			default:
+				logf(ctx, "dropping transaction %s from replaced block %d: %v", tx.ID, b.Index, err)
+			}
+		}
+	}
+	res.Requeued = len(requeued)
+	if s.dropExpired(ctx) > 0 {
+		s.persistPending(ctx)
+	}
+	s.promoteOrphans(ctx)
+	s.assertInvariants(ctx)
+	res.Height = s.chain.Len() - 1
+	if len(dropped) > 0 {
+		newTip, _ := s.chain.BlockAt(res.Height)
+		s.recordReorg(Reorg{
+			Time:     clock.Or(s.opts.Clock).Now().Unix(),
+			Peer:     peer,
+			OldTip:   TipRef{oldTip.Index, oldTip.Hash},
+			NewTip:   TipRef{newTip.Index, newTip.Hash},
+			Depth:    len(dropped),
+			Requeued: append([]string{}, requeued...),
+		})
+	}
+	s.events.Publish(events.ChainReplaced, *res)
+	s.watchBlocks(ctx)
+	s.attest(ctx)
+	// peers still on the old chain sync when the new tip reaches them
+	if tip, ok := s.chain.BlockAt(res.Height); ok {
+		s.broadcastBlock(ctx, tip)
+	}
+	return nil
+}
+
+// take a block broadcast by a peer: POST /p2p/blocks with the block as the body
+func (s *Server) receiveBlockHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if err := s.opts.Chaos.PeerDelay(r.Context()); err != nil {
+		return
+	}
+	b, err := decodeBlock(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+	status, err := s.ReceiveBlock(r.Context(), b)
+	if err != nil {
+		writeChainError(w, err)
+		return
+	}
+	if status == BlockSyncing {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": status})
+}
+
+// sync with the peers under the longest-chain rule: POST /peers/sync
+func (s *Server) syncHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	res, err := s.Sync(r.Context())
+	if err != nil {
+		writeChainError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/events"
+)
+
+// TamperRequest edits one confirmed transaction for the tamper demonstration
+type TamperRequest struct {
+	Block  int     `json:"block"`
+	Tx     int     `json:"tx"` // position within the block
+	Data   *string `json:"data,omitempty"`
+	Amount *int64  `json:"amount,omitempty"` // of the transaction's first output
+	// Rehash recomputes the txid, merkle root and block hash after the
+	// edit, as a forger covering their tracks would
+	Rehash bool `json:"rehash"`
+}
+
+// TamperResult is the response of POST /tamper: the edit and what
+// re-validating the chain makes of it
+type TamperResult struct {
+	Block      int                    `json:"block"`
+	Before     blockchain.Transaction `json:"before"`
+	After      blockchain.Transaction `json:"after"`
+	BeforeHash string                 `json:"before_hash"`
+	AfterHash  string                 `json:"after_hash"`
+	Tampered   []int                  `json:"tampered"` // every block edited so far
+	Validation blockchain.Integrity   `json:"validation"`
+}
+
+// Tamper edits a confirmed transaction in place, when the node runs in demo
+// mode, so /validate can show how the chain exposes it. The edit is never
+// stored and Untamper undoes it.
+func (s *Server) Tamper(ctx context.Context, req TamperRequest) (TamperResult, error) {
+	if req.Data == nil && req.Amount == nil {
+		return TamperResult{}, errors.New("nothing to change: give data or amount")
+	}
+	if req.Amount != nil {
+		if b, ok := s.chain.BlockAt(req.Block); ok && req.Tx >= 0 && req.Tx < len(b.Txns) && len(b.Txns[req.Tx].Outputs) == 0 {
+			return TamperResult{}, errors.New("the transaction has no output to change the amount of")
+		}
+	}
+	before, after, err := s.chain.Tamper(req.Block, req.Tx, func(tx *blockchain.Transaction) {
+		if req.Data != nil {
+			tx.Data = *req.Data
+		}
+		if req.Amount != nil {
+			tx.Outputs[0].Amount = *req.Amount
+		}
+	}, req.Rehash)
+	if err != nil {
+		return TamperResult{}, err
+	}
+	res := TamperResult{
+		Block:      req.Block,
+		Before:     before.Txns[req.Tx],
+		After:      after.Txns[req.Tx],
+		BeforeHash: before.Hash,
+		AfterHash:  after.Hash,
+		Tampered:   s.chain.Tampered(),
+		Validation: s.chain.Verify(),
+	}
+	logf(ctx, "AUDIT tampered with transaction %d of block %d (rehash %t)", req.Tx, req.Block, req.Rehash)
+	s.events.Publish(events.ChainTampered, map[string][]int{"tampered": res.Tampered})
+	return res, nil
+}
+
+// Untamper restores the blocks Tamper edited and returns their heights
+func (s *Server) Untamper(ctx context.Context) []int {
+	restored := s.chain.Untamper()
+	if len(restored) > 0 {
+		logf(ctx, "AUDIT restored tampered blocks %v", restored)
+		s.events.Publish(events.ChainTampered, map[string][]int{"tampered": {}})
+	}
+	return restored
+}
+
+// edit a confirmed transaction (POST), list the edited blocks (GET) or undo
+// every edit (DELETE): /tamper; 404 unless the node runs with --demo
+func (s *Server) tamperHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if !s.opts.Demo {
+		writeError(w, http.StatusNotFound, "tamper demonstration disabled; start the node with --demo")
+		return
+	}
+	switch r.Method {
+	case "GET":
+		json.NewEncoder(w).Encode(map[string][]int{"tampered": s.chain.Tampered()})
+	case "POST":
+		var req TamperRequest
+		if err := decodeJSON(w, r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid body")
+			return
+		}
+		res, err := s.Tamper(r.Context(), req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		json.NewEncoder(w).Encode(res)
+	case "DELETE":
+		json.NewEncoder(w).Encode(map[string][]int{"restored": s.Untamper(r.Context())})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"salmanahmed/blockchain/pkg/clock"
+)
+
+// KeyUsage is one API key's consumption of a chain; the daily counters
+// cover Day (UTC) and reset at midnight
+type KeyUsage struct {
+	Chain             string `json:"chain"` // hosted chain ID, "" for the default chain
+	Key               string `json:"key"`   // api-key:<fingerprint>, never the key itself
+	Day               string `json:"day"`
+	Requests          int    `json:"requests"`
+	Transactions      int    `json:"transactions"`
+	TotalRequests     int64  `json:"total_requests"`
+	TotalTransactions int64  `json:"total_transactions"`
+	DailyRequests     int    `json:"daily_requests_quota,omitempty"`
+	DailyTransactions int    `json:"daily_transactions_quota,omitempty"`
+}
+
+// usageMeter counts requests and transactions per API key
+type usageMeter struct {
+	mu   sync.Mutex
+	keys map[string]*KeyUsage
+}
+
+// take counts one request or transaction for key at now unless it would
+// exceed quota (0 is unlimited); ok is false when the quota is used up
+func (m *usageMeter) take(key string, now time.Time, txn bool, quota int) (ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.keys == nil {
+		m.keys = map[string]*KeyUsage{}
+	}
+	u := m.keys[key]
+	if u == nil {
+		u = &KeyUsage{Key: key}
+		m.keys[key] = u
+	}
+	if day := now.UTC().Format("2006-01-02"); u.Day != day {
+		u.Day, u.Requests, u.Transactions = day, 0, 0
+	}
+	used := &u.Requests
+	total := &u.TotalRequests
+	if txn {
+		used, total = &u.Transactions, &u.TotalTransactions
+	}
+	if quota > 0 && *used >= quota {
+		return false
+	}
+	*used++
+	*total++
+	return true
+}
+
+// untake gives back a transaction counted for a submission that then failed
+func (m *usageMeter) untake(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u := m.keys[key]; u != nil && u.Transactions > 0 {
+		u.Transactions--
+		u.TotalTransactions--
+	}
+}
+
+// untilMidnight is how long until the daily quotas reset
+func untilMidnight(now time.Time) time.Duration {
+	now = now.UTC()
+	y, m, d := now.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC).Sub(now)
+}
+
+// Usage reports the consumption of every API key that has used the server
+func (s *Server) Usage() []KeyUsage {
+	p := s.Policy()
+	s.usage.mu.Lock()
+	defer s.usage.mu.Unlock()
+	out := make([]KeyUsage, 0, len(s.usage.keys))
+	for _, u := range s.usage.keys {
+		row := *u
+		row.DailyRequests, row.DailyTransactions = p.DailyRequests, p.DailyTransactions
+		out = append(out, row)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// meterKeys counts every request made with one of the policy's API keys,
+// reads included, and turns them away once the key's daily quota is spent
+func (s *Server) meterKeys(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := s.Policy()
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if len(p.APIKeys) == 0 || got == "" || !s.apiKeyValid(got) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		now := clock.Or(s.opts.Clock).Now()
+		if key := keyIdentity(got); !s.usage.take(key, now, false, p.DailyRequests) {
+			jsonHeaders(w)
+			w.Header().Set("Retry-After", strconv.Itoa(int(untilMidnight(now)/time.Second)+1))
+			writeError(w, http.StatusTooManyRequests, fmt.Sprintf("%v: %s has used its %d requests for today", ErrQuotaExceeded, key, p.DailyRequests))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// takeTransaction counts a transaction against the caller's API key; the
+// returned func gives it back if the submission fails
+func (s *Server) takeTransaction(ctx context.Context) (undo func(), err error) {
+	key := Identity(ctx)
+	if !strings.HasPrefix(key, "api-key:") {
+		return func() {}, nil
+	}
+	quota := s.Policy().DailyTransactions
+	if !s.usage.take(key, clock.Or(s.opts.Clock).Now(), true, quota) {
+		return nil, fmt.Errorf("%w: %s has used its %d transactions for today", ErrQuotaExceeded, key, quota)
+	}
+	return func() { s.usage.untake(key) }, nil
+}
+
+// Usage reports API key consumption across the default and hosted chains
+func (c *Chains) Usage() []KeyUsage {
+	out := c.primary.Usage()
+	c.mu.RLock()
+	ids := make([]string, 0, len(c.servers))
+	for id := range c.servers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		for _, u := range c.servers[id].Usage() {
+			u.Chain = id
+			out = append(out, u)
+		}
+	}
+	c.mu.RUnlock()
+	return out
+}
+
+// per-key request and transaction counts for every chain: GET /admin/usage
+func (c *Chains) usageHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	json.NewEncoder(w).Encode(c.Usage())
+}
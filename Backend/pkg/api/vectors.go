@@ -0,0 +1,20 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"salmanahmed/blockchain/pkg/fixtures"
+)
+
+// canonical hashing, merkle and signature test vectors computed with this
+// chain's hasher: GET /testvectors
+func (s *Server) testVectorsHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	v, err := fixtures.BuildVectors(s.chain.Hasher())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(v)
+}
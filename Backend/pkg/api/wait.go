@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits on /transactions/{txid}/wait
+const (
+	defaultWaitTimeout = 30 * time.Second
+	maxWaitTimeout     = 5 * time.Minute
+	// waitPoll re-checks depth for blocks that arrive without an event, e.g. imports
+	waitPoll = time.Second
+)
+
+// Transaction statuses
+const (
+	TxPending   = "pending"
+	TxOrphaned  = "orphaned"
+	TxConfirmed = "confirmed"
+)
+
+// ErrUnknownTx is returned for a txid that is neither confirmed nor waiting
+var ErrUnknownTx = errors.New("unknown transaction")
+
+// TxStatus is where a transaction is and how deep it is buried
+type TxStatus struct {
+	TxID          string `json:"txid"`
+	Status        string `json:"status"` // TxPending, TxOrphaned or TxConfirmed
+	BlockIndex    int    `json:"block_index,omitempty"`
+	TxIndex       *int   `json:"tx_index,omitempty"` // position within the block, once confirmed
+	BlockHash     string `json:"block_hash,omitempty"`
+	Confirmations int    `json:"confirmations"`
+}
+
+// TxStatus reports whether txid is confirmed, pending or held as an orphan
+func (s *Server) TxStatus(txid string) (TxStatus, error) {
+	st := TxStatus{TxID: txid}
+	if idx, pos, ok := s.chain.TxPosition(txid); ok {
+		b, _ := s.chain.BlockAt(idx)
+		st.Status, st.BlockIndex, st.TxIndex, st.BlockHash = TxConfirmed, idx, &pos, b.Hash
+		st.Confirmations = s.chain.Confirmations(idx)
+		return st, nil
+	}
+	for _, id := range s.pool.IDs() {
+		if id == txid {
+			st.Status = TxPending
+			return st, nil
+		}
+	}
+	for _, o := range s.orphans.All() {
+		if o.Tx.ID == txid {
+			st.Status = TxOrphaned
+			return st, nil
+		}
+	}
+	return st, fmt.Errorf("%w %s", ErrUnknownTx, txid)
+}
+
+// WaitForTx blocks until txid is confirmations deep or ctx ends, returning
+// its latest status either way. It fails at once for an unknown txid.
+func (s *Server) WaitForTx(ctx context.Context, txid string, confirmations int) (TxStatus, error) {
+	sub, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+	t := time.NewTicker(waitPoll)
+	defer t.Stop()
+	for {
+		st, err := s.TxStatus(txid)
+		if err != nil || st.Confirmations >= confirmations {
+			return st, err
+		}
+		select {
+		case <-ctx.Done():
+			return st, ctx.Err()
+		case <-sub:
+		case <-t.C:
+		}
+	}
+}
+
+// wait for a transaction: GET /transactions/{txid}/wait?confirmations=N&timeout=60s
+// (defaults 1 and 30s) returns its status once it is N blocks deep
+func (s *Server) txWaitHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	txid, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/transactions/"), "/")
+	if txid == "" || rest != "wait" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	q := r.URL.Query()
+	confirmations := 1
+	if v := q.Get("confirmations"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid confirmations")
+			return
+		}
+		confirmations = n
+	}
+	timeout := defaultWaitTimeout
+	if v := q.Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || d > maxWaitTimeout {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("timeout must be a duration up to %s", maxWaitTimeout))
+			return
+		}
+		timeout = d
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	st, err := s.WaitForTx(ctx, txid, confirmations)
+	switch {
+	case errors.Is(err, ErrUnknownTx):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusRequestTimeout, fmt.Sprintf("%s has %d of %d confirmations after %s",
+			txid, st.Confirmations, confirmations, timeout))
+	case err != nil:
+		return // the client went away
+	default:
+		json.NewEncoder(w).Encode(st)
+	}
+}
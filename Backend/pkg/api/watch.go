@@ -0,0 +1,336 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/events"
+	"salmanahmed/blockchain/pkg/script"
+)
+
+// Limits on watches
+const (
+	MaxWatches    = 256
+	MaxWatchDepth = 100
+)
+
+// Watch notification statuses
+const (
+	WatchPending   = "pending"
+	WatchConfirmed = "confirmed"
+)
+
+// webhookTimeout bounds each webhook delivery
+const webhookTimeout = 5 * time.Second
+
+// ErrTooManyWatches is returned once MaxWatches are registered
+var ErrTooManyWatches = errors.New("too many watches")
+
+// Watch asks to be told about transactions paying to Address, or about
+// TxID, as they enter the mempool and once they are Confirmations deep
+type Watch struct {
+	ID            string `json:"id"`
+	Address       string `json:"address,omitempty"`
+	TxID          string `json:"txid,omitempty"`
+	Confirmations int    `json:"confirmations"`
+	Webhook       string `json:"webhook,omitempty"` // URL each notification is POSTed to
+}
+
+// WatchNotification is published on /watch/ws, the events stream and the
+// watch's webhook
+type WatchNotification struct {
+	WatchID       string `json:"watch_id"`
+	Status        string `json:"status"` // WatchPending or WatchConfirmed
+	TxID          string `json:"txid"`
+	Block         int    `json:"block,omitempty"`
+	Confirmations int    `json:"confirmations"`
+
+	webhook string
+}
+
+// matches reports whether tx is what w is watching for
+func (w Watch) matches(tx blockchain.Transaction) bool {
+	if w.TxID != "" {
+		return tx.ID == w.TxID
+	}
+	for _, o := range tx.Outputs {
+		if script.Address(o.Lock) == w.Address {
+			return true
+		}
+	}
+	return false
+}
+
+// watchState is a watch and its matches waiting to get deep enough
+type watchState struct {
+	Watch
+	confirming map[string]int // txid -> block that confirmed it
+}
+
+// watchList holds the registered watches and how far the chain has been scanned
+type watchList struct {
+	watches map[string]*watchState
+	height  int // last block scanned for matches
+}
+
+// AddWatch registers w and returns it with its ID filled in
+func (s *Server) AddWatch(w Watch) (Watch, error) {
+	if (w.Address == "") == (w.TxID == "") {
+		return Watch{}, errors.New("watch exactly one of address or txid")
+	}
+	if w.Address != "" && !blockchain.IsAddress(w.Address) {
+		return Watch{}, fmt.Errorf("invalid address %q", w.Address)
+	}
+	if w.Confirmations == 0 {
+		w.Confirmations = 1
+	}
+	if w.Confirmations < 1 || w.Confirmations > MaxWatchDepth {
+		return Watch{}, fmt.Errorf("confirmations must be 1-%d", MaxWatchDepth)
+	}
+	if w.Webhook != "" {
+		u, err := url.Parse(w.Webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return Watch{}, fmt.Errorf("invalid webhook URL %q", w.Webhook)
+		}
+	}
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	w.ID = hex.EncodeToString(buf)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.watches.watches == nil {
+		s.watches.watches = map[string]*watchState{}
+	}
+	if len(s.watches.watches) >= MaxWatches {
+		return Watch{}, fmt.Errorf("%w: %d registered", ErrTooManyWatches, MaxWatches)
+	}
+	if len(s.watches.watches) == 0 {
+		// nothing was watching, so nothing before the tip needs scanning
+		s.watches.height = s.chain.Len() - 1
+	}
+	st := &watchState{Watch: w, confirming: map[string]int{}}
+	if w.TxID != "" {
+		if idx, ok := s.chain.HasTx(w.TxID); ok {
+			st.confirming[w.TxID] = idx
+		}
+	}
+	s.watches.watches[w.ID] = st
+	return w, nil
+}
+
+// RemoveWatch unregisters the watch with id; false when there is none
+func (s *Server) RemoveWatch(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.watches.watches[id]; !ok {
+		return false
+	}
+	delete(s.watches.watches, id)
+	return true
+}
+
+// Watches returns the registered watches ordered by ID
+func (s *Server) Watches() []Watch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Watch, 0, len(s.watches.watches))
+	for _, st := range s.watches.watches {
+		out = append(out, st.Watch)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// watchPending notifies the watches matching a transaction that just
+// entered the mempool
+func (s *Server) watchPending(ctx context.Context, tx blockchain.Transaction) {
+	var out []WatchNotification
+	s.mu.Lock()
+	for _, st := range s.watches.watches {
+		if st.matches(tx) {
+			out = append(out, WatchNotification{WatchID: st.ID, Status: WatchPending, TxID: tx.ID, webhook: st.Webhook})
+		}
+	}
+	s.mu.Unlock()
+	s.notifyWatches(ctx, out)
+}
+
+// watchBlocks scans the blocks added since the last scan for matches and
+// notifies every match that has reached its watch's depth. Watches on a
+// txid end once it is deep enough.
+func (s *Server) watchBlocks(ctx context.Context) {
+	tip := s.chain.Len() - 1
+	var out []WatchNotification
+	s.mu.Lock()
+	if tip < s.watches.height {
+		s.rewindWatches(tip)
+	}
+	for i := s.watches.height + 1; i <= tip && len(s.watches.watches) > 0; i++ {
+		b, ok := s.chain.BlockAt(i)
+		if !ok {
+			break
+		}
+		for _, tx := range b.Txns {
+			for _, st := range s.watches.watches {
+				if st.matches(tx) {
+					st.confirming[tx.ID] = b.Index
+				}
+			}
+		}
+	}
+	s.watches.height = tip
+	for id, st := range s.watches.watches {
+		for txid, idx := range st.confirming {
+			depth := tip - idx + 1
+			if depth < st.Confirmations {
+				continue
+			}
+			out = append(out, WatchNotification{
+				WatchID: id, Status: WatchConfirmed, TxID: txid,
+				Block: idx, Confirmations: depth, webhook: st.Webhook,
+			})
+			delete(st.confirming, txid)
+			if st.TxID != "" {
+				delete(s.watches.watches, id)
+			}
+		}
+	}
+	s.mu.Unlock()
+	s.notifyWatches(ctx, out)
+}
+
+// rewindWatches forgets the matches above height, in blocks a reset or a
+// switch to a peer's chain dropped, and rescans from there (caller holds mu)
+func (s *Server) rewindWatches(height int) {
+	for _, st := range s.watches.watches {
+		for txid, idx := range st.confirming {
+			if idx > height {
+				delete(st.confirming, txid)
+			}
+		}
+	}
+	if s.watches.height > height {
+		s.watches.height = height
+	}
+}
+
+// notifyWatches publishes each notification and posts it to its webhook
+func (s *Server) notifyWatches(ctx context.Context, ns []WatchNotification) {
+	for _, n := range ns {
+		logf(ctx, "watch %s: %s %s", n.WatchID, n.TxID, n.Status)
+		s.events.Publish(events.WatchMatched, n)
+		if n.webhook != "" {
+			go postWebhook(n)
+		}
+	}
+}
+
+// postWebhook delivers n to its webhook, giving up after webhookTimeout
+func postWebhook(n WatchNotification) {
+	raw, err := json.Marshal(n)
+	if err != nil {
+		return
+	}
+	c := http.Client{Timeout: webhookTimeout}
+	resp, err := c.Post(n.webhook, "application/json", bytes.NewReader(raw))
+	if err != nil {
+		logf(context.Background(), "watch %s: webhook failed: %v", n.WatchID, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		logf(context.Background(), "watch %s: webhook returned %s", n.WatchID, resp.Status)
+	}
+}
+
+// list (GET) or register (POST {"address"|"txid", "confirmations", "webhook"}) watches
+func (s *Server) watchHandler(w http.ResponseWriter, r *http.Request) {
+	jsonHeaders(w)
+	switch r.Method {
+	case "GET":
+		json.NewEncoder(w).Encode(s.Watches())
+	case "POST":
+		var body Watch
+		if err := decodeJSON(w, r, &body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid body")
+			return
+		}
+		body.ID = ""
+		watch, err := s.AddWatch(body)
+		switch {
+		case errors.Is(err, ErrTooManyWatches):
+			writeError(w, http.StatusTooManyRequests, err.Error())
+		case err != nil:
+			writeError(w, http.StatusBadRequest, err.Error())
+		default:
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(watch)
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// DELETE /watch/{id} unregisters a watch; GET /watch/ws streams
+// notifications over a WebSocket, only for ?id= (repeatable) when given
+func (s *Server) watchItemHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/watch/")
+	if id == "ws" && r.Method == "GET" {
+		s.watchWSHandler(w, r)
+		return
+	}
+	jsonHeaders(w)
+	if r.Method != "DELETE" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.RemoveWatch(id) {
+		writeError(w, http.StatusNotFound, "no watch "+id)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "watch removed", "id": id})
+}
+
+// stream watch notifications over a WebSocket, one JSON notification per message
+func (s *Server) watchWSHandler(w http.ResponseWriter, r *http.Request) {
+	ids := map[string]bool{}
+	for _, id := range r.URL.Query()["id"] {
+		ids[id] = true
+	}
+	conn, closed, err := s.upgradeWS(w, r)
+	if err != nil {
+		return // the upgrader has already replied
+	}
+	defer conn.Close()
+
+	sub, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			return
+		case e := <-sub:
+			n, ok := e.Data.(WatchNotification)
+			if e.Type != events.WatchMatched || !ok || (len(ids) > 0 && !ids[n.WatchID]) {
+				continue
+			}
+			if err := conn.WriteJSON(n); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Package blobstore keeps large payloads off-chain, addressed by the SHA-256
+// of their content, on local disk or in an S3-compatible bucket.
+package blobstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MaxSize bounds a single blob
+const MaxSize = 32 << 20
+
+var (
+	// ErrNotFound is returned for hashes the store doesn't hold
+	ErrNotFound = errors.New("blob not found")
+	// ErrCorrupt is returned when stored content no longer matches its hash
+	ErrCorrupt = errors.New("blob content does not match its hash")
+)
+
+// Store holds blobs by hash
+type Store interface {
+	// Put stores data and returns its hex SHA-256
+	Put(ctx context.Context, data []byte) (string, error)
+	// Get returns the content stored under hash, verified against it
+	Get(ctx context.Context, hash string) ([]byte, error)
+	// Has reports whether hash is stored
+	Has(ctx context.Context, hash string) (bool, error)
+}
+
+// Hash returns the hex SHA-256 blobs are addressed by
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidHash reports whether s looks like a blob hash
+func ValidHash(s string) bool {
+	raw, err := hex.DecodeString(s)
+	return err == nil && len(raw) == sha256.Size && s == strings.ToLower(s)
+}
+
+// Open returns the store described by spec: a directory path, or
+// s3://bucket[/prefix] for an S3-compatible bucket at endpoint
+func Open(spec, endpoint, region string) (Store, error) {
+	if rest, ok := strings.CutPrefix(spec, "s3://"); ok {
+		bucket, prefix, _ := strings.Cut(rest, "/")
+		return NewS3(S3Config{
+			Endpoint:  endpoint,
+			Region:    region,
+			Bucket:    bucket,
+			Prefix:    prefix,
+			AccessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		})
+	}
+	return NewDir(spec)
+}
+
+// verify checks data against hash
+func verify(hash string, data []byte) ([]byte, error) {
+	if Hash(data) != hash {
+		return nil, fmt.Errorf("%w: %s", ErrCorrupt, hash)
+	}
+	return data, nil
+}
+
+// Dir stores blobs as files under a directory, fanned out by hash prefix
+type Dir struct {
+	root string
+}
+
+// NewDir returns a store rooted at dir, creating it if needed
+func NewDir(dir string) (*Dir, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("blobstore: %w", err)
+	}
+	return &Dir{root: dir}, nil
+}
+
+func (d *Dir) path(hash string) string {
+	return filepath.Join(d.root, hash[:2], hash)
+}
+
+// Put writes data atomically, so a crash never leaves a partial blob
+func (d *Dir) Put(ctx context.Context, data []byte) (string, error) {
+	hash := Hash(data)
+	p := d.path(hash)
+	if _, err := os.Stat(p); err == nil {
+		return hash, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), hash+".tmp*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return hash, nil
+}
+
+// Get reads and verifies a blob
+func (d *Dir) Get(ctx context.Context, hash string) ([]byte, error) {
+	if !ValidHash(hash) {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(d.path(hash))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return verify(hash, data)
+}
+
+// Has reports whether the blob's file exists
+func (d *Dir) Has(ctx context.Context, hash string) (bool, error) {
+	if !ValidHash(hash) {
+		return false, nil
+	}
+	_, err := os.Stat(d.path(hash))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
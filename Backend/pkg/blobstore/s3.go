@@ -0,0 +1,180 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// emptyHash is the SHA-256 of an empty request body
+const emptyHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Config locates a bucket on an S3-compatible service
+type S3Config struct {
+	Endpoint  string // e.g. https://s3.amazonaws.com or http://localhost:9000
+	Region    string // default us-east-1
+	Bucket    string
+	Prefix    string // key prefix inside the bucket
+	AccessKey string
+	SecretKey string
+}
+
+// S3 stores blobs as objects, addressed path-style and signed with AWS
+// Signature Version 4
+type S3 struct {
+	cfg  S3Config
+	http *http.Client
+	now  func() time.Time
+}
+
+// NewS3 returns a store for the configured bucket
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("blobstore: s3 needs an endpoint and a bucket")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("blobstore: s3 needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	cfg.Prefix = strings.Trim(cfg.Prefix, "/")
+	return &S3{cfg: cfg, http: &http.Client{Timeout: time.Minute}, now: time.Now}, nil
+}
+
+// Put uploads data unless the object already exists
+func (s *S3) Put(ctx context.Context, data []byte) (string, error) {
+	hash := Hash(data)
+	if ok, err := s.Has(ctx, hash); err != nil || ok {
+		return hash, err
+	}
+	resp, err := s.do(ctx, "PUT", hash, data)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("blobstore: s3 put returned %s", resp.Status)
+	}
+	return hash, nil
+}
+
+// Get downloads and verifies a blob
+func (s *S3) Get(ctx context.Context, hash string) ([]byte, error) {
+	if !ValidHash(hash) {
+		return nil, ErrNotFound
+	}
+	resp, err := s.do(ctx, "GET", hash, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, hash)
+	default:
+		return nil, fmt.Errorf("blobstore: s3 get returned %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	return verify(hash, data)
+}
+
+// Has checks for the object with a HEAD request
+func (s *S3) Has(ctx context.Context, hash string) (bool, error) {
+	if !ValidHash(hash) {
+		return false, nil
+	}
+	resp, err := s.do(ctx, "HEAD", hash, nil)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("blobstore: s3 head returned %s", resp.Status)
+}
+
+// do sends a signed request for the object holding hash
+func (s *S3) do(ctx context.Context, method, hash string, body []byte) (*http.Response, error) {
+	key := hash
+	if s.cfg.Prefix != "" {
+		key = s.cfg.Prefix + "/" + hash
+	}
+	path := "/" + s.cfg.Bucket + "/" + escapeKey(key)
+	req, err := http.NewRequestWithContext(ctx, method, s.cfg.Endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	payload := emptyHash
+	if body != nil {
+		payload = Hash(body)
+	}
+	s.sign(req, path, payload)
+	return s.http.Do(req)
+}
+
+// sign adds SigV4 headers for a request with no query string
+func (s *S3) sign(req *http.Request, path, payloadHash string) {
+	t := s.now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	day := t.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	signed := "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		req.Method,
+		path,
+		"", // query
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signed,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + s.cfg.Region + "/s3/aws4_request"
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	k := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), day)
+	k = hmacSHA256(k, s.cfg.Region)
+	k = hmacSHA256(k, "s3")
+	k = hmacSHA256(k, "aws4_request")
+	sig := hex.EncodeToString(hmacSHA256(k, toSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.cfg.AccessKey+"/"+scope+
+		", SignedHeaders="+signed+", Signature="+sig)
+}
+
+func hmacSHA256(key []byte, msg string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(msg))
+	return m.Sum(nil)
+}
+
+// escapeKey URI-encodes each segment of an object key the way SigV4 expects
+func escapeKey(key string) string {
+	parts := strings.Split(key, "/")
+	for i, p := range parts {
+		parts[i] = strings.ReplaceAll(url.PathEscape(p), "+", "%2B")
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,58 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"errors"
+	"strconv"
+
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+// Attestation is a signed statement that a chain was final up to Height,
+// where its block had Hash. Anyone holding one can later show the chain
+// was rewritten at or below Height if the block there differs.
+type Attestation struct {
+	Genesis   string `json:"genesis"` // genesis block hash, naming the chain
+	Height    int    `json:"height"`
+	Hash      string `json:"hash"`
+	Time      int64  `json:"time"` // unix seconds when signed
+	PublicKey string `json:"public_key"`
+	Signer    string `json:"signer"` // address of PublicKey
+	Signature string `json:"signature"`
+}
+
+// Digest is what the signature covers: every field but the signature
+func (a Attestation) Digest() []byte {
+	record := "attestation|genesis:" + a.Genesis +
+		"|height:" + strconv.Itoa(a.Height) +
+		"|hash:" + a.Hash +
+		"|time:" + strconv.FormatInt(a.Time, 10) +
+		"|key:" + a.PublicKey
+	sum := sha256.Sum256([]byte(record))
+	return sum[:]
+}
+
+// NewAttestation signs that block height with hash is final on the chain
+// starting at genesis
+func NewAttestation(privHex, genesis string, height int, hash string, time int64) (Attestation, error) {
+	kp, err := wallet.FromPrivateKey(privHex)
+	if err != nil {
+		return Attestation{}, err
+	}
+	a := Attestation{Genesis: genesis, Height: height, Hash: hash, Time: time, PublicKey: kp.PublicKey, Signer: kp.Address}
+	if a.Signature, err = wallet.Sign(privHex, a.Digest()); err != nil {
+		return Attestation{}, err
+	}
+	return a, nil
+}
+
+// Verify checks the signature and that Signer is the key's address
+func (a Attestation) Verify() error {
+	if wallet.Address(a.PublicKey) != a.Signer {
+		return errors.New("signer does not match the public key")
+	}
+	if !wallet.Verify(a.PublicKey, a.Digest(), a.Signature) {
+		return errors.New("signature does not verify")
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package blockchain_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"testing"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/fixtures"
+)
+
+// Run with `go test -bench . ./pkg/blockchain`; -benchmem adds allocations.
+
+// nonce search at increasing difficulty, a fresh search every iteration
+func BenchmarkMine(b *testing.B) {
+	for d := 1; d <= 4; d++ {
+		b.Run("difficulty="+strconv.Itoa(d), func(b *testing.B) {
+			pow := &blockchain.ProofOfWork{Difficulty: d}
+			tmpl := blockchain.Block{Version: blockchain.CodecVersion, Index: 1, Txns: []blockchain.Transaction{blockchain.NewDataTx("bench")}}
+			tmpl.MerkleRoot = blockchain.ComputeMerkleRoot(tmpl.Txns)
+			for i := 0; i < b.N; i++ {
+				tmpl.PrevHash = strconv.Itoa(i)
+				if _, err := pow.ProduceBlock(context.Background(), tmpl, blockchain.DefaultHasher()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMerkleRoot(b *testing.B) {
+	for _, n := range []int{1, 16, 256, 4096} {
+		txns := make([]blockchain.Transaction, n)
+		for i := range txns {
+			txns[i] = blockchain.NewDataTx(fmt.Sprintf("tx-%d", i))
+		}
+		b.Run("txs="+strconv.Itoa(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				blockchain.ComputeMerkleRoot(txns)
+			}
+		})
+	}
+}
+
+// benchChain mines n blocks of txs data transactions each at difficulty 1
+func benchChain(b *testing.B, n, txs int) []blockchain.Block {
+	b.Helper()
+	c := blockchain.NewChain(1)
+	for i := 1; i <= n; i++ {
+		txns := make([]blockchain.Transaction, txs)
+		for j := range txns {
+			txns[j] = blockchain.NewDataTx(fmt.Sprintf("block-%d-tx-%d", i, j))
+		}
+		mined, err := c.Produce(context.Background(), c.NextBlock(txns))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := c.AddBlock(mined); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return c.Blocks()
+}
+
+// full-chain validation: replaying every block onto a fresh chain, and
+// re-checking the stored blocks as GET /validate does
+func BenchmarkValidateChain(b *testing.B) {
+	golden, err := fixtures.Load("medium")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run(fmt.Sprintf("golden/blocks=%d", len(golden.Blocks)), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := golden.Chain(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	blocks := benchChain(b, 200, 20)
+	pow := &blockchain.ProofOfWork{Difficulty: 1}
+	b.Run(fmt.Sprintf("replay/blocks=%d", len(blocks)), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			c := blockchain.NewChainFromGenesis(blocks[0], pow, blockchain.DefaultHasher())
+			for _, blk := range blocks[1:] {
+				if err := c.AddBlock(blk); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run(fmt.Sprintf("verify/blocks=%d", len(blocks)), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if bad, err := blockchain.VerifyBlocks(blocks, pow, blockchain.DefaultHasher()); err != nil {
+				b.Fatalf("block %d: %v", bad, err)
+			}
+		}
+	})
+}
+
+// JSON and binary encoding of the same blocks
+func BenchmarkEncoding(b *testing.B) {
+	blocks := benchChain(b, 50, 20)
+	rawJSON, err := json.Marshal(blocks)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var stream bytes.Buffer
+	for _, blk := range blocks {
+		if err := blockchain.WriteBlock(&stream, blk); err != nil {
+			b.Fatal(err)
+		}
+	}
+	rawBinary := stream.Bytes()
+
+	b.Run("encode/json", func(b *testing.B) {
+		b.SetBytes(int64(len(rawJSON)))
+		for i := 0; i < b.N; i++ {
+			if _, err := json.Marshal(blocks); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("decode/json", func(b *testing.B) {
+		b.SetBytes(int64(len(rawJSON)))
+		for i := 0; i < b.N; i++ {
+			var out []blockchain.Block
+			if err := json.Unmarshal(rawJSON, &out); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("encode/binary", func(b *testing.B) {
+		b.SetBytes(int64(len(rawBinary)))
+		for i := 0; i < b.N; i++ {
+			var buf bytes.Buffer
+			for _, blk := range blocks {
+				if err := blockchain.WriteBlock(&buf, blk); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("decode/binary", func(b *testing.B) {
+		b.SetBytes(int64(len(rawBinary)))
+		for i := 0; i < b.N; i++ {
+			r := bufio.NewReader(bytes.NewReader(rawBinary))
+			for {
+				if _, _, err := blockchain.ReadBlock(r); err == io.EOF {
+					break
+				} else if err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,110 @@
+// Package blockchain implements blocks, merkle roots, proof-of-work and the
+// validated chain that ties them together.
+package blockchain
+
+import (
+	"strconv"
+	"strings"
+
+	"salmanahmed/blockchain/pkg/clock"
+)
+
+// GenesisTx is the single transaction carried by the genesis block (roll number as required)
+const GenesisTx = "i22-0743"
+
+// Block structure
+type Block struct {
+	// Version is what the hash covers: 0 the original string record,
+	// CodecVersion the binary encoding (see EncodeBlock)
+	Version    int           `json:"version,omitempty"`
+	Index      int           `json:"index"`
+	Timestamp  int64         `json:"timestamp"`
+	Txns       []Transaction `json:"transactions"`
+	MerkleRoot string        `json:"merkle_root"`
+	StateRoot  string        `json:"state_root,omitempty"`
+	LogsRoot   string        `json:"logs_root,omitempty"`
+	PrevHash   string        `json:"prev_hash"`
+	Hash       string        `json:"hash"`
+	Nonce      int64         `json:"nonce"`
+	Miner      string        `json:"miner,omitempty"`      // address or API key credited with the block
+	Producer   string        `json:"producer,omitempty"`   // node ID of the node that mined it
+	Difficulty int           `json:"difficulty,omitempty"` // leading zeros the block was mined to
+
+	// Confirmations and Size are filled in for API responses; they are
+	// never hashed or stored
+	Confirmations int `json:"confirmations,omitempty"`
+	Size          int `json:"size,omitempty"`
+}
+
+// CalculateHash returns the hex digest of input using the default hasher
+func CalculateHash(input string) string {
+	return DefaultHasher().Hash([]byte(input))
+}
+
+// TxID returns the ID of a transaction with the given canonical form
+func TxID(tx string) string {
+	return CalculateHash(tx)
+}
+
+// CalculateBlockHash hashes the block with the default hasher
+func CalculateBlockHash(b Block) string {
+	return HashBlock(DefaultHasher(), b)
+}
+
+// HashBlock hashes the block header and transactions (everything but Hash)
+// with h: their binary encoding for blocks of version 1, BlockRecord for
+// older ones, which keep their hashes
+func HashBlock(h Hasher, b Block) string {
+	if b.Version >= CodecVersion {
+		return h.Hash(blockBody(b).buf)
+	}
+	return h.Hash([]byte(BlockRecord(b)))
+}
+
+// BlockRecord is the string HashBlock hashes for a version 0 block. The
+// state and logs roots, the miner, the producer and the difficulty only take
+// part once set, so blocks from before they existed keep their hashes.
+func BlockRecord(b Block) string {
+	record := strconv.Itoa(b.Index) +
+		strconv.FormatInt(b.Timestamp, 10) +
+		strings.Join(canonicals(b.Txns), "|") +
+		b.MerkleRoot + b.PrevHash +
+		strconv.FormatInt(b.Nonce, 10)
+	if b.StateRoot != "" {
+		record += "|" + b.StateRoot
+	}
+	if b.LogsRoot != "" {
+		record += "|logs:" + b.LogsRoot
+	}
+	if b.Miner != "" {
+		record += "|miner:" + b.Miner
+	}
+	if b.Producer != "" {
+		record += "|producer:" + b.Producer
+	}
+	if b.Difficulty != 0 {
+		record += "|difficulty:" + strconv.Itoa(b.Difficulty)
+	}
+	return record
+}
+
+// NewGenesisBlock creates the genesis block (with first transaction = roll number)
+// timestamped by clk
+func NewGenesisBlock(h Hasher, clk clock.Clock) Block {
+	return NewGenesisBlockData(h, clk, GenesisTx)
+}
+
+// NewGenesisBlockData creates a genesis block whose single transaction is data
+func NewGenesisBlockData(h Hasher, clk clock.Clock, data string) Block {
+	txns := []Transaction{NewDataTx(data)}
+	b := Block{
+		Index:      0,
+		Timestamp:  clock.Or(clk).Now().Unix(),
+		Txns:       txns,
+		MerkleRoot: MerkleRoot(h, txns),
+		PrevHash:   "",
+		Nonce:      0,
+	}
+	b.Hash = HashBlock(h, b)
+	return b
+}
@@ -0,0 +1,488 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"salmanahmed/blockchain/pkg/clock"
+	"salmanahmed/blockchain/pkg/script"
+)
+
+// Chain is a validated, append-only list of blocks. Its state is only
+// reachable through methods, so every block goes through the same rules.
+type Chain struct {
+	mu        sync.Mutex
+	blocks    []Block
+	consensus Consensus
+	hasher    Hasher
+
+	tipHash   string
+	hashIndex map[string]int // block hash -> block index
+	txIndex   map[string]int // confirmed txid -> block index
+	txPos     map[string]int // confirmed txid -> position within its block
+	utxos     map[OutPoint]TxOutput
+	spans     map[OutPoint]*outputSpan // every output ever confirmed, for queries at a height
+
+	state    *worldState
+	receipts map[string]Receipt // contract or transfer txid -> outcome
+	logs     []Log
+
+	kvHistory map[string][]kvVersion // key -> writes in block order
+	sizes     []blockSize            // serialized size of each block
+
+	reward   int64                  // most a coinbase may pay
+	retarget Retarget               // difficulty adjustment; fixed when Every is 0
+	miners   map[string]*MinerStats // leaderboard
+	tampered map[int]Block          // blocks edited by Tamper, as they were before
+
+	validators []namedValidator
+	signed     bool // see RequireSignatures
+	signedFrom int
+}
+
+// TxMatch is a confirmed transaction returned by Search
+type TxMatch struct {
+	BlockIndex    int         `json:"block_index"`
+	TxIndex       int         `json:"tx_index"` // position within the block
+	Transaction   Transaction `json:"transaction"`
+	BlockHash     string      `json:"block_hash"`
+	Confirmations int         `json:"confirmations"`
+
+	Labels map[string]string `json:"labels,omitempty"` // response-only: names of the addresses it pays
+}
+
+// NewChain creates a proof-of-work SHA-256 chain holding only the genesis block
+func NewChain(difficulty int) *Chain {
+	return NewChainWith(&ProofOfWork{Difficulty: difficulty}, DefaultHasher(), clock.Real)
+}
+
+// NewChainWith creates a chain using the given consensus rules and hasher,
+// with its genesis block timestamped by clk
+func NewChainWith(consensus Consensus, hasher Hasher, clk clock.Clock) *Chain {
+	return NewChainFromGenesis(NewGenesisBlock(hasher, clk), consensus, hasher)
+}
+
+// NewChainFromGenesis creates a chain starting at a caller-supplied genesis
+// block, e.g. one loaded from a fixture
+func NewChainFromGenesis(genesis Block, consensus Consensus, hasher Hasher) *Chain {
+	c := &Chain{consensus: consensus, hasher: hasher}
+	c.reset(genesis)
+	return c
+}
+
+// Reset drops every block after genesis and the state built from them,
+// keeping the consensus rules, validators and block reward. The difficulty
+// schedule goes with the blocks it covered. It returns how many blocks
+// were dropped.
+func (c *Chain) Reset() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	dropped := len(c.blocks) - 1
+	c.reset(c.blocks[0])
+	if pow, ok := c.consensus.(*ProofOfWork); ok && len(pow.Schedule) > 0 {
+		// consensus is shared with scratch chains, so swap in a copy
+		next := *pow
+		next.Schedule = nil
+		c.consensus = &next
+	}
+	return dropped
+}
+
+// reset empties the chain down to genesis (caller holds mu)
+func (c *Chain) reset(genesis Block) {
+	c.blocks = nil
+	c.hashIndex = map[string]int{}
+	c.txIndex = map[string]int{}
+	c.txPos = map[string]int{}
+	c.utxos = map[OutPoint]TxOutput{}
+	c.spans = map[OutPoint]*outputSpan{}
+	c.state = newWorldState()
+	c.receipts = map[string]Receipt{}
+	c.logs = nil
+	c.kvHistory = map[string][]kvVersion{}
+	c.sizes = nil
+	c.miners = map[string]*MinerStats{}
+	c.tampered = nil
+	c.link(genesis)
+}
+
+// Blocks returns a copy of the chain
+func (c *Chain) Blocks() []Block {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]Block, len(c.blocks))
+	copy(out, c.blocks)
+	return out
+}
+
+// BlockAt returns the block at index without copying the rest of the chain
+func (c *Chain) BlockAt(index int) (Block, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if index < 0 || index >= len(c.blocks) {
+		return Block{}, false
+	}
+	return c.blocks[index], true
+}
+
+// BlockByHash returns the block with the given hash
+func (c *Chain) BlockByHash(hash string) (Block, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i, ok := c.hashIndex[hash]
+	if !ok {
+		return Block{}, false
+	}
+	return c.blocks[i], true
+}
+
+// Confirmations returns how many blocks deep the block at index is: 1 for
+// the tip, 0 for an index past it
+func (c *Chain) Confirmations(index int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if index < 0 || index >= len(c.blocks) {
+		return 0
+	}
+	return len(c.blocks) - index
+}
+
+// Tip returns the last block
+func (c *Chain) Tip() Block {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.blocks[len(c.blocks)-1]
+}
+
+// Len returns the number of blocks including genesis
+func (c *Chain) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.blocks)
+}
+
+// TxCount returns the number of confirmed transactions, coinbases and
+// genesis included
+func (c *Chain) TxCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.txIndex)
+}
+
+// Difficulty returns the number of leading zeros required for new blocks,
+// retargeted from block times when retargeting is on, or 0 when the chain
+// does not use proof-of-work
+func (c *Chain) Difficulty() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.difficulty()
+}
+
+// difficulty is Difficulty for callers holding mu
+func (c *Chain) difficulty() int {
+	pow, ok := c.consensus.(*ProofOfWork)
+	switch {
+	case !ok:
+		return 0
+	case c.retarget.Every > 0:
+		return c.retargeted(pow.Difficulty)
+	}
+	return pow.Difficulty
+}
+
+// Consensus returns the chain's consensus rules
+func (c *Chain) Consensus() Consensus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.consensus
+}
+
+// Hasher returns the chain's hash function
+func (c *Chain) Hasher() Hasher {
+	return c.hasher
+}
+
+// Produce seals a block template with the chain's consensus rules. It does
+// not hold the chain lock, so long proof-of-work searches don't block reads.
+func (c *Chain) Produce(ctx context.Context, b Block) (Block, error) {
+	return c.Consensus().ProduceBlock(ctx, b, c.hasher)
+}
+
+// NextBlock returns an unmined block template carrying txns on top of the
+// tip. Expired transactions, and contract transactions that fail when run
+// in order, are left out.
+func (c *Chain) NextBlock(txns []Transaction) Block {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tip := c.blocks[len(c.blocks)-1]
+	st := c.state.clone()
+	kept := make([]Transaction, 0, len(txns))
+	var receipts []Receipt
+	for _, t := range txns {
+		if t.Expired(tip.Index + 1) {
+			continue
+		}
+		r, err := applyTx(st, t, tip.Index+1)
+		if err != nil {
+			continue
+		}
+		if r != nil {
+			receipts = append(receipts, *r)
+		}
+		kept = append(kept, t)
+	}
+	return Block{
+		Version:    CodecVersion,
+		Index:      tip.Index + 1,
+		Txns:       kept,
+		MerkleRoot: MerkleRoot(c.hasher, kept),
+		StateRoot:  st.root(c.hasher),
+		LogsRoot:   logsRoot(c.hasher, receipts),
+		PrevHash:   tip.Hash,
+		Difficulty: c.difficulty(),
+	}
+}
+
+// AddBlock validates b against the tip and appends it
+func (c *Chain) AddBlock(b Block) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st, receipts, err := c.validateNext(b)
+	if err != nil {
+		return err
+	}
+	c.link(b)
+	c.state = st
+	c.recordKV(b)
+	c.recordMiner(b)
+	n := 0
+	for _, r := range receipts {
+		r.TxIndex = c.txPos[r.TxID]
+		for i := range r.Logs {
+			r.Logs[i].Index = n
+			n++
+			c.logs = append(c.logs, r.Logs[i])
+		}
+		c.receipts[r.TxID] = r
+	}
+	return nil
+}
+
+// validateNext checks that b may be appended to the current tip and returns
+// the contract state after it (caller holds mu)
+func (c *Chain) validateNext(b Block) (*worldState, []Receipt, error) {
+	prev := c.blocks[len(c.blocks)-1]
+	if b.Version < 0 || b.Version > CodecVersion {
+		return nil, nil, fmt.Errorf("block %d has unknown version %d", b.Index, b.Version)
+	}
+	if b.Version < prev.Version {
+		return nil, nil, fmt.Errorf("block %d has version %d, below its parent's %d", b.Index, b.Version, prev.Version)
+	}
+	if b.Index != prev.Index+1 {
+		return nil, nil, fmt.Errorf("block index %d does not follow %d", b.Index, prev.Index)
+	}
+	if b.PrevHash != prev.Hash {
+		return nil, nil, fmt.Errorf("block %d prev_hash does not match tip", b.Index)
+	}
+	if b.MerkleRoot != MerkleRoot(c.hasher, b.Txns) {
+		return nil, nil, fmt.Errorf("block %d merkle root mismatch", b.Index)
+	}
+	if b.Hash != HashBlock(c.hasher, b) {
+		return nil, nil, fmt.Errorf("block %d hash mismatch", b.Index)
+	}
+	spent := map[OutPoint]bool{}
+	seen := map[string]bool{}
+	st := c.state.clone()
+	var receipts []Receipt
+	for i, t := range b.Txns {
+		if seen[t.ID] {
+			// the txid index holds one position per block
+			return nil, nil, fmt.Errorf("block %d: %w: %s appears twice", b.Index, ErrInvalidTx, t.ID)
+		}
+		seen[t.ID] = true
+		if err := c.validateTx(t, b.Index); err != nil {
+			return nil, nil, fmt.Errorf("block %d: %w", b.Index, err)
+		}
+		if t.Coinbase != 0 {
+			if err := c.checkCoinbase(b, i, t); err != nil {
+				return nil, nil, fmt.Errorf("block %d: %w", b.Index, err)
+			}
+		}
+		for _, op := range t.Spends() {
+			if spent[op] {
+				return nil, nil, fmt.Errorf("block %d: %w: %s spent twice", b.Index, ErrSpend, op)
+			}
+			spent[op] = true
+		}
+		r, err := applyTx(st, t, b.Index)
+		if err != nil {
+			return nil, nil, fmt.Errorf("block %d: %w", b.Index, err)
+		}
+		if r != nil {
+			receipts = append(receipts, *r)
+		}
+	}
+	if root := st.root(c.hasher); b.StateRoot != root {
+		return nil, nil, fmt.Errorf("block %d state root mismatch", b.Index)
+	}
+	if b.LogsRoot != logsRoot(c.hasher, receipts) {
+		return nil, nil, fmt.Errorf("block %d logs root mismatch", b.Index)
+	}
+	if err := c.checkHeader(b); err != nil {
+		return nil, nil, err
+	}
+	return st, receipts, nil
+}
+
+// checkHeader checks b's seal under the consensus rules; with retargeting
+// on, b must record exactly the difficulty in force at its height and meet
+// it, whatever the configured difficulty (caller holds mu)
+func (c *Chain) checkHeader(b Block) error {
+	if c.retarget.Every == 0 {
+		return c.consensus.ValidateHeader(b, c.hasher)
+	}
+	if want := c.difficulty(); b.Difficulty != want {
+		return fmt.Errorf("block %d records difficulty %d, want %d at its height", b.Index, b.Difficulty, want)
+	}
+	if !MeetsDifficulty(b.Hash, b.Difficulty) {
+		return fmt.Errorf("block %d does not meet difficulty %d", b.Index, b.Difficulty)
+	}
+	return nil
+}
+
+// link appends a block and updates the indexes (caller holds mu)
+func (c *Chain) link(b Block) {
+	b.Confirmations, b.Size = 0, 0
+	c.blocks = append(c.blocks, b)
+	c.sizes = append(c.sizes, measure(b))
+	c.tipHash = b.Hash
+	c.hashIndex[b.Hash] = b.Index
+	for i, t := range b.Txns {
+		c.txIndex[t.ID] = b.Index
+		c.txPos[t.ID] = i
+		for _, op := range t.Spends() {
+			delete(c.utxos, op)
+		}
+		for i, out := range t.Outputs {
+			c.utxos[OutPoint{t.ID, i}] = out
+		}
+	}
+	c.recordOutputs(b)
+}
+
+// HasTx reports whether txid is confirmed and in which block
+func (c *Chain) HasTx(txid string) (blockIndex int, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	blockIndex, ok = c.txIndex[txid]
+	return blockIndex, ok
+}
+
+// TxPosition reports where txid is confirmed: its block and its position
+// within that block's transactions
+func (c *Chain) TxPosition(txid string) (blockIndex, txIndex int, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	blockIndex, ok = c.txIndex[txid]
+	return blockIndex, c.txPos[txid], ok
+}
+
+// TxAt returns the transaction at position txIndex in block blockIndex
+func (c *Chain) TxAt(blockIndex, txIndex int) (TxMatch, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if blockIndex < 0 || blockIndex >= len(c.blocks) {
+		return TxMatch{}, false
+	}
+	b := c.blocks[blockIndex]
+	if txIndex < 0 || txIndex >= len(b.Txns) {
+		return TxMatch{}, false
+	}
+	return TxMatch{
+		BlockIndex:    b.Index,
+		TxIndex:       txIndex,
+		Transaction:   b.Txns[txIndex],
+		BlockHash:     b.Hash,
+		Confirmations: len(c.blocks) - b.Index,
+	}, true
+}
+
+// Search returns confirmed transactions whose data contains q
+// (case-insensitive) or whose ID is q
+func (c *Chain) Search(q string) []TxMatch {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	results := []TxMatch{}
+	for _, b := range c.blocks {
+		for i, t := range b.Txns {
+			if t.ID == q || strings.Contains(strings.ToLower(t.Data), strings.ToLower(q)) {
+				results = append(results, TxMatch{
+					BlockIndex:    b.Index,
+					TxIndex:       i,
+					Transaction:   t,
+					BlockHash:     b.Hash,
+					Confirmations: len(c.blocks) - b.Index,
+				})
+			}
+		}
+	}
+	return results
+}
+
+// SearchAddress returns every confirmed transaction with an output paying
+// to address
+func (c *Chain) SearchAddress(address string) []TxMatch {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	results := []TxMatch{}
+	for _, b := range c.blocks {
+		for i, t := range b.Txns {
+			for _, o := range t.Outputs {
+				if script.Address(o.Lock) != address {
+					continue
+				}
+				results = append(results, TxMatch{
+					BlockIndex:    b.Index,
+					TxIndex:       i,
+					Transaction:   t,
+					BlockHash:     b.Hash,
+					Confirmations: len(c.blocks) - b.Index,
+				})
+				break
+			}
+		}
+	}
+	return results
+}
+
+// Balances totals the unspent outputs of every address holding any
+func (c *Chain) Balances() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := map[string]int64{}
+	for _, o := range c.utxos {
+		if addr := script.Address(o.Lock); addr != "" {
+			out[addr] += o.Amount
+		}
+	}
+	return out
+}
+
+// UTXOs returns the unspent outputs paying to address, or all of them when
+// address is empty, ordered by outpoint
+func (c *Chain) UTXOs(address string) []UTXO {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := []UTXO{}
+	for op, o := range c.utxos {
+		addr := script.Address(o.Lock)
+		if address != "" && addr != address {
+			continue
+		}
+		out = append(out, UTXO{OutPoint: op, TxOutput: o, Address: addr})
+	}
+	sortUTXOs(out)
+	return out
+}
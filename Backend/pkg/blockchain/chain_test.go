@@ -0,0 +1,105 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// mineNext mines txns on top of c's tip and appends the block
+func mineNext(t testing.TB, c *Chain, txns ...Transaction) Block {
+	t.Helper()
+	b, err := c.Produce(context.Background(), c.NextBlock(txns))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlock(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestBlockCarryingATxidTwiceIsRejected(t *testing.T) {
+	c := NewChain(1)
+	tx := NewDataTx("twice")
+	b, err := c.Produce(context.Background(), c.NextBlock([]Transaction{tx, tx}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlock(b); !errors.Is(err, ErrInvalidTx) {
+		t.Fatalf("adding a block that carries %s twice: %v, want ErrInvalidTx", tx.ID, err)
+	}
+	// once confirmed, the txid can't be confirmed again
+	mineNext(t, c, tx)
+	if err := c.ValidateTx(tx); !errors.Is(err, ErrConfirmed) {
+		t.Fatalf("ValidateTx of a confirmed transaction returned %v, want ErrConfirmed", err)
+	}
+	again, err := c.Produce(context.Background(), c.NextBlock([]Transaction{tx}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlock(again); !errors.Is(err, ErrConfirmed) {
+		t.Fatalf("AddBlock of a block confirming %s again returned %v, want ErrConfirmed", tx.ID, err)
+	}
+	if err := c.CheckInvariants(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a chain holding it twice anyway fails its invariants
+	c.blocks = append(c.blocks, again)
+	c.tipHash = again.Hash
+	if err := c.CheckInvariants(); err == nil || !strings.Contains(err.Error(), "again in block 2") {
+		t.Fatalf("CheckInvariants of a chain confirming %s twice returned %v", tx.ID, err)
+	}
+}
+
+// FuzzImportChain decodes arbitrary bytes as a JSON chain, as one sent by a
+// peer or read from a file, and replays it onto a chain sharing its genesis
+// block. Whatever arrives, the blocks that are accepted form a chain that
+// passes its invariant checks and hashes as it claims.
+func FuzzImportChain(f *testing.F) {
+	c := NewChain(1)
+	mineNext(f, c, NewDataTx("alice pays bob 5"), NewDataTx("bob pays carol 2"))
+	mineNext(f, c, NewDataTx("carol pays alice 1"))
+	chain, err := json.Marshal(c.Blocks())
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(chain)
+	f.Add(chain[:len(chain)/2])
+	f.Add(bytes.Replace(chain, []byte(`"nonce":`), []byte(`"nonce":1`), 1))
+	f.Add(bytes.Replace(chain, []byte("alice pays bob 5"), []byte("alice pays bob 500"), 1))
+	f.Add([]byte(`[{}]`))
+	f.Add([]byte(`[{"index":1}]`))
+	f.Add([]byte(`[]`))
+	f.Add([]byte(`null`))
+	genesis := c.Blocks()[0]
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		var blocks []Block
+		if err := json.Unmarshal(raw, &blocks); err != nil || len(blocks) == 0 || blocks[0].Hash != genesis.Hash {
+			return
+		}
+		c := NewChainFromGenesis(genesis, &ProofOfWork{Difficulty: 1}, DefaultHasher())
+		accepted := 1
+		for _, b := range blocks[1:] {
+			if c.AddBlock(b) != nil {
+				break
+			}
+			accepted++
+		}
+		if c.Len() != accepted {
+			t.Fatalf("accepted %d blocks, the chain holds %d", accepted, c.Len())
+		}
+		if err := c.CheckInvariants(); err != nil {
+			t.Fatal(err)
+		}
+		for _, b := range c.Blocks()[1:] {
+			if HashBlock(DefaultHasher(), b) != b.Hash || MerkleRoot(DefaultHasher(), b.Txns) != b.MerkleRoot {
+				t.Fatalf("block %d was accepted with a hash or merkle root it doesn't have", b.Index)
+			}
+		}
+	})
+}
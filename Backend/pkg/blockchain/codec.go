@@ -0,0 +1,450 @@
+package blockchain
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// CodecVersion is the binary encoding EncodeBlock and EncodeTx write, and
+// the Block.Version newly mined blocks carry
+const CodecVersion = 1
+
+// BinaryMediaType is the Content-Type of binary blocks and block streams
+const BinaryMediaType = "application/octet-stream"
+
+// MaxEncodedBlock caps one block's binary encoding in a stream
+const MaxEncodedBlock = 16 << 20
+
+// ErrCodec is returned for bytes that aren't a valid encoding
+var ErrCodec = errors.New("invalid binary encoding")
+
+// Transaction fields present in an encoding, one bit each
+const (
+	txHasData = 1 << iota
+	txHasInputs
+	txHasOutputs
+	txHasContract
+	txHasKV
+	txHasConfidential
+	txHasCommit
+	txHasReveal
+	txHasBlob
+	txHasCoinbase
+	txHasPriority
+	txHasExpiry
+	txHasSignature
+	txHasPayment
+)
+
+// EncodeBlock returns the binary encoding of b: the codec version, the
+// header, the transactions and the hash. Response-only fields are left out.
+//
+// Integers are varints (zigzag for signed ones), strings are length
+// prefixed, and hex strings such as hashes are packed into half the bytes.
+func EncodeBlock(b Block) []byte {
+	e := blockBody(b)
+	e.hex(b.Hash)
+	return e.buf
+}
+
+// blockBody encodes b without its hash: what a version 1 block hash covers
+func blockBody(b Block) *encoder {
+	e := &encoder{buf: make([]byte, 0, 256)}
+	e.buf = append(e.buf, CodecVersion)
+	e.int(int64(b.Version))
+	e.int(int64(b.Index))
+	e.int(b.Timestamp)
+	e.hex(b.MerkleRoot)
+	e.hex(b.StateRoot)
+	e.hex(b.LogsRoot)
+	e.hex(b.PrevHash)
+	e.int(b.Nonce)
+	e.str(b.Miner)
+	e.str(b.Producer)
+	e.int(int64(b.Difficulty))
+	e.uint(uint64(len(b.Txns)))
+	for _, t := range b.Txns {
+		e.tx(t)
+	}
+	return e
+}
+
+// DecodeBlock reads a block written by EncodeBlock
+func DecodeBlock(raw []byte) (Block, error) {
+	d := &decoder{buf: raw}
+	d.version()
+	b := Block{
+		Version:    int(d.int()),
+		Index:      int(d.int()),
+		Timestamp:  d.int(),
+		MerkleRoot: d.hex(),
+		StateRoot:  d.hex(),
+		LogsRoot:   d.hex(),
+		PrevHash:   d.hex(),
+		Nonce:      d.int(),
+		Miner:      d.str(),
+		Producer:   d.str(),
+		Difficulty: int(d.int()),
+	}
+	if n := d.count(); n > 0 {
+		b.Txns = make([]Transaction, n)
+		for i := range b.Txns {
+			b.Txns[i] = d.tx()
+		}
+	}
+	b.Hash = d.hex()
+	return b, d.finish()
+}
+
+// EncodeTx returns the binary encoding of t, its ID included
+func EncodeTx(t Transaction) []byte {
+	e := &encoder{}
+	e.buf = append(e.buf, CodecVersion)
+	e.tx(t)
+	return e.buf
+}
+
+// DecodeTx reads a transaction written by EncodeTx
+func DecodeTx(raw []byte) (Transaction, error) {
+	d := &decoder{buf: raw}
+	d.version()
+	t := d.tx()
+	return t, d.finish()
+}
+
+// WriteBlock writes b to w framed for a stream: a varint length followed
+// by EncodeBlock(b)
+func WriteBlock(w io.Writer, b Block) error {
+	raw := EncodeBlock(b)
+	frame := binary.AppendUvarint(make([]byte, 0, len(raw)+binary.MaxVarintLen32), uint64(len(raw)))
+	_, err := w.Write(append(frame, raw...))
+	return err
+}
+
+// ReadBlock reads one block written by WriteBlock and returns it with the
+// bytes its frame took. It returns io.EOF at a clean end of the stream and
+// io.ErrUnexpectedEOF for a frame cut short.
+func ReadBlock(r *bufio.Reader) (Block, int, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		if err == io.EOF {
+			return Block{}, 0, io.EOF
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return Block{}, 0, io.ErrUnexpectedEOF
+		}
+		return Block{}, 0, fmt.Errorf("%w: %v", ErrCodec, err)
+	}
+	if size > MaxEncodedBlock {
+		return Block{}, 0, fmt.Errorf("%w: block of %d bytes exceeds %d", ErrCodec, size, MaxEncodedBlock)
+	}
+	raw := make([]byte, size)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return Block{}, 0, err
+	}
+	b, err := DecodeBlock(raw)
+	return b, uvarintLen(size) + len(raw), err
+}
+
+// uvarintLen is the number of bytes v takes as a uvarint
+func uvarintLen(v uint64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], v)
+}
+
+// encoder appends the binary encoding to buf
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) uint(v uint64) { e.buf = binary.AppendUvarint(e.buf, v) }
+
+func (e *encoder) int(v int64) { e.buf = binary.AppendVarint(e.buf, v) }
+
+func (e *encoder) str(s string) {
+	e.uint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// hex writes s packed when it is lowercase hex of even length, as hashes,
+// keys and signatures are, and as a plain string otherwise; the low bit of
+// the length says which
+func (e *encoder) hex(s string) {
+	if raw, err := hex.DecodeString(s); err == nil && hex.EncodeToString(raw) == s {
+		e.uint(uint64(len(raw))<<1 | 1)
+		e.buf = append(e.buf, raw...)
+		return
+	}
+	e.uint(uint64(len(s)) << 1)
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encoder) tx(t Transaction) {
+	fields := txFields(t)
+	e.hex(t.ID)
+	e.uint(fields)
+	if fields&txHasData != 0 {
+		e.str(t.Data)
+	}
+	if fields&txHasInputs != 0 {
+		e.uint(uint64(len(t.Inputs)))
+		for _, in := range t.Inputs {
+			e.hex(in.TxID)
+			e.int(int64(in.Index))
+			e.str(in.Unlock)
+		}
+	}
+	if fields&txHasOutputs != 0 {
+		e.uint(uint64(len(t.Outputs)))
+		for _, o := range t.Outputs {
+			e.int(o.Amount)
+			e.str(o.Lock)
+		}
+	}
+	if c := t.Contract; c != nil {
+		e.str(c.Code)
+		e.str(c.Address)
+		e.uint(uint64(len(c.Args)))
+		for _, a := range c.Args {
+			e.str(a)
+		}
+		e.int(c.GasLimit)
+		e.hex(c.PubKey)
+		e.hex(c.Sig)
+	}
+	if fields&txHasKV != 0 {
+		e.uint(uint64(len(t.KV)))
+		for _, op := range t.KV {
+			e.str(op.Op)
+			e.str(op.Key)
+			e.str(op.Value)
+		}
+	}
+	if c := t.Confidential; c != nil {
+		e.hex(c.Recipient)
+		e.hex(c.Ciphertext)
+		e.hex(c.Commitment)
+	}
+	if fields&txHasCommit != 0 {
+		e.hex(t.Commit)
+	}
+	if r := t.Reveal; r != nil {
+		e.hex(r.TxID)
+		e.hex(r.Salt)
+	}
+	if fields&txHasBlob != 0 {
+		e.hex(t.Blob)
+	}
+	if fields&txHasCoinbase != 0 {
+		e.int(int64(t.Coinbase))
+	}
+	if fields&txHasPriority != 0 {
+		e.str(t.Priority)
+	}
+	if fields&txHasExpiry != 0 {
+		e.int(int64(t.ExpiresAt))
+	}
+	if fields&txHasSignature != 0 {
+		e.hex(t.Sender)
+		e.int(t.Timestamp)
+		e.hex(t.Signature)
+	}
+	if fields&txHasPayment != 0 {
+		e.str(t.Recipient)
+		e.int(t.Amount)
+	}
+}
+
+// txFields is the bitmask of the fields t sets
+func txFields(t Transaction) uint64 {
+	var fields uint64
+	set := func(bit uint64, present bool) {
+		if present {
+			fields |= bit
+		}
+	}
+	set(txHasData, t.Data != "")
+	set(txHasInputs, len(t.Inputs) > 0)
+	set(txHasOutputs, len(t.Outputs) > 0)
+	set(txHasContract, t.Contract != nil)
+	set(txHasKV, len(t.KV) > 0)
+	set(txHasConfidential, t.Confidential != nil)
+	set(txHasCommit, t.Commit != "")
+	set(txHasReveal, t.Reveal != nil)
+	set(txHasBlob, t.Blob != "")
+	set(txHasCoinbase, t.Coinbase != 0)
+	set(txHasPriority, t.Priority != "")
+	set(txHasExpiry, t.ExpiresAt != 0)
+	set(txHasSignature, t.Sender != "" || t.Timestamp != 0 || t.Signature != "")
+	set(txHasPayment, t.Recipient != "" || t.Amount != 0)
+	return fields
+}
+
+// decoder reads the binary encoding from buf; the first error sticks and
+// every later read returns zero values
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf("%w: "+format, append([]interface{}{ErrCodec}, args...)...)
+	}
+	d.buf = nil
+}
+
+// version checks the leading codec version
+func (d *decoder) version() {
+	switch {
+	case len(d.buf) == 0:
+		d.fail("empty")
+	case d.buf[0] != CodecVersion:
+		d.fail("unsupported codec version %d (want %d)", d.buf[0], CodecVersion)
+	default:
+		d.buf = d.buf[1:]
+	}
+}
+
+// finish reports the first error, or trailing bytes after a complete value
+func (d *decoder) finish() error {
+	if d.err == nil && len(d.buf) > 0 {
+		d.fail("%d trailing bytes", len(d.buf))
+	}
+	return d.err
+}
+
+func (d *decoder) uint() uint64 {
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.fail("truncated or overlong varint")
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) int() int64 {
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.fail("truncated or overlong varint")
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+// count reads a number of items, each taking at least a byte, so a
+// corrupt count can't allocate more than the input holds
+func (d *decoder) count() int {
+	n := d.uint()
+	if n > uint64(len(d.buf)) {
+		d.fail("count %d exceeds the %d bytes left", n, len(d.buf))
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) bytes(n uint64) []byte {
+	if n > uint64(len(d.buf)) {
+		d.fail("length %d exceeds the %d bytes left", n, len(d.buf))
+		return nil
+	}
+	out := d.buf[:n]
+	d.buf = d.buf[n:]
+	return out
+}
+
+func (d *decoder) str() string {
+	return string(d.bytes(d.uint()))
+}
+
+func (d *decoder) hex() string {
+	n := d.uint()
+	raw := d.bytes(n >> 1)
+	if n&1 == 1 {
+		return hex.EncodeToString(raw)
+	}
+	return string(raw)
+}
+
+func (d *decoder) tx() Transaction {
+	t := Transaction{ID: d.hex()}
+	fields := d.uint()
+	if fields >= txHasPayment<<1 {
+		d.fail("unknown transaction fields %#x", fields)
+		return t
+	}
+	if fields&txHasData != 0 {
+		t.Data = d.str()
+	}
+	if fields&txHasInputs != 0 {
+		t.Inputs = make([]TxInput, d.count())
+		for i := range t.Inputs {
+			t.Inputs[i] = TxInput{TxID: d.hex(), Index: int(d.int()), Unlock: d.str()}
+		}
+	}
+	if fields&txHasOutputs != 0 {
+		t.Outputs = make([]TxOutput, d.count())
+		for i := range t.Outputs {
+			t.Outputs[i] = TxOutput{Amount: d.int(), Lock: d.str()}
+		}
+	}
+	if fields&txHasContract != 0 {
+		c := &ContractOp{Code: d.str(), Address: d.str()}
+		if n := d.count(); n > 0 {
+			c.Args = make([]string, n)
+			for i := range c.Args {
+				c.Args[i] = d.str()
+			}
+		}
+		c.GasLimit = d.int()
+		c.PubKey = d.hex()
+		c.Sig = d.hex()
+		t.Contract = c
+	}
+	if fields&txHasKV != 0 {
+		t.KV = make([]KVOp, d.count())
+		for i := range t.KV {
+			t.KV[i] = KVOp{Op: d.str(), Key: d.str(), Value: d.str()}
+		}
+	}
+	if fields&txHasConfidential != 0 {
+		t.Confidential = &Confidential{Recipient: d.hex(), Ciphertext: d.hex(), Commitment: d.hex()}
+	}
+	if fields&txHasCommit != 0 {
+		t.Commit = d.hex()
+	}
+	if fields&txHasReveal != 0 {
+		t.Reveal = &Reveal{TxID: d.hex(), Salt: d.hex()}
+	}
+	if fields&txHasBlob != 0 {
+		t.Blob = d.hex()
+	}
+	if fields&txHasCoinbase != 0 {
+		t.Coinbase = int(d.int())
+	}
+	if fields&txHasPriority != 0 {
+		t.Priority = d.str()
+	}
+	if fields&txHasExpiry != 0 {
+		t.ExpiresAt = int(d.int())
+	}
+	if fields&txHasSignature != 0 {
+		t.Sender = d.hex()
+		t.Timestamp = d.int()
+		t.Signature = d.hex()
+	}
+	if fields&txHasPayment != 0 {
+		t.Recipient = d.str()
+		t.Amount = d.int()
+	}
+	return t
+}
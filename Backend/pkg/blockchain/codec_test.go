@@ -0,0 +1,266 @@
+package blockchain
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// codecChain is a chain of random blocks holding every kind of transaction
+// the builder makes, plus a version 0 genesis block
+func codecChain(t testing.TB) []Block {
+	t.Helper()
+	c := newTestChain(NewChain(1).Tip())
+	builder{rand.New(rand.NewSource(1))}.grow(t, c, 8)
+	return c.Blocks()
+}
+
+func TestBlocksRoundTripThroughTheCodec(t *testing.T) {
+	blocks := codecChain(t)
+	var stream bytes.Buffer
+	for _, b := range blocks {
+		got, err := DecodeBlock(EncodeBlock(b))
+		if err != nil {
+			t.Fatalf("block %d: %v", b.Index, err)
+		}
+		if !reflect.DeepEqual(got, b) {
+			t.Fatalf("block %d decodes to\n%+v\nwant\n%+v", b.Index, got, b)
+		}
+		for _, tx := range b.Txns {
+			if got, err := DecodeTx(EncodeTx(tx)); err != nil || !reflect.DeepEqual(got, tx) {
+				t.Fatalf("transaction %s decodes to %+v, %v", tx.ID, got, err)
+			}
+		}
+		if err := WriteBlock(&stream, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := bufio.NewReader(bytes.NewReader(stream.Bytes()))
+	read := 0
+	for i := 0; ; i++ {
+		b, n, err := ReadBlock(r)
+		if err == io.EOF {
+			if i != len(blocks) {
+				t.Fatalf("the stream ended after %d of %d blocks", i, len(blocks))
+			}
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading block %d: %v", i, err)
+		}
+		if b.Hash != blocks[i].Hash {
+			t.Fatalf("block %d of the stream is %s, want %s", i, b.Hash, blocks[i].Hash)
+		}
+		read += n
+	}
+	if read != stream.Len() {
+		t.Fatalf("frames account for %d of the stream's %d bytes", read, stream.Len())
+	}
+
+	cut := bufio.NewReader(bytes.NewReader(stream.Bytes()[:stream.Len()-1]))
+	var err error
+	for err == nil {
+		_, _, err = ReadBlock(cut)
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("reading a stream cut short: %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+// everyKind holds one transaction of each kind the codec has a field bit
+// for, every optional field set
+func everyKind() map[string]Transaction {
+	parent := CalculateHash("parent")
+	return map[string]Transaction{
+		"data":     NewDataTx("alice pays bob 5"),
+		"issuance": Transaction{Data: "mint", Outputs: []TxOutput{{Amount: 50, Lock: "OP_1"}}}.Seal(),
+		"transfer": Transaction{
+			Inputs:  []TxInput{{TxID: parent, Index: 1, Unlock: "3045 02ab"}, {TxID: parent, Index: 0, Unlock: "OP_1"}},
+			Outputs: []TxOutput{{Amount: 7, Lock: "OP_DUP OP_HASH160 ab OP_EQUALVERIFY OP_CHECKSIG"}, {Amount: 0, Lock: "OP_1"}},
+		}.Seal(),
+		"contract":     Transaction{Contract: &ContractOp{Code: "PUSH 1", Address: "c1", Args: []string{"a", ""}, GasLimit: 1000, PubKey: "02ab", Sig: "3045"}}.Seal(),
+		"kv":           Transaction{KV: []KVOp{{Op: "set", Key: "k", Value: "v"}, {Op: "del", Key: "k"}}}.Seal(),
+		"confidential": Transaction{Confidential: &Confidential{Recipient: "02ab", Ciphertext: "beef", Commitment: parent}}.Seal(),
+		"commit":       Transaction{Commit: parent}.Seal(),
+		"reveal":       NewRevealTx(parent, "00ff", "the payload"),
+		"blob":         Transaction{Blob: parent, Data: "report.pdf"}.Seal(),
+		"coinbase":     NewCoinbaseTx(3, "OP_1", 25),
+		"priority":     Transaction{Data: "urgent", Priority: "high"}.Seal(),
+		"expiry":       Transaction{Data: "soon", ExpiresAt: 12}.Seal(),
+		"signed":       Transaction{Data: "notarised", Sender: "02ab", Timestamp: 1700000000, Signature: "3045"}.Seal(),
+		"payment": Transaction{
+			Inputs:    []TxInput{{TxID: parent, Index: 2}},
+			Outputs:   []TxOutput{{Amount: 5, Lock: "OP_DUP OP_HASH160 ab OP_EQUALVERIFY OP_CHECKSIG"}},
+			Sender:    "02ab",
+			Recipient: "ab",
+			Amount:    5,
+			Timestamp: 1700000000,
+			Signature: "3045",
+		}.Seal(),
+	}
+}
+
+func TestTxRoundTrip(t *testing.T) {
+	for kind, tx := range everyKind() {
+		got, err := DecodeTx(EncodeTx(tx))
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		if !reflect.DeepEqual(got, tx) {
+			t.Errorf("%s: decoded as %+v, want %+v", kind, got, tx)
+		}
+		if got.Seal().ID != tx.ID {
+			t.Errorf("%s: the decoded transaction hashes to %s, not its id %s", kind, got.Seal().ID, tx.ID)
+		}
+	}
+}
+
+func TestBlockRoundTrip(t *testing.T) {
+	c := NewChain(1)
+	var txns []Transaction
+	for _, tx := range everyKind() {
+		txns = append(txns, tx)
+	}
+	b := c.NextBlock(txns)
+	b.StateRoot, b.LogsRoot = CalculateHash("state"), CalculateHash("logs")
+	b.Miner, b.Producer, b.Difficulty = "miner", "node-1", 1
+	b, err := c.Produce(context.Background(), b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range []int{0, CodecVersion} {
+		b := b
+		b.Version = version
+		b.Hash = HashBlock(SHA256{}, b)
+		got, err := DecodeBlock(EncodeBlock(b))
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		if !reflect.DeepEqual(got, b) {
+			t.Errorf("version %d: decoded as %+v, want %+v", version, got, b)
+		}
+		if HashBlock(SHA256{}, got) != b.Hash {
+			t.Errorf("version %d: the decoded block hashes differently", version)
+		}
+
+		var buf bytes.Buffer
+		if err := WriteBlock(&buf, b); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteBlock(&buf, b); err != nil {
+			t.Fatal(err)
+		}
+		r := bufio.NewReader(&buf)
+		for i := 0; i < 2; i++ {
+			got, _, err := ReadBlock(r)
+			if err != nil {
+				t.Fatalf("version %d: frame %d: %v", version, i, err)
+			}
+			if !reflect.DeepEqual(got, b) {
+				t.Errorf("version %d: frame %d read back differently", version, i)
+			}
+		}
+		if _, _, err := ReadBlock(r); err != io.EOF {
+			t.Errorf("version %d: reading past the last frame gave %v, want io.EOF", version, err)
+		}
+	}
+}
+
+// TestVersionHashes pins what each block version hashes: version 0 blocks
+// keep the string record they were mined with, version 1 blocks hash their
+// encoded body, and the version itself is covered so a block can't be relabelled
+func TestVersionHashes(t *testing.T) {
+	b := Block{
+		Index:      1,
+		Timestamp:  1700000000,
+		Txns:       []Transaction{NewDataTx("hello"), NewDataTx("world")},
+		MerkleRoot: "root",
+		PrevHash:   "prev",
+		Nonce:      7,
+	}
+	legacy := sha256.Sum256([]byte("11700000000hello|worldrootprev7"))
+	if got := HashBlock(SHA256{}, b); got != hex.EncodeToString(legacy[:]) {
+		t.Fatalf("a version 0 block hashes to %s, not its legacy record's %s", got, hex.EncodeToString(legacy[:]))
+	}
+	b.Miner, b.Difficulty = "alice", 2
+	legacy = sha256.Sum256([]byte("11700000000hello|worldrootprev7|miner:alice|difficulty:2"))
+	if got := HashBlock(SHA256{}, b); got != hex.EncodeToString(legacy[:]) {
+		t.Fatalf("a version 0 block with a miner hashes to %s, want %s", got, hex.EncodeToString(legacy[:]))
+	}
+
+	v1 := b
+	v1.Version = CodecVersion
+	body := sha256.Sum256(blockBody(v1).buf)
+	if got := HashBlock(SHA256{}, v1); got != hex.EncodeToString(body[:]) {
+		t.Fatalf("a version %d block hashes to %s, not its body's %s", CodecVersion, got, hex.EncodeToString(body[:]))
+	}
+	if HashBlock(SHA256{}, v1) == HashBlock(SHA256{}, b) {
+		t.Fatal("relabelling a block's version keeps its hash")
+	}
+}
+
+func TestVersionUpgrade(t *testing.T) {
+	c := NewChain(1)
+	if v := c.Tip().Version; v != 0 {
+		t.Fatalf("genesis has version %d, want 0", v)
+	}
+	mineNext(t, c, NewDataTx("first"))
+	mineNext(t, c, NewDataTx("second"))
+	if v := c.Tip().Version; v != CodecVersion {
+		t.Fatalf("mined blocks have version %d, want %d", v, CodecVersion)
+	}
+	if in := c.Verify(); !in.Valid {
+		t.Fatalf("a version 0 genesis followed by version %d blocks is invalid: %s", CodecVersion, in.Reason)
+	}
+
+	// a child may not go back to the version its parent moved on from
+	tmpl := c.NextBlock([]Transaction{NewDataTx("downgrade")})
+	tmpl.Version = 0
+	down, err := c.Produce(context.Background(), tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlock(down); err == nil {
+		t.Fatal("AddBlock accepted a block below its parent's version")
+	}
+	blocks := append(c.Blocks(), down)
+	if i, err := VerifyBlocks(blocks, &ProofOfWork{Difficulty: 1}, SHA256{}); err == nil || i != len(blocks)-1 {
+		t.Fatalf("VerifyBlocks = %d, %v; want the downgraded block %d rejected", i, err, len(blocks)-1)
+	}
+}
+
+// FuzzDecodeBlock decodes arbitrary bytes as a binary block. Whatever
+// arrives, decoding fails with ErrCodec or yields a block that encodes and
+// decodes back to itself.
+func FuzzDecodeBlock(f *testing.F) {
+	for _, b := range codecChain(f) {
+		f.Add(EncodeBlock(b))
+	}
+	f.Add([]byte{})
+	f.Add([]byte{CodecVersion})
+	f.Add([]byte{CodecVersion + 1, 0})
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		b, err := DecodeBlock(raw)
+		if err != nil {
+			if !errors.Is(err, ErrCodec) {
+				t.Fatalf("decoding failed without ErrCodec: %v", err)
+			}
+			return
+		}
+		again, err := DecodeBlock(EncodeBlock(b))
+		if err != nil {
+			t.Fatalf("re-encoding a decoded block: %v", err)
+		}
+		if !reflect.DeepEqual(again, b) {
+			t.Fatalf("a decoded block re-encodes as\n%+v\nwant\n%+v", again, b)
+		}
+	})
+}
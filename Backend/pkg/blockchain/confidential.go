@@ -0,0 +1,75 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+// MaxSealedLen bounds a confidential payload's hex ciphertext
+const MaxSealedLen = 16 << 10
+
+// saltLen is the random prefix that keeps commitments to short payloads,
+// like roll numbers, from being brute-forced
+const saltLen = 32
+
+// Confidential is a payload only its recipient can read. The chain stores
+// the ciphertext and a commitment to the salted plaintext, so the recipient
+// can later prove what was submitted.
+type Confidential struct {
+	Recipient  string `json:"recipient"`  // hex public key
+	Ciphertext string `json:"ciphertext"` // wallet.Encrypt of salt || payload
+	Commitment string `json:"commitment"` // hex SHA-256 of salt || payload
+}
+
+// SealPayload encrypts payload to the recipient's hex public key
+func SealPayload(recipientPub, payload string) (*Confidential, error) {
+	plain := make([]byte, saltLen, saltLen+len(payload))
+	if _, err := rand.Read(plain); err != nil {
+		return nil, err
+	}
+	plain = append(plain, payload...)
+	ct, err := wallet.Encrypt(recipientPub, plain)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(plain)
+	return &Confidential{Recipient: recipientPub, Ciphertext: ct, Commitment: hex.EncodeToString(sum[:])}, nil
+}
+
+// Open decrypts the payload with the recipient's hex private key and checks
+// it against the commitment
+func (c *Confidential) Open(privHex string) (string, error) {
+	plain, err := wallet.Decrypt(privHex, c.Ciphertext)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(plain)
+	want, _ := hex.DecodeString(c.Commitment)
+	if len(plain) < saltLen || !bytes.Equal(sum[:], want) {
+		return "", errors.New("payload does not match its commitment")
+	}
+	return string(plain[saltLen:]), nil
+}
+
+// check validates the payload's shape; only the recipient can check its contents
+func (c *Confidential) check() error {
+	if _, err := wallet.DecodePublicKey(c.Recipient); err != nil {
+		return fmt.Errorf("recipient: %v", err)
+	}
+	if c.Ciphertext == "" || len(c.Ciphertext) > MaxSealedLen {
+		return fmt.Errorf("ciphertext must be 1-%d hex characters", MaxSealedLen)
+	}
+	if _, err := hex.DecodeString(c.Ciphertext); err != nil {
+		return fmt.Errorf("ciphertext: %v", err)
+	}
+	if raw, err := hex.DecodeString(c.Commitment); err != nil || len(raw) != sha256.Size {
+		return errors.New("commitment must be a hex SHA-256")
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"salmanahmed/blockchain/pkg/clock"
+)
+
+// Consensus decides how blocks are produced and which headers are valid.
+// Proof-of-work is the default; PoS/PoA modes implement the same interface.
+type Consensus interface {
+	// Name identifies the consensus mode, e.g. "pow"
+	Name() string
+	// ProduceBlock seals a block template so it satisfies the rules; it
+	// gives up with ctx.Err() once ctx is done
+	ProduceBlock(ctx context.Context, b Block, h Hasher) (Block, error)
+	// ValidateHeader checks that a sealed block satisfies the rules
+	ValidateHeader(b Block, h Hasher) error
+}
+
+// ProofOfWork requires block hashes to start with Difficulty zeros
+type ProofOfWork struct {
+	Difficulty int         // leading zeros required of new blocks
+	Clock      clock.Clock // timestamps blocks while mining; nil means the wall clock
+	Workers    int         // nonce searches run in parallel; 0 means one per CPU
+	// Schedule lists the difficulty in force from each height on, oldest
+	// first, so blocks are held to the difficulty of their own height once
+	// it changes; empty holds every block to Difficulty
+	Schedule []DifficultyStep
+	// Overrides are the difficulties a block may be mined at on request,
+	// e.g. to compare mining times, at or above the one in force at its
+	// height. They only bound local mining: ValidateHeader ignores them,
+	// so every node validates a block the same whatever its overrides.
+	Overrides DifficultyRange
+}
+
+// DifficultyRange is an inclusive range of difficulties; the zero range
+// holds none
+type DifficultyRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// Allows reports whether difficulty is in r
+func (r DifficultyRange) Allows(difficulty int) bool {
+	return r.Max > 0 && difficulty >= r.Min && difficulty <= r.Max
+}
+
+// Name implements Consensus
+func (p *ProofOfWork) Name() string { return "pow" }
+
+// Required returns the leading zeros a block at height must meet: the last
+// Schedule step at or below height, or Difficulty without one
+func (p *ProofOfWork) Required(height int) int {
+	for i := len(p.Schedule) - 1; i >= 0; i-- {
+		if p.Schedule[i].Height <= height {
+			return p.Schedule[i].Difficulty
+		}
+	}
+	return p.Difficulty
+}
+
+// Parallelism returns how many workers ProduceBlock searches with
+func (p *ProofOfWork) Parallelism() int {
+	if p.Workers > 0 {
+		return p.Workers
+	}
+	return runtime.NumCPU()
+}
+
+// cancelCheckInterval is how many nonces are tried between context checks
+const cancelCheckInterval = 4096
+
+// ProgressFunc is told how many nonces a search has tried so far
+type ProgressFunc func(tried int64)
+
+type progressKey struct{}
+
+// WithProgress returns a context under which ProduceBlock reports its
+// progress to f every few thousand nonces, and once more when it stops.
+// Any ProgressFunc ctx already carries keeps being told too.
+func WithProgress(ctx context.Context, f ProgressFunc) context.Context {
+	if outer, ok := ctx.Value(progressKey{}).(ProgressFunc); ok {
+		inner := f
+		f = func(tried int64) {
+			outer(tried)
+			inner(tried)
+		}
+	}
+	return context.WithValue(ctx, progressKey{}, f)
+}
+
+// ProduceBlock searches for a nonce such that the block hash has the
+// difficulty the block records, or Difficulty, leading zeros. The nonces
+// from b.Nonce on are split into a disjoint range per worker, and the
+// first worker to find one stops the rest. With a single worker the
+// search is deterministic under a stopped clock.
+func (p *ProofOfWork) ProduceBlock(ctx context.Context, b Block, h Hasher) (Block, error) {
+	difficulty := p.Difficulty
+	if b.Difficulty != 0 {
+		difficulty = b.Difficulty
+	}
+	s := &nonceSearch{
+		target: strings.Repeat("0", difficulty),
+		clk:    clock.Or(p.Clock),
+		hasher: h,
+		found:  make(chan Block, 1),
+	}
+	report, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	search, stop := context.WithCancel(ctx)
+	defer stop()
+
+	workers := p.Parallelism()
+	span := (math.MaxInt64 - b.Nonce) / int64(workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		from := b.Nonce + int64(i)*span
+		var progress ProgressFunc
+		if i == 0 {
+			progress = report // one worker reports the total, so calls don't overlap
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.run(search, b, from, from+span, progress) {
+				stop()
+			}
+		}()
+	}
+	wg.Wait()
+	if report != nil {
+		report(atomic.LoadInt64(&s.tried))
+	}
+	select {
+	case mined := <-s.found:
+		return mined, nil
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		return Block{}, err
+	}
+	return Block{}, fmt.Errorf("no nonce from %d meets difficulty %d", b.Nonce, difficulty)
+}
+
+// nonceSearch is the state ProduceBlock's workers share
+type nonceSearch struct {
+	target string
+	clk    clock.Clock
+	hasher Hasher
+	tried  int64 // atomic, across workers
+	found  chan Block
+}
+
+// run tries the nonces in [from, to) until one meets the target or ctx is
+// done, reporting whether it found one
+func (s *nonceSearch) run(ctx context.Context, b Block, from, to int64, report ProgressFunc) bool {
+	counted := from // nonces before this one are in s.tried
+	flush := func(upto int64) int64 {
+		n := atomic.AddInt64(&s.tried, upto-counted)
+		counted = upto
+		return n
+	}
+	for b.Nonce = from; b.Nonce < to; b.Nonce++ {
+		if (b.Nonce-from)%cancelCheckInterval == 0 {
+			tried := flush(b.Nonce)
+			if ctx.Err() != nil {
+				return false
+			}
+			if report != nil {
+				report(tried)
+			}
+		}
+		b.Timestamp = s.clk.Now().Unix()
+		b.Hash = HashBlock(s.hasher, b)
+		if strings.HasPrefix(b.Hash, s.target) {
+			flush(b.Nonce + 1)
+			select {
+			case s.found <- b:
+			default: // another worker won at the same time
+			}
+			return true
+		}
+	}
+	flush(to)
+	return false
+}
+
+// ValidateHeader checks the block hash meets the difficulty it records,
+// which may not be below the one Required at its height; blocks that
+// predate recorded difficulties must meet that
+func (p *ProofOfWork) ValidateHeader(b Block, h Hasher) error {
+	required := p.Required(b.Index)
+	difficulty := b.Difficulty
+	switch {
+	case difficulty == 0 && b.Version == 0:
+		difficulty = required
+	case difficulty < required:
+		return fmt.Errorf("block %d records difficulty %d, below the %d in force at its height", b.Index, b.Difficulty, required)
+	}
+	if !MeetsDifficulty(b.Hash, difficulty) {
+		return fmt.Errorf("block %d does not meet difficulty %d", b.Index, difficulty)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package blockchain
+
+import (
+	"context"
+	"testing"
+)
+
+// overrides let a block be mined harder than the difficulty in force, never
+// easier, so a peer without them accepts every block they produce
+func TestDifficultyOverridesOnlyRaise(t *testing.T) {
+	c := NewChain(2)
+	if err := c.SetDifficultyOverrides(DifficultyRange{Min: 1, Max: 3}); err != nil {
+		t.Fatal(err)
+	}
+	at := func(difficulty int, data string) Block {
+		t.Helper()
+		template := c.NextBlock([]Transaction{NewDataTx(data)})
+		template.Difficulty = difficulty
+		b, err := c.Produce(context.Background(), template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	if err := c.AddBlock(at(1, "easier")); err == nil {
+		t.Fatal("a block below the difficulty in force was accepted because the overrides allow it")
+	}
+	if err := c.AddBlock(at(3, "harder")); err != nil {
+		t.Fatalf("a block mined harder than required: %v", err)
+	}
+
+	peer := NewChainFromGenesis(c.Blocks()[0], &ProofOfWork{Difficulty: 2}, DefaultHasher())
+	if _, err := peer.Restore(c.Blocks()); err != nil {
+		t.Fatalf("a peer without overrides rejects the chain: %v", err)
+	}
+}
@@ -0,0 +1,289 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"salmanahmed/blockchain/pkg/contract"
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+// ErrContract wraps failed contract deployments and calls
+var ErrContract = errors.New("contract failed")
+
+// ContractOp deploys contract code (Code set) or calls a deployed contract
+// (Address set). A call may be signed, in which case the code sees the
+// signer's address as CALLER.
+type ContractOp struct {
+	Code     string   `json:"code,omitempty"`
+	Address  string   `json:"address,omitempty"`
+	Args     []string `json:"args,omitempty"`
+	GasLimit int64    `json:"gas_limit,omitempty"`
+	PubKey   string   `json:"pubkey,omitempty"`
+	Sig      string   `json:"sig,omitempty"` // over the transaction's SigHash
+}
+
+// check validates the operation on its own; sigHash is the enclosing transaction's
+func (op *ContractOp) check(sigHash []byte) error {
+	switch {
+	case op.Code != "" && op.Address != "":
+		return errors.New("contract operation sets both code and address")
+	case op.Code != "":
+		if _, err := contract.Compile(op.Code); err != nil {
+			return err
+		}
+	case op.Address != "":
+		if op.GasLimit <= 0 || op.GasLimit > contract.MaxGas {
+			return fmt.Errorf("gas limit must be between 1 and %d", contract.MaxGas)
+		}
+		if len(op.Args) > contract.MaxArgs {
+			return fmt.Errorf("more than %d arguments", contract.MaxArgs)
+		}
+	default:
+		return errors.New("contract operation needs code or an address")
+	}
+	if op.Sig != "" && !wallet.Verify(op.PubKey, sigHash, op.Sig) {
+		return errors.New("contract call signature does not verify")
+	}
+	if op.PubKey != "" && op.Sig == "" {
+		return errors.New("contract call names a public key but is unsigned")
+	}
+	return nil
+}
+
+// caller returns the signer's address, or "" for unsigned calls
+func (op *ContractOp) caller() string {
+	if op.PubKey == "" {
+		return ""
+	}
+	return wallet.Address(op.PubKey)
+}
+
+// ContractAddress is the address of the contract deployed by txid
+func ContractAddress(txid string) string {
+	if len(txid) < 40 {
+		return txid
+	}
+	return txid[:40]
+}
+
+// ContractState is a deployed contract and its storage
+type ContractState struct {
+	Address  string            `json:"address"`
+	Code     string            `json:"code"`
+	Storage  map[string]string `json:"storage"`
+	Deployed int               `json:"deployed"` // block index
+}
+
+// Receipt records the outcome of a confirmed contract or transfer transaction
+type Receipt struct {
+	TxID       string `json:"txid"`
+	BlockIndex int    `json:"block_index"`
+	TxIndex    int    `json:"tx_index"` // position within the block
+	Contract   string `json:"contract,omitempty"`
+	contract.Result
+	Logs []Log `json:"logs,omitempty"`
+
+	Confirmations int `json:"confirmations,omitempty"` // filled in by Chain.Receipt
+}
+
+// worldState holds every contract, the key-value store and open
+// commitments. Clones share them until one is written, so validating a
+// block doesn't copy untouched state.
+type worldState struct {
+	contracts map[string]*ContractState
+	owned     map[string]bool // contracts this copy may mutate
+	kv        cowMap
+	commits   cowMap // commit txid -> commitment, until revealed
+	reveals   cowMap // commit txid -> reveal txid
+}
+
+func newWorldState() *worldState {
+	return &worldState{contracts: map[string]*ContractState{}, owned: map[string]bool{}}
+}
+
+func (s *worldState) clone() *worldState {
+	c := newWorldState()
+	for a, cs := range s.contracts {
+		c.contracts[a] = cs
+	}
+	c.kv, c.commits, c.reveals = s.kv.share(), s.commits.share(), s.reveals.share()
+	return c
+}
+
+// mutable returns a contract this copy may write to
+func (s *worldState) mutable(addr string) *ContractState {
+	cs := s.contracts[addr]
+	if cs == nil || s.owned[addr] {
+		return cs
+	}
+	cp := *cs
+	cp.Storage = make(map[string]string, len(cs.Storage))
+	for k, v := range cs.Storage {
+		cp.Storage[k] = v
+	}
+	s.contracts[addr] = &cp
+	s.owned[addr] = true
+	return &cp
+}
+
+// cowMap is a string map shared between clones until one writes to it
+type cowMap struct {
+	m     map[string]string
+	owned bool
+}
+
+func (c cowMap) share() cowMap {
+	return cowMap{m: c.m}
+}
+
+// mutable returns a map this copy may write to
+func (c *cowMap) mutable() map[string]string {
+	if !c.owned {
+		cp := make(map[string]string, len(c.m))
+		for k, v := range c.m {
+			cp[k] = v
+		}
+		c.m, c.owned = cp, true
+	}
+	return c.m
+}
+
+// root commits to the contracts and the key-value store; "" when both are
+// empty, and just the contracts root while the store is empty
+func (s *worldState) root(h Hasher) string {
+	root := s.contractsRoot(h)
+	if kv := kvRoot(h, s.kv.m); kv != "" {
+		root = h.Hash([]byte(root + "|kv:" + kv))
+	}
+	return root
+}
+
+// contractsRoot hashes every contract's code and storage in address order;
+// "" when no contracts exist
+func (s *worldState) contractsRoot(h Hasher) string {
+	if len(s.contracts) == 0 {
+		return ""
+	}
+	addrs := make([]string, 0, len(s.contracts))
+	for a := range s.contracts {
+		addrs = append(addrs, a)
+	}
+	sort.Strings(addrs)
+	leaves := make([]string, len(addrs))
+	for i, a := range addrs {
+		cs := s.contracts[a]
+		keys := make([]string, 0, len(cs.Storage))
+		for k := range cs.Storage {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var b strings.Builder
+		b.WriteString(a + "|" + h.Hash([]byte(cs.Code)))
+		for _, k := range keys {
+			// hash each entry so keys and values can't run together
+			b.WriteString("|" + h.Hash([]byte(k)) + h.Hash([]byte(cs.Storage[k])))
+		}
+		leaves[i] = h.Hash([]byte(b.String()))
+	}
+	return h.Hash([]byte(strings.Join(leaves, "")))
+}
+
+// storage adapts a contract's map to contract.Storage
+type storage map[string]string
+
+func (s storage) Get(k string) string { return s[k] }
+
+func (s storage) Set(k, v string) {
+	if v == "" {
+		delete(s, k)
+		return
+	}
+	s[k] = v
+}
+
+// applyTx runs tx's contract, key-value or commit-reveal operations against
+// st at height and returns its receipt; transfers get a receipt carrying
+// their transfer logs, and other transactions none
+func applyTx(st *worldState, tx Transaction, height int) (*Receipt, error) {
+	if len(tx.KV) > 0 {
+		applyKV(st, tx.KV)
+		return nil, nil
+	}
+	if tx.Commit != "" || tx.Reveal != nil {
+		return nil, applyCommitReveal(st, tx)
+	}
+	op := tx.Contract
+	if op == nil {
+		if len(tx.Outputs) == 0 {
+			return nil, nil
+		}
+		return &Receipt{TxID: tx.ID, BlockIndex: height, Logs: transferLogs(tx, height)}, nil
+	}
+	if op.Code != "" {
+		addr := ContractAddress(tx.ID)
+		if _, ok := st.contracts[addr]; ok {
+			return nil, fmt.Errorf("%w: contract %s already deployed", ErrContract, addr)
+		}
+		st.contracts[addr] = &ContractState{Address: addr, Code: op.Code, Storage: map[string]string{}, Deployed: height}
+		st.owned[addr] = true
+		return &Receipt{TxID: tx.ID, BlockIndex: height, Contract: addr}, nil
+	}
+	cs := st.mutable(op.Address)
+	if cs == nil {
+		return nil, fmt.Errorf("%w: no contract at %s", ErrContract, op.Address)
+	}
+	env := contract.Env{Address: cs.Address, Caller: op.caller(), Height: height, Args: op.Args}
+	res, err := contract.Execute(cs.Code, storage(cs.Storage), env, op.GasLimit)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrContract, cs.Address, err)
+	}
+	r := &Receipt{TxID: tx.ID, BlockIndex: height, Contract: cs.Address, Result: res}
+	for _, e := range res.Events {
+		r.Logs = append(r.Logs, Log{Address: cs.Address, Event: e.Name, Data: e.Data, TxID: tx.ID, BlockIndex: height})
+	}
+	return r, nil
+}
+
+// Contract returns the contract deployed at address
+func (c *Chain) Contract(address string) (ContractState, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cs, ok := c.state.contracts[address]
+	if !ok {
+		return ContractState{}, false
+	}
+	return *cs, true
+}
+
+// Contracts lists the deployed contract addresses in order
+func (c *Chain) Contracts() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]string, 0, len(c.state.contracts))
+	for a := range c.state.contracts {
+		out = append(out, a)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Receipt returns the outcome of a confirmed contract or transfer transaction
+func (c *Chain) Receipt(txid string) (Receipt, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.receipts[txid]
+	if ok {
+		r.Confirmations = len(c.blocks) - r.BlockIndex
+	}
+	return r, ok
+}
+
+// StateRoot returns the root of the current contract and key-value state
+func (c *Chain) StateRoot() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state.root(c.hasher)
+}
@@ -0,0 +1,78 @@
+package blockchain
+
+import "sort"
+
+// BlockFees summarizes the fee rates paid by a block's transfers
+type BlockFees struct {
+	Index     int     `json:"index"`
+	Txns      int     `json:"transactions"`
+	Transfers int     `json:"transfers"`          // transactions with inputs, the only ones that pay fees
+	MinRate   float64 `json:"min_rate"`           // lowest fee per byte among the transfers
+	Median    float64 `json:"median_rate"`        // median fee per byte among the transfers
+	AvgSize   float64 `json:"avg_transfer_bytes"` // mean serialized size of the transfers
+}
+
+// TxFee returns what tx's inputs hold beyond its outputs, which no one can
+// claim. ok is false for transactions without inputs and for inputs the
+// chain has never confirmed.
+func (c *Chain) TxFee(tx Transaction) (fee int64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.txFee(tx)
+}
+
+// txFee is TxFee for callers holding mu
+func (c *Chain) txFee(tx Transaction) (int64, bool) {
+	if len(tx.Inputs) == 0 {
+		return 0, false
+	}
+	var fee int64
+	for _, in := range tx.Inputs {
+		sp := c.spans[OutPoint{in.TxID, in.Index}]
+		if sp == nil {
+			return 0, false
+		}
+		fee += sp.Out.Amount
+	}
+	for _, o := range tx.Outputs {
+		fee -= o.Amount
+	}
+	return fee, true
+}
+
+// FeeRate is fee per serialized byte of tx
+func FeeRate(fee int64, tx Transaction) float64 {
+	return float64(fee) / float64(TxSize(tx))
+}
+
+// FeeHistory returns the fee rates of the last window blocks, genesis
+// excluded, oldest first
+func (c *Chain) FeeHistory(window int) []BlockFees {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	from := 1
+	if window > 0 && len(c.blocks)-window > from {
+		from = len(c.blocks) - window
+	}
+	out := []BlockFees{}
+	for _, b := range c.blocks[from:] {
+		bf := BlockFees{Index: b.Index, Txns: len(b.Txns)}
+		var rates []float64
+		size := 0
+		for _, t := range b.Txns {
+			fee, ok := c.txFee(t)
+			if !ok {
+				continue
+			}
+			rates = append(rates, FeeRate(fee, t))
+			size += TxSize(t)
+		}
+		if bf.Transfers = len(rates); bf.Transfers > 0 {
+			sort.Float64s(rates)
+			bf.MinRate, bf.Median = rates[0], rates[len(rates)/2]
+			bf.AvgSize = float64(size) / float64(len(rates))
+		}
+		out = append(out, bf)
+	}
+	return out
+}
@@ -0,0 +1,32 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Hasher turns bytes into a hex digest. The chain uses it for block hashes
+// and merkle trees, so alternate hash functions only need to implement it.
+type Hasher interface {
+	// Name identifies the hash function, e.g. "sha256"
+	Name() string
+	// Hash returns the hex-encoded digest of data
+	Hash(data []byte) string
+}
+
+// SHA256 is the default Hasher
+type SHA256 struct{}
+
+// Name implements Hasher
+func (SHA256) Name() string { return "sha256" }
+
+// Hash implements Hasher
+func (SHA256) Hash(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// DefaultHasher returns the Hasher the package-level helpers and NewChain
+// use. It is a function rather than a variable so no importer can swap the
+// hash function out from under every chain.
+func DefaultHasher() Hasher { return SHA256{} }
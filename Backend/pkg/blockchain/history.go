@@ -0,0 +1,226 @@
+package blockchain
+
+import (
+	"fmt"
+	"sort"
+
+	"salmanahmed/blockchain/pkg/script"
+)
+
+// outputSpan is the range of blocks an output was unspent for
+type outputSpan struct {
+	Out     TxOutput
+	Created int // block that confirmed it
+	Spent   int // block that spent it, -1 while unspent
+}
+
+// Balance is what an address held as of a block
+type Balance struct {
+	Address string `json:"address"`
+	Height  int    `json:"height"`
+	Balance int64  `json:"balance"`
+	UTXOs   int    `json:"utxos"`
+	Label   string `json:"label,omitempty"` // response-only
+}
+
+// AddressTx is one confirmed transaction moving coins to or from an address
+type AddressTx struct {
+	BlockIndex    int    `json:"block_index"`
+	TxIndex       int    `json:"tx_index"`
+	TxID          string `json:"txid"`
+	Time          int64  `json:"time"` // block timestamp
+	Coinbase      bool   `json:"coinbase,omitempty"`
+	Received      int64  `json:"received"` // outputs paying to the address
+	Sent          int64  `json:"sent"`     // the address's outputs this transaction spent
+	Balance       int64  `json:"balance"`  // after this transaction
+	Confirmations int    `json:"confirmations"`
+}
+
+// AddressHistory returns the confirmed transactions paying to or spending
+// from address, oldest first, each with the balance it left behind
+func (c *Chain) AddressHistory(address string) []AddressTx {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := []AddressTx{}
+	var balance int64
+	for _, b := range c.blocks {
+		for i, t := range b.Txns {
+			var received, sent int64
+			for _, op := range t.Spends() {
+				if sp := c.spans[op]; sp != nil && script.Address(sp.Out.Lock) == address {
+					sent += sp.Out.Amount
+				}
+			}
+			for _, o := range t.Outputs {
+				if script.Address(o.Lock) == address {
+					received += o.Amount
+				}
+			}
+			if received == 0 && sent == 0 {
+				continue
+			}
+			balance += received - sent
+			out = append(out, AddressTx{
+				BlockIndex:    b.Index,
+				TxIndex:       i,
+				TxID:          t.ID,
+				Time:          b.Timestamp,
+				Coinbase:      t.Coinbase != 0,
+				Received:      received,
+				Sent:          sent,
+				Balance:       balance,
+				Confirmations: len(c.blocks) - b.Index,
+			})
+		}
+	}
+	return out
+}
+
+// TxActivity is who a confirmed transaction moved coins between
+type TxActivity struct {
+	Senders    []string // addresses whose outputs it spent, sorted
+	Recipients []string // addresses its outputs pay, sorted
+	Fee        int64    // inputs beyond outputs; 0 without inputs
+}
+
+// BlockActivity returns the activity of each transaction of block index,
+// in block order; ok is false past the tip
+func (c *Chain) BlockActivity(index int) (out []TxActivity, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if index < 0 || index >= len(c.blocks) {
+		return nil, false
+	}
+	addrs := func(set map[string]bool) []string {
+		list := make([]string, 0, len(set))
+		for a := range set {
+			list = append(list, a)
+		}
+		sort.Strings(list)
+		return list
+	}
+	out = make([]TxActivity, 0, len(c.blocks[index].Txns))
+	for _, t := range c.blocks[index].Txns {
+		senders, recipients := map[string]bool{}, map[string]bool{}
+		for _, op := range t.Spends() {
+			if sp := c.spans[op]; sp != nil {
+				if addr := script.Address(sp.Out.Lock); addr != "" {
+					senders[addr] = true
+				}
+			}
+		}
+		for _, o := range t.Outputs {
+			if addr := script.Address(o.Lock); addr != "" {
+				recipients[addr] = true
+			}
+		}
+		fee, _ := c.txFee(t)
+		out = append(out, TxActivity{Senders: addrs(senders), Recipients: addrs(recipients), Fee: fee})
+	}
+	return out, true
+}
+
+// BalanceChanges returns how confirming tx would move each address's
+// balance: the outputs it spends debit their owners and its outputs credit
+// theirs. Inputs the chain has not confirmed and outputs not paying an
+// address are left out.
+func (c *Chain) BalanceChanges(tx Transaction) map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := map[string]int64{}
+	for _, op := range tx.Spends() {
+		if sp := c.spans[op]; sp != nil {
+			if addr := script.Address(sp.Out.Lock); addr != "" {
+				out[addr] -= sp.Out.Amount
+			}
+		}
+	}
+	for _, o := range tx.Outputs {
+		if addr := script.Address(o.Lock); addr != "" {
+			out[addr] += o.Amount
+		}
+	}
+	return out
+}
+
+// recordOutputs updates every output's span for block b (caller holds mu)
+func (c *Chain) recordOutputs(b Block) {
+	for _, t := range b.Txns {
+		for _, op := range t.Spends() {
+			if sp := c.spans[op]; sp != nil {
+				sp.Spent = b.Index
+			}
+		}
+		for i, out := range t.Outputs {
+			c.spans[OutPoint{t.ID, i}] = &outputSpan{Out: out, Created: b.Index, Spent: -1}
+		}
+	}
+}
+
+// resolveHeight maps a negative height to the tip and rejects heights past
+// it (caller holds mu)
+func (c *Chain) resolveHeight(height int) (int, error) {
+	tip := len(c.blocks) - 1
+	if height < 0 {
+		return tip, nil
+	}
+	if height > tip {
+		return 0, fmt.Errorf("height %d is beyond the tip %d", height, tip)
+	}
+	return height, nil
+}
+
+// UTXOsAt returns the outputs paying to address (all of them when it is
+// empty) that were unspent after block height, or at the tip when height is
+// negative, ordered by outpoint
+func (c *Chain) UTXOsAt(address string, height int) ([]UTXO, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	height, err := c.resolveHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	out := []UTXO{}
+	for op, sp := range c.spans {
+		if sp.Created > height || (sp.Spent >= 0 && sp.Spent <= height) {
+			continue
+		}
+		addr := script.Address(sp.Out.Lock)
+		if address != "" && addr != address {
+			continue
+		}
+		out = append(out, UTXO{OutPoint: op, TxOutput: sp.Out, Address: addr})
+	}
+	sortUTXOs(out)
+	return out, nil
+}
+
+// BalanceAt totals the outputs paying to address after block height, or at
+// the tip when height is negative
+func (c *Chain) BalanceAt(address string, height int) (Balance, error) {
+	c.mu.Lock()
+	height, err := c.resolveHeight(height)
+	c.mu.Unlock()
+	if err != nil {
+		return Balance{}, err
+	}
+	utxos, err := c.UTXOsAt(address, height)
+	if err != nil {
+		return Balance{}, err
+	}
+	bal := Balance{Address: address, Height: height, UTXOs: len(utxos)}
+	for _, u := range utxos {
+		bal.Balance += u.Amount
+	}
+	return bal, nil
+}
+
+// sortUTXOs orders utxos by outpoint
+func sortUTXOs(utxos []UTXO) {
+	sort.Slice(utxos, func(i, j int) bool {
+		if utxos[i].TxID != utxos[j].TxID {
+			return utxos[i].TxID < utxos[j].TxID
+		}
+		return utxos[i].Index < utxos[j].Index
+	})
+}
@@ -0,0 +1,47 @@
+package blockchain
+
+import "fmt"
+
+// CheckInvariants verifies the in-memory state is self-consistent: the tip
+// hash matches the last block, indexes are continuous and linked, every
+// confirmed transaction is in the txid index, and the contract state matches
+// the tip's state root.
+func (c *Chain) CheckInvariants() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.blocks) == 0 {
+		return fmt.Errorf("chain is empty")
+	}
+	if tip := c.blocks[len(c.blocks)-1]; tip.Hash != c.tipHash {
+		return fmt.Errorf("tip hash %s does not match last block %d hash %s", c.tipHash, tip.Index, tip.Hash)
+	}
+	if tip := c.blocks[len(c.blocks)-1]; tip.StateRoot != c.state.root(c.hasher) {
+		return fmt.Errorf("contract state does not match block %d state root", tip.Index)
+	}
+	confirmed := map[string]int{} // txid: the block holding it
+	for i, b := range c.blocks {
+		if b.Index != i {
+			return fmt.Errorf("block at position %d has index %d", i, b.Index)
+		}
+		if i > 0 && b.PrevHash != c.blocks[i-1].Hash {
+			return fmt.Errorf("block %d prev_hash does not link to block %d", i, i-1)
+		}
+		for j, t := range b.Txns {
+			if first, ok := confirmed[t.ID]; ok {
+				return fmt.Errorf("txid %s confirmed in block %d and again in block %d", t.ID, first, i)
+			}
+			confirmed[t.ID] = i
+			idx, ok := c.txIndex[t.ID]
+			if !ok {
+				return fmt.Errorf("transaction in block %d missing from txid index", i)
+			}
+			if idx != i {
+				return fmt.Errorf("txid index points transaction %d of block %d at block %d", j, i, idx)
+			}
+			if c.txPos[t.ID] != j {
+				return fmt.Errorf("transaction %d of block %d indexed at position %d", j, i, c.txPos[t.ID])
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Key-value operations
+const (
+	KVSet    = "set"
+	KVDelete = "delete"
+)
+
+// Limits on key-value transactions
+const (
+	MaxKVOps      = 64
+	MaxKVKeyLen   = 256
+	MaxKVValueLen = 4096
+)
+
+// ErrKeyNotFound is returned when a key has no value at the requested height
+var ErrKeyNotFound = errors.New("key not found")
+
+// KVOp sets or deletes one key in the chain's replicated key-value store
+type KVOp struct {
+	Op    string `json:"op"`
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+}
+
+// KVEntry is a key's value as of a block
+type KVEntry struct {
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+	Height  int    `json:"height"`  // block the value was read at
+	Written int    `json:"written"` // block that last wrote it
+}
+
+// kvVersion is a key's value from block Height on; Deleted marks a tombstone
+type kvVersion struct {
+	Height  int
+	Value   string
+	Deleted bool
+}
+
+// checkKV validates a transaction's key-value operations on their own
+func checkKV(ops []KVOp) error {
+	if len(ops) > MaxKVOps {
+		return fmt.Errorf("more than %d key-value operations", MaxKVOps)
+	}
+	for i, op := range ops {
+		if op.Key == "" || len(op.Key) > MaxKVKeyLen {
+			return fmt.Errorf("kv op %d: key must be 1-%d bytes", i, MaxKVKeyLen)
+		}
+		switch op.Op {
+		case KVSet:
+			if op.Value == "" || len(op.Value) > MaxKVValueLen {
+				return fmt.Errorf("kv op %d: value must be 1-%d bytes", i, MaxKVValueLen)
+			}
+		case KVDelete:
+			if op.Value != "" {
+				return fmt.Errorf("kv op %d: delete takes no value", i)
+			}
+		default:
+			return fmt.Errorf("kv op %d: unknown op %q (want set or delete)", i, op.Op)
+		}
+	}
+	return nil
+}
+
+// applyKV runs a transaction's key-value operations against st in order
+func applyKV(st *worldState, ops []KVOp) {
+	kv := st.kv.mutable()
+	for _, op := range ops {
+		if op.Op == KVSet {
+			kv[op.Key] = op.Value
+		} else {
+			delete(kv, op.Key)
+		}
+	}
+}
+
+// kvRoot hashes every key and value in key order; "" when the store is empty
+func kvRoot(h Hasher, kv map[string]string) string {
+	if len(kv) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(kv))
+	for k := range kv {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(h.Hash([]byte(k)) + h.Hash([]byte(kv[k])))
+	}
+	return h.Hash([]byte(b.String()))
+}
+
+// recordKV appends the block's final writes to each key's history (caller holds mu)
+func (c *Chain) recordKV(b Block) {
+	for _, t := range b.Txns {
+		for _, op := range t.KV {
+			v := kvVersion{Height: b.Index, Value: op.Value, Deleted: op.Op == KVDelete}
+			hist := c.kvHistory[op.Key]
+			if n := len(hist); n > 0 && hist[n-1].Height == b.Index {
+				hist[n-1] = v // a later write in the same block wins
+				continue
+			}
+			c.kvHistory[op.Key] = append(hist, v)
+		}
+	}
+}
+
+// KV returns key's value as of block height, or the tip when height is negative
+func (c *Chain) KV(key string, height int) (KVEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	height, err := c.resolveHeight(height)
+	if err != nil {
+		return KVEntry{}, err
+	}
+	hist := c.kvHistory[key]
+	i := sort.Search(len(hist), func(i int) bool { return hist[i].Height > height })
+	if i == 0 || hist[i-1].Deleted {
+		return KVEntry{}, fmt.Errorf("%w: %s at height %d", ErrKeyNotFound, key, height)
+	}
+	v := hist[i-1]
+	return KVEntry{Key: key, Value: v.Value, Height: height, Written: v.Height}, nil
+}
@@ -0,0 +1,106 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+// MinerStats is one row of the mining leaderboard
+type MinerStats struct {
+	Miner     string `json:"miner"`
+	Blocks    int    `json:"blocks"`
+	Rewards   int64  `json:"rewards"`
+	BestHash  string `json:"best_hash"` // lowest block hash found
+	BestBlock int    `json:"best_block"`
+	LastBlock int    `json:"last_block"`
+	Label     string `json:"label,omitempty"` // response-only
+}
+
+// IsAddress reports whether s looks like a wallet address (20 hex bytes)
+func IsAddress(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil && len(s) == 40
+}
+
+// NewCoinbaseTx pays reward to the miner of block height; it must be the
+// block's first transaction
+func NewCoinbaseTx(height int, lock string, reward int64) Transaction {
+	return Transaction{Coinbase: height, Outputs: []TxOutput{{Amount: reward, Lock: lock}}}.Seal()
+}
+
+// SetBlockReward sets the most a block's coinbase may pay; 0 forbids coinbases
+func (c *Chain) SetBlockReward(reward int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reward = reward
+}
+
+// BlockReward returns the most a block's coinbase may pay
+func (c *Chain) BlockReward() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reward
+}
+
+// checkCoinbase validates the coinbase of b at position i (caller holds mu)
+func (c *Chain) checkCoinbase(b Block, i int, t Transaction) error {
+	switch {
+	case i != 0:
+		return fmt.Errorf("%w: coinbase must be the first transaction", ErrInvalidTx)
+	case t.Coinbase != b.Index:
+		return fmt.Errorf("%w: coinbase for block %d in block %d", ErrInvalidTx, t.Coinbase, b.Index)
+	case b.Miner == "":
+		return fmt.Errorf("%w: coinbase in a block without a miner", ErrInvalidTx)
+	}
+	var total int64
+	for _, o := range t.Outputs {
+		total += o.Amount
+	}
+	if total > c.reward {
+		return fmt.Errorf("%w: coinbase pays %d, the block reward is %d", ErrInvalidTx, total, c.reward)
+	}
+	return nil
+}
+
+// recordMiner credits b to its miner on the leaderboard (caller holds mu)
+func (c *Chain) recordMiner(b Block) {
+	if b.Miner == "" {
+		return
+	}
+	m := c.miners[b.Miner]
+	if m == nil {
+		m = &MinerStats{Miner: b.Miner, BestHash: b.Hash, BestBlock: b.Index}
+		c.miners[b.Miner] = m
+	}
+	m.Blocks++
+	m.LastBlock = b.Index
+	if b.Hash < m.BestHash {
+		m.BestHash, m.BestBlock = b.Hash, b.Index
+	}
+	if len(b.Txns) > 0 && b.Txns[0].Coinbase != 0 {
+		for _, o := range b.Txns[0].Outputs {
+			m.Rewards += o.Amount
+		}
+	}
+}
+
+// Leaderboard ranks miners by blocks mined, then by best hash
+func (c *Chain) Leaderboard() []MinerStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]MinerStats, 0, len(c.miners))
+	for _, m := range c.miners {
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Blocks != out[j].Blocks {
+			return out[i].Blocks > out[j].Blocks
+		}
+		if out[i].BestHash != out[j].BestHash {
+			return out[i].BestHash < out[j].BestHash
+		}
+		return out[i].Miner < out[j].Miner
+	})
+	return out
+}
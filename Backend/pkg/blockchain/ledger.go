@@ -0,0 +1,83 @@
+package blockchain
+
+import (
+	"sort"
+
+	"salmanahmed/blockchain/pkg/script"
+)
+
+// Accounts that balance the ledger where coins enter or leave circulation
+const (
+	AccountCoinbase    = "coinbase"    // block rewards minted
+	AccountIssuance    = "issuance"    // coins created by transfers without inputs
+	AccountFees        = "fees"        // what inputs hold beyond outputs, which no one can claim
+	AccountNonstandard = "nonstandard" // outputs with a lock other than P2PKH
+)
+
+// LedgerEntry is one account's movements in one block, double-entry style:
+// a credit adds to an address's balance and a debit takes from it, and a
+// block's debits always total its credits
+type LedgerEntry struct {
+	Block   int    `json:"block"`
+	Account string `json:"account"` // an address or one of the Account constants
+	Debit   int64  `json:"debit"`
+	Credit  int64  `json:"credit"`
+	Balance int64  `json:"balance"` // credits less debits through this block
+}
+
+// BlockLedger returns the journal of block index, one entry per account
+// ordered by account, with Balance left zero. Spent inputs debit the address
+// they paid, outputs credit the address they pay. ok is false past the tip.
+func (c *Chain) BlockLedger(index int) (entries []LedgerEntry, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if index < 0 || index >= len(c.blocks) {
+		return nil, false
+	}
+	b := c.blocks[index]
+	byAccount := map[string]*LedgerEntry{}
+	entry := func(account string) *LedgerEntry {
+		e := byAccount[account]
+		if e == nil {
+			e = &LedgerEntry{Block: b.Index, Account: account}
+			byAccount[account] = e
+		}
+		return e
+	}
+	account := func(lock string) string {
+		if addr := script.Address(lock); addr != "" {
+			return addr
+		}
+		return AccountNonstandard
+	}
+	for _, t := range b.Txns {
+		var in, out int64
+		for _, op := range t.Spends() {
+			if sp := c.spans[op]; sp != nil {
+				entry(account(sp.Out.Lock)).Debit += sp.Out.Amount
+				in += sp.Out.Amount
+			}
+		}
+		for _, o := range t.Outputs {
+			if o.Amount == 0 {
+				continue
+			}
+			entry(account(o.Lock)).Credit += o.Amount
+			out += o.Amount
+		}
+		switch {
+		case t.Coinbase != 0 && out > 0:
+			entry(AccountCoinbase).Debit += out
+		case len(t.Inputs) == 0 && out > 0:
+			entry(AccountIssuance).Debit += out
+		case in > out:
+			entry(AccountFees).Credit += in - out
+		}
+	}
+	entries = make([]LedgerEntry, 0, len(byAccount))
+	for _, e := range byAccount {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Account < entries[j].Account })
+	return entries, true
+}
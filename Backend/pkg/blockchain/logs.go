@@ -0,0 +1,72 @@
+package blockchain
+
+import (
+	"strconv"
+
+	"salmanahmed/blockchain/pkg/script"
+)
+
+// TransferEvent is logged for every output of a transfer
+const TransferEvent = "transfer"
+
+// Log is a named event emitted by a confirmed transaction: LOG instructions
+// in contract code, or one transfer event per output paying an address
+type Log struct {
+	Address    string `json:"address"` // emitting contract, or transfer recipient
+	Event      string `json:"event"`
+	Data       string `json:"data,omitempty"`
+	TxID       string `json:"txid"`
+	BlockIndex int    `json:"block_index"`
+	Index      int    `json:"log_index"` // position within the block
+}
+
+// LogFilter selects logs; zero fields match everything. ToBlock 0 means no upper bound.
+type LogFilter struct {
+	Address   string
+	Event     string
+	FromBlock int
+	ToBlock   int
+}
+
+// Match reports whether l passes the filter
+func (f LogFilter) Match(l Log) bool {
+	return (f.Address == "" || l.Address == f.Address) &&
+		(f.Event == "" || l.Event == f.Event) &&
+		l.BlockIndex >= f.FromBlock &&
+		(f.ToBlock == 0 || l.BlockIndex <= f.ToBlock)
+}
+
+// transferLogs returns a transfer event for each output paying a P2PKH address
+func transferLogs(tx Transaction, height int) []Log {
+	var out []Log
+	for _, o := range tx.Outputs {
+		if addr := script.Address(o.Lock); addr != "" {
+			out = append(out, Log{Address: addr, Event: TransferEvent, Data: strconv.FormatInt(o.Amount, 10), TxID: tx.ID, BlockIndex: height})
+		}
+	}
+	return out
+}
+
+// logsRoot is the merkle root of every log the receipts carry; "" without logs
+func logsRoot(h Hasher, receipts []Receipt) string {
+	var leaves []string
+	for _, r := range receipts {
+		for _, l := range r.Logs {
+			leaves = append(leaves, l.Address+"|"+l.Event+"|"+h.Hash([]byte(l.Data))+"|"+l.TxID)
+		}
+	}
+	return merkleRoot(h, leaves)
+}
+
+// Logs returns the confirmed logs matching f in chain order
+func (c *Chain) Logs(f LogFilter) []Log {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := []Log{}
+	for _, l := range c.logs {
+		if f.Match(l) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
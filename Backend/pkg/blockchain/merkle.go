@@ -0,0 +1,69 @@
+package blockchain
+
+// ComputeMerkleRoot computes the merkle root of txns with the default hasher
+func ComputeMerkleRoot(txns []Transaction) string {
+	return MerkleRoot(DefaultHasher(), txns)
+}
+
+// MerkleRoot computes the merkle root of txns with h; "" for an empty list
+func MerkleRoot(h Hasher, txns []Transaction) string {
+	return merkleRoot(h, canonicals(txns))
+}
+
+// merkleRoot computes the merkle root of leaves with h; "" for an empty list
+func merkleRoot(h Hasher, leaves []string) string {
+	levels := merkleLevels(h, leaves)
+	if len(levels) == 0 {
+		return ""
+	}
+	return levels[len(levels)-1][0]
+}
+
+// merkleLevels hashes leaves and then each level pairwise up to the root.
+// A level with an odd number of nodes pairs its last node with itself; the
+// duplicate is not stored.
+func merkleLevels(h Hasher, leaves []string) [][]string {
+	if len(leaves) == 0 {
+		return nil
+	}
+	// start with leaf hashes
+	hashes := make([]string, len(leaves))
+	for i, l := range leaves {
+		hashes[i] = h.Hash([]byte(l))
+	}
+	levels := [][]string{hashes}
+	for len(hashes) > 1 {
+		next := []string{}
+		for i := 0; i < len(hashes); i += 2 {
+			right := hashes[i]
+			if i+1 < len(hashes) {
+				right = hashes[i+1]
+			}
+			next = append(next, h.Hash([]byte(hashes[i]+right)))
+		}
+		levels = append(levels, next)
+		hashes = next
+	}
+	return levels
+}
+
+// MerkleTree is a block's merkle tree level by level
+type MerkleTree struct {
+	TxIDs  []string   `json:"txids"`  // the leaves, in block order
+	Levels [][]string `json:"levels"` // leaf hashes first, the root last; an odd node out is paired with itself
+	Root   string     `json:"root"`
+}
+
+// BuildMerkleTree returns every level of the merkle tree of txns with h
+func BuildMerkleTree(h Hasher, txns []Transaction) MerkleTree {
+	t := MerkleTree{TxIDs: make([]string, len(txns)), Levels: merkleLevels(h, canonicals(txns))}
+	for i, tx := range txns {
+		t.TxIDs[i] = tx.ID
+	}
+	if t.Levels == nil {
+		t.Levels = [][]string{}
+	} else {
+		t.Root = t.Levels[len(t.Levels)-1][0]
+	}
+	return t
+}
@@ -0,0 +1,80 @@
+package blockchain
+
+import (
+	"bytes"
+	"testing"
+)
+
+// referenceRoot is the merkle root computed the slow, obvious way:
+// recursively, duplicating the last node of an odd level
+func referenceRoot(h Hasher, leaves []string) string {
+	if len(leaves) == 0 {
+		return ""
+	}
+	level := make([]string, len(leaves))
+	for i, l := range leaves {
+		level[i] = h.Hash([]byte(l))
+	}
+	var up func([]string) string
+	up = func(level []string) string {
+		if len(level) == 1 {
+			return level[0]
+		}
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		next := make([]string, len(level)/2)
+		for i := range next {
+			next[i] = h.Hash([]byte(level[2*i] + level[2*i+1]))
+		}
+		return up(next)
+	}
+	return up(level)
+}
+
+// FuzzMerkleRoot splits the input into data transactions at every NUL byte
+// and checks ComputeMerkleRoot against the reference, the tree it reports
+// and an edit to the first transaction
+func FuzzMerkleRoot(f *testing.F) {
+	f.Add([]byte(""))
+	f.Add([]byte(GenesisTx))
+	f.Add([]byte("a\x00b"))
+	f.Add([]byte("a\x00b\x00c"))
+	f.Add([]byte("a\x00b\x00c\x00d\x00e\x00f\x00g"))
+	f.Add([]byte("\x00\x00\x00"))
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		var txns []Transaction
+		var leaves []string
+		if len(raw) > 0 {
+			for _, part := range bytes.Split(raw, []byte{0}) {
+				tx := NewDataTx(string(part))
+				txns = append(txns, tx)
+				leaves = append(leaves, tx.Canonical())
+			}
+		}
+		root := ComputeMerkleRoot(txns)
+		if want := referenceRoot(SHA256{}, leaves); root != want {
+			t.Fatalf("root of %d transactions is %s, reference says %s", len(txns), root, want)
+		}
+		if len(txns) == 0 {
+			if root != "" {
+				t.Fatalf("root of no transactions is %q, want empty", root)
+			}
+			return
+		}
+		if len(root) != 64 {
+			t.Fatalf("root %q is not a SHA-256 hex digest", root)
+		}
+		if tree := BuildMerkleTree(DefaultHasher(), txns); tree.Root != root || len(tree.Levels[0]) != len(txns) {
+			t.Fatalf("BuildMerkleTree reports root %s over %d leaves, want %s over %d", tree.Root, len(tree.Levels[0]), root, len(txns))
+		}
+		if ComputeMerkleRoot(txns) != root {
+			t.Fatal("the same transactions give two different roots")
+		}
+		edited := append([]Transaction(nil), txns...)
+		edited[0] = NewDataTx(edited[0].Data + "x")
+		if ComputeMerkleRoot(edited) == root {
+			t.Fatal("editing a transaction leaves the root unchanged")
+		}
+	})
+}
@@ -0,0 +1,23 @@
+package blockchain
+
+import "strconv"
+
+// Notarization records that a chain's block at Height, with Hash, was
+// published off the node, to another node's chain or a webhook, and what
+// the target acknowledged. Should the chain later hold a different block
+// at Height, the target can show it was rewritten.
+type Notarization struct {
+	Genesis string `json:"genesis"` // genesis block hash, naming the chain
+	Height  int    `json:"height"`
+	Hash    string `json:"hash"`
+	Time    int64  `json:"time"`            // unix seconds when published
+	Target  string `json:"target"`          // URL of the node or webhook
+	Ack     string `json:"ack,omitempty"`   // the anchoring txid on a node, or the webhook's reply
+	Error   string `json:"error,omitempty"` // why publishing failed, leaving no Ack
+}
+
+// NotarizationData is the data of the transaction that anchors a chain's
+// block at height on another node
+func NotarizationData(genesis string, height int, hash string) string {
+	return "notarize|genesis:" + genesis + "|height:" + strconv.Itoa(height) + "|hash:" + hash
+}
@@ -0,0 +1,239 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MeetsDifficulty reports whether hash has at least difficulty leading zeros
+func MeetsDifficulty(hash string, difficulty int) bool {
+	return strings.HasPrefix(hash, strings.Repeat("0", difficulty))
+}
+
+// SetDifficulty changes the leading zeros required for blocks built from
+// now on and returns the previous difficulty. The change is added to the
+// difficulty schedule at the next height, so earlier blocks are still held
+// to the difficulty in force when they were mined.
+func (c *Chain) SetDifficulty(difficulty int) (int, error) {
+	if difficulty < 0 || difficulty > 64 {
+		return 0, fmt.Errorf("difficulty %d out of range 0-64", difficulty)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pow, ok := c.consensus.(*ProofOfWork)
+	if !ok {
+		return 0, fmt.Errorf("%s chains have no difficulty", c.consensus.Name())
+	}
+	if c.retarget.Every > 0 {
+		return 0, fmt.Errorf("difficulty retargets every %d blocks and can't be set", c.retarget.Every)
+	}
+	// consensus is read without the lock while mining, so swap in a copy
+	next := *pow
+	next.Difficulty = difficulty
+	next.Schedule = scheduleStep(pow.Schedule, pow.Difficulty, DifficultyStep{Height: len(c.blocks), Difficulty: difficulty})
+	c.consensus = &next
+	return pow.Difficulty, nil
+}
+
+// DifficultySchedule returns the difficulty in force from each height on,
+// oldest first, or nil when it never changed or the chain does not use
+// proof-of-work
+func (c *Chain) DifficultySchedule() []DifficultyStep {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pow, ok := c.consensus.(*ProofOfWork)
+	if !ok || len(pow.Schedule) == 0 {
+		return nil
+	}
+	return append([]DifficultyStep(nil), pow.Schedule...)
+}
+
+// SetDifficultySchedule restores a schedule saved from DifficultySchedule,
+// before the blocks it covers are loaded. When the configured difficulty
+// differs from the schedule's last step it takes over from height from on.
+func (c *Chain) SetDifficultySchedule(steps []DifficultyStep, from int) error {
+	for i, s := range steps {
+		if s.Height < 1 || s.Difficulty < 0 || s.Difficulty > 64 || (i > 0 && s.Height <= steps[i-1].Height) {
+			return fmt.Errorf("invalid difficulty schedule: step %d is difficulty %d from height %d", i, s.Difficulty, s.Height)
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pow, ok := c.consensus.(*ProofOfWork)
+	if !ok {
+		if len(steps) > 0 {
+			return fmt.Errorf("%s chains have no difficulty", c.consensus.Name())
+		}
+		return nil
+	}
+	// steps past from belong to blocks the store no longer holds
+	for len(steps) > 0 && steps[len(steps)-1].Height > from {
+		steps = steps[:len(steps)-1]
+	}
+	next := *pow
+	next.Schedule = nil
+	if len(steps) > 0 {
+		next.Schedule = scheduleStep(steps, steps[len(steps)-1].Difficulty, DifficultyStep{Height: from, Difficulty: pow.Difficulty})
+	}
+	c.consensus = &next
+	return nil
+}
+
+// SetDifficultyOverrides sets the difficulties a block may be mined at on
+// request instead of the one in force; the zero range allows none. Only
+// those at or above the one in force produce valid blocks. Chains that
+// retarget hold every block to the retargeted difficulty, so they take no
+// overrides.
+func (c *Chain) SetDifficultyOverrides(r DifficultyRange) error {
+	if r != (DifficultyRange{}) && (r.Min < 1 || r.Max < r.Min || r.Max > 64) {
+		return fmt.Errorf("invalid difficulty overrides %d-%d", r.Min, r.Max)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pow, ok := c.consensus.(*ProofOfWork)
+	if !ok {
+		if r.Max > 0 {
+			return fmt.Errorf("%s chains have no difficulty", c.consensus.Name())
+		}
+		return nil
+	}
+	if c.retarget.Every > 0 && r.Max > 0 {
+		return fmt.Errorf("difficulty retargets every %d blocks and can't be overridden", c.retarget.Every)
+	}
+	next := *pow
+	next.Overrides = r
+	c.consensus = &next
+	return nil
+}
+
+// DifficultyOverrides returns the difficulties a block may be mined at on
+// request
+func (c *Chain) DifficultyOverrides() DifficultyRange {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if pow, ok := c.consensus.(*ProofOfWork); ok {
+		return pow.Overrides
+	}
+	return DifficultyRange{}
+}
+
+// scheduleStep returns schedule with step appended, first recording that
+// old was in force from height 1 if the schedule is empty. A step at the
+// height of the last one replaces it, and one that changes nothing is
+// left out.
+func scheduleStep(schedule []DifficultyStep, old int, step DifficultyStep) []DifficultyStep {
+	out := append([]DifficultyStep(nil), schedule...)
+	if len(out) == 0 {
+		if old == step.Difficulty {
+			return nil
+		}
+		out = append(out, DifficultyStep{Height: 1, Difficulty: old})
+	}
+	if n := len(out); out[n-1].Height >= step.Height {
+		out = out[:n-1]
+	}
+	if n := len(out); n > 0 && out[n-1].Difficulty == step.Difficulty {
+		return out
+	}
+	return append(out, step)
+}
+
+// Mine searches for a nonce such that the block hash has difficulty leading
+// zeros, using the default hasher
+func Mine(ctx context.Context, b Block, difficulty int) (Block, error) {
+	return (&ProofOfWork{Difficulty: difficulty}).ProduceBlock(ctx, b, DefaultHasher())
+}
+
+// MeasureHashrate hashes throwaway blocks for d on workers goroutines, the
+// way ProduceBlock does, and returns the hashes per second achieved
+func MeasureHashrate(h Hasher, d time.Duration, workers int) float64 {
+	var total int64
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b := Block{Version: CodecVersion, Index: 1, Txns: []Transaction{NewDataTx(GenesisTx)}, PrevHash: strings.Repeat("0", 64)}
+			b.MerkleRoot = MerkleRoot(h, b.Txns)
+			n := int64(0)
+			for time.Since(start) < d {
+				for i := 0; i < cancelCheckInterval; i++ {
+					b.Timestamp = time.Now().Unix()
+					b.Nonce++
+					HashBlock(h, b)
+				}
+				n += cancelCheckInterval
+			}
+			atomic.AddInt64(&total, n)
+		}()
+	}
+	wg.Wait()
+	return float64(total) / time.Since(start).Seconds()
+}
+
+// DifficultyFor returns the difficulty whose expected mining time at rate
+// hashes per second is closest to target; each leading hex zero multiplies
+// the expected work by 16
+func DifficultyFor(rate float64, target time.Duration) int {
+	work := rate * target.Seconds()
+	if work <= 1 {
+		return 0
+	}
+	d := int(math.Round(math.Log(work) / math.Log(16)))
+	if d > 64 {
+		d = 64
+	}
+	return d
+}
+
+// ExpectedBlockTime is the mean time to mine at difficulty with rate hashes per second
+func ExpectedBlockTime(rate float64, difficulty int) time.Duration {
+	return time.Duration(math.Pow(16, float64(difficulty)) / rate * float64(time.Second))
+}
+
+// IntervalStats summarizes the time between the last Window blocks
+type IntervalStats struct {
+	Window    int     `json:"window"`    // blocks requested; 0 means the whole chain
+	Intervals int     `json:"intervals"` // gaps actually measured
+	Min       float64 `json:"min_seconds"`
+	Avg       float64 `json:"avg_seconds"`
+	Max       float64 `json:"max_seconds"`
+}
+
+// BlockIntervals measures the gaps between block timestamps over the last
+// window blocks (the whole chain when window is 0 or more than its length)
+func (c *Chain) BlockIntervals(window int) IntervalStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := IntervalStats{Window: window}
+	from := 0
+	if window > 0 && window < len(c.blocks) {
+		from = len(c.blocks) - window
+	}
+	var sum int64
+	for i := from + 1; i < len(c.blocks); i++ {
+		gap := c.blocks[i].Timestamp - c.blocks[i-1].Timestamp
+		if gap < 0 {
+			gap = 0 // clock skew between miners
+		}
+		secs := float64(gap)
+		if st.Intervals == 0 || secs < st.Min {
+			st.Min = secs
+		}
+		if secs > st.Max {
+			st.Max = secs
+		}
+		sum += gap
+		st.Intervals++
+	}
+	if st.Intervals > 0 {
+		st.Avg = float64(sum) / float64(st.Intervals)
+	}
+	return st
+}
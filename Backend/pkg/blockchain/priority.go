@@ -0,0 +1,58 @@
+package blockchain
+
+import "sort"
+
+// Priority lanes; block templates take high before normal before low, and
+// an empty priority is normal
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+// priorityRank orders lanes for template construction; ok is false for an
+// unknown priority
+func priorityRank(p string) (rank int, ok bool) {
+	switch p {
+	case PriorityHigh:
+		return 0, true
+	case "", PriorityNormal:
+		return 1, true
+	case PriorityLow:
+		return 2, true
+	}
+	return 0, false
+}
+
+// ByPriority orders txns high lane first, keeping arrival order within a lane
+func ByPriority(txns []Transaction) {
+	sort.SliceStable(txns, func(i, j int) bool {
+		a, _ := priorityRank(txns[i].Priority)
+		b, _ := priorityRank(txns[j].Priority)
+		return a < b
+	})
+}
+
+// ByPriorityAndFee orders txns as ByPriority does and, within a lane, by
+// rate highest first, keeping arrival order among equal rates
+func ByPriorityAndFee(txns []Transaction, rate func(Transaction) float64) {
+	type ranked struct {
+		lane int
+		rate float64
+		tx   Transaction
+	}
+	rs := make([]ranked, len(txns))
+	for i, t := range txns {
+		lane, _ := priorityRank(t.Priority)
+		rs[i] = ranked{lane, rate(t), t}
+	}
+	sort.SliceStable(rs, func(i, j int) bool {
+		if rs[i].lane != rs[j].lane {
+			return rs[i].lane < rs[j].lane
+		}
+		return rs[i].rate > rs[j].rate
+	})
+	for i, r := range rs {
+		txns[i] = r.tx
+	}
+}
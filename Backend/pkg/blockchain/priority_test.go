@@ -0,0 +1,31 @@
+package blockchain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestByPriorityAndFee(t *testing.T) {
+	tx := func(data, priority string) Transaction {
+		return Transaction{Data: data, Priority: priority}.Seal()
+	}
+	txns := []Transaction{
+		tx("low", PriorityLow),
+		tx("normal cheap", ""),
+		tx("normal rich", PriorityNormal),
+		tx("high cheap", PriorityHigh),
+		tx("normal cheap too", ""),
+		tx("high rich", PriorityHigh),
+	}
+	rates := map[string]float64{"normal rich": 3, "high rich": 2, "low": 9}
+	ByPriorityAndFee(txns, func(t Transaction) float64 { return rates[t.Data] })
+
+	var got []string
+	for _, t := range txns {
+		got = append(got, t.Data)
+	}
+	want := []string{"high rich", "high cheap", "normal rich", "normal cheap", "normal cheap too", "low"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("order %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,301 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+	"testing/quick"
+
+	"salmanahmed/blockchain/pkg/script"
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+// testReward is the block reward of the chains the properties grow
+const testReward = 50
+
+// testKeys are the deterministic parties of the random transactions
+var testKeys = func() []wallet.Keypair {
+	keys := make([]wallet.Keypair, 4)
+	for i := range keys {
+		kp, err := wallet.FromPrivateKey(CalculateHash(fmt.Sprintf("property-key-%d", i)))
+		if err != nil {
+			panic(err)
+		}
+		keys[i] = kp
+	}
+	return keys
+}()
+
+// newTestChain returns a difficulty 1 chain paying testReward from genesis
+func newTestChain(genesis Block) *Chain {
+	c := NewChainFromGenesis(genesis, &ProofOfWork{Difficulty: 1}, DefaultHasher())
+	c.SetBlockReward(testReward)
+	return c
+}
+
+// builder grows chains with random blocks that are valid by construction:
+// a coinbase to a random miner, then data records drawn from a small set,
+// key-value writes and signed transfers between testKeys
+type builder struct {
+	r *rand.Rand
+}
+
+// txPosition is the position of txid in txns, or -1
+func txPosition(txns []Transaction, txid string) int {
+	for i, t := range txns {
+		if t.ID == txid {
+			return i
+		}
+	}
+	return -1
+}
+
+// grow mines n random blocks onto c
+func (g builder) grow(t testing.TB, c *Chain, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		height := c.Len()
+		miner := testKeys[g.r.Intn(len(testKeys))].Address
+		txns := []Transaction{NewCoinbaseTx(height, script.P2PKH(miner), testReward)}
+		spent := map[OutPoint]bool{}
+		for j := g.r.Intn(4); j > 0; j-- {
+			var tx Transaction
+			switch g.r.Intn(3) {
+			case 0:
+				tx = NewDataTx(fmt.Sprintf("record-%d", g.r.Intn(6)))
+			case 1:
+				op := KVOp{Op: KVSet, Key: fmt.Sprintf("key-%d", g.r.Intn(3)), Value: fmt.Sprint(g.r.Intn(100))}
+				if g.r.Intn(4) == 0 {
+					op.Op, op.Value = KVDelete, ""
+				}
+				tx = Transaction{KV: []KVOp{op}}.Seal()
+			case 2:
+				var ok bool
+				if tx, ok = g.transfer(t, c, spent); !ok {
+					continue
+				}
+			}
+			// a txid is confirmed once, so repeats of one drawn already are left out
+			if _, confirmed := c.HasTx(tx.ID); !confirmed && txPosition(txns, tx.ID) < 0 {
+				txns = append(txns, tx)
+			}
+		}
+		template := c.NextBlock(txns)
+		template.Miner = miner
+		b, err := c.Produce(context.Background(), template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.AddBlock(b); err != nil {
+			t.Fatalf("block %d of a random valid chain: %v", b.Index, err)
+		}
+	}
+}
+
+// transfer signs a payment from a random key holding unspent outputs,
+// none of them in spent, to another key, with the change back to itself
+func (g builder) transfer(t testing.TB, c *Chain, spent map[OutPoint]bool) (Transaction, bool) {
+	t.Helper()
+	from := testKeys[g.r.Intn(len(testKeys))]
+	to := testKeys[g.r.Intn(len(testKeys))]
+	var tx Transaction
+	var in int64
+	for _, u := range c.UTXOs(from.Address) {
+		if spent[u.OutPoint] || len(tx.Inputs) == 2 {
+			continue
+		}
+		tx.Inputs = append(tx.Inputs, TxInput{TxID: u.TxID, Index: u.Index})
+		in += u.Amount
+	}
+	if in == 0 {
+		return Transaction{}, false
+	}
+	amount := 1 + g.r.Int63n(in)
+	tx.Outputs = []TxOutput{{Amount: amount, Lock: script.P2PKH(to.Address)}}
+	if change := in - amount; change > 0 {
+		tx.Outputs = append(tx.Outputs, TxOutput{Amount: change, Lock: script.P2PKH(from.Address)})
+	}
+	digest := tx.SigHash()
+	for i := range tx.Inputs {
+		sig, err := wallet.Sign(from.PrivateKey, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx.Inputs[i].Unlock = script.P2PKHUnlock(sig, from.PublicKey)
+		spent[OutPoint{tx.Inputs[i].TxID, tx.Inputs[i].Index}] = true
+	}
+	return tx.Seal(), true
+}
+
+// chainState is everything a chain derives from its blocks that callers
+// can read back
+type chainState struct {
+	Hashes      []string
+	StateRoot   string
+	UTXOs       map[string][]UTXO
+	KV          map[string]string
+	Leaderboard []MinerStats
+}
+
+func stateOf(c *Chain) chainState {
+	st := chainState{
+		StateRoot:   c.StateRoot(),
+		UTXOs:       map[string][]UTXO{},
+		KV:          map[string]string{},
+		Leaderboard: c.Leaderboard(),
+	}
+	for _, b := range c.Blocks() {
+		st.Hashes = append(st.Hashes, b.Hash)
+	}
+	for _, kp := range testKeys {
+		utxos := c.UTXOs(kp.Address)
+		sort.Slice(utxos, func(i, j int) bool { return utxos[i].OutPoint.String() < utxos[j].OutPoint.String() })
+		st.UTXOs[kp.Address] = utxos
+	}
+	for i := 0; i < 3; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if e, err := c.KV(key, -1); err == nil {
+			st.KV[key] = e.Value
+		}
+	}
+	return st
+}
+
+// checkSound fails t unless c's indexes are consistent
+func checkSound(t *testing.T, c *Chain, what string) {
+	t.Helper()
+	if err := c.CheckInvariants(); err != nil {
+		t.Fatalf("%s: %v", what, err)
+	}
+	if res := c.Verify(); !res.Valid {
+		t.Fatalf("%s: block %d fails verification: %s", what, *res.Block, res.Reason)
+	}
+}
+
+// chainScenario is a random chain of up to 12 blocks
+type chainScenario struct {
+	Seed   int64
+	Blocks int
+}
+
+func (chainScenario) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(chainScenario{Seed: r.Int63(), Blocks: r.Intn(13)})
+}
+
+// any valid chain re-validates: replaying its blocks onto a fresh chain
+// from the same genesis block, as they are or through the binary codec,
+// accepts every one and derives the same state
+func TestValidChainRevalidates(t *testing.T) {
+	prop := func(s chainScenario) bool {
+		c := newTestChain(NewChain(1).Tip())
+		builder{rand.New(rand.NewSource(s.Seed))}.grow(t, c, s.Blocks)
+		blocks := c.Blocks()
+		checkSound(t, c, "the chain as grown")
+
+		replayed := newTestChain(blocks[0])
+		for _, b := range blocks[1:] {
+			if err := replayed.AddBlock(b); err != nil {
+				t.Fatalf("replaying block %d: %v", b.Index, err)
+			}
+		}
+		checkSound(t, replayed, "the replayed chain")
+		if got, want := stateOf(replayed), stateOf(c); !reflect.DeepEqual(got, want) {
+			t.Fatalf("the replayed chain derives different state:\n got %+v\nwant %+v", got, want)
+		}
+
+		decoded := newTestChain(blocks[0])
+		for _, b := range blocks[1:] {
+			got, err := DecodeBlock(EncodeBlock(b))
+			if err != nil {
+				t.Fatalf("block %d: %v", b.Index, err)
+			}
+			if err := decoded.AddBlock(got); err != nil {
+				t.Fatalf("replaying block %d from binary: %v", b.Index, err)
+			}
+		}
+		if got, want := stateOf(decoded), stateOf(c); !reflect.DeepEqual(got, want) {
+			t.Fatalf("the chain replayed from binary derives different state:\n got %+v\nwant %+v", got, want)
+		}
+		return true
+	}
+	if err := quick.Check(prop, &quick.Config{MaxCount: 50}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// reorgScenario forks a random chain after Prefix blocks: ours carries Ours
+// blocks past the fork, theirs is Extra blocks longer
+type reorgScenario struct {
+	Seed                int64
+	Prefix, Ours, Extra int
+}
+
+func (reorgScenario) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(reorgScenario{Seed: r.Int63(), Prefix: r.Intn(5), Ours: 1 + r.Intn(4), Extra: 1 + r.Intn(3)})
+}
+
+// replay returns a chain from blocks[0] that has appended the rest
+func replay(t *testing.T, blocks []Block) *Chain {
+	t.Helper()
+	c := newTestChain(blocks[0])
+	for _, b := range blocks[1:] {
+		if err := c.AddBlock(b); err != nil {
+			t.Fatalf("replaying block %d: %v", b.Index, err)
+		}
+	}
+	return c
+}
+
+// reorging onto a longer fork and back restores identical state: the state
+// after each reorg is exactly that of a chain that only ever held the
+// winning blocks
+func TestReorgAndBackRestoresState(t *testing.T) {
+	prop := func(s reorgScenario) bool {
+		g := builder{rand.New(rand.NewSource(s.Seed))}
+		c := newTestChain(NewChain(1).Tip())
+		g.grow(t, c, s.Prefix)
+		prefix := c.Blocks()
+		g.grow(t, c, s.Ours)
+		ours := c.Blocks()
+
+		other := replay(t, prefix)
+		// their first block differs from ours whatever the random blocks hold
+		mineNext(t, other, NewDataTx("their fork"))
+		g.grow(t, other, s.Ours+s.Extra-1)
+		theirs := other.Blocks()
+
+		dropped, err := c.Replace(theirs)
+		if err != nil {
+			t.Fatalf("reorging onto a fork %d blocks longer: %v", s.Extra, err)
+		}
+		if len(dropped) != s.Ours || dropped[0].Hash != ours[s.Prefix+1].Hash {
+			t.Fatalf("the reorg dropped %d blocks, want our %d past block %d", len(dropped), s.Ours, s.Prefix)
+		}
+		checkSound(t, c, "after the reorg")
+		if got, want := stateOf(c), stateOf(other); !reflect.DeepEqual(got, want) {
+			t.Fatalf("state after the reorg differs from the fork's own:\n got %+v\nwant %+v", got, want)
+		}
+
+		// our side of the fork outgrows theirs, and the chain goes back to it
+		mine := replay(t, ours)
+		g.grow(t, mine, s.Extra+1)
+		dropped, err = c.Replace(mine.Blocks())
+		if err != nil {
+			t.Fatalf("reorging back: %v", err)
+		}
+		if len(dropped) != len(theirs)-len(prefix) {
+			t.Fatalf("reorging back dropped %d blocks, want their %d", len(dropped), len(theirs)-len(prefix))
+		}
+		checkSound(t, c, "after reorging back")
+		if got, want := stateOf(c), stateOf(mine); !reflect.DeepEqual(got, want) {
+			t.Fatalf("state after reorging back differs from our side's own:\n got %+v\nwant %+v", got, want)
+		}
+		return true
+	}
+	if err := quick.Check(prop, &quick.Config{MaxCount: 50}); err != nil {
+		t.Fatal(err)
+	}
+}
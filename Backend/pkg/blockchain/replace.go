@@ -0,0 +1,180 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrForeignGenesis is returned for a chain that starts from another genesis block
+	ErrForeignGenesis = errors.New("chain starts from a different genesis block")
+	// ErrNotLonger is returned for a chain no longer than the local one
+	ErrNotLonger = errors.New("chain is not longer than the local chain")
+)
+
+// Replace swaps the chain for blocks under the longest-chain rule: blocks
+// must start from the same genesis block, be longer, and every block must
+// validate under the current consensus rules, retargeting, validators and
+// block reward.
+// A chain holding nothing but its genesis block takes any valid chain, so a
+// fresh node can join a network started elsewhere. It returns the blocks
+// the swap dropped from the old chain, oldest first, so their transactions
+// can be requeued.
+func (c *Chain) Replace(blocks []Block) ([]Block, error) {
+	return c.replace(blocks, false)
+}
+
+// Restore swaps the chain for blocks whatever their length or genesis
+// block, as when loading a backup. blocks are validated as fully as by
+// Replace, and nothing changes unless all of them pass. It returns the
+// blocks dropped from the old chain, oldest first, as Replace does.
+func (c *Chain) Restore(blocks []Block) ([]Block, error) {
+	return c.replace(blocks, true)
+}
+
+// ReplacePlan is what swapping the chain for a candidate would do, worked
+// out without changing anything
+type ReplacePlan struct {
+	Fork    int     // the last block both chains share; -1 when the genesis block differs
+	Dropped []Block // local blocks rolled back, oldest first
+	Added   []Block // candidate blocks applied, oldest first
+	Tip     Block   // the tip afterwards
+}
+
+// PlanReplace validates blocks as Replace would and returns what Replace
+// would roll back and apply, leaving the chain as it is
+func (c *Chain) PlanReplace(blocks []Block) (ReplacePlan, error) {
+	return c.plan(blocks, false)
+}
+
+// PlanRestore validates blocks as Restore would and returns what Restore
+// would roll back and apply, leaving the chain as it is
+func (c *Chain) PlanRestore(blocks []Block) (ReplacePlan, error) {
+	return c.plan(blocks, true)
+}
+
+// PlanAppend validates blocks as if appended to the chain one by one, as
+// AddBlock would, and returns the plan of doing so, leaving the chain as it is
+func (c *Chain) PlanAppend(blocks []Block) (ReplacePlan, error) {
+	c.mu.Lock()
+	candidate := make([]Block, len(c.blocks), len(c.blocks)+len(blocks))
+	for i := range c.blocks {
+		candidate[i] = c.mined(i)
+	}
+	c.mu.Unlock()
+	return c.plan(append(candidate, blocks...), true)
+}
+
+// plan validates blocks and works out the swap without making it
+func (c *Chain) plan(blocks []Block, force bool) (ReplacePlan, error) {
+	scratch, err := c.candidate(blocks, force)
+	if err != nil {
+		return ReplacePlan{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fork := c.forkWith(scratch)
+	p := ReplacePlan{Fork: fork - 1, Dropped: []Block{}, Tip: scratch.blocks[len(scratch.blocks)-1]}
+	if c.blocks[0].Hash != scratch.blocks[0].Hash {
+		p.Fork, fork = -1, 0
+	}
+	for i := fork; i < len(c.blocks); i++ {
+		p.Dropped = append(p.Dropped, c.mined(i))
+	}
+	p.Added = append([]Block{}, scratch.blocks[fork:]...)
+	return p, nil
+}
+
+// candidate validates blocks on a scratch chain; force skips the
+// longest-chain rule
+func (c *Chain) candidate(blocks []Block, force bool) (*Chain, error) {
+	c.mu.Lock()
+	genesis, height := c.blocks[0], len(c.blocks)
+	scratch := &Chain{consensus: c.consensus, hasher: c.hasher, reward: c.reward, retarget: c.retarget, validators: c.validators,
+		signed: c.signed, signedFrom: c.signedFrom}
+	c.mu.Unlock()
+	if len(blocks) == 0 {
+		if force {
+			return nil, errors.New("no blocks")
+		}
+		return nil, ErrNotLonger
+	}
+	if blocks[0].Hash != genesis.Hash {
+		g := blocks[0]
+		if (height > 1 && !force) || g.Index != 0 || g.PrevHash != "" || g.Hash != HashBlock(scratch.hasher, g) {
+			return nil, ErrForeignGenesis
+		}
+		genesis = g
+	}
+	if len(blocks) <= height && !force {
+		return nil, fmt.Errorf("%w: %d blocks against %d", ErrNotLonger, len(blocks), height)
+	}
+
+	// validate outside the lock; the chain keeps serving meanwhile. The
+	// cheap integrity checks weed out corrupt chains before any replay.
+	if bad, err := VerifyBlocks(blocks, scratch.consensus, scratch.hasher); err != nil {
+		return nil, fmt.Errorf("block %d: %w", bad, err)
+	}
+	scratch.reset(genesis)
+	for _, b := range blocks[1:] {
+		if err := scratch.AddBlock(b); err != nil {
+			return nil, fmt.Errorf("block %d: %w", b.Index, err)
+		}
+	}
+	return scratch, nil
+}
+
+// mined is the block at i as mined, not as tampered with (caller holds mu)
+func (c *Chain) mined(i int) Block {
+	if b, ok := c.tampered[i]; ok {
+		return b
+	}
+	return c.blocks[i]
+}
+
+// forkWith is the index of the first block the chain and scratch don't
+// share, at least 1 (caller holds mu)
+func (c *Chain) forkWith(scratch *Chain) int {
+	fork := 1
+	for c.blocks[0].Hash == scratch.blocks[0].Hash && fork < len(c.blocks) && fork < len(scratch.blocks) && c.mined(fork).Hash == scratch.blocks[fork].Hash {
+		fork++
+	}
+	return fork
+}
+
+// replace validates blocks and swaps them in; force skips the longest-chain rule
+func (c *Chain) replace(blocks []Block, force bool) ([]Block, error) {
+	scratch, err := c.candidate(blocks, force)
+	if err != nil {
+		return nil, err
+	}
+	genesis := scratch.blocks[0]
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !force && (len(c.blocks) >= len(scratch.blocks) || (c.blocks[0].Hash != genesis.Hash && len(c.blocks) > 1)) {
+		// blocks were appended while validating
+		return nil, fmt.Errorf("%w: %d blocks against %d", ErrNotLonger, len(scratch.blocks), len(c.blocks))
+	}
+	for i, b := range c.tampered {
+		c.blocks[i] = b // drop and requeue blocks as mined, not as edited
+	}
+	fork := c.forkWith(scratch)
+	dropped := append([]Block(nil), c.blocks[fork:]...)
+
+	c.blocks = scratch.blocks
+	c.tipHash = scratch.tipHash
+	c.hashIndex = scratch.hashIndex
+	c.txIndex = scratch.txIndex
+	c.txPos = scratch.txPos
+	c.utxos = scratch.utxos
+	c.spans = scratch.spans
+	c.state = scratch.state
+	c.receipts = scratch.receipts
+	c.logs = scratch.logs
+	c.kvHistory = scratch.kvHistory
+	c.sizes = scratch.sizes
+	c.miners = scratch.miners
+	c.tampered = nil
+	return dropped, nil
+}
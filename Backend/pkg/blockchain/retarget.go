@@ -0,0 +1,130 @@
+package blockchain
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Retarget makes proof-of-work difficulty follow block times: every Every
+// blocks the difficulty moves toward one block per Target, by at most one
+// leading zero (16x the work) at a time
+type Retarget struct {
+	Every  int           // blocks between adjustments; 0 keeps the difficulty fixed
+	Target time.Duration // desired time between blocks
+}
+
+// DifficultyStep is a height at which the recorded difficulty changed
+type DifficultyStep struct {
+	Height     int `json:"height"`
+	Difficulty int `json:"difficulty"`
+}
+
+// SetRetarget turns on difficulty retargeting, or off when r.Every is 0.
+// Every block must then record the difficulty the rule yields at its
+// height, so nodes sharing a chain must agree on r.
+func (c *Chain) SetRetarget(r Retarget) error {
+	if r.Every < 0 || (r.Every > 0 && r.Target <= 0) {
+		return fmt.Errorf("invalid retarget: every %d blocks toward %s", r.Every, r.Target)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pow, ok := c.consensus.(*ProofOfWork)
+	if !ok && r.Every > 0 {
+		return fmt.Errorf("%s chains have no difficulty", c.consensus.Name())
+	}
+	if ok && r.Every > 0 && pow.Overrides.Max > 0 {
+		return fmt.Errorf("difficulty overrides are set; a retargeting chain can't take them")
+	}
+	c.retarget = r
+	return nil
+}
+
+// Retarget returns the chain's retargeting rule
+func (c *Chain) Retarget() Retarget {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.retarget
+}
+
+// retargeted returns the difficulty the block after the tip must record
+// under the retargeting rule (caller holds mu). Blocks between adjustments
+// keep the tip's difficulty; at an adjustment the mean interval over the
+// last Every blocks, genesis excluded, is compared with the target.
+func (c *Chain) retargeted(base int) int {
+	tip := c.blocks[len(c.blocks)-1]
+	d := tip.Difficulty
+	if d == 0 {
+		d = base
+	}
+	height := len(c.blocks)
+	if height%c.retarget.Every != 0 {
+		return d
+	}
+	from := height - 1 - c.retarget.Every
+	if from < 1 {
+		from = 1
+	}
+	intervals := height - 1 - from
+	if intervals < 1 {
+		return d
+	}
+	avg := float64(tip.Timestamp-c.blocks[from].Timestamp) / float64(intervals)
+	step := 1 // blocks landing within the same second are always too fast
+	if avg > 0 {
+		step = int(math.Round(math.Log(c.retarget.Target.Seconds()/avg) / math.Log(16)))
+		if step > 1 {
+			step = 1
+		}
+		if step < -1 {
+			step = -1
+		}
+	}
+	d += step
+	// 0 means "not recorded" in a block, so retargeting never goes below 1
+	if d < 1 {
+		d = 1
+	}
+	if d > 64 {
+		d = 64
+	}
+	return d
+}
+
+// NextRetarget returns the height of the next difficulty adjustment, 0
+// when retargeting is off
+func (c *Chain) NextRetarget() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.retarget.Every == 0 {
+		return 0
+	}
+	height := len(c.blocks)
+	return (height + c.retarget.Every - 1) / c.retarget.Every * c.retarget.Every
+}
+
+// RecordedSteps returns the heights at which the difficulty blocks record
+// changed, oldest first, skipping genesis and blocks that record none
+func RecordedSteps(blocks []Block) []DifficultyStep {
+	steps := []DifficultyStep{}
+	prev := 0
+	for _, b := range blocks {
+		if b.Index > 0 && b.Difficulty != 0 && b.Difficulty != prev {
+			steps = append(steps, DifficultyStep{Height: b.Index, Difficulty: b.Difficulty})
+			prev = b.Difficulty
+		}
+	}
+	return steps
+}
+
+// DifficultySteps returns the last limit heights at which the recorded
+// difficulty changed, oldest first; all of them when limit is 0
+func (c *Chain) DifficultySteps(limit int) []DifficultyStep {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	steps := RecordedSteps(c.blocks)
+	if limit > 0 && len(steps) > limit {
+		steps = steps[len(steps)-limit:]
+	}
+	return steps
+}
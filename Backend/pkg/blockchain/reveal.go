@@ -0,0 +1,117 @@
+package blockchain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNoCommitment is returned when a reveal names a transaction that
+	// isn't an open commitment
+	ErrNoCommitment = errors.New("no open commitment")
+	// ErrRevealMismatch is returned when a reveal doesn't hash to its commitment
+	ErrRevealMismatch = errors.New("reveal does not match commitment")
+)
+
+// Reveal discloses the salt behind an earlier commit transaction; the
+// revealing transaction's Data is the committed payload
+type Reveal struct {
+	TxID string `json:"txid"` // the commit transaction
+	Salt string `json:"salt"` // hex
+}
+
+// RevealStatus describes a commit transaction and its reveal, if any
+type RevealStatus struct {
+	TxID       string `json:"txid"`
+	Commitment string `json:"commitment"`
+	Revealed   bool   `json:"revealed"`
+	RevealTxID string `json:"reveal_txid,omitempty"`
+	Data       string `json:"data,omitempty"`
+}
+
+// Commitment is the hex SHA-256 of salt || data that a commit transaction carries
+func Commitment(salt []byte, data string) string {
+	sum := sha256.Sum256(append(append([]byte{}, salt...), data...))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewCommitTx returns a sealed transaction committing to data under a fresh
+// random salt; keep the hex salt to reveal it later
+func NewCommitTx(data string) (Transaction, string, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return Transaction{}, "", err
+	}
+	return Transaction{Commit: Commitment(salt, data)}.Seal(), hex.EncodeToString(salt), nil
+}
+
+// NewRevealTx returns a sealed transaction revealing data behind commit transaction txid
+func NewRevealTx(txid, saltHex, data string) Transaction {
+	return Transaction{Data: data, Reveal: &Reveal{TxID: txid, Salt: saltHex}}.Seal()
+}
+
+// checkCommit validates a commitment's shape
+func checkCommit(c string) error {
+	if raw, err := hex.DecodeString(c); err != nil || len(raw) != sha256.Size {
+		return errors.New("commit must be a hex SHA-256")
+	}
+	return nil
+}
+
+// applyCommitReveal opens a commitment, or closes one whose reveal hashes to it
+func applyCommitReveal(st *worldState, tx Transaction) error {
+	if tx.Commit != "" {
+		st.commits.mutable()[tx.ID] = tx.Commit
+		return nil
+	}
+	r := tx.Reveal
+	want, ok := st.commits.m[r.TxID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoCommitment, r.TxID)
+	}
+	salt, err := hex.DecodeString(r.Salt)
+	if err != nil || Commitment(salt, tx.Data) != want {
+		return fmt.Errorf("%w %s", ErrRevealMismatch, r.TxID)
+	}
+	delete(st.commits.mutable(), r.TxID)
+	st.reveals.mutable()[r.TxID] = tx.ID
+	return nil
+}
+
+// RevealStatus returns the state of commit transaction txid
+func (c *Chain) RevealStatus(txid string) (RevealStatus, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if commit, ok := c.state.commits.m[txid]; ok {
+		return RevealStatus{TxID: txid, Commitment: commit}, true
+	}
+	revealTx, ok := c.state.reveals.m[txid]
+	if !ok {
+		return RevealStatus{}, false
+	}
+	st := RevealStatus{TxID: txid, Revealed: true, RevealTxID: revealTx}
+	if t, ok := c.findTx(txid); ok {
+		st.Commitment = t.Commit
+	}
+	if t, ok := c.findTx(revealTx); ok {
+		st.Data = t.Data
+	}
+	return st, true
+}
+
+// findTx returns a confirmed transaction (caller holds mu)
+func (c *Chain) findTx(txid string) (Transaction, bool) {
+	i, ok := c.txIndex[txid]
+	if !ok {
+		return Transaction{}, false
+	}
+	for _, t := range c.blocks[i].Txns {
+		if t.ID == txid {
+			return t, true
+		}
+	}
+	return Transaction{}, false
+}
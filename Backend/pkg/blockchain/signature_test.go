@@ -0,0 +1,173 @@
+package blockchain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"salmanahmed/blockchain/pkg/script"
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+// newKey returns a fresh keypair
+func newKey(t testing.TB) wallet.Keypair {
+	t.Helper()
+	kp, err := wallet.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kp
+}
+
+func TestSign(t *testing.T) {
+	alice, bob := newKey(t), newKey(t)
+	tx, err := Transaction{Data: "signed record"}.Sign(alice.PrivateKey, 1700000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.CheckStructure(); err != nil {
+		t.Fatalf("a signed record is malformed: %v", err)
+	}
+	if tx.Sender != alice.PublicKey || tx.Canonical() == tx.Data {
+		t.Fatalf("signed as %+v, want alice's key in the hashed fields", tx)
+	}
+
+	edited := tx
+	edited.Data = "edited record"
+	if err := edited.Seal().CheckStructure(); err == nil {
+		t.Fatal("a record edited after signing passes")
+	}
+	claimed := tx
+	claimed.Sender = bob.PublicKey
+	if err := claimed.Seal().CheckStructure(); err == nil {
+		t.Fatal("a record claimed by someone other than its signer passes")
+	}
+	unsigned := tx
+	unsigned.Signature = ""
+	if err := unsigned.Seal().CheckStructure(); err == nil {
+		t.Fatal("a sender without a signature passes")
+	}
+}
+
+func TestPayment(t *testing.T) {
+	alice, bob := newKey(t), newKey(t)
+	pay := func(amount int64) Transaction {
+		t.Helper()
+		tx, err := Transaction{
+			Inputs:    []TxInput{{TxID: CalculateHash("parent"), Index: 0}},
+			Outputs:   []TxOutput{{Amount: 5, Lock: script.P2PKH(bob.Address)}},
+			Recipient: bob.Address,
+			Amount:    amount,
+		}.Sign(alice.PrivateKey, 1700000000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	tx := pay(5)
+	if err := tx.CheckStructure(); err != nil {
+		t.Fatalf("a payment is malformed: %v", err)
+	}
+	if tx.Inputs[0].Unlock != script.P2PKHUnlock(tx.Signature, alice.PublicKey) {
+		t.Fatalf("Sign unlocked the input with %q, want alice's signature", tx.Inputs[0].Unlock)
+	}
+	if err := pay(6).CheckStructure(); err == nil {
+		t.Fatal("a payment whose outputs don't pay its amount passes")
+	}
+	if err := pay(0).CheckStructure(); err == nil {
+		t.Fatal("a payment of nothing passes")
+	}
+}
+
+// expectUnsigned fails t unless tx is refused as unsigned on submit and in
+// a block
+func expectUnsigned(t *testing.T, c *Chain, what string, tx Transaction) {
+	t.Helper()
+	if err := c.ValidateTx(tx); !errors.Is(err, ErrUnsigned) {
+		t.Fatalf("ValidateTx of %s returned %v, want ErrUnsigned", what, err)
+	}
+	b, err := c.Produce(context.Background(), c.NextBlock([]Transaction{tx}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddBlock(b); !errors.Is(err, ErrUnsigned) {
+		t.Fatalf("AddBlock of a block holding %s returned %v, want ErrUnsigned", what, err)
+	}
+}
+
+func TestRequireSignatures(t *testing.T) {
+	alice := newKey(t)
+	preimage := []byte("open sesame")
+	hash := sha256.Sum256(preimage)
+	c := NewChain(1)
+	locked := Transaction{Outputs: []TxOutput{{Amount: 10, Lock: script.HashLock(hex.EncodeToString(hash[:]))}}}.Seal()
+	mineNext(t, c, locked)
+
+	c.RequireSignatures(0)
+	if from, ok := c.SignaturesFrom(); !ok || from != 0 {
+		t.Fatalf("SignaturesFrom = %d, %v after RequireSignatures(0)", from, ok)
+	}
+	expectUnsigned(t, c, "unsigned data", NewDataTx("unsigned"))
+	issue, err := Transaction{Outputs: []TxOutput{{Amount: 10, Lock: script.P2PKH(alice.Address)}}}.Sign(alice.PrivateKey, 1700000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectUnsigned(t, c, "a signed issuance", issue)
+	claim := Transaction{
+		Inputs:  []TxInput{{TxID: locked.ID, Index: 0, Unlock: script.HashLockUnlock(preimage)}},
+		Outputs: []TxOutput{{Amount: 10, Lock: script.P2PKH(alice.Address)}},
+	}
+	expectUnsigned(t, c, "a hash lock claim", claim.Seal())
+	signedClaim, err := claim.Sign(alice.PrivateKey, 1700000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectUnsigned(t, c, "a signed hash lock claim", signedClaim)
+
+	record, err := Transaction{Data: "signed"}.Sign(alice.PrivateKey, 1700000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ValidateTx(record); err != nil {
+		t.Fatalf("ValidateTx of a signed record: %v", err)
+	}
+	mineNext(t, c, record)
+
+	c.RequireSignatures(-1)
+	if err := c.ValidateTx(claim.Seal()); err != nil {
+		t.Fatalf("ValidateTx of a hash lock claim with signatures optional: %v", err)
+	}
+	if err := c.CheckInvariants(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// a chain with unsigned history keeps it when the rule starts above its tip,
+// and reloads under the rule; blocks from the activation height on must be
+// signed
+func TestSignaturesFrom(t *testing.T) {
+	alice := newKey(t)
+	c := NewChain(1)
+	mineNext(t, c, NewDataTx("unsigned history"))
+	c.RequireSignatures(3)
+	mineNext(t, c, NewDataTx("unsigned, still before the rule"))
+	expectUnsigned(t, c, "unsigned data at the activation height", NewDataTx("too late"))
+	record, err := Transaction{Data: "signed"}.Sign(alice.PrivateKey, 1700000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mineNext(t, c, record)
+
+	reloaded := NewChain(1)
+	reloaded.RequireSignatures(3)
+	if _, err := reloaded.Restore(c.Blocks()); err != nil {
+		t.Fatalf("restoring a chain signed from its activation height: %v", err)
+	}
+	strict := NewChain(1)
+	strict.RequireSignatures(1)
+	if _, err := strict.Restore(c.Blocks()); !errors.Is(err, ErrUnsigned) {
+		t.Fatalf("restoring unsigned history under the rule returned %v, want ErrUnsigned", err)
+	}
+}
@@ -0,0 +1,94 @@
+package blockchain
+
+import "encoding/json"
+
+// blockSize is a block's serialized size and how much of it is transactions
+type blockSize struct {
+	Bytes   int
+	Txns    int
+	TxBytes int
+}
+
+// SizeStats summarizes the serialized size of the last Window blocks
+type SizeStats struct {
+	Window        int     `json:"window"` // blocks requested; 0 means the whole chain
+	Blocks        int     `json:"blocks"`
+	Transactions  int     `json:"transactions"`
+	TotalBytes    int     `json:"total_bytes"`
+	AvgBlockBytes float64 `json:"avg_block_bytes"`
+	MaxBlockBytes int     `json:"max_block_bytes"`
+	MaxBlock      int     `json:"max_block"` // index of the largest block
+	AvgTxBytes    float64 `json:"avg_tx_bytes"`
+}
+
+// TxSize is the number of bytes t takes as JSON, the form it is stored,
+// gossiped and exported in
+func TxSize(t Transaction) int {
+	t.Size = 0
+	raw, _ := json.Marshal(t)
+	return len(raw)
+}
+
+// BlockSize is the number of bytes b takes as JSON, transactions included
+func BlockSize(b Block) int {
+	return measure(b).Bytes
+}
+
+// WithSizes returns a copy of b with Size filled in on it and each of its
+// transactions
+func WithSizes(b Block) Block {
+	txns := make([]Transaction, len(b.Txns))
+	for i, t := range b.Txns {
+		t.Size = TxSize(t)
+		txns[i] = t
+	}
+	b.Size = BlockSize(b)
+	b.Txns = txns
+	return b
+}
+
+// measure sizes b without its response-only fields
+func measure(b Block) blockSize {
+	b.Confirmations, b.Size = 0, 0
+	txns := make([]Transaction, len(b.Txns))
+	st := blockSize{Txns: len(b.Txns)}
+	for i, t := range b.Txns {
+		t.Size = 0
+		txns[i] = t
+		st.TxBytes += TxSize(t)
+	}
+	b.Txns = txns
+	raw, _ := json.Marshal(b)
+	st.Bytes = len(raw)
+	return st
+}
+
+// BlockSizes summarizes block and transaction sizes over the last window
+// blocks (the whole chain when window is 0 or more than its length)
+func (c *Chain) BlockSizes(window int) SizeStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := SizeStats{Window: window}
+	from := 0
+	if window > 0 && window < len(c.sizes) {
+		from = len(c.sizes) - window
+	}
+	txBytes := 0
+	for i := from; i < len(c.sizes); i++ {
+		sz := c.sizes[i]
+		st.Blocks++
+		st.Transactions += sz.Txns
+		st.TotalBytes += sz.Bytes
+		txBytes += sz.TxBytes
+		if sz.Bytes > st.MaxBlockBytes {
+			st.MaxBlockBytes, st.MaxBlock = sz.Bytes, i
+		}
+	}
+	if st.Blocks > 0 {
+		st.AvgBlockBytes = float64(st.TotalBytes) / float64(st.Blocks)
+	}
+	if st.Transactions > 0 {
+		st.AvgTxBytes = float64(txBytes) / float64(st.Transactions)
+	}
+	return st
+}
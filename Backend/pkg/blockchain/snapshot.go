@@ -0,0 +1,13 @@
+package blockchain
+
+// Snapshot records a chain's tip, balances and mempool at one moment, so
+// later states can be compared against it
+type Snapshot struct {
+	ID       int              `json:"id"`
+	Name     string           `json:"name,omitempty"`
+	Time     int64            `json:"time"` // unix seconds when taken
+	Height   int              `json:"height"`
+	TipHash  string           `json:"tip_hash"`
+	Balances map[string]int64 `json:"balances"` // confirmed, per address
+	Pending  []Transaction    `json:"pending"`
+}
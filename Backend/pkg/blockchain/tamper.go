@@ -0,0 +1,73 @@
+package blockchain
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Tamper edits transaction tx of the block at index in place, for teaching
+// how a chain exposes tampering: the stored block no longer matches its
+// hash, which Verify reports. With rehash the transaction ID, merkle root
+// and block hash are recomputed as a forger would, so the break moves to
+// the proof of work and the next block's link instead. Nothing else is
+// updated, the tip can't be edited so new blocks still link to it, and
+// Untamper puts the blocks back as mined. It returns the block before and
+// after the edit.
+func (c *Chain) Tamper(index, tx int, edit func(*Transaction), rehash bool) (before, after Block, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if index < 0 || index >= len(c.blocks) {
+		return before, after, fmt.Errorf("no block at height %d", index)
+	}
+	if index == len(c.blocks)-1 {
+		// the next block is built and checked against the tip as stored
+		return before, after, fmt.Errorf("block %d is the tip; tamper with an earlier block", index)
+	}
+	before = c.blocks[index]
+	if tx < 0 || tx >= len(before.Txns) {
+		return before, after, fmt.Errorf("block %d has no transaction %d", index, tx)
+	}
+	after = before
+	after.Txns = append([]Transaction(nil), before.Txns...)
+	after.Txns[tx].Outputs = append([]TxOutput(nil), before.Txns[tx].Outputs...)
+	edit(&after.Txns[tx])
+	if rehash {
+		after.Txns[tx] = after.Txns[tx].Seal()
+		after.MerkleRoot = MerkleRoot(c.hasher, after.Txns)
+		after.Hash = HashBlock(c.hasher, after)
+	}
+	if c.tampered == nil {
+		c.tampered = map[int]Block{}
+	}
+	if _, ok := c.tampered[index]; !ok {
+		c.tampered[index] = before
+	}
+	c.blocks[index] = after
+	return before, after, nil
+}
+
+// Tampered returns the heights of the blocks Tamper has edited, lowest first
+func (c *Chain) Tampered() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]int, 0, len(c.tampered))
+	for i := range c.tampered {
+		out = append(out, i)
+	}
+	sort.Ints(out)
+	return out
+}
+
+// Untamper restores every block Tamper edited and returns their heights
+func (c *Chain) Untamper() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]int, 0, len(c.tampered))
+	for i, b := range c.tampered {
+		c.blocks[i] = b
+		out = append(out, i)
+	}
+	c.tampered = nil
+	sort.Ints(out)
+	return out
+}
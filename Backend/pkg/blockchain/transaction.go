@@ -0,0 +1,376 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"salmanahmed/blockchain/pkg/script"
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+// Transaction is either a plain data record (the original format, e.g. a
+// roll number) or a transfer that spends earlier outputs and creates new
+// ones. A data-only transaction hashes exactly like its data string did
+// before transfers existed, so older blocks keep their hashes.
+type Transaction struct {
+	ID      string     `json:"id"`
+	Data    string     `json:"data,omitempty"`
+	Inputs  []TxInput  `json:"inputs,omitempty"`
+	Outputs []TxOutput `json:"outputs,omitempty"`
+
+	Contract *ContractOp `json:"contract,omitempty"`
+	KV       []KVOp      `json:"kv,omitempty"`
+
+	Confidential *Confidential `json:"confidential,omitempty"`
+	Commit       string        `json:"commit,omitempty"` // hex SHA-256 of salt || payload, revealed later
+	Reveal       *Reveal       `json:"reveal,omitempty"`
+	Blob         string        `json:"blob,omitempty"`              // hex SHA-256 of an off-chain payload
+	Coinbase     int           `json:"coinbase,omitempty"`          // height of the block whose reward this pays
+	Priority     string        `json:"priority,omitempty"`          // template lane: high, normal or low
+	ExpiresAt    int           `json:"expires_at_height,omitempty"` // last block height that may include it; 0 never expires
+
+	// Signature is Sender's ECDSA signature over SigHash. Recipient and
+	// Amount, which only a signed transaction may state, name the payment
+	// one of its outputs makes.
+	Sender    string `json:"sender,omitempty"`    // hex public key
+	Recipient string `json:"recipient,omitempty"` // address
+	Amount    int64  `json:"amount,omitempty"`
+	Timestamp int64  `json:"timestamp,omitempty"` // Unix seconds, when the sender signed
+	Signature string `json:"signature,omitempty"`
+
+	// Size is filled in for API responses; it is never hashed or signed
+	Size int `json:"size,omitempty"`
+}
+
+// TxInput spends output Index of transaction TxID; Unlock is the unlocking script
+type TxInput struct {
+	TxID   string `json:"txid"`
+	Index  int    `json:"index"`
+	Unlock string `json:"unlock"`
+}
+
+// TxOutput assigns Amount to whoever can satisfy the locking script Lock
+type TxOutput struct {
+	Amount int64  `json:"amount"`
+	Lock   string `json:"lock"`
+}
+
+// OutPoint names a single transaction output
+type OutPoint struct {
+	TxID  string `json:"txid"`
+	Index int    `json:"index"`
+}
+
+func (o OutPoint) String() string {
+	return o.TxID + ":" + strconv.Itoa(o.Index)
+}
+
+// UTXO is an unspent output and where it lives
+type UTXO struct {
+	OutPoint
+	TxOutput
+	Address string `json:"address,omitempty"` // set for P2PKH outputs
+}
+
+// MaxAmount bounds output values so totals can't overflow
+const MaxAmount int64 = 1e15
+
+var (
+	// ErrInvalidTx is returned for malformed transactions
+	ErrInvalidTx = errors.New("invalid transaction")
+	// ErrSpend is returned when an input cannot spend the output it references
+	ErrSpend = errors.New("invalid spend")
+	// ErrMissingInput is an ErrSpend for an input whose transaction hasn't
+	// been confirmed (yet); it may still become valid
+	ErrMissingInput = fmt.Errorf("%w: unknown parent transaction", ErrSpend)
+	// ErrTxExpired is an ErrInvalidTx for a transaction past its expires_at_height
+	ErrTxExpired = fmt.Errorf("%w: expired", ErrInvalidTx)
+	// ErrUnsigned is returned for a transaction without the signatures a
+	// chain requiring them wants (see RequireSignatures)
+	ErrUnsigned = errors.New("transaction is not signed")
+	// ErrConfirmed is returned for a transaction whose txid a block already
+	// holds; a txid is confirmed at most once
+	ErrConfirmed = errors.New("transaction already confirmed")
+)
+
+// NewDataTx returns a data-only transaction with its ID set
+func NewDataTx(data string) Transaction {
+	return Transaction{Data: data}.Seal()
+}
+
+// Seal returns t with ID set to the hash of its contents
+func (t Transaction) Seal() Transaction {
+	t.ID = TxID(t.Canonical())
+	return t
+}
+
+// Sign has the holder of privHex sign t as its sender at timestamp: it
+// sets Sender and Signature, unlocks each input left without an unlock as
+// a P2PKH spend by the same signature, and seals the result
+func (t Transaction) Sign(privHex string, timestamp int64) (Transaction, error) {
+	kp, err := wallet.FromPrivateKey(privHex)
+	if err != nil {
+		return t, err
+	}
+	t.Sender, t.Timestamp = kp.PublicKey, timestamp
+	sig, err := wallet.Sign(kp.PrivateKey, t.SigHash())
+	if err != nil {
+		return t, err
+	}
+	t.Signature = sig
+	inputs := make([]TxInput, len(t.Inputs))
+	for i, in := range t.Inputs {
+		if in.Unlock == "" {
+			in.Unlock = script.P2PKHUnlock(sig, kp.PublicKey)
+		}
+		inputs[i] = in
+	}
+	t.Inputs = inputs
+	return t.Seal(), nil
+}
+
+// Expired reports whether t may no longer be included in a block at height
+func (t Transaction) Expired(height int) bool {
+	return t.ExpiresAt != 0 && height > t.ExpiresAt
+}
+
+// String describes the transaction for display: its data, or a transfer summary
+func (t Transaction) String() string {
+	if t.Commit != "" {
+		return "commitment " + t.Commit[:12]
+	}
+	if t.Blob != "" {
+		return strings.TrimSpace(t.Data + " blob " + t.Blob[:12])
+	}
+	if t.Data != "" {
+		return t.Data
+	}
+	if c := t.Contract; c != nil {
+		if c.Code != "" {
+			return "deploy contract " + ContractAddress(t.ID)
+		}
+		return fmt.Sprintf("call contract %s %v", c.Address, c.Args)
+	}
+	if c := t.Confidential; c != nil {
+		return "confidential payload for " + wallet.Address(c.Recipient)
+	}
+	if len(t.KV) > 0 {
+		ops := make([]string, len(t.KV))
+		for i, op := range t.KV {
+			ops[i] = op.Op + " " + op.Key
+		}
+		return "kv " + strings.Join(ops, ", ")
+	}
+	var total int64
+	for _, o := range t.Outputs {
+		total += o.Amount
+	}
+	if t.Coinbase != 0 {
+		return fmt.Sprintf("coinbase for block %d: %d", t.Coinbase, total)
+	}
+	if t.Recipient != "" {
+		return fmt.Sprintf("%s pays %s %d", wallet.Address(t.Sender), t.Recipient, t.Amount)
+	}
+	return fmt.Sprintf("transfer %.12s: %d in, %d out, %d total", t.ID, len(t.Inputs), len(t.Outputs), total)
+}
+
+// Canonical is the string a transaction is identified and hashed by: the
+// data itself for data-only transactions, JSON without the ID otherwise
+func (t Transaction) Canonical() string {
+	if len(t.Inputs) == 0 && len(t.Outputs) == 0 && t.Contract == nil && len(t.KV) == 0 && t.Confidential == nil &&
+		t.Commit == "" && t.Reveal == nil && t.Blob == "" && t.Priority == "" && t.ExpiresAt == 0 && t.Signature == "" &&
+		t.Sender == "" && t.Recipient == "" && t.Amount == 0 && t.Timestamp == 0 {
+		return t.Data
+	}
+	raw, _ := json.Marshal(struct {
+		Data         string        `json:"data,omitempty"`
+		Inputs       []TxInput     `json:"inputs,omitempty"`
+		Outputs      []TxOutput    `json:"outputs,omitempty"`
+		Contract     *ContractOp   `json:"contract,omitempty"`
+		KV           []KVOp        `json:"kv,omitempty"`
+		Confidential *Confidential `json:"confidential,omitempty"`
+		Commit       string        `json:"commit,omitempty"`
+		Reveal       *Reveal       `json:"reveal,omitempty"`
+		Blob         string        `json:"blob,omitempty"`
+		Coinbase     int           `json:"coinbase,omitempty"`
+		Priority     string        `json:"priority,omitempty"`
+		ExpiresAt    int           `json:"expires_at_height,omitempty"`
+		Sender       string        `json:"sender,omitempty"`
+		Recipient    string        `json:"recipient,omitempty"`
+		Amount       int64         `json:"amount,omitempty"`
+		Timestamp    int64         `json:"timestamp,omitempty"`
+		Signature    string        `json:"signature,omitempty"`
+	}{t.Data, t.Inputs, t.Outputs, t.Contract, t.KV, t.Confidential, t.Commit, t.Reveal, t.Blob, t.Coinbase, t.Priority, t.ExpiresAt,
+		t.Sender, t.Recipient, t.Amount, t.Timestamp, t.Signature})
+	return string(raw)
+}
+
+// SigHash is the digest signatures commit to: the transaction with every
+// unlocking script, the contract signature and the sender's signature blanked
+func (t Transaction) SigHash() []byte {
+	t.Signature = ""
+	inputs := make([]TxInput, len(t.Inputs))
+	for i, in := range t.Inputs {
+		in.Unlock = ""
+		inputs[i] = in
+	}
+	t.Inputs = inputs
+	if t.Contract != nil {
+		op := *t.Contract
+		op.Sig = ""
+		t.Contract = &op
+	}
+	sum := sha256.Sum256([]byte(t.Canonical()))
+	return sum[:]
+}
+
+// CheckStructure validates a transaction on its own, without chain state
+func (t Transaction) CheckStructure() error {
+	if _, ok := priorityRank(t.Priority); !ok {
+		return fmt.Errorf("%w: unknown priority %q (want high, normal or low)", ErrInvalidTx, t.Priority)
+	}
+	if t.ExpiresAt < 0 {
+		return fmt.Errorf("%w: expires_at_height must not be negative", ErrInvalidTx)
+	}
+	if t.Coinbase != 0 {
+		if t.Coinbase < 0 || t.ExpiresAt != 0 || t.Data != "" || len(t.Inputs) > 0 || t.Contract != nil || len(t.KV) > 0 ||
+			t.Confidential != nil || t.Commit != "" || t.Reveal != nil || t.Blob != "" || t.Sender != "" || t.Recipient != "" {
+			return fmt.Errorf("%w: a coinbase only pays the block reward", ErrInvalidTx)
+		}
+	}
+	if err := t.checkSignature(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTx, err)
+	}
+	if t.Blob != "" {
+		if len(t.Inputs) > 0 || len(t.Outputs) > 0 || t.Contract != nil || len(t.KV) > 0 ||
+			t.Confidential != nil || t.Commit != "" || t.Reveal != nil {
+			return fmt.Errorf("%w: blob transactions carry only the hash and optional data", ErrInvalidTx)
+		}
+		if !isSHA256Hex(t.Blob) {
+			return fmt.Errorf("%w: blob must be a lowercase hex SHA-256", ErrInvalidTx)
+		}
+	} else if t.Commit != "" || t.Reveal != nil {
+		if len(t.Inputs) > 0 || len(t.Outputs) > 0 || t.Contract != nil || len(t.KV) > 0 || t.Confidential != nil {
+			return fmt.Errorf("%w: commit and reveal transactions cannot carry other operations", ErrInvalidTx)
+		}
+		switch {
+		case t.Commit != "" && (t.Reveal != nil || t.Data != ""):
+			return fmt.Errorf("%w: a commit carries only its commitment", ErrInvalidTx)
+		case t.Commit != "":
+			if err := checkCommit(t.Commit); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidTx, err)
+			}
+		case t.Reveal.TxID == "" || t.Reveal.Salt == "":
+			return fmt.Errorf("%w: reveal needs the commit txid and salt", ErrInvalidTx)
+		}
+	} else if c := t.Confidential; c != nil {
+		if t.Data != "" || len(t.Inputs) > 0 || len(t.Outputs) > 0 || t.Contract != nil || len(t.KV) > 0 {
+			return fmt.Errorf("%w: confidential transactions carry only their sealed payload", ErrInvalidTx)
+		}
+		if err := c.check(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTx, err)
+		}
+	} else if len(t.KV) > 0 {
+		if len(t.Inputs) > 0 || len(t.Outputs) > 0 || t.Contract != nil {
+			return fmt.Errorf("%w: key-value transactions cannot also transfer value or call contracts", ErrInvalidTx)
+		}
+		if err := checkKV(t.KV); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTx, err)
+		}
+	} else if t.Contract != nil {
+		if len(t.Inputs) > 0 || len(t.Outputs) > 0 {
+			return fmt.Errorf("%w: contract transactions cannot also transfer value", ErrInvalidTx)
+		}
+		if err := t.Contract.check(t.SigHash()); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTx, err)
+		}
+	} else if t.Data == "" && len(t.Outputs) == 0 {
+		return fmt.Errorf("%w: transaction needs data or outputs", ErrInvalidTx)
+	}
+	if len(t.Inputs) > 0 && len(t.Outputs) == 0 {
+		return fmt.Errorf("%w: transfer has no outputs", ErrInvalidTx)
+	}
+	if want := TxID(t.Canonical()); t.ID != want {
+		return fmt.Errorf("%w: id %q does not match contents", ErrInvalidTx, t.ID)
+	}
+	seen := map[OutPoint]bool{}
+	for _, in := range t.Inputs {
+		op := OutPoint{in.TxID, in.Index}
+		if seen[op] {
+			return fmt.Errorf("%w: %s spent twice", ErrInvalidTx, op)
+		}
+		seen[op] = true
+		if _, err := script.Parse(in.Unlock); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTx, err)
+		}
+	}
+	var total int64
+	for i, out := range t.Outputs {
+		if out.Amount <= 0 {
+			return fmt.Errorf("%w: output %d amount must be positive", ErrInvalidTx, i)
+		}
+		if total += out.Amount; out.Amount > MaxAmount || total > MaxAmount {
+			return fmt.Errorf("%w: outputs exceed %d", ErrInvalidTx, MaxAmount)
+		}
+		if _, err := script.Parse(out.Lock); err != nil {
+			return fmt.Errorf("%w: output %d: %v", ErrInvalidTx, i, err)
+		}
+	}
+	return nil
+}
+
+// checkSignature validates the sender's signature and the payment it states
+func (t Transaction) checkSignature() error {
+	switch {
+	case t.Signature == "" && (t.Sender != "" || t.Recipient != "" || t.Amount != 0 || t.Timestamp != 0):
+		return errors.New("sender, recipient, amount and timestamp need the sender's signature")
+	case t.Signature == "":
+		return nil
+	case !wallet.Verify(t.Sender, t.SigHash(), t.Signature):
+		return errors.New("signature does not verify against the sender's key")
+	case t.Timestamp < 0:
+		return errors.New("timestamp must not be negative")
+	case t.Recipient == "" && t.Amount == 0:
+		return nil
+	case !IsAddress(t.Recipient):
+		return fmt.Errorf("invalid recipient address %q", t.Recipient)
+	case t.Amount <= 0 || t.Amount > MaxAmount:
+		return fmt.Errorf("amount must be 1-%d", MaxAmount)
+	}
+	lock := script.P2PKH(t.Recipient)
+	for _, o := range t.Outputs {
+		if o.Lock == lock && o.Amount == t.Amount {
+			return nil
+		}
+	}
+	return fmt.Errorf("no output pays the recipient %s the amount %d", t.Recipient, t.Amount)
+}
+
+// Spends returns the outputs t consumes
+func (t Transaction) Spends() []OutPoint {
+	out := make([]OutPoint, len(t.Inputs))
+	for i, in := range t.Inputs {
+		out[i] = OutPoint{in.TxID, in.Index}
+	}
+	return out
+}
+
+// isSHA256Hex reports whether s is a lowercase hex SHA-256 digest
+func isSHA256Hex(s string) bool {
+	raw, err := hex.DecodeString(s)
+	return err == nil && len(raw) == sha256.Size && s == strings.ToLower(s)
+}
+
+// canonicals returns the canonical form of each transaction
+func canonicals(txns []Transaction) []string {
+	out := make([]string, len(txns))
+	for i, t := range txns {
+		out[i] = t.Canonical()
+	}
+	return out
+}
@@ -0,0 +1,160 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+
+	"salmanahmed/blockchain/pkg/script"
+)
+
+// ErrTxRejected wraps every error returned by a registered TxValidator
+var ErrTxRejected = errors.New("transaction rejected")
+
+// TxValidator enforces a custom rule on a transaction; a non-nil error rejects it
+type TxValidator func(tx Transaction) error
+
+// namedValidator keeps the registration name for error messages
+type namedValidator struct {
+	name string
+	fn   TxValidator
+}
+
+// RegisterTxValidator adds a rule every new transaction must pass, both when
+// it is submitted and when a block carrying it is appended. Validators run
+// in registration order.
+func (c *Chain) RegisterTxValidator(name string, fn TxValidator) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.validators = append(c.validators, namedValidator{name: name, fn: fn})
+}
+
+// TxValidators returns the names of the registered validators, in the
+// order they run
+func (c *Chain) TxValidators() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]string, 0, len(c.validators))
+	for _, v := range c.validators {
+		out = append(out, v.name)
+	}
+	return out
+}
+
+// RequireSignatures makes every transaction but coinbases in blocks from
+// height from on carry an ECDSA signature, checked on submission and again
+// in each block appended: its sender's, a signed contract call's, or one on
+// each input, whose spent output's lock must pass an OP_CHECKSIG or
+// OP_CHECKMULTISIG, so hash locks and OP_1 outputs can't be spent. Outputs
+// without inputs are refused, so coins only enter through coinbases.
+// Blocks below from are held to the rules they were mined under, so a chain
+// with unsigned history turns the rule on from a height above its tip; every
+// node validating the chain needs the same from. A negative from turns the
+// rule off.
+func (c *Chain) RequireSignatures(from int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.signed, c.signedFrom = from >= 0, from
+	if !c.signed {
+		c.signedFrom = 0
+	}
+}
+
+// SignaturesFrom returns the height RequireSignatures applies from, and
+// false when it is off
+func (c *Chain) SignaturesFrom() (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.signedFrom, c.signed
+}
+
+// signedAt reports whether RequireSignatures covers a block at height
+// (caller holds mu)
+func (c *Chain) signedAt(height int) bool {
+	return c.signed && height >= c.signedFrom
+}
+
+// checkSigned enforces RequireSignatures on tx for inclusion at height,
+// short of its inputs, which checkSpends verifies one by one (caller holds mu)
+func (c *Chain) checkSigned(tx Transaction, height int) error {
+	switch {
+	case !c.signedAt(height) || tx.Coinbase != 0 || len(tx.Inputs) > 0:
+		return nil
+	case len(tx.Outputs) > 0:
+		return fmt.Errorf("%w: %s creates coins without spending any; only coinbases may", ErrUnsigned, tx.ID)
+	case tx.Signature == "" && (tx.Contract == nil || tx.Contract.Sig == ""):
+		return fmt.Errorf("%w: %s carries no signature", ErrUnsigned, tx.ID)
+	}
+	return nil
+}
+
+// ValidateTx checks tx could go into the next block: it must be well formed,
+// pass the registered validators, only spend unspent outputs it can unlock
+// and, for contract transactions, run successfully against the current state
+func (c *Chain) ValidateTx(tx Transaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if tx.Coinbase != 0 {
+		return fmt.Errorf("%w: coinbase transactions are created by miners", ErrInvalidTx)
+	}
+	if err := c.validateTx(tx, len(c.blocks)); err != nil {
+		return err
+	}
+	_, err := applyTx(c.state.clone(), tx, len(c.blocks))
+	return err
+}
+
+// validateTx checks tx for inclusion at height (caller holds mu)
+func (c *Chain) validateTx(tx Transaction, height int) error {
+	if err := tx.CheckStructure(); err != nil {
+		return err
+	}
+	if idx, ok := c.txIndex[tx.ID]; ok {
+		return fmt.Errorf("%w: %s in block %d", ErrConfirmed, tx.ID, idx)
+	}
+	if tx.Expired(height) {
+		return fmt.Errorf("%w at height %d, block %d is next", ErrTxExpired, tx.ExpiresAt, height)
+	}
+	for _, v := range c.validators {
+		if err := v.fn(tx); err != nil {
+			return fmt.Errorf("%w by %s: %v", ErrTxRejected, v.name, err)
+		}
+	}
+	if err := c.checkSigned(tx, height); err != nil {
+		return err
+	}
+	return c.checkSpends(tx, height)
+}
+
+// checkSpends runs every input's unlocking script against the output it
+// spends and checks the transfer creates no value (caller holds mu).
+// Transactions without inputs may create outputs freely.
+func (c *Chain) checkSpends(tx Transaction, height int) error {
+	if len(tx.Inputs) == 0 {
+		return nil
+	}
+	ctx := script.Context{SigHash: tx.SigHash(), Height: height, Signed: c.signedAt(height)}
+	var in, out int64
+	for i, input := range tx.Inputs {
+		op := OutPoint{input.TxID, input.Index}
+		prev, ok := c.utxos[op]
+		if !ok {
+			if _, confirmed := c.txIndex[op.TxID]; !confirmed {
+				return fmt.Errorf("%w: input %d: %s", ErrMissingInput, i, op)
+			}
+			return fmt.Errorf("%w: input %d: %s is not an unspent output", ErrSpend, i, op)
+		}
+		if err := script.Verify(input.Unlock, prev.Lock, ctx); errors.Is(err, script.ErrNoSignature) {
+			return fmt.Errorf("%w: input %d: %v", ErrUnsigned, i, err)
+		} else if err != nil {
+			return fmt.Errorf("%w: input %d: %v", ErrSpend, i, err)
+		}
+		in += prev.Amount
+	}
+	for _, o := range tx.Outputs {
+		out += o.Amount
+	}
+	if out > in {
+		return fmt.Errorf("%w: outputs total %d but inputs only %d", ErrSpend, out, in)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRegisteredValidatorsRunInOrder(t *testing.T) {
+	c := NewChain(1)
+	var ran []string
+	c.RegisterTxValidator("first", func(tx Transaction) error {
+		ran = append(ran, "first")
+		return nil
+	})
+	c.RegisterTxValidator("no-bob", func(tx Transaction) error {
+		ran = append(ran, "no-bob")
+		if strings.Contains(tx.Data, "bob") {
+			return errors.New("bob is banned")
+		}
+		return nil
+	})
+	c.RegisterTxValidator("last", func(tx Transaction) error {
+		ran = append(ran, "last")
+		return nil
+	})
+
+	if err := c.ValidateTx(NewDataTx("alice pays carol 1")); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(ran, ","); got != "first,no-bob,last" {
+		t.Fatalf("validators ran as %s", got)
+	}
+	ran = nil
+	err := c.ValidateTx(NewDataTx("alice pays bob 1"))
+	if !errors.Is(err, ErrTxRejected) || !strings.Contains(err.Error(), "no-bob") {
+		t.Fatalf("ValidateTx = %v, want ErrTxRejected naming no-bob", err)
+	}
+	if got := strings.Join(ran, ","); got != "first,no-bob" {
+		t.Fatalf("validators after a rejection still ran: %s", got)
+	}
+}
+
+func TestBlocksMustPassTheValidators(t *testing.T) {
+	c := NewChain(1)
+	b, err := c.Produce(context.Background(), c.NextBlock([]Transaction{NewDataTx("alice pays bob 1")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.RegisterTxValidator("no-bob", func(tx Transaction) error {
+		if strings.Contains(tx.Data, "bob") {
+			return errors.New("bob is banned")
+		}
+		return nil
+	})
+	if err := c.AddBlock(b); !errors.Is(err, ErrTxRejected) {
+		t.Fatalf("AddBlock = %v, want ErrTxRejected", err)
+	}
+	if c.Len() != 1 {
+		t.Fatalf("the chain holds %d blocks after a rejected one", c.Len())
+	}
+}
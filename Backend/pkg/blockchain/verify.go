@@ -0,0 +1,124 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Integrity is the outcome of re-checking a chain's blocks
+type Integrity struct {
+	Valid    bool      `json:"valid"`
+	Height   int       `json:"height"`
+	Block    *int      `json:"block,omitempty"`    // the first block failing, when one does
+	Reason   string    `json:"reason,omitempty"`   // why it fails
+	Failures []Failure `json:"failures,omitempty"` // every check failing, block by block
+}
+
+// Failure is one check a block fails
+type Failure struct {
+	Block  int    `json:"block"`
+	Check  string `json:"check"` // index, version, txid, merkle_root, hash, prev_hash or work
+	Reason string `json:"reason"`
+}
+
+// VerifyBlocks re-checks blocks as stored, genesis first: every index
+// follows on, every hash is recomputed, links to the block before and meets
+// its proof of work, and every merkle root and transaction ID matches the
+// transactions. It returns the index of the first block failing and why,
+// or -1 and nil when all pass. Unlike AddBlock it doesn't replay spends or
+// contract state, so it is cheap enough to run over the whole chain.
+func VerifyBlocks(blocks []Block, consensus Consensus, h Hasher) (int, error) {
+	for i := range blocks {
+		if f := checkBlock(blocks, i, consensus, h, false); len(f) > 0 {
+			return i, errors.New(f[0].Reason)
+		}
+	}
+	return -1, nil
+}
+
+// AuditBlocks runs VerifyBlocks' checks on every block without stopping at
+// the first failure, so a broken chain shows each link that no longer holds
+func AuditBlocks(blocks []Block, consensus Consensus, h Hasher) []Failure {
+	var out []Failure
+	for i := range blocks {
+		out = append(out, checkBlock(blocks, i, consensus, h, true)...)
+	}
+	return out
+}
+
+// checkBlock checks blocks[i] and its link to the block before, returning
+// the first failure, or every one when all is set
+func checkBlock(blocks []Block, i int, consensus Consensus, h Hasher, all bool) []Failure {
+	var out []Failure
+	fail := func(check, format string, args ...interface{}) bool {
+		out = append(out, Failure{Block: i, Check: check, Reason: fmt.Sprintf(format, args...)})
+		return !all
+	}
+	b := blocks[i]
+	if b.Index != i && fail("index", "block at position %d has index %d", i, b.Index) {
+		return out
+	}
+	if (b.Version < 0 || b.Version > CodecVersion) && fail("version", "unknown block version %d", b.Version) {
+		return out
+	}
+	if i > 0 && b.Version < blocks[i-1].Version && fail("version", "version %d is below its parent's %d", b.Version, blocks[i-1].Version) {
+		return out
+	}
+	for j, t := range b.Txns {
+		if want := TxID(t.Canonical()); t.ID != want && fail("txid", "transaction %d has id %s, its contents hash to %s", j, t.ID, want) {
+			return out
+		}
+	}
+	if root := MerkleRoot(h, b.Txns); b.MerkleRoot != root && fail("merkle_root", "merkle root %s does not match its transactions (%s)", b.MerkleRoot, root) {
+		return out
+	}
+	if hash := HashBlock(h, b); b.Hash != hash && fail("hash", "hash %s does not match its contents (%s)", b.Hash, hash) {
+		return out
+	}
+	if i == 0 {
+		if b.PrevHash != "" {
+			fail("prev_hash", "genesis block has prev_hash %s", b.PrevHash)
+		}
+		return out
+	}
+	if b.PrevHash != blocks[i-1].Hash && fail("prev_hash", "prev_hash %s does not link to block %d (%s)", b.PrevHash, i-1, blocks[i-1].Hash) {
+		return out
+	}
+	if err := checkWork(consensus, h, b); err != nil {
+		fail("work", "%v", err)
+	}
+	return out
+}
+
+// checkWork checks b's proof of work against the difficulty it records,
+// so blocks mined before a difficulty change still pass; blocks that
+// predate recorded difficulties are held to the one in force at their height
+func checkWork(consensus Consensus, h Hasher, b Block) error {
+	pow, ok := consensus.(*ProofOfWork)
+	if !ok {
+		return consensus.ValidateHeader(b, h)
+	}
+	difficulty := b.Difficulty
+	if difficulty == 0 {
+		difficulty = pow.Required(b.Index)
+		if b.Version > 0 && difficulty > 0 {
+			return fmt.Errorf("block records no difficulty, %d was in force at its height", difficulty)
+		}
+	}
+	if !MeetsDifficulty(b.Hash, difficulty) {
+		return fmt.Errorf("hash %s does not meet difficulty %d", b.Hash, difficulty)
+	}
+	return nil
+}
+
+// Verify re-checks every block of the chain, reporting every failure
+func (c *Chain) Verify() Integrity {
+	blocks := c.Blocks()
+	res := Integrity{Height: len(blocks) - 1, Failures: AuditBlocks(blocks, c.Consensus(), c.Hasher())}
+	res.Valid = len(res.Failures) == 0
+	if !res.Valid {
+		bad := res.Failures[0].Block
+		res.Block, res.Reason = &bad, res.Failures[0].Reason
+	}
+	return res
+}
@@ -0,0 +1,31 @@
+package blockchain
+
+import "testing"
+
+func TestVerifyBlocksFindsTheFirstBadBlock(t *testing.T) {
+	c := NewChain(1)
+	for i := 0; i < 3; i++ {
+		mineNext(t, c, NewDataTx("verify "+string(rune('a'+i))), NewDataTx(string(rune('a'+i))))
+	}
+	if res := c.Verify(); !res.Valid || res.Height != 3 || res.Block != nil {
+		t.Fatalf("Verify on a mined chain = %+v", res)
+	}
+
+	for name, edit := range map[string]func(b *Block){
+		"index":         func(b *Block) { b.Index++ },
+		"transaction":   func(b *Block) { b.Txns[0].Data = "edited" },
+		"merkle root":   func(b *Block) { b.Txns = b.Txns[:1] },
+		"hash":          func(b *Block) { b.Nonce++ },
+		"prev hash":     func(b *Block) { b.PrevHash = CalculateHash("elsewhere") },
+		"proof of work": func(b *Block) { b.Difficulty = 64 },
+	} {
+		blocks := c.Blocks()
+		b := blocks[2]
+		b.Txns = append([]Transaction(nil), b.Txns...)
+		edit(&b)
+		blocks[2] = b
+		if bad, err := VerifyBlocks(blocks, c.Consensus(), c.Hasher()); bad != 2 || err == nil {
+			t.Errorf("%s edited: VerifyBlocks = %d, %v; want block 2 to fail", name, bad, err)
+		}
+	}
+}
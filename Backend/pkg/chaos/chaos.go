@@ -0,0 +1,133 @@
+// Package chaos injects faults — failed storage writes, delayed or dropped
+// peer traffic, a skewed clock — so resilience code can be exercised
+// against a running node. Every method is safe on a nil *Injector, which
+// injects nothing.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"salmanahmed/blockchain/pkg/clock"
+)
+
+// ErrInjected is returned by injected failures
+var ErrInjected = errors.New("chaos: injected failure")
+
+// Faults is the active fault configuration; the zero value injects nothing
+type Faults struct {
+	StorageFailRate float64 `json:"storage_fail_rate"` // probability a storage write fails
+	PeerDelayMS     int64   `json:"peer_delay_ms"`     // added to every peer message
+	GossipDropRate  float64 `json:"gossip_drop_rate"`  // probability a gossip message is dropped
+	ClockSkewMS     int64   `json:"clock_skew_ms"`     // added to the node's clock, may be negative
+}
+
+// Validate checks rates are probabilities and delays are sane
+func (f Faults) Validate() error {
+	for name, r := range map[string]float64{"storage_fail_rate": f.StorageFailRate, "gossip_drop_rate": f.GossipDropRate} {
+		if r < 0 || r > 1 {
+			return fmt.Errorf("chaos: %s must be between 0 and 1", name)
+		}
+	}
+	if f.PeerDelayMS < 0 || f.PeerDelayMS > time.Minute.Milliseconds() {
+		return errors.New("chaos: peer_delay_ms must be between 0 and 60000")
+	}
+	if day := (24 * time.Hour).Milliseconds(); f.ClockSkewMS < -day || f.ClockSkewMS > day {
+		return errors.New("chaos: clock_skew_ms must be within a day")
+	}
+	return nil
+}
+
+// Injector holds the current faults; they can be changed while the node runs
+type Injector struct {
+	mu     sync.Mutex
+	faults Faults
+	rng    *rand.Rand
+}
+
+// New returns an injector with every fault off
+func New() *Injector {
+	return &Injector{rng: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// Set replaces the active faults
+func (i *Injector) Set(f Faults) error {
+	if i == nil {
+		return errors.New("chaos: fault injection is disabled")
+	}
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.faults = f
+	return nil
+}
+
+// Faults returns the active faults
+func (i *Injector) Faults() Faults {
+	if i == nil {
+		return Faults{}
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.faults
+}
+
+// roll returns true with probability p
+func (i *Injector) roll(p float64) bool {
+	if i == nil || p <= 0 {
+		return false
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rng.Float64() < p
+}
+
+// StorageWrite is called before persisting; a non-nil error means the write
+// must be treated as failed
+func (i *Injector) StorageWrite() error {
+	if i.roll(i.Faults().StorageFailRate) {
+		return fmt.Errorf("%w: storage write", ErrInjected)
+	}
+	return nil
+}
+
+// PeerDelay holds up a peer message by the configured delay, or until ctx ends
+func (i *Injector) PeerDelay(ctx context.Context) error {
+	d := time.Duration(i.Faults().PeerDelayMS) * time.Millisecond
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// DropGossip reports whether a gossip message should be silently dropped
+func (i *Injector) DropGossip() bool {
+	return i.roll(i.Faults().GossipDropRate)
+}
+
+// Clock wraps base so it reads the configured skew ahead of (or behind) it
+func (i *Injector) Clock(base clock.Clock) clock.Clock {
+	return skewed{i: i, base: clock.Or(base)}
+}
+
+type skewed struct {
+	i    *Injector
+	base clock.Clock
+}
+
+func (s skewed) Now() time.Time {
+	return s.base.Now().Add(time.Duration(s.i.Faults().ClockSkewMS) * time.Millisecond)
+}
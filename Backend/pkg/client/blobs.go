@@ -0,0 +1,86 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"salmanahmed/blockchain/pkg/blobstore"
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+// PutBlob stores data off-chain on the node and returns its hash
+func (c *Client) PutBlob(ctx context.Context, data []byte) (string, error) {
+	req, err := c.newRequest(ctx, "POST", "/blobs", bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Hash  string `json:"hash"`
+		Error string `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&out)
+	if resp.StatusCode != http.StatusOK {
+		msg := out.Error
+		if msg == "" {
+			msg = resp.Status
+		}
+		return "", &Error{StatusCode: resp.StatusCode, Message: msg}
+	}
+	if want := blobstore.Hash(data); out.Hash != want {
+		return "", fmt.Errorf("node stored blob as %s, expected %s", out.Hash, want)
+	}
+	return out.Hash, nil
+}
+
+// GetBlob fetches a blob and checks it against its hash, so a faulty node
+// can't substitute content
+func (c *Client) GetBlob(ctx context.Context, hash string) ([]byte, error) {
+	req, err := c.newRequest(ctx, "GET", "/blobs/"+url.PathEscape(hash), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		msg := resp.Status
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error != "" {
+			msg = e.Error
+		}
+		return nil, &Error{StatusCode: resp.StatusCode, Message: msg}
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, blobstore.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if blobstore.Hash(data) != hash {
+		return nil, fmt.Errorf("%w: %s", blobstore.ErrCorrupt, hash)
+	}
+	return data, nil
+}
+
+// SubmitBlob stores data off-chain and queues a transaction carrying its
+// hash and an optional note
+func (c *Client) SubmitBlob(ctx context.Context, data []byte, note string) (SubmitResult, error) {
+	hash, err := c.PutBlob(ctx, data)
+	if err != nil {
+		return SubmitResult{}, err
+	}
+	return c.SubmitTransaction(ctx, blockchain.Transaction{Data: note, Blob: hash})
+}
@@ -0,0 +1,227 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"salmanahmed/blockchain/pkg/api"
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/mempool"
+)
+
+// Broadcaster defaults
+const (
+	defaultAttempts    = 3
+	defaultBackoff     = 500 * time.Millisecond
+	defaultWaitTimeout = 30 * time.Second
+)
+
+// Broadcaster submits transactions to several nodes and tracks them to
+// confirmation, so an application keeps working while any one node is
+// down. Nodes don't relay transactions to each other, so every node is
+// offered each transaction and will mine it.
+type Broadcaster struct {
+	Nodes    []*Client
+	Attempts int           // tries per node on transient failures; 0 means 3
+	Backoff  time.Duration // wait before the first retry, doubling after each; 0 means 500ms
+	Wait     time.Duration // how long one node is asked to wait for confirmations before the next is tried; 0 means 30s
+
+	mu        sync.Mutex
+	preferred int // the node that last answered Confirm
+}
+
+// NewBroadcaster returns a broadcaster over nodes with the default retries
+func NewBroadcaster(nodes ...*Client) *Broadcaster {
+	return &Broadcaster{Nodes: nodes}
+}
+
+// NodeResult is how one node took a broadcast transaction
+type NodeResult struct {
+	Node     string `json:"node"`
+	Status   string `json:"status,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Attempts int    `json:"attempts"`
+}
+
+// BroadcastResult is the outcome of Broadcast
+type BroadcastResult struct {
+	TxID     string       `json:"txid"`
+	Accepted int          `json:"accepted"` // nodes holding the transaction
+	Nodes    []NodeResult `json:"nodes"`
+}
+
+// Broadcast submits tx, sealing it first if its ID is unset, to every node
+// at once. Network errors and 429 or 5xx responses are retried with
+// backoff; a node answering that the transaction is already pending or
+// confirmed counts as having accepted it. It fails only when no node accepted the transaction.
+func (b *Broadcaster) Broadcast(ctx context.Context, tx blockchain.Transaction) (BroadcastResult, error) {
+	if len(b.Nodes) == 0 {
+		return BroadcastResult{}, errors.New("no nodes to broadcast to")
+	}
+	if tx.ID == "" {
+		tx = tx.Seal()
+	}
+	res := BroadcastResult{TxID: tx.ID, Nodes: make([]NodeResult, len(b.Nodes))}
+	var wg sync.WaitGroup
+	for i, c := range b.Nodes {
+		wg.Add(1)
+		go func(i int, c *Client) {
+			defer wg.Done()
+			res.Nodes[i] = b.submit(ctx, c, tx)
+		}(i, c)
+	}
+	wg.Wait()
+	var errs []string
+	for _, n := range res.Nodes {
+		if n.Error != "" {
+			errs = append(errs, n.Node+": "+n.Error)
+			continue
+		}
+		res.Accepted++
+	}
+	if res.Accepted == 0 {
+		return res, fmt.Errorf("no node accepted %s: %s", tx.ID, strings.Join(errs, "; "))
+	}
+	return res, nil
+}
+
+// submit offers tx to one node, retrying transient failures
+func (b *Broadcaster) submit(ctx context.Context, c *Client, tx blockchain.Transaction) NodeResult {
+	out := NodeResult{Node: c.BaseURL()}
+	attempts := b.Attempts
+	if attempts <= 0 {
+		attempts = defaultAttempts
+	}
+	backoff := b.Backoff
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+	for {
+		out.Attempts++
+		res, err := c.SubmitTransaction(ctx, tx)
+		switch {
+		case err == nil:
+			out.Status, out.Error = res.Status, ""
+			return out
+		case holds(err):
+			out.Status, out.Error = err.(*Error).Message, ""
+			return out
+		}
+		out.Error = err.Error()
+		if !transient(err) || out.Attempts >= attempts {
+			return out
+		}
+		select {
+		case <-ctx.Done():
+			return out
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// Confirm waits until tx is confirmations blocks deep, asking one node at a
+// time, starting with the last to answer, and failing over to the next
+// when it is unreachable or doesn't know the transaction. Should every node
+// that answers have lost it, e.g. after a restart with an empty mempool,
+// tx is broadcast once more. It returns the status from the node that saw
+// the depth, or ctx's error.
+func (b *Broadcaster) Confirm(ctx context.Context, tx blockchain.Transaction, confirmations int) (api.TxStatus, error) {
+	if len(b.Nodes) == 0 {
+		return api.TxStatus{}, errors.New("no nodes to confirm with")
+	}
+	if tx.ID == "" {
+		tx = tx.Seal()
+	}
+	wait := b.Wait
+	if wait <= 0 {
+		wait = defaultWaitTimeout
+	}
+	backoff := b.Backoff
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+	b.mu.Lock()
+	start := b.preferred
+	b.mu.Unlock()
+	for rebroadcast := false; ; {
+		answered, unknown := 0, 0
+		var st api.TxStatus
+		for j := range b.Nodes {
+			n := (start + j) % len(b.Nodes)
+			var err error
+			st, err = b.Nodes[n].WaitForTx(ctx, tx.ID, confirmations, wait)
+			var e *Error
+			switch {
+			case err == nil:
+				b.mu.Lock()
+				b.preferred = n
+				b.mu.Unlock()
+				return st, nil
+			case ctx.Err() != nil:
+				return st, ctx.Err()
+			case errors.As(err, &e) && e.StatusCode == http.StatusRequestTimeout:
+				answered++ // known, not deep enough yet
+			case errors.As(err, &e) && e.StatusCode == http.StatusNotFound:
+				answered++
+				unknown++
+			case !transient(err):
+				return st, err
+			}
+		}
+		switch {
+		case answered == 0:
+			// every node is unreachable; give them a moment
+			select {
+			case <-ctx.Done():
+				return st, ctx.Err()
+			case <-time.After(backoff):
+			}
+		case unknown == answered && rebroadcast:
+			return st, fmt.Errorf("%s was dropped by every node", tx.ID)
+		case unknown == answered:
+			// it left every mempool unconfirmed
+			if _, err := b.Broadcast(ctx, tx); err != nil {
+				return st, err
+			}
+			rebroadcast = true
+		}
+	}
+}
+
+// BroadcastAndConfirm broadcasts tx and waits for it to be confirmations
+// blocks deep
+func (b *Broadcaster) BroadcastAndConfirm(ctx context.Context, tx blockchain.Transaction, confirmations int) (BroadcastResult, api.TxStatus, error) {
+	if tx.ID == "" {
+		tx = tx.Seal()
+	}
+	res, err := b.Broadcast(ctx, tx)
+	if err != nil {
+		return res, api.TxStatus{}, err
+	}
+	st, err := b.Confirm(ctx, tx, confirmations)
+	return res, st, err
+}
+
+// holds reports whether err is a node refusing a transaction it already
+// holds, pending or confirmed
+func holds(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.StatusCode == http.StatusConflict &&
+		(strings.Contains(e.Message, mempool.ErrDuplicate.Error()) || strings.Contains(e.Message, api.ErrAlreadyConfirmed.Error()))
+}
+
+// transient reports whether err may go away on a retry: the node couldn't
+// be reached, was overloaded or failed on its side
+func transient(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
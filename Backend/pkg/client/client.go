@@ -0,0 +1,923 @@
+// Package client is a typed Go SDK for a node's HTTP API, so Go programs and
+// the CLI don't hand-roll requests.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"salmanahmed/blockchain/pkg/api"
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/chaos"
+	"salmanahmed/blockchain/pkg/clock"
+	"salmanahmed/blockchain/pkg/fixtures"
+	"salmanahmed/blockchain/pkg/labels"
+	"salmanahmed/blockchain/pkg/mempool"
+	"salmanahmed/blockchain/pkg/sim"
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+// Client talks to one node
+type Client struct {
+	baseURL string
+	token   string
+	key     string // hex private key signing submissions; see WithKey
+	clock   clock.Clock
+	http    *http.Client
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithToken sends token as a bearer token on every request
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithKey signs each transaction SubmitTransaction is given unsealed, with
+// no ID, as sent by the holder of privHex (see Transaction.Sign), as nodes
+// requiring signatures want. Sealed transactions are sent as they are.
+func WithKey(privHex string) Option {
+	return func(c *Client) { c.key = privHex }
+}
+
+// WithClock stamps WithKey's signatures from c instead of the wall clock
+func WithClock(c clock.Clock) Option {
+	return func(cl *Client) { cl.clock = c }
+}
+
+// WithHTTPClient replaces the default HTTP client
+func WithHTTPClient(h *http.Client) Option {
+	return func(c *Client) { c.http = h }
+}
+
+// New returns a client for the node at baseURL, e.g. "http://localhost:8080"
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http: &http.Client{
+			Timeout:       5 * time.Minute, // mining can take a while
+			CheckRedirect: keepToken,
+		},
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// keepToken carries the bearer token over a replica's redirect of a write
+// to its primary, which net/http would drop for another host
+func keepToken(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return fmt.Errorf("stopped after %d redirects", len(via))
+	}
+	if auth := via[0].Header.Get("Authorization"); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	return nil
+}
+
+// BaseURL returns the node URL the client talks to
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// Error is a non-2xx response from the node
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("node returned %d: %s", e.StatusCode, e.Message)
+}
+
+// SubmitResult is the outcome of SubmitTx
+type SubmitResult struct {
+	Status string `json:"status"`
+	TxID   string `json:"txid"`
+}
+
+// DropResult is the response of DropTransaction
+type DropResult struct {
+	Status      string                 `json:"status"`
+	TxID        string                 `json:"txid"`
+	Transaction blockchain.Transaction `json:"transaction"`
+}
+
+// MineResult is the outcome of Mine; Block is nil when nothing was pending
+type MineResult struct {
+	Status   string            `json:"status,omitempty"`
+	Block    *blockchain.Block `json:"block,omitempty"`
+	Nonces   int64             `json:"nonces,omitempty"` // tried across every worker
+	Hashrate float64           `json:"hashrate,omitempty"`
+	Workers  int               `json:"workers,omitempty"`
+	Elapsed  float64           `json:"elapsed_seconds,omitempty"`
+}
+
+// Blocks returns the whole chain
+func (c *Client) Blocks(ctx context.Context) ([]blockchain.Block, error) {
+	var blocks []blockchain.Block
+	err := c.do(ctx, "GET", "/blocks", nil, &blocks)
+	return blocks, err
+}
+
+// BlocksPage returns the page of blocks q selects
+func (c *Client) BlocksPage(ctx context.Context, q api.BlockQuery) (api.BlockPage, error) {
+	v := url.Values{}
+	if q.Page > 0 {
+		v.Set("page", strconv.Itoa(q.Page))
+	}
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.From != nil {
+		v.Set("from", strconv.FormatInt(*q.From, 10))
+	}
+	if q.To != nil {
+		v.Set("to", strconv.FormatInt(*q.To, 10))
+	}
+	if q.ByTime {
+		v.Set("by", "time")
+	}
+	if q.Miner != "" {
+		v.Set("miner", q.Miner)
+	}
+	if q.Producer != "" {
+		v.Set("producer", q.Producer)
+	}
+	order := "asc"
+	if q.Desc {
+		order = "desc"
+	}
+	v.Set("order", order) // always one parameter, so the node answers with a page
+	var out api.BlockPage
+	err := c.do(ctx, "GET", "/blocks?"+v.Encode(), nil, &out)
+	return out, err
+}
+
+// GetBlock returns the block at index
+func (c *Client) GetBlock(ctx context.Context, index int) (blockchain.Block, error) {
+	var b blockchain.Block
+	err := c.do(ctx, "GET", "/block/"+strconv.Itoa(index), nil, &b)
+	return b, err
+}
+
+// BlockByHash returns the block with the given hash
+func (c *Client) BlockByHash(ctx context.Context, hash string) (blockchain.Block, error) {
+	var b blockchain.Block
+	err := c.do(ctx, "GET", "/block/hash/"+url.PathEscape(hash), nil, &b)
+	return b, err
+}
+
+// MerkleTree returns every level of block index's merkle tree
+func (c *Client) MerkleTree(ctx context.Context, index int) (blockchain.MerkleTree, error) {
+	var out blockchain.MerkleTree
+	err := c.do(ctx, "GET", "/blocks/"+strconv.Itoa(index)+"/merkle-tree", nil, &out)
+	return out, err
+}
+
+// TxAt returns the transaction at position pos of block index
+func (c *Client) TxAt(ctx context.Context, index, pos int) (blockchain.TxMatch, error) {
+	var out blockchain.TxMatch
+	err := c.do(ctx, "GET", "/blocks/"+strconv.Itoa(index)+"/txs/"+strconv.Itoa(pos), nil, &out)
+	return out, err
+}
+
+// Pending returns the mempool
+func (c *Client) Pending(ctx context.Context) ([]blockchain.Transaction, error) {
+	var pending []blockchain.Transaction
+	err := c.do(ctx, "GET", "/pending", nil, &pending)
+	return pending, err
+}
+
+// SubmitTx queues a data-only transaction, signed under WithKey
+func (c *Client) SubmitTx(ctx context.Context, data string) (SubmitResult, error) {
+	return c.SubmitTransaction(ctx, blockchain.Transaction{Data: data})
+}
+
+// SubmitTransaction queues a transaction, signing it under WithKey and
+// sealing it first if its ID is unset
+func (c *Client) SubmitTransaction(ctx context.Context, tx blockchain.Transaction) (SubmitResult, error) {
+	if tx.ID == "" && c.key != "" {
+		signed, err := tx.Sign(c.key, clock.Or(c.clock).Now().Unix())
+		if err != nil {
+			return SubmitResult{}, err
+		}
+		tx = signed
+	}
+	if tx.ID == "" {
+		tx = tx.Seal()
+	}
+	var res SubmitResult
+	if err := c.do(ctx, "POST", "/transactions", tx, &res); err != nil {
+		return SubmitResult{}, err
+	}
+	res.TxID = tx.ID
+	return res, nil
+}
+
+// Pay sends amount to the address to from the WithKey wallet, with fee for
+// the miner, as a payment stating its sender, recipient and amount
+func (c *Client) Pay(ctx context.Context, to string, amount, fee int64) (SubmitResult, error) {
+	if c.key == "" {
+		return SubmitResult{}, errors.New("client: paying needs a key (WithKey)")
+	}
+	kp, err := wallet.FromPrivateKey(c.key)
+	if err != nil {
+		return SubmitResult{}, err
+	}
+	utx, err := c.BuildTransaction(ctx, kp.Address, to, amount, fee)
+	if err != nil {
+		return SubmitResult{}, err
+	}
+	tx := utx.Tx
+	tx.Recipient, tx.Amount = to, amount
+	return c.SubmitTransaction(ctx, tx)
+}
+
+// BuildTransaction asks the node for an unsigned transfer of amount from
+// one address to another, to be signed offline
+func (c *Client) BuildTransaction(ctx context.Context, from, to string, amount, fee int64) (api.UnsignedTx, error) {
+	var utx api.UnsignedTx
+	req := api.TransferRequest{From: from, To: to, Amount: amount, Fee: fee}
+	if err := c.do(ctx, "POST", "/transactions/build", req, &utx); err != nil {
+		return api.UnsignedTx{}, err
+	}
+	return utx, nil
+}
+
+// PreviewTransaction validates tx and reports its txid, fee and effect on
+// balances without submitting it
+func (c *Client) PreviewTransaction(ctx context.Context, tx blockchain.Transaction) (api.TxPreview, error) {
+	var out api.TxPreview
+	err := c.do(ctx, "POST", "/transactions/preview", tx, &out)
+	return out, err
+}
+
+// DropTransaction removes a pending transaction from the mempool
+func (c *Client) DropTransaction(ctx context.Context, txid string) (DropResult, error) {
+	var res DropResult
+	err := c.do(ctx, "DELETE", "/transactions/"+url.PathEscape(txid), nil, &res)
+	return res, err
+}
+
+// SubmitSigned submits a transaction signed offline
+func (c *Client) SubmitSigned(ctx context.Context, tx blockchain.Transaction) (SubmitResult, error) {
+	var res SubmitResult
+	if err := c.do(ctx, "POST", "/transactions/submit-signed", tx, &res); err != nil {
+		return SubmitResult{}, err
+	}
+	return res, nil
+}
+
+// SubmitConfidential encrypts payload to the recipient's hex public key and
+// queues it; the chain only sees the ciphertext and a commitment
+func (c *Client) SubmitConfidential(ctx context.Context, recipientPub, payload string) (SubmitResult, error) {
+	sealed, err := blockchain.SealPayload(recipientPub, payload)
+	if err != nil {
+		return SubmitResult{}, err
+	}
+	return c.SubmitTransaction(ctx, blockchain.Transaction{Confidential: sealed})
+}
+
+// Decrypt opens a confidential transaction's payload with the recipient's
+// hex private key, checking it against the on-chain commitment
+func Decrypt(tx blockchain.Transaction, privHex string) (string, error) {
+	if tx.Confidential == nil {
+		return "", fmt.Errorf("transaction %s is not confidential", tx.ID)
+	}
+	return tx.Confidential.Open(privHex)
+}
+
+// SubmitCommitment queues a commitment to data under a fresh salt; keep the
+// returned hex salt to reveal data once the commitment is mined
+func (c *Client) SubmitCommitment(ctx context.Context, data string) (res SubmitResult, salt string, err error) {
+	tx, salt, err := blockchain.NewCommitTx(data)
+	if err != nil {
+		return SubmitResult{}, "", err
+	}
+	tx.ID = "" // signed under WithKey
+	res, err = c.SubmitTransaction(ctx, tx)
+	return res, salt, err
+}
+
+// Reveal discloses the data behind a mined commitment; the node checks it
+// against the commitment and queues the reveal. Under WithKey the reveal is
+// signed and submitted as a transaction instead.
+func (c *Client) Reveal(ctx context.Context, txid, salt, data string) (SubmitResult, error) {
+	if c.key != "" {
+		tx := blockchain.NewRevealTx(txid, salt, data)
+		tx.ID = ""
+		return c.SubmitTransaction(ctx, tx)
+	}
+	var res SubmitResult
+	err := c.do(ctx, "POST", "/reveal/"+url.PathEscape(txid), map[string]string{"salt": salt, "data": data}, &res)
+	return res, err
+}
+
+// RevealStatus reports whether a commitment has been revealed, and to what
+func (c *Client) RevealStatus(ctx context.Context, txid string) (blockchain.RevealStatus, error) {
+	var out blockchain.RevealStatus
+	err := c.do(ctx, "GET", "/reveal/"+url.PathEscape(txid), nil, &out)
+	return out, err
+}
+
+// Contracts lists the deployed contract addresses
+func (c *Client) Contracts(ctx context.Context) ([]string, error) {
+	var out []string
+	err := c.do(ctx, "GET", "/contracts", nil, &out)
+	return out, err
+}
+
+// Contract returns a deployed contract's code and storage
+func (c *Client) Contract(ctx context.Context, address string) (blockchain.ContractState, error) {
+	var out blockchain.ContractState
+	err := c.do(ctx, "GET", "/contracts?address="+url.QueryEscape(address), nil, &out)
+	return out, err
+}
+
+// Receipt returns the outcome of a confirmed contract transaction
+func (c *Client) Receipt(ctx context.Context, txid string) (blockchain.Receipt, error) {
+	var out blockchain.Receipt
+	err := c.do(ctx, "GET", "/receipts?txid="+url.QueryEscape(txid), nil, &out)
+	return out, err
+}
+
+// KV reads key from the chain's key-value store as of block height, or the
+// tip when height is negative
+func (c *Client) KV(ctx context.Context, key string, height int) (blockchain.KVEntry, error) {
+	path := "/kv/" + url.PathEscape(key)
+	if height >= 0 {
+		path += "?at_height=" + strconv.Itoa(height)
+	}
+	var out blockchain.KVEntry
+	err := c.do(ctx, "GET", path, nil, &out)
+	return out, err
+}
+
+// Simulate asks the node to fabricate blocks of random activity
+func (c *Client) Simulate(ctx context.Context, opts sim.Options) (sim.Report, error) {
+	var rep sim.Report
+	err := c.do(ctx, "POST", "/admin/simulate", opts, &rep)
+	return rep, err
+}
+
+// Chains lists the chains the node hosts besides its default one. Call it
+// on a client for the node's root URL.
+func (c *Client) Chains(ctx context.Context) ([]api.ChainInfo, error) {
+	var out []api.ChainInfo
+	err := c.do(ctx, "GET", "/chains", nil, &out)
+	return out, err
+}
+
+// CreateChain starts a new hosted chain governed by policy; talk to it with
+// a client for BaseURL()+"/chains/"+spec.ID
+func (c *Client) CreateChain(ctx context.Context, spec api.ChainSpec, policy api.ChainPolicy) error {
+	body := struct {
+		api.ChainSpec
+		Policy api.ChainPolicy `json:"policy"`
+	}{spec, policy}
+	return c.do(ctx, "POST", "/chains", body, nil)
+}
+
+// ChainAdmin returns a hosted chain's spec and policy; it needs the node token
+func (c *Client) ChainAdmin(ctx context.Context, id string) (api.ChainAdmin, error) {
+	var out api.ChainAdmin
+	err := c.do(ctx, "GET", "/admin/chains/"+url.PathEscape(id), nil, &out)
+	return out, err
+}
+
+// SetChainPolicy replaces a hosted chain's policy; it needs the node token
+func (c *Client) SetChainPolicy(ctx context.Context, id string, p api.ChainPolicy) (api.ChainAdmin, error) {
+	var out api.ChainAdmin
+	err := c.do(ctx, "PUT", "/admin/chains/"+url.PathEscape(id), p, &out)
+	return out, err
+}
+
+// DeleteChain stops hosting a chain and discards it; it needs the node token
+func (c *Client) DeleteChain(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", "/admin/chains/"+url.PathEscape(id), nil, nil)
+}
+
+// Chaos returns the faults the node is injecting
+func (c *Client) Chaos(ctx context.Context) (chaos.Faults, error) {
+	var f chaos.Faults
+	err := c.do(ctx, "GET", "/admin/chaos", nil, &f)
+	return f, err
+}
+
+// Usage returns per-API-key request and transaction counts for every chain
+// on the node (node token required)
+func (c *Client) Usage(ctx context.Context) ([]api.KeyUsage, error) {
+	var out []api.KeyUsage
+	err := c.do(ctx, "GET", "/admin/usage", nil, &out)
+	return out, err
+}
+
+// SetChaos replaces the faults the node injects; the zero Faults turns them off
+func (c *Client) SetChaos(ctx context.Context, f chaos.Faults) (chaos.Faults, error) {
+	var out chaos.Faults
+	err := c.do(ctx, "PUT", "/admin/chaos", f, &out)
+	return out, err
+}
+
+// SetDifficulty changes the difficulty new blocks are mined to
+func (c *Client) SetDifficulty(ctx context.Context, difficulty int) (api.DifficultyChange, error) {
+	var out api.DifficultyChange
+	err := c.do(ctx, "PUT", "/admin/difficulty", map[string]int{"difficulty": difficulty}, &out)
+	return out, err
+}
+
+// Deadline returns when the node stops accepting new transactions
+func (c *Client) Deadline(ctx context.Context) (api.Deadline, error) {
+	var out api.Deadline
+	err := c.do(ctx, "GET", "/deadline", nil, &out)
+	return out, err
+}
+
+// TestVectors returns the node's canonical hashing, merkle and signature
+// test vectors
+func (c *Client) TestVectors(ctx context.Context) (fixtures.Vectors, error) {
+	var out fixtures.Vectors
+	err := c.do(ctx, "GET", "/testvectors", nil, &out)
+	return out, err
+}
+
+// ReplicaStatus reports how far a read-only replica has followed its upstream
+func (c *Client) ReplicaStatus(ctx context.Context) (api.ReplicaStatus, error) {
+	var out api.ReplicaStatus
+	err := c.do(ctx, "GET", "/replica", nil, &out)
+	return out, err
+}
+
+// TakeSnapshot records the node's tip, balances and mempool under name
+func (c *Client) TakeSnapshot(ctx context.Context, name string) (api.SnapshotRef, error) {
+	var out api.SnapshotRef
+	err := c.do(ctx, "POST", "/snapshots", map[string]string{"name": name}, &out)
+	return out, err
+}
+
+// Snapshots lists the node's snapshots, oldest first
+func (c *Client) Snapshots(ctx context.Context) ([]api.SnapshotRef, error) {
+	var out []api.SnapshotRef
+	err := c.do(ctx, "GET", "/snapshots", nil, &out)
+	return out, err
+}
+
+// Snapshot returns one snapshot with its balances and mempool
+func (c *Client) Snapshot(ctx context.Context, id int) (blockchain.Snapshot, error) {
+	var out blockchain.Snapshot
+	err := c.do(ctx, "GET", "/snapshots/"+strconv.Itoa(id), nil, &out)
+	return out, err
+}
+
+// DiffSnapshots reports the blocks, balance changes and mempool changes
+// between snapshots a and b
+func (c *Client) DiffSnapshots(ctx context.Context, a, b int) (api.SnapshotDiff, error) {
+	var out api.SnapshotDiff
+	err := c.do(ctx, "GET", "/snapshots/"+strconv.Itoa(a)+"/diff/"+strconv.Itoa(b), nil, &out)
+	return out, err
+}
+
+// SetDeadline moves the submission deadline; the zero time removes it
+func (c *Client) SetDeadline(ctx context.Context, deadline time.Time) (api.Deadline, error) {
+	var unix int64
+	if !deadline.IsZero() {
+		unix = deadline.Unix()
+	}
+	var out api.Deadline
+	err := c.do(ctx, "PUT", "/admin/deadline", map[string]int64{"deadline": unix}, &out)
+	return out, err
+}
+
+// Labels lists the node's address labels
+func (c *Client) Labels(ctx context.Context) ([]labels.Label, error) {
+	var out []labels.Label
+	err := c.do(ctx, "GET", "/labels", nil, &out)
+	return out, err
+}
+
+// SetLabel names an address; it needs the node token
+func (c *Client) SetLabel(ctx context.Context, address, label string) (labels.Label, error) {
+	var out labels.Label
+	err := c.do(ctx, "PUT", "/admin/labels/"+url.PathEscape(address), map[string]string{"label": label}, &out)
+	return out, err
+}
+
+// DeleteLabel removes an address's label; it needs the node token
+func (c *Client) DeleteLabel(ctx context.Context, address string) error {
+	return c.do(ctx, "DELETE", "/admin/labels/"+url.PathEscape(address), nil, nil)
+}
+
+// RequestReset asks the node for a token to confirm a chain reset with
+func (c *Client) RequestReset(ctx context.Context) (api.ResetConfirmation, error) {
+	var out api.ResetConfirmation
+	err := c.do(ctx, "POST", "/admin/reset", nil, &out)
+	return out, err
+}
+
+// Reset wipes the chain back to genesis, confirmed with a RequestReset token
+func (c *Client) Reset(ctx context.Context, confirm string) (api.ResetResult, error) {
+	var out api.ResetResult
+	err := c.do(ctx, "POST", "/admin/reset", map[string]string{"confirm": confirm}, &out)
+	return out, err
+}
+
+// Freeze halts mining, new transactions and peer sync on the node until
+// Unfreeze, for maintenance; reads carry on
+func (c *Client) Freeze(ctx context.Context, reason string) (api.FreezeStatus, error) {
+	var out api.FreezeStatus
+	err := c.do(ctx, "POST", "/admin/freeze", map[string]string{"reason": reason}, &out)
+	return out, err
+}
+
+// Unfreeze lifts a Freeze
+func (c *Client) Unfreeze(ctx context.Context) (api.FreezeStatus, error) {
+	var out api.FreezeStatus
+	err := c.do(ctx, "POST", "/admin/unfreeze", nil, &out)
+	return out, err
+}
+
+// FreezeStatus reports whether the node is frozen for maintenance
+func (c *Client) FreezeStatus(ctx context.Context) (api.FreezeStatus, error) {
+	var out api.FreezeStatus
+	err := c.do(ctx, "GET", "/admin/freeze", nil, &out)
+	return out, err
+}
+
+// UTXOs returns the unspent outputs paying to address, or all when address is empty
+func (c *Client) UTXOs(ctx context.Context, address string) ([]blockchain.UTXO, error) {
+	return c.UTXOsAt(ctx, address, -1)
+}
+
+// UTXOsAt is UTXOs as of block height, or the tip when height is negative
+func (c *Client) UTXOsAt(ctx context.Context, address string, height int) ([]blockchain.UTXO, error) {
+	path := "/utxos?address=" + url.QueryEscape(address)
+	if height >= 0 {
+		path += "&at_height=" + strconv.Itoa(height)
+	}
+	var out []blockchain.UTXO
+	err := c.do(ctx, "GET", path, nil, &out)
+	return out, err
+}
+
+// Balance returns what address holds as of block height, or the tip when
+// height is negative
+func (c *Client) Balance(ctx context.Context, address string, height int) (blockchain.Balance, error) {
+	path := "/balance/" + url.PathEscape(address)
+	if height >= 0 {
+		path += "?at_height=" + strconv.Itoa(height)
+	}
+	var out blockchain.Balance
+	err := c.do(ctx, "GET", path, nil, &out)
+	return out, err
+}
+
+// History returns the transactions moving coins to or from address, only
+// the latest limit when limit is positive
+func (c *Client) History(ctx context.Context, address string, limit int) (api.AddressHistory, error) {
+	var out api.AddressHistory
+	err := c.do(ctx, "GET", "/address/"+url.PathEscape(address)+"/history?limit="+strconv.Itoa(limit), nil, &out)
+	return out, err
+}
+
+// FaucetResult is the outcome of Faucet
+type FaucetResult struct {
+	Status string `json:"status"`
+	TxID   string `json:"txid"`
+	Amount int64  `json:"amount"`
+}
+
+// Faucet asks the node's faucet to fund address
+func (c *Client) Faucet(ctx context.Context, address string) (FaucetResult, error) {
+	var res FaucetResult
+	err := c.do(ctx, "POST", "/faucet", map[string]string{"address": address}, &res)
+	return res, err
+}
+
+// Stats returns block and transaction sizes and the mempool's size
+func (c *Client) Stats(ctx context.Context) (api.Stats, error) {
+	var out api.Stats
+	err := c.do(ctx, "GET", "/stats", nil, &out)
+	return out, err
+}
+
+// Status returns the node's version, configuration, features, tip, peer
+// count and mempool depth
+func (c *Client) Status(ctx context.Context) (api.NodeStatus, error) {
+	var out api.NodeStatus
+	err := c.do(ctx, "GET", "/status", nil, &out)
+	return out, err
+}
+
+// Reorgs returns the node's recorded reorganizations, only the latest
+// limit when limit is positive
+func (c *Client) Reorgs(ctx context.Context, limit int) (api.ReorgHistory, error) {
+	var out api.ReorgHistory
+	err := c.do(ctx, "GET", "/reorgs?limit="+strconv.Itoa(limit), nil, &out)
+	return out, err
+}
+
+// Attestations returns the node's finality attestations, only the latest
+// limit when limit is positive
+func (c *Client) Attestations(ctx context.Context, limit int) (api.Attestations, error) {
+	var out api.Attestations
+	err := c.do(ctx, "GET", "/attestations?limit="+strconv.Itoa(limit), nil, &out)
+	return out, err
+}
+
+// BlockTimeStats returns recent block intervals and the next-block ETA
+func (c *Client) BlockTimeStats(ctx context.Context) (api.BlockTimeStats, error) {
+	var out api.BlockTimeStats
+	err := c.do(ctx, "GET", "/stats/blocktime", nil, &out)
+	return out, err
+}
+
+// EstimateFee suggests a fee likely to confirm within target blocks
+func (c *Client) EstimateFee(ctx context.Context, target int) (api.FeeEstimate, error) {
+	var out api.FeeEstimate
+	err := c.do(ctx, "GET", "/fees/estimate?target_blocks="+strconv.Itoa(target), nil, &out)
+	return out, err
+}
+
+// Difficulty returns the difficulty in force and how it retargets
+func (c *Client) Difficulty(ctx context.Context) (api.DifficultyStatus, error) {
+	var out api.DifficultyStatus
+	err := c.do(ctx, "GET", "/difficulty", nil, &out)
+	return out, err
+}
+
+// EstimateDifficulty returns the expected work and mining time at level
+func (c *Client) EstimateDifficulty(ctx context.Context, level int) (api.DifficultyEstimate, error) {
+	var out api.DifficultyEstimate
+	err := c.do(ctx, "GET", "/difficulty/estimate?level="+strconv.Itoa(level), nil, &out)
+	return out, err
+}
+
+// Leaderboard returns blocks mined, rewards and best hash per miner
+func (c *Client) Leaderboard(ctx context.Context) ([]blockchain.MinerStats, error) {
+	var out []blockchain.MinerStats
+	err := c.do(ctx, "GET", "/leaderboard", nil, &out)
+	return out, err
+}
+
+// Orphans returns the transactions held until their parents confirm
+func (c *Client) Orphans(ctx context.Context) ([]mempool.Orphan, error) {
+	var out []mempool.Orphan
+	err := c.do(ctx, "GET", "/orphans", nil, &out)
+	return out, err
+}
+
+// MempoolHistory returns what became of transactions that left the
+// mempool, newest first: only txid's or those with outcome when given, and
+// only the latest limit when limit is positive
+func (c *Client) MempoolHistory(ctx context.Context, txid, outcome string, limit int) (api.MempoolHistory, error) {
+	q := url.Values{"limit": {strconv.Itoa(limit)}}
+	if txid != "" {
+		q.Set("txid", txid)
+	}
+	if outcome != "" {
+		q.Set("outcome", outcome)
+	}
+	var out api.MempoolHistory
+	err := c.do(ctx, "GET", "/mempool/history?"+q.Encode(), nil, &out)
+	return out, err
+}
+
+// WaitForTx returns once txid is confirmations blocks deep, or fails with a
+// 408 after timeout (the node's default when zero)
+func (c *Client) WaitForTx(ctx context.Context, txid string, confirmations int, timeout time.Duration) (api.TxStatus, error) {
+	q := url.Values{"confirmations": {strconv.Itoa(confirmations)}}
+	if timeout > 0 {
+		q.Set("timeout", timeout.String())
+	}
+	var out api.TxStatus
+	err := c.do(ctx, "GET", "/transactions/"+url.PathEscape(txid)+"/wait?"+q.Encode(), nil, &out)
+	return out, err
+}
+
+// Mine mines the pending transactions into a block
+func (c *Client) Mine(ctx context.Context) (MineResult, error) {
+	return c.MineAs(ctx, "")
+}
+
+// minePollInterval is how often MineAs checks on its mining job
+const minePollInterval = 250 * time.Millisecond
+
+// MineAs is Mine crediting the block (and any block reward) to a wallet
+// address. It polls the node's mining job until the block is mined, and
+// cancels the job if ctx ends first.
+func (c *Client) MineAs(ctx context.Context, miner string) (MineResult, error) {
+	return c.MineAt(ctx, miner, 0)
+}
+
+// MineAt is MineAs mining the block at difficulty instead of the chain's,
+// within the range the node allows; 0 keeps the chain's
+func (c *Client) MineAt(ctx context.Context, miner string, difficulty int) (MineResult, error) {
+	job, err := c.StartMining(ctx, miner, difficulty)
+	if err != nil {
+		return MineResult{}, err
+	}
+	for !job.Finished() {
+		select {
+		case <-ctx.Done():
+			c.CancelMining(context.Background(), job.ID)
+			return MineResult{}, ctx.Err()
+		case <-time.After(minePollInterval):
+		}
+		if job, err = c.MineStatus(ctx, job.ID); err != nil {
+			return MineResult{}, err
+		}
+	}
+	switch job.State {
+	case api.JobMined:
+		return MineResult{Status: "mined", Block: job.Block, Nonces: job.Nonces, Hashrate: job.Hashrate, Workers: job.Workers, Elapsed: job.Elapsed}, nil
+	case api.JobEmpty:
+		return MineResult{Status: "no transactions to mine"}, nil
+	case api.JobCancelled:
+		return MineResult{}, fmt.Errorf("mining job %s was cancelled", job.ID)
+	default:
+		return MineResult{}, fmt.Errorf("mining job %s failed: %s", job.ID, job.Error)
+	}
+}
+
+// StartMining has the node mine a block in the background, crediting miner
+// when set and at difficulty unless 0, and returns the job without waiting
+// for it
+func (c *Client) StartMining(ctx context.Context, miner string, difficulty int) (api.MineJob, error) {
+	q := url.Values{}
+	if miner != "" {
+		q.Set("miner", miner)
+	}
+	if difficulty != 0 {
+		q.Set("difficulty", strconv.Itoa(difficulty))
+	}
+	path := "/mine"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	var out api.MineJob
+	err := c.do(ctx, "POST", path, nil, &out)
+	return out, err
+}
+
+// MineStatus returns a mining job's progress
+func (c *Client) MineStatus(ctx context.Context, id string) (api.MineJob, error) {
+	var out api.MineJob
+	err := c.do(ctx, "GET", "/mine/status/"+url.PathEscape(id), nil, &out)
+	return out, err
+}
+
+// CancelMining aborts a mining job
+func (c *Client) CancelMining(ctx context.Context, id string) (api.MineJob, error) {
+	var out api.MineJob
+	err := c.do(ctx, "POST", "/mine/cancel/"+url.PathEscape(id), nil, &out)
+	return out, err
+}
+
+// Search finds confirmed transactions containing q
+func (c *Client) Search(ctx context.Context, q string) ([]blockchain.TxMatch, error) {
+	var out []blockchain.TxMatch
+	err := c.do(ctx, "GET", "/search?q="+url.QueryEscape(q), nil, &out)
+	return out, err
+}
+
+// Query evaluates an analytics query on the node
+func (c *Client) Query(ctx context.Context, q api.Query) (api.QueryResult, error) {
+	var out api.QueryResult
+	err := c.do(ctx, "POST", "/query", q, &out)
+	return out, err
+}
+
+// Peers lists the node's peers
+func (c *Client) Peers(ctx context.Context) ([]string, error) {
+	var out []string
+	err := c.do(ctx, "GET", "/peers", nil, &out)
+	return out, err
+}
+
+// AddPeer registers a peer with the node and returns its status message
+func (c *Client) AddPeer(ctx context.Context, url string) (string, error) {
+	var out struct {
+		Status string `json:"status"`
+	}
+	err := c.do(ctx, "POST", "/peers", map[string]string{"url": url}, &out)
+	return out.Status, err
+}
+
+// Sync has the node adopt the longest valid chain among its peers
+func (c *Client) Sync(ctx context.Context) (api.SyncResult, error) {
+	var out api.SyncResult
+	err := c.do(ctx, "POST", "/peers/sync", nil, &out)
+	return out, err
+}
+
+// Validate has the node re-check every block of its chain
+func (c *Client) Validate(ctx context.Context) (blockchain.Integrity, error) {
+	var out blockchain.Integrity
+	err := c.do(ctx, "GET", "/validate", nil, &out)
+	return out, err
+}
+
+// Tamper edits a transaction in a confirmed block of a node running with
+// --demo and returns the edit with the node's re-validation of its chain
+func (c *Client) Tamper(ctx context.Context, req api.TamperRequest) (api.TamperResult, error) {
+	var out api.TamperResult
+	err := c.do(ctx, "POST", "/tamper", req, &out)
+	return out, err
+}
+
+// Untamper undoes every Tamper edit and returns the heights restored
+func (c *Client) Untamper(ctx context.Context) ([]int, error) {
+	var out struct {
+		Restored []int `json:"restored"`
+	}
+	err := c.do(ctx, "DELETE", "/tamper", nil, &out)
+	return out.Restored, err
+}
+
+// ForkView returns the top depth blocks of the chain and the blocks that
+// branched off them; depth 0 uses the node's default
+func (c *Client) ForkView(ctx context.Context, depth int) (api.ForkView, error) {
+	path := "/fork-view"
+	if depth > 0 {
+		path += "?depth=" + strconv.Itoa(depth)
+	}
+	var out api.ForkView
+	err := c.do(ctx, "GET", path, nil, &out)
+	return out, err
+}
+
+// Ready returns nil when the node reports itself ready
+func (c *Client) Ready(ctx context.Context) error {
+	return c.do(ctx, "GET", "/readyz", nil, nil)
+}
+
+// do sends a request and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var rd io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rd = bytes.NewReader(raw)
+	}
+	req, err := c.newRequest(ctx, method, path, rd)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		var e struct {
+			Error  string `json:"error"`
+			Reason string `json:"reason"`
+		}
+		msg := resp.Status
+		if json.Unmarshal(raw, &e) == nil {
+			if e.Error != "" {
+				msg = e.Error
+			} else if e.Reason != "" {
+				msg = e.Reason
+			}
+		}
+		return &Error{StatusCode: resp.StatusCode, Message: msg}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(raw, out)
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
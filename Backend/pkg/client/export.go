@@ -0,0 +1,144 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+
+	"salmanahmed/blockchain/pkg/api"
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+// stream returns a copy of the HTTP client without the request timeout,
+// for transfers whose length depends on the chain
+func (c *Client) stream() *http.Client {
+	h := *c.http
+	h.Timeout = 0
+	return &h
+}
+
+// Export streams the chain to w as newline-delimited JSON, one block per line
+func (c *Client) Export(ctx context.Context, w io.Writer) error {
+	return c.download(ctx, "/export?format=ndjson", w)
+}
+
+// ExportBinary streams the chain to w as framed binary blocks, as
+// blockchain.ReadBlock reads them
+func (c *Client) ExportBinary(ctx context.Context, w io.Writer) error {
+	return c.download(ctx, "/export?format=binary", w)
+}
+
+// ExportSnapshot streams the chain and mempool to w as a single JSON
+// document that RestoreSnapshot or --snapshot can load
+func (c *Client) ExportSnapshot(ctx context.Context, w io.Writer) error {
+	return c.download(ctx, "/export?format=snapshot", w)
+}
+
+// download copies the body of GET path to w
+func (c *Client) download(ctx context.Context, path string, w io.Writer) error {
+	req, err := c.newRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.stream().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &Error{StatusCode: resp.StatusCode, Message: resp.Status}
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// ExportLedger streams the node's double-entry journal to w as CSV, or as
+// a JSON array when format is "json"; a non-empty account limits it to
+// that account
+func (c *Client) ExportLedger(ctx context.Context, w io.Writer, format, account string) error {
+	q := url.Values{"format": {format}}
+	if account != "" {
+		q.Set("account", account)
+	}
+	req, err := c.newRequest(ctx, "GET", "/export/ledger?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.stream().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &Error{StatusCode: resp.StatusCode, Message: resp.Status}
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Import streams newline-delimited blocks from r to the node, which appends
+// them as they arrive
+func (c *Client) Import(ctx context.Context, r io.Reader) (api.ImportResult, error) {
+	var res api.ImportResult
+	err := c.upload(ctx, "/import", "application/x-ndjson", r, &res)
+	return res, err
+}
+
+// ImportBinary streams framed binary blocks from r to the node, which
+// appends them as they arrive
+func (c *Client) ImportBinary(ctx context.Context, r io.Reader) (api.ImportResult, error) {
+	var res api.ImportResult
+	err := c.upload(ctx, "/import?format=binary", blockchain.BinaryMediaType, r, &res)
+	return res, err
+}
+
+// RestoreSnapshot sends a snapshot from ExportSnapshot to the node, which
+// validates it in full and then replaces its chain and mempool with it
+func (c *Client) RestoreSnapshot(ctx context.Context, r io.Reader) (api.RestoreResult, error) {
+	var res api.RestoreResult
+	err := c.upload(ctx, "/import?format=snapshot", "application/json", r, &res)
+	return res, err
+}
+
+// PlanImport sends blocks in format, "ndjson", "binary" or "snapshot", for
+// the node to validate and report what importing them would do; the node's
+// chain and mempool are left as they are
+func (c *Client) PlanImport(ctx context.Context, r io.Reader, format string) (api.ImportPlan, error) {
+	var plan api.ImportPlan
+	contentType := "application/x-ndjson"
+	switch format {
+	case "binary":
+		contentType = blockchain.BinaryMediaType
+	case "snapshot":
+		contentType = "application/json"
+	}
+	err := c.upload(ctx, "/import?dry_run=true&format="+url.QueryEscape(format), contentType, r, &plan)
+	return plan, err
+}
+
+// upload streams r as the body of POST path and decodes the response into out
+func (c *Client) upload(ctx context.Context, path, contentType string, r io.Reader, out interface{}) error {
+	req, err := c.newRequest(ctx, "POST", path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := c.stream().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		msg := resp.Status
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error != "" {
+			msg = e.Error
+		}
+		return &Error{StatusCode: resp.StatusCode, Message: msg}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
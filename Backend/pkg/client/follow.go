@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"salmanahmed/blockchain/pkg/api"
+)
+
+// followRetry is how long FollowChain waits before reconnecting
+const followRetry = 2 * time.Second
+
+// FollowChain mirrors the chain from block fromHeight, or right after the
+// block resume names when it isn't empty: historical blocks first, then a
+// live message, then new blocks as they land. Rollback messages ask the
+// caller to drop the blocks above their height. When the connection drops
+// it reconnects with the last resume token, so nothing is missed or sent
+// twice; the channel is closed once ctx ends.
+func (c *Client) FollowChain(ctx context.Context, fromHeight int, resume string) (<-chan api.FollowMessage, error) {
+	path := func() string {
+		if resume != "" {
+			return "/follow?resume=" + url.QueryEscape(resume)
+		}
+		return "/follow?from_height=" + strconv.Itoa(fromHeight)
+	}
+	conn, done, err := c.dialWS(ctx, path())
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan api.FollowMessage, 16)
+	go func() {
+		defer close(out)
+		for {
+			for {
+				var m api.FollowMessage
+				if err := conn.ReadJSON(&m); err != nil {
+					break
+				}
+				switch {
+				case m.Type == api.FollowRollback && m.Resume == "":
+					resume, fromHeight = "", 0 // rolled back past genesis: replay it all
+				case m.Resume != "":
+					resume = m.Resume
+				}
+				select {
+				case out <- m:
+				case <-ctx.Done():
+				}
+			}
+			close(done)
+			conn.Close()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(followRetry):
+				}
+				if conn, done, err = c.dialWS(ctx, path()); err == nil {
+					break
+				}
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/websocket"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+// logQuery encodes a filter as /logs query parameters
+func logQuery(f blockchain.LogFilter) string {
+	q := url.Values{}
+	if f.Address != "" {
+		q.Set("address", f.Address)
+	}
+	if f.Event != "" {
+		q.Set("event", f.Event)
+	}
+	if f.FromBlock > 0 {
+		q.Set("from_block", strconv.Itoa(f.FromBlock))
+	}
+	if f.ToBlock > 0 {
+		q.Set("to_block", strconv.Itoa(f.ToBlock))
+	}
+	if len(q) == 0 {
+		return ""
+	}
+	return "?" + q.Encode()
+}
+
+// Logs returns the confirmed logs matching f
+func (c *Client) Logs(ctx context.Context, f blockchain.LogFilter) ([]blockchain.Log, error) {
+	var out []blockchain.Log
+	err := c.do(ctx, "GET", "/logs"+logQuery(f), nil, &out)
+	return out, err
+}
+
+// SubscribeLogs streams newly confirmed logs matching f over a WebSocket
+// until ctx ends or the connection drops; the channel is closed in either case
+func (c *Client) SubscribeLogs(ctx context.Context, f blockchain.LogFilter) (<-chan blockchain.Log, error) {
+	conn, done, err := c.dialWS(ctx, "/logs/ws"+logQuery(f))
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan blockchain.Log, 16)
+	go func() {
+		defer close(out)
+		defer close(done)
+		defer conn.Close()
+		for {
+			var l blockchain.Log
+			if err := conn.ReadJSON(&l); err != nil {
+				return
+			}
+			select {
+			case out <- l:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// dialWS opens a WebSocket to path that is closed once ctx ends; the caller
+// closes done when it stops reading
+func (c *Client) dialWS(ctx context.Context, path string) (conn *websocket.Conn, done chan struct{}, err error) {
+	wsURL := "ws" + strings.TrimPrefix(c.baseURL, "http") + path
+	conn, _, err = websocket.DefaultDialer.DialContext(ctx, wsURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	done = make(chan struct{})
+	go func() {
+		// unblock reads when ctx ends
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	return conn, done, nil
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"salmanahmed/blockchain/pkg/events"
+)
+
+// Subscribe streams the node's events until ctx ends or the connection
+// drops; the channel is closed in either case
+func (c *Client) Subscribe(ctx context.Context) (<-chan events.Event, error) {
+	req, err := c.newRequest(ctx, "GET", "/events", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	// the stream is long-lived, so don't apply the request timeout
+	stream := *c.http
+	stream.Timeout = 0
+	resp, err := stream.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &Error{StatusCode: resp.StatusCode, Message: resp.Status}
+	}
+	out := make(chan events.Event, 16)
+	go func() {
+		defer close(out)
+		defer resp.Body.Close()
+		sc := bufio.NewScanner(resp.Body)
+		sc.Buffer(make([]byte, 64*1024), 1<<20)
+		for sc.Scan() {
+			line := sc.Text()
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+			var e events.Event
+			if json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e) != nil {
+				continue
+			}
+			select {
+			case out <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"salmanahmed/blockchain/pkg/api"
+)
+
+// AddWatch registers a watch on an address or txid and returns it with its ID
+func (c *Client) AddWatch(ctx context.Context, w api.Watch) (api.Watch, error) {
+	var out api.Watch
+	err := c.do(ctx, "POST", "/watch", w, &out)
+	return out, err
+}
+
+// Watches lists the registered watches
+func (c *Client) Watches(ctx context.Context) ([]api.Watch, error) {
+	var out []api.Watch
+	err := c.do(ctx, "GET", "/watch", nil, &out)
+	return out, err
+}
+
+// RemoveWatch unregisters a watch
+func (c *Client) RemoveWatch(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", "/watch/"+url.PathEscape(id), nil, nil)
+}
+
+// SubscribeWatches streams notifications for the watches ids, or for every
+// watch when none are given, until ctx ends or the connection drops; the
+// channel is closed in either case
+func (c *Client) SubscribeWatches(ctx context.Context, ids ...string) (<-chan api.WatchNotification, error) {
+	path := "/watch/ws"
+	if len(ids) > 0 {
+		path += "?" + url.Values{"id": ids}.Encode()
+	}
+	conn, done, err := c.dialWS(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan api.WatchNotification, 16)
+	go func() {
+		defer close(out)
+		defer close(done)
+		defer conn.Close()
+		for {
+			var n api.WatchNotification
+			if err := conn.ReadJSON(&n); err != nil {
+				return
+			}
+			select {
+			case out <- n:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
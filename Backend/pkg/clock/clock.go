@@ -0,0 +1,62 @@
+// Package clock abstracts the current time so time-dependent behaviour
+// (block timestamps, TTLs, retargeting) can be tested deterministically.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the wall clock
+type System struct{}
+
+// Now implements Clock
+func (System) Now() time.Time { return time.Now() }
+
+// Real is the shared wall clock
+var Real Clock = System{}
+
+// Or returns c, or Real when c is nil
+func Or(c Clock) Clock {
+	if c == nil {
+		return Real
+	}
+	return c
+}
+
+// Mock is a manually driven clock for tests and simulations
+type Mock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewMock returns a mock clock stopped at t
+func NewMock(t time.Time) *Mock {
+	return &Mock{now: t}
+}
+
+// Now implements Clock
+func (m *Mock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+// Advance moves the clock forward by d
+func (m *Mock) Advance(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = m.now.Add(d)
+}
+
+// Set moves the clock to t
+func (m *Mock) Set(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = t
+}
@@ -0,0 +1,765 @@
+// Package config loads node settings from defaults, an optional named
+// profile, an optional YAML or TOML file, BLOCKCHAIN_* environment variables
+// and command-line flags, in that order of increasing precedence.
+package config
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is prepended to every environment variable name
+const EnvPrefix = "BLOCKCHAIN_"
+
+// maxNodeID bounds node_id, which every block the node mines carries
+const maxNodeID = 64
+
+// Config is the full node configuration
+type Config struct {
+	Profile     string        `yaml:"profile" toml:"profile"`         // built-in settings the rest override: dev, classroom, benchmark or public-demo
+	PublicDemo  bool          `yaml:"public_demo" toml:"public_demo"` // shorthand for the public-demo profile
+	Port        int           `yaml:"port" toml:"port"`
+	Difficulty  int           `yaml:"difficulty" toml:"difficulty"`     // leading zeros required
+	BlockTime   time.Duration `yaml:"block_time" toml:"block_time"`     // target interval between blocks
+	DataDir     string        `yaml:"data_dir" toml:"data_dir"`         // where the chain is stored; empty keeps it in memory
+	CORSOrigins []string      `yaml:"cors_origins" toml:"cors_origins"` // "*" allows any origin
+	AuthToken   string        `yaml:"auth_token" toml:"auth_token"`     // bearer token for writes; empty disables auth
+	Peers       []string      `yaml:"peers" toml:"peers"`               // seed peer URLs
+	NodeID      string        `yaml:"node_id" toml:"node_id"`           // recorded as the producer of blocks this node mines; empty is host:port
+	Upstream    string        `yaml:"upstream" toml:"upstream"`         // primary node URL to follow as a read-only replica; empty runs a primary
+	FollowEvery time.Duration `yaml:"follow_every" toml:"follow_every"` // how often a replica polls its upstream
+	Consensus   string        `yaml:"consensus" toml:"consensus"`       // consensus mode, currently "pow"
+	Debug       bool          `yaml:"debug" toml:"debug"`               // check invariants after every write
+	Chaos       bool          `yaml:"chaos" toml:"chaos"`               // allow fault injection through /admin/chaos
+	Demo        bool          `yaml:"demo" toml:"demo"`                 // allow editing confirmed blocks through /tamper, for teaching
+	Chains      []string      `yaml:"chains" toml:"chains"`             // extra chains served under /chains/{id}/
+	Import      string        `yaml:"import" toml:"import"`             // ndjson export to rebuild the default chain from at startup
+	Snapshot    string        `yaml:"snapshot" toml:"snapshot"`         // chain snapshot to restore the default chain and mempool from at startup
+	BlobStore   string        `yaml:"blob_store" toml:"blob_store"`     // directory or s3://bucket/prefix for off-chain payloads; empty disables
+	S3Endpoint  string        `yaml:"s3_endpoint" toml:"s3_endpoint"`   // S3-compatible endpoint for an s3:// blob store
+	S3Region    string        `yaml:"s3_region" toml:"s3_region"`
+
+	// what to do with a stored chain failing verification at startup:
+	// refuse to start, truncate it before the first bad block, or restore
+	// snapshot in its place
+	OnCorruption string `yaml:"on_corruption" toml:"on_corruption"`
+
+	AutoDifficulty bool `yaml:"auto_difficulty" toml:"auto_difficulty"` // measure the hashrate at startup and pick the difficulty that hits block_time
+	RetargetBlocks int  `yaml:"retarget_blocks" toml:"retarget_blocks"` // adjust the difficulty toward block_time every N blocks; 0 keeps it fixed
+	MineWorkers    int  `yaml:"mine_workers" toml:"mine_workers"`       // goroutines searching nonces per block; 0 is one per CPU
+
+	// difficulties POST /mine?difficulty= may mine a block at instead of
+	// the one in force; a max of 0 allows no overrides
+	MineDifficultyMin int `yaml:"mine_difficulty_min" toml:"mine_difficulty_min"`
+	MineDifficultyMax int `yaml:"mine_difficulty_max" toml:"mine_difficulty_max"`
+
+	BlockReward int64         `yaml:"block_reward" toml:"block_reward"` // coins a block's coinbase may pay its miner; 0 disables rewards
+	MineEvery   time.Duration `yaml:"mine_every" toml:"mine_every"`     // mine a block, empty or not, on this schedule; 0 mines only on request
+	MineAddress string        `yaml:"mine_address" toml:"mine_address"` // address scheduled blocks pay block_reward to; empty mines them unrewarded
+
+	MaxPending   int `yaml:"max_pending" toml:"max_pending"`       // transactions each chain's mempool holds; 0 is unlimited
+	MaxBlockTxns int `yaml:"max_block_txns" toml:"max_block_txns"` // pending transactions a mined block takes, highest fee rate first; 0 takes them all
+
+	MaxTxBytes    int           `yaml:"max_tx_bytes" toml:"max_tx_bytes"`       // largest transaction accepted, as JSON; 0 is unlimited
+	MaxBlockBytes int           `yaml:"max_block_bytes" toml:"max_block_bytes"` // transaction bytes a mined block takes; 0 is unlimited
+	PendingTTL    time.Duration `yaml:"pending_ttl" toml:"pending_ttl"`         // drop transactions pending longer than this; 0 keeps them
+	ResetEvery    time.Duration `yaml:"reset_every" toml:"reset_every"`         // wipe the chain back to genesis on this schedule; 0 never does
+
+	RatePerIP     int  `yaml:"rate_per_ip" toml:"rate_per_ip"`         // requests a minute per client IP, the auth token aside; 0 is unlimited
+	ReadOnlyAdmin bool `yaml:"read_only_admin" toml:"read_only_admin"` // refuse admin writes without the auth token, even when none is set
+
+	FaucetKey      string        `yaml:"faucet_key" toml:"faucet_key"` // hex private key of a funded account; enables POST /faucet
+	FaucetAmount   int64         `yaml:"faucet_amount" toml:"faucet_amount"`
+	FaucetCooldown time.Duration `yaml:"faucet_cooldown" toml:"faucet_cooldown"` // per address and per IP
+
+	AttestKey   string `yaml:"attest_key" toml:"attest_key"`     // hex private key signing finality attestations; empty disables them
+	AttestEvery int    `yaml:"attest_every" toml:"attest_every"` // blocks between attestations
+	AttestDepth int    `yaml:"attest_depth" toml:"attest_depth"` // confirmations before a block is attested
+
+	// publish the tip hash off the node every notarize_every: anchored on
+	// another node's chain as a data transaction, and/or POSTed to a webhook
+	NotarizeNode    string        `yaml:"notarize_node" toml:"notarize_node"`
+	NotarizeToken   string        `yaml:"notarize_token" toml:"notarize_token"` // bearer token for notarize_node
+	NotarizeKey     string        `yaml:"notarize_key" toml:"notarize_key"`     // hex private key signing anchors, for a notarize_node requiring signatures
+	NotarizeWebhook string        `yaml:"notarize_webhook" toml:"notarize_webhook"`
+	NotarizeEvery   time.Duration `yaml:"notarize_every" toml:"notarize_every"`
+
+	SubmissionDeadline string `yaml:"submission_deadline" toml:"submission_deadline"` // RFC 3339 time after which new transactions are refused; empty never closes
+
+	// requests in progress at once and how long each may take, per class; 0 is unlimited
+	ReadConcurrency  int           `yaml:"read_concurrency" toml:"read_concurrency"`
+	ReadTimeout      time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	MineConcurrency  int           `yaml:"mine_concurrency" toml:"mine_concurrency"` // mining jobs, queued ones included
+	MineTimeout      time.Duration `yaml:"mine_timeout" toml:"mine_timeout"`
+	AdminConcurrency int           `yaml:"admin_concurrency" toml:"admin_concurrency"`
+	AdminTimeout     time.Duration `yaml:"admin_timeout" toml:"admin_timeout"`
+
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"` // how long a stopping node lets in-flight requests and mining finish; 0 aborts them at once
+
+	// every transaction but a coinbase in blocks from signatures_from_height
+	// on must carry an ECDSA signature (see Chain.RequireSignatures)
+	RequireSignatures bool `yaml:"require_signatures" toml:"require_signatures"`
+	SignaturesFrom    int  `yaml:"signatures_from_height" toml:"signatures_from_height"`
+
+	Validators       []string `yaml:"validators" toml:"validators"`               // built-in tx validators, e.g. "student-id"
+	ValidatorPlugins []string `yaml:"validator_plugins" toml:"validator_plugins"` // Go plugin files exporting Validate
+	ValidatorWASM    []string `yaml:"validator_wasm" toml:"validator_wasm"`       // WebAssembly modules exporting alloc and validate
+}
+
+// Default returns the settings the node used before it was configurable
+func Default() Config {
+	return Config{
+		Port:        8080,
+		Difficulty:  3,
+		BlockTime:   10 * time.Second,
+		CORSOrigins: []string{"*"},
+		Consensus:   "pow",
+		S3Region:    "us-east-1",
+
+		OnCorruption: "refuse",
+
+		FaucetAmount:   10,
+		FaucetCooldown: time.Hour,
+
+		AttestEvery: 10,
+		AttestDepth: 6,
+
+		NotarizeEvery: time.Minute,
+
+		FollowEvery: 2 * time.Second,
+
+		ReadConcurrency:  256,
+		ReadTimeout:      30 * time.Second,
+		MineConcurrency:  4,
+		AdminConcurrency: 8,
+
+		ShutdownTimeout: 5 * time.Second,
+	}
+}
+
+// SignaturesRequired reports whether require_signatures is on, directly or
+// through the deprecated "signed" validator it replaced
+func (c Config) SignaturesRequired() bool {
+	for _, v := range c.Validators {
+		if v == "signed" {
+			return true
+		}
+	}
+	return c.RequireSignatures
+}
+
+// LoadFile merges a YAML (.yaml/.yml) or TOML (.toml) file over c
+func (c *Config) LoadFile(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, c)
+	case ".toml":
+		err = toml.Unmarshal(raw, c)
+	default:
+		return fmt.Errorf("config: unsupported file type %q (want .yaml, .yml or .toml)", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	return nil
+}
+
+// ApplyEnv overrides c with any BLOCKCHAIN_* variables that are set
+func (c *Config) ApplyEnv() error {
+	var err error
+	env := func(name string, apply func(string) error) {
+		if v, ok := os.LookupEnv(EnvPrefix + name); ok && err == nil {
+			if e := apply(v); e != nil {
+				err = fmt.Errorf("config: %s%s: %w", EnvPrefix, name, e)
+			}
+		}
+	}
+	env("PROFILE", stringVar(&c.Profile))
+	env("PUBLIC_DEMO", boolVar(&c.PublicDemo))
+	env("PORT", intVar(&c.Port))
+	env("DIFFICULTY", intVar(&c.Difficulty))
+	env("BLOCK_TIME", durationVar(&c.BlockTime))
+	env("AUTO_DIFFICULTY", boolVar(&c.AutoDifficulty))
+	env("RETARGET_BLOCKS", intVar(&c.RetargetBlocks))
+	env("MINE_WORKERS", intVar(&c.MineWorkers))
+	env("MINE_DIFFICULTY_MIN", intVar(&c.MineDifficultyMin))
+	env("MINE_DIFFICULTY_MAX", intVar(&c.MineDifficultyMax))
+	env("BLOCK_REWARD", int64Var(&c.BlockReward))
+	env("MINE_EVERY", durationVar(&c.MineEvery))
+	env("MINE_ADDRESS", stringVar(&c.MineAddress))
+	env("MAX_PENDING", intVar(&c.MaxPending))
+	env("MAX_BLOCK_TXNS", intVar(&c.MaxBlockTxns))
+	env("MAX_TX_BYTES", intVar(&c.MaxTxBytes))
+	env("MAX_BLOCK_BYTES", intVar(&c.MaxBlockBytes))
+	env("PENDING_TTL", durationVar(&c.PendingTTL))
+	env("RESET_EVERY", durationVar(&c.ResetEvery))
+	env("RATE_PER_IP", intVar(&c.RatePerIP))
+	env("READ_ONLY_ADMIN", boolVar(&c.ReadOnlyAdmin))
+	env("FAUCET_KEY", stringVar(&c.FaucetKey))
+	env("FAUCET_AMOUNT", int64Var(&c.FaucetAmount))
+	env("FAUCET_COOLDOWN", durationVar(&c.FaucetCooldown))
+	env("ATTEST_KEY", stringVar(&c.AttestKey))
+	env("ATTEST_EVERY", intVar(&c.AttestEvery))
+	env("ATTEST_DEPTH", intVar(&c.AttestDepth))
+	env("NOTARIZE_NODE", stringVar(&c.NotarizeNode))
+	env("NOTARIZE_TOKEN", stringVar(&c.NotarizeToken))
+	env("NOTARIZE_KEY", stringVar(&c.NotarizeKey))
+	env("NOTARIZE_WEBHOOK", stringVar(&c.NotarizeWebhook))
+	env("NOTARIZE_EVERY", durationVar(&c.NotarizeEvery))
+	env("SUBMISSION_DEADLINE", stringVar(&c.SubmissionDeadline))
+	env("READ_CONCURRENCY", intVar(&c.ReadConcurrency))
+	env("READ_TIMEOUT", durationVar(&c.ReadTimeout))
+	env("MINE_CONCURRENCY", intVar(&c.MineConcurrency))
+	env("MINE_TIMEOUT", durationVar(&c.MineTimeout))
+	env("ADMIN_CONCURRENCY", intVar(&c.AdminConcurrency))
+	env("ADMIN_TIMEOUT", durationVar(&c.AdminTimeout))
+	env("SHUTDOWN_TIMEOUT", durationVar(&c.ShutdownTimeout))
+	env("DATA_DIR", stringVar(&c.DataDir))
+	env("CORS_ORIGINS", listVar(&c.CORSOrigins))
+	env("AUTH_TOKEN", stringVar(&c.AuthToken))
+	env("PEERS", listVar(&c.Peers))
+	env("NODE_ID", stringVar(&c.NodeID))
+	env("UPSTREAM", stringVar(&c.Upstream))
+	env("FOLLOW_EVERY", durationVar(&c.FollowEvery))
+	env("CONSENSUS", stringVar(&c.Consensus))
+	env("DEBUG", boolVar(&c.Debug))
+	env("CHAOS", boolVar(&c.Chaos))
+	env("DEMO", boolVar(&c.Demo))
+	env("CHAINS", listVar(&c.Chains))
+	env("IMPORT", stringVar(&c.Import))
+	env("SNAPSHOT", stringVar(&c.Snapshot))
+	env("ON_CORRUPTION", stringVar(&c.OnCorruption))
+	env("BLOB_STORE", stringVar(&c.BlobStore))
+	env("S3_ENDPOINT", stringVar(&c.S3Endpoint))
+	env("S3_REGION", stringVar(&c.S3Region))
+	env("REQUIRE_SIGNATURES", boolVar(&c.RequireSignatures))
+	env("SIGNATURES_FROM_HEIGHT", intVar(&c.SignaturesFrom))
+	env("VALIDATORS", listVar(&c.Validators))
+	env("VALIDATOR_PLUGINS", listVar(&c.ValidatorPlugins))
+	env("VALIDATOR_WASM", listVar(&c.ValidatorWASM))
+	return err
+}
+
+// RegisterFlags adds a flag per setting to fs, defaulting to Default()
+func RegisterFlags(fs *pflag.FlagSet) {
+	d := Default()
+	fs.String("config", "", "YAML or TOML config file (env "+EnvPrefix+"CONFIG)")
+	fs.String("profile", d.Profile, "built-in settings to start from, overridden by the config file, env and flags: "+profileHelp())
+	fs.Bool("public-demo", d.PublicDemo, "start from the public-demo profile: per-IP rate limits, read-only admin, small transactions and blocks, mempool expiry and a daily reset, for exposing the node to the internet")
+	fs.Int("port", d.Port, "HTTP listen port")
+	fs.Int("difficulty", d.Difficulty, "leading zeros required in block hashes")
+	fs.Duration("block-time", d.BlockTime, "target interval between blocks")
+	fs.Bool("auto-difficulty", d.AutoDifficulty, "measure this host's hashrate and pick the difficulty that mines a block every --block-time")
+	fs.Int("retarget-blocks", d.RetargetBlocks, "adjust the difficulty every N blocks toward one block per --block-time; 0 keeps it fixed")
+	fs.Int("mine-workers", d.MineWorkers, "goroutines searching nonces in parallel for each block; 0 is one per CPU")
+	fs.Int("mine-difficulty-min", d.MineDifficultyMin, "lowest difficulty POST /mine?difficulty= may mine a block at")
+	fs.Int("mine-difficulty-max", d.MineDifficultyMax, "highest difficulty POST /mine?difficulty= may mine a block at; 0 allows no overrides")
+	fs.Int64("block-reward", d.BlockReward, "coins paid to the miner of each block mined with --miner; 0 disables rewards")
+	fs.Duration("mine-every", d.MineEvery, "mine a block on this schedule, even an empty one; 0 mines only on request")
+	fs.String("mine-address", d.MineAddress, "address the blocks mined by --mine-every pay --block-reward to")
+	fs.Int("max-pending", d.MaxPending, "transactions the mempool holds before refusing more; 0 is unlimited")
+	fs.Int("max-block-txns", d.MaxBlockTxns, "pending transactions each block takes, highest fee rate first, leaving the rest pending; 0 takes them all")
+	fs.Int("max-tx-bytes", d.MaxTxBytes, "largest transaction accepted, in bytes of JSON; 0 is unlimited")
+	fs.Int("max-block-bytes", d.MaxBlockBytes, "transaction bytes each block takes, leaving the rest pending; 0 is unlimited")
+	fs.Duration("pending-ttl", d.PendingTTL, "drop transactions pending longer than this; 0 keeps them until mined")
+	fs.Duration("reset-every", d.ResetEvery, "wipe the chain back to genesis on this schedule, e.g. 24h for a public demo; 0 never does")
+	fs.Int("rate-per-ip", d.RatePerIP, "requests a minute each client IP may make, more get 429; the auth token is exempt (0 is unlimited)")
+	fs.Bool("read-only-admin", d.ReadOnlyAdmin, "refuse admin writes, such as /admin/*, /import and /tamper, to callers without the auth token, even when none is set")
+	fs.String("faucet-key", d.FaucetKey, "private key of a funded account to serve POST /faucet from")
+	fs.Int64("faucet-amount", d.FaucetAmount, "coins the faucet sends per request")
+	fs.Duration("faucet-cooldown", d.FaucetCooldown, "how long an address or IP waits between faucet payouts")
+	fs.String("attest-key", d.AttestKey, "private key to sign finality attestations with, served at GET /attestations")
+	fs.Int("attest-every", d.AttestEvery, "blocks between finality attestations")
+	fs.Int("attest-depth", d.AttestDepth, "confirmations a block needs before it is attested as final")
+	fs.String("notarize-node", d.NotarizeNode, "URL of another node to anchor the tip hash on as a data transaction, so rewrites of this chain can be shown")
+	fs.String("notarize-token", d.NotarizeToken, "bearer token for --notarize-node")
+	fs.String("notarize-key", d.NotarizeKey, "hex private key signing anchors, for a --notarize-node requiring signatures")
+	fs.String("notarize-webhook", d.NotarizeWebhook, "URL to POST the tip hash to as JSON, recording the reply")
+	fs.Duration("notarize-every", d.NotarizeEvery, "how often to notarize the tip, when it has moved")
+	fs.Int("read-concurrency", d.ReadConcurrency, "requests other than mining and admin served at once; more get 503 (0 is unlimited)")
+	fs.Duration("read-timeout", d.ReadTimeout, "answer those requests 503 after this long (0 waits forever)")
+	fs.Int("mine-concurrency", d.MineConcurrency, "mining jobs, queued ones included, at once across all chains; more get 503 (0 is unlimited)")
+	fs.Duration("mine-timeout", d.MineTimeout, "fail a mining job after this long (0 waits forever)")
+	fs.Int("admin-concurrency", d.AdminConcurrency, "/admin, /import and /peers/sync requests served at once; more get 503 (0 is unlimited)")
+	fs.Duration("admin-timeout", d.AdminTimeout, "answer those requests 503 after this long (0 waits forever)")
+	fs.Duration("shutdown-timeout", d.ShutdownTimeout, "on SIGINT or SIGTERM, let in-flight requests and mining jobs finish for this long before aborting them (0 aborts at once)")
+	fs.String("submission-deadline", d.SubmissionDeadline, "RFC 3339 time after which new transactions are refused while mining and reads go on, e.g. 2026-05-01T23:59:00Z")
+	fs.String("datadir", d.DataDir, "directory the chain, mempool and address labels are stored in, reloaded on restart (empty keeps them in memory)")
+	fs.StringSlice("cors-origins", d.CORSOrigins, "allowed CORS origins, * for any")
+	fs.String("auth-token", d.AuthToken, "bearer token required for write endpoints")
+	fs.StringSlice("peers", d.Peers, "seed peer URLs")
+	fs.String("node-id", d.NodeID, "name recorded as the producer of every block this node mines (default host:port)")
+	fs.String("upstream", d.Upstream, "run as a read-only replica of the node at this URL, redirecting writes to it")
+	fs.Duration("follow-every", d.FollowEvery, "how often a replica polls --upstream for new blocks and pending transactions")
+	fs.String("consensus", d.Consensus, "consensus mode (pow)")
+	fs.Bool("debug", d.Debug, "check internal invariants after every write")
+	fs.Bool("chaos", d.Chaos, "allow runtime fault injection through /admin/chaos")
+	fs.Bool("demo", d.Demo, "allow tampering with confirmed blocks through /tamper to demonstrate validation")
+	fs.StringSlice("chains", d.Chains, "IDs of extra chains to host under /chains/{id}/")
+	fs.String("import", d.Import, "newline-delimited export to rebuild the default chain from at startup")
+	fs.String("snapshot", d.Snapshot, "JSON chain snapshot (node chain export --snapshot) to restore the default chain and mempool from at startup")
+	fs.String("on-corruption", d.OnCorruption, "if the stored chain fails verification at startup: refuse to start, truncate it before the first bad block, or restore --snapshot")
+	fs.String("blob-store", d.BlobStore, "directory or s3://bucket/prefix holding off-chain payloads")
+	fs.String("s3-endpoint", d.S3Endpoint, "S3-compatible endpoint URL for an s3:// blob store")
+	fs.String("s3-region", d.S3Region, "region for an s3:// blob store")
+	fs.Bool("require-signatures", d.RequireSignatures, "refuse transactions without an ECDSA signature in blocks from --signatures-from-height on")
+	fs.Int("signatures-from-height", d.SignaturesFrom, "first block height --require-signatures applies to; set it above the tip of a chain with unsigned history")
+	fs.StringSlice("validators", d.Validators, "built-in transaction validators to enforce")
+	fs.StringSlice("validator-plugins", d.ValidatorPlugins, "Go plugin files providing transaction validators")
+	fs.StringSlice("validator-wasm", d.ValidatorWASM, "WebAssembly modules run on every transaction to accept or reject it")
+}
+
+// ApplyFlags overrides c with flags the user set explicitly
+func (c *Config) ApplyFlags(fs *pflag.FlagSet) {
+	changed := func(name string) bool { return fs.Changed(name) }
+	if changed("profile") {
+		c.Profile, _ = fs.GetString("profile")
+	}
+	if changed("public-demo") {
+		c.PublicDemo, _ = fs.GetBool("public-demo")
+	}
+	if changed("port") {
+		c.Port, _ = fs.GetInt("port")
+	}
+	if changed("difficulty") {
+		c.Difficulty, _ = fs.GetInt("difficulty")
+	}
+	if changed("block-time") {
+		c.BlockTime, _ = fs.GetDuration("block-time")
+	}
+	if changed("auto-difficulty") {
+		c.AutoDifficulty, _ = fs.GetBool("auto-difficulty")
+	}
+	if changed("retarget-blocks") {
+		c.RetargetBlocks, _ = fs.GetInt("retarget-blocks")
+	}
+	if changed("mine-workers") {
+		c.MineWorkers, _ = fs.GetInt("mine-workers")
+	}
+	if changed("mine-difficulty-min") {
+		c.MineDifficultyMin, _ = fs.GetInt("mine-difficulty-min")
+	}
+	if changed("mine-difficulty-max") {
+		c.MineDifficultyMax, _ = fs.GetInt("mine-difficulty-max")
+	}
+	if changed("block-reward") {
+		c.BlockReward, _ = fs.GetInt64("block-reward")
+	}
+	if changed("mine-every") {
+		c.MineEvery, _ = fs.GetDuration("mine-every")
+	}
+	if changed("mine-address") {
+		c.MineAddress, _ = fs.GetString("mine-address")
+	}
+	if changed("max-pending") {
+		c.MaxPending, _ = fs.GetInt("max-pending")
+	}
+	if changed("max-block-txns") {
+		c.MaxBlockTxns, _ = fs.GetInt("max-block-txns")
+	}
+	if changed("max-tx-bytes") {
+		c.MaxTxBytes, _ = fs.GetInt("max-tx-bytes")
+	}
+	if changed("max-block-bytes") {
+		c.MaxBlockBytes, _ = fs.GetInt("max-block-bytes")
+	}
+	if changed("pending-ttl") {
+		c.PendingTTL, _ = fs.GetDuration("pending-ttl")
+	}
+	if changed("reset-every") {
+		c.ResetEvery, _ = fs.GetDuration("reset-every")
+	}
+	if changed("rate-per-ip") {
+		c.RatePerIP, _ = fs.GetInt("rate-per-ip")
+	}
+	if changed("read-only-admin") {
+		c.ReadOnlyAdmin, _ = fs.GetBool("read-only-admin")
+	}
+	if changed("faucet-key") {
+		c.FaucetKey, _ = fs.GetString("faucet-key")
+	}
+	if changed("faucet-amount") {
+		c.FaucetAmount, _ = fs.GetInt64("faucet-amount")
+	}
+	if changed("faucet-cooldown") {
+		c.FaucetCooldown, _ = fs.GetDuration("faucet-cooldown")
+	}
+	if changed("attest-key") {
+		c.AttestKey, _ = fs.GetString("attest-key")
+	}
+	if changed("attest-every") {
+		c.AttestEvery, _ = fs.GetInt("attest-every")
+	}
+	if changed("attest-depth") {
+		c.AttestDepth, _ = fs.GetInt("attest-depth")
+	}
+	if changed("notarize-node") {
+		c.NotarizeNode, _ = fs.GetString("notarize-node")
+	}
+	if changed("notarize-token") {
+		c.NotarizeToken, _ = fs.GetString("notarize-token")
+	}
+	if changed("notarize-key") {
+		c.NotarizeKey, _ = fs.GetString("notarize-key")
+	}
+	if changed("notarize-webhook") {
+		c.NotarizeWebhook, _ = fs.GetString("notarize-webhook")
+	}
+	if changed("notarize-every") {
+		c.NotarizeEvery, _ = fs.GetDuration("notarize-every")
+	}
+	if changed("submission-deadline") {
+		c.SubmissionDeadline, _ = fs.GetString("submission-deadline")
+	}
+	if changed("read-concurrency") {
+		c.ReadConcurrency, _ = fs.GetInt("read-concurrency")
+	}
+	if changed("read-timeout") {
+		c.ReadTimeout, _ = fs.GetDuration("read-timeout")
+	}
+	if changed("mine-concurrency") {
+		c.MineConcurrency, _ = fs.GetInt("mine-concurrency")
+	}
+	if changed("mine-timeout") {
+		c.MineTimeout, _ = fs.GetDuration("mine-timeout")
+	}
+	if changed("admin-concurrency") {
+		c.AdminConcurrency, _ = fs.GetInt("admin-concurrency")
+	}
+	if changed("admin-timeout") {
+		c.AdminTimeout, _ = fs.GetDuration("admin-timeout")
+	}
+	if changed("shutdown-timeout") {
+		c.ShutdownTimeout, _ = fs.GetDuration("shutdown-timeout")
+	}
+	if changed("datadir") {
+		c.DataDir, _ = fs.GetString("datadir")
+	}
+	if changed("cors-origins") {
+		c.CORSOrigins, _ = fs.GetStringSlice("cors-origins")
+	}
+	if changed("auth-token") {
+		c.AuthToken, _ = fs.GetString("auth-token")
+	}
+	if changed("peers") {
+		c.Peers, _ = fs.GetStringSlice("peers")
+	}
+	if changed("node-id") {
+		c.NodeID, _ = fs.GetString("node-id")
+	}
+	if changed("upstream") {
+		c.Upstream, _ = fs.GetString("upstream")
+	}
+	if changed("follow-every") {
+		c.FollowEvery, _ = fs.GetDuration("follow-every")
+	}
+	if changed("consensus") {
+		c.Consensus, _ = fs.GetString("consensus")
+	}
+	if changed("debug") {
+		c.Debug, _ = fs.GetBool("debug")
+	}
+	if changed("chaos") {
+		c.Chaos, _ = fs.GetBool("chaos")
+	}
+	if changed("demo") {
+		c.Demo, _ = fs.GetBool("demo")
+	}
+	if changed("chains") {
+		c.Chains, _ = fs.GetStringSlice("chains")
+	}
+	if changed("import") {
+		c.Import, _ = fs.GetString("import")
+	}
+	if changed("snapshot") {
+		c.Snapshot, _ = fs.GetString("snapshot")
+	}
+	if changed("on-corruption") {
+		c.OnCorruption, _ = fs.GetString("on-corruption")
+	}
+	if changed("blob-store") {
+		c.BlobStore, _ = fs.GetString("blob-store")
+	}
+	if changed("s3-endpoint") {
+		c.S3Endpoint, _ = fs.GetString("s3-endpoint")
+	}
+	if changed("s3-region") {
+		c.S3Region, _ = fs.GetString("s3-region")
+	}
+	if changed("require-signatures") {
+		c.RequireSignatures, _ = fs.GetBool("require-signatures")
+	}
+	if changed("signatures-from-height") {
+		c.SignaturesFrom, _ = fs.GetInt("signatures-from-height")
+	}
+	if changed("validators") {
+		c.Validators, _ = fs.GetStringSlice("validators")
+	}
+	if changed("validator-plugins") {
+		c.ValidatorPlugins, _ = fs.GetStringSlice("validator-plugins")
+	}
+	if changed("validator-wasm") {
+		c.ValidatorWASM, _ = fs.GetStringSlice("validator-wasm")
+	}
+}
+
+// Load builds the configuration from defaults, profile, file, env and
+// flags. The profile is named by --profile, else BLOCKCHAIN_PROFILE, else
+// the file's profile setting; public_demo, set anywhere, names public-demo.
+func Load(fs *pflag.FlagSet) (Config, error) {
+	c := Default()
+	path, _ := fs.GetString("config")
+	if path == "" {
+		path = os.Getenv(EnvPrefix + "CONFIG")
+	}
+	var file Config
+	if path != "" {
+		if err := file.LoadFile(path); err != nil {
+			return c, err
+		}
+	}
+	name := file.Profile
+	if v, ok := os.LookupEnv(EnvPrefix + "PROFILE"); ok {
+		name = v
+	}
+	if fs.Changed("profile") {
+		name, _ = fs.GetString("profile")
+	}
+	demo := file.PublicDemo
+	if v, ok := os.LookupEnv(EnvPrefix + "PUBLIC_DEMO"); ok {
+		demo, _ = strconv.ParseBool(v)
+	}
+	if fs.Changed("public-demo") {
+		demo, _ = fs.GetBool("public-demo")
+	}
+	if demo {
+		if name != "" && name != publicDemo {
+			return c, fmt.Errorf("config: public_demo is the %s profile; it cannot run with the %s profile", publicDemo, name)
+		}
+		name = publicDemo
+	}
+	if err := c.ApplyProfile(name); err != nil {
+		return c, err
+	}
+	if path != "" {
+		if err := c.LoadFile(path); err != nil {
+			return c, err
+		}
+	}
+	if err := c.ApplyEnv(); err != nil {
+		return c, err
+	}
+	c.ApplyFlags(fs)
+	return c, c.Validate()
+}
+
+// Validate rejects settings the node cannot run with
+func (c Config) Validate() error {
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("config: port %d out of range", c.Port)
+	}
+	if c.Difficulty < 0 || c.Difficulty > 64 {
+		return fmt.Errorf("config: difficulty %d out of range 0-64", c.Difficulty)
+	}
+	if c.BlockTime <= 0 {
+		return fmt.Errorf("config: block_time must be positive")
+	}
+	if c.RetargetBlocks < 0 {
+		return fmt.Errorf("config: retarget_blocks must not be negative")
+	}
+	if c.MineWorkers < 0 {
+		return fmt.Errorf("config: mine_workers must not be negative")
+	}
+	if c.MineDifficultyMax != 0 {
+		if c.MineDifficultyMin < 1 || c.MineDifficultyMax < c.MineDifficultyMin || c.MineDifficultyMax > 64 {
+			return fmt.Errorf("config: mine_difficulty_min %d and mine_difficulty_max %d must be a range within 1-64", c.MineDifficultyMin, c.MineDifficultyMax)
+		}
+		if c.RetargetBlocks > 0 {
+			return fmt.Errorf("config: a retargeting chain holds every block to its difficulty; unset mine_difficulty_max or retarget_blocks")
+		}
+	}
+	if c.BlockReward < 0 {
+		return fmt.Errorf("config: block_reward must not be negative")
+	}
+	if c.SignaturesFrom < 0 {
+		return fmt.Errorf("config: signatures_from_height must not be negative")
+	}
+	if c.SignaturesFrom > 0 && !c.SignaturesRequired() {
+		return fmt.Errorf("config: signatures_from_height is set without require_signatures")
+	}
+	if c.MineEvery < 0 {
+		return fmt.Errorf("config: mine_every must not be negative")
+	}
+	if c.MineAddress != "" {
+		if _, err := hex.DecodeString(c.MineAddress); err != nil || len(c.MineAddress) != 40 {
+			return fmt.Errorf("config: mine_address must be an address, 40 hex digits")
+		}
+		if c.MineEvery == 0 {
+			return fmt.Errorf("config: mine_address is set without mine_every")
+		}
+	}
+	if c.MaxPending < 0 || c.MaxBlockTxns < 0 {
+		return fmt.Errorf("config: max_pending and max_block_txns must not be negative")
+	}
+	if c.MaxTxBytes < 0 || c.MaxBlockBytes < 0 {
+		return fmt.Errorf("config: max_tx_bytes and max_block_bytes must not be negative")
+	}
+	if c.MaxTxBytes > 0 && c.MaxBlockBytes > 0 && c.MaxTxBytes > c.MaxBlockBytes {
+		return fmt.Errorf("config: max_tx_bytes %d is over max_block_bytes %d", c.MaxTxBytes, c.MaxBlockBytes)
+	}
+	if c.PendingTTL < 0 || c.ResetEvery < 0 || c.RatePerIP < 0 {
+		return fmt.Errorf("config: pending_ttl, reset_every and rate_per_ip must not be negative")
+	}
+	if c.Upstream != "" && (c.PendingTTL > 0 || c.ResetEvery > 0) {
+		return fmt.Errorf("config: a replica mirrors its upstream; set pending_ttl and reset_every there")
+	}
+	if c.FaucetKey != "" && (c.FaucetAmount <= 0 || c.FaucetCooldown < 0) {
+		return fmt.Errorf("config: faucet_amount must be positive and faucet_cooldown not negative")
+	}
+	if c.AttestKey != "" && (c.AttestEvery < 1 || c.AttestDepth < 0) {
+		return fmt.Errorf("config: attest_every must be positive and attest_depth not negative")
+	}
+	for _, u := range []string{c.NotarizeNode, c.NotarizeWebhook} {
+		if u == "" {
+			continue
+		}
+		if p, err := url.Parse(u); err != nil || (p.Scheme != "http" && p.Scheme != "https") || p.Host == "" {
+			return fmt.Errorf("config: notarize target %q is not an http(s) URL", u)
+		}
+		if c.NotarizeEvery <= 0 {
+			return fmt.Errorf("config: notarize_every must be positive")
+		}
+	}
+	if c.ReadConcurrency < 0 || c.MineConcurrency < 0 || c.AdminConcurrency < 0 {
+		return fmt.Errorf("config: read_concurrency, mine_concurrency and admin_concurrency must not be negative")
+	}
+	if c.ReadTimeout < 0 || c.MineTimeout < 0 || c.AdminTimeout < 0 {
+		return fmt.Errorf("config: read_timeout, mine_timeout and admin_timeout must not be negative")
+	}
+	if c.ShutdownTimeout < 0 {
+		return fmt.Errorf("config: shutdown_timeout must not be negative")
+	}
+	if c.SubmissionDeadline != "" {
+		if _, err := time.Parse(time.RFC3339, c.SubmissionDeadline); err != nil {
+			return fmt.Errorf("config: submission_deadline %q is not an RFC 3339 time", c.SubmissionDeadline)
+		}
+	}
+	if c.PublicDemo && c.Profile != publicDemo {
+		return fmt.Errorf("config: public_demo is the %s profile; it cannot run with the %s profile", publicDemo, c.Profile)
+	}
+	if c.Profile != "" {
+		p, ok := LookupProfile(c.Profile)
+		if !ok {
+			return fmt.Errorf("config: unknown profile %q (want %s)", c.Profile, profileHelp())
+		}
+		if p.RequireAuth && c.AuthToken == "" {
+			return fmt.Errorf("config: the %s profile needs an auth_token", p.Name)
+		}
+	}
+	if c.Consensus != "pow" {
+		return fmt.Errorf("config: unsupported consensus mode %q", c.Consensus)
+	}
+	if len(c.NodeID) > maxNodeID || strings.ContainsAny(c.NodeID, "| \t\r\n") {
+		return fmt.Errorf("config: node_id must be at most %d characters without spaces or |", maxNodeID)
+	}
+	if c.Demo && c.Debug {
+		return fmt.Errorf("config: demo tampering breaks the invariants debug checks; set one of them")
+	}
+	if c.Import != "" && c.Snapshot != "" {
+		return fmt.Errorf("config: set import or snapshot, not both")
+	}
+	switch c.OnCorruption {
+	case "refuse", "truncate":
+	case "snapshot":
+		if c.Snapshot == "" {
+			return fmt.Errorf("config: on_corruption snapshot needs a snapshot to restore")
+		}
+	default:
+		return fmt.Errorf("config: on_corruption must be refuse, truncate or snapshot, not %q", c.OnCorruption)
+	}
+	if c.Upstream != "" {
+		if u, err := url.Parse(c.Upstream); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("config: upstream %q is not an http(s) URL", c.Upstream)
+		}
+		if c.FollowEvery <= 0 {
+			return fmt.Errorf("config: follow_every must be positive")
+		}
+		if c.MineEvery > 0 || len(c.Chains) > 0 {
+			return fmt.Errorf("config: a replica following upstream cannot mine_every or host chains")
+		}
+	}
+	return nil
+}
+
+// Addr returns the HTTP listen address
+func (c Config) Addr() string {
+	return ":" + strconv.Itoa(c.Port)
+}
+
+// Deadline returns the submission deadline, zero when there is none or it
+// doesn't parse (see Validate)
+func (c Config) Deadline() time.Time {
+	t, _ := time.Parse(time.RFC3339, c.SubmissionDeadline)
+	return t
+}
+
+func intVar(p *int) func(string) error {
+	return func(v string) (err error) {
+		*p, err = strconv.Atoi(v)
+		return err
+	}
+}
+
+func int64Var(p *int64) func(string) error {
+	return func(v string) (err error) {
+		*p, err = strconv.ParseInt(v, 10, 64)
+		return err
+	}
+}
+
+func boolVar(p *bool) func(string) error {
+	return func(v string) (err error) {
+		*p, err = strconv.ParseBool(v)
+		return err
+	}
+}
+
+func durationVar(p *time.Duration) func(string) error {
+	return func(v string) (err error) {
+		*p, err = time.ParseDuration(v)
+		return err
+	}
+}
+
+func stringVar(p *string) func(string) error {
+	return func(v string) error {
+		*p = v
+		return nil
+	}
+}
+
+func listVar(p *[]string) func(string) error {
+	return func(v string) error {
+		*p = nil
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				*p = append(*p, s)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// flags returns the node's flag set parsed from args
+func flags(t *testing.T, args ...string) *pflag.FlagSet {
+	t.Helper()
+	fs := pflag.NewFlagSet("node", pflag.ContinueOnError)
+	RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
+// writeFile writes a config file called name and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadWithoutProfile(t *testing.T) {
+	c, err := Load(flags(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := Default(); c.Profile != "" || c.Difficulty != d.Difficulty || c.Debug != d.Debug {
+		t.Fatalf("loading nothing gives %+v, want the defaults", c)
+	}
+}
+
+func TestProfileIsOverriddenByFileEnvAndFlags(t *testing.T) {
+	path := writeFile(t, "node.yaml", "profile: dev\ndifficulty: 3\nblock_time: 5s\n")
+
+	c, err := Load(flags(t, "--config", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Profile != "dev" || !c.Debug || !c.Chaos {
+		t.Fatalf("the file's dev profile wasn't applied: %+v", c)
+	}
+	if c.Difficulty != 3 || c.BlockTime != 5*time.Second {
+		t.Fatalf("the file sets difficulty 3 every 5s over the profile, got %d every %s", c.Difficulty, c.BlockTime)
+	}
+
+	t.Setenv(EnvPrefix+"DIFFICULTY", "4")
+	if c, err = Load(flags(t, "--config", path)); err != nil || c.Difficulty != 4 {
+		t.Fatalf("the environment sets difficulty 4 over the file, got %d, %v", c.Difficulty, err)
+	}
+	if c, err = Load(flags(t, "--config", path, "--difficulty", "5")); err != nil || c.Difficulty != 5 {
+		t.Fatalf("a flag sets difficulty 5 over the environment, got %d, %v", c.Difficulty, err)
+	}
+}
+
+func TestProfileIsNamedByFlagThenEnvThenFile(t *testing.T) {
+	path := writeFile(t, "node.toml", "profile = \"classroom\"\nauth_token = \"secret\"\n")
+
+	c, err := Load(flags(t, "--config", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Profile != "classroom" || c.MaxPending != 1000 || !c.Demo {
+		t.Fatalf("the file's classroom profile wasn't applied: %+v", c)
+	}
+
+	t.Setenv(EnvPrefix+"PROFILE", "dev")
+	if c, err = Load(flags(t, "--config", path)); err != nil || c.Profile != "dev" || !c.Debug {
+		t.Fatalf("the environment picks dev over the file's profile, got %q, %v", c.Profile, err)
+	}
+	if c.AuthToken != "secret" {
+		t.Fatalf("the file's auth_token is lost under the dev profile: %q", c.AuthToken)
+	}
+
+	c, err = Load(flags(t, "--config", path, "--profile", "benchmark"))
+	if err != nil || c.Profile != "benchmark" || c.MaxPending != 0 || c.Debug {
+		t.Fatalf("a flag picks benchmark over the environment's profile, got %+v, %v", c, err)
+	}
+}
+
+func TestUnknownProfileIsRejected(t *testing.T) {
+	_, err := Load(flags(t, "--profile", "prod"))
+	if err == nil || !strings.Contains(err.Error(), "dev, classroom, benchmark or public-demo") {
+		t.Fatalf("loading an unknown profile: %v", err)
+	}
+	c := Default()
+	c.Profile = "prod"
+	if err := c.Validate(); err == nil {
+		t.Fatal("a config naming an unknown profile validates")
+	}
+}
+
+func TestClassroomProfileNeedsAnAuthToken(t *testing.T) {
+	if _, err := Load(flags(t, "--profile", "classroom")); err == nil {
+		t.Fatal("the classroom profile loaded without an auth token")
+	}
+	t.Setenv(EnvPrefix+"AUTH_TOKEN", "secret")
+	c, err := Load(flags(t, "--profile", "classroom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MineEvery != 15*time.Second || c.MaxBlockTxns != 100 {
+		t.Fatalf("the classroom profile wasn't applied: %+v", c)
+	}
+}
+
+func TestPublicDemoSelectsItsProfile(t *testing.T) {
+	for name, setup := range map[string]func(t *testing.T) *pflag.FlagSet{
+		"flag": func(t *testing.T) *pflag.FlagSet { return flags(t, "--public-demo") },
+		"env": func(t *testing.T) *pflag.FlagSet {
+			t.Setenv(EnvPrefix+"PUBLIC_DEMO", "true")
+			return flags(t)
+		},
+		"file": func(t *testing.T) *pflag.FlagSet {
+			return flags(t, "--config", writeFile(t, "node.yaml", "public_demo: true\n"))
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, err := Load(setup(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Profile != "public-demo" || c.RatePerIP != 60 || !c.ReadOnlyAdmin || c.ResetEvery != 24*time.Hour {
+				t.Fatalf("the public-demo profile wasn't applied: %+v", c)
+			}
+		})
+	}
+}
+
+func TestSignaturesFromHeightNeedsRequireSignatures(t *testing.T) {
+	if _, err := Load(flags(t, "--signatures-from-height", "10")); err == nil {
+		t.Fatal("signatures_from_height loaded without require_signatures")
+	}
+	if _, err := Load(flags(t, "--require-signatures", "--signatures-from-height", "-1")); err == nil {
+		t.Fatal("a negative signatures_from_height loaded")
+	}
+	c, err := Load(flags(t, "--require-signatures", "--signatures-from-height", "10"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.RequireSignatures || c.SignaturesFrom != 10 {
+		t.Fatalf("got require_signatures %t from height %d", c.RequireSignatures, c.SignaturesFrom)
+	}
+}
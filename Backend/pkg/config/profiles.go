@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Profile is a named bundle of settings for a kind of deployment, applied
+// over Default() so one flag brings up a sensible node
+type Profile struct {
+	Name        string
+	Description string
+	RequireAuth bool // the node refuses to start without auth_token
+	apply       func(*Config)
+}
+
+// publicDemo is the profile public_demo selects
+const publicDemo = "public-demo"
+
+// profiles are the built-in profiles, in the order --help lists them
+var profiles = []Profile{
+	{
+		Name:        "dev",
+		Description: "local development: fast blocks, no auth, invariants checked and fault injection allowed",
+		apply: func(c *Config) {
+			c.Difficulty = 1
+			c.BlockTime = 2 * time.Second
+			c.AuthToken = ""
+			c.Debug = true
+			c.Chaos = true
+		},
+	},
+	{
+		Name:        "classroom",
+		Description: "a shared teaching node: blocks every 15s, tampering demos, bounded mempool and requests, auth required",
+		RequireAuth: true,
+		apply: func(c *Config) {
+			c.Difficulty = 2
+			c.BlockTime = 15 * time.Second
+			c.MineEvery = 15 * time.Second
+			c.Demo = true
+			c.MaxPending = 1000
+			c.MaxBlockTxns = 100
+			c.ReadConcurrency = 128
+			c.ReadTimeout = 10 * time.Second
+			c.MineConcurrency = 2
+			c.MineTimeout = time.Minute
+			c.AdminConcurrency = 2
+		},
+	},
+	{
+		Name:        "benchmark",
+		Description: "throughput runs: minimal proof of work, no auth and no limits on the mempool, blocks or requests",
+		apply: func(c *Config) {
+			c.Difficulty = 1
+			c.AuthToken = ""
+			c.MaxPending = 0
+			c.MaxBlockTxns = 0
+			c.ReadConcurrency, c.ReadTimeout = 0, 0
+			c.MineConcurrency, c.MineTimeout = 0, 0
+			c.AdminConcurrency, c.AdminTimeout = 0, 0
+			c.Debug = false
+		},
+	},
+	{
+		Name:        publicDemo,
+		Description: "a node open to the internet for the frontend demo: per-IP rate limits, read-only admin, small transactions and blocks, mempool expiry and a daily reset",
+		apply: func(c *Config) {
+			c.PublicDemo = true
+			c.Difficulty = 2
+			c.MineEvery = 10 * time.Second
+			c.RatePerIP = 60
+			c.ReadOnlyAdmin = true
+			c.MaxTxBytes = 2 << 10
+			c.MaxBlockBytes = 64 << 10
+			c.MaxBlockTxns = 100
+			c.MaxPending = 500
+			c.PendingTTL = 10 * time.Minute
+			c.ResetEvery = 24 * time.Hour
+			c.ReadConcurrency = 64
+			c.ReadTimeout = 10 * time.Second
+			c.MineConcurrency = 1
+			c.MineTimeout = 30 * time.Second
+			c.AdminConcurrency = 1
+			c.AdminTimeout = 10 * time.Second
+			c.Demo = false
+			c.Chaos = false
+			c.Debug = false
+		},
+	},
+}
+
+// Profiles returns the built-in profiles
+func Profiles() []Profile {
+	return append([]Profile(nil), profiles...)
+}
+
+// LookupProfile returns the built-in profile called name
+func LookupProfile(name string) (Profile, bool) {
+	for _, p := range profiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// ApplyProfile sets c to the settings of the profile called name; empty
+// applies none
+func (c *Config) ApplyProfile(name string) error {
+	if name == "" {
+		return nil
+	}
+	p, ok := LookupProfile(name)
+	if !ok {
+		return fmt.Errorf("config: unknown profile %q (want %s)", name, profileHelp())
+	}
+	p.apply(c)
+	c.Profile = p.Name
+	return nil
+}
+
+// profileHelp lists the profile names, e.g. "dev, classroom, benchmark or public-demo"
+func profileHelp() string {
+	names := make([]string, len(profiles))
+	for i, p := range profiles {
+		names[i] = p.Name
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
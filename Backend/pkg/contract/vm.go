@@ -0,0 +1,436 @@
+// Package contract runs deployed contract code: a small deterministic,
+// gas-metered stack language. Values are strings; arithmetic treats them as
+// decimal integers, and a value is true unless it is "" or "0".
+//
+// A program is a list of whitespace-separated instructions. Numbers and
+// "quoted strings" (no spaces or escapes) are pushed, "name:" defines a jump
+// target for JUMP/name and JUMPI/name, and # starts a comment. For example,
+// a counter:
+//
+//	"count" "count" SLOAD 1 ADD SSTORE
+//	"count" SLOAD RETURN
+package contract
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Limits that keep execution bounded
+const (
+	MaxCodeLen  = 16 * 1024 // characters
+	MaxGas      = 1000000   // per call
+	MaxStack    = 1024
+	MaxValueLen = 256
+	MaxArgs     = 16
+)
+
+// gas charged per instruction; anything missing costs 1
+var gasCost = map[string]int64{
+	"SLOAD":  20,
+	"SSTORE": 100,
+	"LOG":    50,
+}
+
+var (
+	// ErrOutOfGas is returned when a call exhausts its gas limit
+	ErrOutOfGas = errors.New("contract: out of gas")
+	// ErrReverted is returned when the code executes REVERT or a failed ASSERT
+	ErrReverted = errors.New("contract: reverted")
+)
+
+// Storage is a contract's key/value state
+type Storage interface {
+	Get(key string) string
+	Set(key, value string) // "" deletes
+}
+
+// Env describes the call being executed
+type Env struct {
+	Address string   // the contract's own address
+	Caller  string   // address of the signing caller, "" if unsigned
+	Height  int      // height of the block the call is included in
+	Args    []string // call arguments
+}
+
+// MaxLogs bounds the events one call may emit
+const MaxLogs = 32
+
+// Event is emitted by LOG/name, which pops its data
+type Event struct {
+	Name string `json:"event"`
+	Data string `json:"data,omitempty"`
+}
+
+// Result is the outcome of a successful call
+type Result struct {
+	Return  string  `json:"return,omitempty"`
+	GasUsed int64   `json:"gas_used"`
+	Events  []Event `json:"-"`
+}
+
+// instr is one compiled instruction
+type instr struct {
+	op     string
+	value  string // for pushes
+	target int    // for jumps
+	push   bool
+}
+
+// Compile checks code and resolves jump targets
+func Compile(code string) ([]instr, error) {
+	if len(code) > MaxCodeLen {
+		return nil, fmt.Errorf("contract: code longer than %d characters", MaxCodeLen)
+	}
+	var fields []string
+	for _, line := range strings.Split(code, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields = append(fields, strings.Fields(line)...)
+	}
+	labels := map[string]int{}
+	var prog []instr
+	for _, f := range fields {
+		if name, ok := strings.CutSuffix(f, ":"); ok && name != "" {
+			if _, dup := labels[name]; dup {
+				return nil, fmt.Errorf("contract: label %q defined twice", name)
+			}
+			labels[name] = len(prog)
+			continue
+		}
+		switch {
+		case len(f) >= 2 && f[0] == '"' && f[len(f)-1] == '"':
+			prog = append(prog, instr{value: f[1 : len(f)-1], push: true})
+		case isNumber(f):
+			prog = append(prog, instr{value: f, push: true})
+		default:
+			op, arg, _ := strings.Cut(f, "/")
+			if _, ok := ops[op]; !ok && op != "JUMP" && op != "JUMPI" {
+				return nil, fmt.Errorf("contract: unknown instruction %s", f)
+			}
+			prog = append(prog, instr{op: op, value: arg})
+		}
+	}
+	for i, in := range prog {
+		if in.op != "JUMP" && in.op != "JUMPI" {
+			continue
+		}
+		t, ok := labels[in.value]
+		if !ok {
+			return nil, fmt.Errorf("contract: %s to unknown label %q", in.op, in.value)
+		}
+		prog[i].target = t
+	}
+	return prog, nil
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseInt(s, 10, 64)
+	return err == nil
+}
+
+// Execute runs code against storage within gasLimit. Writes only reach
+// storage if the call succeeds.
+func Execute(code string, storage Storage, env Env, gasLimit int64) (Result, error) {
+	if gasLimit <= 0 || gasLimit > MaxGas {
+		return Result{}, fmt.Errorf("contract: gas limit must be between 1 and %d", MaxGas)
+	}
+	if len(env.Args) > MaxArgs {
+		return Result{}, fmt.Errorf("contract: more than %d arguments", MaxArgs)
+	}
+	prog, err := Compile(code)
+	if err != nil {
+		return Result{}, err
+	}
+	m := &machine{env: env, storage: storage, writes: map[string]string{}}
+	var gas int64
+	for pc := 0; pc < len(prog); {
+		in := prog[pc]
+		pc++
+		cost, ok := gasCost[in.op]
+		if !ok {
+			cost = 1
+		}
+		if gas += cost; gas > gasLimit {
+			return Result{GasUsed: gasLimit}, ErrOutOfGas
+		}
+		switch {
+		case in.push:
+			err = m.push(in.value)
+		case in.op == "JUMP":
+			pc = in.target
+		case in.op == "JUMPI":
+			var v string
+			if v, err = m.pop(); err == nil && truthy(v) {
+				pc = in.target
+			}
+		default:
+			err = ops[in.op](m, in.value)
+		}
+		if err != nil {
+			return Result{GasUsed: gas}, err
+		}
+		if m.done {
+			break
+		}
+	}
+	for k, v := range m.writes {
+		storage.Set(k, v)
+	}
+	return Result{Return: m.ret, GasUsed: gas, Events: m.events}, nil
+}
+
+// machine is the execution state of one call
+type machine struct {
+	stack   []string
+	env     Env
+	storage Storage
+	writes  map[string]string // buffered until the call succeeds
+	events  []Event
+	ret     string
+	done    bool
+}
+
+func (m *machine) push(v string) error {
+	if len(v) > MaxValueLen {
+		return fmt.Errorf("contract: value longer than %d bytes", MaxValueLen)
+	}
+	if len(m.stack) >= MaxStack {
+		return fmt.Errorf("contract: stack deeper than %d", MaxStack)
+	}
+	m.stack = append(m.stack, v)
+	return nil
+}
+
+func (m *machine) pop() (string, error) {
+	if len(m.stack) == 0 {
+		return "", errors.New("contract: stack underflow")
+	}
+	v := m.stack[len(m.stack)-1]
+	m.stack = m.stack[:len(m.stack)-1]
+	return v, nil
+}
+
+func (m *machine) popInt() (int64, error) {
+	v, err := m.pop()
+	if err != nil || v == "" {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("contract: %q is not a number", v)
+	}
+	return n, nil
+}
+
+func (m *machine) load(key string) string {
+	if v, ok := m.writes[key]; ok {
+		return v
+	}
+	return m.storage.Get(key)
+}
+
+func truthy(v string) bool {
+	return v != "" && v != "0"
+}
+
+func boolValue(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// ops maps instruction names to handlers; arg is the text after "/" (e.g. ARG/0)
+var ops = map[string]func(m *machine, arg string) error{
+	"STOP": func(m *machine, _ string) error {
+		m.done = true
+		return nil
+	},
+	"RETURN": func(m *machine, _ string) error {
+		v, err := m.pop()
+		m.ret, m.done = v, true
+		return err
+	},
+	"REVERT": func(m *machine, _ string) error {
+		v, _ := m.pop()
+		return fmt.Errorf("%w: %s", ErrReverted, v)
+	},
+	"ASSERT": func(m *machine, _ string) error {
+		v, err := m.pop()
+		if err != nil {
+			return err
+		}
+		if !truthy(v) {
+			return fmt.Errorf("%w: assertion failed", ErrReverted)
+		}
+		return nil
+	},
+	"DUP": func(m *machine, _ string) error {
+		if len(m.stack) == 0 {
+			return errors.New("contract: stack underflow")
+		}
+		return m.push(m.stack[len(m.stack)-1])
+	},
+	"DROP": func(m *machine, _ string) error {
+		_, err := m.pop()
+		return err
+	},
+	"SWAP": func(m *machine, _ string) error {
+		n := len(m.stack)
+		if n < 2 {
+			return errors.New("contract: stack underflow")
+		}
+		m.stack[n-1], m.stack[n-2] = m.stack[n-2], m.stack[n-1]
+		return nil
+	},
+	"ROT": func(m *machine, _ string) error {
+		n := len(m.stack)
+		if n < 3 {
+			return errors.New("contract: stack underflow")
+		}
+		m.stack[n-3], m.stack[n-2], m.stack[n-1] = m.stack[n-2], m.stack[n-1], m.stack[n-3]
+		return nil
+	},
+	"OVER": func(m *machine, _ string) error {
+		if len(m.stack) < 2 {
+			return errors.New("contract: stack underflow")
+		}
+		return m.push(m.stack[len(m.stack)-2])
+	},
+	"ADD": arith(func(a, b int64) (int64, bool) { c := a + b; return c, (c > a) == (b > 0) }),
+	"SUB": arith(func(a, b int64) (int64, bool) { c := a - b; return c, (c < a) == (b > 0) }),
+	"MUL": arith(func(a, b int64) (int64, bool) {
+		c := a * b
+		return c, a == 0 || (c/a == b && !(a == -1 && b == -1<<63))
+	}),
+	"DIV": arith(func(a, b int64) (int64, bool) { return safeDiv(a, b, false) }),
+	"MOD": arith(func(a, b int64) (int64, bool) { return safeDiv(a, b, true) }),
+	"LT":  compare(func(a, b int64) bool { return a < b }),
+	"GT":  compare(func(a, b int64) bool { return a > b }),
+	"EQ": func(m *machine, _ string) error {
+		b, err := m.pop()
+		if err != nil {
+			return err
+		}
+		a, err := m.pop()
+		if err != nil {
+			return err
+		}
+		return m.push(boolValue(a == b))
+	},
+	"NOT": func(m *machine, _ string) error {
+		v, err := m.pop()
+		if err != nil {
+			return err
+		}
+		return m.push(boolValue(!truthy(v)))
+	},
+	"CONCAT": func(m *machine, _ string) error {
+		b, err := m.pop()
+		if err != nil {
+			return err
+		}
+		a, err := m.pop()
+		if err != nil {
+			return err
+		}
+		return m.push(a + b)
+	},
+	"ARG": func(m *machine, arg string) error {
+		i, err := strconv.Atoi(arg)
+		if err != nil || i < 0 {
+			return fmt.Errorf("contract: ARG needs an index, e.g. ARG/0")
+		}
+		if i >= len(m.env.Args) {
+			return m.push("")
+		}
+		return m.push(m.env.Args[i])
+	},
+	"LOG": func(m *machine, arg string) error {
+		if arg == "" {
+			return errors.New("contract: LOG needs an event name, e.g. LOG/Transfer")
+		}
+		if len(m.events) >= MaxLogs {
+			return fmt.Errorf("contract: more than %d events", MaxLogs)
+		}
+		v, err := m.pop()
+		if err != nil {
+			return err
+		}
+		m.events = append(m.events, Event{Name: arg, Data: v})
+		return nil
+	},
+	"ARGC":    func(m *machine, _ string) error { return m.push(strconv.Itoa(len(m.env.Args))) },
+	"CALLER":  func(m *machine, _ string) error { return m.push(m.env.Caller) },
+	"ADDRESS": func(m *machine, _ string) error { return m.push(m.env.Address) },
+	"HEIGHT":  func(m *machine, _ string) error { return m.push(strconv.Itoa(m.env.Height)) },
+	"SLOAD": func(m *machine, _ string) error {
+		k, err := m.pop()
+		if err != nil {
+			return err
+		}
+		return m.push(m.load(k))
+	},
+	"SSTORE": func(m *machine, _ string) error {
+		v, err := m.pop()
+		if err != nil {
+			return err
+		}
+		k, err := m.pop()
+		if err != nil {
+			return err
+		}
+		if k == "" {
+			return errors.New("contract: empty storage key")
+		}
+		m.writes[k] = v
+		return nil
+	},
+}
+
+// arith pops b then a and pushes f(a, b); ok is false on overflow or division by zero
+func arith(f func(a, b int64) (int64, bool)) func(m *machine, _ string) error {
+	return func(m *machine, _ string) error {
+		b, err := m.popInt()
+		if err != nil {
+			return err
+		}
+		a, err := m.popInt()
+		if err != nil {
+			return err
+		}
+		c, ok := f(a, b)
+		if !ok {
+			return errors.New("contract: arithmetic overflow or division by zero")
+		}
+		return m.push(strconv.FormatInt(c, 10))
+	}
+}
+
+func compare(f func(a, b int64) bool) func(m *machine, _ string) error {
+	return func(m *machine, _ string) error {
+		b, err := m.popInt()
+		if err != nil {
+			return err
+		}
+		a, err := m.popInt()
+		if err != nil {
+			return err
+		}
+		return m.push(boolValue(f(a, b)))
+	}
+}
+
+func safeDiv(a, b int64, mod bool) (int64, bool) {
+	if b == 0 || (a == -1<<63 && b == -1) {
+		return 0, false
+	}
+	if mod {
+		return a % b, true
+	}
+	return a / b, true
+}
@@ -0,0 +1,74 @@
+// Package events is an in-process pub/sub hub for chain events, so handlers
+// can publish without knowing who is listening.
+package events
+
+import (
+	"sync"
+
+	"salmanahmed/blockchain/pkg/clock"
+)
+
+// Event types published by the node
+const (
+	TxAdded       = "tx_added"
+	TxDropped     = "tx_dropped" // removed from the mempool on request
+	MiningStarted = "mining_started"
+	BlockMined    = "block_mined" // here or, once it arrives, by a peer
+	PeerAdded     = "peer_added"
+	ChainReset    = "chain_reset"
+	ChainReplaced = "chain_replaced" // data is an api.SyncResult
+	WatchMatched  = "watch"          // data is an api.WatchNotification
+	Logs          = "logs"           // data is the []blockchain.Log of a newly mined block
+	ChainTampered = "chain_tampered" // a demo edit to confirmed blocks was made or undone
+)
+
+// Event is a single notification
+type Event struct {
+	Type string      `json:"type"`
+	Time int64       `json:"time"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// Hub fans published events out to every subscriber
+type Hub struct {
+	mu    sync.Mutex
+	subs  map[chan Event]struct{}
+	clock clock.Clock
+}
+
+// NewHub returns a hub without subscribers that timestamps events with clk
+func NewHub(clk clock.Clock) *Hub {
+	return &Hub{subs: map[chan Event]struct{}{}, clock: clock.Or(clk)}
+}
+
+// Publish delivers an event to every subscriber. Slow subscribers whose
+// buffer is full miss the event rather than blocking the publisher.
+func (h *Hub) Publish(typ string, data interface{}) {
+	e := Event{Type: typ, Time: h.clock.Now().Unix(), Data: data}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel of events and a function that unsubscribes
+// and closes it
+func (h *Hub) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, 64)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs, ch)
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
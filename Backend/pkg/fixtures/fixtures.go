@@ -0,0 +1,149 @@
+// Package fixtures deterministically builds reference chains (fixed keys,
+// fixed clock, fixed nonces) and loads the golden copies kept in testdata,
+// so hashing and validation changes show up as fixture mismatches.
+//
+// Regenerate the golden files with `go generate ./pkg/fixtures`.
+package fixtures
+
+//go:generate go run ./gen -out testdata
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/clock"
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+// Epoch is the fixed clock every fixture starts from (2023-11-14T22:13:20Z)
+const Epoch int64 = 1700000000
+
+// BlockInterval is the fixed number of seconds between fixture blocks
+const BlockInterval int64 = 10
+
+// Spec describes a reference chain
+type Spec struct {
+	Name        string
+	Blocks      int // blocks after genesis
+	TxsPerBlock int
+	Difficulty  int
+	Keys        int // deterministic keypairs used as tx parties
+}
+
+// Specs are the reference chains kept as golden files
+var Specs = []Spec{
+	{Name: "genesis", Blocks: 0, Difficulty: 2, Keys: 2},
+	{Name: "small", Blocks: 3, TxsPerBlock: 2, Difficulty: 2, Keys: 3},
+	{Name: "medium", Blocks: 12, TxsPerBlock: 5, Difficulty: 3, Keys: 5},
+}
+
+// Golden is a generated chain together with what produced it
+type Golden struct {
+	Name       string             `json:"name"`
+	Difficulty int                `json:"difficulty"`
+	Keys       []wallet.Keypair   `json:"keys"`
+	Blocks     []blockchain.Block `json:"blocks"`
+}
+
+//go:embed testdata/*.json
+var golden embed.FS
+
+// Key returns the i-th deterministic keypair
+func Key(i int) wallet.Keypair {
+	seed := blockchain.CalculateHash(fmt.Sprintf("fixture-key-%d", i))
+	kp, err := wallet.FromPrivateKey(seed)
+	if err != nil {
+		// a SHA-256 digest is below the P-256 order with overwhelming probability
+		panic(fmt.Sprintf("fixtures: key %d: %v", i, err))
+	}
+	return kp
+}
+
+// Generate builds the chain described by spec. The same spec always yields
+// byte-identical blocks.
+func Generate(spec Spec) Golden {
+	g := Golden{Name: spec.Name, Difficulty: spec.Difficulty}
+	for i := 0; i < spec.Keys; i++ {
+		g.Keys = append(g.Keys, Key(i))
+	}
+
+	clk := clock.NewMock(time.Unix(Epoch, 0))
+	// a single worker tries nonces in order, so the search is reproducible
+	pow := &blockchain.ProofOfWork{Difficulty: spec.Difficulty, Clock: clk, Workers: 1}
+	g.Blocks = append(g.Blocks, blockchain.NewGenesisBlock(blockchain.DefaultHasher(), clk))
+
+	for i := 1; i <= spec.Blocks; i++ {
+		prev := g.Blocks[len(g.Blocks)-1]
+		txns := make([]blockchain.Transaction, spec.TxsPerBlock)
+		for j := range txns {
+			from := g.Keys[(i+j)%len(g.Keys)].Address
+			to := g.Keys[(i+j+1)%len(g.Keys)].Address
+			txns[j] = blockchain.NewDataTx(fmt.Sprintf("%s->%s:%d", from, to, i*100+j))
+		}
+		b := blockchain.Block{
+			Index:    i,
+			Txns:     txns,
+			PrevHash: prev.Hash,
+		}
+		b.MerkleRoot = blockchain.ComputeMerkleRoot(txns)
+		clk.Advance(time.Duration(BlockInterval) * time.Second)
+		// the clock is stopped while mining, so nonces are fixed too
+		mined, err := pow.ProduceBlock(context.Background(), b, blockchain.DefaultHasher())
+		if err != nil {
+			panic(err) // background context never cancels
+		}
+		g.Blocks = append(g.Blocks, mined)
+	}
+	return g
+}
+
+// Load reads the golden chain called name from testdata
+func Load(name string) (Golden, error) {
+	raw, err := golden.ReadFile("testdata/" + name + ".json")
+	if err != nil {
+		return Golden{}, fmt.Errorf("fixtures: %w", err)
+	}
+	var g Golden
+	if err := json.Unmarshal(raw, &g); err != nil {
+		return Golden{}, fmt.Errorf("fixtures: %s: %w", name, err)
+	}
+	return g, nil
+}
+
+// Chain replays a golden chain through blockchain validation
+func (g Golden) Chain() (*blockchain.Chain, error) {
+	if len(g.Blocks) == 0 {
+		return nil, fmt.Errorf("fixtures: %s has no blocks", g.Name)
+	}
+	c := blockchain.NewChainFromGenesis(g.Blocks[0],
+		&blockchain.ProofOfWork{Difficulty: g.Difficulty}, blockchain.DefaultHasher())
+	for _, b := range g.Blocks[1:] {
+		if err := c.AddBlock(b); err != nil {
+			return nil, fmt.Errorf("fixtures: %s: %w", g.Name, err)
+		}
+	}
+	return c, nil
+}
+
+// Verify regenerates every spec and reports the first golden file that no
+// longer matches, e.g. after a change to block hashing
+func Verify() error {
+	for _, spec := range Specs {
+		want, err := Load(spec.Name)
+		if err != nil {
+			return err
+		}
+		if got := Generate(spec); !reflect.DeepEqual(got, want) {
+			return fmt.Errorf("fixtures: %s no longer matches its golden file", spec.Name)
+		}
+		if _, err := want.Chain(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package fixtures
+
+import (
+	"testing"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+// TestGoldenFilesMatch fails whenever a change to hashing, mining or
+// encoding makes the generator disagree with the golden files; regenerate
+// them with `go generate ./pkg/fixtures` only if the change is intended
+func TestGoldenFilesMatch(t *testing.T) {
+	if err := Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGoldenChainsReplay(t *testing.T) {
+	for _, spec := range Specs {
+		spec := spec
+		t.Run(spec.Name, func(t *testing.T) {
+			g, err := Load(spec.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(g.Blocks) != spec.Blocks+1 {
+				t.Fatalf("golden file holds %d blocks, want %d", len(g.Blocks), spec.Blocks+1)
+			}
+			c, err := g.Chain()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Len() != len(g.Blocks) {
+				t.Fatalf("replayed chain holds %d blocks, want %d", c.Len(), len(g.Blocks))
+			}
+			if tip := c.Tip(); tip.Hash != g.Blocks[len(g.Blocks)-1].Hash {
+				t.Fatalf("replayed tip %s, golden tip %s", tip.Hash, g.Blocks[len(g.Blocks)-1].Hash)
+			}
+			for i, b := range g.Blocks {
+				if got := blockchain.HashBlock(blockchain.DefaultHasher(), b); got != b.Hash {
+					t.Errorf("block %d hashes to %s, golden file says %s", i, got, b.Hash)
+				}
+				if got := blockchain.ComputeMerkleRoot(b.Txns); got != b.MerkleRoot {
+					t.Errorf("block %d merkle root is %s, golden file says %s", i, got, b.MerkleRoot)
+				}
+			}
+		})
+	}
+}
+
+func TestEditedGoldenChainIsRejected(t *testing.T) {
+	g, err := Load("small")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := make([]blockchain.Block, len(g.Blocks))
+	copy(blocks, g.Blocks)
+	edited := blocks[1]
+	edited.Txns = append([]blockchain.Transaction(nil), edited.Txns...)
+	edited.Txns[0] = blockchain.NewDataTx("edited")
+	blocks[1] = edited
+	g.Blocks = blocks
+	if _, err := g.Chain(); err == nil {
+		t.Fatal("a golden chain with an edited transaction replays without error")
+	}
+	bad, err := blockchain.VerifyBlocks(blocks, &blockchain.ProofOfWork{Difficulty: g.Difficulty}, blockchain.DefaultHasher())
+	if err == nil || bad != 1 {
+		t.Fatalf("VerifyBlocks on the edited chain = %d, %v; want block 1 to fail", bad, err)
+	}
+}
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	spec := Specs[1]
+	a, b := Generate(spec), Generate(spec)
+	for i := range a.Blocks {
+		if a.Blocks[i].Hash != b.Blocks[i].Hash || a.Blocks[i].Nonce != b.Blocks[i].Nonce {
+			t.Fatalf("block %d differs between two runs of the generator", i)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+// Command gen writes the golden fixture chains as indented JSON.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"salmanahmed/blockchain/pkg/fixtures"
+)
+
+func main() {
+	out := flag.String("out", "testdata", "directory to write golden files into")
+	flag.Parse()
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatal(err)
+	}
+	for _, spec := range fixtures.Specs {
+		raw, err := json.MarshalIndent(fixtures.Generate(spec), "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		path := filepath.Join(*out, spec.Name+".json")
+		if err := os.WriteFile(path, append(raw, '\n'), 0o644); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("wrote %s", path)
+	}
+}
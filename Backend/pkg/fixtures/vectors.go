@@ -0,0 +1,234 @@
+package fixtures
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/clock"
+	"salmanahmed/blockchain/pkg/script"
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+// Vectors are inputs paired with what this implementation computes for
+// them, so other implementations (the explorer's JavaScript, ports to other
+// languages) can check they hash, build merkle trees and verify signatures
+// exactly alike
+type Vectors struct {
+	Hasher     string            `json:"hasher"` // hashes blocks and merkle trees; txids and sighashes always use SHA-256
+	Hashes     []HashVector      `json:"hashes"`
+	TxIDs      []TxIDVector      `json:"txids"`
+	SigHashes  []SigHashVector   `json:"sighashes"`
+	Merkle     []MerkleVector    `json:"merkle"`
+	Blocks     []BlockVector     `json:"blocks"`
+	Addresses  []AddressVector   `json:"addresses"`
+	Signatures []SignatureVector `json:"signatures"`
+}
+
+// HashVector is the hasher's digest of a UTF-8 string
+type HashVector struct {
+	Input  string `json:"input"`
+	Digest string `json:"digest"`
+}
+
+// TxIDVector is a transaction's canonical form, the ID hashed from it and
+// its binary encoding
+type TxIDVector struct {
+	Name      string                 `json:"name"`
+	Tx        blockchain.Transaction `json:"tx"`
+	Canonical string                 `json:"canonical"`
+	TxID      string                 `json:"txid"`
+	Encoding  string                 `json:"encoding"` // hex of blockchain.EncodeTx
+}
+
+// SigHashVector is the digest a transaction's signatures commit to
+type SigHashVector struct {
+	Tx      blockchain.Transaction `json:"tx"`
+	SigHash string                 `json:"sighash"` // hex
+}
+
+// MerkleVector is the merkle tree over transactions given by canonical form
+type MerkleVector struct {
+	Leaves []string   `json:"leaves"` // canonical forms, in block order
+	Levels [][]string `json:"levels"` // leaf hashes first, the root last
+	Root   string     `json:"root"`
+}
+
+// BlockVector is a block, what its hash covers, the hash and its binary
+// encoding
+type BlockVector struct {
+	Name     string           `json:"name"`
+	Block    blockchain.Block `json:"block"`
+	Record   string           `json:"record,omitempty"` // hashed by version 0 blocks
+	Hash     string           `json:"hash"`
+	Encoding string           `json:"encoding"` // hex of blockchain.EncodeBlock; version 1 blocks hash it without the trailing hash
+}
+
+// AddressVector derives an address and its P2PKH locking script from a key
+type AddressVector struct {
+	PrivateKey string `json:"private_key"`
+	PublicKey  string `json:"public_key"`
+	Address    string `json:"address"`
+	Lock       string `json:"lock"`
+}
+
+// SignatureVector is a DER signature and whether it verifies. ECDSA
+// signing is randomized, so the valid signature differs between calls.
+type SignatureVector struct {
+	PublicKey string `json:"public_key"`
+	Digest    string `json:"digest"` // hex
+	Signature string `json:"signature"`
+	Valid     bool   `json:"valid"`
+}
+
+// vectorStrings are hashed as-is, covering the empty string, the genesis
+// data and multi-byte UTF-8
+var vectorStrings = []string{"", "abc", blockchain.GenesisTx, "héllo, wörld ✓"}
+
+// BuildVectors computes the test vectors with hasher h
+func BuildVectors(h blockchain.Hasher) (Vectors, error) {
+	v := Vectors{Hasher: h.Name()}
+	for _, s := range vectorStrings {
+		v.Hashes = append(v.Hashes, HashVector{Input: s, Digest: h.Hash([]byte(s))})
+	}
+
+	alice, bob := Key(0), Key(1)
+	preimage := []byte("fixture-preimage")
+	transfer := blockchain.Transaction{
+		Inputs: []blockchain.TxInput{{
+			TxID:   blockchain.CalculateHash("fixture-parent"),
+			Index:  0,
+			Unlock: script.HashLockUnlock(preimage),
+		}},
+		Outputs: []blockchain.TxOutput{
+			{Amount: 30, Lock: script.P2PKH(bob.Address)},
+			{Amount: 12, Lock: script.P2PKH(alice.Address)},
+		},
+	}
+	coinbase := blockchain.Transaction{
+		Coinbase: 1,
+		Outputs:  []blockchain.TxOutput{{Amount: 50, Lock: script.P2PKH(alice.Address)}},
+	}
+	txs := []struct {
+		name string
+		tx   blockchain.Transaction
+	}{
+		{"data", blockchain.Transaction{Data: "fixture data"}},
+		{"data-unicode", blockchain.Transaction{Data: "héllo, wörld ✓"}},
+		{"transfer", transfer},
+		{"coinbase", coinbase},
+		{"kv", blockchain.Transaction{
+			KV:        []blockchain.KVOp{{Op: "set", Key: "fixture", Value: "héllo"}, {Op: "delete", Key: "old"}},
+			Priority:  blockchain.PriorityHigh,
+			ExpiresAt: 9,
+		}},
+		{"contract", blockchain.Transaction{
+			Contract: &blockchain.ContractOp{Address: blockchain.CalculateHash("fixture-contract")[:40], Args: []string{"add", "2"}, GasLimit: 1000},
+			Reveal:   &blockchain.Reveal{TxID: blockchain.CalculateHash("fixture-commit"), Salt: "00ff"},
+		}},
+	}
+	var sealed []blockchain.Transaction
+	for _, t := range txs {
+		tx := t.tx.Seal()
+		sealed = append(sealed, tx)
+		if err := roundTrip(tx, blockchain.EncodeTx(tx), func(raw []byte) (interface{}, error) { return blockchain.DecodeTx(raw) }); err != nil {
+			return Vectors{}, fmt.Errorf("transaction %s: %w", t.name, err)
+		}
+		v.TxIDs = append(v.TxIDs, TxIDVector{Name: t.name, Tx: tx, Canonical: tx.Canonical(), TxID: tx.ID, Encoding: hex.EncodeToString(blockchain.EncodeTx(tx))})
+	}
+	v.SigHashes = append(v.SigHashes, SigHashVector{Tx: sealed[2], SigHash: hex.EncodeToString(sealed[2].SigHash())})
+
+	// one to five leaves covers the odd node paired with itself at each level
+	for n := 1; n <= 5; n++ {
+		leaves := make([]blockchain.Transaction, n)
+		for i := range leaves {
+			leaves[i] = sealed[i%len(sealed)]
+		}
+		tree := blockchain.BuildMerkleTree(h, leaves)
+		m := MerkleVector{Levels: tree.Levels, Root: tree.Root}
+		for _, tx := range leaves {
+			m.Leaves = append(m.Leaves, tx.Canonical())
+		}
+		v.Merkle = append(v.Merkle, m)
+	}
+
+	clk := clock.NewMock(time.Unix(Epoch, 0))
+	genesis := blockchain.NewGenesisBlock(h, clk)
+	clk.Advance(time.Duration(BlockInterval) * time.Second)
+	next := blockchain.Block{
+		Index:      1,
+		Timestamp:  clk.Now().Unix(),
+		Txns:       sealed,
+		MerkleRoot: blockchain.MerkleRoot(h, sealed),
+		PrevHash:   genesis.Hash,
+		Nonce:      42,
+		Miner:      alice.Address,
+		Difficulty: 2,
+	}
+	next.Hash = blockchain.HashBlock(h, next)
+	binary := next
+	binary.Version = blockchain.CodecVersion
+	binary.Hash = blockchain.HashBlock(h, binary)
+	for _, b := range []struct {
+		name  string
+		block blockchain.Block
+	}{{"genesis", genesis}, {"block", next}, {"block-binary", binary}} {
+		raw := blockchain.EncodeBlock(b.block)
+		if err := roundTrip(b.block, raw, func(raw []byte) (interface{}, error) { return blockchain.DecodeBlock(raw) }); err != nil {
+			return Vectors{}, fmt.Errorf("block %s: %w", b.name, err)
+		}
+		bv := BlockVector{Name: b.name, Block: b.block, Hash: b.block.Hash, Encoding: hex.EncodeToString(raw)}
+		if b.block.Version == 0 {
+			bv.Record = blockchain.BlockRecord(b.block)
+		}
+		v.Blocks = append(v.Blocks, bv)
+	}
+
+	for _, kp := range []wallet.Keypair{alice, bob} {
+		v.Addresses = append(v.Addresses, AddressVector{
+			PrivateKey: kp.PrivateKey,
+			PublicKey:  kp.PublicKey,
+			Address:    kp.Address,
+			Lock:       script.P2PKH(kp.Address),
+		})
+	}
+
+	digest := sealed[2].SigHash()
+	sig, err := wallet.Sign(alice.PrivateKey, digest)
+	if err != nil {
+		return Vectors{}, err
+	}
+	tampered := append([]byte{}, digest...)
+	tampered[0] ^= 1
+	// the signature, then over a tampered digest and under the wrong key
+	for _, c := range []struct {
+		pub    string
+		digest []byte
+	}{{alice.PublicKey, digest}, {alice.PublicKey, tampered}, {bob.PublicKey, digest}} {
+		v.Signatures = append(v.Signatures, SignatureVector{
+			PublicKey: c.pub,
+			Digest:    hex.EncodeToString(c.digest),
+			Signature: sig,
+			Valid:     wallet.Verify(c.pub, c.digest, sig),
+		})
+	}
+	return v, nil
+}
+
+// roundTrip checks that decoding raw gives back want, field for field as
+// the JSON API shows them
+func roundTrip(want interface{}, raw []byte, decode func([]byte) (interface{}, error)) error {
+	got, err := decode(raw)
+	if err != nil {
+		return err
+	}
+	a, _ := json.Marshal(want)
+	b, _ := json.Marshal(got)
+	if !bytes.Equal(a, b) {
+		return fmt.Errorf("binary encoding does not round-trip: %s decodes as %s", a, b)
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+// Package integration holds the node's end-to-end tests: each boots
+// complete nodes in-process behind httptest and drives them over HTTP
+// through the client, submitting, mining, syncing, following, restarting
+// from disk and calling every route the node serves, many of them from
+// parallel submitters and miners at once. It is the regression net the
+// node's features share; run it with `go test ./pkg/integration`, adding
+// -race, -v to see the nodes' logs, or -submitters, -txs and -miners to
+// change the load.
+package integration
@@ -0,0 +1,199 @@
+package integration
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/client"
+	"salmanahmed/blockchain/pkg/script"
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+// maxDrainBlocks bounds the blocks drain mines before giving up
+const maxDrainBlocks = 1000
+
+// submit submits a data transaction and returns its txid
+func submit(t testing.TB, ctx context.Context, c *client.Client, data string) string {
+	t.Helper()
+	res, err := c.SubmitTx(ctx, data)
+	if err != nil {
+		t.Fatalf("submitting %q: %v", data, err)
+	}
+	return res.TxID
+}
+
+// mine mines the pending transactions and returns the block, failing t
+// when there was nothing to mine
+func mine(t testing.TB, ctx context.Context, c *client.Client) blockchain.Block {
+	t.Helper()
+	return mineAs(t, ctx, c, "")
+}
+
+// mineAs is mine crediting the block to miner
+func mineAs(t testing.TB, ctx context.Context, c *client.Client, miner string) blockchain.Block {
+	t.Helper()
+	res, err := c.MineAs(ctx, miner)
+	if err != nil {
+		t.Fatalf("mining: %v", err)
+	}
+	if res.Block == nil {
+		t.Fatalf("nothing was mined: %s", res.Status)
+	}
+	return *res.Block
+}
+
+// drain mines until the mempool is empty
+func drain(t testing.TB, ctx context.Context, c *client.Client) {
+	t.Helper()
+	for i := 0; i < maxDrainBlocks; i++ {
+		pending, err := c.Pending(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pending) == 0 {
+			return
+		}
+		if _, err := c.Mine(ctx); err != nil && !busy(err) {
+			t.Fatalf("mining: %v", err)
+		}
+	}
+	t.Fatalf("the mempool is not empty after %d blocks", maxDrainBlocks)
+}
+
+// holdsTx reports whether txs include txid
+func holdsTx(txs []blockchain.Transaction, txid string) bool {
+	return txPosition(txs, txid) >= 0
+}
+
+// txPosition returns where txid is in txs, or -1
+func txPosition(txs []blockchain.Transaction, txid string) int {
+	for i, t := range txs {
+		if t.ID == txid {
+			return i
+		}
+	}
+	return -1
+}
+
+// expectValid fails t unless /validate reports the chain valid
+func expectValid(t testing.TB, ctx context.Context, c *client.Client) {
+	t.Helper()
+	integrity, err := c.Validate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !integrity.Valid {
+		t.Fatalf("validate reports the chain invalid: %s", integrity.Reason)
+	}
+}
+
+// expectBalance fails t unless address holds want at the tip
+func expectBalance(t testing.TB, ctx context.Context, c *client.Client, address string, want int64) {
+	t.Helper()
+	b, err := c.Balance(ctx, address, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Balance != want {
+		t.Fatalf("%s holds %d, want %d", address, b.Balance, want)
+	}
+}
+
+// expectHeight fails t unless the chain's tip is at height
+func expectHeight(t testing.TB, ctx context.Context, c *client.Client, height int) []blockchain.Block {
+	t.Helper()
+	blocks, err := c.Blocks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks)-1 != height {
+		t.Fatalf("the chain is at height %d, want %d", len(blocks)-1, height)
+	}
+	return blocks
+}
+
+// expectStatus fails t unless err is the node answering status
+func expectStatus(t testing.TB, err error, status int, what string) {
+	t.Helper()
+	var e *client.Error
+	switch {
+	case err == nil:
+		t.Fatalf("%s succeeded, want %d %s", what, status, http.StatusText(status))
+	case !errors.As(err, &e):
+		t.Fatalf("%s: %v", what, err)
+	case e.StatusCode != status:
+		t.Fatalf("%s: %v, want %d", what, err, status)
+	}
+}
+
+// busy reports whether err is the node turning a request away for load,
+// which concurrent callers are expected to meet
+func busy(err error) bool {
+	var e *client.Error
+	return errors.As(err, &e) && (e.StatusCode == http.StatusServiceUnavailable || e.StatusCode == http.StatusTooManyRequests)
+}
+
+// newWallet returns a fresh keypair
+func newWallet(t testing.TB) wallet.Keypair {
+	t.Helper()
+	kp, err := wallet.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kp
+}
+
+// signedTransfer has the node build a transfer from kp and signs it
+func signedTransfer(t testing.TB, ctx context.Context, c *client.Client, kp wallet.Keypair, to string, amount, fee int64) blockchain.Transaction {
+	t.Helper()
+	utx, err := c.BuildTransaction(ctx, kp.Address, to, amount, fee)
+	if err != nil {
+		t.Fatalf("building a transfer of %d from %s: %v", amount, kp.Address, err)
+	}
+	if got := hex.EncodeToString(utx.Tx.SigHash()); got != utx.SigHash {
+		t.Fatalf("the node's sighash %s does not match the transaction's %s", utx.SigHash, got)
+	}
+	return sign(t, kp, utx.Tx)
+}
+
+// sign unlocks every input of tx, all paying to kp, and seals it
+func sign(t testing.TB, kp wallet.Keypair, tx blockchain.Transaction) blockchain.Transaction {
+	t.Helper()
+	for i := range tx.Inputs {
+		tx.Inputs[i].Unlock = ""
+	}
+	sig, err := wallet.Sign(kp.PrivateKey, tx.SigHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range tx.Inputs {
+		tx.Inputs[i].Unlock = script.P2PKHUnlock(sig, kp.PublicKey)
+	}
+	return tx.Seal()
+}
+
+// randomHex returns 8 random bytes as hex, keeping transaction data unique
+// across tests
+func randomHex() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// waitFor polls cond every 10ms until it holds, failing t once ctx ends
+func waitFor(t testing.TB, ctx context.Context, what string, cond func() bool) {
+	t.Helper()
+	for !cond() {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("gave up waiting for %s: %v", what, ctx.Err())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
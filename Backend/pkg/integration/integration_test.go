@@ -0,0 +1,1595 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"salmanahmed/blockchain/pkg/api"
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/client"
+	"salmanahmed/blockchain/pkg/config"
+	"salmanahmed/blockchain/pkg/events"
+	"salmanahmed/blockchain/pkg/node"
+	"salmanahmed/blockchain/pkg/script"
+)
+
+var (
+	submitters = flag.Int("submitters", 8, "goroutines submitting at once in the concurrency tests")
+	txsEach    = flag.Int("txs", 25, "transactions each submitter sends")
+	miners     = flag.Int("miners", 2, "goroutines mining while they submit")
+)
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !testing.Verbose() {
+		// the nodes under test log every request
+		log.SetOutput(io.Discard)
+	}
+	os.Exit(m.Run())
+}
+
+// token is the auth token every node under test requires for writes
+const token = "integration"
+
+// testTimeout bounds each test, nodes and all
+const testTimeout = 2 * time.Minute
+
+// baseConfig is a node that mines in milliseconds, keeps everything in
+// memory, requires the token for writes and checks its invariants after
+// every write
+func baseConfig() config.Config {
+	cfg := config.Default()
+	cfg.Difficulty = 1
+	cfg.AuthToken = token
+	cfg.NodeID = "integration"
+	cfg.Debug = true
+	return cfg
+}
+
+// env is a node under test and clients for it
+type env struct {
+	t   testing.TB
+	ctx context.Context // ends with the test
+	cfg config.Config
+
+	node *node.Node
+	srv  *httptest.Server
+	c    *client.Client // with the token
+	anon *client.Client // without
+}
+
+// newEnv boots a node from baseConfig adjusted by setup; it is stopped
+// when the test ends
+func newEnv(t *testing.T, setup ...func(*config.Config)) *env {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	e := &env{t: t, ctx: ctx, cfg: baseConfig()}
+	for _, f := range setup {
+		f(&e.cfg)
+	}
+	// streams end with ctx, so the server has nothing left to wait for
+	t.Cleanup(func() {
+		cancel()
+		e.shutdown()
+	})
+	e.boot()
+	return e
+}
+
+// newPeer boots another node starting from e's chain as it stands, so the
+// two share a genesis block and can sync
+func (e *env) newPeer(t *testing.T, setup ...func(*config.Config)) *env {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "chain.ndjson")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = e.anon.Export(e.ctx, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newEnv(t, append([]func(*config.Config){func(c *config.Config) {
+		c.Import = path
+		c.NodeID = "peer"
+	}}, setup...)...)
+}
+
+// boot starts a node from e.cfg behind a fresh httptest server
+func (e *env) boot() {
+	e.t.Helper()
+	n, err := node.New(e.cfg, node.WithoutHTTP())
+	if err != nil {
+		e.t.Fatalf("booting the node: %v", err)
+	}
+	if err := n.Start(e.ctx); err != nil {
+		n.Stop()
+		e.t.Fatalf("starting the node: %v", err)
+	}
+	e.node = n
+	e.srv = httptest.NewServer(n.Handler())
+	e.c = client.New(e.srv.URL, client.WithToken(token))
+	e.anon = client.New(e.srv.URL)
+	if err := e.c.Ready(e.ctx); err != nil {
+		e.t.Fatalf("the node never got ready: %v", err)
+	}
+}
+
+// shutdown stops the node and its server
+func (e *env) shutdown() {
+	if e.srv != nil {
+		e.srv.Close()
+		e.srv = nil
+	}
+	if e.node != nil {
+		e.node.Stop()
+		e.node = nil
+	}
+}
+
+// restart stops the node and boots another from the same config, so a
+// test can see what survives
+func (e *env) restart() {
+	e.t.Helper()
+	e.shutdown()
+	e.boot()
+}
+
+// noRedirects is an HTTP client that reports redirects instead of
+// following them
+var noRedirects = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+// request sends method path with body straight to the node, with the
+// token when auth is set, and returns the status and the start of the body
+func (e *env) request(method, path, body string, auth bool) (int, string) {
+	e.t.Helper()
+	bearer := ""
+	if auth {
+		bearer = token
+	}
+	return e.requestAs(method, path, body, bearer)
+}
+
+// requestAs is request sending bearer, if any, as the Authorization
+func (e *env) requestAs(method, path, body, bearer string) (int, string) {
+	e.t.Helper()
+	req, err := http.NewRequestWithContext(e.ctx, method, e.srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	resp, err := noRedirects.Do(req)
+	if err != nil {
+		e.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	out, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return resp.StatusCode, strings.TrimSpace(string(out))
+}
+
+// demoConfig allows /tamper, which breaks the invariants debug checks
+func demoConfig(c *config.Config) {
+	c.Demo = true
+	c.Debug = false
+}
+
+// the node is up and reports an empty chain
+func TestStatus(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	st, err := e.anon.Status(e.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.NodeID != e.cfg.NodeID {
+		t.Fatalf("status names node %q, want %q", st.NodeID, e.cfg.NodeID)
+	}
+	expectHeight(t, e.ctx, e.anon, 0)
+	d, err := e.anon.Difficulty(e.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Difficulty != e.cfg.Difficulty {
+		t.Fatalf("difficulty is %d, want %d", d.Difficulty, e.cfg.Difficulty)
+	}
+}
+
+// a submitted transaction is pending, mined into the next block and then
+// found by id, position, search and its mempool record
+func TestSubmitMineSearch(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	data := "integration-" + randomHex()
+	txid := submit(t, e.ctx, e.c, data)
+	pending, err := e.anon.Pending(e.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !holdsTx(pending, txid) {
+		t.Fatalf("%s is not pending after submission", txid)
+	}
+	b := mine(t, e.ctx, e.c)
+	pos := txPosition(b.Txns, txid)
+	if b.Index != 1 || pos < 0 {
+		t.Fatalf("block %d does not carry %s", b.Index, txid)
+	}
+	got, err := e.anon.GetBlock(e.ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Hash != b.Hash {
+		t.Fatalf("GET /block/1 has hash %s, mined %s", got.Hash, b.Hash)
+	}
+	if got, err = e.anon.BlockByHash(e.ctx, b.Hash); err != nil || got.Index != 1 {
+		t.Fatalf("looking up block %s by hash: index %d, %v", b.Hash, got.Index, err)
+	}
+	at, err := e.anon.TxAt(e.ctx, 1, pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if at.Transaction.ID != txid {
+		t.Fatalf("block 1 position %d holds %s, want %s", pos, at.Transaction.ID, txid)
+	}
+	matches, err := e.anon.Search(e.ctx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Transaction.ID != txid || matches[0].BlockIndex != 1 {
+		t.Fatalf("searching %q found %d matches, want %s in block 1", data, len(matches), txid)
+	}
+	st, err := e.anon.WaitForTx(e.ctx, txid, 1, time.Second)
+	if err != nil {
+		t.Fatalf("waiting for %s: %v", txid, err)
+	}
+	if st.Confirmations < 1 {
+		t.Fatalf("%s has %d confirmations, want 1", txid, st.Confirmations)
+	}
+	hist, err := e.anon.MempoolHistory(e.ctx, txid, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hist.Records) != 1 || hist.Records[0].Outcome != api.PendingConfirmed || hist.Records[0].Block != 1 {
+		t.Fatalf("mempool history of %s is %+v, want confirmed in block 1", txid, hist.Records)
+	}
+}
+
+// every transaction of a block is proven into its merkle root, and the tree
+// the node serves is the one the header commits to
+func TestMerkleProof(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	for i := 0; i < 5; i++ {
+		submit(t, e.ctx, e.c, fmt.Sprintf("merkle-%d-%s", i, randomHex()))
+	}
+	b := mine(t, e.ctx, e.c)
+	tree, err := e.anon.MerkleTree(e.ctx, b.Index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := blockchain.BuildMerkleTree(blockchain.DefaultHasher(), b.Txns).Root; tree.Root != b.MerkleRoot || tree.Root != want {
+		t.Fatalf("merkle tree root %s, header %s, recomputed %s", tree.Root, b.MerkleRoot, want)
+	}
+	for i, tx := range b.Txns {
+		leaf := blockchain.BuildMerkleTree(blockchain.DefaultHasher(), []blockchain.Transaction{tx}).Levels[0][0]
+		if err := proveLeaf(tree, i, leaf); err != nil {
+			t.Fatalf("transaction %s: %v", tx.ID, err)
+		}
+	}
+}
+
+// proveLeaf walks from leaf at position pos up tree's levels, hashing in
+// each sibling, and checks it arrives at the root
+func proveLeaf(tree blockchain.MerkleTree, pos int, leaf string) error {
+	if len(tree.Levels) == 0 || pos >= len(tree.Levels[0]) || tree.Levels[0][pos] != leaf {
+		return fmt.Errorf("leaf %d is not the transaction's hash", pos)
+	}
+	h := leaf
+	for l := 0; l < len(tree.Levels)-1; l++ {
+		level := tree.Levels[l]
+		sibling := h // an odd node out is paired with itself
+		if s := pos ^ 1; s < len(level) {
+			sibling = level[s]
+		}
+		if pos%2 == 0 {
+			h = blockchain.DefaultHasher().Hash([]byte(h + sibling))
+		} else {
+			h = blockchain.DefaultHasher().Hash([]byte(sibling + h))
+		}
+		pos /= 2
+		if tree.Levels[l+1][pos] != h {
+			return fmt.Errorf("level %d does not hash to level %d", l, l+1)
+		}
+	}
+	if h != tree.Root {
+		return fmt.Errorf("proof ends at %s, not the root %s", h, tree.Root)
+	}
+	return nil
+}
+
+// the chain validates, stops validating once a confirmed transaction is
+// edited, and validates again once the edit is undone
+func TestValidateTamper(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t, demoConfig)
+	// the tip can't be tampered with, so block 1 needs one on top
+	for i := 0; i < 2; i++ {
+		submit(t, e.ctx, e.c, "honest-"+randomHex())
+		mine(t, e.ctx, e.c)
+	}
+	expectValid(t, e.ctx, e.anon)
+	forged := "forged"
+	if _, err := e.c.Tamper(e.ctx, api.TamperRequest{Block: 1, Tx: 0, Data: &forged}); err != nil {
+		t.Fatal(err)
+	}
+	integrity, err := e.anon.Validate(e.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if integrity.Valid || integrity.Block == nil || *integrity.Block != 1 {
+		t.Fatalf("after tampering block 1, validate reports %+v", integrity)
+	}
+	if _, err := e.c.Untamper(e.ctx); err != nil {
+		t.Fatal(err)
+	}
+	expectValid(t, e.ctx, e.anon)
+}
+
+// coins issued to a wallet are spent by a transfer the node builds and the
+// wallet signs, and a second spend of the same outputs is refused
+func TestSignedTransfer(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	alice, bob := newWallet(t), newWallet(t)
+	issue := blockchain.Transaction{Outputs: []blockchain.TxOutput{{Amount: 100, Lock: script.P2PKH(alice.Address)}}}
+	if _, err := e.c.SubmitTransaction(e.ctx, issue); err != nil {
+		t.Fatal(err)
+	}
+	mine(t, e.ctx, e.c)
+	expectBalance(t, e.ctx, e.anon, alice.Address, 100)
+	pay := signedTransfer(t, e.ctx, e.c, alice, bob.Address, 30, 1)
+	if _, err := e.c.SubmitSigned(e.ctx, pay); err != nil {
+		t.Fatal(err)
+	}
+	// the same outputs again, to someone else
+	again := blockchain.Transaction{
+		Inputs:  append([]blockchain.TxInput(nil), pay.Inputs...),
+		Outputs: []blockchain.TxOutput{{Amount: 99, Lock: script.P2PKH(alice.Address)}},
+	}
+	_, err := e.c.SubmitSigned(e.ctx, sign(t, alice, again))
+	expectStatus(t, err, http.StatusConflict, "spending the same outputs twice")
+	mine(t, e.ctx, e.c)
+	expectBalance(t, e.ctx, e.anon, bob.Address, 30)
+	expectBalance(t, e.ctx, e.anon, alice.Address, 69)
+}
+
+// with signatures required a node takes only transactions their sender signed and inputs unlocked by a
+// signature, whether submitted or in a block from a peer
+func TestSignatures(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t, func(c *config.Config) {
+		c.RequireSignatures = true
+		c.BlockReward = 50
+	})
+	loose := e.newPeer(t) // accepts unsigned transactions
+	alice, bob := newWallet(t), newWallet(t)
+	signer := client.New(e.srv.URL, client.WithToken(token), client.WithKey(alice.PrivateKey))
+
+	st, err := e.anon.Status(e.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Features.SignaturesRequired {
+		t.Fatal("the node does not report signatures as required")
+	}
+
+	submit(t, loose.ctx, loose.c, "unsigned-"+randomHex())
+	relayed, err := json.Marshal(mine(t, loose.ctx, loose.c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code, body := e.request("POST", "/p2p/blocks", string(relayed), true); code != http.StatusBadRequest {
+		t.Fatalf("a peer's block holding an unsigned transaction: %d %s, want 400", code, body)
+	}
+
+	_, err = e.c.SubmitTx(e.ctx, "unsigned-"+randomHex())
+	expectStatus(t, err, http.StatusBadRequest, "submitting unsigned data")
+	issue := blockchain.Transaction{Outputs: []blockchain.TxOutput{{Amount: 100, Lock: script.P2PKH(alice.Address)}}}
+	_, err = e.c.SubmitTransaction(e.ctx, issue)
+	expectStatus(t, err, http.StatusBadRequest, "issuing coins")
+	_, err = signer.SubmitTransaction(e.ctx, issue)
+	expectStatus(t, err, http.StatusBadRequest, "issuing coins, signed")
+	forged, err := blockchain.Transaction{Data: "forged-" + randomHex()}.Sign(bob.PrivateKey, time.Now().Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged.Sender = alice.PublicKey
+	_, err = e.c.SubmitTransaction(e.ctx, forged.Seal())
+	expectStatus(t, err, http.StatusBadRequest, "submitting data signed by someone other than its sender")
+
+	// with nothing pending a block holds only the reward, which funds alice;
+	// without a miner to pay there is nothing to mine
+	reward := mineAs(t, e.ctx, e.c, alice.Address)
+	if len(reward.Txns) != 1 || reward.Txns[0].Coinbase != reward.Index {
+		t.Fatalf("block %d holds %d transactions, want only the coinbase", reward.Index, len(reward.Txns))
+	}
+	expectBalance(t, e.ctx, e.anon, alice.Address, 50)
+	if res, err := e.c.MineAs(e.ctx, ""); err != nil || res.Block != nil {
+		t.Fatalf("mining nothing for no one: %+v, %v; want no block", res, err)
+	}
+	submit(t, e.ctx, signer, "signed-"+randomHex())
+	mine(t, e.ctx, e.c)
+
+	paid, err := signer.Pay(e.ctx, bob.Address, 20, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := mineAs(t, e.ctx, e.c, bob.Address)
+	if pos := txPosition(b.Txns, paid.TxID); pos < 0 || b.Txns[pos].Recipient != bob.Address || b.Txns[pos].Amount != 20 {
+		t.Fatalf("block %d does not hold the payment %s to %s", b.Index, paid.TxID, bob.Address)
+	}
+	expectBalance(t, e.ctx, e.anon, alice.Address, 29)
+	expectBalance(t, e.ctx, e.anon, bob.Address, 70) // fees go unclaimed
+
+	// coins behind a hash lock can't be spent by the preimage alone, even
+	// by a transaction its sender signed
+	preimage := []byte("preimage-" + randomHex())
+	hash := sha256.Sum256(preimage)
+	utx, err := e.c.BuildTransaction(e.ctx, alice.Address, bob.Address, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	utx.Tx.Outputs[0].Lock = script.HashLock(hex.EncodeToString(hash[:]))
+	locked, err := signer.SubmitTransaction(e.ctx, utx.Tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mine(t, e.ctx, e.c)
+	claim := blockchain.Transaction{
+		Inputs:  []blockchain.TxInput{{TxID: locked.TxID, Index: 0, Unlock: script.HashLockUnlock(preimage)}},
+		Outputs: []blockchain.TxOutput{{Amount: 10, Lock: script.P2PKH(bob.Address)}},
+	}
+	_, err = e.c.SubmitTransaction(e.ctx, claim)
+	expectStatus(t, err, http.StatusBadRequest, "claiming a hash lock")
+	_, err = signer.SubmitTransaction(e.ctx, claim)
+	expectStatus(t, err, http.StatusBadRequest, "claiming a hash lock, signed")
+	expectValid(t, e.ctx, e.anon)
+}
+
+// a mining job is tracked to its block, can be mined at a requested
+// difficulty within the node's range, is refused one outside it and can be
+// scheduled blocks pay the block reward to mine_address, which is how a
+// node requiring signatures that nobody mines by hand gets coins
+func TestScheduledReward(t *testing.T) {
+	t.Parallel()
+	miner := newWallet(t)
+	e := newEnv(t, func(c *config.Config) {
+		c.RequireSignatures = true
+		c.BlockReward = 5
+		c.MineEvery = 20 * time.Millisecond
+		c.MineAddress = miner.Address
+	})
+	waitFor(t, e.ctx, "a scheduled block's reward", func() bool {
+		b, err := e.anon.Balance(e.ctx, miner.Address, -1)
+		return err == nil && b.Balance >= 5
+	})
+	blocks, err := e.c.Blocks(e.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tip := blocks[len(blocks)-1]; tip.Miner != miner.Address {
+		t.Fatalf("scheduled block %d credited to %q, want %s", tip.Index, tip.Miner, miner.Address)
+	}
+}
+
+// cancelled
+func TestMineJobs(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t, func(c *config.Config) {
+		c.Difficulty = 2
+		c.MineDifficultyMin, c.MineDifficultyMax = 1, 3
+	})
+	submit(t, e.ctx, e.c, "job-"+randomHex())
+	job, err := e.c.StartMining(e.ctx, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, e.ctx, "the mining job", func() bool {
+		if job, err = e.c.MineStatus(e.ctx, job.ID); err != nil {
+			t.Fatal(err)
+		}
+		return job.Finished()
+	})
+	if job.State != api.JobMined || job.Block == nil || job.Block.Index != 1 {
+		t.Fatalf("mining job ended %s with block %v, want block 1 mined", job.State, job.Block)
+	}
+	submit(t, e.ctx, e.c, "harder-"+randomHex())
+	res, err := e.c.MineAt(e.ctx, "", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Block == nil || res.Block.Difficulty != 3 || !blockchain.MeetsDifficulty(res.Block.Hash, 3) {
+		t.Fatalf("mining at difficulty 3 gave %+v", res.Block)
+	}
+	_, err = e.c.StartMining(e.ctx, "", 4)
+	expectStatus(t, err, http.StatusBadRequest, "mining at difficulty 4, outside 1-3")
+	_, err = e.c.StartMining(e.ctx, "", 1)
+	expectStatus(t, err, http.StatusBadRequest, "mining at difficulty 1, below the 2 in force")
+	_, err = e.c.CancelMining(e.ctx, "no-such-job")
+	expectStatus(t, err, http.StatusNotFound, "cancelling a job that doesn't exist")
+	expectValid(t, e.ctx, e.anon)
+}
+
+// a confirmed reset drops every block and pending transaction
+func TestReset(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	for i := 0; i < 2; i++ {
+		submit(t, e.ctx, e.c, "before-reset-"+randomHex())
+		mine(t, e.ctx, e.c)
+	}
+	submit(t, e.ctx, e.c, "pending-at-reset")
+	_, err := e.c.Reset(e.ctx, "not-the-token")
+	expectStatus(t, err, http.StatusConflict, "resetting with a wrong confirmation")
+	confirm, err := e.c.RequestReset(e.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := e.c.Reset(e.ctx, confirm.Confirm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.DroppedBlocks != 2 || res.DroppedPending != 1 {
+		t.Fatalf("reset dropped %d blocks and %d pending, want 2 and 1", res.DroppedBlocks, res.DroppedPending)
+	}
+	if blocks := expectHeight(t, e.ctx, e.anon, 0); blocks[0].Hash != res.Genesis {
+		t.Fatalf("after the reset the chain starts at %s, want %s", blocks[0].Hash, res.Genesis)
+	}
+	expectValid(t, e.ctx, e.anon)
+}
+
+// a hosted chain keeps its own blocks and mempool apart from the default
+// chain's
+func TestHostedChains(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	if err := e.c.CreateChain(e.ctx, api.ChainSpec{ID: "class", Genesis: "integration"}, api.ChainPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	class := client.New(e.srv.URL+"/chains/class", client.WithToken(token))
+	txid := submit(t, e.ctx, class, "hosted-"+randomHex())
+	if b := mine(t, e.ctx, class); txPosition(b.Txns, txid) < 0 {
+		t.Fatalf("the hosted chain's block 1 does not carry %s", txid)
+	}
+	expectHeight(t, e.ctx, e.anon, 0)
+	chains, err := e.anon.Chains(e.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed := false
+	for _, c := range chains {
+		listed = listed || c.ID == "class"
+	}
+	if !listed {
+		t.Fatal("GET /chains does not list the hosted chain")
+	}
+	expectValid(t, e.ctx, class)
+}
+
+// the chain and mempool survive a restart from the data directory
+func TestPersistence(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t, func(c *config.Config) { c.DataDir = t.TempDir() })
+	var tip blockchain.Block
+	for i := 0; i < 2; i++ {
+		submit(t, e.ctx, e.c, "stored-"+randomHex())
+		tip = mine(t, e.ctx, e.c)
+	}
+	pending := submit(t, e.ctx, e.c, "pending-"+randomHex())
+	e.restart()
+	if blocks := expectHeight(t, e.ctx, e.anon, 2); blocks[2].Hash != tip.Hash {
+		t.Fatalf("after a restart the chain ends in %s, want %s", blocks[2].Hash, tip.Hash)
+	}
+	txs, err := e.anon.Pending(e.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !holdsTx(txs, pending) {
+		t.Fatalf("%s was pending before the restart and is not after", pending)
+	}
+	expectValid(t, e.ctx, e.anon)
+}
+
+// a config still listing the "signed" validator, which signatures replaced,
+// starts with them required
+func TestSignedValidatorAlias(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t, func(c *config.Config) { c.Validators = []string{"printable", "signed"} })
+	st, err := e.anon.Status(e.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Features.SignaturesRequired || len(st.Features.Validators) != 1 || st.Features.Validators[0] != "printable" {
+		t.Fatalf("/status reports %+v, want signatures required and the printable validator", st.Features)
+	}
+	_, err = e.c.SubmitTx(e.ctx, "unsigned-"+randomHex())
+	expectStatus(t, err, http.StatusBadRequest, "submitting unsigned data")
+}
+
+// signatures turned on for a chain with unsigned history: from above its
+// tip the history loads intact, while from below it the node refuses to
+// start, whatever on_corruption says, and leaves the store as it was
+func TestSignaturesMigration(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t, func(c *config.Config) {
+		c.DataDir = t.TempDir()
+		c.OnCorruption = "truncate"
+	})
+	var tip blockchain.Block
+	for i := 0; i < 2; i++ {
+		submit(t, e.ctx, e.c, "unsigned-"+randomHex())
+		tip = mine(t, e.ctx, e.c)
+	}
+	e.shutdown()
+
+	strict := e.cfg
+	strict.RequireSignatures = true
+	if n, err := node.New(strict, node.WithoutHTTP()); err == nil {
+		n.Stop()
+		t.Fatal("a node requiring signatures from genesis loaded unsigned history")
+	} else if !errors.Is(err, blockchain.ErrUnsigned) {
+		t.Fatalf("loading unsigned history under the rule: %v, want ErrUnsigned", err)
+	}
+
+	e.cfg.RequireSignatures = true
+	e.cfg.SignaturesFrom = 3
+	e.boot()
+	if blocks := expectHeight(t, e.ctx, e.anon, 2); blocks[2].Hash != tip.Hash {
+		t.Fatalf("the chain ends in %s after migrating, want %s", blocks[2].Hash, tip.Hash)
+	}
+	st, err := e.anon.Status(e.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Features.SignaturesRequired || st.Features.SignaturesFrom != 3 {
+		t.Fatalf("/status reports %+v, want signatures required from block 3", st.Features)
+	}
+	_, err = e.c.SubmitTx(e.ctx, "unsigned-"+randomHex())
+	expectStatus(t, err, http.StatusBadRequest, "submitting unsigned data after the activation height")
+	expectValid(t, e.ctx, e.anon)
+}
+
+// submitters and miners working at once lose no transaction, confirm none
+// twice and leave a valid chain
+func TestConcurrentSubmitMine(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	var (
+		mu        sync.Mutex
+		submitted = map[string]bool{}
+		left      = int32(*submitters) // submitters still running
+	)
+	// the parallel subtests run together once load's function returns,
+	// and load returns once they all have
+	t.Run("load", func(t *testing.T) {
+		for w := 0; w < *submitters; w++ {
+			w := w
+			t.Run(fmt.Sprintf("submitter-%d", w), func(t *testing.T) {
+				t.Parallel()
+				defer atomic.AddInt32(&left, -1)
+				for i := 0; i < *txsEach; i++ {
+					txid := submit(t, e.ctx, e.c, fmt.Sprintf("concurrent-%d-%d-%s", w, i, randomHex()))
+					mu.Lock()
+					submitted[txid] = true
+					mu.Unlock()
+				}
+			})
+		}
+		// a miner stops once the submitters are done, and after as many
+		// blocks as each sends in case -parallel runs it before them
+		for m := 0; m < *miners; m++ {
+			t.Run(fmt.Sprintf("miner-%d", m), func(t *testing.T) {
+				t.Parallel()
+				for i := 0; i < *txsEach && atomic.LoadInt32(&left) > 0; i++ {
+					if _, err := e.c.Mine(e.ctx); err != nil && !busy(err) {
+						t.Fatalf("mining: %v", err)
+					}
+				}
+			})
+		}
+	})
+	if t.Failed() {
+		return
+	}
+	drain(t, e.ctx, e.c)
+	blocks, err := e.anon.Blocks(e.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]int{}
+	for _, b := range blocks[1:] {
+		for _, tx := range b.Txns {
+			seen[tx.ID]++
+		}
+	}
+	for txid := range submitted {
+		if seen[txid] != 1 {
+			t.Fatalf("%s was confirmed %d times, want once", txid, seen[txid])
+		}
+	}
+	if want := *submitters * *txsEach; len(submitted) != want || len(seen) != want {
+		t.Fatalf("%d transactions accepted and %d confirmed, want %d", len(submitted), len(seen), want)
+	}
+	expectValid(t, e.ctx, e.anon)
+}
+
+// the same transaction submitted from many goroutines at once is accepted
+// exactly once
+func TestConcurrentDuplicates(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	tx := blockchain.NewDataTx("duplicate-" + randomHex())
+	var accepted, refused int32
+	t.Run("submit", func(t *testing.T) {
+		for w := 0; w < *submitters; w++ {
+			t.Run(fmt.Sprintf("submitter-%d", w), func(t *testing.T) {
+				t.Parallel()
+				_, err := e.c.SubmitTransaction(e.ctx, tx)
+				if err == nil {
+					atomic.AddInt32(&accepted, 1)
+					return
+				}
+				expectStatus(t, err, http.StatusConflict, "submitting a duplicate")
+				atomic.AddInt32(&refused, 1)
+			})
+		}
+	})
+	if accepted != 1 || int(refused) != *submitters-1 {
+		t.Fatalf("%d submissions accepted and %d refused as duplicates, want 1 and %d", accepted, refused, *submitters-1)
+	}
+	pending, err := e.anon.Pending(e.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].ID != tx.ID {
+		t.Fatalf("the mempool holds %d transactions, want %s alone", len(pending), tx.ID)
+	}
+}
+
+// nextFollow returns the next message of a /follow stream
+func nextFollow(t *testing.T, ctx context.Context, msgs <-chan api.FollowMessage) api.FollowMessage {
+	t.Helper()
+	select {
+	case m, ok := <-msgs:
+		if !ok {
+			t.Fatal("the follow stream closed")
+		}
+		return m
+	case <-ctx.Done():
+		t.Fatalf("waiting on the follow stream: %v", ctx.Err())
+	}
+	return api.FollowMessage{}
+}
+
+// expectFollowBlock fails t unless m delivers block b
+func expectFollowBlock(t *testing.T, m api.FollowMessage, b blockchain.Block) {
+	t.Helper()
+	if m.Type != api.FollowBlock || m.Block == nil || m.Block.Hash != b.Hash || m.Height != b.Index {
+		t.Fatalf("follow sent %s at height %d, want block %d %s", m.Type, m.Height, b.Index, b.Hash)
+	}
+}
+
+// a follower is replayed the chain from the height it asks for, then sent
+// each block as it is mined, and resumes right where it left off
+func TestFollow(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	var mined []blockchain.Block
+	for i := 0; i < 2; i++ {
+		submit(t, e.ctx, e.c, "followed-"+randomHex())
+		mined = append(mined, mine(t, e.ctx, e.c))
+	}
+	ctx, stop := context.WithCancel(e.ctx)
+	msgs, err := e.anon.FollowChain(ctx, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectFollowBlock(t, nextFollow(t, ctx, msgs), mined[0])
+	expectFollowBlock(t, nextFollow(t, ctx, msgs), mined[1])
+	live := nextFollow(t, ctx, msgs)
+	if live.Type != api.FollowLive || live.Height != 2 || live.Resume == "" {
+		t.Fatalf("after the replay follow sent %+v, want live at height 2 with a resume token", live)
+	}
+	submit(t, e.ctx, e.c, "live-"+randomHex())
+	third := mine(t, e.ctx, e.c)
+	expectFollowBlock(t, nextFollow(t, ctx, msgs), third)
+	stop()
+
+	// resuming from the live message replays only what came after it
+	msgs, err = e.anon.FollowChain(e.ctx, 0, live.Resume)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectFollowBlock(t, nextFollow(t, e.ctx, msgs), third)
+	if m := nextFollow(t, e.ctx, msgs); m.Type != api.FollowLive || m.Height != 3 {
+		t.Fatalf("a resumed follow sent %+v, want live at height 3", m)
+	}
+	if code, body := e.request("GET", "/follow?from_height=99", "", false); code != http.StatusBadRequest {
+		t.Fatalf("following from past the tip: %d %s, want 400", code, body)
+	}
+}
+
+// a node syncing a longer fork from a peer reorganizes onto it: /reorgs
+// records the switch, the transactions only the dropped block carried are
+// pending again, and a follower is rolled back to the fork point
+func TestReorg(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	peer := e.newPeer(t)
+
+	ours := submit(t, e.ctx, e.c, "ours-"+randomHex())
+	dropped := mine(t, e.ctx, e.c)
+	var theirs []blockchain.Block
+	for i := 0; i < 2; i++ {
+		submit(t, peer.ctx, peer.c, "theirs-"+randomHex())
+		theirs = append(theirs, mine(t, peer.ctx, peer.c))
+	}
+	msgs, err := e.anon.FollowChain(e.ctx, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectFollowBlock(t, nextFollow(t, e.ctx, msgs), dropped)
+	if m := nextFollow(t, e.ctx, msgs); m.Type != api.FollowLive {
+		t.Fatalf("follow sent %+v, want live", m)
+	}
+	before, err := e.anon.Reorgs(e.ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.Count != 0 {
+		t.Fatalf("/reorgs counts %d reorganizations before any", before.Count)
+	}
+
+	// adding the peer syncs in the background; syncing again is a no-op then
+	if _, err := e.c.AddPeer(e.ctx, peer.srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.c.Sync(e.ctx); err != nil {
+		t.Fatal(err)
+	}
+	var hist api.ReorgHistory
+	waitFor(t, e.ctx, "the reorg", func() bool {
+		if hist, err = e.anon.Reorgs(e.ctx, 0); err != nil {
+			t.Fatal(err)
+		}
+		return hist.Count > 0
+	})
+	if hist.Count != 1 || hist.MaxDepth != 1 || len(hist.Reorgs) != 1 {
+		t.Fatalf("/reorgs reports %+v, want one reorg 1 block deep", hist)
+	}
+	r := hist.Reorgs[0]
+	if r.Depth != 1 || r.OldTip != (api.TipRef{Index: 1, Hash: dropped.Hash}) || r.NewTip != (api.TipRef{Index: 2, Hash: theirs[1].Hash}) {
+		t.Fatalf("the reorg went from %+v to %+v dropping %d, want %s to %s dropping 1", r.OldTip, r.NewTip, r.Depth, dropped.Hash, theirs[1].Hash)
+	}
+	if strings.TrimRight(r.Peer, "/") != peer.srv.URL || len(r.Requeued) != 1 || r.Requeued[0] != ours {
+		t.Fatalf("the reorg adopted %q and requeued %v, want %s and [%s]", r.Peer, r.Requeued, peer.srv.URL, ours)
+	}
+	if limited, err := e.anon.Reorgs(e.ctx, 1); err != nil || len(limited.Reorgs) != 1 {
+		t.Fatalf("/reorgs?limit=1: %+v, %v", limited, err)
+	}
+	pending, err := e.anon.Pending(e.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].ID != ours {
+		t.Fatalf("after the reorg the mempool holds %d transactions, want %s alone", len(pending), ours)
+	}
+	if blocks := expectHeight(t, e.ctx, e.anon, 2); blocks[2].Hash != theirs[1].Hash {
+		t.Fatalf("after the reorg the tip is %s, want the peer's %s", blocks[2].Hash, theirs[1].Hash)
+	}
+
+	if m := nextFollow(t, e.ctx, msgs); m.Type != api.FollowRollback || m.Height != 0 {
+		t.Fatalf("after the reorg follow sent %+v, want a rollback to height 0", m)
+	}
+	expectFollowBlock(t, nextFollow(t, e.ctx, msgs), theirs[0])
+	expectFollowBlock(t, nextFollow(t, e.ctx, msgs), theirs[1])
+	expectValid(t, e.ctx, e.anon)
+}
+
+// POST /query filters, groups and aggregates the chain's rows
+func TestQuery(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	for i := 0; i < 3; i++ {
+		submit(t, e.ctx, e.c, "queried-"+randomHex())
+	}
+	mine(t, e.ctx, e.c)
+	submit(t, e.ctx, e.c, "queried-"+randomHex())
+	kv := blockchain.Transaction{KV: []blockchain.KVOp{{Op: blockchain.KVSet, Key: "course", Value: "blockchain"}}}.Seal()
+	if _, err := e.c.SubmitTransaction(e.ctx, kv); err != nil {
+		t.Fatal(err)
+	}
+	mine(t, e.ctx, e.c)
+
+	one := 1
+	res, err := e.anon.Query(e.ctx, api.Query{
+		Select:    "transactions",
+		Where:     api.QueryFilter{FromHeight: &one},
+		Aggregate: []string{"count"},
+		GroupBy:   "type",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int64{}
+	for _, g := range res.Groups {
+		counts[g.Key] = g.Values["count"]
+	}
+	if res.Matched != 5 || counts["data"] != 4 || counts["kv"] != 1 {
+		t.Fatalf("counting transactions by type from height 1 gives %d matched, %v, want 5 with data 4 and kv 1", res.Matched, counts)
+	}
+
+	res, err = e.anon.Query(e.ctx, api.Query{Select: "blocks", Where: api.QueryFilter{FromHeight: &one}, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Matched != 2 || !res.Truncated || len(res.Rows) != 1 || res.Rows[0]["index"] != float64(1) {
+		t.Fatalf("the first block from height 1 gives %+v, want block 1 of 2", res)
+	}
+	_, err = e.anon.Query(e.ctx, api.Query{Select: "accounts"})
+	expectStatus(t, err, http.StatusBadRequest, "selecting an unknown kind of row")
+	_, err = e.anon.Query(e.ctx, api.Query{Select: "transactions", Aggregate: []string{"sum:txid"}})
+	expectStatus(t, err, http.StatusBadRequest, "summing a field that isn't numeric")
+}
+
+// /import?dry_run=true reports what an import would skip, apply and end
+// at without touching the chain or mempool, refuses one that would fail,
+// and the import itself then ends where it said
+func TestImportDryRun(t *testing.T) {
+	t.Parallel()
+	src := newEnv(t)
+	dst := src.newPeer(t)
+	diverged := src.newPeer(t)
+	for i := 0; i < 3; i++ {
+		submit(t, src.ctx, src.c, "imported-"+randomHex())
+		mine(t, src.ctx, src.c)
+	}
+	var export, binary, snapshot bytes.Buffer
+	if err := src.anon.Export(src.ctx, &export); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.anon.ExportBinary(src.ctx, &binary); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.anon.ExportSnapshot(src.ctx, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	tip := expectHeight(t, src.ctx, src.anon, 3)[3]
+
+	plan, err := dst.c.PlanImport(dst.ctx, bytes.NewReader(export.Bytes()), "ndjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.DryRun || plan.Fork != 0 || len(plan.Rollback) != 0 || len(plan.Apply) != 3 || plan.Skipped != 1 ||
+		plan.Height != 3 || plan.TipHash != tip.Hash {
+		t.Fatalf("the dry run plans %+v, want genesis skipped and 3 blocks applied up to %s", plan, tip.Hash)
+	}
+	if bplan, err := dst.c.PlanImport(dst.ctx, bytes.NewReader(binary.Bytes()), "binary"); err != nil || bplan.TipHash != plan.TipHash {
+		t.Fatalf("the binary dry run plans %+v, %v, want the ndjson plan's tip %s", bplan, err, plan.TipHash)
+	}
+	expectHeight(t, dst.ctx, dst.anon, 0)
+	broken := bytes.Replace(export.Bytes(), []byte(`"nonce":`), []byte(`"nonce":9`), -1)
+	_, err = dst.c.PlanImport(dst.ctx, bytes.NewReader(broken), "ndjson")
+	expectStatus(t, err, http.StatusBadRequest, "a dry run of a broken import")
+	_, err = dst.anon.PlanImport(dst.ctx, bytes.NewReader(export.Bytes()), "ndjson")
+	expectStatus(t, err, http.StatusUnauthorized, "a dry run without the token")
+
+	if _, err := dst.c.Import(dst.ctx, bytes.NewReader(export.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if blocks := expectHeight(t, dst.ctx, dst.anon, plan.Height); blocks[plan.Height].Hash != plan.TipHash {
+		t.Fatalf("the import ended at %s, the dry run planned %s", blocks[plan.Height].Hash, plan.TipHash)
+	}
+	expectValid(t, dst.ctx, dst.anon)
+
+	// a node with a block of its own can't append the blocks, but can
+	// restore the snapshot over its chain
+	local := submit(t, diverged.ctx, diverged.c, "local-"+randomHex())
+	mine(t, diverged.ctx, diverged.c)
+	_, err = diverged.c.PlanImport(diverged.ctx, bytes.NewReader(export.Bytes()), "ndjson")
+	expectStatus(t, err, http.StatusBadRequest, "a dry run of blocks conflicting with the local chain")
+	splan, err := diverged.c.PlanImport(diverged.ctx, bytes.NewReader(snapshot.Bytes()), "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if splan.Fork != 0 || len(splan.Rollback) != 1 || len(splan.Apply) != 3 || splan.TipHash != tip.Hash {
+		t.Fatalf("the snapshot dry run plans %+v, want block 1 rolled back and 3 applied up to %s", splan, tip.Hash)
+	}
+	expectHeight(t, diverged.ctx, diverged.anon, 1)
+	if pending, err := diverged.anon.Pending(diverged.ctx); err != nil || len(pending) != 0 {
+		t.Fatalf("after the dry runs %d transactions are pending, %v", len(pending), err)
+	}
+	if matches, err := diverged.anon.Search(diverged.ctx, "local-"); err != nil || len(matches) != 1 || matches[0].Transaction.ID != local {
+		t.Fatalf("after the dry runs searching the local block finds %+v, %v", matches, err)
+	}
+}
+
+// /snapshots/{a}/diff/{b} reports the blocks mined between two snapshots,
+// the balances they moved and how the mempool changed
+func TestSnapshotDiff(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	alice := newWallet(t)
+	confirmed := submit(t, e.ctx, e.c, "confirmed-"+randomHex())
+	first, err := e.c.TakeSnapshot(e.ctx, "before")
+	if err != nil {
+		t.Fatal(err)
+	}
+	issue := blockchain.Transaction{Outputs: []blockchain.TxOutput{{Amount: 40, Lock: script.P2PKH(alice.Address)}}}.Seal()
+	if _, err := e.c.SubmitTransaction(e.ctx, issue); err != nil {
+		t.Fatal(err)
+	}
+	b := mine(t, e.ctx, e.c)
+	added := submit(t, e.ctx, e.c, "added-"+randomHex())
+	second, err := e.c.TakeSnapshot(e.ctx, "after")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Height != 0 || first.Pending != 1 || second.Height != 1 || second.Pending != 1 || second.Name != "after" {
+		t.Fatalf("the snapshots are %+v and %+v", first, second)
+	}
+
+	diff, err := e.anon.DiffSnapshots(e.ctx, first.ID, second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Reorganized || len(diff.Blocks) != 1 || diff.Blocks[0].Hash != b.Hash {
+		t.Fatalf("the diff reports blocks %+v (reorganized %t), want block 1 %s", diff.Blocks, diff.Reorganized, b.Hash)
+	}
+	if len(diff.Balances) != 1 || diff.Balances[0].Address != alice.Address || diff.Balances[0].Change != 40 {
+		t.Fatalf("the diff reports balances %+v, want %s up 40", diff.Balances, alice.Address)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].ID != added {
+		t.Fatalf("the diff reports %v added to the mempool, want %s", diff.Added, added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].ID != confirmed || !diff.Removed[0].Confirmed {
+		t.Fatalf("the diff reports %+v removed from the mempool, want %s confirmed", diff.Removed, confirmed)
+	}
+	if _, err := e.anon.DiffSnapshots(e.ctx, first.ID, 99); err == nil {
+		t.Fatal("diffing against a snapshot that doesn't exist succeeded")
+	}
+	if code, body := e.request("GET", "/snapshots/1/diff", "", false); code != http.StatusNotFound {
+		t.Fatalf("GET /snapshots/1/diff: %d %s, want 404", code, body)
+	}
+}
+
+// the faucet pays a wallet from its funded account once per cooldown, for
+// each address and each client IP, and says so when it runs dry
+func TestFaucet(t *testing.T) {
+	t.Parallel()
+	faucet := newWallet(t)
+	e := newEnv(t, func(c *config.Config) {
+		c.FaucetKey = faucet.PrivateKey
+		c.FaucetAmount = 15
+		c.BlockReward = 20
+	})
+	alice, bob := newWallet(t), newWallet(t)
+	_, err := e.anon.Faucet(e.ctx, alice.Address)
+	expectStatus(t, err, http.StatusServiceUnavailable, "the faucet before it holds any coins")
+
+	// fund the faucet with a block reward
+	submit(t, e.ctx, e.c, "funding-"+randomHex())
+	mineAs(t, e.ctx, e.c, faucet.Address)
+	expectBalance(t, e.ctx, e.anon, faucet.Address, 20)
+
+	res, err := e.anon.Faucet(e.ctx, alice.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Amount != 15 || res.TxID == "" {
+		t.Fatalf("the faucet answered %+v, want 15 coins", res)
+	}
+	_, err = e.anon.Faucet(e.ctx, alice.Address)
+	expectStatus(t, err, http.StatusTooManyRequests, "asking the faucet again")
+	_, err = e.anon.Faucet(e.ctx, bob.Address)
+	expectStatus(t, err, http.StatusTooManyRequests, "asking the faucet for another address from the same IP")
+	_, err = e.anon.Faucet(e.ctx, "not-an-address")
+	expectStatus(t, err, http.StatusBadRequest, "asking the faucet for an invalid address")
+
+	mine(t, e.ctx, e.c)
+	expectBalance(t, e.ctx, e.anon, alice.Address, 15)
+	expectBalance(t, e.ctx, e.anon, faucet.Address, 5)
+	expectValid(t, e.ctx, e.anon)
+}
+
+// a watch on a txid is notified when the transaction enters the mempool
+// and once it is as deep as asked, over /watch/ws and the events stream
+func TestWatch(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	tx := blockchain.NewDataTx("watched-" + randomHex())
+	_, err := e.anon.AddWatch(e.ctx, api.Watch{TxID: tx.ID})
+	expectStatus(t, err, http.StatusUnauthorized, "adding a watch without the token")
+	_, err = e.c.AddWatch(e.ctx, api.Watch{TxID: tx.ID, Address: newWallet(t).Address})
+	expectStatus(t, err, http.StatusBadRequest, "watching a txid and an address at once")
+	w, err := e.c.AddWatch(e.ctx, api.Watch{TxID: tx.ID, Confirmations: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	watches, err := e.c.Watches(e.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(watches) != 1 || watches[0].ID != w.ID {
+		t.Fatalf("GET /watch lists %+v, want %s alone", watches, w.ID)
+	}
+	notes, err := e.c.SubscribeWatches(e.ctx, w.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	evs, err := e.anon.Subscribe(e.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := func() api.WatchNotification {
+		t.Helper()
+		select {
+		case n, ok := <-notes:
+			if !ok {
+				t.Fatal("the watch stream closed")
+			}
+			return n
+		case <-e.ctx.Done():
+			t.Fatal("no watch notification arrived")
+		}
+		return api.WatchNotification{}
+	}
+
+	if _, err := e.c.SubmitTransaction(e.ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	if n := next(); n.WatchID != w.ID || n.Status != api.WatchPending || n.TxID != tx.ID {
+		t.Fatalf("on submission the watch was sent %+v, want %s pending", n, tx.ID)
+	}
+	b := mine(t, e.ctx, e.c)
+	submit(t, e.ctx, e.c, "on-top-"+randomHex())
+	mine(t, e.ctx, e.c)
+	if n := next(); n.Status != api.WatchConfirmed || n.Block != b.Index || n.Confirmations != 2 {
+		t.Fatalf("two blocks deep the watch was sent %+v, want confirmed in block %d with 2 confirmations", n, b.Index)
+	}
+	waitFor(t, e.ctx, "the watch on the events stream", func() bool {
+		select {
+		case ev := <-evs:
+			return ev.Type == events.WatchMatched
+		default:
+			return false
+		}
+	})
+	// a watch on a txid ends once it is deep enough
+	waitFor(t, e.ctx, "the finished watch to go", func() bool {
+		watches, err := e.c.Watches(e.ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(watches) == 0
+	})
+
+	addr, err := e.c.AddWatch(e.ctx, api.Watch{Address: newWallet(t).Address})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.c.RemoveWatch(e.ctx, addr.ID); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, e.c.RemoveWatch(e.ctx, addr.ID), http.StatusNotFound, "removing a removed watch")
+}
+
+// /admin/freeze halts mining, submissions and peer blocks until
+// /admin/unfreeze, while reads carry on; both need the node token
+func TestFreeze(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	submit(t, e.ctx, e.c, "before-freeze-"+randomHex())
+	_, err := e.anon.Freeze(e.ctx, "backup")
+	expectStatus(t, err, http.StatusUnauthorized, "freezing without the token")
+	_, err = e.anon.FreezeStatus(e.ctx)
+	expectStatus(t, err, http.StatusUnauthorized, "reading the freeze without the token")
+
+	st, err := e.c.Freeze(e.ctx, "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Frozen || st.Reason != "backup" || st.Height != 0 || st.Pending != 1 {
+		t.Fatalf("freezing reports %+v, want frozen for backup at height 0 with 1 pending", st)
+	}
+	if again, err := e.c.Freeze(e.ctx, "other"); err != nil || again.Reason != "backup" {
+		t.Fatalf("freezing again reports %+v, %v, want the original reason kept", again, err)
+	}
+	_, err = e.c.SubmitTx(e.ctx, "while-frozen")
+	expectStatus(t, err, http.StatusServiceUnavailable, "submitting while frozen")
+	_, err = e.c.StartMining(e.ctx, "", 0)
+	expectStatus(t, err, http.StatusServiceUnavailable, "mining while frozen")
+	_, err = e.c.Sync(e.ctx)
+	expectStatus(t, err, http.StatusServiceUnavailable, "syncing while frozen")
+	expectHeight(t, e.ctx, e.anon, 0)
+	if pending, err := e.anon.Pending(e.ctx); err != nil || len(pending) != 1 {
+		t.Fatalf("reading the mempool while frozen: %d pending, %v", len(pending), err)
+	}
+
+	if st, err = e.c.Unfreeze(e.ctx); err != nil || st.Frozen {
+		t.Fatalf("unfreezing reports %+v, %v", st, err)
+	}
+	mine(t, e.ctx, e.c)
+	expectHeight(t, e.ctx, e.anon, 1)
+}
+
+// key-value writes are confirmed in blocks and read back at any height
+func TestKV(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	set := func(value string) blockchain.Block {
+		tx := blockchain.Transaction{KV: []blockchain.KVOp{{Op: blockchain.KVSet, Key: "grade", Value: value}}}.Seal()
+		if _, err := e.c.SubmitTransaction(e.ctx, tx); err != nil {
+			t.Fatal(err)
+		}
+		return mine(t, e.ctx, e.c)
+	}
+	first := set("B")
+	set("A")
+	if got, err := e.anon.KV(e.ctx, "grade", -1); err != nil || got.Value != "A" {
+		t.Fatalf("grade at the tip is %+v, %v, want A", got, err)
+	}
+	if got, err := e.anon.KV(e.ctx, "grade", first.Index); err != nil || got.Value != "B" {
+		t.Fatalf("grade at block %d is %+v, %v, want B", first.Index, got, err)
+	}
+	_, err := e.anon.KV(e.ctx, "missing", -1)
+	expectStatus(t, err, http.StatusNotFound, "reading a key never set")
+}
+
+// a commitment is confirmed first and its reveal checked against it
+func TestCommitReveal(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	res, salt, err := e.c.SubmitCommitment(e.ctx, "sealed answer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mine(t, e.ctx, e.c)
+	if st, err := e.anon.RevealStatus(e.ctx, res.TxID); err != nil || st.Revealed {
+		t.Fatalf("before the reveal the commitment reports %+v, %v", st, err)
+	}
+	_, err = e.c.Reveal(e.ctx, res.TxID, salt, "another answer")
+	expectStatus(t, err, http.StatusBadRequest, "revealing data that doesn't match the commitment")
+	if _, err := e.c.Reveal(e.ctx, res.TxID, salt, "sealed answer"); err != nil {
+		t.Fatal(err)
+	}
+	mine(t, e.ctx, e.c)
+	if st, err := e.anon.RevealStatus(e.ctx, res.TxID); err != nil || !st.Revealed || st.Data != "sealed answer" {
+		t.Fatalf("after the reveal the commitment reports %+v, %v", st, err)
+	}
+}
+
+// payloads stored off-chain are served back by hash, and a blob
+// transaction commits to one
+func TestBlobs(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t, func(c *config.Config) { c.BlobStore = t.TempDir() })
+	payload := []byte("lecture notes " + randomHex())
+	_, err := e.anon.PutBlob(e.ctx, payload)
+	expectStatus(t, err, http.StatusUnauthorized, "storing a blob without the token")
+	hash, err := e.c.PutBlob(e.ctx, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := e.anon.GetBlob(e.ctx, hash); err != nil || !bytes.Equal(got, payload) {
+		t.Fatalf("GET /blobs/%s gives %q, %v", hash, got, err)
+	}
+	res, err := e.c.SubmitBlob(e.ctx, payload, "notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := mine(t, e.ctx, e.c)
+	if pos := txPosition(b.Txns, res.TxID); pos < 0 || b.Txns[pos].Blob != hash {
+		t.Fatalf("block %d does not carry the blob transaction %s committing to %s", b.Index, res.TxID, hash)
+	}
+}
+
+// admins name addresses; everyone reads the names
+func TestLabels(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	alice := newWallet(t)
+	_, err := e.anon.SetLabel(e.ctx, alice.Address, "alice")
+	expectStatus(t, err, http.StatusUnauthorized, "labelling without the token")
+	if _, err := e.c.SetLabel(e.ctx, alice.Address, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	names, err := e.anon.Labels(e.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0].Address != alice.Address || names[0].Label != "alice" {
+		t.Fatalf("GET /labels lists %+v, want %s as alice", names, alice.Address)
+	}
+	if err := e.c.DeleteLabel(e.ctx, alice.Address); err != nil {
+		t.Fatal(err)
+	}
+	if names, err = e.anon.Labels(e.ctx); err != nil || len(names) != 0 {
+		t.Fatalf("after deleting the label GET /labels lists %+v, %v", names, err)
+	}
+}
+
+// submissions close at the deadline and reopen when it is lifted
+func TestDeadline(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	d, err := e.c.SetDeadline(e.ctx, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Closed {
+		t.Fatalf("a deadline in the past reports %+v, want closed", d)
+	}
+	_, err = e.c.SubmitTx(e.ctx, "late")
+	expectStatus(t, err, http.StatusForbidden, "submitting after the deadline")
+	if d, err = e.c.SetDeadline(e.ctx, time.Time{}); err != nil || d.Closed {
+		t.Fatalf("lifting the deadline reports %+v, %v", d, err)
+	}
+	submit(t, e.ctx, e.c, "on-time-"+randomHex())
+}
+
+// an admin changes the difficulty new blocks are mined to
+func TestDifficultyAdmin(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	_, err := e.anon.SetDifficulty(e.ctx, 2)
+	expectStatus(t, err, http.StatusUnauthorized, "changing the difficulty without the token")
+	change, err := e.c.SetDifficulty(e.ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Previous != 1 || change.Difficulty != 2 {
+		t.Fatalf("changing the difficulty reports %+v, want 1 to 2", change)
+	}
+	submit(t, e.ctx, e.c, "harder-"+randomHex())
+	if b := mine(t, e.ctx, e.c); b.Difficulty != 2 || !blockchain.MeetsDifficulty(b.Hash, 2) {
+		t.Fatalf("block %d was mined at difficulty %d, want 2", b.Index, b.Difficulty)
+	}
+}
+
+// a pending transaction can be previewed before submission and dropped
+// after it
+func TestPreviewAndDrop(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	tx := blockchain.NewDataTx("dropped-" + randomHex())
+	if _, err := e.c.PreviewTransaction(e.ctx, tx); err != nil {
+		t.Fatalf("previewing a valid transaction: %v", err)
+	}
+	if pending, err := e.anon.Pending(e.ctx); err != nil || len(pending) != 0 {
+		t.Fatalf("a preview left %d transactions pending, %v", len(pending), err)
+	}
+	if _, err := e.c.SubmitTransaction(e.ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	_, err := e.anon.DropTransaction(e.ctx, tx.ID)
+	expectStatus(t, err, http.StatusUnauthorized, "dropping without the token")
+	if res, err := e.c.DropTransaction(e.ctx, tx.ID); err != nil || res.TxID != tx.ID {
+		t.Fatalf("dropping %s: %+v, %v", tx.ID, res, err)
+	}
+	if pending, err := e.anon.Pending(e.ctx); err != nil || len(pending) != 0 {
+		t.Fatalf("after the drop %d transactions are pending, %v", len(pending), err)
+	}
+}
+
+// a read-only replica follows its upstream's chain and sends writes there
+func TestReplica(t *testing.T) {
+	t.Parallel()
+	primary := newEnv(t)
+	replica := primary.newPeer(t, func(c *config.Config) {
+		c.Upstream = primary.srv.URL
+		c.FollowEvery = 20 * time.Millisecond
+	})
+	var tip blockchain.Block
+	for i := 0; i < 2; i++ {
+		submit(t, primary.ctx, primary.c, "replicated-"+randomHex())
+		tip = mine(t, primary.ctx, primary.c)
+	}
+	waitFor(t, replica.ctx, "the replica to catch up", func() bool {
+		st, err := replica.anon.ReplicaStatus(replica.ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return st.Height == 2 && st.Lag == 0
+	})
+	if blocks := expectHeight(t, replica.ctx, replica.anon, 2); blocks[2].Hash != tip.Hash {
+		t.Fatalf("the replica's tip is %s, want the primary's %s", blocks[2].Hash, tip.Hash)
+	}
+	if code, body := replica.request("POST", "/transactions", `{"data":"to the replica"}`, true); code != http.StatusTemporaryRedirect {
+		t.Fatalf("writing to the replica: %d %s, want a redirect to the primary", code, body)
+	}
+}
+
+// route is a request TestRoutes makes, with the statuses it expects from
+// a caller without the node token and from one with it
+type route struct {
+	method, path, body string
+	anon, token        int
+}
+
+// routes covers every route the node serves. Paths may name {txid}, a
+// confirmed data transaction, {hash}, the hash of block 1, and {address},
+// an address it paid. Entries run in order, so the writes are arranged not
+// to disturb the ones after them.
+var routes = []route{
+	{"GET", "/", "", 200, 200},
+	{"GET", "/blocks", "", 200, 200},
+	{"GET", "/blocks?page=1&limit=10", "", 200, 200},
+	{"GET", "/blocks?format=binary", "", 200, 200},
+	{"GET", "/blocks/1/merkle-tree", "", 200, 200},
+	{"GET", "/blocks/1/txs/0", "", 200, 200},
+	{"GET", "/block/1", "", 200, 200},
+	{"GET", "/block/hash/{hash}", "", 200, 200},
+	{"GET", "/block/99", "", 404, 404},
+	{"GET", "/export", "", 200, 200},
+	{"GET", "/export/ledger", "", 200, 200},
+	{"POST", "/import?dry_run=true", "", 401, 200},
+	{"POST", "/import", "{}", 401, 400},
+	{"POST", "/transactions", `{"data":"routes"}`, 401, 200},
+	{"DELETE", "/transactions/{txid}", "", 401, 409},
+	{"GET", "/transactions/{txid}/wait?confirmations=1", "", 200, 200},
+	{"POST", "/transactions/build", `{"from":"{address}","to":"{address}","amount":1}`, 401, 200},
+	{"POST", "/transactions/preview", `{"data":"preview"}`, 401, 200},
+	{"POST", "/transactions/submit-signed", `{"data":"unsigned"}`, 401, 400},
+	{"POST", "/mine", "", 401, 202},
+	{"GET", "/mine/status/none", "", 404, 404},
+	{"POST", "/mine/cancel/none", "", 401, 404},
+	{"GET", "/leaderboard", "", 200, 200},
+	{"GET", "/stats", "", 200, 200},
+	{"GET", "/stats/blocktime", "", 200, 200},
+	{"GET", "/metrics", "", 200, 200},
+	{"GET", "/difficulty", "", 200, 200},
+	{"GET", "/difficulty/estimate?difficulty=2", "", 200, 200},
+	{"GET", "/attestations", "", 200, 200},
+	{"GET", "/notarizations", "", 200, 200},
+	{"GET", "/deadline", "", 200, 200},
+	{"GET", "/testvectors", "", 200, 200},
+	{"GET", "/fees/estimate", "", 200, 200},
+	{"POST", "/faucet", `{"address":"{address}"}`, 404, 404},
+	{"GET", "/search?q=routes", "", 200, 200},
+	{"POST", "/query", `{"select":"blocks"}`, 200, 200},
+	{"GET", "/pending", "", 200, 200},
+	{"GET", "/orphans", "", 200, 200},
+	{"GET", "/mempool/history", "", 200, 200},
+	{"GET", "/utxos?address={address}", "", 200, 200},
+	{"GET", "/balance/{address}", "", 200, 200},
+	{"GET", "/address/{address}/history", "", 200, 200},
+	{"POST", "/wallet/new", "", 201, 201},
+	{"GET", "/contracts", "", 200, 200},
+	{"GET", "/receipts?txid={txid}", "", 404, 404},
+	{"GET", "/kv/missing", "", 404, 404},
+	{"POST", "/blobs", "payload", 401, 404},
+	{"GET", "/blobs/00", "", 404, 404},
+	{"POST", "/reveal/{txid}", `{"salt":"00","data":"x"}`, 401, 404},
+	{"GET", "/logs", "", 200, 200},
+	{"GET", "/logs/ws", "", 400, 400},
+	{"POST", "/admin/simulate", `{"blocks":1,"txs_per_block":1}`, 401, 200},
+	{"GET", "/admin/chaos", "", 401, 404},
+	{"GET", "/admin/difficulty", "", 401, 200},
+	{"POST", "/admin/notarize", "", 401, 400},
+	{"GET", "/admin/deadline", "", 401, 200},
+	{"POST", "/admin/reset", "", 401, 200},
+	{"GET", "/admin/freeze", "", 401, 200},
+	{"POST", "/admin/unfreeze", "", 401, 200},
+	{"PUT", "/admin/labels/{address}", `{"label":"routes"}`, 401, 200},
+	{"GET", "/labels", "", 200, 200},
+	{"GET", "/admin/usage", "", 401, 200},
+	{"GET", "/admin/chains/none", "", 401, 404},
+	{"GET", "/readyz", "", 200, 200},
+	{"GET", "/status", "", 200, 200},
+	{"GET", "/validate", "", 200, 200},
+	{"POST", "/tamper", `{"block":1,"tx":0,"data":"forged"}`, 401, 404},
+	{"GET", "/fork-view", "", 200, 200},
+	{"GET", "/peers", "", 200, 200},
+	{"POST", "/peers", `{"url":"not a url"}`, 401, 400},
+	{"POST", "/peers/sync", "", 401, 200},
+	{"GET", "/reorgs", "", 200, 200},
+	{"GET", "/snapshots", "", 200, 200},
+	{"POST", "/snapshots", `{"name":"routes"}`, 401, 201},
+	{"GET", "/snapshots/1", "", 200, 200},
+	{"GET", "/snapshots/1/diff/1", "", 200, 200},
+	{"GET", "/replica", "", 404, 404},
+	{"POST", "/p2p/blocks", "{}", 401, 200},
+	{"GET", "/ws", "", 400, 400},
+	{"GET", "/follow", "", 400, 400},
+	{"GET", "/watch", "", 200, 200},
+	{"POST", "/watch", `{"txid":"{txid}"}`, 401, 201},
+	{"DELETE", "/watch/none", "", 401, 404},
+	{"GET", "/chains", "", 200, 200},
+	{"POST", "/chains", `{"id":"routes"}`, 401, 201},
+	{"GET", "/chains/routes/status", "", 200, 200},
+	{"GET", "/chains/none/status", "", 404, 404},
+	{"DELETE", "/admin/labels/{address}", "", 401, 200},
+	{"POST", "/admin/freeze", `{"reason":"routes"}`, 401, 200},
+}
+
+// every route answers a caller without the token and one with it as it
+// should, and none fails with a server error
+func TestRoutes(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t)
+	kp := newWallet(t)
+	txid := submit(t, e.ctx, e.c, "routes-"+randomHex())
+	issue := blockchain.Transaction{Outputs: []blockchain.TxOutput{{Amount: 10, Lock: script.P2PKH(kp.Address)}}}.Seal()
+	if _, err := e.c.SubmitTransaction(e.ctx, issue); err != nil {
+		t.Fatal(err)
+	}
+	b := mine(t, e.ctx, e.c)
+	expand := strings.NewReplacer("{txid}", txid, "{hash}", b.Hash, "{address}", kp.Address).Replace
+	for _, r := range routes {
+		path, body := expand(r.path), expand(r.body)
+		if code, out := e.request(r.method, path, body, false); code != r.anon {
+			t.Errorf("%s %s without the token: %d %s, want %d", r.method, r.path, code, out, r.anon)
+		}
+		if code, out := e.request(r.method, path, body, true); code != r.token {
+			t.Errorf("%s %s with the token: %d %s, want %d", r.method, r.path, code, out, r.token)
+		}
+	}
+}
+
+// an API key may submit and mine but gets the same 401 as an anonymous
+// caller from the routes that rewrite the chain or run the node
+func TestAPIKeys(t *testing.T) {
+	t.Parallel()
+	e := newEnv(t, demoConfig)
+	key := "routes-key-" + randomHex()
+	e.node.Server().SetPolicy(api.ChainPolicy{APIKeys: []string{key}})
+	keyed := client.New(e.srv.URL, client.WithToken(key))
+	for i := 0; i < 2; i++ {
+		submit(t, e.ctx, keyed, "keyed-"+randomHex())
+		mine(t, e.ctx, keyed)
+	}
+
+	for _, r := range []route{
+		{"POST", "/import?dry_run=true", "", 401, 200},
+		{"POST", "/import", "{}", 401, 400},
+		{"POST", "/tamper", `{"block":1,"tx":0,"data":"forged"}`, 401, 200},
+		{"DELETE", "/tamper", "", 401, 200},
+		{"POST", "/p2p/blocks", "{}", 401, 200},
+		{"POST", "/admin/simulate", `{"blocks":1,"txs_per_block":1}`, 401, 200},
+		{"GET", "/admin/simulate", "", 401, 405},
+	} {
+		if code, out := e.requestAs(r.method, r.path, r.body, key); code != r.anon {
+			t.Errorf("%s %s with an API key: %d %s, want %d", r.method, r.path, code, out, r.anon)
+		}
+		if code, out := e.request(r.method, r.path, r.body, true); code != r.token {
+			t.Errorf("%s %s with the token: %d %s, want %d", r.method, r.path, code, out, r.token)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Package labels keeps a node-local registry of human-readable names for
+// addresses ("faucet", "miner-1", a student's name), so API responses are
+// easier to read. Labels never touch the chain.
+package labels
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+const (
+	// File is the registry's name under a node's data directory
+	File = "labels.json"
+	// MaxLen bounds a label
+	MaxLen = 64
+)
+
+// ErrTaken is returned by Set for a label another address already holds
+var ErrTaken = errors.New("label already in use")
+
+// Label names one address
+type Label struct {
+	Address string `json:"address"`
+	Label   string `json:"label"`
+}
+
+// Registry maps addresses to labels; labels are unique, ignoring case
+type Registry struct {
+	path string // "" keeps the labels in memory only
+
+	mu     sync.Mutex
+	labels map[string]string // address -> label
+}
+
+// New returns an empty in-memory registry
+func New() *Registry {
+	return &Registry{labels: map[string]string{}}
+}
+
+// Open returns the registry saved at path, or an empty one if the file
+// doesn't exist yet; every change is written back to path
+func Open(path string) (*Registry, error) {
+	r := New()
+	r.path = path
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("labels: %w", err)
+	}
+	var saved []Label
+	if err := json.Unmarshal(raw, &saved); err != nil {
+		return nil, fmt.Errorf("labels: %s: %w", path, err)
+	}
+	for _, l := range saved {
+		r.labels[l.Address] = l.Label
+	}
+	return r, nil
+}
+
+// Set labels address, replacing any label it had
+func (r *Registry) Set(address, label string) error {
+	address = strings.ToLower(address)
+	label = strings.TrimSpace(label)
+	if !blockchain.IsAddress(address) {
+		return fmt.Errorf("invalid address %q", address)
+	}
+	if err := checkLabel(label); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for a, l := range r.labels {
+		if a != address && strings.EqualFold(l, label) {
+			return fmt.Errorf("%w: %q names %s", ErrTaken, label, a)
+		}
+	}
+	prev, had := r.labels[address]
+	r.labels[address] = label
+	if err := r.save(); err != nil {
+		if had {
+			r.labels[address] = prev
+		} else {
+			delete(r.labels, address)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes address's label; it returns false if it had none
+func (r *Registry) Delete(address string) (bool, error) {
+	address = strings.ToLower(address)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	prev, ok := r.labels[address]
+	if !ok {
+		return false, nil
+	}
+	delete(r.labels, address)
+	if err := r.save(); err != nil {
+		r.labels[address] = prev
+		return false, err
+	}
+	return true, nil
+}
+
+// Get returns address's label, "" if it has none
+func (r *Registry) Get(address string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.labels[strings.ToLower(address)]
+}
+
+// Lookup returns the address labelled label, ignoring case
+func (r *Registry) Lookup(label string) (string, bool) {
+	label = strings.TrimSpace(label)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for a, l := range r.labels {
+		if strings.EqualFold(l, label) {
+			return a, true
+		}
+	}
+	return "", false
+}
+
+// List returns every label, ordered by address
+func (r *Registry) List() []Label {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.list()
+}
+
+// list is List (caller holds mu)
+func (r *Registry) list() []Label {
+	out := make([]Label, 0, len(r.labels))
+	for a, l := range r.labels {
+		out = append(out, Label{Address: a, Label: l})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Address < out[j].Address })
+	return out
+}
+
+// save writes the registry to its file atomically (caller holds mu)
+func (r *Registry) save() error {
+	if r.path == "" {
+		return nil
+	}
+	raw, err := json.Marshal(r.list())
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("labels: %w", err)
+	}
+	if err = tmp.Chmod(0o644); err == nil {
+		_, err = tmp.Write(raw)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), r.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("labels: %w", err)
+	}
+	return nil
+}
+
+// checkLabel rejects empty, overlong and unprintable labels
+func checkLabel(label string) error {
+	if label == "" || len(label) > MaxLen {
+		return fmt.Errorf("label must be 1-%d bytes", MaxLen)
+	}
+	for _, c := range label {
+		if !unicode.IsPrint(c) {
+			return fmt.Errorf("label %q has unprintable characters", label)
+		}
+	}
+	return nil
+}
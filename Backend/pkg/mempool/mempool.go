@@ -0,0 +1,251 @@
+// Package mempool holds transactions waiting to be mined.
+package mempool
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+var (
+	// ErrEmptyTx is returned when adding a transaction without data
+	ErrEmptyTx = errors.New("transaction data required")
+	// ErrConflict is returned when a transaction spends an output another pending transaction already spends
+	ErrConflict = errors.New("conflicts with a pending transaction")
+	// ErrDuplicate is returned when a transaction with the same txid is already pending
+	ErrDuplicate = errors.New("already pending")
+)
+
+// Mempool is a FIFO of pending transactions with a txid index
+type Mempool struct {
+	mu    sync.Mutex
+	txs   []blockchain.Transaction
+	index map[string]int                 // txid -> occurrences in txs; at most 1 once added through Add
+	spent map[blockchain.OutPoint]string // outpoint -> pending txid spending it
+}
+
+// New returns an empty mempool
+func New() *Mempool {
+	return &Mempool{
+		txs:   []blockchain.Transaction{},
+		index: map[string]int{},
+		spent: map[blockchain.OutPoint]string{},
+	}
+}
+
+// Add queues a transaction for the next block
+func (m *Mempool) Add(tx blockchain.Transaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(tx); err != nil {
+		return err
+	}
+	m.txs = append(m.txs, tx)
+	m.track(tx)
+	return nil
+}
+
+// Check reports the error Add would return for tx, without queuing it
+func (m *Mempool) Check(tx blockchain.Transaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.check(tx)
+}
+
+// check is Check for callers holding mu
+func (m *Mempool) check(tx blockchain.Transaction) error {
+	if tx.Data == "" && len(tx.Outputs) == 0 && tx.Contract == nil && len(tx.KV) == 0 && tx.Confidential == nil &&
+		tx.Commit == "" && tx.Blob == "" {
+		return ErrEmptyTx
+	}
+	if m.index[tx.ID] > 0 {
+		return fmt.Errorf("%w: %s", ErrDuplicate, tx.ID)
+	}
+	if m.conflicts(tx) {
+		return fmt.Errorf("%w: an input of %s is already being spent", ErrConflict, tx.ID)
+	}
+	return nil
+}
+
+// track adds tx to the indexes (caller holds mu)
+func (m *Mempool) track(tx blockchain.Transaction) {
+	m.index[tx.ID]++
+	for _, op := range tx.Spends() {
+		m.spent[op] = tx.ID
+	}
+}
+
+// untrack removes one copy of tx from the indexes (caller holds mu)
+func (m *Mempool) untrack(tx blockchain.Transaction) {
+	if m.index[tx.ID]--; m.index[tx.ID] > 0 {
+		return
+	}
+	delete(m.index, tx.ID)
+	for _, op := range tx.Spends() {
+		delete(m.spent, op)
+	}
+}
+
+// All returns a copy of the pending transactions in arrival order
+func (m *Mempool) All() []blockchain.Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]blockchain.Transaction, len(m.txs))
+	copy(out, m.txs)
+	return out
+}
+
+// IDs returns the distinct txids currently pending
+func (m *Mempool) IDs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]string, 0, len(m.index))
+	for id := range m.index {
+		out = append(out, id)
+	}
+	return out
+}
+
+// Len returns the number of pending transactions
+func (m *Mempool) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.txs)
+}
+
+// Drain removes and returns every pending transaction
+func (m *Mempool) Drain() []blockchain.Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := m.txs
+	m.txs = []blockchain.Transaction{}
+	m.index = map[string]int{}
+	m.spent = map[blockchain.OutPoint]string{}
+	return out
+}
+
+// CheckInvariants verifies the txid and spend indexes match the queued transactions
+func (m *Mempool) CheckInvariants() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := map[string]int{}
+	spends := 0
+	for _, t := range m.txs {
+		if counts[t.ID]++; counts[t.ID] > 1 {
+			continue
+		}
+		for _, op := range t.Spends() {
+			spends++
+			if m.spent[op] != t.ID {
+				return fmt.Errorf("mempool spend of %s by %s not indexed", op, t.ID)
+			}
+		}
+	}
+	if len(counts) != len(m.index) {
+		return fmt.Errorf("mempool holds %d distinct txids but index has %d", len(counts), len(m.index))
+	}
+	for id, n := range counts {
+		if m.index[id] != n {
+			return fmt.Errorf("mempool txid %s indexed %d times, present %d times", id, m.index[id], n)
+		}
+	}
+	if spends != len(m.spent) {
+		return fmt.Errorf("mempool spends %d outputs but index has %d", spends, len(m.spent))
+	}
+	return nil
+}
+
+// RemoveConfirmed drops every pending transaction for which confirmed(txid)
+// is true and returns them
+func (m *Mempool) RemoveConfirmed(confirmed func(txid string) bool) []blockchain.Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.txs[:0]
+	var removed []blockchain.Transaction
+	for _, t := range m.txs {
+		if confirmed(t.ID) {
+			removed = append(removed, t)
+			m.untrack(t)
+			continue
+		}
+		kept = append(kept, t)
+	}
+	m.txs = kept
+	return removed
+}
+
+// RemoveExpired drops every pending transaction that may no longer be
+// included in a block at height and returns them
+func (m *Mempool) RemoveExpired(height int) []blockchain.Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.txs[:0]
+	var removed []blockchain.Transaction
+	for _, t := range m.txs {
+		if t.Expired(height) {
+			removed = append(removed, t)
+			m.untrack(t)
+			continue
+		}
+		kept = append(kept, t)
+	}
+	m.txs = kept
+	return removed
+}
+
+// Remove drops the pending transaction txid, reporting whether there was one
+func (m *Mempool) Remove(txid string) (blockchain.Transaction, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, t := range m.txs {
+		if t.ID == txid {
+			m.untrack(t)
+			m.txs = append(m.txs[:i], m.txs[i+1:]...)
+			return t, true
+		}
+	}
+	return blockchain.Transaction{}, false
+}
+
+// Restore puts txs back at the front of the queue, e.g. after a failed
+// mining attempt. Transactions resubmitted in the meantime are skipped, and
+// ones that now conflict with a transaction accepted in the meantime are
+// dropped and returned.
+func (m *Mempool) Restore(txs []blockchain.Transaction) (conflicts []blockchain.Transaction) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := make([]blockchain.Transaction, 0, len(txs)+len(m.txs))
+	for _, t := range txs {
+		if m.index[t.ID] > 0 {
+			continue
+		}
+		if m.conflicts(t) {
+			conflicts = append(conflicts, t)
+			continue
+		}
+		m.track(t)
+		kept = append(kept, t)
+	}
+	m.txs = append(kept, m.txs...)
+	return conflicts
+}
+
+// Spending reports whether a pending transaction spends op
+func (m *Mempool) Spending(op blockchain.OutPoint) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.spent[op]
+	return ok
+}
+
+// conflicts reports whether another pending transaction spends one of tx's inputs (caller holds mu)
+func (m *Mempool) conflicts(tx blockchain.Transaction) bool {
+	for _, op := range tx.Spends() {
+		if other, ok := m.spent[op]; ok && other != tx.ID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package mempool
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+// spend returns a transaction spending output 0 of txid
+func spend(txid string, amount int64) blockchain.Transaction {
+	return blockchain.Transaction{
+		Inputs:  []blockchain.TxInput{{TxID: txid, Index: 0}},
+		Outputs: []blockchain.TxOutput{{Amount: amount, Lock: "lock"}},
+	}.Seal()
+}
+
+func ids(txs []blockchain.Transaction) []string {
+	out := make([]string, len(txs))
+	for i, t := range txs {
+		out[i] = t.ID
+	}
+	return out
+}
+
+func checkInvariants(t *testing.T, m *Mempool) {
+	t.Helper()
+	if err := m.CheckInvariants(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAddRejectsDuplicatesConflictsAndEmptyTransactions(t *testing.T) {
+	m := New()
+	a := blockchain.NewDataTx("a")
+	parent := blockchain.CalculateHash("parent")
+	if err := m.Add(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add(spend(parent, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add(a); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("adding a pending txid again: %v, want ErrDuplicate", err)
+	}
+	if err := m.Add(spend(parent, 2)); !errors.Is(err, ErrConflict) {
+		t.Errorf("spending a pending input again: %v, want ErrConflict", err)
+	}
+	if err := m.Add(blockchain.Transaction{}.Seal()); !errors.Is(err, ErrEmptyTx) {
+		t.Errorf("adding an empty transaction: %v, want ErrEmptyTx", err)
+	}
+	if err := m.Check(a); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Check on a pending txid: %v, want ErrDuplicate", err)
+	}
+	if m.Len() != 2 {
+		t.Fatalf("mempool holds %d transactions, want 2", m.Len())
+	}
+	checkInvariants(t, m)
+}
+
+func TestRemoveFreesTheTxidAndInputs(t *testing.T) {
+	m := New()
+	parent := blockchain.CalculateHash("parent")
+	first, second := spend(parent, 1), spend(parent, 2)
+	for _, tx := range []blockchain.Transaction{blockchain.NewDataTx("a"), first, blockchain.NewDataTx("b")} {
+		if err := m.Add(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, ok := m.Remove(first.ID); !ok || got.ID != first.ID {
+		t.Fatalf("Remove(%s) = %s, %t", first.ID, got.ID, ok)
+	}
+	if _, ok := m.Remove(first.ID); ok {
+		t.Fatal("removed the same transaction twice")
+	}
+	checkInvariants(t, m)
+	if m.Spending(blockchain.OutPoint{TxID: parent, Index: 0}) {
+		t.Fatal("the removed transaction's input is still marked spent")
+	}
+	if err := m.Add(second); err != nil {
+		t.Fatalf("spending the freed input: %v", err)
+	}
+	want := []string{blockchain.NewDataTx("a").ID, blockchain.NewDataTx("b").ID, second.ID}
+	if got := ids(m.All()); !reflect.DeepEqual(got, want) {
+		t.Fatalf("pending %v, want %v in arrival order", got, want)
+	}
+	checkInvariants(t, m)
+}
+
+func TestRestorePutsTransactionsBackFirst(t *testing.T) {
+	m := New()
+	parent := blockchain.CalculateHash("parent")
+	a, b, c := blockchain.NewDataTx("a"), blockchain.NewDataTx("b"), spend(parent, 1)
+	for _, tx := range []blockchain.Transaction{a, b, c} {
+		if err := m.Add(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	drained := m.Drain()
+	if m.Len() != 0 {
+		t.Fatalf("%d transactions left after Drain", m.Len())
+	}
+
+	// while they were out, b was resubmitted and c's input spent again
+	late := blockchain.NewDataTx("late")
+	rival := spend(parent, 2)
+	for _, tx := range []blockchain.Transaction{late, b, rival} {
+		if err := m.Add(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conflicts := m.Restore(drained)
+	if got, want := ids(conflicts), []string{c.ID}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Restore reported conflicts %v, want %v", got, want)
+	}
+	want := []string{a.ID, late.ID, b.ID, rival.ID}
+	if got := ids(m.All()); !reflect.DeepEqual(got, want) {
+		t.Fatalf("pending %v, want %v", got, want)
+	}
+	checkInvariants(t, m)
+}
+
+func TestRemoveConfirmed(t *testing.T) {
+	m := New()
+	a, b, c := blockchain.NewDataTx("a"), blockchain.NewDataTx("b"), blockchain.NewDataTx("c")
+	for _, tx := range []blockchain.Transaction{a, b, c} {
+		if err := m.Add(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	removed := m.RemoveConfirmed(func(txid string) bool { return txid == b.ID })
+	if got, want := ids(removed), []string{b.ID}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("removed %v, want %v", got, want)
+	}
+	if got, want := ids(m.All()), []string{a.ID, c.ID}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("pending %v, want %v", got, want)
+	}
+	checkInvariants(t, m)
+}
@@ -0,0 +1,135 @@
+package mempool
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+// Limits on the orphan pool
+const (
+	MaxOrphans = 256
+	OrphanTTL  = 30 * time.Minute
+)
+
+// ErrOrphanPoolFull is returned when no more orphans can be held
+var ErrOrphanPoolFull = errors.New("orphan pool full")
+
+// Orphan is a transaction waiting for the transactions it spends from to confirm
+type Orphan struct {
+	Tx      blockchain.Transaction `json:"transaction"`
+	Parents []string               `json:"parents"` // txids it spends from
+	Added   int64                  `json:"added"`   // unix time it arrived
+}
+
+// Orphans holds transactions that arrived before their parents, keyed by txid
+type Orphans struct {
+	mu  sync.Mutex
+	txs map[string]Orphan
+}
+
+// NewOrphans returns an empty orphan pool
+func NewOrphans() *Orphans {
+	return &Orphans{txs: map[string]Orphan{}}
+}
+
+// Add holds tx until its parents confirm; adding a held txid again is a no-op
+func (o *Orphans) Add(tx blockchain.Transaction, now time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.txs[tx.ID]; ok {
+		return nil
+	}
+	if len(o.txs) >= MaxOrphans {
+		return fmt.Errorf("%w: %d transactions are waiting for their inputs", ErrOrphanPoolFull, MaxOrphans)
+	}
+	seen := map[string]bool{}
+	var parents []string
+	for _, op := range tx.Spends() {
+		if !seen[op.TxID] {
+			seen[op.TxID] = true
+			parents = append(parents, op.TxID)
+		}
+	}
+	o.txs[tx.ID] = Orphan{Tx: tx, Parents: parents, Added: now.Unix()}
+	return nil
+}
+
+// Promote removes and returns, oldest first, the orphans whose parents have
+// all confirmed
+func (o *Orphans) Promote(confirmed func(txid string) bool) []blockchain.Transaction {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var ready []Orphan
+next:
+	for id, orphan := range o.txs {
+		for _, p := range orphan.Parents {
+			if !confirmed(p) {
+				continue next
+			}
+		}
+		ready = append(ready, orphan)
+		delete(o.txs, id)
+	}
+	sortOrphans(ready)
+	out := make([]blockchain.Transaction, len(ready))
+	for i, orphan := range ready {
+		out[i] = orphan.Tx
+	}
+	return out
+}
+
+// Expire drops orphans held longer than ttl and returns how many it dropped
+func (o *Orphans) Expire(now time.Time, ttl time.Duration) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	n := 0
+	for id, orphan := range o.txs {
+		if now.Sub(time.Unix(orphan.Added, 0)) > ttl {
+			delete(o.txs, id)
+			n++
+		}
+	}
+	return n
+}
+
+// All returns the held orphans, oldest first
+func (o *Orphans) All() []Orphan {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	out := make([]Orphan, 0, len(o.txs))
+	for _, orphan := range o.txs {
+		out = append(out, orphan)
+	}
+	sortOrphans(out)
+	return out
+}
+
+// Clear drops every held orphan and returns how many there were
+func (o *Orphans) Clear() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	n := len(o.txs)
+	o.txs = map[string]Orphan{}
+	return n
+}
+
+// Len returns the number of held orphans
+func (o *Orphans) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.txs)
+}
+
+func sortOrphans(orphans []Orphan) {
+	sort.Slice(orphans, func(i, j int) bool {
+		if orphans[i].Added != orphans[j].Added {
+			return orphans[i].Added < orphans[j].Added
+		}
+		return orphans[i].Tx.ID < orphans[j].Tx.ID
+	})
+}
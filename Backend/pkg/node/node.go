@@ -0,0 +1,708 @@
+// Package node assembles a complete node (chain, mempool, API server and
+// hosted chains) from a config, so Go programs can embed one in-process.
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"salmanahmed/blockchain/pkg/api"
+	"salmanahmed/blockchain/pkg/blobstore"
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/chaos"
+	"salmanahmed/blockchain/pkg/clock"
+	"salmanahmed/blockchain/pkg/config"
+	"salmanahmed/blockchain/pkg/labels"
+	"salmanahmed/blockchain/pkg/mempool"
+	"salmanahmed/blockchain/pkg/store"
+	"salmanahmed/blockchain/pkg/validators"
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+const (
+	// hashrateSample is how long --auto-difficulty benchmarks the hasher
+	hashrateSample = 500 * time.Millisecond
+)
+
+var (
+	// ErrStarted is returned by Start on a node that is already running
+	ErrStarted = errors.New("node already started")
+	// ErrStopped is returned by Start on a node that has been stopped
+	ErrStopped = errors.New("node stopped")
+)
+
+// Node is a running or runnable node
+type Node struct {
+	cfg    config.Config
+	listen bool
+	srv    *api.Server
+	chains *api.Chains
+	store  *store.Store // nil without a data directory
+
+	mu      sync.Mutex
+	started bool
+	stopped bool
+	cancel  context.CancelFunc // stops the background loops: seed sync, scheduled mining, replication
+	loops   sync.WaitGroup
+	http    *http.Server
+	addr    net.Addr
+	done    chan struct{} // closed once the listener has stopped
+	err     error         // why the listener stopped, nil after Stop
+}
+
+// Option configures a Node
+type Option func(*Node)
+
+// WithoutHTTP skips the HTTP listener; the node is then driven through
+// Server(), Chains() or Handler()
+func WithoutHTTP() Option {
+	return func(n *Node) { n.listen = false }
+}
+
+// New builds a node from cfg without starting it
+func New(cfg config.Config, opts ...Option) (_ *Node, err error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	n := &Node{listen: true, done: make(chan struct{})}
+	for _, o := range opts {
+		o(n)
+	}
+
+	if cfg.AutoDifficulty {
+		workers := (&blockchain.ProofOfWork{Workers: cfg.MineWorkers}).Parallelism()
+		rate := blockchain.MeasureHashrate(blockchain.DefaultHasher(), hashrateSample, workers)
+		cfg.Difficulty = blockchain.DifficultyFor(rate, cfg.BlockTime)
+		log.Printf("measured %.0f hashes/s: difficulty %d mines a block in about %s (target %s)",
+			rate, cfg.Difficulty, blockchain.ExpectedBlockTime(rate, cfg.Difficulty).Round(time.Millisecond), cfg.BlockTime)
+	}
+
+	var faults *chaos.Injector
+	clk := clock.Real
+	if cfg.Chaos {
+		faults = chaos.New()
+		clk = faults.Clock(clock.Real)
+		log.Printf("fault injection enabled at /admin/chaos")
+	}
+
+	if cfg.FaucetKey != "" {
+		kp, err := wallet.FromPrivateKey(cfg.FaucetKey)
+		if err != nil {
+			return nil, fmt.Errorf("faucet key: %w", err)
+		}
+		log.Printf("faucet enabled: fund %s to serve POST /faucet", kp.Address)
+	}
+	if cfg.AttestKey != "" {
+		kp, err := wallet.FromPrivateKey(cfg.AttestKey)
+		if err != nil {
+			return nil, fmt.Errorf("attest key: %w", err)
+		}
+		log.Printf("attesting finality every %d blocks at depth %d as %s", cfg.AttestEvery, cfg.AttestDepth, kp.Address)
+	}
+	cfg.Upstream = strings.TrimRight(cfg.Upstream, "/")
+	if cfg.NodeID == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			host = "localhost"
+		}
+		cfg.NodeID = host + ":" + strconv.Itoa(cfg.Port)
+	}
+	for _, name := range cfg.Validators {
+		if name == validators.Signed {
+			log.Printf("the %q validator is deprecated: set require_signatures instead", name)
+		}
+	}
+	if cfg.SignaturesRequired() {
+		log.Printf("requiring signed transactions from block %d", cfg.SignaturesFrom)
+	}
+	if d := cfg.Deadline(); !d.IsZero() {
+		log.Printf("submissions close at %s", d.UTC().Format(time.RFC3339))
+	}
+
+	var blobs blobstore.Store
+	if cfg.BlobStore != "" {
+		if blobs, err = blobstore.Open(cfg.BlobStore, cfg.S3Endpoint, cfg.S3Region); err != nil {
+			return nil, err
+		}
+	}
+
+	// the default chain resumes from the data directory when it holds one
+	var (
+		st            *store.Store
+		stored        []blockchain.Block
+		storedPending []blockchain.Transaction
+		unreadable    error // the block log breaks off after stored
+		srv           *api.Server
+	)
+	if cfg.DataDir != "" {
+		if st, err = store.Open(cfg.DataDir, faults); err != nil {
+			return nil, err
+		}
+		// a node that fails to start releases what it opened; servers stop
+		// first, as in Stop, since they write to the store
+		defer func() {
+			if err == nil {
+				return
+			}
+			if n.chains != nil {
+				n.chains.Close()
+			} else if srv != nil {
+				srv.Close()
+			}
+			st.Close()
+		}()
+		stored, storedPending, err = st.Load()
+		if errors.Is(err, store.ErrCorrupt) {
+			unreadable, err = err, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	// address labels are shared by every hosted chain
+	names := labels.New()
+	if cfg.DataDir != "" {
+		if names, err = labels.Open(filepath.Join(cfg.DataDir, labels.File)); err != nil {
+			return nil, err
+		}
+	}
+
+	// initialize each chain with its genesis block, or the stored or
+	// imported one
+	var imported *blockchain.Block
+	switch {
+	case len(stored) > 0:
+		if err := checkGenesis(stored[0]); err != nil {
+			return nil, fmt.Errorf("%s: %w", cfg.DataDir, err)
+		}
+		imported = &stored[0]
+		if cfg.Import != "" {
+			log.Printf("ignoring --import %s: %s already holds a chain", cfg.Import, cfg.DataDir)
+		}
+		if cfg.Snapshot != "" && cfg.OnCorruption != "snapshot" {
+			log.Printf("ignoring --snapshot %s: %s already holds a chain", cfg.Snapshot, cfg.DataDir)
+		}
+	case cfg.Import != "":
+		g, err := readGenesis(cfg.Import)
+		if err != nil {
+			return nil, err
+		}
+		imported = &g
+	}
+	// shared by every chain, so the limits hold node-wide
+	limits := api.NewLimits(api.LimitOptions{
+		Read:  api.ClassLimit{Concurrency: cfg.ReadConcurrency, Timeout: cfg.ReadTimeout},
+		Mine:  api.ClassLimit{Concurrency: cfg.MineConcurrency, Timeout: cfg.MineTimeout},
+		Admin: api.ClassLimit{Concurrency: cfg.AdminConcurrency, Timeout: cfg.AdminTimeout},
+	})
+	newServer := func(spec api.ChainSpec) (*api.Server, error) {
+		difficulty := cfg.Difficulty
+		if spec.Difficulty > 0 {
+			difficulty = spec.Difficulty
+		}
+		genesis := spec.Genesis
+		if genesis == "" {
+			genesis = blockchain.GenesisTx
+		}
+		first := blockchain.NewGenesisBlockData(blockchain.DefaultHasher(), clock.Real, genesis)
+		if spec.ID == "" && imported != nil {
+			first = *imported
+		}
+		chain := blockchain.NewChainFromGenesis(first,
+			&blockchain.ProofOfWork{Difficulty: difficulty, Clock: clk, Workers: cfg.MineWorkers},
+			blockchain.DefaultHasher())
+		if err := registerValidators(chain, cfg); err != nil {
+			return nil, err
+		}
+		if cfg.SignaturesRequired() {
+			chain.RequireSignatures(cfg.SignaturesFrom)
+		}
+		chain.SetBlockReward(cfg.BlockReward)
+		if err := chain.SetRetarget(blockchain.Retarget{Every: cfg.RetargetBlocks, Target: cfg.BlockTime}); err != nil {
+			return nil, err
+		}
+		if err := chain.SetDifficultyOverrides(blockchain.DifficultyRange{Min: cfg.MineDifficultyMin, Max: cfg.MineDifficultyMax}); err != nil {
+			return nil, err
+		}
+		// only the default chain is stored, attested and notarized
+		var chainStore *store.Store
+		var attest api.AttestOptions
+		var notarize api.NotarizeOptions
+		var upstream string
+		var mineEvery time.Duration
+		var mineAddress string
+		if spec.ID == "" {
+			chainStore = st
+			attest = api.AttestOptions{Key: cfg.AttestKey, Every: cfg.AttestEvery, Depth: cfg.AttestDepth}
+			notarize = api.NotarizeOptions{
+				Node:    strings.TrimRight(cfg.NotarizeNode, "/"),
+				Token:   cfg.NotarizeToken,
+				Key:     cfg.NotarizeKey,
+				Webhook: cfg.NotarizeWebhook,
+				Every:   cfg.NotarizeEvery,
+			}
+			upstream = cfg.Upstream
+			mineEvery = cfg.MineEvery
+			mineAddress = cfg.MineAddress
+		}
+		return api.NewServer(chain, mempool.New(), api.Options{
+			Debug:       cfg.Debug,
+			CORSOrigins: cfg.CORSOrigins,
+			AuthToken:   cfg.AuthToken,
+			Clock:       clk,
+			Chaos:       faults,
+			Demo:        cfg.Demo,
+			Blobs:       blobs,
+			Store:       chainStore,
+			Labels:      names,
+			Faucet: api.FaucetOptions{
+				Key:      cfg.FaucetKey,
+				Amount:   cfg.FaucetAmount,
+				Cooldown: cfg.FaucetCooldown,
+			},
+			Attest:        attest,
+			Notarize:      notarize,
+			Deadline:      cfg.Deadline(),
+			Limits:        limits,
+			MaxPending:    cfg.MaxPending,
+			MaxBlockTxns:  cfg.MaxBlockTxns,
+			MaxTxBytes:    cfg.MaxTxBytes,
+			MaxBlockBytes: cfg.MaxBlockBytes,
+			PendingTTL:    cfg.PendingTTL,
+			RatePerIP:     cfg.RatePerIP,
+			ReadOnlyAdmin: cfg.ReadOnlyAdmin,
+			Upstream:      upstream,
+			NodeID:        cfg.NodeID,
+			ChainID:       spec.ID,
+			MineEvery:     mineEvery,
+			MineAddress:   mineAddress,
+			Profile:       cfg.Profile,
+		}), nil
+	}
+	srv, err = newServer(api.ChainSpec{})
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range cfg.Peers {
+		if _, err := srv.Peers().Add(p); err != nil {
+			log.Printf("skipping seed peer: %v", err)
+		}
+	}
+	if st != nil {
+		if err := loadStored(srv, st, cfg, stored, storedPending, unreadable); err != nil {
+			return nil, fmt.Errorf("%s: %w", cfg.DataDir, err)
+		}
+	}
+	fresh := len(stored) == 0 && unreadable == nil
+	if cfg.Import != "" && fresh {
+		if err := importFile(srv, cfg.Import); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Snapshot != "" && fresh {
+		if err := restoreFile(srv, cfg.Snapshot); err != nil {
+			return nil, err
+		}
+	}
+	n.cfg = cfg
+	n.store = st
+	n.srv = srv
+	n.chains = api.NewChains(srv, newServer)
+	for _, id := range cfg.Chains {
+		if _, err := n.chains.Create(api.ChainSpec{ID: id}, api.ChainPolicy{}); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// Server returns the default chain's server
+func (n *Node) Server() *api.Server {
+	return n.srv
+}
+
+// Chains returns the node's hosted chains
+func (n *Node) Chains() *api.Chains {
+	return n.chains
+}
+
+// Handler returns the node's HTTP routes, for serving it some other way
+func (n *Node) Handler() http.Handler {
+	return n.chains.Handler()
+}
+
+// Start begins serving HTTP on the configured port, unless WithoutHTTP was
+// given. The node stops when ctx is done or Stop is called.
+func (n *Node) Start(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	switch {
+	case n.stopped:
+		return ErrStopped
+	case n.started:
+		return ErrStarted
+	}
+	n.started = true
+	bg, cancel := context.WithCancel(context.Background())
+	n.cancel = cancel
+	if len(n.cfg.Peers) > 0 {
+		n.background(func() { n.syncSeeds(bg) })
+	}
+	if n.cfg.MineEvery > 0 {
+		n.background(func() { n.mineEvery(bg, n.cfg.MineEvery) })
+	}
+	if n.srv.Replica() {
+		n.background(func() { n.follow(bg) })
+	}
+	if n.cfg.NotarizeNode != "" || n.cfg.NotarizeWebhook != "" {
+		n.background(func() { n.notarize(bg) })
+	}
+	if n.cfg.PendingTTL > 0 {
+		n.background(func() { n.expirePending(bg) })
+	}
+	if n.cfg.ResetEvery > 0 {
+		n.background(func() { n.resetEvery(bg, n.cfg.ResetEvery) })
+	}
+	if !n.listen {
+		go func() {
+			select {
+			case <-ctx.Done():
+				n.Stop()
+			case <-n.done:
+			}
+		}()
+		return nil
+	}
+	ln, err := net.Listen("tcp", n.cfg.Addr())
+	if err != nil {
+		n.stopped = true
+		close(n.done)
+		return err
+	}
+	n.addr = ln.Addr()
+	n.http = &http.Server{Handler: n.Handler()}
+	log.Printf("Starting backend on %s as node %s", n.addr, n.cfg.NodeID)
+	go func() {
+		err := n.http.Serve(ln)
+		n.mu.Lock()
+		if !errors.Is(err, http.ErrServerClosed) {
+			n.err = err
+		}
+		n.mu.Unlock()
+		n.Stop()
+	}()
+	go func() {
+		select {
+		case <-ctx.Done():
+			n.Stop()
+		case <-n.done:
+		}
+	}()
+	return nil
+}
+
+// Addr returns the address the HTTP listener is bound to, or nil when the
+// node isn't listening
+func (n *Node) Addr() net.Addr {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.addr
+}
+
+// Stop shuts the node down: the background loops stop, then in-flight
+// requests and mining jobs get the configured shutdown timeout to finish
+// before they are aborted, and the store is closed last. It is safe to
+// call more than once.
+func (n *Node) Stop() error {
+	n.mu.Lock()
+	if n.stopped {
+		n.mu.Unlock()
+		return nil
+	}
+	n.stopped = true
+	hs, cancel := n.http, n.cancel
+	n.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	var err error
+	if hs != nil {
+		log.Printf("shutting down, waiting up to %s for in-flight requests", n.cfg.ShutdownTimeout)
+		ctx, cancel := context.WithTimeout(context.Background(), n.cfg.ShutdownTimeout)
+		defer cancel()
+		if err = hs.Shutdown(ctx); err != nil {
+			// streaming clients (events, logs) don't finish on their own
+			err = hs.Close()
+		}
+	}
+	n.loops.Wait()
+	// blocks mined in the background are stored, so stop before the store
+	n.chains.Close()
+	if n.store != nil {
+		n.store.Close()
+	}
+	close(n.done)
+	return err
+}
+
+// Wait blocks until the node stops and returns the error that stopped it,
+// or nil when it was stopped deliberately
+func (n *Node) Wait() error {
+	<-n.done
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.err
+}
+
+// background runs loop until it returns, which Stop waits for (caller holds mu)
+func (n *Node) background(loop func()) {
+	n.loops.Add(1)
+	go func() {
+		defer n.loops.Done()
+		loop()
+	}()
+}
+
+// mineEvery mines a block on the default chain every interval until ctx ends
+func (n *Node) mineEvery(ctx context.Context, interval time.Duration) {
+	log.Printf("mining a block every %s", interval)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			// a frozen chain skips its scheduled blocks until unfrozen
+			if _, err := n.srv.MineScheduled(ctx); err != nil && ctx.Err() == nil && !errors.Is(err, api.ErrFrozen) {
+				log.Printf("scheduled mining failed: %v", err)
+			}
+		}
+	}
+}
+
+// follow keeps the default chain in step with the upstream until ctx ends
+func (n *Node) follow(ctx context.Context) {
+	log.Printf("read-only replica of %s, polling every %s", n.cfg.Upstream, n.cfg.FollowEvery)
+	n.srv.Follow(ctx, n.cfg.FollowEvery)
+}
+
+// notarize publishes the default chain's tip off the node until ctx ends
+func (n *Node) notarize(ctx context.Context) {
+	log.Printf("notarizing the tip every %s", n.cfg.NotarizeEvery)
+	n.srv.Notarize(ctx)
+}
+
+// expirePending drops transactions pending longer than PendingTTL from the
+// default chain's mempool until ctx ends, checking at least once a minute;
+// other chains drop them when they mine
+func (n *Node) expirePending(ctx context.Context) {
+	log.Printf("dropping transactions pending for over %s", n.cfg.PendingTTL)
+	every := n.cfg.PendingTTL
+	if every > time.Minute {
+		every = time.Minute
+	}
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			n.srv.ExpirePending(ctx)
+		}
+	}
+}
+
+// resetEvery wipes the default chain back to genesis on a schedule until
+// ctx ends
+func (n *Node) resetEvery(ctx context.Context, interval time.Duration) {
+	log.Printf("resetting the chain every %s", interval)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			n.srv.ResetScheduled(ctx)
+		}
+	}
+}
+
+// syncSeeds catches the default chain up with the seed peers
+func (n *Node) syncSeeds(ctx context.Context) {
+	res, err := n.srv.Sync(ctx)
+	switch {
+	case err != nil:
+		log.Printf("syncing with seed peers failed: %v", err)
+	case res.Replaced:
+		log.Printf("adopted %s's chain, height %d", res.Peer, res.Height)
+	}
+}
+
+// readGenesis returns the first block of an ndjson export
+func readGenesis(path string) (blockchain.Block, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return blockchain.Block{}, err
+	}
+	defer f.Close()
+	var g blockchain.Block
+	if err := json.NewDecoder(f).Decode(&g); err != nil {
+		return g, fmt.Errorf("import %s: %v", path, err)
+	}
+	if err := checkGenesis(g); err != nil {
+		return g, fmt.Errorf("import %s: %w", path, err)
+	}
+	return g, nil
+}
+
+// checkGenesis verifies that g can start a chain
+func checkGenesis(g blockchain.Block) error {
+	if g.Index != 0 || g.Hash != blockchain.HashBlock(blockchain.DefaultHasher(), g) {
+		return errors.New("first block is not a valid genesis block")
+	}
+	return nil
+}
+
+// importFile appends the rest of an ndjson export to srv's chain
+func importFile(srv *api.Server, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	res, err := srv.ImportBlocks(context.Background(), f)
+	if err != nil {
+		return fmt.Errorf("import %s: %w", path, err)
+	}
+	log.Printf("imported %d blocks from %s, height %d", res.Imported, path, res.Height)
+	return nil
+}
+
+// loadStored replays the stored chain onto srv. A chain failing
+// verification, or a block log that breaks off unreadable, is repaired as
+// cfg.OnCorruption says: refused, truncated before the first bad block with
+// the transactions of the blocks dropped requeued, or replaced by
+// cfg.Snapshot. Sound blocks refused only because signatures are required
+// below them are never repaired away: loading stops so the operator can set
+// signatures_from_height.
+func loadStored(srv *api.Server, st *store.Store, cfg config.Config, blocks []blockchain.Block, pending []blockchain.Transaction, unreadable error) error {
+	ctx := context.Background()
+	bad, err := len(blocks), unreadable
+	// an unreadable genesis leaves nothing to load, and loading no blocks
+	// would start the log over
+	if len(blocks) > 0 || unreadable == nil {
+		loadErr := srv.LoadChain(ctx, blocks, pending)
+		var corrupt *api.CorruptChainError
+		switch {
+		case errors.As(loadErr, &corrupt):
+			bad, err = corrupt.Block, loadErr
+		case errors.Is(loadErr, blockchain.ErrUnsigned):
+			return fmt.Errorf("%w; to require signatures on a chain with unsigned history, set signatures_from_height above its tip, height %d", loadErr, len(blocks)-1)
+		case loadErr != nil:
+			return loadErr
+		case err == nil:
+			if len(blocks) > 0 {
+				log.Printf("loaded %d blocks and %d pending transactions from %s", len(blocks), len(pending), cfg.DataDir)
+			}
+			return nil
+		}
+	}
+	switch cfg.OnCorruption {
+	case "truncate":
+		if bad == 0 {
+			return fmt.Errorf("%w; the genesis block is bad, so there is nothing to truncate to", err)
+		}
+		log.Printf("%s: %v", cfg.DataDir, err)
+		if bad < len(blocks) {
+			// LoadChain left the chain at genesis
+			for _, b := range blocks[bad:] {
+				for _, tx := range b.Txns {
+					if tx.Coinbase == 0 {
+						pending = append(pending, tx)
+					}
+				}
+			}
+			if err := srv.LoadChain(ctx, blocks[:bad], pending); err != nil {
+				return err
+			}
+		}
+		if err := st.Rewrite(blocks[:bad]); err != nil {
+			return err
+		}
+		log.Printf("repaired %s: truncated the chain to block %d, dropping everything after", cfg.DataDir, bad-1)
+		return nil
+	case "snapshot":
+		log.Printf("%s: %v", cfg.DataDir, err)
+		if err := restoreFile(srv, cfg.Snapshot); err != nil {
+			return err
+		}
+		log.Printf("repaired %s: replaced the chain with snapshot %s", cfg.DataDir, cfg.Snapshot)
+		return nil
+	}
+	return fmt.Errorf("%w (start with --on-corruption truncate or snapshot to repair it)", err)
+}
+
+// restoreFile replaces srv's chain and mempool with a snapshot file's
+func restoreFile(srv *api.Server, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var snap api.ChainSnapshot
+	if err := json.NewDecoder(f).Decode(&snap); err != nil {
+		return fmt.Errorf("snapshot %s: %v", path, err)
+	}
+	res, err := srv.RestoreChain(context.Background(), snap)
+	if err != nil {
+		return fmt.Errorf("snapshot %s: %w", path, err)
+	}
+	log.Printf("restored %d blocks and %d pending transactions from %s, height %d", len(snap.Blocks), res.Pending, path, res.Height)
+	return nil
+}
+
+// registerValidators installs the configured built-in, plugin and WASM validators
+func registerValidators(chain *blockchain.Chain, cfg config.Config) error {
+	for _, name := range cfg.Validators {
+		if name == validators.Signed {
+			continue // see config.Config.SignaturesRequired
+		}
+		v, err := validators.Builtin(name)
+		if err != nil {
+			return err
+		}
+		chain.RegisterTxValidator(name, v)
+	}
+	for _, path := range cfg.ValidatorPlugins {
+		name, v, err := validators.LoadPlugin(path)
+		if err != nil {
+			return err
+		}
+		chain.RegisterTxValidator(name, v)
+		log.Printf("loaded validator plugin %s", name)
+	}
+	for _, path := range cfg.ValidatorWASM {
+		name, v, err := validators.LoadWASM(path)
+		if err != nil {
+			return err
+		}
+		chain.RegisterTxValidator(name, v)
+		log.Printf("loaded WASM validator %s", name)
+	}
+	return nil
+}
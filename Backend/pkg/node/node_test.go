@@ -0,0 +1,357 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"salmanahmed/blockchain/pkg/api"
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/config"
+	"salmanahmed/blockchain/pkg/store"
+)
+
+// freePort returns a TCP port nothing is listening on
+func freePort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestStartAndStop(t *testing.T) {
+	n, err := New(config.Default(), WithoutHTTP())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Start(context.Background()); !errors.Is(err, ErrStarted) {
+		t.Fatalf("starting a running node: %v, want ErrStarted", err)
+	}
+	if n.Addr() != nil {
+		t.Fatalf("a node without HTTP listens on %s", n.Addr())
+	}
+	if err := n.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Stop(); err != nil {
+		t.Fatalf("stopping twice: %v", err)
+	}
+	if err := n.Wait(); err != nil {
+		t.Fatalf("a node stopped on purpose reports %v", err)
+	}
+	if err := n.Start(context.Background()); !errors.Is(err, ErrStopped) {
+		t.Fatalf("restarting a stopped node: %v, want ErrStopped", err)
+	}
+}
+
+func TestServesHTTPUntilTheContextEnds(t *testing.T) {
+	cfg := config.Default()
+	cfg.Port = freePort(t)
+	n, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := n.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.Get("http://" + n.Addr().String() + "/blocks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("GET /blocks: %s", res.Status)
+	}
+
+	cancel()
+	done := make(chan error, 1)
+	go func() { done <- n.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("a node stopped by its context reports %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the node kept running after its context ended")
+	}
+}
+
+func TestNewAppliesTheConfiguredValidators(t *testing.T) {
+	cfg := config.Default()
+	cfg.Validators = []string{"student-id"}
+	cfg.Chains = []string{"extra"}
+	n, err := New(cfg, WithoutHTTP())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Stop()
+	extra, ok := n.Chains().Get("extra")
+	if !ok {
+		t.Fatal("the configured chain isn't hosted")
+	}
+	ctx := context.Background()
+	for name, srv := range map[string]interface {
+		AddTransaction(context.Context, blockchain.Transaction) error
+	}{"default": n.Server(), "extra": extra} {
+		if err := srv.AddTransaction(ctx, blockchain.NewDataTx("hello")); !errors.Is(err, blockchain.ErrTxRejected) {
+			t.Errorf("%s chain: submitting a non-ID: %v, want ErrTxRejected", name, err)
+		}
+		if err := srv.AddTransaction(ctx, blockchain.NewDataTx("i23-0001")); err != nil {
+			t.Errorf("%s chain: submitting an ID: %v", name, err)
+		}
+	}
+}
+
+func TestNewRejectsBadConfig(t *testing.T) {
+	for name, setup := range map[string]func(*config.Config){
+		"unknown validator": func(c *config.Config) { c.Validators = []string{"nope"} },
+		"missing plugin":    func(c *config.Config) { c.ValidatorPlugins = []string{t.TempDir() + "/missing.so"} },
+		"bad chain id":      func(c *config.Config) { c.Chains = []string{"Not_An_ID"} },
+		"duplicate chain":   func(c *config.Config) { c.Chains = []string{"extra", "extra"} },
+	} {
+		cfg := config.Default()
+		setup(&cfg)
+		if n, err := New(cfg, WithoutHTTP()); err == nil {
+			n.Stop()
+			t.Errorf("%s: the node was built", name)
+		}
+	}
+}
+
+// storeChain runs a node on dir that mines n blocks of two transactions
+// each, and returns its blocks
+func storeChain(t *testing.T, dir string, n int) []blockchain.Block {
+	t.Helper()
+	cfg := config.Default()
+	cfg.DataDir = dir
+	node, err := New(cfg, WithoutHTTP())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Stop()
+	ctx := context.Background()
+	srv := node.Server()
+	for i := 1; i <= n; i++ {
+		for j := 0; j < 2; j++ {
+			if err := srv.AddTransaction(ctx, blockchain.NewDataTx(fmt.Sprintf("tx-%d-%d", i, j))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, _, err := srv.MinePending(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	blocks := make([]blockchain.Block, n+1)
+	for i := range blocks {
+		blocks[i], _ = srv.Block(i)
+	}
+	return blocks
+}
+
+// corruptBlock rewrites dir's block log with one transaction of block bad
+// edited, so the block no longer matches its hash
+func corruptBlock(t *testing.T, dir string, blocks []blockchain.Block, bad int) {
+	t.Helper()
+	edited := append([]blockchain.Block(nil), blocks...)
+	b := edited[bad]
+	b.Txns = append([]blockchain.Transaction(nil), b.Txns...)
+	b.Txns[0] = blockchain.NewDataTx("edited")
+	edited[bad] = b
+	st, err := store.Open(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	if err := st.Rewrite(edited); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// startOn builds a node on dir that repairs a corrupt chain as onCorruption says
+func startOn(dir, onCorruption, snapshot string) (*Node, error) {
+	cfg := config.Default()
+	cfg.DataDir, cfg.OnCorruption, cfg.Snapshot = dir, onCorruption, snapshot
+	return New(cfg, WithoutHTTP())
+}
+
+func TestNewRefusesACorruptStoredChain(t *testing.T) {
+	dir := t.TempDir()
+	blocks := storeChain(t, dir, 3)
+	corruptBlock(t, dir, blocks, 2)
+	if n, err := startOn(dir, "refuse", ""); err == nil {
+		n.Stop()
+		t.Fatal("a node started on a chain that fails verification")
+	}
+}
+
+func TestNewTruncatesACorruptStoredChain(t *testing.T) {
+	dir := t.TempDir()
+	blocks := storeChain(t, dir, 3)
+	corruptBlock(t, dir, blocks, 2)
+	n, err := startOn(dir, "truncate", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := n.Server().Status()
+	if st.Tip.Index != 1 || st.Tip.Hash != blocks[1].Hash {
+		t.Fatalf("truncated to block %d %s, want block 1 %s", st.Tip.Index, st.Tip.Hash, blocks[1].Hash)
+	}
+	// the transactions of the blocks dropped are pending again; block 2's
+	// edited one is no longer anywhere
+	for _, b := range blocks[2:] {
+		for _, tx := range b.Txns[1:] {
+			if got, err := n.Server().TxStatus(tx.ID); err != nil || got.Status != api.TxPending {
+				t.Errorf("%s of dropped block %d: %+v, %v; want it pending", tx.Data, b.Index, got, err)
+			}
+		}
+	}
+	n.Stop()
+
+	// the repair was written back, so a node refusing corruption now starts
+	n, err = startOn(dir, "refuse", "")
+	if err != nil {
+		t.Fatalf("restarting on the repaired chain: %v", err)
+	}
+	defer n.Stop()
+	if tip := n.Server().Status().Tip; tip.Hash != blocks[1].Hash {
+		t.Fatalf("the repaired chain reloads with tip %d %s", tip.Index, tip.Hash)
+	}
+}
+
+func TestNewRestoresACorruptStoredChainFromASnapshot(t *testing.T) {
+	dir := t.TempDir()
+	blocks := storeChain(t, dir, 3)
+	raw, err := json.Marshal(api.ChainSnapshot{Version: 1, Blocks: blocks})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := os.WriteFile(snapshot, raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	corruptBlock(t, dir, blocks, 1)
+	n, err := startOn(dir, "snapshot", snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Stop()
+	if tip := n.Server().Status().Tip; tip.Index != 3 || tip.Hash != blocks[3].Hash {
+		t.Fatalf("restored to block %d %s, want block 3 %s", tip.Index, tip.Hash, blocks[3].Hash)
+	}
+}
+
+func TestNewTruncatesAnUnreadableBlockLog(t *testing.T) {
+	dir := t.TempDir()
+	blocks := storeChain(t, dir, 2)
+	// a complete frame that doesn't decode as a block
+	f, err := os.OpenFile(filepath.Join(dir, store.BlocksFile), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte{4, 0xff, 0xff, 0xff, 0xff}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if n, err := startOn(dir, "refuse", ""); !errors.Is(err, store.ErrCorrupt) {
+		if err == nil {
+			n.Stop()
+		}
+		t.Fatalf("starting on an unreadable block log: %v, want store.ErrCorrupt", err)
+	}
+	n, err := startOn(dir, "truncate", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Stop()
+	if tip := n.Server().Status().Tip; tip.Hash != blocks[2].Hash {
+		t.Fatalf("truncated to block %d %s, want the readable tip %s", tip.Index, tip.Hash, blocks[2].Hash)
+	}
+}
+
+func TestRequiringSignaturesKeepsUnsignedHistory(t *testing.T) {
+	dir := t.TempDir()
+	blocks := storeChain(t, dir, 2)
+	cfg := config.Default()
+	cfg.DataDir, cfg.OnCorruption, cfg.RequireSignatures = dir, "truncate", true
+
+	// the unsigned history isn't corruption, so it is neither truncated nor
+	// loaded while the rule covers it
+	n, err := New(cfg, WithoutHTTP())
+	if err == nil {
+		n.Stop()
+		t.Fatal("a node requiring signatures from genesis loaded an unsigned chain")
+	}
+	if !strings.Contains(err.Error(), "signatures_from_height") {
+		t.Fatalf("refusing an unsigned chain: %v, want it to name signatures_from_height", err)
+	}
+
+	cfg.SignaturesFrom = len(blocks)
+	if n, err = New(cfg, WithoutHTTP()); err != nil {
+		t.Fatalf("requiring signatures above the tip: %v", err)
+	}
+	defer n.Stop()
+	if tip := n.Server().Status().Tip; tip.Hash != blocks[2].Hash {
+		t.Fatalf("the chain loaded with tip %d %s, want %s", tip.Index, tip.Hash, blocks[2].Hash)
+	}
+	if err := n.Server().AddTransaction(context.Background(), blockchain.NewDataTx("unsigned")); err == nil {
+		t.Fatal("an unsigned transaction was accepted for a block the rule covers")
+	}
+}
+
+// openUnder lists the files this process holds open inside dir, from
+// /proc/self/fd
+func openUnder(t *testing.T, dir string) []string {
+	t.Helper()
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skipf("can't list open files: %v", err)
+	}
+	var open []string
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join("/proc/self/fd", fd.Name()))
+		if err == nil && strings.HasPrefix(target, dir+string(filepath.Separator)) {
+			open = append(open, target)
+		}
+	}
+	return open
+}
+
+func TestNewClosesTheStoreOnError(t *testing.T) {
+	for name, setup := range map[string]func(*config.Config){
+		"unreadable import":   func(c *config.Config) { c.Import = filepath.Join(c.DataDir, "missing.ndjson") },
+		"unreadable snapshot": func(c *config.Config) { c.Snapshot = filepath.Join(c.DataDir, "missing.json") },
+		"bad chain id":        func(c *config.Config) { c.Chains = []string{"Not_An_ID"} },
+		"duplicate chain":     func(c *config.Config) { c.Chains = []string{"extra", "extra"} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.DataDir = t.TempDir()
+			setup(&cfg)
+			n, err := New(cfg, WithoutHTTP())
+			if err == nil {
+				n.Stop()
+				t.Fatal("the node started")
+			}
+			if open := openUnder(t, cfg.DataDir); len(open) > 0 {
+				t.Fatalf("failing to start left %v open", open)
+			}
+		})
+	}
+}
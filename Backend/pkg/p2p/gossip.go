@@ -0,0 +1,146 @@
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+const (
+	// requestTimeout bounds a block broadcast or height query to one peer
+	requestTimeout = 10 * time.Second
+	// fetchTimeout bounds downloading a peer's whole chain
+	fetchTimeout = time.Minute
+
+	// maxReplyBytes caps a peer's status, height or error reply
+	maxReplyBytes = 64 << 10
+	// maxListBytes caps a peer's page of blocks or its mempool
+	maxListBytes = 64 << 20
+)
+
+// Caps on a peer's whole chain, which FetchChain fails past rather than
+// hold in memory; vars so tests can lower them
+var (
+	maxChainBytes  int64 = 1 << 30
+	maxChainBlocks       = 1 << 20
+)
+
+// Client talks to peers over their HTTP API
+type Client struct {
+	Token string // bearer token sent to peers, for networks sharing one
+}
+
+// SendBlock offers b to peer in the binary encoding and returns the peer's
+// status for it
+func (c Client) SendBlock(ctx context.Context, peer string, b blockchain.Block) (string, error) {
+	var out struct {
+		Status string `json:"status"`
+	}
+	body := bytes.NewReader(blockchain.EncodeBlock(b))
+	err := c.do(ctx, requestTimeout, "POST", peer+"/p2p/blocks", body, func(r io.Reader) error {
+		return json.NewDecoder(io.LimitReader(r, maxReplyBytes)).Decode(&out)
+	})
+	return out.Status, err
+}
+
+// Height returns the index of peer's tip
+func (c Client) Height(ctx context.Context, peer string) (int, error) {
+	var out struct {
+		Height int `json:"height"`
+	}
+	err := c.do(ctx, requestTimeout, "GET", peer+"/stats", nil, func(r io.Reader) error {
+		return json.NewDecoder(io.LimitReader(r, maxReplyBytes)).Decode(&out)
+	})
+	return out.Height, err
+}
+
+// FetchChain downloads peer's whole chain in the binary encoding, genesis
+// first. It fails once the chain passes maxChainBytes or maxChainBlocks.
+func (c Client) FetchChain(ctx context.Context, peer string) ([]blockchain.Block, error) {
+	var blocks []blockchain.Block
+	err := c.do(ctx, fetchTimeout, "GET", peer+"/export?format=binary", nil, func(r io.Reader) error {
+		body := &io.LimitedReader{R: r, N: maxChainBytes + 1}
+		br := bufio.NewReaderSize(body, 64*1024)
+		var read int64
+		for {
+			b, n, err := blockchain.ReadBlock(br)
+			read += int64(n)
+			if read > maxChainBytes || (err != nil && body.N == 0) {
+				return fmt.Errorf("chain is over %d bytes", maxChainBytes)
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("block %d: %v", len(blocks), err)
+			}
+			if len(blocks) == maxChainBlocks {
+				return fmt.Errorf("chain is over %d blocks", maxChainBlocks)
+			}
+			blocks = append(blocks, b)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// FetchBlocks downloads up to limit of peer's blocks from index from on
+func (c Client) FetchBlocks(ctx context.Context, peer string, from, limit int) ([]blockchain.Block, error) {
+	var out struct {
+		Blocks []blockchain.Block `json:"blocks"`
+	}
+	url := fmt.Sprintf("%s/blocks?from=%d&limit=%d", peer, from, limit)
+	err := c.do(ctx, fetchTimeout, "GET", url, nil, func(r io.Reader) error {
+		return json.NewDecoder(io.LimitReader(r, maxListBytes)).Decode(&out)
+	})
+	return out.Blocks, err
+}
+
+// Pending returns peer's mempool
+func (c Client) Pending(ctx context.Context, peer string) ([]blockchain.Transaction, error) {
+	var txs []blockchain.Transaction
+	err := c.do(ctx, requestTimeout, "GET", peer+"/pending", nil, func(r io.Reader) error {
+		return json.NewDecoder(io.LimitReader(r, maxListBytes)).Decode(&txs)
+	})
+	return txs, err
+}
+
+// do sends one request to a peer and hands a successful response's body to read
+func (c Client) do(ctx context.Context, timeout time.Duration, method, url string, body io.Reader, read func(io.Reader) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", blockchain.BinaryMediaType) // only blocks are sent
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(io.LimitReader(resp.Body, maxReplyBytes)).Decode(&e) == nil && e.Error != "" {
+			return fmt.Errorf("%s: %s", url, e.Error)
+		}
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return read(resp.Body)
+}
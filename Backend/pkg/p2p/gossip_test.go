@@ -0,0 +1,69 @@
+package p2p
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+// servePeer serves blocks as a peer's binary export
+func servePeer(t *testing.T, blocks []blockchain.Block) string {
+	t.Helper()
+	var body bytes.Buffer
+	for _, b := range blocks {
+		if err := blockchain.WriteBlock(&body, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body.Bytes())
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// FetchChain downloads a chain within its caps and fails past either one
+func TestFetchChainLimits(t *testing.T) {
+	chain := blockchain.NewChain(1)
+	for _, data := range []string{"one", "two", "three"} {
+		b, err := chain.Produce(context.Background(), chain.NextBlock([]blockchain.Transaction{blockchain.NewDataTx(data)}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := chain.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	peer := servePeer(t, chain.Blocks())
+	var size int64
+	for _, b := range chain.Blocks() {
+		var frame bytes.Buffer
+		blockchain.WriteBlock(&frame, b)
+		size += int64(frame.Len())
+	}
+
+	limit := func(chainBytes int64, blocks int) {
+		oldBytes, oldBlocks := maxChainBytes, maxChainBlocks
+		maxChainBytes, maxChainBlocks = chainBytes, blocks
+		t.Cleanup(func() { maxChainBytes, maxChainBlocks = oldBytes, oldBlocks })
+	}
+	limit(size, 4)
+	blocks, err := (Client{}).FetchChain(context.Background(), peer)
+	if err != nil || len(blocks) != 4 {
+		t.Fatalf("fetching a chain at both caps: %d blocks, %v", len(blocks), err)
+	}
+
+	limit(size-1, 4)
+	if blocks, err := (Client{}).FetchChain(context.Background(), peer); err == nil || !strings.Contains(err.Error(), "bytes") {
+		t.Fatalf("fetching a chain a byte over the cap: %d blocks, %v", len(blocks), err)
+	}
+	limit(size, 3)
+	if blocks, err := (Client{}).FetchChain(context.Background(), peer); err == nil || !strings.Contains(err.Error(), "blocks") {
+		t.Fatalf("fetching a chain a block over the cap: %d blocks, %v", len(blocks), err)
+	}
+}
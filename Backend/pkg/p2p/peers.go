@@ -0,0 +1,50 @@
+// Package p2p tracks the other nodes this node talks to and exchanges
+// blocks with them.
+package p2p
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Peers is the set of known peer base URLs, e.g. "http://10.0.0.2:8080"
+type Peers struct {
+	mu   sync.Mutex
+	urls map[string]struct{}
+}
+
+// NewPeers returns an empty peer set
+func NewPeers() *Peers {
+	return &Peers{urls: map[string]struct{}{}}
+}
+
+// Add registers a peer; it returns false if the peer was already known
+func (p *Peers) Add(raw string) (added bool, err error) {
+	u, err := url.Parse(strings.TrimRight(raw, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false, fmt.Errorf("invalid peer url %q", raw)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := u.String()
+	if _, ok := p.urls[key]; ok {
+		return false, nil
+	}
+	p.urls[key] = struct{}{}
+	return true, nil
+}
+
+// List returns the known peers in sorted order
+func (p *Peers) List() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]string, 0, len(p.urls))
+	for u := range p.urls {
+		out = append(out, u)
+	}
+	sort.Strings(out)
+	return out
+}
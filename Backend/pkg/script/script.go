@@ -0,0 +1,477 @@
+// Package script is a small Bitcoin-script-like stack machine. Transaction
+// outputs carry a locking script, inputs carry an unlocking script, and a
+// spend is valid when running the unlocking script and then the locking
+// script on the same stack leaves a true value on top.
+//
+// Scripts are written as space-separated tokens: opcodes such as OP_DUP and
+// hex-encoded data pushes, e.g.
+//
+//	OP_DUP OP_HASH160 <address> OP_EQUALVERIFY OP_CHECKSIG
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+// Limits that keep evaluation cheap
+const (
+	MaxScriptLen = 10000 // characters
+	MaxOps       = 201
+	MaxStack     = 1000
+	MaxPushBytes = 520
+)
+
+// Context is what a script can observe about the spending transaction
+type Context struct {
+	SigHash []byte // digest that signatures must cover
+	Height  int    // height of the block the spend will be included in
+	// Signed fails spends that get through without a passing OP_CHECKSIG
+	// or OP_CHECKMULTISIG, e.g. by a hash preimage alone
+	Signed bool
+}
+
+// ErrFailed is returned when a script runs to completion but leaves false on the stack
+var ErrFailed = errors.New("script: evaluated to false")
+
+// ErrNoSignature is returned by Verify under Context.Signed for a spend
+// that checked no signature
+var ErrNoSignature = errors.New("script: spend checks no signature")
+
+// opcodes maps names to handlers; data pushes are handled separately
+var opcodes = map[string]func(m *machine) error{
+	"OP_NOP":                 func(m *machine) error { return nil },
+	"OP_VERIFY":              opVerify,
+	"OP_RETURN":              func(m *machine) error { return errors.New("script: OP_RETURN") },
+	"OP_DUP":                 opDup,
+	"OP_DROP":                opDrop,
+	"OP_SWAP":                opSwap,
+	"OP_OVER":                opOver,
+	"OP_SIZE":                opSize,
+	"OP_NOT":                 opNot,
+	"OP_EQUAL":               opEqual,
+	"OP_EQUALVERIFY":         verifying(opEqual),
+	"OP_SHA256":              opSHA256,
+	"OP_HASH160":             opHash160,
+	"OP_CHECKSIG":            opCheckSig,
+	"OP_CHECKSIGVERIFY":      verifying(opCheckSig),
+	"OP_CHECKMULTISIG":       opCheckMultiSig,
+	"OP_CHECKMULTISIGVERIFY": verifying(opCheckMultiSig),
+	"OP_CHECKLOCKTIMEVERIFY": opCheckLockTimeVerify,
+}
+
+// token is one parsed script element
+type token struct {
+	op   string
+	data []byte // set for pushes
+	push bool
+}
+
+// Parse splits a script into tokens, rejecting unknown opcodes and bad hex
+func Parse(s string) ([]token, error) {
+	if len(s) > MaxScriptLen {
+		return nil, fmt.Errorf("script: longer than %d characters", MaxScriptLen)
+	}
+	var out []token
+	for _, f := range strings.Fields(s) {
+		if n, ok := smallInt(f); ok {
+			out = append(out, token{op: f, data: encodeInt(int64(n)), push: true})
+			continue
+		}
+		if strings.HasPrefix(f, "OP_") {
+			switch f {
+			case "OP_IF", "OP_NOTIF", "OP_ELSE", "OP_ENDIF":
+			default:
+				if _, ok := opcodes[f]; !ok {
+					return nil, fmt.Errorf("script: unknown opcode %s", f)
+				}
+			}
+			out = append(out, token{op: f})
+			continue
+		}
+		data, err := hex.DecodeString(f)
+		if err != nil {
+			return nil, fmt.Errorf("script: data push %q is not hex", f)
+		}
+		if len(data) > MaxPushBytes {
+			return nil, fmt.Errorf("script: push of %d bytes exceeds %d", len(data), MaxPushBytes)
+		}
+		out = append(out, token{data: data, push: true})
+	}
+	return out, nil
+}
+
+// smallInt recognises OP_0..OP_16 and their OP_FALSE/OP_TRUE aliases
+func smallInt(op string) (int, bool) {
+	switch op {
+	case "OP_0", "OP_FALSE":
+		return 0, true
+	case "OP_TRUE":
+		return 1, true
+	}
+	var n int
+	if _, err := fmt.Sscanf(op, "OP_%d", &n); err == nil && n >= 1 && n <= 16 && op == fmt.Sprintf("OP_%d", n) {
+		return n, true
+	}
+	return 0, false
+}
+
+// Verify runs unlock then lock and succeeds if the result is true. The
+// unlocking script may only push data.
+func Verify(unlock, lock string, ctx Context) error {
+	ut, err := Parse(unlock)
+	if err != nil {
+		return err
+	}
+	for _, t := range ut {
+		if !t.push {
+			return fmt.Errorf("script: unlocking script may only push data, found %s", t.op)
+		}
+	}
+	lt, err := Parse(lock)
+	if err != nil {
+		return err
+	}
+	m := &machine{ctx: ctx}
+	if err := m.run(ut); err != nil {
+		return err
+	}
+	if err := m.run(lt); err != nil {
+		return err
+	}
+	if len(m.stack) == 0 || !truthy(m.stack[len(m.stack)-1]) {
+		return ErrFailed
+	}
+	if ctx.Signed && m.signed == 0 {
+		return ErrNoSignature
+	}
+	return nil
+}
+
+// machine is the evaluation state
+type machine struct {
+	stack  [][]byte
+	ops    int
+	ctx    Context
+	signed int // signature checks that passed
+}
+
+func (m *machine) run(tokens []token) error {
+	// exec tracks nested OP_IF branches; code runs only when all are true
+	var exec []bool
+	running := func() bool {
+		for _, e := range exec {
+			if !e {
+				return false
+			}
+		}
+		return true
+	}
+	for _, t := range tokens {
+		if !t.push {
+			if m.ops++; m.ops > MaxOps {
+				return fmt.Errorf("script: more than %d operations", MaxOps)
+			}
+		}
+		switch t.op {
+		case "OP_IF", "OP_NOTIF":
+			branch := false
+			if running() {
+				v, err := m.pop()
+				if err != nil {
+					return err
+				}
+				branch = truthy(v) == (t.op == "OP_IF")
+			}
+			exec = append(exec, branch)
+			continue
+		case "OP_ELSE":
+			if len(exec) == 0 {
+				return errors.New("script: OP_ELSE without OP_IF")
+			}
+			exec[len(exec)-1] = !exec[len(exec)-1]
+			continue
+		case "OP_ENDIF":
+			if len(exec) == 0 {
+				return errors.New("script: OP_ENDIF without OP_IF")
+			}
+			exec = exec[:len(exec)-1]
+			continue
+		}
+		if !running() {
+			continue
+		}
+		if t.push {
+			m.push(t.data)
+		} else if err := opcodes[t.op](m); err != nil {
+			return err
+		}
+		if len(m.stack) > MaxStack {
+			return fmt.Errorf("script: stack deeper than %d", MaxStack)
+		}
+	}
+	if len(exec) != 0 {
+		return errors.New("script: unbalanced OP_IF")
+	}
+	return nil
+}
+
+func (m *machine) push(b []byte) {
+	m.stack = append(m.stack, b)
+}
+
+func (m *machine) pop() ([]byte, error) {
+	if len(m.stack) == 0 {
+		return nil, errors.New("script: stack underflow")
+	}
+	v := m.stack[len(m.stack)-1]
+	m.stack = m.stack[:len(m.stack)-1]
+	return v, nil
+}
+
+func (m *machine) popInt() (int64, error) {
+	v, err := m.pop()
+	if err != nil {
+		return 0, err
+	}
+	return decodeInt(v)
+}
+
+func (m *machine) peek(depth int) ([]byte, error) {
+	if len(m.stack) <= depth {
+		return nil, errors.New("script: stack underflow")
+	}
+	return m.stack[len(m.stack)-1-depth], nil
+}
+
+// truthy is false for empty values and all-zero bytes
+func truthy(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func boolBytes(v bool) []byte {
+	if v {
+		return []byte{1}
+	}
+	return []byte{}
+}
+
+// encodeInt encodes non-negative numbers as minimal big-endian bytes
+func encodeInt(n int64) []byte {
+	var out []byte
+	for ; n > 0; n >>= 8 {
+		out = append([]byte{byte(n)}, out...)
+	}
+	return out
+}
+
+func decodeInt(b []byte) (int64, error) {
+	if len(b) > 7 {
+		return 0, fmt.Errorf("script: number of %d bytes too large", len(b))
+	}
+	var n int64
+	for _, c := range b {
+		n = n<<8 | int64(c)
+	}
+	return n, nil
+}
+
+// verifying turns an op that pushes a bool into its *VERIFY form
+func verifying(op func(m *machine) error) func(m *machine) error {
+	return func(m *machine) error {
+		if err := op(m); err != nil {
+			return err
+		}
+		return opVerify(m)
+	}
+}
+
+func opVerify(m *machine) error {
+	v, err := m.pop()
+	if err != nil {
+		return err
+	}
+	if !truthy(v) {
+		return errors.New("script: verify failed")
+	}
+	return nil
+}
+
+func opDup(m *machine) error {
+	v, err := m.peek(0)
+	if err != nil {
+		return err
+	}
+	m.push(v)
+	return nil
+}
+
+func opDrop(m *machine) error {
+	_, err := m.pop()
+	return err
+}
+
+func opSwap(m *machine) error {
+	if len(m.stack) < 2 {
+		return errors.New("script: stack underflow")
+	}
+	n := len(m.stack)
+	m.stack[n-1], m.stack[n-2] = m.stack[n-2], m.stack[n-1]
+	return nil
+}
+
+func opOver(m *machine) error {
+	v, err := m.peek(1)
+	if err != nil {
+		return err
+	}
+	m.push(v)
+	return nil
+}
+
+func opSize(m *machine) error {
+	v, err := m.peek(0)
+	if err != nil {
+		return err
+	}
+	m.push(encodeInt(int64(len(v))))
+	return nil
+}
+
+func opNot(m *machine) error {
+	v, err := m.pop()
+	if err != nil {
+		return err
+	}
+	m.push(boolBytes(!truthy(v)))
+	return nil
+}
+
+func opEqual(m *machine) error {
+	a, err := m.pop()
+	if err != nil {
+		return err
+	}
+	b, err := m.pop()
+	if err != nil {
+		return err
+	}
+	m.push(boolBytes(bytes.Equal(a, b)))
+	return nil
+}
+
+func opSHA256(m *machine) error {
+	v, err := m.pop()
+	if err != nil {
+		return err
+	}
+	h := sha256.Sum256(v)
+	m.push(h[:])
+	return nil
+}
+
+// opHash160 hashes a public key to its 20-byte address (see wallet.Address)
+func opHash160(m *machine) error {
+	v, err := m.pop()
+	if err != nil {
+		return err
+	}
+	h := sha256.Sum256(v)
+	m.push(h[:20])
+	return nil
+}
+
+func opCheckSig(m *machine) error {
+	pub, err := m.pop()
+	if err != nil {
+		return err
+	}
+	sig, err := m.pop()
+	if err != nil {
+		return err
+	}
+	ok := m.checkSig(sig, pub)
+	if ok {
+		m.signed++
+	}
+	m.push(boolBytes(ok))
+	return nil
+}
+
+func (m *machine) checkSig(sig, pub []byte) bool {
+	return len(m.ctx.SigHash) > 0 &&
+		wallet.Verify(hex.EncodeToString(pub), m.ctx.SigHash, hex.EncodeToString(sig))
+}
+
+// opCheckMultiSig pops n, n keys, m, m signatures; signatures must appear
+// in the same order as their keys
+func opCheckMultiSig(m *machine) error {
+	n, err := m.popInt()
+	if err != nil {
+		return err
+	}
+	if n < 1 || n > 16 {
+		return fmt.Errorf("script: multisig key count %d out of range", n)
+	}
+	pubs := make([][]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		if pubs[i], err = m.pop(); err != nil {
+			return err
+		}
+	}
+	need, err := m.popInt()
+	if err != nil {
+		return err
+	}
+	if need < 1 || need > n {
+		return fmt.Errorf("script: multisig threshold %d of %d out of range", need, n)
+	}
+	sigs := make([][]byte, need)
+	for i := need - 1; i >= 0; i-- {
+		if sigs[i], err = m.pop(); err != nil {
+			return err
+		}
+	}
+	k := 0
+	for _, sig := range sigs {
+		for k < len(pubs) && !m.checkSig(sig, pubs[k]) {
+			k++
+		}
+		if k == len(pubs) {
+			m.push(boolBytes(false))
+			return nil
+		}
+		k++
+	}
+	m.signed++
+	m.push(boolBytes(true))
+	return nil
+}
+
+// opCheckLockTimeVerify fails unless the spend is at or above the height on
+// top of the stack, which it leaves in place
+func opCheckLockTimeVerify(m *machine) error {
+	v, err := m.peek(0)
+	if err != nil {
+		return err
+	}
+	h, err := decodeInt(v)
+	if err != nil {
+		return err
+	}
+	if int64(m.ctx.Height) < h {
+		return fmt.Errorf("script: locked until height %d", h)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package script
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// P2PKH locks an output to the holder of the key behind a wallet address
+func P2PKH(address string) string {
+	return "OP_DUP OP_HASH160 " + address + " OP_EQUALVERIFY OP_CHECKSIG"
+}
+
+// P2PKHUnlock spends a P2PKH output with a signature and the matching public key
+func P2PKHUnlock(sigHex, pubHex string) string {
+	return sigHex + " " + pubHex
+}
+
+// Multisig locks an output to any m of the given public keys
+func Multisig(m int, pubHexes []string) string {
+	return fmt.Sprintf("%s %s %s OP_CHECKMULTISIG",
+		Int(int64(m)), strings.Join(pubHexes, " "), Int(int64(len(pubHexes))))
+}
+
+// MultisigUnlock spends a multisig output; signatures go in key order
+func MultisigUnlock(sigHexes []string) string {
+	return strings.Join(sigHexes, " ")
+}
+
+// HashLock locks an output to whoever reveals the preimage of a SHA-256 hash
+func HashLock(hashHex string) string {
+	return "OP_SHA256 " + hashHex + " OP_EQUAL"
+}
+
+// HashLockUnlock reveals the preimage of a hash lock
+func HashLockUnlock(preimage []byte) string {
+	return hex.EncodeToString(preimage)
+}
+
+// HTLC locks an output so recipient can claim it by revealing the preimage
+// of a SHA-256 hash, or refund can take it back from block timeout on
+func HTLC(hashHex, recipient, refund string, timeout int64) string {
+	return "OP_IF OP_SHA256 " + hashHex + " OP_EQUALVERIFY OP_DUP OP_HASH160 " + recipient +
+		" OP_ELSE " + Int(timeout) + " OP_CHECKLOCKTIMEVERIFY OP_DROP OP_DUP OP_HASH160 " + refund +
+		" OP_ENDIF OP_EQUALVERIFY OP_CHECKSIG"
+}
+
+// HTLCClaim spends an HTLC output as its recipient, revealing the preimage
+func HTLCClaim(sigHex, pubHex string, preimage []byte) string {
+	return sigHex + " " + pubHex + " " + hex.EncodeToString(preimage) + " OP_TRUE"
+}
+
+// HTLCRefund spends an HTLC output as its refund address once it has timed out
+func HTLCRefund(sigHex, pubHex string) string {
+	return sigHex + " " + pubHex + " OP_FALSE"
+}
+
+// HTLCTerms are the parameters of an HTLC locking script
+type HTLCTerms struct {
+	Hash      string `json:"hash"`
+	Recipient string `json:"recipient"`
+	Refund    string `json:"refund"`
+	Timeout   int64  `json:"timeout"`
+}
+
+// ParseHTLC recovers the terms of a script written by HTLC
+func ParseHTLC(lock string) (HTLCTerms, bool) {
+	f := strings.Fields(lock)
+	if len(f) != 17 {
+		return HTLCTerms{}, false
+	}
+	t, err := Parse(f[8])
+	if err != nil || len(t) != 1 || !t[0].push {
+		return HTLCTerms{}, false
+	}
+	timeout, err := decodeInt(t[0].data)
+	if err != nil || HTLC(f[2], f[6], f[13], timeout) != strings.Join(f, " ") {
+		return HTLCTerms{}, false
+	}
+	return HTLCTerms{Hash: f[2], Recipient: f[6], Refund: f[13], Timeout: timeout}, true
+}
+
+// HTLCPreimage returns the preimage an HTLCClaim unlocking script reveals
+func HTLCPreimage(unlock string) ([]byte, bool) {
+	f := strings.Fields(unlock)
+	if len(f) != 4 || f[3] != "OP_TRUE" {
+		return nil, false
+	}
+	b, err := hex.DecodeString(f[2])
+	return b, err == nil
+}
+
+// Int writes a number as a script token
+func Int(n int64) string {
+	if n == 0 {
+		return "OP_0"
+	}
+	if n >= 1 && n <= 16 {
+		return fmt.Sprintf("OP_%d", n)
+	}
+	return hex.EncodeToString(encodeInt(n))
+}
+
+// Address returns the wallet address a P2PKH locking script pays to, or ""
+func Address(lock string) string {
+	f := strings.Fields(lock)
+	if len(f) == 5 && f[0] == "OP_DUP" && f[1] == "OP_HASH160" &&
+		f[3] == "OP_EQUALVERIFY" && f[4] == "OP_CHECKSIG" {
+		return f[2]
+	}
+	return ""
+}
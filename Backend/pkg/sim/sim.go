@@ -0,0 +1,148 @@
+// Package sim fabricates chain activity — roll-number records, coin
+// issuance and signed transfers between a handful of wallets — so explorers
+// and analytics can be demoed against a non-trivial chain.
+package sim
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/clock"
+	"salmanahmed/blockchain/pkg/fixtures"
+	"salmanahmed/blockchain/pkg/script"
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+// Limits on a single run
+const (
+	MaxBlocks      = 1000
+	MaxTxsPerBlock = 500
+	wallets        = 8
+)
+
+// Options describes a simulation run
+type Options struct {
+	Blocks      int   `json:"blocks"`
+	TxsPerBlock int   `json:"txs_per_block"`
+	Seed        int64 `json:"seed,omitempty"` // 0 picks one from the clock
+}
+
+// Validate checks the options are within limits
+func (o Options) Validate() error {
+	if o.Blocks < 1 || o.Blocks > MaxBlocks {
+		return fmt.Errorf("blocks must be between 1 and %d", MaxBlocks)
+	}
+	if o.TxsPerBlock < 1 || o.TxsPerBlock > MaxTxsPerBlock {
+		return fmt.Errorf("txs_per_block must be between 1 and %d", MaxTxsPerBlock)
+	}
+	return nil
+}
+
+// Node is what a simulation drives. Its clock picks an unset seed and
+// stamps the signatures, so a run against a mock clock is repeatable.
+type Node interface {
+	clock.Clock
+	AddTransaction(ctx context.Context, tx blockchain.Transaction) error
+	MinePending(ctx context.Context) (blockchain.Block, bool, error)
+	UTXOs(address string) []blockchain.UTXO
+}
+
+// Report summarises a run
+type Report struct {
+	Seed         int64  `json:"seed"`
+	Blocks       int    `json:"blocks"`
+	Transactions int    `json:"transactions"`
+	Rejected     int    `json:"rejected"` // refused by the node, e.g. by a validator
+	FirstBlock   int    `json:"first_block"`
+	LastBlock    int    `json:"last_block"`
+	Elapsed      string `json:"elapsed"`
+}
+
+// Run submits and mines opts.Blocks blocks of random activity. The wallets
+// are the fixture keys, so their private keys are known for later demos.
+func Run(ctx context.Context, n Node, opts Options) (Report, error) {
+	if err := opts.Validate(); err != nil {
+		return Report{}, err
+	}
+	if opts.Seed == 0 {
+		opts.Seed = n.Now().UnixNano()
+	}
+	rep := Report{Seed: opts.Seed, FirstBlock: -1}
+	rng := rand.New(rand.NewSource(opts.Seed))
+	keys := make([]wallet.Keypair, wallets)
+	for i := range keys {
+		keys[i] = fixtures.Key(i)
+	}
+	start := time.Now()
+	for b := 0; b < opts.Blocks; b++ {
+		used := map[blockchain.OutPoint]bool{} // spent by this block's transfers
+		for t := 0; t < opts.TxsPerBlock; t++ {
+			tx, err := randomTx(rng, n, keys, used)
+			if err != nil {
+				return rep, err
+			}
+			if err := n.AddTransaction(ctx, tx); err != nil {
+				if ctx.Err() != nil {
+					return rep, ctx.Err()
+				}
+				rep.Rejected++
+				continue
+			}
+			rep.Transactions++
+		}
+		mined, ok, err := n.MinePending(ctx)
+		if err != nil {
+			return rep, err
+		}
+		if !ok {
+			continue
+		}
+		rep.Blocks++
+		if rep.FirstBlock < 0 {
+			rep.FirstBlock = mined.Index
+		}
+		rep.LastBlock = mined.Index
+	}
+	rep.Elapsed = time.Since(start).Round(time.Millisecond).String()
+	return rep, nil
+}
+
+// randomTx picks a record (half the time), a transfer when the chosen
+// wallet has coins, or else an issuance to it. Records and transfers are
+// signed by the wallet; issuance isn't, so nodes requiring signatures
+// refuse it and only block rewards fund the wallets there.
+func randomTx(rng *rand.Rand, n Node, keys []wallet.Keypair, used map[blockchain.OutPoint]bool) (blockchain.Transaction, error) {
+	from := keys[rng.Intn(len(keys))]
+	if rng.Intn(2) == 0 {
+		record := blockchain.Transaction{Data: fmt.Sprintf("%c%02d-%04d", 'a'+rng.Intn(26), 18+rng.Intn(8), rng.Intn(10000))}
+		return record.Sign(from.PrivateKey, n.Now().Unix())
+	}
+	to := keys[rng.Intn(len(keys))]
+	var tx blockchain.Transaction
+	var total int64
+	for _, u := range n.UTXOs(from.Address) {
+		if used[u.OutPoint] {
+			continue
+		}
+		used[u.OutPoint] = true
+		tx.Inputs = append(tx.Inputs, blockchain.TxInput{TxID: u.TxID, Index: u.Index})
+		total += u.Amount
+		break
+	}
+	if total == 0 {
+		amount := int64(10 + rng.Intn(991))
+		return blockchain.Transaction{Outputs: []blockchain.TxOutput{
+			{Amount: amount, Lock: script.P2PKH(from.Address)},
+		}}.Seal(), nil
+	}
+	amount := 1 + rng.Int63n(total)
+	tx.Outputs = []blockchain.TxOutput{{Amount: amount, Lock: script.P2PKH(to.Address)}}
+	if change := total - amount; change > 0 {
+		tx.Outputs = append(tx.Outputs, blockchain.TxOutput{Amount: change, Lock: script.P2PKH(from.Address)})
+	}
+	tx.Recipient, tx.Amount = to.Address, amount
+	return tx.Sign(from.PrivateKey, n.Now().Unix())
+}
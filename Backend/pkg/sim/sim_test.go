@@ -0,0 +1,88 @@
+package sim
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/clock"
+)
+
+// chainNode mines everything it accepts straight onto a chain
+type chainNode struct {
+	*clock.Mock
+	chain   *blockchain.Chain
+	pending []blockchain.Transaction
+}
+
+func newChainNode() *chainNode {
+	clk := clock.NewMock(time.Unix(1700000000, 0))
+	return &chainNode{
+		Mock:  clk,
+		chain: blockchain.NewChainWith(&blockchain.ProofOfWork{Difficulty: 1, Clock: clk}, blockchain.DefaultHasher(), clk),
+	}
+}
+
+func (n *chainNode) AddTransaction(ctx context.Context, tx blockchain.Transaction) error {
+	if err := n.chain.ValidateTx(tx); err != nil {
+		return err
+	}
+	n.pending = append(n.pending, tx)
+	return nil
+}
+
+func (n *chainNode) MinePending(ctx context.Context) (blockchain.Block, bool, error) {
+	if len(n.pending) == 0 {
+		return blockchain.Block{}, false, nil
+	}
+	b, err := n.chain.Produce(ctx, n.chain.NextBlock(n.pending))
+	if err != nil {
+		return blockchain.Block{}, false, err
+	}
+	n.pending = nil
+	n.Advance(time.Minute)
+	return b, true, n.chain.AddBlock(b)
+}
+
+func (n *chainNode) UTXOs(address string) []blockchain.UTXO {
+	return n.chain.UTXOs(address)
+}
+
+// a seed replays to the same chain, signatures included, on a mock clock
+func TestRunIsDeterministic(t *testing.T) {
+	run := func(seed int64) (Report, []blockchain.Block) {
+		t.Helper()
+		n := newChainNode()
+		rep, err := Run(context.Background(), n, Options{Blocks: 6, TxsPerBlock: 8, Seed: seed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rep, n.chain.Blocks()
+	}
+	rep, first := run(0)
+	if want := time.Unix(1700000000, 0).UnixNano(); rep.Seed != want {
+		t.Fatalf("an unset seed became %d, want %d from the node's clock", rep.Seed, want)
+	}
+	if rep.Blocks != 6 || rep.Transactions == 0 {
+		t.Fatalf("ran %+v, want 6 blocks of activity", rep)
+	}
+	again, second := run(rep.Seed)
+	if again.Transactions != rep.Transactions || len(second) != len(first) {
+		t.Fatalf("replaying seed %d gave %+v, want %+v", rep.Seed, again, rep)
+	}
+	signed := 0
+	for i := range first {
+		if first[i].Hash != second[i].Hash {
+			t.Fatalf("block %d differs on replay: %s vs %s", i, first[i].Hash, second[i].Hash)
+		}
+		for _, tx := range first[i].Txns {
+			if tx.Signature != "" {
+				signed++
+			}
+		}
+	}
+	if signed == 0 {
+		t.Fatal("the run signed nothing")
+	}
+}
@@ -0,0 +1,433 @@
+// Package store keeps a chain on disk under a data directory, so a node
+// picks up where it left off after a restart. Blocks go to an append-only
+// log in the binary format /export?format=binary writes; the mempool,
+// finality attestations, notarizations, chain snapshots and difficulty
+// schedule are JSON files rewritten whenever they change.
+//
+// Blocks are only ever appended and read back in order, so a flat log does
+// what a key-value store like BoltDB would, with no dependency, and a
+// damaged tail can be found and truncated away block by block.
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/chaos"
+)
+
+// File names under the data directory
+const (
+	BlocksFile        = "blocks.bin"
+	LegacyBlocksFile  = "blocks.ndjson" // the block log before it went binary, migrated on Open
+	PendingFile       = "pending.json"
+	AttestationsFile  = "attestations.json"
+	SnapshotsFile     = "snapshots.json"
+	DifficultyFile    = "difficulty.json"
+	NotarizationsFile = "notarizations.json"
+)
+
+// ErrCorrupt is wrapped by the error Load returns for a block log it can't
+// read to the end
+var ErrCorrupt = errors.New("corrupt block log")
+
+// ErrUnusable is wrapped by the errors block reads and appends return once
+// the block log couldn't be reopened after a Rewrite; a later Rewrite that
+// reopens it makes the store usable again
+var ErrUnusable = errors.New("block log unusable")
+
+// openBlocks opens the block log for reading and appending; a var so tests
+// can fail it
+var openBlocks = func(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+}
+
+// maxBlockLine bounds one stored block
+const maxBlockLine = 64 << 20
+
+// Store persists one chain's blocks and pending transactions
+type Store struct {
+	dir    string
+	faults *chaos.Injector // nil never fails a write
+
+	mu     sync.Mutex
+	blocks *os.File // nil once unusable
+	broken error    // why blocks is nil
+}
+
+// Open returns the store under dir, creating it if needed. faults, when
+// set, can fail writes on demand. A block log from before the binary format
+// is converted to it.
+func Open(dir string, faults *chaos.Injector) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("store: %w", err)
+	}
+	if err := migrateBlocks(dir); err != nil {
+		return nil, fmt.Errorf("store: %w", err)
+	}
+	f, err := openBlocks(filepath.Join(dir, BlocksFile))
+	if err != nil {
+		return nil, fmt.Errorf("store: %w", err)
+	}
+	return &Store{dir: dir, faults: faults, blocks: f}, nil
+}
+
+// Dir returns the directory the store lives in
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Load returns the stored blocks, genesis first, and the pending
+// transactions; both are empty for a new store. A block cut short by a
+// crash mid-append is dropped from the log. A block that can't be decoded
+// fails Load with ErrCorrupt, still returning the blocks before it and the
+// pending transactions.
+func (s *Store) Load() ([]blockchain.Block, []blockchain.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.blocks == nil {
+		return nil, nil, s.broken
+	}
+	if _, err := s.blocks.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	var blocks []blockchain.Block
+	var good int64 // bytes of complete blocks
+	var corrupt error
+	r := bufio.NewReaderSize(s.blocks, 64*1024)
+	for {
+		b, n, err := blockchain.ReadBlock(r)
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			// a partial final block: the append never finished
+			if err := s.blocks.Truncate(good); err != nil {
+				return nil, nil, err
+			}
+			break
+		}
+		if err != nil {
+			corrupt = fmt.Errorf("store: %w: %s block %d: %v", ErrCorrupt, BlocksFile, len(blocks), err)
+			break
+		}
+		good += int64(n)
+		blocks = append(blocks, b)
+	}
+
+	var pending []blockchain.Transaction
+	raw, err := os.ReadFile(filepath.Join(s.dir, PendingFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, nil, err
+	default:
+		if err := json.Unmarshal(raw, &pending); err != nil {
+			return nil, nil, fmt.Errorf("store: %s: %v", PendingFile, err)
+		}
+	}
+	return blocks, pending, corrupt
+}
+
+// AppendBlock adds b to the end of the log and syncs it to disk
+func (s *Store) AppendBlock(b blockchain.Block) error {
+	if err := s.faults.StorageWrite(); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := blockchain.WriteBlock(&buf, b); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.blocks == nil {
+		return s.broken
+	}
+	if _, err := s.blocks.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return s.blocks.Sync()
+}
+
+// Rewrite replaces the log with blocks, as after a reset. If the new log
+// can't be reopened the old handle would append to the replaced file, so
+// the store fails block reads and appends with ErrUnusable until a Rewrite
+// succeeds.
+func (s *Store) Rewrite(blocks []blockchain.Block) error {
+	if err := s.faults.StorageWrite(); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, b := range blocks {
+		if err := blockchain.WriteBlock(&buf, b); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writeFile(BlocksFile, buf.Bytes()); err != nil {
+		return err
+	}
+	f, err := openBlocks(filepath.Join(s.dir, BlocksFile))
+	if s.blocks != nil {
+		s.blocks.Close()
+	}
+	s.blocks = f
+	if err != nil {
+		s.blocks = nil
+		s.broken = fmt.Errorf("store: %w: reopening %s after a rewrite: %v", ErrUnusable, BlocksFile, err)
+		return s.broken
+	}
+	s.broken = nil
+	return nil
+}
+
+// SavePending replaces the mempool snapshot with txns
+func (s *Store) SavePending(txns []blockchain.Transaction) error {
+	if err := s.faults.StorageWrite(); err != nil {
+		return err
+	}
+	if txns == nil {
+		txns = []blockchain.Transaction{}
+	}
+	raw, err := json.Marshal(txns)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writeFile(PendingFile, raw)
+}
+
+// LoadAttestations returns the stored finality attestations, oldest first
+func (s *Store) LoadAttestations() ([]blockchain.Attestation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []blockchain.Attestation
+	raw, err := os.ReadFile(filepath.Join(s.dir, AttestationsFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("store: %s: %v", AttestationsFile, err)
+	}
+	return out, nil
+}
+
+// SaveAttestations replaces the stored finality attestations with atts
+func (s *Store) SaveAttestations(atts []blockchain.Attestation) error {
+	if err := s.faults.StorageWrite(); err != nil {
+		return err
+	}
+	if atts == nil {
+		atts = []blockchain.Attestation{}
+	}
+	raw, err := json.Marshal(atts)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writeFile(AttestationsFile, raw)
+}
+
+// LoadSnapshots returns the stored chain snapshots, oldest first
+func (s *Store) LoadSnapshots() ([]blockchain.Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []blockchain.Snapshot
+	raw, err := os.ReadFile(filepath.Join(s.dir, SnapshotsFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("store: %s: %v", SnapshotsFile, err)
+	}
+	return out, nil
+}
+
+// SaveSnapshots replaces the stored chain snapshots with snaps
+func (s *Store) SaveSnapshots(snaps []blockchain.Snapshot) error {
+	if err := s.faults.StorageWrite(); err != nil {
+		return err
+	}
+	if snaps == nil {
+		snaps = []blockchain.Snapshot{}
+	}
+	raw, err := json.Marshal(snaps)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writeFile(SnapshotsFile, raw)
+}
+
+// LoadNotarizations returns the stored notarizations, oldest first
+func (s *Store) LoadNotarizations() ([]blockchain.Notarization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []blockchain.Notarization
+	raw, err := os.ReadFile(filepath.Join(s.dir, NotarizationsFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("store: %s: %v", NotarizationsFile, err)
+	}
+	return out, nil
+}
+
+// SaveNotarizations replaces the stored notarizations with ns
+func (s *Store) SaveNotarizations(ns []blockchain.Notarization) error {
+	if err := s.faults.StorageWrite(); err != nil {
+		return err
+	}
+	if ns == nil {
+		ns = []blockchain.Notarization{}
+	}
+	raw, err := json.Marshal(ns)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writeFile(NotarizationsFile, raw)
+}
+
+// LoadDifficulty returns the stored difficulty schedule, oldest first
+func (s *Store) LoadDifficulty() ([]blockchain.DifficultyStep, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []blockchain.DifficultyStep
+	raw, err := os.ReadFile(filepath.Join(s.dir, DifficultyFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("store: %s: %v", DifficultyFile, err)
+	}
+	return out, nil
+}
+
+// SaveDifficulty replaces the stored difficulty schedule with steps
+func (s *Store) SaveDifficulty(steps []blockchain.DifficultyStep) error {
+	if err := s.faults.StorageWrite(); err != nil {
+		return err
+	}
+	if steps == nil {
+		steps = []blockchain.DifficultyStep{}
+	}
+	raw, err := json.Marshal(steps)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writeFile(DifficultyFile, raw)
+}
+
+// Close releases the block log
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.blocks == nil {
+		return nil
+	}
+	return s.blocks.Close()
+}
+
+// writeFile replaces name atomically, so a crash leaves the old or the new
+// content and never a mix (caller holds mu)
+func (s *Store) writeFile(name string, data []byte) error {
+	tmp, err := os.CreateTemp(s.dir, name+".tmp*")
+	if err != nil {
+		return err
+	}
+	if err = tmp.Chmod(0o644); err == nil {
+		_, err = tmp.Write(data)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(s.dir, name))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// migrateBlocks rewrites an ndjson block log from before the binary format
+// as BlocksFile and removes it. A block cut short by a crash mid-append is
+// dropped, as Load would.
+func migrateBlocks(dir string) error {
+	legacy := filepath.Join(dir, LegacyBlocksFile)
+	f, err := os.Open(legacy)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := os.Stat(filepath.Join(dir, BlocksFile)); err == nil {
+		// written in full, as writeFile is atomic, before a crash kept the
+		// old log from being removed
+		return os.Remove(legacy)
+	}
+
+	var buf bytes.Buffer
+	r := bufio.NewReaderSize(f, 64*1024)
+	for line := 1; ; line++ {
+		raw, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			break // a partial final line never finished appending
+		}
+		if err != nil {
+			return err
+		}
+		if len(raw) > maxBlockLine {
+			return fmt.Errorf("%s line %d exceeds %d bytes", LegacyBlocksFile, line, maxBlockLine)
+		}
+		if len(bytes.TrimSpace(raw)) == 0 {
+			continue
+		}
+		var b blockchain.Block
+		if err := json.Unmarshal(raw, &b); err != nil {
+			return fmt.Errorf("%s line %d: %v", LegacyBlocksFile, line, err)
+		}
+		if err := blockchain.WriteBlock(&buf, b); err != nil {
+			return err
+		}
+	}
+	s := &Store{dir: dir}
+	if err := s.writeFile(BlocksFile, buf.Bytes()); err != nil {
+		return err
+	}
+	return os.Remove(legacy)
+}
@@ -0,0 +1,272 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+// testBlocks returns genesis and n blocks after it, each holding a data
+// transaction
+func testBlocks(t *testing.T, n int) []blockchain.Block {
+	t.Helper()
+	c := blockchain.NewChain(1)
+	for i := 0; i < n; i++ {
+		b, err := c.Produce(context.Background(), c.NextBlock([]blockchain.Transaction{blockchain.NewDataTx("block " + string(rune('a'+i)))}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.AddBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c.Blocks()
+}
+
+// openStore opens the store under dir, closing it when t ends
+func openStore(t *testing.T, dir string) *Store {
+	t.Helper()
+	s, err := Open(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// expectBlocks fails t unless got encodes exactly as want
+func expectBlocks(t *testing.T, got, want []blockchain.Block) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("loaded %d blocks, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(blockchain.EncodeBlock(got[i]), blockchain.EncodeBlock(want[i])) {
+			t.Fatalf("block %d loaded as %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	blocks := testBlocks(t, 3)
+	pending := []blockchain.Transaction{blockchain.NewDataTx("waiting")}
+	s := openStore(t, dir)
+	for _, b := range blocks {
+		if err := s.AppendBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.SavePending(pending); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	got, gotPending, err := openStore(t, dir).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectBlocks(t, got, blocks)
+	if len(gotPending) != 1 || gotPending[0].ID != pending[0].ID {
+		t.Fatalf("loaded pending %+v, want %+v", gotPending, pending)
+	}
+}
+
+// a crash mid-append leaves part of the last block's frame; Load drops it,
+// truncates the log to the blocks before and later appends follow them
+func TestTruncatedTail(t *testing.T) {
+	blocks := testBlocks(t, 3)
+	var whole bytes.Buffer
+	for _, b := range blocks[:3] {
+		if err := blockchain.WriteBlock(&whole, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	good := whole.Len()
+	if err := blockchain.WriteBlock(&whole, blocks[3]); err != nil {
+		t.Fatal(err)
+	}
+	for _, cut := range []int{1, 2, (whole.Len() - good) / 2, whole.Len() - good - 1} {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, BlocksFile), whole.Bytes()[:good+cut], 0o644); err != nil {
+			t.Fatal(err)
+		}
+		s := openStore(t, dir)
+		got, _, err := s.Load()
+		if err != nil {
+			t.Fatalf("%d bytes of the last block: %v", cut, err)
+		}
+		expectBlocks(t, got, blocks[:3])
+		fi, err := os.Stat(filepath.Join(dir, BlocksFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Size() != int64(good) {
+			t.Fatalf("%d bytes of the last block: the log holds %d bytes, want it truncated to %d", cut, fi.Size(), good)
+		}
+
+		if err := s.AppendBlock(blocks[3]); err != nil {
+			t.Fatal(err)
+		}
+		s.Close()
+		got, _, err = openStore(t, dir).Load()
+		if err != nil {
+			t.Fatalf("%d bytes of the last block, appended again: %v", cut, err)
+		}
+		expectBlocks(t, got, blocks)
+	}
+}
+
+// a block that is all there but doesn't decode is corruption, not a torn
+// append: Load reports it and keeps the log for inspection
+func TestCorruptBlock(t *testing.T) {
+	dir := t.TempDir()
+	blocks := testBlocks(t, 3)
+	var log bytes.Buffer
+	for i, b := range blocks {
+		if i == 2 {
+			// a frame announcing one byte, holding a version no codec knows
+			log.Write([]byte{1, 0xff})
+		}
+		if err := blockchain.WriteBlock(&log, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, BlocksFile), log.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := openStore(t, dir).Load()
+	if !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("Load = %v, want ErrCorrupt", err)
+	}
+	expectBlocks(t, got, blocks[:2])
+	if fi, err := os.Stat(filepath.Join(dir, BlocksFile)); err != nil || fi.Size() != int64(log.Len()) {
+		t.Fatal("Load changed a corrupt log")
+	}
+}
+
+// writeLegacy writes blocks as the ndjson log stores had before the binary
+// format, followed by tail
+func writeLegacy(t *testing.T, dir string, blocks []blockchain.Block, tail string) {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, b := range blocks {
+		raw, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(append(raw, '\n'))
+	}
+	buf.WriteString(tail)
+	if err := os.WriteFile(filepath.Join(dir, LegacyBlocksFile), buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	blocks := testBlocks(t, 3)
+
+	t.Run("converted", func(t *testing.T) {
+		dir := t.TempDir()
+		// the last line never finished appending
+		writeLegacy(t, dir, blocks, `{"index":4,"timesta`)
+		got, _, err := openStore(t, dir).Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectBlocks(t, got, blocks)
+		for i, b := range got {
+			if blockchain.HashBlock(blockchain.SHA256{}, b) != b.Hash {
+				t.Fatalf("migrated block %d no longer hashes to %s", i, b.Hash)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, LegacyBlocksFile)); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("the legacy log is still there: %v", err)
+		}
+	})
+
+	t.Run("interrupted", func(t *testing.T) {
+		// a crash after the binary log was written, before the legacy one
+		// was removed: the binary log is complete and wins
+		dir := t.TempDir()
+		writeLegacy(t, dir, blocks, "")
+		var log bytes.Buffer
+		for _, b := range blocks[:2] {
+			if err := blockchain.WriteBlock(&log, b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.WriteFile(filepath.Join(dir, BlocksFile), log.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, _, err := openStore(t, dir).Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectBlocks(t, got, blocks[:2])
+		if _, err := os.Stat(filepath.Join(dir, LegacyBlocksFile)); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("the legacy log is still there: %v", err)
+		}
+	})
+
+	t.Run("unreadable", func(t *testing.T) {
+		dir := t.TempDir()
+		writeLegacy(t, dir, blocks[:1], "not json\n")
+		if s, err := Open(dir, nil); err == nil {
+			s.Close()
+			t.Fatal("Open converted a legacy log with a broken line")
+		}
+		if _, err := os.Stat(filepath.Join(dir, LegacyBlocksFile)); err != nil {
+			t.Fatalf("a legacy log that failed to convert was removed: %v", err)
+		}
+	})
+}
+
+// a rewrite whose new log can't be reopened leaves the store refusing block
+// appends, which would otherwise go to the replaced file, until a rewrite
+// reopens it
+func TestRewriteReopenFails(t *testing.T) {
+	dir := t.TempDir()
+	blocks := testBlocks(t, 3)
+	s := openStore(t, dir)
+	for _, b := range blocks[:2] {
+		if err := s.AppendBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	open := openBlocks
+	t.Cleanup(func() { openBlocks = open })
+	openBlocks = func(string) (*os.File, error) { return nil, errors.New("too many open files") }
+	if err := s.Rewrite(blocks[:1]); !errors.Is(err, ErrUnusable) {
+		t.Fatalf("Rewrite failing to reopen the log returned %v, want ErrUnusable", err)
+	}
+	if err := s.AppendBlock(blocks[1]); !errors.Is(err, ErrUnusable) {
+		t.Fatalf("AppendBlock after a failed reopen returned %v, want ErrUnusable", err)
+	}
+	if _, _, err := s.Load(); !errors.Is(err, ErrUnusable) {
+		t.Fatalf("Load after a failed reopen returned %v, want ErrUnusable", err)
+	}
+
+	openBlocks = open
+	if err := s.Rewrite(blocks[:1]); err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range blocks[1:] {
+		if err := s.AppendBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Close()
+	got, _, err := openStore(t, dir).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectBlocks(t, got, blocks)
+}
@@ -0,0 +1,179 @@
+// Package swap runs hashed-timelock (HTLC) atomic swaps between two
+// independent chains. Each side locks coins to the other behind the same
+// hash; claiming one lock reveals the secret that claims the other, and a
+// side that never gets claimed is refunded after its timeout.
+package swap
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+	"salmanahmed/blockchain/pkg/client"
+	"salmanahmed/blockchain/pkg/script"
+	"salmanahmed/blockchain/pkg/wallet"
+)
+
+// ErrNotFound is returned when no HTLC output matches
+var ErrNotFound = errors.New("no matching HTLC output")
+
+// Lock is an HTLC output on one chain
+type Lock struct {
+	blockchain.OutPoint
+	script.HTLCTerms
+	Amount int64 `json:"amount"`
+}
+
+// NewSecret returns a random preimage and its hash
+func NewSecret() (preimage []byte, hashHex string, err error) {
+	preimage = make([]byte, 32)
+	if _, err := rand.Read(preimage); err != nil {
+		return nil, "", err
+	}
+	return preimage, Hash(preimage), nil
+}
+
+// Hash is the hex SHA-256 that locks are opened behind
+func Hash(preimage []byte) string {
+	sum := sha256.Sum256(preimage)
+	return hex.EncodeToString(sum[:])
+}
+
+// Open locks amount of kp's coins on c to recipient behind hashHex,
+// refundable to kp from block timeout on; change returns to kp
+func Open(ctx context.Context, c *client.Client, kp wallet.Keypair, hashHex, recipient string, amount, timeout int64) (Lock, error) {
+	utxos, err := c.UTXOs(ctx, kp.Address)
+	if err != nil {
+		return Lock{}, err
+	}
+	var tx blockchain.Transaction
+	var total int64
+	for _, u := range utxos {
+		if total >= amount {
+			break
+		}
+		tx.Inputs = append(tx.Inputs, blockchain.TxInput{TxID: u.TxID, Index: u.Index})
+		total += u.Amount
+	}
+	if total < amount {
+		return Lock{}, fmt.Errorf("insufficient funds: %s holds %d", kp.Address, total)
+	}
+	terms := script.HTLCTerms{Hash: hashHex, Recipient: recipient, Refund: kp.Address, Timeout: timeout}
+	tx.Outputs = append(tx.Outputs, blockchain.TxOutput{Amount: amount, Lock: script.HTLC(hashHex, recipient, kp.Address, timeout)})
+	if change := total - amount; change > 0 {
+		tx.Outputs = append(tx.Outputs, blockchain.TxOutput{Amount: change, Lock: script.P2PKH(kp.Address)})
+	}
+	sig, err := wallet.Sign(kp.PrivateKey, tx.SigHash())
+	if err != nil {
+		return Lock{}, err
+	}
+	for i := range tx.Inputs {
+		tx.Inputs[i].Unlock = script.P2PKHUnlock(sig, kp.PublicKey)
+	}
+	res, err := c.SubmitTransaction(ctx, tx.Seal())
+	if err != nil {
+		return Lock{}, err
+	}
+	return Lock{OutPoint: blockchain.OutPoint{TxID: res.TxID, Index: 0}, HTLCTerms: terms, Amount: amount}, nil
+}
+
+// Claim spends l to kp, its recipient, revealing preimage
+func Claim(ctx context.Context, c *client.Client, kp wallet.Keypair, l Lock, preimage []byte) (string, error) {
+	return spend(ctx, c, kp, l, func(sig string) string { return script.HTLCClaim(sig, kp.PublicKey, preimage) })
+}
+
+// Refund spends l back to kp, its refund address, once it has timed out
+func Refund(ctx context.Context, c *client.Client, kp wallet.Keypair, l Lock) (string, error) {
+	return spend(ctx, c, kp, l, func(sig string) string { return script.HTLCRefund(sig, kp.PublicKey) })
+}
+
+// spend moves l's whole amount to kp with the unlocking script unlock builds
+func spend(ctx context.Context, c *client.Client, kp wallet.Keypair, l Lock, unlock func(sig string) string) (string, error) {
+	tx := blockchain.Transaction{
+		Inputs:  []blockchain.TxInput{{TxID: l.TxID, Index: l.Index}},
+		Outputs: []blockchain.TxOutput{{Amount: l.Amount, Lock: script.P2PKH(kp.Address)}},
+	}
+	sig, err := wallet.Sign(kp.PrivateKey, tx.SigHash())
+	if err != nil {
+		return "", err
+	}
+	tx.Inputs[0].Unlock = unlock(sig)
+	res, err := c.SubmitTransaction(ctx, tx.Seal())
+	return res.TxID, err
+}
+
+// Find returns the unspent HTLC output on c locked behind hashHex; an empty
+// recipient matches any
+func Find(ctx context.Context, c *client.Client, hashHex, recipient string) (Lock, error) {
+	utxos, err := c.UTXOs(ctx, "")
+	if err != nil {
+		return Lock{}, err
+	}
+	for _, u := range utxos {
+		terms, ok := script.ParseHTLC(u.Lock)
+		if ok && terms.Hash == hashHex && (recipient == "" || terms.Recipient == recipient) {
+			return Lock{OutPoint: u.OutPoint, HTLCTerms: terms, Amount: u.Amount}, nil
+		}
+	}
+	return Lock{}, fmt.Errorf("%w: hash %s", ErrNotFound, hashHex)
+}
+
+// Preimage looks through c's pending and confirmed transactions for a claim
+// revealing the preimage of hashHex; ok is false if there is none yet
+func Preimage(ctx context.Context, c *client.Client, hashHex string) (preimage []byte, ok bool, err error) {
+	txns, err := c.Pending(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	blocks, err := c.Blocks(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, b := range blocks {
+		txns = append(txns, b.Txns...)
+	}
+	for _, t := range txns {
+		for _, in := range t.Inputs {
+			p, ok := script.HTLCPreimage(in.Unlock)
+			if !ok {
+				continue
+			}
+			if Hash(p) == hashHex {
+				return p, true, nil
+			}
+		}
+	}
+	return nil, false, nil
+}
+
+// Relay waits for the secret behind hashHex to be revealed on from, then
+// uses it to claim the matching HTLC output on to for kp. It polls every
+// interval until ctx ends.
+func Relay(ctx context.Context, from, to *client.Client, kp wallet.Keypair, hashHex string, interval time.Duration, logf func(format string, args ...interface{})) (string, error) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		preimage, ok, err := Preimage(ctx, from, hashHex)
+		if err != nil {
+			logf("watching for the secret: %v", err)
+		}
+		if ok {
+			logf("secret revealed: %x", preimage)
+			l, err := Find(ctx, to, hashHex, kp.Address)
+			if err != nil {
+				return "", err
+			}
+			return Claim(ctx, to, kp, l, preimage)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Package validators provides ready-made transaction rules and loads custom
+// ones from Go plugins and WebAssembly modules, for use with
+// Chain.RegisterTxValidator. The rules look at a transaction's data payload,
+// and transfers without data pass, except for WebAssembly modules, which
+// see the whole transaction.
+package validators
+
+import (
+	"fmt"
+	"plugin"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+// studentID matches roll numbers like the genesis transaction, e.g. i22-0743
+var studentID = regexp.MustCompile(`^[a-z]\d{2}-\d{4}$`)
+
+// StudentID requires the payload to be a valid student roll number
+func StudentID(tx string) error {
+	if !studentID.MatchString(tx) {
+		return fmt.Errorf("payload %q is not a valid student ID", tx)
+	}
+	return nil
+}
+
+// MaxLength rejects payloads longer than n bytes
+func MaxLength(n int) func(tx string) error {
+	return func(tx string) error {
+		if len(tx) > n {
+			return fmt.Errorf("payload is %d bytes, limit is %d", len(tx), n)
+		}
+		return nil
+	}
+}
+
+// Payload adapts a rule on the data payload to a TxValidator
+func Payload(rule func(data string) error) blockchain.TxValidator {
+	return func(tx blockchain.Transaction) error {
+		if tx.Data == "" {
+			return nil
+		}
+		return rule(tx.Data)
+	}
+}
+
+// Printable rejects payloads containing control characters
+func Printable(tx string) error {
+	for _, r := range tx {
+		if r < 0x20 || r == 0x7f {
+			return fmt.Errorf("payload contains control character %U", r)
+		}
+	}
+	return nil
+}
+
+// Signed names the validator that required signed inputs before signatures
+// became a chain rule. It is deprecated: a config listing it turns on
+// require_signatures instead (see config.Config.SignaturesRequired), and
+// Builtin refuses it.
+const Signed = "signed"
+
+// builtins maps configuration names to payload validators; "max-length:N"
+// is handled separately
+var builtins = map[string]func(string) error{
+	"student-id": StudentID,
+	"printable":  Printable,
+}
+
+// Names lists the built-in validators accepted by Builtin
+func Names() []string {
+	out := []string{"max-length:N"}
+	for n := range builtins {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Builtin returns the built-in validator called name
+func Builtin(name string) (blockchain.TxValidator, error) {
+	if v, ok := builtins[name]; ok {
+		return Payload(v), nil
+	}
+	if name == Signed {
+		return nil, fmt.Errorf("validators: %q is deprecated; it is the require_signatures setting now", name)
+	}
+	if rest, ok := strings.CutPrefix(name, "max-length:"); ok {
+		n, err := strconv.Atoi(rest)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("validators: invalid limit in %q", name)
+		}
+		return Payload(MaxLength(n)), nil
+	}
+	return nil, fmt.Errorf("validators: unknown validator %q (have %s)", name, strings.Join(Names(), ", "))
+}
+
+// LoadPlugin opens a Go plugin (built with -buildmode=plugin) exporting
+//
+//	func Validate(tx string) error
+//
+// and optionally `var Name string`, which defaults to the plugin path.
+// Validate sees the data payload, as with the built-in rules.
+// Plugins need cgo and a matching toolchain, so they only work on Linux and
+// macOS builds of the node.
+func LoadPlugin(path string) (name string, fn blockchain.TxValidator, err error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("validators: %w", err)
+	}
+	sym, err := p.Lookup("Validate")
+	if err != nil {
+		return "", nil, fmt.Errorf("validators: %s: %w", path, err)
+	}
+	validate, ok := sym.(func(string) error)
+	if !ok {
+		return "", nil, fmt.Errorf("validators: %s: Validate has type %T, want func(string) error", path, sym)
+	}
+	name = path
+	if sym, err := p.Lookup("Name"); err == nil {
+		if n, ok := sym.(*string); ok && *n != "" {
+			name = *n
+		}
+	}
+	return name, Payload(validate), nil
+}
@@ -0,0 +1,83 @@
+package validators
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+func TestBuiltin(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		tx     string
+		reject bool
+	}{
+		{"student-id", "i22-0743", false},
+		{"student-id", "I22-0743", true},
+		{"student-id", "i22-0743 ", true},
+		{"printable", "alice pays bob 5", false},
+		{"printable", "alice\npays bob", true},
+		{"max-length:5", "12345", false},
+		{"max-length:5", "123456", true},
+	} {
+		v, err := Builtin(c.name)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if err := v(blockchain.NewDataTx(c.tx)); (err != nil) != c.reject {
+			t.Errorf("%s(%q) = %v, want rejected %v", c.name, c.tx, err, c.reject)
+		}
+	}
+}
+
+func TestPayloadRulesSkipTransfers(t *testing.T) {
+	transfer := blockchain.Transaction{
+		Inputs:  []blockchain.TxInput{{TxID: blockchain.CalculateHash("parent"), Index: 0, Unlock: "OP_1"}},
+		Outputs: []blockchain.TxOutput{{Amount: 5, Lock: "OP_1"}},
+	}.Seal()
+	for _, name := range []string{"student-id", "printable", "max-length:1"} {
+		v, err := Builtin(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := v(transfer); err != nil {
+			t.Errorf("%s rejects a transfer without data: %v", name, err)
+		}
+	}
+}
+
+func TestBuiltinRejectsUnknownNames(t *testing.T) {
+	for _, name := range []string{"", "student_id", "max-length", "max-length:", "max-length:0", "max-length:-3", "max-length:ten"} {
+		if _, err := Builtin(name); err == nil {
+			t.Errorf("Builtin(%q) selected a validator", name)
+		}
+	}
+	_, err := Builtin("nope")
+	if err == nil || !strings.Contains(err.Error(), strings.Join(Names(), ", ")) {
+		t.Fatalf("the error for an unknown validator doesn't list the known ones: %v", err)
+	}
+}
+
+func TestNamesAreSortedAndSelectable(t *testing.T) {
+	names := Names()
+	if !sort.StringsAreSorted(names) {
+		t.Fatalf("Names() = %v, not sorted", names)
+	}
+	for _, name := range names {
+		if name == "max-length:N" {
+			name = "max-length:10"
+		}
+		if _, err := Builtin(name); err != nil {
+			t.Errorf("Names() lists %s, but Builtin refuses it: %v", name, err)
+		}
+	}
+}
+
+func TestLoadPluginReportsAMissingFile(t *testing.T) {
+	if _, _, err := LoadPlugin(filepath.Join(t.TempDir(), "missing.so")); err == nil {
+		t.Fatal("loading a plugin that doesn't exist succeeded")
+	}
+}
@@ -0,0 +1,157 @@
+package validators
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"salmanahmed/blockchain/pkg/blockchain"
+)
+
+// Limits on a WASM validator, per transaction
+const (
+	wasmTimeout   = 100 * time.Millisecond
+	wasmMaxPages  = 256 // of 64 KiB, so 16 MiB of memory
+	maxWASMReason = 512 // bytes of a rejection reason kept
+)
+
+// wasmExports are the functions a validator module must export
+var wasmExports = []struct {
+	name            string
+	params, results []api.ValueType
+}{
+	{"alloc", []api.ValueType{api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32}},
+	{"validate", []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{api.ValueTypeI64}},
+}
+
+// wasmValidator runs a compiled validator module
+type wasmValidator struct {
+	runtime wazero.Runtime
+	module  wazero.CompiledModule
+}
+
+// LoadWASM compiles a WebAssembly module exporting its memory and
+//
+//	alloc(size i32) -> i32
+//	validate(ptr i32, len i32) -> i64
+//
+// For every transaction the node writes its JSON into a buffer from alloc
+// and calls validate on it. 0 accepts the transaction; anything else
+// rejects it, the high 32 bits giving the address and the low 32 bits the
+// length of a UTF-8 reason in memory (0 for none). Each call gets a fresh
+// instance, with WASI but no files, environment or real clock, 16 MiB of
+// memory and 100ms to run; a module that traps or runs out of time rejects
+// the transaction. The name is the file path.
+func LoadWASM(path string) (name string, fn blockchain.TxValidator, err error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("validators: %w", err)
+	}
+	ctx := context.Background()
+	rt := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(wasmMaxPages))
+	mod, err := rt.CompileModule(ctx, code)
+	if err == nil {
+		err = checkWASMExports(mod)
+	}
+	if err == nil {
+		_, err = wasi_snapshot_preview1.Instantiate(ctx, rt)
+	}
+	if err != nil {
+		rt.Close(ctx)
+		return "", nil, fmt.Errorf("validators: %s: %w", path, err)
+	}
+	w := &wasmValidator{runtime: rt, module: mod}
+	return path, w.validate, nil
+}
+
+// checkWASMExports makes sure mod exports what validate calls
+func checkWASMExports(mod wazero.CompiledModule) error {
+	if _, ok := mod.ExportedMemories()["memory"]; !ok {
+		return errors.New("module does not export its memory")
+	}
+	fns := mod.ExportedFunctions()
+	for _, want := range wasmExports {
+		f, ok := fns[want.name]
+		if !ok {
+			return fmt.Errorf("module does not export %s", want.name)
+		}
+		if !sameTypes(f.ParamTypes(), want.params) || !sameTypes(f.ResultTypes(), want.results) {
+			return fmt.Errorf("%s has the wrong signature (want %s(%s) -> %s)", want.name, want.name,
+				typeNames(want.params), typeNames(want.results))
+		}
+	}
+	return nil
+}
+
+// validate runs the module on tx in a fresh instance
+func (w *wasmValidator) validate(tx blockchain.Transaction) error {
+	in, err := json.Marshal(tx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), wasmTimeout)
+	defer cancel()
+	// _initialize sets up reactor modules, e.g. Go's -buildmode=c-shared
+	m, err := w.runtime.InstantiateModule(ctx, w.module, wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize"))
+	if err != nil {
+		return fmt.Errorf("validator module failed to start: %w", err)
+	}
+	defer m.Close(context.Background())
+
+	res, err := m.ExportedFunction("alloc").Call(ctx, uint64(len(in)))
+	if err != nil {
+		return fmt.Errorf("validator module failed: alloc: %w", err)
+	}
+	ptr := uint32(res[0])
+	if !m.Memory().Write(ptr, in) {
+		return fmt.Errorf("validator module failed: alloc returned %d, outside its memory", ptr)
+	}
+	res, err = m.ExportedFunction("validate").Call(ctx, uint64(ptr), uint64(len(in)))
+	if err != nil {
+		return fmt.Errorf("validator module failed: %w", err)
+	}
+	if res[0] == 0 {
+		return nil
+	}
+	at, n := uint32(res[0]>>32), uint32(res[0])
+	if n > maxWASMReason {
+		n = maxWASMReason
+	}
+	reason, ok := m.Memory().Read(at, n)
+	if !ok || n == 0 {
+		return errors.New("rejected by the validator module")
+	}
+	return errors.New(strings.ToValidUTF8(string(reason), "?"))
+}
+
+// sameTypes reports whether a and b list the same value types
+func sameTypes(a, b []api.ValueType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// typeNames lists types as WebAssembly text, e.g. "i32, i32"
+func typeNames(types []api.ValueType) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = api.ValueTypeName(t)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,97 @@
+package wallet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// p256PointLen is the size of an uncompressed P-256 public key
+const p256PointLen = 65
+
+// ErrDecrypt is returned when a sealed message can't be opened with a key
+var ErrDecrypt = errors.New("cannot decrypt")
+
+// Encrypt seals plaintext to a hex public key with ECIES: an ephemeral
+// P-256 ECDH key, SHA-256 over the shared secret as the key, and AES-256-GCM.
+// The result is hex of ephemeral public key || nonce || ciphertext.
+func Encrypt(pubHex string, plaintext []byte) (string, error) {
+	raw, err := hex.DecodeString(pubHex)
+	if err != nil {
+		return "", fmt.Errorf("public key: %w", err)
+	}
+	pub, err := ecdh.P256().NewPublicKey(raw)
+	if err != nil {
+		return "", fmt.Errorf("public key: %w", err)
+	}
+	eph, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	shared, err := eph.ECDH(pub)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := sealer(shared, eph.PublicKey().Bytes())
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	out := append(eph.PublicKey().Bytes(), nonce...)
+	return hex.EncodeToString(gcm.Seal(out, nonce, plaintext, nil)), nil
+}
+
+// Decrypt opens a message sealed by Encrypt with the recipient's hex private key
+func Decrypt(privHex, sealedHex string) ([]byte, error) {
+	raw, err := hex.DecodeString(privHex)
+	if err != nil {
+		return nil, fmt.Errorf("private key: %w", err)
+	}
+	priv, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("private key: %w", err)
+	}
+	sealed, err := hex.DecodeString(sealedHex)
+	if err != nil || len(sealed) < p256PointLen {
+		return nil, fmt.Errorf("%w: malformed message", ErrDecrypt)
+	}
+	ephRaw, rest := sealed[:p256PointLen], sealed[p256PointLen:]
+	eph, err := ecdh.P256().NewPublicKey(ephRaw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecrypt, err)
+	}
+	shared, err := priv.ECDH(eph)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecrypt, err)
+	}
+	gcm, err := sealer(shared, ephRaw)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: malformed message", ErrDecrypt)
+	}
+	plain, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: wrong key or tampered message", ErrDecrypt)
+	}
+	return plain, nil
+}
+
+// sealer derives the AES-GCM cipher for a shared secret and ephemeral key
+func sealer(shared, ephPub []byte) (cipher.AEAD, error) {
+	key := sha256.Sum256(append(append([]byte{}, shared...), ephPub...))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
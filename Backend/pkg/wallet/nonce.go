@@ -0,0 +1,83 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/asn1"
+	"math/big"
+)
+
+// signDeterministic signs digest with a nonce derived from the key and the
+// digest as RFC 6979 describes (HMAC-SHA256), so the same key signing the
+// same transaction always produces the same signature and the same txid:
+// simulations and fixtures replay byte for byte, and a weak random source
+// can't leak the key. The result is ASN.1 DER, as ecdsa.SignASN1 returns.
+func signDeterministic(key *ecdsa.PrivateKey, digest []byte) ([]byte, error) {
+	curve := key.Curve
+	n := curve.Params().N
+	size := (n.BitLen() + 7) / 8
+	e := hashToInt(digest, n)
+	x := key.D.FillBytes(make([]byte, size))
+	h := new(big.Int).Mod(e, n).FillBytes(make([]byte, size))
+
+	v := make([]byte, sha256.Size)
+	for i := range v {
+		v[i] = 1
+	}
+	k := make([]byte, sha256.Size)
+	mac := func(key []byte, parts ...[]byte) []byte {
+		m := hmac.New(sha256.New, key)
+		for _, p := range parts {
+			m.Write(p)
+		}
+		return m.Sum(nil)
+	}
+	k = mac(k, v, []byte{0}, x, h)
+	v = mac(k, v)
+	k = mac(k, v, []byte{1}, x, h)
+	v = mac(k, v)
+
+	for {
+		var t []byte
+		for len(t) < size {
+			v = mac(k, v)
+			t = append(t, v...)
+		}
+		nonce := hashToInt(t[:size], n)
+		if nonce.Sign() > 0 && nonce.Cmp(n) < 0 {
+			rx, _ := curve.ScalarBaseMult(nonce.FillBytes(make([]byte, size)))
+			r := rx.Mod(rx, n)
+			if r.Sign() > 0 {
+				s := new(big.Int).Mul(r, key.D)
+				s.Add(s, e)
+				s.Mul(s, new(big.Int).ModInverse(nonce, n))
+				s.Mod(s, n)
+				if s.Sign() > 0 {
+					return encodeSignature(r, s)
+				}
+			}
+		}
+		k = mac(k, v, []byte{0})
+		v = mac(k, v)
+	}
+}
+
+// hashToInt converts the leftmost bits of hash, as many as n has, to an
+// integer, as ECDSA does
+func hashToInt(hash []byte, n *big.Int) *big.Int {
+	bits := n.BitLen()
+	if size := (bits + 7) / 8; len(hash) > size {
+		hash = hash[:size]
+	}
+	i := new(big.Int).SetBytes(hash)
+	if excess := len(hash)*8 - bits; excess > 0 {
+		i.Rsh(i, uint(excess))
+	}
+	return i
+}
+
+// encodeSignature returns r and s as an ASN.1 DER SEQUENCE
+func encodeSignature(r, s *big.Int) ([]byte, error) {
+	return asn1.Marshal(struct{ R, S *big.Int }{r, s})
+}
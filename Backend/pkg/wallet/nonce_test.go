@@ -0,0 +1,45 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+// RFC 6979 A.2.5, P-256 with SHA-256, message "sample"
+func TestSignDeterministic(t *testing.T) {
+	kp, err := FromPrivateKey("c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721")
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte("sample"))
+	sigHex, err := Sign(kp.PrivateKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := hex.DecodeString(sigHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sig struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(raw, &sig); err != nil {
+		t.Fatal(err)
+	}
+	wantR, _ := new(big.Int).SetString("efd48b2aacb6a8fd1140dd9cd45e81d69d2c877b56aaf991c34d0ea84eaf3716", 16)
+	wantS, _ := new(big.Int).SetString("f7cb1c942d657c41d436c7a1b6e29f65f3e900dbb9aff4064dc4ab2f843acda8", 16)
+	if sig.R.Cmp(wantR) != 0 || sig.S.Cmp(wantS) != 0 {
+		t.Fatalf("signed (%x, %x), want the RFC 6979 vector (%x, %x)", sig.R, sig.S, wantR, wantS)
+	}
+	if !Verify(kp.PublicKey, digest[:], sigHex) {
+		t.Fatal("the signature doesn't verify")
+	}
+	again, err := Sign(kp.PrivateKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != sigHex {
+		t.Fatal("signing the same digest twice gave different signatures")
+	}
+}
@@ -0,0 +1,120 @@
+// Package wallet generates and encodes ECDSA P-256 keypairs.
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+)
+
+// Keypair is a hex-encoded ECDSA P-256 keypair and its address
+type Keypair struct {
+	PrivateKey string `json:"private_key"`
+	PublicKey  string `json:"public_key"`
+	Address    string `json:"address"`
+}
+
+// New generates a fresh keypair
+func New() (Keypair, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return Keypair{}, err
+	}
+	pub := EncodePublicKey(&key.PublicKey)
+	return Keypair{
+		PrivateKey: hex.EncodeToString(key.D.FillBytes(make([]byte, 32))),
+		PublicKey:  pub,
+		Address:    Address(pub),
+	}, nil
+}
+
+// EncodePublicKey returns the uncompressed point as hex
+func EncodePublicKey(pub *ecdsa.PublicKey) string {
+	return hex.EncodeToString(elliptic.Marshal(elliptic.P256(), pub.X, pub.Y))
+}
+
+// DecodePublicKey parses a hex public key produced by EncodePublicKey
+func DecodePublicKey(s string) (*ecdsa.PublicKey, error) {
+	raw, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("public key: %w", err)
+	}
+	x, y := elliptic.Unmarshal(elliptic.P256(), raw)
+	if x == nil {
+		return nil, fmt.Errorf("public key: not a P-256 point")
+	}
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+}
+
+// DecodePrivateKey parses a hex private scalar produced by New
+func DecodePrivateKey(s string) (*ecdsa.PrivateKey, error) {
+	raw, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("private key: %w", err)
+	}
+	curve := elliptic.P256()
+	d := new(big.Int).SetBytes(raw)
+	if d.Sign() == 0 || d.Cmp(curve.Params().N) >= 0 {
+		return nil, fmt.Errorf("private key: out of range")
+	}
+	key := &ecdsa.PrivateKey{D: d}
+	key.PublicKey.Curve = curve
+	key.PublicKey.X, key.PublicKey.Y = curve.ScalarBaseMult(raw)
+	return key, nil
+}
+
+// Address derives the account address from a hex public key: the first 20
+// bytes of its SHA-256
+func Address(pubHex string) string {
+	raw, err := hex.DecodeString(pubHex)
+	if err != nil {
+		raw = []byte(pubHex)
+	}
+	h := sha256.Sum256(raw)
+	return hex.EncodeToString(h[:20])
+}
+
+// FromPrivateKey rebuilds the keypair for a hex private key
+func FromPrivateKey(privHex string) (Keypair, error) {
+	key, err := DecodePrivateKey(privHex)
+	if err != nil {
+		return Keypair{}, err
+	}
+	pub := EncodePublicKey(&key.PublicKey)
+	return Keypair{
+		PrivateKey: hex.EncodeToString(key.D.FillBytes(make([]byte, 32))),
+		PublicKey:  pub,
+		Address:    Address(pub),
+	}, nil
+}
+
+// Sign signs a digest with a hex private key and returns the DER signature
+// as hex. Signing is deterministic (see signDeterministic).
+func Sign(privHex string, digest []byte) (string, error) {
+	key, err := DecodePrivateKey(privHex)
+	if err != nil {
+		return "", err
+	}
+	sig, err := signDeterministic(key, digest)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sig), nil
+}
+
+// Verify checks a hex DER signature over digest against a hex public key
+func Verify(pubHex string, digest []byte, sigHex string) bool {
+	pub, err := DecodePublicKey(pubHex)
+	if err != nil {
+		return false
+	}
+	sig, err := hex.DecodeString(sigHex)
+	if err != nil {
+		return false
+	}
+	return ecdsa.VerifyASN1(pub, digest, sig)
+}
@@ -1,6 +1,23 @@
 import React, { useState, useEffect } from 'react';
 import './App.css';
 
+// Describe a transaction: its data, or a short contract or transfer summary
+const txLabel = (tx) => {
+  if (tx.commit) return `commitment ${tx.commit.slice(0, 12)}...`;
+  if (tx.blob) return `${tx.data ? tx.data + ' ' : ''}blob ${tx.blob.slice(0, 12)}...`;
+  if (tx.data) return tx.data;
+  if (tx.contract) {
+    return tx.contract.code
+      ? `deploy contract ${tx.id.slice(0, 40)}`
+      : `call contract ${tx.contract.address.slice(0, 12)}... (${(tx.contract.args || []).join(', ')})`;
+  }
+  if (tx.confidential) return `confidential payload (${tx.confidential.commitment.slice(0, 12)}…)`;
+  if (tx.kv) return `kv ${tx.kv.map((op) => `${op.op} ${op.key}`).join(', ')}`;
+  const total = (tx.outputs || []).reduce((sum, o) => sum + o.amount, 0);
+  if (tx.coinbase) return `coinbase for block ${tx.coinbase} (${total} to ${tx.outputs[0].lock.split(' ')[2].slice(0, 12)}...)`;
+  return `transfer ${tx.id.slice(0, 12)}... (${(tx.inputs || []).length} in, ${total} out)`;
+};
+
 const App = () => {
   const [transactionData, setTransactionData] = useState('');
   const [pendingTransactions, setPendingTransactions] = useState([]);
@@ -9,6 +26,10 @@ const App = () => {
   const [searchResults, setSearchResults] = useState([]);
   const [loading, setLoading] = useState(false);
   const [message, setMessage] = useState('');
+  const [blockTime, setBlockTime] = useState(null);
+  const [tamper, setTamper] = useState({ block: '', tx: '0', data: '', rehash: false });
+  const [validation, setValidation] = useState(null);
+  const [forkView, setForkView] = useState(null);
 
   const API_BASE = 'http://localhost:8080';
 
@@ -30,12 +51,90 @@ const App = () => {
       const response = await fetch(`${API_BASE}/blocks`);
       const data = await response.json();
       setBlockchain(data || []);
+      fetchBlockTime();
+      fetchForkView();
     } catch (error) {
       console.error('Error fetching blockchain:', error);
       setMessage('Error fetching blockchain');
     }
   };
 
+  // Fetch block interval statistics and the next-block ETA
+  const fetchBlockTime = async () => {
+    try {
+      const response = await fetch(`${API_BASE}/stats/blocktime`);
+      setBlockTime(await response.json());
+    } catch (error) {
+      console.error('Error fetching block time stats:', error);
+    }
+  };
+
+  // Re-check every block; failures say which check broke where
+  const validateChain = async () => {
+    try {
+      const response = await fetch(`${API_BASE}/validate`);
+      setValidation(await response.json());
+    } catch (error) {
+      console.error('Error validating chain:', error);
+      setMessage('Error validating chain');
+    }
+  };
+
+  // Fetch the chain and the blocks that forked off it
+  const fetchForkView = async () => {
+    try {
+      const response = await fetch(`${API_BASE}/fork-view?depth=20`);
+      setForkView(await response.json());
+    } catch (error) {
+      console.error('Error fetching fork view:', error);
+    }
+  };
+
+  // Edit a confirmed transaction (the node must run with --demo)
+  const tamperBlock = async (e) => {
+    e.preventDefault();
+    try {
+      const response = await fetch(`${API_BASE}/tamper`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({
+          block: Number(tamper.block),
+          tx: Number(tamper.tx),
+          data: tamper.data,
+          rehash: tamper.rehash,
+        }),
+      });
+      const data = await response.json();
+      if (response.ok) {
+        setValidation(data.validation);
+        setMessage(`Block ${data.block} tampered with`);
+      } else {
+        setMessage(`Error tampering: ${data.error}`);
+      }
+    } catch (error) {
+      console.error('Error tampering:', error);
+      setMessage('Error tampering');
+    }
+  };
+
+  // Put every tampered block back as mined
+  const untamper = async () => {
+    try {
+      const response = await fetch(`${API_BASE}/tamper`, { method: 'DELETE' });
+      if (response.ok) {
+        setMessage('Tampered blocks restored');
+        validateChain();
+      } else {
+        setMessage(`Error restoring: ${(await response.json()).error}`);
+      }
+    } catch (error) {
+      console.error('Error restoring blocks:', error);
+      setMessage('Error restoring blocks');
+    }
+  };
+
   // Add transaction
   const addTransaction = async (e) => {
     e.preventDefault();
@@ -78,7 +177,19 @@ const App = () => {
       });
 
       if (response.ok) {
-        setMessage('Block mined successfully!');
+        // mining runs in the background; poll the job until it finishes
+        let job = await response.json();
+        while (job.state === 'queued' || job.state === 'mining') {
+          await new Promise((resolve) => setTimeout(resolve, 500));
+          job = await (await fetch(`${API_BASE}/mine/status/${job.id}`)).json();
+        }
+        if (job.state === 'mined') {
+          setMessage('Block mined successfully!');
+        } else if (job.state === 'empty') {
+          setMessage('No transactions to mine');
+        } else {
+          setMessage(`Error mining block: ${job.error || job.state}`);
+        }
         fetchPendingTransactions();
         fetchBlockchain();
       } else {
@@ -119,6 +230,31 @@ const App = () => {
     fetchBlockchain();
   }, []);
 
+  // Refresh when the node pushes events, reconnecting if the socket drops
+  useEffect(() => {
+    let socket;
+    let retry;
+    const connect = () => {
+      socket = new WebSocket(`${API_BASE.replace(/^http/, 'ws')}/ws?types=tx_added,tx_dropped,block_mined,chain_replaced,chain_reset,chain_tampered`);
+      socket.onmessage = (msg) => {
+        const event = JSON.parse(msg.data);
+        fetchPendingTransactions();
+        if (event.type !== 'tx_added' && event.type !== 'tx_dropped') {
+          fetchBlockchain();
+        }
+      };
+      socket.onclose = () => {
+        retry = setTimeout(connect, 2000);
+      };
+    };
+    connect();
+    return () => {
+      clearTimeout(retry);
+      socket.onclose = null;
+      socket.close();
+    };
+  }, []);
+
   // Clear message after 3 seconds
   useEffect(() => {
     if (message) {
@@ -131,6 +267,12 @@ const App = () => {
     <div className="app">
       <header className="app-header">
         <h1>Salman Ahmed Blockchain</h1>
+        {blockTime && (
+          <span className="block-time">
+            {blockTime.windows[0].intervals > 0 && `avg block ${blockTime.windows[0].avg_seconds.toFixed(1)}s · `}
+            next {blockTime.mining ? 'in' : 'takes'} ~{blockTime.eta_seconds.toFixed(1)}s
+          </span>
+        )}
       </header>
 
       {message && (
@@ -167,7 +309,7 @@ const App = () => {
             ) : (
               pendingTransactions.map((transaction, index) => (
                 <div key={index} className="transaction-item">
-                  {transaction}
+                  {txLabel(transaction)}
                 </div>
               ))
             )}
@@ -198,7 +340,68 @@ const App = () => {
               <h3>Search Results:</h3>
               {searchResults.map((result, index) => (
                 <div key={index} className="search-result">
-                  <strong>Block {result.block_index}:</strong> {result.transaction}
+                  <strong>Block {result.block_index}:</strong> {txLabel(result.transaction)}
+                </div>
+              ))}
+            </div>
+          )}
+        </section>
+
+        {/* Tamper Demo Section */}
+        <section className="section">
+          <h2>Tamper Demo</h2>
+          <form onSubmit={tamperBlock} className="transaction-form">
+            <input
+              type="number"
+              value={tamper.block}
+              onChange={(e) => setTamper({ ...tamper, block: e.target.value })}
+              placeholder="Block"
+              className="input"
+            />
+            <input
+              type="number"
+              value={tamper.tx}
+              onChange={(e) => setTamper({ ...tamper, tx: e.target.value })}
+              placeholder="Transaction"
+              className="input"
+            />
+            <input
+              type="text"
+              value={tamper.data}
+              onChange={(e) => setTamper({ ...tamper, data: e.target.value })}
+              placeholder="New transaction data..."
+              className="input"
+            />
+            <label>
+              <input
+                type="checkbox"
+                checked={tamper.rehash}
+                onChange={(e) => setTamper({ ...tamper, rehash: e.target.checked })}
+              />
+              Recompute hashes
+            </label>
+            <button type="submit" className="btn btn-primary">Tamper</button>
+          </form>
+          <button onClick={validateChain} className="btn btn-secondary">Validate Chain</button>
+          <button onClick={untamper} className="btn btn-success">Undo Tampering</button>
+          {validation && (
+            <div className="search-results">
+              <h3>{validation.valid ? `Chain valid up to block ${validation.height}` : 'Chain invalid'}</h3>
+              {(validation.failures || []).map((f, index) => (
+                <div key={index} className="search-result">
+                  <strong>Block {f.block} ({f.check}):</strong> {f.reason}
+                </div>
+              ))}
+            </div>
+          )}
+          {forkView && (
+            <div className="search-results">
+              <h3>Fork View</h3>
+              {forkView.nodes.map((node) => (
+                <div key={node.hash} className="search-result">
+                  <strong>#{node.index}</strong> <code className="hash">{node.hash.slice(0, 16)}</code>{' '}
+                  {node.status}{node.source && ` (${node.source})`}{node.tampered && ' · tampered'}
+                  {!node.children.length && node.status === 'main' && node.hash !== forkView.tip && ' · link broken'}
                 </div>
               ))}
             </div>
@@ -239,13 +442,23 @@ const App = () => {
                     <div className="detail-row">
                       <strong>Nonce:</strong> {block.nonce}
                     </div>
+                    {block.confirmations > 0 && (
+                      <div className="detail-row">
+                        <strong>Confirmations:</strong> {block.confirmations}
+                      </div>
+                    )}
+                    {block.difficulty > 0 && (
+                      <div className="detail-row">
+                        <strong>Difficulty:</strong> {block.difficulty}
+                      </div>
+                    )}
                     <div className="detail-row">
                       <strong>Transactions ({block.transactions?.length || 0}):</strong>
                       <div className="transactions">
                         {block.transactions && block.transactions.length > 0 ? (
                           block.transactions.map((tx, txIndex) => (
                             <div key={txIndex} className="transaction">
-                              {tx}
+                              {txLabel(tx)}
                             </div>
                           ))
                         ) : (